@@ -92,6 +92,9 @@ const (
 	NotSupportedError     FsError = FsError(int(unix.ENOTSUP))      // Operation not supported
 	NoDataError           FsError = FsError(int(unix.ENODATA))      // No data available
 	TimedOut              FsError = FsError(int(unix.ETIMEDOUT))    // Connection Timed Out
+	QuotaExceededError    FsError = FsError(int(unix.EDQUOT))       // Quota exceeded
+	DeadlockError         FsError = FsError(int(unix.EDEADLK))      // Resource deadlock would occur
+	InterruptedError      FsError = FsError(int(unix.EINTR))        // Interrupted system call
 )
 
 // Errors that map to constants already defined above
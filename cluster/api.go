@@ -0,0 +1,98 @@
+// Package cluster tracks the liveness of the Peers making up the cluster and, should the Peer
+// serving a Volume die, arranges for a Peer listed in the Volume's StandbyPeerList to take it over.
+//
+// Liveness is established by each Peer sending a UDP heartbeat every [Cluster]HeartBeatInterval
+// (+/- [Cluster]HeartBeatVariance) to [Cluster]PrivateClusterUDPPort at every other Peer's
+// [Peer:<PeerName>]PrivateIPAddr. A Peer not heard from for [Cluster]HeartBeatExpiration is
+// considered dead.
+//
+// Ownership of a Volume is arbitrated by a fencing token recording the owning Peer and an ever
+// increasing epoch. Each epoch is claimed by conditionally creating (i.e. with If-None-Match: *)
+// an object named FencingTokenObjectName + "." + the epoch (in hex) in the Volume's checkpoint
+// container, so at most one Peer can claim any given epoch. The FencingTokenObjectName object
+// itself merely hints at the latest claim. Should the owning Peer die, the first live Peer in
+// [Volume:<VolumeName>]PrimaryPeer followed by [Volume:<VolumeName>]StandbyPeerList claims the
+// next epoch, waits long enough for a merely unreachable former owner to have noticed, and - if
+// the token remains its own - invokes the callback registered via SetFailoverCallbackFunc(). The
+// owning Peer periodically re-reads its fencing tokens and, upon finding one claimed by another
+// Peer, immediately ceases checkpointing the Volume (see VolumeIsFenced()) and similarly invokes
+// the callback to relinquish it.
+//
+// The callback is expected to trigger the same PauseAndContract()/ExpandAndResume() sequence as
+// that used to apply an updated .conf file, having first passed the confMap to be applied through
+// ApplyVolumeOwnership() so that each [Volume:<VolumeName>]PrimaryPeer reflects the fencing tokens.
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// FencingTokenObjectName is the name of the object, in each Volume's checkpoint container,
+// recording the Peer currently owning the Volume (and the prefix of its per-epoch claim objects).
+const FencingTokenObjectName = "FencingToken"
+
+// FailoverCallbackFunc specifies the signature of a callback function to be invoked whenever
+// this Peer has taken over or relinquished a Volume.
+type FailoverCallbackFunc func()
+
+// SetFailoverCallbackFunc sets (or resets, if passed nil) the callback function to be invoked
+// whenever this Peer has taken over or relinquished a Volume.
+func SetFailoverCallbackFunc(failoverCallback FailoverCallbackFunc) {
+	globals.Lock()
+	globals.failoverCallback = failoverCallback
+	globals.Unlock()
+}
+
+// ApplyVolumeOwnership updates [Volume:<VolumeName>]PrimaryPeer of each Volume in confMap
+// to name the Peer currently holding the Volume's fencing token. A Volume without a fencing
+// token is claimed by this Peer if it is the Volume's configured PrimaryPeer.
+//
+// A fencing token that cannot be fetched (or claimed) is not reported as an error. Rather, the
+// Volume retains the owner last applied (or, if none, is left unserved) while the cluster package
+// retries with backoff... invoking the failover callback should the owner turn out to differ.
+// Hence, an error is only returned for an invalid confMap.
+func ApplyVolumeOwnership(confMap conf.ConfMap) (err error) {
+	member := fetchMember()
+	if nil == member {
+		err = fmt.Errorf("%s: cluster package not up", utils.GetFnName())
+		err = blunder.AddError(err, blunder.NotActiveError)
+		return
+	}
+
+	err = member.applyVolumeOwnership(confMap)
+
+	return
+}
+
+// FetchVolumeOwner returns the name of the Peer this Peer believes to currently own the Volume.
+func FetchVolumeOwner(volumeName string) (peerName string, err error) {
+	member := fetchMember()
+	if nil == member {
+		err = fmt.Errorf("%s: cluster package not up", utils.GetFnName())
+		err = blunder.AddError(err, blunder.NotActiveError)
+		return
+	}
+
+	peerName, err = member.fetchVolumeOwner(volumeName)
+
+	return
+}
+
+// VolumeIsFenced returns true if the Volume's fencing token is known to be held by another
+// Peer. In that case, this Peer must not update the Volume's checkpoint. If the cluster
+// package is not up (e.g. in tools operating on a single Volume), false is returned.
+func VolumeIsFenced(volumeName string) (fenced bool) {
+	member := fetchMember()
+	if nil == member {
+		fenced = false
+		return
+	}
+
+	fenced = member.volumeIsFenced(volumeName)
+
+	return
+}
@@ -0,0 +1,592 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+// Each Peer listens on its own loopback address so that several may share PrivateClusterUDPPort in one process
+var testConfStrings = []string{
+	"Stats.IPAddr=localhost",
+	"Stats.UDPPort=52184",
+	"Stats.BufferLength=100",
+	"Stats.MaxLatency=1s",
+	"Logging.LogFilePath=",
+	"SwiftClient.NoAuthTCPPort=45263",
+	"SwiftClient.Timeout=10s",
+	"SwiftClient.RetryLimit=1",
+	"SwiftClient.RetryLimitObject=1",
+	"SwiftClient.RetryDelay=10ms",
+	"SwiftClient.RetryDelayObject=10ms",
+	"SwiftClient.RetryExpBackoff=1.2",
+	"SwiftClient.RetryExpBackoffObject=1.0",
+	"SwiftClient.StarvationCallbackFrequency=100ms",
+	"SwiftClient.ChunkedConnectionPoolSize=64",
+	"SwiftClient.NonChunkedConnectionPoolSize=32",
+	"Peer:Peer0.PrivateIPAddr=127.0.0.1",
+	"Peer:Peer1.PrivateIPAddr=127.0.0.2",
+	"Peer:Peer2.PrivateIPAddr=127.0.0.3",
+	"Cluster.WhoAmI=Peer0", // Overridden by testStartPeer()
+	"Cluster.Peers=Peer0 Peer1 Peer2",
+	"Cluster.ServerGuid=9f0d1d1e-35c4-4b53-9d2c-6ad5f4f5b2a1",
+	"Cluster.PrivateClusterUDPPort=5003",
+	"Cluster.HeartBeatInterval=50ms",
+	"Cluster.HeartBeatVariance=5ms",
+	"Cluster.HeartBeatExpiration=200ms",
+	"Cluster.MessageExpiration=500ms",
+	"Cluster.RequestExpiration=1s",
+	"Cluster.FenceCheckInterval=100ms",
+	"Cluster.UDPReadSize=8000",
+	"Cluster.UDPWriteSize=7000",
+	"Volume:TestVolume.PrimaryPeer=Peer0",
+	"Volume:TestVolume.StandbyPeerList=Peer1 Peer2",
+	"Volume:TestVolume.AccountName=AUTH_test",
+	"Volume:TestVolume.CheckpointContainerName=.__checkpoint__",
+	"FSGlobals.VolumeList=TestVolume",
+	"RamSwiftInfo.MaxAccountNameLength=256",
+	"RamSwiftInfo.MaxContainerNameLength=256",
+	"RamSwiftInfo.MaxObjectNameLength=1024",
+}
+
+var testObjectStore objectstore.ObjectStore // TestVolume's (i.e. that of ramswift)
+
+func TestMain(m *testing.M) {
+	err := testSetup()
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "cluster test setup failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	testVerdict := m.Run()
+
+	swiftclient.Down()
+	stats.Down()
+	logger.Down()
+
+	os.Exit(testVerdict)
+}
+
+func testSetup() (err error) {
+	testConfMap, err := conf.MakeConfMapFromStrings(testConfStrings)
+	if nil != err {
+		return
+	}
+
+	signalHandlerIsArmed := false
+	doneChan := make(chan bool, 1)
+	go ramswift.Daemon("/dev/null", testConfStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
+
+	for !signalHandlerIsArmed {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = logger.Up(testConfMap)
+	if nil != err {
+		return
+	}
+
+	err = stats.Up(testConfMap)
+	if nil != err {
+		return
+	}
+
+	err = swiftclient.Up(testConfMap)
+	if nil != err {
+		return
+	}
+
+	err = swiftclient.AccountPut("AUTH_test", map[string][]string{})
+	if nil != err {
+		return
+	}
+
+	err = swiftclient.ContainerPut("AUTH_test", ".__checkpoint__", map[string][]string{})
+	if nil != err {
+		return
+	}
+
+	testObjectStore, err = objectstore.FetchObjectStore(testConfMap, "TestVolume")
+
+	return
+}
+
+type testPeerStruct struct {
+	confMap      conf.ConfMap
+	member       *memberStruct
+	failoverChan chan struct{}
+}
+
+func testStartPeer(t *testing.T, peerName string) (testPeer *testPeerStruct) {
+	var err error
+
+	testPeer = &testPeerStruct{failoverChan: make(chan struct{}, 10)}
+
+	testPeer.confMap, err = conf.MakeConfMapFromStrings(testConfStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+	err = testPeer.confMap.UpdateFromString("Cluster.WhoAmI=" + peerName)
+	if nil != err {
+		t.Fatalf("UpdateFromString() failed: %v", err)
+	}
+
+	testPeer.member, err = newMember(testPeer.confMap, func() { testPeer.failoverChan <- struct{}{} })
+	if nil != err {
+		t.Fatalf("newMember() for %v failed: %v", peerName, err)
+	}
+
+	return
+}
+
+func (testPeer *testPeerStruct) expectPrimaryPeer(t *testing.T, expectedPeerName string) {
+	primaryPeerList, err := testPeer.confMap.FetchOptionValueStringSlice("Volume:TestVolume", "PrimaryPeer")
+	if nil != err {
+		t.Fatalf("FetchOptionValueStringSlice() failed: %v", err)
+	}
+	if (1 != len(primaryPeerList)) || (expectedPeerName != primaryPeerList[0]) {
+		t.Fatalf("%v's [Volume:TestVolume]PrimaryPeer should have been %v but was %v", testPeer.member.whoAmI, expectedPeerName, primaryPeerList)
+	}
+}
+
+func (testPeer *testPeerStruct) expectFailover(t *testing.T) {
+	select {
+	case <-testPeer.failoverChan:
+		// Expected
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%v's failover callback was not invoked", testPeer.member.whoAmI)
+	}
+}
+
+func (testPeer *testPeerStruct) expectNoFailover(t *testing.T) {
+	select {
+	case <-testPeer.failoverChan:
+		t.Fatalf("%v's failover callback unexpectedly invoked", testPeer.member.whoAmI)
+	default:
+		// Expected
+	}
+}
+
+func testExpectFencingToken(t *testing.T, expectedFencingToken fencingTokenStruct) {
+	fencingToken, found, err := fetchFencingToken(testObjectStore, "AUTH_test", ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("fetchFencingToken() failed: %v", err)
+	}
+	if !found || (expectedFencingToken != fencingToken) {
+		t.Fatalf("fetchFencingToken() returned %+v (found == %v)... expected %+v", fencingToken, found, expectedFencingToken)
+	}
+}
+
+// testDeleteFencingTokens removes TestVolume's fencing token (and all of its per-epoch claims) from objectStore
+func testDeleteFencingTokens(t *testing.T, objectStore objectstore.ObjectStore) {
+	_, objectList, err := objectStore.ContainerGet("AUTH_test", ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("ContainerGet() failed: %v", err)
+	}
+	for _, objectName := range objectList {
+		if strings.HasPrefix(objectName, FencingTokenObjectName) {
+			err = objectStore.ObjectDeleteSync("AUTH_test", ".__checkpoint__", objectName)
+			if nil != err {
+				t.Fatalf("ObjectDeleteSync(,,\"%v\") failed: %v", objectName, err)
+			}
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	testDeleteFencingTokens(t, testObjectStore)
+
+	// Bring up all three Peers... Peer0 claims the fencing token as PrimaryPeer
+
+	testPeer0 := testStartPeer(t, "Peer0")
+	testPeer1 := testStartPeer(t, "Peer1")
+	testPeer2 := testStartPeer(t, "Peer2")
+
+	testExpectFencingToken(t, fencingTokenStruct{PeerName: "Peer0", Epoch: 1})
+
+	testPeer0.expectPrimaryPeer(t, "Peer0")
+	testPeer1.expectPrimaryPeer(t, "Peer0")
+	testPeer2.expectPrimaryPeer(t, "Peer0")
+
+	if testPeer0.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should not be fenced from Peer0")
+	}
+	if !testPeer1.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should be fenced from Peer1")
+	}
+
+	// While heartbeats flow, nobody takes over
+
+	time.Sleep(3 * testPeer1.member.heartBeatExpiration)
+
+	testPeer0.expectNoFailover(t)
+	testPeer1.expectNoFailover(t)
+	testPeer2.expectNoFailover(t)
+
+	// Once Peer0 dies, Peer1 (the first StandbyPeer) takes over
+
+	testPeer0.member.stop()
+
+	testPeer1.expectFailover(t)
+
+	testExpectFencingToken(t, fencingTokenStruct{PeerName: "Peer1", Epoch: 2})
+
+	peerName, err := testPeer1.member.fetchVolumeOwner("TestVolume")
+	if nil != err {
+		t.Fatalf("fetchVolumeOwner() failed: %v", err)
+	}
+	if "Peer1" != peerName {
+		t.Fatalf("Peer1 should own TestVolume but fetchVolumeOwner() returned %v", peerName)
+	}
+	if testPeer1.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should not be fenced from Peer1 after take over")
+	}
+
+	err = testPeer1.member.applyVolumeOwnership(testPeer1.confMap)
+	if nil != err {
+		t.Fatalf("applyVolumeOwnership() failed: %v", err)
+	}
+	testPeer1.expectPrimaryPeer(t, "Peer1")
+
+	testPeer2.expectNoFailover(t)
+
+	// A restarted Peer0 honors the fencing token rather than its configured PrimaryPeer
+
+	testPeer0 = testStartPeer(t, "Peer0")
+
+	testPeer0.expectPrimaryPeer(t, "Peer1")
+
+	if !testPeer0.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should be fenced from restarted Peer0")
+	}
+
+	// Should another Peer claim the fencing token out from under Peer1, Peer1 relinquishes TestVolume
+
+	claimed, err := claimFencingToken(testObjectStore, "AUTH_test", ".__checkpoint__", fencingTokenStruct{PeerName: "Peer2", Epoch: 3})
+	if nil != err {
+		t.Fatalf("claimFencingToken() failed: %v", err)
+	}
+	if !claimed {
+		t.Fatalf("claimFencingToken() of epoch 3 should have succeeded")
+	}
+
+	// ...while a claim of an already claimed epoch is refused
+
+	claimed, err = claimFencingToken(testObjectStore, "AUTH_test", ".__checkpoint__", fencingTokenStruct{PeerName: "Peer0", Epoch: 3})
+	if nil != err {
+		t.Fatalf("claimFencingToken() failed: %v", err)
+	}
+	if claimed {
+		t.Fatalf("claimFencingToken() of an already claimed epoch should have been refused")
+	}
+
+	testPeer1.expectFailover(t)
+
+	if !testPeer1.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should be fenced from Peer1 once Peer2 claimed its fencing token")
+	}
+
+	testPeer0.member.stop()
+	testPeer1.member.stop()
+	testPeer2.member.stop()
+}
+
+func TestTakeOverRace(t *testing.T) {
+	testDeleteFencingTokens(t, testObjectStore)
+
+	testPeer0 := testStartPeer(t, "Peer0")
+	testPeer1 := testStartPeer(t, "Peer1")
+	testPeer2 := testStartPeer(t, "Peer2")
+
+	testExpectFencingToken(t, fencingTokenStruct{PeerName: "Peer0", Epoch: 1})
+
+	// Both standbys (e.g. having differing views of which Peers are alive) race to take over from Peer0
+
+	startChan := make(chan struct{})
+
+	for _, testPeer := range []*testPeerStruct{testPeer1, testPeer2} {
+		member := testPeer.member
+		member.Lock()
+		volume := member.volumeMap["TestVolume"]
+		volume.takeOverInProgress = true
+		member.wg.Add(1)
+		member.Unlock()
+		go func() {
+			<-startChan
+			member.takeOver(volume, "Peer0")
+		}()
+	}
+
+	close(startChan)
+
+	// Exactly one of them wins... the other abandons its take over
+
+	var winner, loser *testPeerStruct
+
+	select {
+	case <-testPeer1.failoverChan:
+		winner, loser = testPeer1, testPeer2
+	case <-testPeer2.failoverChan:
+		winner, loser = testPeer2, testPeer1
+	case <-time.After(5 * time.Second):
+		t.Fatalf("neither Peer1 nor Peer2 took over TestVolume")
+	}
+
+	testExpectFencingToken(t, fencingTokenStruct{PeerName: winner.member.whoAmI, Epoch: 2})
+
+	for {
+		loser.member.Lock()
+		takeOverInProgress := loser.member.volumeMap["TestVolume"].takeOverInProgress
+		loser.member.Unlock()
+		if !takeOverInProgress {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	loser.expectNoFailover(t)
+
+	if winner.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should not be fenced from %v", winner.member.whoAmI)
+	}
+	if !loser.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should be fenced from %v", loser.member.whoAmI)
+	}
+
+	peerName, err := loser.member.fetchVolumeOwner("TestVolume")
+	if nil != err {
+		t.Fatalf("fetchVolumeOwner() failed: %v", err)
+	}
+	if winner.member.whoAmI != peerName {
+		t.Fatalf("%v should know %v owns TestVolume but fetchVolumeOwner() returned %v", loser.member.whoAmI, winner.member.whoAmI, peerName)
+	}
+
+	// Peer0 relinquishes TestVolume
+
+	testPeer0.expectFailover(t)
+
+	if !testPeer0.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should be fenced from Peer0 once taken over")
+	}
+
+	testPeer0.member.stop()
+	testPeer1.member.stop()
+	testPeer2.member.stop()
+}
+
+func TestFencingTokenInLocalObjectStore(t *testing.T) {
+	testDeleteFencingTokens(t, testObjectStore)
+
+	localPath, err := ioutil.TempDir("", "clusterTest_")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(localPath)
+
+	confMap, err := conf.MakeConfMapFromStrings(testConfStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+	err = confMap.UpdateFromStrings([]string{
+		"Volume:TestVolume.ObjectStore=TestLocal",
+		"ObjectStore:TestLocal.Type=Local",
+		"ObjectStore:TestLocal.Path=" + localPath,
+	})
+	if nil != err {
+		t.Fatalf("UpdateFromStrings() failed: %v", err)
+	}
+
+	localObjectStore, err := objectstore.FetchObjectStore(confMap, "TestVolume")
+	if nil != err {
+		t.Fatalf("objectstore.FetchObjectStore() failed: %v", err)
+	}
+	err = localObjectStore.ContainerPut("AUTH_test", ".__checkpoint__", map[string][]string{})
+	if nil != err {
+		t.Fatalf("ContainerPut() failed: %v", err)
+	}
+
+	// The fencing token is claimed in the Volume's own ObjectStore... not in Swift
+
+	member, err := newMember(confMap, nil)
+	if nil != err {
+		t.Fatalf("newMember() failed: %v", err)
+	}
+
+	fencingToken, found, err := fetchFencingToken(localObjectStore, "AUTH_test", ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("fetchFencingToken() failed: %v", err)
+	}
+	if !found || (fencingTokenStruct{PeerName: "Peer0", Epoch: 1} != fencingToken) {
+		t.Fatalf("fetchFencingToken() returned %+v (found == %v)... expected it to have been claimed by Peer0", fencingToken, found)
+	}
+
+	_, found, err = fetchFencingToken(testObjectStore, "AUTH_test", ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("fetchFencingToken() failed: %v", err)
+	}
+	if found {
+		t.Fatalf("fetchFencingToken() should not have found a fencing token in Swift")
+	}
+
+	// Another Peer claiming the fencing token there is noticed
+
+	err = putFencingToken(localObjectStore, "AUTH_test", ".__checkpoint__", fencingTokenStruct{PeerName: "Peer1", Epoch: 2})
+	if nil != err {
+		t.Fatalf("putFencingToken() failed: %v", err)
+	}
+
+	for !member.volumeIsFenced("TestVolume") {
+		time.Sleep(member.fenceCheckInterval)
+	}
+
+	member.stop()
+}
+
+// testBreakFencingToken replaces the fencing token object in the Local ObjectStore at localPath with a
+// directory... causing it to exist but be unreadable (as if the ObjectStore were failing) until repaired
+func testBreakFencingToken(t *testing.T, localObjectStore objectstore.ObjectStore, localPath string) (fencingTokenPath string) {
+	err := putFencingToken(localObjectStore, "AUTH_test", ".__checkpoint__", fencingTokenStruct{PeerName: "Peer0", Epoch: 1})
+	if nil != err {
+		t.Fatalf("putFencingToken() failed: %v", err)
+	}
+
+	fencingTokenPathList, err := filepath.Glob(filepath.Join(localPath, "AUTH_test", "*", ".__checkpoint__", "*", FencingTokenObjectName))
+	if (nil != err) || (1 != len(fencingTokenPathList)) {
+		t.Fatalf("filepath.Glob() returned %v (err == %v)... expected one path", fencingTokenPathList, err)
+	}
+	fencingTokenPath = fencingTokenPathList[0]
+
+	err = os.Remove(fencingTokenPath)
+	if nil != err {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+	err = os.Mkdir(fencingTokenPath, 0700)
+	if nil != err {
+		t.Fatalf("os.Mkdir() failed: %v", err)
+	}
+
+	return
+}
+
+func TestFencingTokenResolutionRetried(t *testing.T) {
+	localPath, err := ioutil.TempDir("", "clusterTest_")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(localPath)
+
+	testPeer := &testPeerStruct{failoverChan: make(chan struct{}, 10)}
+
+	testPeer.confMap, err = conf.MakeConfMapFromStrings(testConfStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+	err = testPeer.confMap.UpdateFromStrings([]string{
+		"Volume:TestVolume.ObjectStore=TestLocal",
+		"ObjectStore:TestLocal.Type=Local",
+		"ObjectStore:TestLocal.Path=" + localPath,
+	})
+	if nil != err {
+		t.Fatalf("UpdateFromStrings() failed: %v", err)
+	}
+
+	localObjectStore, err := objectstore.FetchObjectStore(testPeer.confMap, "TestVolume")
+	if nil != err {
+		t.Fatalf("objectstore.FetchObjectStore() failed: %v", err)
+	}
+	err = localObjectStore.ContainerPut("AUTH_test", ".__checkpoint__", map[string][]string{})
+	if nil != err {
+		t.Fatalf("ContainerPut() failed: %v", err)
+	}
+
+	// Coming up while the fencing token is unreadable succeeds... leaving TestVolume unserved
+
+	fencingTokenPath := testBreakFencingToken(t, localObjectStore, localPath)
+
+	testPeer.member, err = newMember(testPeer.confMap, func() { testPeer.failoverChan <- struct{}{} })
+	if nil != err {
+		t.Fatalf("newMember() should have tolerated an unreadable fencing token but failed: %v", err)
+	}
+
+	primaryPeerList, err := testPeer.confMap.FetchOptionValueStringSlice("Volume:TestVolume", "PrimaryPeer")
+	if nil != err {
+		t.Fatalf("FetchOptionValueStringSlice() failed: %v", err)
+	}
+	if (0 != len(primaryPeerList)) && ((1 != len(primaryPeerList)) || ("" != primaryPeerList[0])) {
+		t.Fatalf("[Volume:TestVolume]PrimaryPeer should have been empty but was %v", primaryPeerList)
+	}
+	if !testPeer.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should be fenced while its fencing token is unresolved")
+	}
+
+	// Once readable, the retry claims TestVolume (as PrimaryPeer)... and invokes the failover callback
+
+	err = os.Remove(fencingTokenPath)
+	if nil != err {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+
+	testPeer.expectFailover(t)
+
+	if testPeer.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should not be fenced once its fencing token was claimed")
+	}
+
+	err = testPeer.member.applyVolumeOwnership(testPeer.confMap)
+	if nil != err {
+		t.Fatalf("applyVolumeOwnership() failed: %v", err)
+	}
+	testPeer.expectPrimaryPeer(t, "Peer0")
+
+	// A reconfig while the fencing token is unreadable retains the last known owner
+
+	fencingTokenPath = testBreakFencingToken(t, localObjectStore, localPath)
+
+	err = testPeer.member.applyVolumeOwnership(testPeer.confMap)
+	if nil != err {
+		t.Fatalf("applyVolumeOwnership() should have tolerated an unreadable fencing token but failed: %v", err)
+	}
+	testPeer.expectPrimaryPeer(t, "Peer0")
+
+	if testPeer.member.volumeIsFenced("TestVolume") {
+		t.Fatalf("TestVolume should not be fenced while its fencing token is merely unreadable")
+	}
+
+	// Once readable again, resolving the unchanged owner does not invoke the failover callback
+
+	err = os.Remove(fencingTokenPath)
+	if nil != err {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+	err = putFencingToken(localObjectStore, "AUTH_test", ".__checkpoint__", fencingTokenStruct{PeerName: "Peer0", Epoch: 1})
+	if nil != err {
+		t.Fatalf("putFencingToken() failed: %v", err)
+	}
+
+	for {
+		testPeer.member.Lock()
+		ownershipRetryPending := testPeer.member.volumeMap["TestVolume"].ownershipRetryPending
+		testPeer.member.Unlock()
+		if !ownershipRetryPending {
+			break
+		}
+		time.Sleep(testPeer.member.fenceCheckInterval)
+	}
+
+	testPeer.expectNoFailover(t)
+
+	testPeer.member.stop()
+}
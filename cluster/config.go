@@ -0,0 +1,335 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+type peerStruct struct {
+	name      string
+	udpAddr   *net.UDPAddr
+	lastHeard time.Time // Initialized to when the peerStruct was created (or the Peer was last added)
+	expired   bool      // Only used to log (and count) transitions
+}
+
+type volumeStruct struct {
+	name                    string
+	objectStore             objectstore.ObjectStore // where the Volume's checkpoint container (and so its fencing token) resides
+	accountName             string
+	checkpointContainerName string
+	primaryPeer             string   // [Volume:<VolumeName>]PrimaryPeer as configured ("" if none)
+	candidatePeerList       []string // [Volume:<VolumeName>]PrimaryPeer followed by [Volume:<VolumeName>]StandbyPeerList
+	owner                   string   // Peer believed to currently hold the fencing token ("" if none)
+	epoch                   uint64   // Epoch of the fencing token held by owner
+	takeOverInProgress      bool
+	ownershipRetryPending   bool          // if true, the fencing token could not be resolved when ownership was last applied
+	ownershipRetryDelay     time.Duration // doubled (up to ownershipRetryDelayLimit) following each failed retry
+	ownershipRetryTime      time.Time     // when the fencing token is next to be resolved
+}
+
+type memberStruct struct {
+	sync.Mutex
+	whoAmI              string
+	serverGuid          string
+	udpAddr             *net.UDPAddr
+	heartBeatInterval   time.Duration
+	heartBeatVariance   time.Duration
+	heartBeatExpiration time.Duration
+	messageExpiration   time.Duration
+	fenceCheckInterval  time.Duration
+	udpReadSize         uint64
+	udpWriteSize        uint64
+	peerMap             map[string]*peerStruct   // Key == peerStruct.name (excludes whoAmI)
+	volumeMap           map[string]*volumeStruct // Key == volumeStruct.name
+	udpConn             *net.UDPConn
+	failoverCallback    FailoverCallbackFunc
+	stopChan            chan struct{}
+	wg                  sync.WaitGroup
+}
+
+type globalsStruct struct {
+	sync.Mutex
+	member           *memberStruct
+	failoverCallback FailoverCallbackFunc
+}
+
+var globals globalsStruct
+
+// Up brings up the cluster package, claiming (if necessary) the fencing token of each Volume
+// for which this Peer is the PrimaryPeer and updating each [Volume:<VolumeName>]PrimaryPeer
+// in confMap to reflect the fencing tokens (see ApplyVolumeOwnership()). As such, it must be
+// called before Up() of any package consulting [Volume:<VolumeName>]PrimaryPeer.
+func Up(confMap conf.ConfMap) (err error) {
+	member, err := newMember(confMap, invokeFailoverCallback)
+	if nil != err {
+		return
+	}
+
+	globals.Lock()
+	globals.member = member
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+// PauseAndContract does nothing... Volume ownership changes are applied via ApplyVolumeOwnership().
+func PauseAndContract(confMap conf.ConfMap) (err error) {
+	err = nil
+	return
+}
+
+// ExpandAndResume applies changes to the set of Peers and the heartbeat settings.
+func ExpandAndResume(confMap conf.ConfMap) (err error) {
+	member := fetchMember()
+	if nil == member {
+		err = fmt.Errorf("cluster.ExpandAndResume() called while not up")
+		return
+	}
+
+	member.Lock()
+	err = member.loadConfMap(confMap)
+	member.Unlock()
+
+	return
+}
+
+func Down() (err error) {
+	globals.Lock()
+	member := globals.member
+	globals.member = nil
+	globals.Unlock()
+
+	if nil != member {
+		member.stop()
+	}
+
+	err = nil
+	return
+}
+
+func fetchMember() (member *memberStruct) {
+	globals.Lock()
+	member = globals.member
+	globals.Unlock()
+	return
+}
+
+func invokeFailoverCallback() {
+	globals.Lock()
+	failoverCallback := globals.failoverCallback
+	globals.Unlock()
+
+	if nil != failoverCallback {
+		failoverCallback()
+	}
+}
+
+// newMember constructs and starts a memberStruct for [Cluster]WhoAmI. While Up() only ever
+// constructs one, tests construct several (with differing [Cluster]WhoAmI) in one process.
+func newMember(confMap conf.ConfMap, failoverCallback FailoverCallbackFunc) (member *memberStruct, err error) {
+	member = &memberStruct{
+		peerMap:          make(map[string]*peerStruct),
+		volumeMap:        make(map[string]*volumeStruct),
+		failoverCallback: failoverCallback,
+		stopChan:         make(chan struct{}),
+	}
+
+	err = member.loadConfMap(confMap)
+	if nil != err {
+		return
+	}
+
+	member.udpConn, err = net.ListenUDP("udp", member.udpAddr)
+	if nil != err {
+		err = fmt.Errorf("net.ListenUDP(\"udp\", %v) failed: %v", member.udpAddr, err)
+		return
+	}
+
+	err = member.applyVolumeOwnership(confMap)
+	if nil != err {
+		_ = member.udpConn.Close()
+		return
+	}
+
+	member.wg.Add(3)
+	go member.sendHeartBeats()
+	go member.receiveHeartBeats()
+	go member.watchVolumes()
+
+	logger.Infof("cluster member %v up (listening on %v)", member.whoAmI, member.udpAddr)
+
+	err = nil
+	return
+}
+
+func (member *memberStruct) stop() {
+	close(member.stopChan)
+	_ = member.udpConn.Close()
+	member.wg.Wait()
+
+	logger.Infof("cluster member %v down", member.whoAmI)
+}
+
+// loadConfMap (re)loads the [Cluster] settings and set of Peers. Once set, neither [Cluster]WhoAmI
+// nor the address upon which heartbeats are received may be changed. Called while locked.
+func (member *memberStruct) loadConfMap(confMap conf.ConfMap) (err error) {
+	var (
+		fenceCheckInterval    time.Duration
+		heartBeatExpiration   time.Duration
+		heartBeatInterval     time.Duration
+		heartBeatVariance     time.Duration
+		messageExpiration     time.Duration
+		ok                    bool
+		peer                  *peerStruct
+		peerMap               map[string]*peerStruct
+		peerName              string
+		peerNames             []string
+		privateClusterUDPPort uint16
+		privateIPAddr         string
+		serverGuid            string
+		udpAddr               *net.UDPAddr
+		udpReadSize           uint64
+		udpWriteSize          uint64
+		whoAmI                string
+	)
+
+	whoAmI, err = confMap.FetchOptionValueString("Cluster", "WhoAmI")
+	if nil != err {
+		return
+	}
+	if ("" != member.whoAmI) && (whoAmI != member.whoAmI) {
+		err = fmt.Errorf("[Cluster]WhoAmI cannot be changed (from %v to %v)", member.whoAmI, whoAmI)
+		return
+	}
+
+	serverGuid, err = confMap.FetchOptionValueString("Cluster", "ServerGuid")
+	if nil != err {
+		return
+	}
+
+	privateClusterUDPPort, err = confMap.FetchOptionValueUint16("Cluster", "PrivateClusterUDPPort")
+	if nil != err {
+		return
+	}
+
+	heartBeatInterval, err = confMap.FetchOptionValueDuration("Cluster", "HeartBeatInterval")
+	if nil != err {
+		return
+	}
+	if time.Duration(0) == heartBeatInterval {
+		err = fmt.Errorf("[Cluster]HeartBeatInterval must be non-zero")
+		return
+	}
+
+	heartBeatVariance, err = confMap.FetchOptionValueDuration("Cluster", "HeartBeatVariance")
+	if nil != err {
+		return
+	}
+	if heartBeatVariance >= heartBeatInterval {
+		err = fmt.Errorf("[Cluster]HeartBeatVariance must be less than [Cluster]HeartBeatInterval")
+		return
+	}
+
+	heartBeatExpiration, err = confMap.FetchOptionValueDuration("Cluster", "HeartBeatExpiration")
+	if nil != err {
+		return
+	}
+	if heartBeatExpiration <= (heartBeatInterval + heartBeatVariance) {
+		err = fmt.Errorf("[Cluster]HeartBeatExpiration must exceed [Cluster]HeartBeatInterval + [Cluster]HeartBeatVariance")
+		return
+	}
+
+	messageExpiration, err = confMap.FetchOptionValueDuration("Cluster", "MessageExpiration")
+	if nil != err {
+		return
+	}
+
+	fenceCheckInterval, err = confMap.FetchOptionValueDuration("Cluster", "FenceCheckInterval")
+	if nil != err {
+		fenceCheckInterval = heartBeatExpiration // TODO: eventually, just return
+	}
+	if time.Duration(0) == fenceCheckInterval {
+		err = fmt.Errorf("[Cluster]FenceCheckInterval must be non-zero")
+		return
+	}
+
+	udpReadSize, err = confMap.FetchOptionValueUint64("Cluster", "UDPReadSize")
+	if nil != err {
+		return
+	}
+
+	udpWriteSize, err = confMap.FetchOptionValueUint64("Cluster", "UDPWriteSize")
+	if nil != err {
+		return
+	}
+
+	peerNames, err = confMap.FetchOptionValueStringSlice("Cluster", "Peers")
+	if nil != err {
+		return
+	}
+
+	peerMap = make(map[string]*peerStruct)
+
+	for _, peerName = range peerNames {
+		privateIPAddr, err = confMap.FetchOptionValueString(utils.PeerNameConfSection(peerName), "PrivateIPAddr")
+		if nil != err {
+			return
+		}
+
+		udpAddr, err = net.ResolveUDPAddr("udp", net.JoinHostPort(privateIPAddr, strconv.FormatUint(uint64(privateClusterUDPPort), 10)))
+		if nil != err {
+			return
+		}
+
+		if whoAmI == peerName {
+			if (nil != member.udpAddr) && (udpAddr.String() != member.udpAddr.String()) {
+				err = fmt.Errorf("Peer %v's PrivateIPAddr and [Cluster]PrivateClusterUDPPort cannot be changed", whoAmI)
+				return
+			}
+			member.udpAddr = udpAddr
+			continue
+		}
+
+		peer, ok = member.peerMap[peerName]
+		if ok {
+			peer.udpAddr = udpAddr
+		} else {
+			peer = &peerStruct{
+				name:      peerName,
+				udpAddr:   udpAddr,
+				lastHeard: time.Now(),
+				expired:   false,
+			}
+		}
+
+		peerMap[peerName] = peer
+	}
+
+	if nil == member.udpAddr {
+		err = fmt.Errorf("[Cluster]WhoAmI (%v) not found in [Cluster]Peers", whoAmI)
+		return
+	}
+
+	member.whoAmI = whoAmI
+	member.serverGuid = serverGuid
+	member.heartBeatInterval = heartBeatInterval
+	member.heartBeatVariance = heartBeatVariance
+	member.heartBeatExpiration = heartBeatExpiration
+	member.messageExpiration = messageExpiration
+	member.fenceCheckInterval = fenceCheckInterval
+	member.udpReadSize = udpReadSize
+	member.udpWriteSize = udpWriteSize
+	member.peerMap = peerMap
+
+	err = nil
+	return
+}
@@ -0,0 +1,350 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// ownershipRetryDelayLimit bounds the backoff between attempts to resolve a Volume's fencing token
+const ownershipRetryDelayLimit = 30 * time.Second
+
+// watchVolumes notices expired Peers, takes over Volumes whose owner has expired (if this
+// Peer is the first live candidate), retries resolving fencing tokens that applyVolumeOwnership()
+// could not, and periodically verifies this Peer still holds the fencing token of each Volume it owns.
+func (member *memberStruct) watchVolumes() {
+	var (
+		fenceCheckInterval time.Duration
+		heartBeatInterval  time.Duration
+		nextFenceCheck     time.Time
+		now                time.Time
+		ownedVolumes       []*volumeStruct
+		peer               *peerStruct
+		retryVolumes       []*volumeStruct
+		volume             *volumeStruct
+	)
+
+	defer member.wg.Done()
+
+	nextFenceCheck = time.Now()
+
+	for {
+		member.Lock()
+
+		for _, peer = range member.peerMap {
+			if !peer.expired && !member.peerIsAliveWhileLocked(peer.name) {
+				peer.expired = true
+				stats.IncrementOperations(&stats.ClusterPeerExpiredOps)
+				logger.Warnf("Peer %v not heard from in %v", peer.name, member.heartBeatExpiration)
+			}
+		}
+
+		ownedVolumes = make([]*volumeStruct, 0)
+		retryVolumes = make([]*volumeStruct, 0)
+
+		now = time.Now()
+
+		for _, volume = range member.volumeMap {
+			if volume.takeOverInProgress {
+				continue
+			}
+			if volume.ownershipRetryPending && !now.Before(volume.ownershipRetryTime) {
+				retryVolumes = append(retryVolumes, volume)
+			}
+			if member.whoAmI == volume.owner {
+				ownedVolumes = append(ownedVolumes, volume)
+				continue
+			}
+			if volume.ownershipRetryPending {
+				continue // Until its fencing token is resolved, its owner may merely be unknown
+			}
+			if member.peerIsAliveWhileLocked(volume.owner) {
+				continue
+			}
+			if member.whoAmI == member.takeOverCandidateWhileLocked(volume) {
+				volume.takeOverInProgress = true
+				member.wg.Add(1)
+				go member.takeOver(volume, volume.owner)
+			}
+		}
+
+		fenceCheckInterval = member.fenceCheckInterval
+		heartBeatInterval = member.heartBeatInterval
+
+		member.Unlock()
+
+		if 0 < len(retryVolumes) {
+			member.retryVolumeOwnership(retryVolumes)
+		}
+
+		if !time.Now().Before(nextFenceCheck) {
+			member.checkFencingTokens(ownedVolumes)
+			nextFenceCheck = time.Now().Add(fenceCheckInterval)
+		}
+
+		select {
+		case <-member.stopChan:
+			return
+		case <-time.After(heartBeatInterval):
+			// Time to look again
+		}
+	}
+}
+
+// takeOverCandidateWhileLocked returns the first live Peer (other than the current owner)
+// in the Volume's candidatePeerList... or "" if there is none.
+func (member *memberStruct) takeOverCandidateWhileLocked(volume *volumeStruct) (peerName string) {
+	for _, peerName = range volume.candidatePeerList {
+		if (volume.owner != peerName) && member.peerIsAliveWhileLocked(peerName) {
+			return
+		}
+	}
+
+	peerName = ""
+	return
+}
+
+// takeOver claims the Volume's fencing token from deadOwner. As the claim is a conditional
+// create of the next epoch's claim object, only one of any Peers racing to take over the Volume
+// succeeds... the others abandon their take over. Before invoking the failover callback, it
+// waits long enough for a former owner - that may merely have been unreachable - to have re-read
+// its fencing token and stopped updating the Volume's checkpoint. Should another Peer have
+// claimed the fencing token in the meantime, the take over is abandoned.
+func (member *memberStruct) takeOver(volume *volumeStruct, deadOwner string) {
+	var (
+		accountName             string
+		checkpointContainerName string
+		claimed                 bool
+		claimedFencingToken     fencingTokenStruct
+		err                     error
+		fencingToken            fencingTokenStruct
+		found                   bool
+		objectStore             objectstore.ObjectStore
+		settleDuration          time.Duration
+	)
+
+	defer member.wg.Done()
+
+	member.Lock()
+	objectStore = volume.objectStore
+	accountName = volume.accountName
+	checkpointContainerName = volume.checkpointContainerName
+	settleDuration = member.heartBeatExpiration + member.fenceCheckInterval
+	member.Unlock()
+
+	fencingToken, found, err = fetchFencingToken(objectStore, accountName, checkpointContainerName)
+	if nil != err {
+		logger.ErrorfWithError(err, "unable to fetch fencing token of Volume %v", volume.name)
+		member.abandonTakeOver(volume, nil)
+		return
+	}
+
+	if found && (deadOwner != fencingToken.PeerName) && (member.whoAmI != fencingToken.PeerName) {
+		// Another Peer has already taken over the Volume
+
+		member.abandonTakeOver(volume, &fencingToken)
+		return
+	}
+
+	claimedFencingToken = fencingTokenStruct{PeerName: member.whoAmI, Epoch: fencingToken.Epoch + 1}
+
+	claimed, err = claimFencingToken(objectStore, accountName, checkpointContainerName, claimedFencingToken)
+	if nil != err {
+		logger.ErrorfWithError(err, "unable to claim fencing token of Volume %v", volume.name)
+		member.abandonTakeOver(volume, nil)
+		return
+	}
+	if !claimed {
+		// Another Peer claimed the same epoch first
+
+		fencingToken, found, err = fetchFencingToken(objectStore, accountName, checkpointContainerName)
+		if (nil != err) || !found {
+			member.abandonTakeOver(volume, nil)
+			return
+		}
+
+		logger.Infof("Fencing token of Volume %v (epoch %v) claimed by Peer %v before us", volume.name, fencingToken.Epoch, fencingToken.PeerName)
+		member.abandonTakeOver(volume, &fencingToken)
+		return
+	}
+
+	logger.Infof("Claimed fencing token of Volume %v (epoch %v) from expired Peer %v", volume.name, claimedFencingToken.Epoch, deadOwner)
+
+	select {
+	case <-member.stopChan:
+		member.abandonTakeOver(volume, nil)
+		return
+	case <-time.After(settleDuration):
+		// Any former owner should have noticed by now
+	}
+
+	fencingToken, found, err = fetchFencingToken(objectStore, accountName, checkpointContainerName)
+	if nil != err {
+		logger.ErrorfWithError(err, "unable to re-fetch fencing token of Volume %v", volume.name)
+		member.abandonTakeOver(volume, nil)
+		return
+	}
+	if !found || (claimedFencingToken != fencingToken) {
+		logger.Warnf("Fencing token of Volume %v claimed by Peer %v (epoch %v) during take over", volume.name, fencingToken.PeerName, fencingToken.Epoch)
+		member.abandonTakeOver(volume, &fencingToken)
+		return
+	}
+
+	member.Lock()
+	volume.owner = member.whoAmI
+	volume.epoch = claimedFencingToken.Epoch
+	volume.takeOverInProgress = false
+	member.Unlock()
+
+	stats.IncrementOperations(&stats.ClusterTakeOverOps)
+	logger.Infof("Taking over Volume %v from expired Peer %v", volume.name, deadOwner)
+
+	if nil != member.failoverCallback {
+		member.failoverCallback()
+	}
+}
+
+func (member *memberStruct) abandonTakeOver(volume *volumeStruct, fencingToken *fencingTokenStruct) {
+	member.Lock()
+	if nil != fencingToken {
+		volume.owner = fencingToken.PeerName
+		volume.epoch = fencingToken.Epoch
+	}
+	volume.takeOverInProgress = false
+	member.Unlock()
+}
+
+// checkFencingTokens relinquishes each of the supplied Volumes whose fencing token has been
+// claimed by another Peer. Until the failover callback has reconfigured this Peer accordingly,
+// VolumeIsFenced() will prevent the Volume's checkpoint from being updated.
+func (member *memberStruct) checkFencingTokens(ownedVolumes []*volumeStruct) {
+	var (
+		accountName             string
+		checkpointContainerName string
+		epoch                   uint64
+		err                     error
+		fencingToken            fencingTokenStruct
+		found                   bool
+		objectStore             objectstore.ObjectStore
+		relinquished            bool
+		volume                  *volumeStruct
+	)
+
+	relinquished = false
+
+	for _, volume = range ownedVolumes {
+		member.Lock()
+		objectStore = volume.objectStore
+		accountName = volume.accountName
+		checkpointContainerName = volume.checkpointContainerName
+		epoch = volume.epoch
+		member.Unlock()
+
+		fencingToken, found, err = fetchFencingToken(objectStore, accountName, checkpointContainerName)
+		if nil != err {
+			logger.WarnfWithError(err, "unable to verify fencing token of Volume %v", volume.name)
+			continue
+		}
+
+		if !found {
+			// Someone removed it... so just put it back
+
+			fencingToken = fencingTokenStruct{PeerName: member.whoAmI, Epoch: epoch}
+			err = putFencingToken(objectStore, accountName, checkpointContainerName, fencingToken)
+			if nil != err {
+				logger.WarnfWithError(err, "unable to restore fencing token of Volume %v", volume.name)
+			}
+			continue
+		}
+
+		if member.whoAmI == fencingToken.PeerName {
+			continue
+		}
+
+		member.Lock()
+		if member.whoAmI == volume.owner {
+			volume.owner = fencingToken.PeerName
+			volume.epoch = fencingToken.Epoch
+			relinquished = true
+		}
+		member.Unlock()
+
+		stats.IncrementOperations(&stats.ClusterStepDownOps)
+		logger.Warnf("Fencing token of Volume %v claimed by Peer %v (epoch %v)... relinquishing it", volume.name, fencingToken.PeerName, fencingToken.Epoch)
+	}
+
+	if relinquished && (nil != member.failoverCallback) {
+		member.failoverCallback()
+	}
+}
+
+// retryVolumeOwnership re-attempts resolving the fencing token of each of the supplied Volumes
+// that applyVolumeOwnership() could not, backing off after each failure. Should any Volume's
+// owner turn out to differ from that last applied, the failover callback is invoked to apply it.
+func (member *memberStruct) retryVolumeOwnership(retryVolumes []*volumeStruct) {
+	var (
+		accountName             string
+		changed                 bool
+		checkpointContainerName string
+		err                     error
+		fencingToken            fencingTokenStruct
+		objectStore             objectstore.ObjectStore
+		primaryPeer             string
+		retryDelay              time.Duration
+		volume                  *volumeStruct
+	)
+
+	changed = false
+
+	for _, volume = range retryVolumes {
+		member.Lock()
+		objectStore = volume.objectStore
+		accountName = volume.accountName
+		checkpointContainerName = volume.checkpointContainerName
+		primaryPeer = volume.primaryPeer
+		member.Unlock()
+
+		fencingToken, err = member.resolveFencingToken(objectStore, accountName, checkpointContainerName, volume.name, primaryPeer)
+
+		member.Lock()
+
+		if !volume.ownershipRetryPending {
+			// Resolved by applyVolumeOwnership() in the meantime
+
+			member.Unlock()
+			continue
+		}
+
+		if nil != err {
+			volume.ownershipRetryDelay *= 2
+			if volume.ownershipRetryDelay > ownershipRetryDelayLimit {
+				volume.ownershipRetryDelay = ownershipRetryDelayLimit
+			}
+			volume.ownershipRetryTime = time.Now().Add(volume.ownershipRetryDelay)
+			retryDelay = volume.ownershipRetryDelay
+
+			member.Unlock()
+
+			logger.WarnfWithError(err, "unable to resolve fencing token of Volume %v... will retry in %v", volume.name, retryDelay)
+			continue
+		}
+
+		volume.ownershipRetryPending = false
+
+		if !volume.takeOverInProgress && ((fencingToken.PeerName != volume.owner) || (fencingToken.Epoch != volume.epoch)) {
+			changed = changed || (fencingToken.PeerName != volume.owner)
+			volume.owner = fencingToken.PeerName
+			volume.epoch = fencingToken.Epoch
+		}
+
+		member.Unlock()
+
+		logger.Infof("Resolved fencing token of Volume %v (Peer %v epoch %v)", volume.name, fencingToken.PeerName, fencingToken.Epoch)
+	}
+
+	if changed && (nil != member.failoverCallback) {
+		member.failoverCallback()
+	}
+}
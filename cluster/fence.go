@@ -0,0 +1,363 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+type fencingTokenStruct struct {
+	PeerName string
+	Epoch    uint64 // Incremented by each Peer claiming the fencing token
+}
+
+// fencingTokenClaimObjectName returns the name of the object recording the claim of epoch.
+func fencingTokenClaimObjectName(epoch uint64) (objectName string) {
+	objectName = FencingTokenObjectName + "." + utils.Uint64ToHexStr(epoch)
+	return
+}
+
+func readFencingTokenObject(objectStore objectstore.ObjectStore, accountName string, checkpointContainerName string, objectName string) (fencingToken fencingTokenStruct, found bool, err error) {
+	var (
+		fencingTokenBuf    []byte
+		fencingTokenLength uint64
+	)
+
+	fencingTokenLength, err = objectStore.ObjectContentLength(accountName, checkpointContainerName, objectName)
+	if nil == err {
+		fencingTokenBuf, err = objectStore.ObjectGet(accountName, checkpointContainerName, objectName, 0, fencingTokenLength)
+	}
+	if nil != err {
+		if 404 == blunder.HTTPCode(err) {
+			found = false
+			err = nil
+		}
+		return
+	}
+
+	err = json.Unmarshal(fencingTokenBuf, &fencingToken)
+	if nil != err {
+		err = fmt.Errorf("malformed %v/%v/%v: %v", accountName, checkpointContainerName, objectName, err)
+		return
+	}
+
+	found = true
+	err = nil
+	return
+}
+
+// fetchFencingToken returns the latest claim of the fencing token. As the FencingTokenObjectName
+// object is only a hint (it may lag, or even be missing), claims of subsequent epochs are probed
+// for until one is not found.
+func fetchFencingToken(objectStore objectstore.ObjectStore, accountName string, checkpointContainerName string) (fencingToken fencingTokenStruct, found bool, err error) {
+	var (
+		claimedFencingToken fencingTokenStruct
+		claimFound          bool
+	)
+
+	fencingToken, found, err = readFencingTokenObject(objectStore, accountName, checkpointContainerName, FencingTokenObjectName)
+	if nil != err {
+		return
+	}
+	if !found {
+		fencingToken = fencingTokenStruct{PeerName: "", Epoch: 0}
+	}
+
+	for {
+		claimedFencingToken, claimFound, err = readFencingTokenObject(objectStore, accountName, checkpointContainerName, fencingTokenClaimObjectName(fencingToken.Epoch+1))
+		if nil != err {
+			return
+		}
+		if !claimFound {
+			break
+		}
+		fencingToken = claimedFencingToken
+		found = true
+	}
+
+	err = nil
+	return
+}
+
+// putFencingToken (re)writes the FencingTokenObjectName hint.
+func putFencingToken(objectStore objectstore.ObjectStore, accountName string, checkpointContainerName string, fencingToken fencingTokenStruct) (err error) {
+	fencingTokenBuf, err := json.Marshal(&fencingToken)
+	if nil != err {
+		return
+	}
+
+	chunkedPutContext, err := objectStore.ObjectFetchChunkedPutContext(accountName, checkpointContainerName, FencingTokenObjectName)
+	if nil != err {
+		return
+	}
+
+	err = chunkedPutContext.SendChunk(fencingTokenBuf)
+	if nil != err {
+		return
+	}
+
+	err = chunkedPutContext.Close()
+
+	return
+}
+
+// claimFencingToken attempts to claim fencingToken.Epoch by conditionally creating its claim
+// object... so that, of any number of Peers racing to claim the same epoch, exactly one succeeds.
+// Should the claim object already exist, claimed is only returned true if it records this very
+// fencingToken (i.e. an earlier attempt of the caller's succeeded despite reporting an error).
+func claimFencingToken(objectStore objectstore.ObjectStore, accountName string, checkpointContainerName string, fencingToken fencingTokenStruct) (claimed bool, err error) {
+	var (
+		claimObjectName      string
+		existingFound        bool
+		existingFencingToken fencingTokenStruct
+		fencingTokenBuf      []byte
+	)
+
+	fencingTokenBuf, err = json.Marshal(&fencingToken)
+	if nil != err {
+		return
+	}
+
+	claimObjectName = fencingTokenClaimObjectName(fencingToken.Epoch)
+
+	err = objectStore.ObjectPutIfNoneMatch(accountName, checkpointContainerName, claimObjectName, fencingTokenBuf)
+	if nil != err {
+		if http.StatusPreconditionFailed != blunder.HTTPCode(err) {
+			return
+		}
+
+		existingFencingToken, existingFound, err = readFencingTokenObject(objectStore, accountName, checkpointContainerName, claimObjectName)
+		if nil != err {
+			return
+		}
+		if !existingFound || (fencingToken != existingFencingToken) {
+			claimed = false
+			err = nil
+			return
+		}
+	}
+
+	err = putFencingToken(objectStore, accountName, checkpointContainerName, fencingToken)
+	if nil != err {
+		logger.WarnfWithError(err, "unable to update %v/%v/%v following claim of epoch %v", accountName, checkpointContainerName, FencingTokenObjectName, fencingToken.Epoch)
+	}
+
+	claimed = true
+	err = nil
+	return
+}
+
+// resolveFencingToken fetches the Volume's fencing token... claiming it should none exist and this
+// Peer be the Volume's PrimaryPeer. If none exists (and is not claimed), a fencing token naming the
+// PrimaryPeer (with an epoch of zero) is returned.
+func (member *memberStruct) resolveFencingToken(objectStore objectstore.ObjectStore, accountName string, checkpointContainerName string, volumeName string, primaryPeer string) (fencingToken fencingTokenStruct, err error) {
+	var (
+		claimed bool
+		found   bool
+	)
+
+	fencingToken, found, err = fetchFencingToken(objectStore, accountName, checkpointContainerName)
+	if nil != err {
+		return
+	}
+
+	if !found {
+		if member.whoAmI == primaryPeer {
+			fencingToken = fencingTokenStruct{PeerName: member.whoAmI, Epoch: 1}
+			claimed, err = claimFencingToken(objectStore, accountName, checkpointContainerName, fencingToken)
+			if nil != err {
+				return
+			}
+			if claimed {
+				logger.Infof("Claimed fencing token of Volume %v (epoch %v)", volumeName, fencingToken.Epoch)
+			} else {
+				// Another Peer beat us to it
+
+				fencingToken, _, err = fetchFencingToken(objectStore, accountName, checkpointContainerName)
+				if nil != err {
+					return
+				}
+			}
+		} else {
+			fencingToken = fencingTokenStruct{PeerName: primaryPeer, Epoch: 0}
+		}
+	}
+
+	err = nil
+	return
+}
+
+// applyVolumeOwnership updates each [Volume:<VolumeName>]PrimaryPeer in confMap to name the Peer
+// holding the Volume's fencing token. Should the fencing token not be resolvable (e.g. due to a
+// transient ObjectStore failure), the Volume retains its last known owner (or, if none is known,
+// is left unserved) and watchVolumes() retries resolving it. Hence, only confMap errors are returned.
+func (member *memberStruct) applyVolumeOwnership(confMap conf.ConfMap) (err error) {
+	var (
+		accountName             string
+		candidatePeerList       []string
+		checkpointContainerName string
+		fencingToken            fencingTokenStruct
+		objectStore             objectstore.ObjectStore
+		ok                      bool
+		peerName                string
+		primaryPeer             string
+		primaryPeerList         []string
+		resolveErr              error
+		standbyPeerList         []string
+		volume                  *volumeStruct
+		volumeList              []string
+		volumeMap               map[string]*volumeStruct
+		volumeName              string
+		volumeSectionName       string
+	)
+
+	volumeList, err = confMap.FetchOptionValueStringSlice("FSGlobals", "VolumeList")
+	if nil != err {
+		return
+	}
+
+	volumeMap = make(map[string]*volumeStruct)
+
+	for _, volumeName = range volumeList {
+		volumeSectionName = utils.VolumeNameConfSection(volumeName)
+
+		primaryPeerList, err = confMap.FetchOptionValueStringSlice(volumeSectionName, "PrimaryPeer")
+		if nil != err {
+			return
+		}
+		if 1 < len(primaryPeerList) {
+			err = fmt.Errorf("%s.PrimaryPeer cannot have multiple values", volumeSectionName)
+			return
+		}
+
+		standbyPeerList, err = confMap.FetchOptionValueStringSlice(volumeSectionName, "StandbyPeerList")
+		if nil != err {
+			standbyPeerList = []string{} // TODO: eventually, just return
+		}
+
+		accountName, err = confMap.FetchOptionValueString(volumeSectionName, "AccountName")
+		if nil != err {
+			return
+		}
+
+		checkpointContainerName, err = confMap.FetchOptionValueString(volumeSectionName, "CheckpointContainerName")
+		if nil != err {
+			return
+		}
+
+		objectStore, err = objectstore.FetchObjectStore(confMap, volumeName)
+		if nil != err {
+			return
+		}
+
+		if 1 == len(primaryPeerList) {
+			primaryPeer = primaryPeerList[0]
+		} else {
+			primaryPeer = ""
+		}
+
+		candidatePeerList = make([]string, 0, 1+len(standbyPeerList))
+		candidatePeerList = append(candidatePeerList, primaryPeerList...)
+		for _, peerName = range standbyPeerList {
+			if peerName != primaryPeer {
+				candidatePeerList = append(candidatePeerList, peerName)
+			}
+		}
+
+		// Fetch (or, if we are the PrimaryPeer and none exists, claim) the fencing token without holding the lock
+
+		fencingToken, resolveErr = member.resolveFencingToken(objectStore, accountName, checkpointContainerName, volumeName, primaryPeer)
+		if nil != resolveErr {
+			logger.WarnfWithError(resolveErr, "unable to resolve fencing token of Volume %v... will retry", volumeName)
+		}
+
+		member.Lock()
+
+		volume, ok = member.volumeMap[volumeName]
+		if !ok {
+			volume = &volumeStruct{name: volumeName} // owner == "" until the fencing token is resolved
+		}
+
+		volume.objectStore = objectStore
+		volume.accountName = accountName
+		volume.checkpointContainerName = checkpointContainerName
+		volume.primaryPeer = primaryPeer
+		volume.candidatePeerList = candidatePeerList
+
+		if nil == resolveErr {
+			if !volume.takeOverInProgress {
+				volume.owner = fencingToken.PeerName
+				volume.epoch = fencingToken.Epoch
+			}
+			volume.ownershipRetryPending = false
+		} else if !volume.ownershipRetryPending {
+			volume.ownershipRetryPending = true
+			volume.ownershipRetryDelay = member.fenceCheckInterval
+			volume.ownershipRetryTime = time.Now().Add(volume.ownershipRetryDelay)
+		}
+
+		volumeMap[volumeName] = volume
+
+		// A fencing token naming an unknown Peer leaves the Volume unserved until a candidate takes it over
+
+		_, ok = member.peerMap[volume.owner]
+		if ok || (member.whoAmI == volume.owner) {
+			peerName = volume.owner
+		} else {
+			peerName = ""
+		}
+
+		member.Unlock()
+
+		err = confMap.UpdateFromString(volumeSectionName + ".PrimaryPeer=" + peerName)
+		if nil != err {
+			return
+		}
+	}
+
+	member.Lock()
+	member.volumeMap = volumeMap
+	member.Unlock()
+
+	err = nil
+	return
+}
+
+func (member *memberStruct) fetchVolumeOwner(volumeName string) (peerName string, err error) {
+	member.Lock()
+	defer member.Unlock()
+
+	volume, ok := member.volumeMap[volumeName]
+	if !ok {
+		err = fmt.Errorf("%s: volumeName \"%v\" unknown", utils.GetFnName(), volumeName)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	peerName = volume.owner
+
+	err = nil
+	return
+}
+
+func (member *memberStruct) volumeIsFenced(volumeName string) (fenced bool) {
+	member.Lock()
+	defer member.Unlock()
+
+	volume, ok := member.volumeMap[volumeName]
+	if !ok {
+		fenced = false
+		return
+	}
+
+	fenced = (member.whoAmI != volume.owner)
+
+	return
+}
@@ -0,0 +1,164 @@
+package cluster
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+type heartBeatMsgStruct struct {
+	ServerGuid string    // Heartbeats from another cluster's Peers are ignored
+	PeerName   string    // Sending Peer
+	SentTime   time.Time // Heartbeats older than [Cluster]MessageExpiration are ignored
+}
+
+func (member *memberStruct) sendHeartBeats() {
+	var (
+		err          error
+		heartBeatBuf []byte
+		interval     time.Duration
+		peer         *peerStruct
+		udpAddrList  []*net.UDPAddr
+		udpAddr      *net.UDPAddr
+		udpWriteSize uint64
+	)
+
+	defer member.wg.Done()
+
+	for {
+		member.Lock()
+
+		interval = member.heartBeatInterval
+		if time.Duration(0) < member.heartBeatVariance {
+			interval += time.Duration(rand.Int63n(int64(2*member.heartBeatVariance)+1)) - member.heartBeatVariance
+		}
+
+		heartBeatBuf, err = json.Marshal(&heartBeatMsgStruct{
+			ServerGuid: member.serverGuid,
+			PeerName:   member.whoAmI,
+			SentTime:   time.Now(),
+		})
+		if nil != err {
+			logger.Fatalf("json.Marshal() of heartBeatMsgStruct failed: %v", err)
+		}
+
+		udpWriteSize = member.udpWriteSize
+
+		udpAddrList = make([]*net.UDPAddr, 0, len(member.peerMap))
+		for _, peer = range member.peerMap {
+			udpAddrList = append(udpAddrList, peer.udpAddr)
+		}
+
+		member.Unlock()
+
+		if uint64(len(heartBeatBuf)) > udpWriteSize {
+			logger.Errorf("heartbeat of %v bytes exceeds [Cluster]UDPWriteSize (%v)", len(heartBeatBuf), udpWriteSize)
+		} else {
+			for _, udpAddr = range udpAddrList {
+				_, err = member.udpConn.WriteToUDP(heartBeatBuf, udpAddr)
+				if nil == err {
+					stats.IncrementOperations(&stats.ClusterHeartBeatSentOps)
+				} else {
+					logger.Warnf("sending heartbeat to %v failed: %v", udpAddr, err)
+				}
+			}
+		}
+
+		select {
+		case <-member.stopChan:
+			return
+		case <-time.After(interval):
+			// Time to send the next heartbeat
+		}
+	}
+}
+
+func (member *memberStruct) receiveHeartBeats() {
+	var (
+		err          error
+		heartBeatBuf []byte
+		heartBeatMsg heartBeatMsgStruct
+		n            int
+		ok           bool
+		peer         *peerStruct
+	)
+
+	defer member.wg.Done()
+
+	for {
+		member.Lock()
+		heartBeatBuf = make([]byte, member.udpReadSize)
+		member.Unlock()
+
+		n, _, err = member.udpConn.ReadFromUDP(heartBeatBuf)
+		if nil != err {
+			select {
+			case <-member.stopChan:
+				return
+			default:
+				logger.Warnf("receiving heartbeat failed: %v", err)
+				continue
+			}
+		}
+
+		heartBeatMsg = heartBeatMsgStruct{}
+
+		err = json.Unmarshal(heartBeatBuf[:n], &heartBeatMsg)
+		if nil != err {
+			logger.Warnf("ignoring malformed heartbeat: %v", err)
+			continue
+		}
+
+		member.Lock()
+
+		if heartBeatMsg.ServerGuid != member.serverGuid {
+			member.Unlock()
+			continue
+		}
+
+		if time.Since(heartBeatMsg.SentTime) > member.messageExpiration {
+			member.Unlock()
+			continue
+		}
+
+		peer, ok = member.peerMap[heartBeatMsg.PeerName]
+		if !ok {
+			member.Unlock()
+			logger.Warnf("ignoring heartbeat from unknown Peer %v", heartBeatMsg.PeerName)
+			continue
+		}
+
+		peer.lastHeard = time.Now()
+
+		if peer.expired {
+			peer.expired = false
+			logger.Infof("Peer %v is alive", peer.name)
+		}
+
+		member.Unlock()
+
+		stats.IncrementOperations(&stats.ClusterHeartBeatReceivedOps)
+	}
+}
+
+// peerIsAliveWhileLocked returns true if peerName is whoAmI or has been heard from within [Cluster]HeartBeatExpiration.
+func (member *memberStruct) peerIsAliveWhileLocked(peerName string) (alive bool) {
+	if member.whoAmI == peerName {
+		alive = true
+		return
+	}
+
+	peer, ok := member.peerMap[peerName]
+	if !ok {
+		alive = false
+		return
+	}
+
+	alive = time.Since(peer.lastHeard) < member.heartBeatExpiration
+
+	return
+}
@@ -80,6 +80,14 @@ type RWLockStruct struct {
 	LockCallerID CallerID
 }
 
+// InodeLockID returns the LockID naming the lock of inodeNumber in volumeName, of the
+// form "vol.<VolumeName>:ino.<InodeNumber>". Every user of an inode lock (packages fs and
+// inode alike) must name it this way... and only such LockIDs are granted by a lock server.
+func InodeLockID(volumeName string, inodeNumber uint64) (lockID string) {
+	lockID = fmt.Sprintf("vol.%s:ino.%d", volumeName, inodeNumber)
+	return
+}
+
 // Lock for generating unique caller IDs
 // For now, this is just an in-memory thing.
 var callerIDLock sync.Mutex
@@ -0,0 +1,539 @@
+package dlm
+
+// In distributed mode (i.e. if [DLM]TCPPort is specified), the LLM only grants a lock whose
+// LockID names a Volume in [FSGlobals]VolumeList once this Peer holds a covering node-level lock
+// from the lock server of the Volume's PrimaryPeer (see server.go). A node-level lock is acquired
+// by the first thread needing it and released once the last thread holding the lock unlocks it
+// or, should the lock server revoke it, once the threads holding it at that time have unlocked it.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// serverConnStruct is this Peer's connection to a lock server (possibly its own).
+type serverConnStruct struct {
+	sync.Mutex
+	addr      string     // "" if connected to this Peer's own lock server
+	sendMutex sync.Mutex // Serializes calls to send()
+	send      func(msg *messageStruct) (err error)
+	closeFunc func()
+	nextSeq   uint64
+	replyMap  map[uint64]chan *messageStruct // Key == messageStruct.Seq of an outstanding request
+	closed    bool
+}
+
+func newServerConn(addr string) (conn *serverConnStruct) {
+	conn = &serverConnStruct{
+		addr:     addr,
+		nextSeq:  1,
+		replyMap: make(map[uint64]chan *messageStruct),
+		closed:   false,
+	}
+	return
+}
+
+// newLocalServerConn connects to this Peer's own lock server without going through TCP.
+func newLocalServerConn(server *lockServerStruct, whoAmI string) (conn *serverConnStruct) {
+	conn = newServerConn("")
+
+	session := newSession(whoAmI, func(msg *messageStruct) (err error) {
+		conn.dispatch(msg)
+		return nil
+	})
+
+	conn.send = func(msg *messageStruct) (err error) {
+		server.handleMessage(session, msg)
+		return nil
+	}
+
+	conn.closeFunc = func() {
+		server.dropSession(session)
+		session.close()
+	}
+
+	return
+}
+
+// newRemoteServerConn connects to the lock server of another Peer listening on addr.
+func newRemoteServerConn(addr string, whoAmI string, dialTimeout time.Duration) (conn *serverConnStruct, err error) {
+	netConn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if nil != err {
+		return
+	}
+
+	encoder := json.NewEncoder(netConn)
+
+	err = encoder.Encode(&messageStruct{Op: helloOp, PeerName: whoAmI})
+	if nil != err {
+		_ = netConn.Close()
+		return
+	}
+
+	conn = newServerConn(addr)
+
+	conn.send = func(msg *messageStruct) (err error) { return encoder.Encode(msg) }
+	conn.closeFunc = func() { _ = netConn.Close() }
+
+	go func() {
+		decoder := json.NewDecoder(netConn)
+		for {
+			msg := &messageStruct{}
+			err := decoder.Decode(msg)
+			if nil != err {
+				conn.fail(err)
+				return
+			}
+			conn.dispatch(msg)
+		}
+	}()
+
+	err = nil
+	return
+}
+
+// request sends an acquireOp or releaseOp and awaits its replyOp.
+func (conn *serverConnStruct) request(msg *messageStruct) (reply *messageStruct, err error) {
+	conn.Lock()
+	if conn.closed {
+		conn.Unlock()
+		err = fmt.Errorf("connection to dlm lock server %v closed", conn.describe())
+		return
+	}
+	msg.Seq = conn.nextSeq
+	conn.nextSeq++
+	replyChan := make(chan *messageStruct, 1)
+	conn.replyMap[msg.Seq] = replyChan
+	conn.Unlock()
+
+	conn.sendMutex.Lock()
+	err = conn.send(msg)
+	conn.sendMutex.Unlock()
+	if nil != err {
+		conn.fail(err)
+		return
+	}
+
+	reply, ok := <-replyChan
+	if !ok {
+		err = fmt.Errorf("connection to dlm lock server %v lost", conn.describe())
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (conn *serverConnStruct) dispatch(msg *messageStruct) {
+	switch msg.Op {
+	case replyOp:
+		conn.Lock()
+		replyChan, ok := conn.replyMap[msg.Seq]
+		delete(conn.replyMap, msg.Seq)
+		conn.Unlock()
+		if ok {
+			replyChan <- msg
+		}
+	case revokeOp:
+		go handleRevoke(conn, msg.LockID, msg.Reason)
+	default:
+		logger.Warnf("dlm ignoring unexpected %v from lock server %v", msg.Op, conn.describe())
+	}
+}
+
+// fail closes the connection, fails any outstanding requests, and forgets the node-level locks
+// acquired via the connection (the lock server will have released them upon losing the connection).
+func (conn *serverConnStruct) fail(err error) {
+	conn.Lock()
+	if conn.closed {
+		conn.Unlock()
+		return
+	}
+	conn.closed = true
+	for _, replyChan := range conn.replyMap {
+		close(replyChan)
+	}
+	conn.replyMap = make(map[uint64]chan *messageStruct)
+	conn.Unlock()
+
+	conn.closeFunc()
+
+	if nil != err {
+		logger.Warnf("dlm lost connection to lock server %v: %v", conn.describe(), err)
+	}
+
+	connectionLost(conn)
+}
+
+func (conn *serverConnStruct) isClosed() (closed bool) {
+	conn.Lock()
+	closed = conn.closed
+	conn.Unlock()
+	return
+}
+
+func (conn *serverConnStruct) describe() string {
+	if "" == conn.addr {
+		return "(local)"
+	}
+	return conn.addr
+}
+
+// distributedVolumeWhileLocked returns whether or not lockID must be granted by a lock server and,
+// if so, the name of the Volume whose lock server grants it. Called while globals are locked.
+func distributedVolumeWhileLocked(lockID string) (distributed bool, volumeName string) {
+	if !globals.distributed {
+		distributed = false
+		return
+	}
+
+	volumeName, ok := volumeNameFromLockID(lockID)
+	if !ok {
+		distributed = false
+		return
+	}
+
+	_, distributed = globals.volumeServerMap[volumeName]
+
+	return
+}
+
+// fetchServerConn returns a connection to the lock server of volumeName, dialing it if necessary.
+func fetchServerConn(volumeName string) (conn *serverConnStruct, err error) {
+	globals.Lock()
+
+	peerName, ok := globals.volumeServerMap[volumeName]
+	if !ok || ("" == peerName) {
+		globals.Unlock()
+		err = fmt.Errorf("Volume %v not currently served by any Peer", volumeName)
+		return
+	}
+
+	var addr string
+	if globals.whoAmI == peerName {
+		addr = ""
+	} else {
+		addr, ok = globals.peerAddrMap[peerName]
+		if !ok {
+			globals.Unlock()
+			err = fmt.Errorf("Volume %v served by unknown Peer %v", volumeName, peerName)
+			return
+		}
+	}
+
+	conn, ok = globals.serverConnMap[addr]
+	if ok {
+		globals.Unlock()
+		err = nil
+		return
+	}
+
+	if "" == addr {
+		conn = newLocalServerConn(globals.lockServer, globals.whoAmI)
+		globals.serverConnMap[addr] = conn
+		globals.Unlock()
+		err = nil
+		return
+	}
+
+	whoAmI := globals.whoAmI
+	dialTimeout := globals.dialTimeout
+
+	globals.Unlock()
+
+	conn, err = newRemoteServerConn(addr, whoAmI, dialTimeout)
+	if nil != err {
+		return
+	}
+
+	globals.Lock()
+	existingConn, ok := globals.serverConnMap[addr]
+	if ok {
+		// Another thread dialed the lock server first
+		globals.Unlock()
+		conn.fail(nil)
+		conn = existingConn
+		err = nil
+		return
+	}
+	globals.serverConnMap[addr] = conn
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+// acquireNodeLock obtains a node-level lock from the lock server of volumeName. Unless try is
+// set, failures (e.g. while the Volume fails over to another Peer) are retried every [DLM]RetryDelay.
+func acquireNodeLock(lockID string, volumeName string, exclusive bool, try bool) (conn *serverConnStruct, err error) {
+	var reply *messageStruct
+
+	for {
+		conn, err = fetchServerConn(volumeName)
+		if nil == err {
+			reply, err = conn.request(&messageStruct{Op: acquireOp, LockID: lockID, Exclusive: exclusive, Try: try})
+			if nil == err {
+				switch reply.Err {
+				case "":
+					stats.IncrementOperations(&stats.DLMNodeLockAcquireOps)
+					return
+				case errTryAgain:
+					err = fmt.Errorf("Lock is busy on another Peer - try again!")
+					err = blunder.AddError(err, blunder.TryAgainError)
+					return
+				default:
+					err = fmt.Errorf("dlm lock server %v returned %v", conn.describe(), reply.Err)
+				}
+			}
+		}
+
+		if try {
+			err = fmt.Errorf("Unable to acquire node-level lock %v - try again! (%v)", lockID, err)
+			err = blunder.AddError(err, blunder.TryAgainError)
+			return
+		}
+
+		globals.Lock()
+		retryDelay := globals.retryDelay
+		globals.Unlock()
+
+		logger.Warnf("dlm unable to acquire node-level lock %v (retrying in %v): %v", lockID, retryDelay, err)
+
+		time.Sleep(retryDelay)
+	}
+}
+
+// releaseNodeLock returns a node-level lock to the lock server that granted it. Failures are
+// merely logged as the lock server releases the node-level locks of connections it loses.
+func releaseNodeLock(conn *serverConnStruct, lockID string) {
+	_, err := conn.request(&messageStruct{Op: releaseOp, LockID: lockID})
+	if nil != err {
+		logger.Infof("dlm unable to release node-level lock %v: %v", lockID, err)
+		return
+	}
+
+	stats.IncrementOperations(&stats.DLMNodeLockReleaseOps)
+}
+
+// nodeLockCovers returns whether or not a node-level lock in nodeState permits the LLM to grant requestedState.
+func nodeLockCovers(nodeState lockState, requestedState lockState) bool {
+	return (exclusive == nodeState) || ((shared == nodeState) && (shared == requestedState))
+}
+
+// releaseNodeLockWhileLocked releases track's node-level lock. Called (and returns) with
+// track.Mutex held... though it is dropped while awaiting the lock server's reply.
+func releaseNodeLockWhileLocked(track *localLockTrack) {
+	conn := track.nodeConn
+
+	track.nodeOpInFlight = true
+	track.Mutex.Unlock()
+
+	if nil != conn {
+		releaseNodeLock(conn, track.lockId)
+	}
+
+	track.Mutex.Lock()
+	track.nodeOpInFlight = false
+	track.nodeState = stale
+	track.nodeConn = nil
+	track.revokePending = false
+
+	kickWaiters(track)
+}
+
+// kickWaiters wakes each waiting thread to re-evaluate whether it should acquire (or release) the
+// node-level lock. Called while track.Mutex is held.
+func kickWaiters(track *localLockTrack) {
+	for elem := track.waitReqQ.Front(); nil != elem; elem = elem.Next() {
+		elem.Value.(*localLockRequest).Cond.Broadcast()
+	}
+}
+
+// discardTrackIfIdle removes track from localLockMap if no thread holds or awaits the lock.
+func discardTrackIfIdle(track *localLockTrack) {
+	globals.Lock()
+	track.Mutex.Lock()
+
+	if (0 == track.owners) && (0 == track.waiters) && (stale == track.nodeState) && !track.nodeOpInFlight {
+		if track == globals.localLockMap[track.lockId] {
+			delete(globals.localLockMap, track.lockId)
+		}
+	}
+
+	track.Mutex.Unlock()
+	globals.Unlock()
+}
+
+// commonDistributedLock is commonLock() for a lock that also requires a node-level lock. Called
+// with track.Mutex held... which is released upon return.
+func (l *RWLockStruct) commonDistributedLock(track *localLockTrack, requestedState lockState, try bool) (err error) {
+	var conn *serverConnStruct
+
+	// A try lock must not wait behind other threads (nor on a lock server's reply to them)
+
+	if try && (tryWouldBlock(track, requestedState) || (0 < track.waitReqQ.Len()) || track.nodeOpInFlight || (track.revokePending && track.grantedSinceNodeAcquire)) {
+		track.Mutex.Unlock()
+		err = fmt.Errorf("Lock is busy - try again!")
+		return blunder.AddError(err, blunder.TryAgainError)
+	}
+
+	localRequest := &localLockRequest{requestedState: requestedState, LockCallerID: l.LockCallerID, notify: l.Notify, wakeUp: false}
+	localRequest.Cond = sync.NewCond(&track.Mutex)
+	track.waitReqQ.PushBack(localRequest)
+
+	track.waiters++
+
+	for {
+		processLocalQ(track)
+
+		if localRequest.wakeUp {
+			break
+		}
+
+		// Only the first waiting thread acquires (or releases) the node-level lock... and then only
+		// once no thread holds the lock
+
+		if !track.nodeOpInFlight && (0 == track.owners) && (localRequest == track.waitReqQ.Front().Value.(*localLockRequest)) {
+			if (stale != track.nodeState) && (track.revokePending || !nodeLockCovers(track.nodeState, requestedState)) {
+				releaseNodeLockWhileLocked(track)
+				continue
+			}
+
+			if stale == track.nodeState {
+				track.nodeOpInFlight = true
+				track.Mutex.Unlock()
+
+				conn, err = acquireNodeLock(track.lockId, track.volumeName, (exclusive == requestedState), try)
+
+				track.Mutex.Lock()
+				track.nodeOpInFlight = false
+
+				if nil != err {
+					track.waitReqQ.Remove(track.waitReqQ.Front())
+					track.waiters--
+					kickWaiters(track)
+					track.Mutex.Unlock()
+					discardTrackIfIdle(track)
+					return
+				}
+
+				if !conn.isClosed() {
+					track.nodeState = requestedState
+					track.nodeConn = conn
+					track.grantedSinceNodeAcquire = false
+				}
+
+				continue
+			}
+		}
+
+		localRequest.Cond.Wait()
+	}
+
+	track.waiters--
+
+	track.Mutex.Unlock()
+
+	err = nil
+	return
+}
+
+// distributedUnlock is unlock() for a lock that also requires a node-level lock. Called with
+// track.Mutex held... which is released upon return.
+func (l *RWLockStruct) distributedUnlock(track *localLockTrack) {
+	track.owners--
+	removeFromListOfOwners(track.listOfOwners, l.LockCallerID)
+	delete(track.ownerNotifyMap, l.LockCallerID)
+	if 0 == track.owners {
+		track.state = stale
+	}
+
+	processLocalQ(track)
+
+	// Any waiting thread will release (or keep) the node-level lock once woken
+
+	if (0 == track.owners) && (0 == track.waiters) && (stale != track.nodeState) && !track.nodeOpInFlight {
+		releaseNodeLockWhileLocked(track)
+	} else {
+		kickWaiters(track)
+	}
+
+	track.Mutex.Unlock()
+
+	discardTrackIfIdle(track)
+}
+
+// handleRevoke processes a lock server's request that this Peer release its node-level lock on
+// lockID. Each thread holding the lock is notified and, once they have all unlocked it, the
+// node-level lock is released... before any further threads are granted the lock.
+func handleRevoke(conn *serverConnStruct, lockID string, reason NotifyReason) {
+	globals.Lock()
+
+	track, ok := globals.localLockMap[lockID]
+	if !ok {
+		// The node-level lock has already been released
+		globals.Unlock()
+		return
+	}
+
+	track.Mutex.Lock()
+
+	globals.Unlock()
+
+	if !track.distributed || (!track.nodeOpInFlight && (conn != track.nodeConn)) {
+		track.Mutex.Unlock()
+		return
+	}
+
+	stats.IncrementOperations(&stats.DLMNodeLockRevokeOps)
+
+	track.revokePending = true
+
+	notifyList := make([]Notify, 0, len(track.ownerNotifyMap))
+	for _, notify := range track.ownerNotifyMap {
+		notifyList = append(notifyList, notify)
+	}
+
+	if 0 == track.owners {
+		kickWaiters(track)
+	}
+
+	track.Mutex.Unlock()
+
+	for _, notify := range notifyList {
+		notify.NotifyNodeChange(reason)
+	}
+}
+
+// connectionLost forgets the node-level locks acquired via conn. Threads still holding such a lock
+// are no longer protected from threads on other Peers... which is logged.
+func connectionLost(conn *serverConnStruct) {
+	globals.Lock()
+	defer globals.Unlock()
+
+	if conn == globals.serverConnMap[conn.addr] {
+		delete(globals.serverConnMap, conn.addr)
+	}
+
+	for lockID, track := range globals.localLockMap {
+		track.Mutex.Lock()
+		if conn == track.nodeConn {
+			if 0 < track.owners {
+				stats.IncrementOperations(&stats.DLMNodeLockLostOps)
+				logger.Warnf("dlm lost node-level lock %v while held by %v thread(s)", lockID, track.owners)
+			}
+			track.nodeState = stale
+			track.nodeConn = nil
+			track.revokePending = false
+			kickWaiters(track)
+		}
+		track.Mutex.Unlock()
+	}
+}
@@ -3,9 +3,15 @@ package dlm
 // Configuration variables for DLM
 
 import (
+	"fmt"
+	"net"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/utils"
 )
 
 type globalsStruct struct {
@@ -15,11 +21,17 @@ type globalsStruct struct {
 	// NOTE: This map is protected by the Mutex
 	localLockMap map[string]*localLockTrack
 
-	// TODO - channels for STOP and from DLM lock master?
-	// is the channel lock one per lock or a global one from DLM?
-	// how could it be... probably just one receive thread the locks
-	// map, checks bit and releases lock if no one using, otherwise
-	// blocks until it is free...
+	// Distributed mode is only enabled if [DLM]TCPPort is specified
+	// NOTE: These fields are protected by the Mutex
+	distributed     bool
+	whoAmI          string
+	tcpAddr         string                       // Upon which lockServer listens
+	dialTimeout     time.Duration                //
+	retryDelay      time.Duration                //
+	volumeServerMap map[string]string            // Key == VolumeName; Value == PrimaryPeer ("" if none)
+	peerAddrMap     map[string]string            // Key == PeerName;   Value == "<PrivateIPAddr>:<[DLM]TCPPort>"
+	lockServer      *lockServerStruct            // Grants node-level locks for the Volumes this Peer serves
+	serverConnMap   map[string]*serverConnStruct // Key == serverConnStruct.addr
 }
 
 var globals globalsStruct
@@ -27,6 +39,27 @@ var globals globalsStruct
 func Up(confMap conf.ConfMap) (err error) {
 	// Create map used to store locks
 	globals.localLockMap = make(map[string]*localLockTrack)
+
+	globals.distributed = false
+	globals.serverConnMap = make(map[string]*serverConnStruct)
+
+	err = loadConfMap(confMap)
+	if nil != err {
+		return
+	}
+
+	if globals.distributed {
+		globals.lockServer, err = newLockServer(globals.tcpAddr)
+		if nil != err {
+			return
+		}
+
+		globals.lockServer.setVolumes(globals.servedVolumeList())
+
+		logger.Infof("dlm lock server for %v listening on %v", globals.whoAmI, globals.tcpAddr)
+	}
+
+	err = nil
 	return
 }
 
@@ -36,12 +69,170 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 	return
 }
 
+// ExpandAndResume picks up changes to the PrimaryPeer of each Volume (e.g. following a failover).
+// Requests for node-level locks are thereafter sent to the new PrimaryPeer's lock server.
 func ExpandAndResume(confMap conf.ConfMap) (err error) {
-	// Nothing to do here
+	globals.Lock()
+	distributed := globals.distributed
+	tcpAddr := globals.tcpAddr
+	globals.Unlock()
+
+	err = loadConfMap(confMap)
+	if nil != err {
+		return
+	}
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	if (distributed != globals.distributed) || (tcpAddr != globals.tcpAddr) {
+		err = fmt.Errorf("[DLM]TCPPort (and this Peer's PrivateIPAddr) cannot be changed")
+		return
+	}
+
+	if globals.distributed {
+		globals.lockServer.setVolumes(globals.servedVolumeList())
+	}
+
 	err = nil
 	return
 }
 
 func Down() (err error) {
+	globals.Lock()
+	lockServer := globals.lockServer
+	globals.lockServer = nil
+	connList := make([]*serverConnStruct, 0, len(globals.serverConnMap))
+	for _, conn := range globals.serverConnMap {
+		connList = append(connList, conn)
+	}
+	globals.distributed = false
+	globals.Unlock()
+
+	for _, conn := range connList {
+		conn.fail(nil)
+	}
+
+	if nil != lockServer {
+		lockServer.stop()
+	}
+
+	err = nil
+	return
+}
+
+// loadConfMap (re)loads the distributed mode settings. Should [DLM]TCPPort not be specified,
+// all locks are granted by the LLM alone.
+func loadConfMap(confMap conf.ConfMap) (err error) {
+	var (
+		dialTimeout     time.Duration
+		peerAddrMap     map[string]string
+		peerName        string
+		peerNames       []string
+		primaryPeerList []string
+		privateIPAddr   string
+		retryDelay      time.Duration
+		tcpAddr         string
+		tcpPort         uint16
+		volumeList      []string
+		volumeName      string
+		volumeServerMap map[string]string
+		whoAmI          string
+	)
+
+	tcpPort, err = confMap.FetchOptionValueUint16("DLM", "TCPPort")
+	if nil != err {
+		globals.Lock()
+		globals.distributed = false
+		globals.Unlock()
+		err = nil
+		return
+	}
+
+	dialTimeout, err = confMap.FetchOptionValueDuration("DLM", "DialTimeout")
+	if nil != err {
+		dialTimeout = 5 * time.Second // TODO: eventually, just return
+	}
+
+	retryDelay, err = confMap.FetchOptionValueDuration("DLM", "RetryDelay")
+	if nil != err {
+		retryDelay = 100 * time.Millisecond // TODO: eventually, just return
+	}
+
+	whoAmI, err = confMap.FetchOptionValueString("Cluster", "WhoAmI")
+	if nil != err {
+		return
+	}
+
+	peerNames, err = confMap.FetchOptionValueStringSlice("Cluster", "Peers")
+	if nil != err {
+		return
+	}
+
+	peerAddrMap = make(map[string]string)
+
+	for _, peerName = range peerNames {
+		privateIPAddr, err = confMap.FetchOptionValueString(utils.PeerNameConfSection(peerName), "PrivateIPAddr")
+		if nil != err {
+			return
+		}
+
+		peerAddrMap[peerName] = net.JoinHostPort(privateIPAddr, strconv.FormatUint(uint64(tcpPort), 10))
+	}
+
+	tcpAddr, ok := peerAddrMap[whoAmI]
+	if !ok {
+		err = fmt.Errorf("[Cluster]WhoAmI (%v) not found in [Cluster]Peers", whoAmI)
+		return
+	}
+
+	volumeList, err = confMap.FetchOptionValueStringSlice("FSGlobals", "VolumeList")
+	if nil != err {
+		return
+	}
+
+	volumeServerMap = make(map[string]string)
+
+	for _, volumeName = range volumeList {
+		primaryPeerList, err = confMap.FetchOptionValueStringSlice(utils.VolumeNameConfSection(volumeName), "PrimaryPeer")
+		if nil != err {
+			return
+		}
+
+		switch len(primaryPeerList) {
+		case 0:
+			volumeServerMap[volumeName] = ""
+		case 1:
+			volumeServerMap[volumeName] = primaryPeerList[0]
+		default:
+			err = fmt.Errorf("%s.PrimaryPeer cannot have multiple values", utils.VolumeNameConfSection(volumeName))
+			return
+		}
+	}
+
+	globals.Lock()
+	globals.distributed = true
+	globals.whoAmI = whoAmI
+	globals.tcpAddr = tcpAddr
+	globals.dialTimeout = dialTimeout
+	globals.retryDelay = retryDelay
+	globals.volumeServerMap = volumeServerMap
+	globals.peerAddrMap = peerAddrMap
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+// servedVolumeList returns the Volumes whose PrimaryPeer is this Peer. Called while locked.
+func (globals *globalsStruct) servedVolumeList() (volumeList []string) {
+	volumeList = make([]string, 0, len(globals.volumeServerMap))
+
+	for volumeName, peerName := range globals.volumeServerMap {
+		if globals.whoAmI == peerName {
+			volumeList = append(volumeList, volumeName)
+		}
+	}
+
 	return
 }
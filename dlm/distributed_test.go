@@ -0,0 +1,320 @@
+package dlm
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+// This Peer (Peer0) serves LocalVolume... Peer1's lock server (serving RemoteVolume) is stood up by the test
+var testDistributedConfStrings = []string{
+	"Peer:Peer0.PrivateIPAddr=127.0.0.1",
+	"Peer:Peer1.PrivateIPAddr=127.0.0.2",
+	"Cluster.WhoAmI=Peer0",
+	"Cluster.Peers=Peer0 Peer1",
+	"DLM.TCPPort=52190",
+	"DLM.DialTimeout=1s",
+	"DLM.RetryDelay=10ms",
+	"Volume:LocalVolume.PrimaryPeer=Peer0",
+	"Volume:RemoteVolume.PrimaryPeer=Peer1",
+	"FSGlobals.VolumeList=LocalVolume RemoteVolume",
+}
+
+type testNotifyStruct struct {
+	reasonChan chan NotifyReason
+}
+
+func (testNotify *testNotifyStruct) NotifyNodeChange(reason NotifyReason) {
+	testNotify.reasonChan <- reason
+}
+
+func (testNotify *testNotifyStruct) expectReason(t *testing.T, expectedReason NotifyReason) {
+	select {
+	case reason := <-testNotify.reasonChan:
+		if expectedReason != reason {
+			t.Fatalf("NotifyNodeChange() passed %v... expected %v", reason, expectedReason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("NotifyNodeChange() not called")
+	}
+}
+
+// testPeerConnStruct stands in for another Peer speaking to a lock server
+type testPeerConnStruct struct {
+	netConn net.Conn
+	encoder *json.Encoder
+	msgChan chan *messageStruct
+}
+
+func testDialLockServer(t *testing.T, addr string, peerName string) (testPeerConn *testPeerConnStruct) {
+	netConn, err := net.Dial("tcp", addr)
+	if nil != err {
+		t.Fatalf("net.Dial(\"tcp\", %v) failed: %v", addr, err)
+	}
+
+	testPeerConn = &testPeerConnStruct{
+		netConn: netConn,
+		encoder: json.NewEncoder(netConn),
+		msgChan: make(chan *messageStruct, 10),
+	}
+
+	go func() {
+		decoder := json.NewDecoder(netConn)
+		for {
+			msg := &messageStruct{}
+			if nil != decoder.Decode(msg) {
+				return
+			}
+			testPeerConn.msgChan <- msg
+		}
+	}()
+
+	testPeerConn.send(t, &messageStruct{Op: helloOp, PeerName: peerName})
+
+	return
+}
+
+func (testPeerConn *testPeerConnStruct) send(t *testing.T, msg *messageStruct) {
+	err := testPeerConn.encoder.Encode(msg)
+	if nil != err {
+		t.Fatalf("sending %v failed: %v", msg.Op, err)
+	}
+}
+
+func (testPeerConn *testPeerConnStruct) expect(t *testing.T, expectedMsg messageStruct) {
+	select {
+	case msg := <-testPeerConn.msgChan:
+		if expectedMsg != *msg {
+			t.Fatalf("received %+v... expected %+v", *msg, expectedMsg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("nothing received... expected %+v", expectedMsg)
+	}
+}
+
+func (testPeerConn *testPeerConnStruct) expectNothing(t *testing.T) {
+	select {
+	case msg := <-testPeerConn.msgChan:
+		t.Fatalf("unexpectedly received %+v", *msg)
+	case <-time.After(100 * time.Millisecond):
+		// Expected
+	}
+}
+
+func testLockInBackground(lock *RWLockStruct, requestedState lockState) (doneChan chan error) {
+	doneChan = make(chan error, 1)
+	go func() {
+		doneChan <- lock.commonLock(requestedState, false)
+	}()
+	return
+}
+
+func testExpectLocked(t *testing.T, doneChan chan error) {
+	select {
+	case err := <-doneChan:
+		if nil != err {
+			t.Fatalf("lock failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("lock not granted")
+	}
+}
+
+func testExpectBlocked(t *testing.T, doneChan chan error) {
+	select {
+	case err := <-doneChan:
+		t.Fatalf("lock unexpectedly granted (err == %v)", err)
+	case <-time.After(100 * time.Millisecond):
+		// Expected
+	}
+}
+
+func TestDistributedLocks(t *testing.T) {
+	confMap, err := conf.MakeConfMapFromStrings(testDistributedConfStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	// Restart (in distributed mode) the DLM brought up by testSetup()
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	defer func() {
+		err = Down()
+		if nil != err {
+			t.Fatalf("Down() failed: %v", err)
+		}
+		err = Up(conf.MakeConfMap())
+		if nil != err {
+			t.Fatalf("Up() failed: %v", err)
+		}
+	}()
+
+	testDistributedLocalVolume(t)
+	testDistributedRemoteVolume(t)
+}
+
+// Peer1 contends with this Peer's threads for a lock granted by this Peer's own lock server
+func testDistributedLocalVolume(t *testing.T) {
+	lockID := InodeLockID("LocalVolume", 1)
+
+	writeNotify := &testNotifyStruct{reasonChan: make(chan NotifyReason, 1)}
+	writeLock := &RWLockStruct{LockID: lockID, Notify: writeNotify, LockCallerID: GenerateCallerID()}
+	readLock := &RWLockStruct{LockID: lockID, Notify: nil, LockCallerID: GenerateCallerID()}
+
+	peer1 := testDialLockServer(t, "127.0.0.1:52190", "Peer1")
+	defer peer1.netConn.Close()
+
+	// A shared request from Peer1 is notified to this Peer's exclusive owner... and blocks subsequent local grants
+
+	err := writeLock.WriteLock()
+	if nil != err {
+		t.Fatalf("WriteLock() failed: %v", err)
+	}
+
+	peer1.send(t, &messageStruct{Op: acquireOp, Seq: 1, LockID: lockID})
+	writeNotify.expectReason(t, ReasonReadRequest)
+	peer1.expectNothing(t)
+
+	readDoneChan := testLockInBackground(readLock, shared)
+	testExpectBlocked(t, readDoneChan)
+
+	err = writeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	peer1.expect(t, messageStruct{Op: replyOp, Seq: 1, LockID: lockID})
+	testExpectLocked(t, readDoneChan)
+
+	// Shared node-level locks coexist... but exclude exclusive ones
+
+	peer1.send(t, &messageStruct{Op: acquireOp, Seq: 2, LockID: lockID, Exclusive: true, Try: true})
+	peer1.expect(t, messageStruct{Op: replyOp, Seq: 2, LockID: lockID, Err: errTryAgain})
+
+	err = readLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	peer1.send(t, &messageStruct{Op: releaseOp, Seq: 3, LockID: lockID})
+	peer1.expect(t, messageStruct{Op: replyOp, Seq: 3, LockID: lockID})
+
+	// While Peer1 holds the lock exclusively, local try locks fail and local locks block (revoking Peer1's lock)
+
+	peer1.send(t, &messageStruct{Op: acquireOp, Seq: 4, LockID: lockID, Exclusive: true})
+	peer1.expect(t, messageStruct{Op: replyOp, Seq: 4, LockID: lockID})
+
+	err = writeLock.TryWriteLock()
+	if !blunder.Is(err, blunder.TryAgainError) {
+		t.Fatalf("TryWriteLock() should have failed with TryAgainError but returned %v", err)
+	}
+
+	writeDoneChan := testLockInBackground(writeLock, exclusive)
+	peer1.expect(t, messageStruct{Op: revokeOp, LockID: lockID, Reason: ReasonWriteRequest})
+	testExpectBlocked(t, writeDoneChan)
+
+	peer1.send(t, &messageStruct{Op: releaseOp, Seq: 5, LockID: lockID})
+	peer1.expect(t, messageStruct{Op: replyOp, Seq: 5, LockID: lockID})
+	testExpectLocked(t, writeDoneChan)
+
+	err = writeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	// Node-level locks held by a departed Peer are released
+
+	peer1.send(t, &messageStruct{Op: acquireOp, Seq: 6, LockID: lockID, Exclusive: true})
+	peer1.expect(t, messageStruct{Op: replyOp, Seq: 6, LockID: lockID})
+
+	writeDoneChan = testLockInBackground(writeLock, exclusive)
+	peer1.expect(t, messageStruct{Op: revokeOp, LockID: lockID, Reason: ReasonWriteRequest})
+	testExpectBlocked(t, writeDoneChan)
+
+	_ = peer1.netConn.Close()
+	testExpectLocked(t, writeDoneChan)
+
+	err = writeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	// Once no thread holds or awaits the lock, it is discarded
+
+	globals.Lock()
+	track, ok := globals.localLockMap[lockID]
+	globals.Unlock()
+	if ok {
+		t.Fatalf("localLockMap should not still contain %v (%+v)", lockID, track)
+	}
+}
+
+// This Peer acquires a lock from Peer1's lock server... and gives it up when Peer1's lock server revokes it
+func testDistributedRemoteVolume(t *testing.T) {
+	lockID := InodeLockID("RemoteVolume", 1)
+
+	peer1LockServer, err := newLockServer("127.0.0.2:52190")
+	if nil != err {
+		t.Fatalf("newLockServer() failed: %v", err)
+	}
+	defer peer1LockServer.stop()
+
+	peer1LockServer.setVolumes([]string{"RemoteVolume"})
+
+	writeNotify := &testNotifyStruct{reasonChan: make(chan NotifyReason, 1)}
+	writeLock := &RWLockStruct{LockID: lockID, Notify: writeNotify, LockCallerID: GenerateCallerID()}
+
+	err = writeLock.WriteLock()
+	if nil != err {
+		t.Fatalf("WriteLock() failed: %v", err)
+	}
+	if !writeLock.IsWriteHeld() {
+		t.Fatalf("IsWriteHeld() should have returned true")
+	}
+
+	peer2 := testDialLockServer(t, "127.0.0.2:52190", "Peer2")
+	defer peer2.netConn.Close()
+
+	peer2.send(t, &messageStruct{Op: acquireOp, Seq: 1, LockID: lockID, Exclusive: true})
+	writeNotify.expectReason(t, ReasonWriteRequest)
+	peer2.expectNothing(t)
+
+	err = writeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	peer2.expect(t, messageStruct{Op: replyOp, Seq: 1, LockID: lockID})
+
+	// Once Peer2 releases the lock, this Peer may acquire it... until Peer1's lock server stops serving RemoteVolume
+
+	peer2.send(t, &messageStruct{Op: releaseOp, Seq: 2, LockID: lockID})
+	peer2.expect(t, messageStruct{Op: replyOp, Seq: 2, LockID: lockID})
+
+	err = writeLock.TryWriteLock()
+	if nil != err {
+		t.Fatalf("TryWriteLock() failed: %v", err)
+	}
+	err = writeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	peer1LockServer.setVolumes([]string{})
+
+	err = writeLock.TryWriteLock()
+	if !blunder.Is(err, blunder.TryAgainError) {
+		t.Fatalf("TryWriteLock() of a Volume not being served should have failed with TryAgainError but returned %v", err)
+	}
+}
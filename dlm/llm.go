@@ -19,6 +19,16 @@ type localLockTrack struct {
 	state        lockState
 	listOfOwners []CallerID
 	waitReqQ     *list.List // List of requests waiting for lock
+
+	// The following are only used if the lock also requires a node-level lock (see client.go)
+	distributed             bool
+	volumeName              string
+	nodeState               lockState           // Node-level lock held by this Peer (stale if none)
+	nodeConn                *serverConnStruct   // Connection via which nodeState was granted
+	nodeOpInFlight          bool                // A node-level lock is being acquired or released
+	revokePending           bool                // The lock server has asked for the node-level lock back
+	grantedSinceNodeAcquire bool                // Once set, revokePending prevents further grants
+	ownerNotifyMap          map[CallerID]Notify // Notify of each owner supplying one
 }
 
 type localLockRequest struct {
@@ -26,6 +36,7 @@ type localLockRequest struct {
 	*sync.Cond
 	wakeUp       bool
 	LockCallerID CallerID
+	notify       Notify
 }
 
 type lockState int
@@ -167,6 +178,12 @@ func grantAndSignal(track *localLockTrack, localQRequest *localLockRequest) {
 	track.state = localQRequest.requestedState
 	track.listOfOwners = append(track.listOfOwners, localQRequest.LockCallerID)
 	track.owners++
+	if track.distributed {
+		track.grantedSinceNodeAcquire = true
+		if nil != localQRequest.notify {
+			track.ownerNotifyMap[localQRequest.LockCallerID] = localQRequest.notify
+		}
+	}
 	localQRequest.wakeUp = true
 	localQRequest.Cond.Broadcast()
 }
@@ -186,6 +203,10 @@ func processLocalQ(track *localLockTrack) {
 		return
 	}
 
+	// Once the lock server has revoked the node-level lock, no further grants are made
+	// (provided at least one grant was made since the node-level lock was acquired).
+	revokeBlocksGrant := track.revokePending && track.grantedSinceNodeAcquire
+
 	// At this point, the lock is either stale or shared
 	//
 	// Loop through Q and see if a request can be granted.  If it can then pop it off the Q.
@@ -197,6 +218,12 @@ func processLocalQ(track *localLockTrack) {
 			panic("Remove of elem failed!!!")
 		}
 
+		// If the node-level lock does not (yet) permit granting the request, leave it on the Q.
+		if track.distributed && (track.nodeOpInFlight || revokeBlocksGrant || !nodeLockCovers(track.nodeState, localQRequest.requestedState)) {
+			track.waitReqQ.PushFront(localQRequest)
+			return
+		}
+
 		// If the lock is already free and then want it exclusive
 		if (localQRequest.requestedState == exclusive) && (track.state == stale) {
 			grantAndSignal(track, localQRequest)
@@ -214,37 +241,49 @@ func processLocalQ(track *localLockTrack) {
 	}
 }
 
+// tryWouldBlock returns whether or not the lock could not be granted immediately to a TryWriteLock
+// or TryReadLock. This function assumes that the tracking mutex is held.
+func tryWouldBlock(track *localLockTrack, requestedState lockState) bool {
+	if requestedState == exclusive {
+		return track.state != stale
+	}
+	return track.state == exclusive
+}
+
 func (l *RWLockStruct) commonLock(requestedState lockState, try bool) (err error) {
 
 	globals.Lock()
 	track, ok := globals.localLockMap[l.LockID]
 	if !ok {
-		// TODO - handle blocking waiting for lock from DLM
-
 		// Lock does not exist in map, create one
 		track = &localLockTrack{lockId: l.LockID, state: stale}
 		track.waitReqQ = list.New()
+		track.distributed, track.volumeName = distributedVolumeWhileLocked(l.LockID)
+		if track.distributed {
+			track.nodeState = stale
+			track.ownerNotifyMap = make(map[CallerID]Notify)
+		}
 		globals.localLockMap[l.LockID] = track
 
 	}
 
 	track.Mutex.Lock()
-	defer track.Mutex.Unlock()
 
 	globals.Unlock()
 
+	if track.distributed {
+		// Blocking waiting for the node-level lock from the lock server is handled separately
+		err = l.commonDistributedLock(track, requestedState, try)
+		return
+	}
+
+	defer track.Mutex.Unlock()
+
 	// If we are doing a TryWriteLock or TryReadLock, see if we could
 	// grab the lock before putting on queue.
-	if try {
-		if (requestedState == exclusive) && (track.state != stale) {
-			err = errors.New("Lock is busy - try again!")
-			return blunder.AddError(err, blunder.TryAgainError)
-		} else {
-			if track.state == exclusive {
-				err = errors.New("Lock is busy - try again!")
-				return blunder.AddError(err, blunder.TryAgainError)
-			}
-		}
+	if try && tryWouldBlock(track, requestedState) {
+		err = errors.New("Lock is busy - try again!")
+		return blunder.AddError(err, blunder.TryAgainError)
 	}
 	localRequest := localLockRequest{requestedState: requestedState, LockCallerID: l.LockCallerID, wakeUp: false}
 	localRequest.Cond = sync.NewCond(&track.Mutex)
@@ -282,6 +321,12 @@ func (l *RWLockStruct) unlock() (err error) {
 
 	track.Mutex.Lock()
 
+	if track.distributed {
+		globals.Unlock()
+		l.distributedUnlock(track)
+		return nil
+	}
+
 	// Remove lock from localLockMap if no other thread using.
 	//
 	// We have track structure for lock.  While holding mutex on localLockMap, remove
@@ -0,0 +1,408 @@
+package dlm
+
+// The lock server of a Peer grants node-level locks for each Volume for which it is the
+// PrimaryPeer. A Peer must hold a node-level lock (shared or exclusive) on a LockID before its
+// LLM may grant the lock to any of its threads. Node-level locks are requested over a TCP
+// connection to the lock server's [DLM]TCPPort on its PrivateIPAddr... except that a Peer
+// requests node-level locks from its own lock server without going through TCP.
+//
+// When a node-level lock request conflicts with node-level locks already held, the lock server
+// sends a revokeOp to each conflicting Peer. Such Peers invoke Notify.NotifyNodeChange() for each
+// of their threads holding the lock and release their node-level lock once those threads have
+// unlocked it. Should a Peer's connection be lost, the node-level locks it held are released.
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// messageStruct is JSON-encoded on the TCP connection between a Peer and a lock server.
+type messageStruct struct {
+	Op        string       // One of helloOp, acquireOp, releaseOp, replyOp, or revokeOp
+	Seq       uint64       // Matches a replyOp to its acquireOp or releaseOp
+	PeerName  string       // helloOp only
+	LockID    string       //
+	Exclusive bool         // acquireOp only
+	Try       bool         // acquireOp only... if set, a lock that cannot be immediately granted is failed with errTryAgain
+	Reason    NotifyReason // revokeOp only
+	Err       string       // replyOp only... "" if successful
+}
+
+const (
+	helloOp   = "Hello"   // Peer -> lock server (first message on each connection)
+	acquireOp = "Acquire" // Peer -> lock server
+	releaseOp = "Release" // Peer -> lock server
+	replyOp   = "Reply"   // lock server -> Peer
+	revokeOp  = "Revoke"  // lock server -> Peer
+)
+
+const (
+	errTryAgain  = "TryAgain"
+	errNotServed = "NotServed"
+)
+
+// volumeNameFromLockID extracts <VolumeName> from a LockID of the form "vol.<VolumeName>:ino.<InodeNumber>"
+// (see InodeLockID()). Only such LockIDs are granted by a lock server.
+func volumeNameFromLockID(lockID string) (volumeName string, ok bool) {
+	if !strings.HasPrefix(lockID, "vol.") {
+		ok = false
+		return
+	}
+
+	inoIndex := strings.LastIndex(lockID, ":ino.")
+	if inoIndex < len("vol.") {
+		ok = false
+		return
+	}
+
+	volumeName = lockID[len("vol."):inoIndex]
+	ok = true
+	return
+}
+
+// sessionStruct tracks a Peer from the lock server's perspective. Messages to the Peer are
+// delivered, in order, by a goroutine so that the lock server never blocks sending them.
+type sessionStruct struct {
+	sync.Mutex
+	peerName  string
+	deliver   func(msg *messageStruct) (err error)
+	outboundQ *list.List // of *messageStruct
+	cond      *sync.Cond
+	closed    bool
+}
+
+func newSession(peerName string, deliver func(msg *messageStruct) (err error)) (session *sessionStruct) {
+	session = &sessionStruct{
+		peerName:  peerName,
+		deliver:   deliver,
+		outboundQ: list.New(),
+		closed:    false,
+	}
+
+	session.cond = sync.NewCond(&session.Mutex)
+
+	go session.sender()
+
+	return
+}
+
+func (session *sessionStruct) send(msg *messageStruct) {
+	session.Lock()
+	if !session.closed {
+		session.outboundQ.PushBack(msg)
+		session.cond.Signal()
+	}
+	session.Unlock()
+}
+
+func (session *sessionStruct) sender() {
+	for {
+		session.Lock()
+		for (0 == session.outboundQ.Len()) && !session.closed {
+			session.cond.Wait()
+		}
+		if session.closed {
+			session.Unlock()
+			return
+		}
+		msg := session.outboundQ.Remove(session.outboundQ.Front()).(*messageStruct)
+		session.Unlock()
+
+		err := session.deliver(msg)
+		if nil != err {
+			logger.Warnf("dlm lock server unable to send %v to Peer %v: %v", msg.Op, session.peerName, err)
+		}
+	}
+}
+
+func (session *sessionStruct) close() {
+	session.Lock()
+	session.closed = true
+	session.cond.Broadcast()
+	session.Unlock()
+}
+
+type serverRequestStruct struct {
+	session   *sessionStruct
+	seq       uint64
+	exclusive bool
+}
+
+type serverLockStruct struct {
+	exclusive bool                        // Only meaningful if 0 < len(holders)
+	holders   map[*sessionStruct]struct{} // Peers holding the node-level lock
+	revoked   map[*sessionStruct]struct{} // Subset of holders already sent a revokeOp
+	waitQ     *list.List                  // of *serverRequestStruct
+}
+
+type lockServerStruct struct {
+	sync.Mutex
+	volumeSet  map[string]struct{}          // Volumes for which node-level locks are granted
+	lockMap    map[string]*serverLockStruct // Key == LockID
+	listener   net.Listener
+	netConnSet map[net.Conn]struct{}
+	wg         sync.WaitGroup
+}
+
+// newLockServer starts a lock server listening on tcpAddr. While Up() only ever constructs one,
+// tests construct additional ones (standing in for other Peers) in the same process.
+func newLockServer(tcpAddr string) (server *lockServerStruct, err error) {
+	server = &lockServerStruct{
+		volumeSet:  make(map[string]struct{}),
+		lockMap:    make(map[string]*serverLockStruct),
+		netConnSet: make(map[net.Conn]struct{}),
+	}
+
+	server.listener, err = net.Listen("tcp", tcpAddr)
+	if nil != err {
+		err = fmt.Errorf("net.Listen(\"tcp\", %v) failed: %v", tcpAddr, err)
+		return
+	}
+
+	server.wg.Add(1)
+	go server.accept()
+
+	err = nil
+	return
+}
+
+func (server *lockServerStruct) stop() {
+	_ = server.listener.Close()
+
+	server.Lock()
+	for netConn := range server.netConnSet {
+		_ = netConn.Close()
+	}
+	server.Unlock()
+
+	server.wg.Wait()
+}
+
+// setVolumes replaces the set of Volumes for which node-level locks are granted. The node-level
+// locks of Volumes no longer being served are forgotten and their waiters failed with errNotServed.
+func (server *lockServerStruct) setVolumes(volumeList []string) {
+	server.Lock()
+	defer server.Unlock()
+
+	server.volumeSet = make(map[string]struct{})
+	for _, volumeName := range volumeList {
+		server.volumeSet[volumeName] = struct{}{}
+	}
+
+	for lockID, lock := range server.lockMap {
+		volumeName, _ := volumeNameFromLockID(lockID)
+		_, ok := server.volumeSet[volumeName]
+		if ok {
+			continue
+		}
+		for elem := lock.waitQ.Front(); nil != elem; elem = elem.Next() {
+			request := elem.Value.(*serverRequestStruct)
+			request.session.send(&messageStruct{Op: replyOp, Seq: request.seq, LockID: lockID, Err: errNotServed})
+		}
+		delete(server.lockMap, lockID)
+	}
+}
+
+func (server *lockServerStruct) accept() {
+	defer server.wg.Done()
+
+	for {
+		netConn, err := server.listener.Accept()
+		if nil != err {
+			return // server.stop() closed the listener
+		}
+
+		server.Lock()
+		server.netConnSet[netConn] = struct{}{}
+		server.Unlock()
+
+		server.wg.Add(1)
+		go server.serveConn(netConn)
+	}
+}
+
+func (server *lockServerStruct) serveConn(netConn net.Conn) {
+	var (
+		decoder *json.Decoder
+		encoder *json.Encoder
+		err     error
+		msg     messageStruct
+		session *sessionStruct
+	)
+
+	defer server.wg.Done()
+
+	decoder = json.NewDecoder(netConn)
+	encoder = json.NewEncoder(netConn)
+
+	err = decoder.Decode(&msg)
+	if (nil != err) || (helloOp != msg.Op) {
+		logger.Warnf("dlm lock server dropping connection from %v lacking %v", netConn.RemoteAddr(), helloOp)
+		server.closeConn(netConn)
+		return
+	}
+
+	session = newSession(msg.PeerName, func(msg *messageStruct) (err error) { return encoder.Encode(msg) })
+
+	for {
+		msg = messageStruct{}
+		err = decoder.Decode(&msg)
+		if nil != err {
+			break
+		}
+		server.handleMessage(session, &msg)
+	}
+
+	server.dropSession(session)
+	session.close()
+	server.closeConn(netConn)
+}
+
+func (server *lockServerStruct) closeConn(netConn net.Conn) {
+	server.Lock()
+	delete(server.netConnSet, netConn)
+	server.Unlock()
+
+	_ = netConn.Close()
+}
+
+// handleMessage processes an acquireOp or releaseOp from session. Replies are sent via session.send().
+func (server *lockServerStruct) handleMessage(session *sessionStruct, msg *messageStruct) {
+	server.Lock()
+	defer server.Unlock()
+
+	switch msg.Op {
+	case acquireOp:
+		server.acquireWhileLocked(session, msg)
+	case releaseOp:
+		lock, ok := server.lockMap[msg.LockID]
+		if ok {
+			_, ok = lock.holders[session]
+			if ok {
+				delete(lock.holders, session)
+				delete(lock.revoked, session)
+				server.processWaitQWhileLocked(msg.LockID, lock)
+			}
+		}
+		session.send(&messageStruct{Op: replyOp, Seq: msg.Seq, LockID: msg.LockID})
+	default:
+		logger.Warnf("dlm lock server ignoring unexpected %v from Peer %v", msg.Op, session.peerName)
+	}
+}
+
+func (server *lockServerStruct) acquireWhileLocked(session *sessionStruct, msg *messageStruct) {
+	volumeName, ok := volumeNameFromLockID(msg.LockID)
+	if ok {
+		_, ok = server.volumeSet[volumeName]
+	}
+	if !ok {
+		session.send(&messageStruct{Op: replyOp, Seq: msg.Seq, LockID: msg.LockID, Err: errNotServed})
+		return
+	}
+
+	lock, ok := server.lockMap[msg.LockID]
+	if !ok {
+		lock = &serverLockStruct{
+			holders: make(map[*sessionStruct]struct{}),
+			revoked: make(map[*sessionStruct]struct{}),
+			waitQ:   list.New(),
+		}
+		server.lockMap[msg.LockID] = lock
+	}
+
+	_, ok = lock.holders[session]
+	if ok {
+		if lock.exclusive || !msg.Exclusive {
+			session.send(&messageStruct{Op: replyOp, Seq: msg.Seq, LockID: msg.LockID})
+			return
+		}
+
+		// Upgrading from shared to exclusive is treated as a release followed by an acquire
+
+		delete(lock.holders, session)
+		delete(lock.revoked, session)
+	}
+
+	if msg.Try && ((0 < lock.waitQ.Len()) || !lock.grantable(msg.Exclusive)) {
+		session.send(&messageStruct{Op: replyOp, Seq: msg.Seq, LockID: msg.LockID, Err: errTryAgain})
+		server.processWaitQWhileLocked(msg.LockID, lock)
+		return
+	}
+
+	lock.waitQ.PushBack(&serverRequestStruct{session: session, seq: msg.Seq, exclusive: msg.Exclusive})
+
+	server.processWaitQWhileLocked(msg.LockID, lock)
+}
+
+func (lock *serverLockStruct) grantable(exclusive bool) bool {
+	return (0 == len(lock.holders)) || (!lock.exclusive && !exclusive)
+}
+
+// processWaitQWhileLocked grants node-level locks to waiting requests (in order) until one
+// cannot be granted. The holders conflicting with that request are then sent a revokeOp.
+func (server *lockServerStruct) processWaitQWhileLocked(lockID string, lock *serverLockStruct) {
+	for 0 < lock.waitQ.Len() {
+		request := lock.waitQ.Front().Value.(*serverRequestStruct)
+
+		if !lock.grantable(request.exclusive) {
+			var reason NotifyReason
+			if request.exclusive {
+				reason = ReasonWriteRequest
+			} else {
+				reason = ReasonReadRequest
+			}
+			for holder := range lock.holders {
+				_, ok := lock.revoked[holder]
+				if ok {
+					continue
+				}
+				lock.revoked[holder] = struct{}{}
+				holder.send(&messageStruct{Op: revokeOp, LockID: lockID, Reason: reason})
+				stats.IncrementOperations(&stats.DLMServerRevokeOps)
+			}
+			return
+		}
+
+		lock.waitQ.Remove(lock.waitQ.Front())
+		lock.exclusive = request.exclusive
+		lock.holders[request.session] = struct{}{}
+		request.session.send(&messageStruct{Op: replyOp, Seq: request.seq, LockID: lockID})
+		stats.IncrementOperations(&stats.DLMServerGrantOps)
+	}
+
+	if 0 == len(lock.holders) {
+		delete(server.lockMap, lockID)
+	}
+}
+
+// dropSession releases the node-level locks held (or waited for) by a departed Peer.
+func (server *lockServerStruct) dropSession(session *sessionStruct) {
+	server.Lock()
+	defer server.Unlock()
+
+	for lockID, lock := range server.lockMap {
+		delete(lock.holders, session)
+		delete(lock.revoked, session)
+
+		elem := lock.waitQ.Front()
+		for nil != elem {
+			nextElem := elem.Next()
+			if session == elem.Value.(*serverRequestStruct).session {
+				lock.waitQ.Remove(elem)
+			}
+			elem = nextElem
+		}
+
+		server.processWaitQWhileLocked(lockID, lock)
+	}
+
+	stats.IncrementOperations(&stats.DLMServerSessionDroppedOps)
+	logger.Infof("dlm lock server dropped session of Peer %v", session.peerName)
+}
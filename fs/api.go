@@ -5,6 +5,8 @@ package fs
 import "C"
 
 import (
+	"time"
+
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/utils"
@@ -50,6 +52,33 @@ type HeadResponse struct {
 	NumWrites        uint64
 }
 
+// FSCKProblem classifies an FSCKFinding
+type FSCKProblem string
+
+const (
+	FSCKProblemCorruptInode        FSCKProblem = "corrupt inode"            // inode.Validate() failed
+	FSCKProblemUnreadableDirectory FSCKProblem = "unreadable directory"     // directory entries could not be read... so were not walked
+	FSCKProblemDanglingDirEntry    FSCKProblem = "dangling directory entry" // directory entry references an inode that cannot be fetched
+	FSCKProblemLinkCountMismatch   FSCKProblem = "link count mismatch"      // LinkCount differs from the number of directory entries referencing the inode
+)
+
+// FSCKFinding describes a single problem found by ValidateVolume
+type FSCKFinding struct {
+	InodeNumber inode.InodeNumber
+	Path        string // path by which the inode was (first) reached
+	Problem     FSCKProblem
+	Detail      string
+	Action      string // taken (if Repaired) or proposed
+	Repaired    bool
+}
+
+// Returned by ValidateVolume
+type FSCKReport struct {
+	Repair          bool   // if false, a dry run (nothing was repaired)
+	InodesValidated uint64 // distinct inodes reached by the tree walk
+	Findings        []FSCKFinding
+}
+
 // The following constants are used to ensure that the length of file fullpath and basenames are POSIX-compliant
 const (
 	FilePathMax = C.PATH_MAX
@@ -76,23 +105,22 @@ const (
 	FsOptimalTransferSize = 64 * KiloByte
 )
 
-// XXX TBD: For now we are cooking up these numbers...
+// Swift imposes no limit on a volume's size nor its number of inodes. Absent a configured
+// [Volume:<VolumeName>]Capacity, StatVfs reports this much free space beyond what is in use.
+// Absent a configured [Volume:<VolumeName>]QuotaHardInodes, StatVfs reports this many inodes
+// in total (of which those not in use are free).
 const (
-	VolFakeTotalBlocks = TeraByte / FsBlockSize
-	VolFakeFreeBlocks  = TeraByte / FsBlockSize
-	VolFakeAvailBlocks = TeraByte / FsBlockSize
-
-	VolFakeTotalInodes = TeraByte
-	VolFakeFreeInodes  = TeraByte
-	VolFakeAvailInodes = TeraByte
+	VolUnboundedFreeBlocks  = TeraByte / FsBlockSize
+	VolUnboundedTotalInodes = TeraByte
 )
 
 type FlockStruct struct {
-	Type   int32
-	Whence int32
-	Start  uint64
-	Len    uint64
-	Pid    uint64
+	Type    int32
+	Whence  int32
+	Start   uint64
+	Len     uint64
+	Pid     uint64
+	mountID MountID // Mount via which the lock was set (Pids are only unique within a mount)
 }
 
 type MountOptions uint64
@@ -101,6 +129,10 @@ const (
 	MountReadOnly MountOptions = 1 << iota
 )
 
+// SnapshotNameSeparator separates a volume name from the name of one of its snapshots. Passing
+// "<volumeName>@<snapshotName>" to Mount() mounts the snapshot... which requires MountReadOnly.
+const SnapshotNameSeparator = "@"
+
 type StatKey uint64
 
 const (
@@ -128,17 +160,23 @@ type Stat map[StatKey]uint64 // key is one of StatKey consts
 type StatVFSKey uint64
 
 const (
-	StatVFSBlockSize      StatVFSKey = iota + 1 // statvfs.f_bsize - Filesystem block size
-	StatVFSFragmentSize                         // statvfs.f_frsize - Filesystem fragment size, smallest addressable data size in the filesystem
-	StatVFSTotalBlocks                          // statvfs.f_blocks - Filesystem size in StatVFSFragmentSize units
-	StatVFSFreeBlocks                           // statvfs.f_bfree - number of free blocks
-	StatVFSAvailBlocks                          // statvfs.f_bavail - number of free blocks for unprivileged users
-	StatVFSTotalInodes                          // statvfs.f_files - number of inodes in the filesystem
-	StatVFSFreeInodes                           // statvfs.f_ffree - number of free inodes in the filesystem
-	StatVFSAvailInodes                          // statvfs.f_favail - number of free inodes for unprivileged users
-	StatVFSFilesystemID                         // statvfs.f_fsid  - Our filesystem ID
-	StatVFSMountFlags                           // statvfs.f_flag  - mount flags
-	StatVFSMaxFilenameLen                       // statvfs.f_namemax - maximum filename length
+	StatVFSBlockSize       StatVFSKey = iota + 1 // statvfs.f_bsize - Filesystem block size
+	StatVFSFragmentSize                          // statvfs.f_frsize - Filesystem fragment size, smallest addressable data size in the filesystem
+	StatVFSTotalBlocks                           // statvfs.f_blocks - Filesystem size in StatVFSFragmentSize units
+	StatVFSFreeBlocks                            // statvfs.f_bfree - number of free blocks
+	StatVFSAvailBlocks                           // statvfs.f_bavail - number of free blocks for unprivileged users
+	StatVFSTotalInodes                           // statvfs.f_files - number of inodes in the filesystem
+	StatVFSFreeInodes                            // statvfs.f_ffree - number of free inodes in the filesystem
+	StatVFSAvailInodes                           // statvfs.f_favail - number of free inodes for unprivileged users
+	StatVFSFilesystemID                          // statvfs.f_fsid  - Our filesystem ID
+	StatVFSMountFlags                            // statvfs.f_flag  - mount flags
+	StatVFSMaxFilenameLen                        // statvfs.f_namemax - maximum filename length
+	StatVFSLogSegmentBytes                       // (no statvfs equivalent) bytes held in live log segments
+	StatVFSReferencedBytes                       // (no statvfs equivalent) bytes of log segments referenced by file inodes
+)
+
+const (
+	StatVFSMountFlagReadOnly = uint64(1) // statvfs.f_flag ST_RDONLY - mount (or volume) is read-only
 )
 
 type StatVFS map[StatVFSKey]uint64 // key is one of StatVFSKey consts
@@ -156,7 +194,9 @@ type MountHandle interface {
 	Create(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string, filePerm inode.InodeMode) (fileInodeNumber inode.InodeNumber, err error)
 	Flush(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber) (err error)
 	Flock(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, lockCmd int32, inFlockStruct *FlockStruct) (outFlockStruct *FlockStruct, err error)
+	FlockWait(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, inFlockStruct *FlockStruct, timeout time.Duration, stopChan chan bool) (outFlockStruct *FlockStruct, err error)
 	Getstat(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber) (stat Stat, err error)
+	GetQuota(userID inode.InodeUserID, groupID inode.InodeGroupID) (userQuota inode.OwnerQuotaStatus, groupQuota inode.OwnerQuotaStatus, err error)
 	GetType(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber) (inodeType inode.InodeType, err error)
 	GetXAttr(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, streamName string) (value []byte, err error)
 	IsDir(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber) (inodeIsDir bool, err error)
@@ -170,7 +210,7 @@ type MountHandle interface {
 	MiddlewareDelete(parentDir string, baseName string) (err error)
 	MiddlewareGetAccount(maxEntries uint64, marker string) (accountEnts []AccountEntry, mtime uint64, err error)
 	MiddlewareGetContainer(vContainerName string, maxEntries uint64, marker string, prefix string) (containerEnts []ContainerEntry, err error)
-	MiddlewareGetObject(volumeName string, containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, ino uint64, numWrites uint64, serializedMetadata []byte, err error)
+	MiddlewareGetObject(volumeName string, containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, ino uint64, numWrites uint64, serializedMetadata []byte, leaseID string, err error)
 	MiddlewareHeadResponse(entityPath string) (response HeadResponse, err error)
 	MiddlewarePost(parentDir string, baseName string, newMetaData []byte, oldMetaData []byte) (err error)
 	MiddlewareMkdir(vContainerName string, vObjectPath string, metadata []byte) (mtime uint64, inodeNumber inode.InodeNumber, numWrites uint64, err error)
@@ -192,6 +232,10 @@ type MountHandle interface {
 	StatVfs() (statVFS StatVFS, err error)
 	Symlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string, target string) (symlinkInodeNumber inode.InodeNumber, err error)
 	Unlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string) (err error)
+
+	// Unmount() flushes in-flight file data, releases all locks set via the mount (interrupting
+	// any F_SETLKW's it has waiting), and invalidates the mount.
+	Unmount() (err error)
 	Validate(inodeNumber inode.InodeNumber) (err error)
 	VolumeName() (volumeName string)
 	Write(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, buf []byte, profiler *utils.Profiler) (size uint64, err error)
@@ -209,9 +253,15 @@ func ValidateFullPath(fullPath string) (err error) {
 	return
 }
 
-func ValidateVolume(volumeName string, stopChan chan bool, errChan chan error) {
+// ValidateVolume walks the volume's directory tree from the root validating each inode reached and then
+// comparing each inode's LinkCount to the number of directory entries found referencing it. Every problem
+// encountered is described in fsckReport. Only if repair is set are LinkCounts corrected... and then only
+// if the entire tree could be walked. A non-nil err indicates the walk could not be completed (or that
+// problems were found). Should stopChan be signaled, the (partial) fsckReport is returned with err == nil.
+func ValidateVolume(volumeName string, repair bool, stopChan chan bool) (fsckReport *FSCKReport, err error) {
 	stats.IncrementOperations(&stats.FsVolumeValidateOps)
-	errChan <- validateVolume(volumeName, stopChan)
+	fsckReport, err = validateVolume(volumeName, repair, stopChan)
+	return
 }
 
 func AccountNameToVolumeName(accountName string) (volumeName string, ok bool) {
@@ -225,3 +275,17 @@ func VolumeNameToActivePeerPrivateIPAddr(volumeName string) (activePeerPrivateIP
 	stats.IncrementOperations(&stats.FsVolumeToActivePeerOps)
 	return
 }
+
+// RenewLease extends the lease returned by MiddlewareGetObject() on the log segments in its read plan.
+func RenewLease(leaseID string) (err error) {
+	err = inode.RenewLease(leaseID)
+	stats.IncrementOperations(&stats.FsMwRenewLeaseOps)
+	return
+}
+
+// ReleaseLease drops the lease returned by MiddlewareGetObject() on the log segments in its read plan.
+func ReleaseLease(leaseID string) (err error) {
+	err = inode.ReleaseLease(leaseID)
+	stats.IncrementOperations(&stats.FsMwReleaseLeaseOps)
+	return
+}
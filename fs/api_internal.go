@@ -223,11 +223,12 @@ func mount(volumeName string, mountOptions MountOptions) (mountHandle MountHandl
 	globals.Lock()
 
 	volStruct, ok = globals.volumeMap[volumeName]
-	if !ok {
-		err = fmt.Errorf("Unknown volumeName passed to mount(): \"%s\"", volumeName)
-		err = blunder.AddError(err, blunder.NotFoundError)
-		globals.Unlock()
-		return
+	if !ok || ("" != volStruct.snapshotOf) {
+		volStruct, err = fetchSnapshotVolumeWhileLocked(volumeName, mountOptions)
+		if nil != err {
+			globals.Unlock()
+			return
+		}
 	}
 
 	globals.lastMountID++
@@ -236,6 +237,7 @@ func mount(volumeName string, mountOptions MountOptions) (mountHandle MountHandl
 		id:        globals.lastMountID,
 		options:   mountOptions,
 		volStruct: volStruct,
+		flockMap:  make(map[inode.InodeNumber]bool),
 	}
 
 	globals.mountMap[mS.id] = mS
@@ -252,18 +254,153 @@ func mount(volumeName string, mountOptions MountOptions) (mountHandle MountHandl
 	return
 }
 
+func (mS *mountStruct) Unmount() (err error) {
+	var (
+		mountIndex int
+		ok         bool
+	)
+
+	globals.Lock()
+
+	_, ok = globals.mountMap[mS.id]
+	if !ok {
+		globals.Unlock()
+		err = fmt.Errorf("MountID %v already unmounted", mS.id)
+		err = blunder.AddError(err, blunder.BadMountIDError)
+		return
+	}
+
+	delete(globals.mountMap, mS.id)
+
+	mS.volStruct.Lock()
+	for mountIndex = range mS.volStruct.mountList {
+		if mS.id == mS.volStruct.mountList[mountIndex] {
+			mS.volStruct.mountList = append(mS.volStruct.mountList[:mountIndex], mS.volStruct.mountList[mountIndex+1:]...)
+			break
+		}
+	}
+	mS.volStruct.Unlock()
+
+	globals.Unlock()
+
+	// In-flight file data is tracked per volume (not per mount)... so flush all of it
+
+	mS.volStruct.untrackInFlightFileInodeDataAll()
+
+	mS.flockUnlockAll()
+
+	stats.IncrementOperations(&stats.FsUnmountOps)
+
+	err = nil
+	return
+}
+
+// fetchSnapshotVolumeWhileLocked returns the volumeStruct to mount for a snapshotVolumeName of the form
+// "<volumeName>@<snapshotName>" (see SnapshotNameSeparator). The caller must hold globals.Lock().
+func fetchSnapshotVolumeWhileLocked(snapshotVolumeName string, mountOptions MountOptions) (volStruct *volumeStruct, err error) {
+	var (
+		baseVolStruct        *volumeStruct
+		ok                   bool
+		separatorIndex       int
+		snapshotName         string
+		snapshotVolumeHandle inode.VolumeHandle
+	)
+
+	separatorIndex = strings.LastIndex(snapshotVolumeName, SnapshotNameSeparator)
+	if 0 <= separatorIndex {
+		baseVolStruct, ok = globals.volumeMap[snapshotVolumeName[:separatorIndex]]
+		ok = ok && ("" == baseVolStruct.snapshotOf)
+	}
+	if !ok {
+		err = fmt.Errorf("Unknown volumeName passed to mount(): \"%s\"", snapshotVolumeName)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	if 0 == (mountOptions & MountReadOnly) {
+		err = fmt.Errorf("Snapshot \"%s\" may only be mounted with MountReadOnly", snapshotVolumeName)
+		err = blunder.AddError(err, blunder.ReadOnlyError)
+		return
+	}
+
+	snapshotName = snapshotVolumeName[separatorIndex+len(SnapshotNameSeparator):]
+
+	snapshotVolumeHandle, err = baseVolStruct.VolumeHandle.FetchSnapshotVolumeHandle(snapshotName)
+	if nil != err {
+		return
+	}
+
+	// Reuse any volumeStruct from a prior mount unless the snapshot has since been deleted (and perhaps re-created)
+
+	volStruct, ok = globals.volumeMap[snapshotVolumeName]
+	if ok && (snapshotVolumeHandle == volStruct.VolumeHandle) {
+		err = nil
+		return
+	}
+
+	volStruct = &volumeStruct{
+		volumeName:               snapshotVolumeName,
+		doCheckpointPerFlush:     false,
+		maxFlushTime:             baseVolStruct.maxFlushTime,
+		FLockMap:                 make(map[inode.InodeNumber]*list.List),
+		FLockWaitMap:             make(map[inode.InodeNumber]*list.List),
+		flockWaiterMap:           make(map[flockOwnerStruct]*flockWaiterStruct),
+		inFlightFileInodeDataMap: make(map[inode.InodeNumber]*inFlightFileInodeDataStruct),
+		mountList:                make([]MountID, 0),
+		readOnly:                 true,
+		snapshotOf:               baseVolStruct.volumeName,
+		VolumeHandle:             snapshotVolumeHandle,
+	}
+
+	globals.volumeMap[snapshotVolumeName] = volStruct
+
+	err = nil
+	return
+}
+
+// isReadOnly reports whether either this mount or its volume is read-only.
+func (mS *mountStruct) isReadOnly() (readOnly bool) {
+	mS.volStruct.Lock()
+	readOnly = (0 != (mS.options & MountReadOnly)) || mS.volStruct.readOnly
+	mS.volStruct.Unlock()
+
+	return
+}
+
+// checkWritable returns a blunder.ReadOnlyError if either this mount or its volume is read-only.
+func (mS *mountStruct) checkWritable() (err error) {
+	if mS.isReadOnly() {
+		stats.IncrementOperations(&stats.FsReadOnlyRejectedOps)
+		err = blunder.NewError(blunder.ReadOnlyError, "EROFS")
+		return
+	}
+
+	err = nil
+	return
+}
+
 func (mS *mountStruct) Access(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, accessMode inode.InodeMode) (accessReturn bool) {
 	accessReturn = mS.volStruct.VolumeHandle.Access(inodeNumber, userID, groupID, otherGroupIDs, accessMode)
 	return
 }
 
 func (mS *mountStruct) CallInodeToProvisionObject() (pPath string, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	pPath, err = mS.volStruct.VolumeHandle.ProvisionObject()
 	stats.IncrementOperations(&stats.FsProvisionObjOps)
 	return
 }
 
 func (mS *mountStruct) Create(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string, filePerm inode.InodeMode) (fileInodeNumber inode.InodeNumber, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	err = validateBaseName(basename)
 	if err != nil {
 		return 0, err
@@ -287,11 +424,18 @@ func (mS *mountStruct) Create(userID inode.InodeUserID, groupID inode.InodeGroup
 		return 0, blunder.NewError(blunder.PermDeniedError, "EACCES")
 	}
 
+	reservation, err := mS.volStruct.VolumeHandle.CheckQuota(userID, groupID, 0, 1)
+	if err != nil {
+		return 0, err
+	}
+
 	// create the file and add it to the directory
 	fileInodeNumber, err = mS.volStruct.VolumeHandle.CreateFile(filePerm, userID, groupID)
 	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
 		return 0, err
 	}
+	mS.volStruct.VolumeHandle.SettleQuotaReservation(fileInodeNumber, reservation)
 
 	err = mS.volStruct.VolumeHandle.Link(dirInodeNumber, basename, fileInodeNumber)
 	if err != nil {
@@ -324,6 +468,11 @@ func (mS *mountStruct) doInlineCheckpointIfEnabled() {
 
 	err = mS.headhunterVolumeHandle.DoCheckpoint()
 	if nil != err {
+		if blunder.Is(err, blunder.NotActiveError) {
+			// Volume fenced by another Peer... it will be relinquished once reconfigured
+			logger.Warnf("fs.doInlineCheckpoint() call to headhunter.DoCheckpoint() refused: %v", err)
+			return
+		}
 		logger.Fatalf("fs.doInlineCheckpoint() call to headhunter.DoCheckpoint() failed: %v", err)
 	}
 }
@@ -368,10 +517,20 @@ func (mS *mountStruct) getFileLockList(inodeNumber inode.InodeNumber) (flockList
 	return
 }
 
-// Check for lock conflict with other Pids, if there is a conflict then it will return the first occurance of conflicting range.
+// flockOwnerStruct identifies the holder of a lock... a Pid of a particular mount
+type flockOwnerStruct struct {
+	mountID MountID
+	pid     uint64
+}
+
+func (flock *FlockStruct) owner() flockOwnerStruct {
+	return flockOwnerStruct{mountID: flock.mountID, pid: flock.Pid}
+}
+
+// Check for lock conflict with other owners, if there is a conflict then it will return the first occurance of conflicting range.
 func checkConflict(elm *FlockStruct, flock *FlockStruct) bool {
 
-	if flock.Pid == elm.Pid {
+	if flock.owner() == elm.owner() {
 		return false
 	}
 
@@ -439,7 +598,7 @@ func (mS *mountStruct) fileLockInsert(inodeNumber inode.InodeNumber, inFlock *Fl
 			return
 		}
 
-		if elm.Pid == inFlock.Pid {
+		if elm.owner() == inFlock.owner() {
 			overlapList.PushBack(e)
 		}
 	}
@@ -447,7 +606,7 @@ func (mS *mountStruct) fileLockInsert(inodeNumber inode.InodeNumber, inFlock *Fl
 	if overlapList.Len() == 0 {
 		if beforeElm != nil {
 			elm := beforeElm.Value.(*FlockStruct)
-			if elm.Pid == inFlock.Pid && elm.Type == inFlock.Type && (elm.Start+elm.Len) == inFlock.Start {
+			if elm.owner() == inFlock.owner() && elm.Type == inFlock.Type && (elm.Start+elm.Len) == inFlock.Start {
 				elm.Len = inFlock.Start + inFlock.Len - elm.Len
 			} else {
 				flockList.InsertAfter(inFlock, beforeElm)
@@ -478,14 +637,14 @@ func (mS *mountStruct) fileLockInsert(inodeNumber inode.InodeNumber, inFlock *Fl
 	// First adjust the after:
 	if afterElm != nil {
 		elm := afterElm.Value.(*FlockStruct)
-		if elm.Pid == inFlock.Pid && elm.Type == inFlock.Type && (inFlock.Start+inFlock.Len) == elm.Start {
+		if elm.owner() == inFlock.owner() && elm.Type == inFlock.Type && (inFlock.Start+inFlock.Len) == elm.Start {
 			// We can collapse the entry:
 			elm.Len = elm.Start + elm.Len - inFlock.Start
 			elm.Start = inFlock.Start
 
 			if beforeElm != nil {
 				belm := beforeElm.Value.(*FlockStruct)
-				if belm.Pid == elm.Pid && belm.Type == elm.Type && (belm.Start+belm.Len) == elm.Start {
+				if belm.owner() == elm.owner() && belm.Type == elm.Type && (belm.Start+belm.Len) == elm.Start {
 					belm.Len = elm.Start + elm.Len - belm.Start
 					flockList.Remove(afterElm)
 				}
@@ -497,7 +656,7 @@ func (mS *mountStruct) fileLockInsert(inodeNumber inode.InodeNumber, inFlock *Fl
 
 	if beforeElm != nil {
 		belm := beforeElm.Value.(*FlockStruct)
-		if belm.Pid == inFlock.Pid && belm.Type == inFlock.Type && (belm.Start+belm.Len) == inFlock.Start {
+		if belm.owner() == inFlock.owner() && belm.Type == inFlock.Type && (belm.Start+belm.Len) == inFlock.Start {
 			belm.Len = inFlock.Start + inFlock.Len - belm.Start
 		}
 
@@ -515,7 +674,7 @@ func (mS *mountStruct) fileLockInsert(inodeNumber inode.InodeNumber, inFlock *Fl
 
 }
 
-// Unlock a given range. All locks held in this range by the process (indentified by Pid) are removed.
+// Unlock a given range. All locks held in this range by the process (indentified by mount & Pid) are removed.
 func (mS *mountStruct) fileUnlock(inodeNumber inode.InodeNumber, inFlock *FlockStruct) (err error) {
 
 	flockList := mS.getFileLockList(inodeNumber)
@@ -532,7 +691,7 @@ func (mS *mountStruct) fileUnlock(inodeNumber inode.InodeNumber, inFlock *FlockS
 	for e := flockList.Front(); e != nil; e = e.Next() {
 		elm := e.Value.(*FlockStruct)
 
-		if elm.Pid != inFlock.Pid {
+		if elm.owner() != inFlock.owner() {
 			continue
 		}
 
@@ -571,6 +730,7 @@ func (mS *mountStruct) fileUnlock(inodeNumber inode.InodeNumber, inFlock *FlockS
 			elmTail.Start = start + len
 			elmTail.Len = elmLen - elm.Start
 			elmTail.Pid = elm.Pid
+			elmTail.mountID = elm.mountID
 			elmTail.Type = elm.Type
 			elmTail.Whence = elm.Whence
 			flockList.InsertAfter(elmTail, e)
@@ -586,16 +746,267 @@ func (mS *mountStruct) fileUnlock(inodeNumber inode.InodeNumber, inFlock *FlockS
 	return
 }
 
-// Implements file locking conforming to fcntl(2) locking description. F_SETLKW is not implemented. Supports F_SETLW and F_GETLW.
+// flockWaiterStruct tracks an F_SETLKW blocked on a conflicting lock. Each is enqueued on its inode's
+// FLockWaitMap list (and in flockWaiterMap by owner) while blocked and signaled via wakeChan whenever a
+// lock on that inode is released or changed (at which point the F_SETLKW is re-attempted).
+type flockWaiterStruct struct {
+	mountID     MountID
+	inodeNumber inode.InodeNumber
+	flock       *FlockStruct
+	element     *list.Element // in FLockWaitMap[inodeNumber]
+	wakeChan    chan bool     // buffered (1) so signalers need not block... true == re-attempt, false == give up
+}
+
+// flockEnqueueWaiter adds an F_SETLKW (via mountID) for flock to inodeNumber's wait queue. Caller must hold vS.FLockMutex.
+func (vS *volumeStruct) flockEnqueueWaiter(mountID MountID, inodeNumber inode.InodeNumber, flock *FlockStruct) (waiter *flockWaiterStruct) {
+	waitList, ok := vS.FLockWaitMap[inodeNumber]
+	if !ok {
+		waitList = new(list.List)
+		vS.FLockWaitMap[inodeNumber] = waitList
+	}
+
+	waiter = &flockWaiterStruct{
+		mountID:     mountID,
+		inodeNumber: inodeNumber,
+		flock:       flock,
+		wakeChan:    make(chan bool, 1),
+	}
+
+	waiter.element = waitList.PushBack(waiter)
+	vS.flockWaiterMap[flock.owner()] = waiter
+
+	return
+}
+
+// flockDequeueWaiter removes waiter from its inode's wait queue (if it has not already been removed)
+func (vS *volumeStruct) flockDequeueWaiter(waiter *flockWaiterStruct) {
+	vS.FLockMutex.Lock()
+	defer vS.FLockMutex.Unlock()
+
+	waitList, ok := vS.FLockWaitMap[waiter.inodeNumber]
+	if ok && (nil != waiter.element) {
+		waitList.Remove(waiter.element)
+		waiter.element = nil
+		if 0 == waitList.Len() {
+			delete(vS.FLockWaitMap, waiter.inodeNumber)
+		}
+	}
+
+	if vS.flockWaiterMap[waiter.flock.owner()] == waiter {
+		delete(vS.flockWaiterMap, waiter.flock.owner())
+	}
+}
+
+// flockWakeWaiters signals each F_SETLKW blocked on inodeNumber to re-attempt. Caller must hold vS.FLockMutex.
+func (vS *volumeStruct) flockWakeWaiters(inodeNumber inode.InodeNumber) {
+	waitList, ok := vS.FLockWaitMap[inodeNumber]
+	if !ok {
+		return
+	}
+
+	for e := waitList.Front(); e != nil; e = e.Next() {
+		waiter := e.Value.(*flockWaiterStruct)
+		select {
+		case waiter.wakeChan <- true:
+		default: // already signaled
+		}
+	}
+}
+
+// flockInterruptWaitersAll signals each F_SETLKW blocked on any inode of the volume to give up
+func (vS *volumeStruct) flockInterruptWaitersAll() {
+	vS.FLockMutex.Lock()
+	defer vS.FLockMutex.Unlock()
+
+	for _, waitList := range vS.FLockWaitMap {
+		for e := waitList.Front(); e != nil; e = e.Next() {
+			e.Value.(*flockWaiterStruct).interrupt()
+		}
+	}
+}
+
+// interrupt signals waiter to give up. Caller must hold vS.FLockMutex.
+func (waiter *flockWaiterStruct) interrupt() {
+	select {
+	case <-waiter.wakeChan: // discard any pending re-attempt signal
+	default:
+	}
+	waiter.wakeChan <- false
+}
+
+// flockUnlockAll releases every lock set via this mount and signals any F_SETLKW's it has waiting to give up
+func (mS *mountStruct) flockUnlockAll() {
+	mS.volStruct.FLockMutex.Lock()
+	defer mS.volStruct.FLockMutex.Unlock()
+
+	for _, waitList := range mS.volStruct.FLockWaitMap {
+		for e := waitList.Front(); e != nil; e = e.Next() {
+			waiter := e.Value.(*flockWaiterStruct)
+			if mS.id == waiter.mountID {
+				waiter.interrupt()
+			}
+		}
+	}
+
+	for inodeNumber := range mS.flockMap {
+		flockList, ok := mS.volStruct.FLockMap[inodeNumber]
+		if ok {
+			var next *list.Element
+			for e := flockList.Front(); e != nil; e = next {
+				next = e.Next()
+				if mS.id == e.Value.(*FlockStruct).mountID {
+					flockList.Remove(e)
+				}
+			}
+		}
+
+		mS.volStruct.flockPruneList(inodeNumber)
+		mS.volStruct.flockWakeWaiters(inodeNumber)
+	}
+
+	mS.flockMap = make(map[inode.InodeNumber]bool)
+}
+
+// flockTrackInode records whether this mount holds any locks on inodeNumber (so that flockUnlockAll
+// need only visit those inodes). Caller must hold vS.FLockMutex.
+func (mS *mountStruct) flockTrackInode(inodeNumber inode.InodeNumber) {
+	flockList, ok := mS.volStruct.FLockMap[inodeNumber]
+	if ok {
+		for e := flockList.Front(); e != nil; e = e.Next() {
+			if mS.id == e.Value.(*FlockStruct).mountID {
+				mS.flockMap[inodeNumber] = true
+				return
+			}
+		}
+	}
+
+	delete(mS.flockMap, inodeNumber)
+}
+
+// flockPruneList discards inodeNumber's lock list once no locks remain on it. Caller must hold vS.FLockMutex.
+func (vS *volumeStruct) flockPruneList(inodeNumber inode.InodeNumber) {
+	vS.Lock()
+	defer vS.Unlock()
+
+	flockList, ok := vS.FLockMap[inodeNumber]
+	if ok && (0 == flockList.Len()) {
+		delete(vS.FLockMap, inodeNumber)
+	}
+}
+
+// conflictingOwners returns the owners of locks on inodeNumber that conflict with flock. Caller must hold vS.FLockMutex.
+func (vS *volumeStruct) conflictingOwners(inodeNumber inode.InodeNumber, flock *FlockStruct) (owners []flockOwnerStruct) {
+	owners = make([]flockOwnerStruct, 0)
+
+	flockList, ok := vS.FLockMap[inodeNumber]
+	if !ok {
+		return
+	}
+
+	for e := flockList.Front(); e != nil; e = e.Next() {
+		elm := e.Value.(*FlockStruct)
+		if checkConflict(elm, flock) {
+			owners = append(owners, elm.owner())
+		}
+	}
+
+	return
+}
+
+// flockWouldDeadlock reports whether blocking flock's owner until the locks conflicting with flock on inodeNumber are
+// released would complete a cycle of owners (each blocked in F_SETLKW on a lock held by the next). Caller must hold vS.FLockMutex.
+func (vS *volumeStruct) flockWouldDeadlock(inodeNumber inode.InodeNumber, flock *FlockStruct) bool {
+	visitedOwners := make(map[flockOwnerStruct]bool)
+	pendingOwners := vS.conflictingOwners(inodeNumber, flock)
+
+	for 0 < len(pendingOwners) {
+		owner := pendingOwners[len(pendingOwners)-1]
+		pendingOwners = pendingOwners[:len(pendingOwners)-1]
+
+		if owner == flock.owner() {
+			return true
+		}
+		if visitedOwners[owner] {
+			continue
+		}
+		visitedOwners[owner] = true
+
+		waiter, ok := vS.flockWaiterMap[owner]
+		if ok {
+			pendingOwners = append(pendingOwners, vS.conflictingOwners(waiter.inodeNumber, waiter.flock)...)
+		}
+	}
+
+	return false
+}
+
+// Implements file locking conforming to fcntl(2) locking description. Supports F_SETLK, F_SETLKW, and F_GETLK.
 // whence: FS supports only SEEK_SET - starting from 0, since it does not manage file handles, caller is expected to supply the start and length relative to offset ZERO.
+// An F_SETLKW waits indefinitely (as would FlockWait() with timeout == 0 and stopChan == nil).
 func (mS *mountStruct) Flock(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, lockCmd int32, inFlock *FlockStruct) (outFlock *FlockStruct, err error) {
-	outFlock = inFlock
-
 	if lockCmd == syscall.F_SETLKW {
-		err = blunder.AddError(nil, blunder.NotSupportedError)
+		outFlock, err = mS.FlockWait(userID, groupID, otherGroupIDs, inodeNumber, inFlock, 0, nil)
 		return
 	}
 
+	outFlock, _, err = mS.flockAttempt(userID, groupID, otherGroupIDs, inodeNumber, lockCmd, inFlock)
+
+	stats.IncrementOperations(&stats.FsFlockOps)
+	return
+}
+
+// FlockWait performs an F_SETLKW... waiting while a conflicting lock is held by another Pid. Should the wait exceed
+// timeout (if non-zero), TimedOut is returned. Should stopChan (if non-nil) be signaled, InterruptedError is returned.
+// Should waiting complete a cycle of Pids each waiting on a lock held by the next, DeadlockError is returned.
+func (mS *mountStruct) FlockWait(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, inFlock *FlockStruct, timeout time.Duration, stopChan chan bool) (outFlock *FlockStruct, err error) {
+	var (
+		timeoutChan <-chan time.Time
+		wake        bool
+	)
+
+	if 0 != timeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	for {
+		var waiter *flockWaiterStruct
+
+		outFlock, waiter, err = mS.flockAttempt(userID, groupID, otherGroupIDs, inodeNumber, syscall.F_SETLKW, inFlock)
+		if (nil != err) || (nil == waiter) {
+			break
+		}
+
+		// Wait (without holding the inode lock) for a lock on inodeNumber to be released or changed
+
+		select {
+		case wake = <-waiter.wakeChan:
+			if !wake {
+				err = blunder.NewError(blunder.InterruptedError, "EINTR")
+			}
+		case <-timeoutChan:
+			err = blunder.NewError(blunder.TimedOut, "ETIMEDOUT")
+		case <-stopChan:
+			err = blunder.NewError(blunder.InterruptedError, "EINTR")
+		}
+
+		mS.volStruct.flockDequeueWaiter(waiter)
+
+		if nil != err {
+			break
+		}
+	}
+
+	stats.IncrementOperations(&stats.FsFlockOps)
+	return
+}
+
+// flockAttempt performs a single attempt at lockCmd. Should an F_SETLKW find a conflicting lock (that would not
+// lead to a deadlock), the returned waiter will have been enqueued on inodeNumber's wait queue.
+func (mS *mountStruct) flockAttempt(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, lockCmd int32, inFlock *FlockStruct) (outFlock *FlockStruct, waiter *flockWaiterStruct, err error) {
+	outFlock = inFlock
+
 	// Make sure the inode does not go away, while we are applying the flock.
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
@@ -620,6 +1031,11 @@ func (mS *mountStruct) Flock(userID inode.InodeUserID, groupID inode.InodeGroupI
 		inFlock.Len = ^uint64(0)
 	}
 
+	mS.volStruct.FLockMutex.Lock()
+	defer mS.volStruct.FLockMutex.Unlock()
+
+	inFlock.mountID = mS.id
+
 	switch lockCmd {
 	case syscall.F_GETLK:
 		conflictLock := mS.verifyLock(inodeNumber, inFlock)
@@ -630,18 +1046,35 @@ func (mS *mountStruct) Flock(userID inode.InodeUserID, groupID inode.InodeGroupI
 			outFlock = inFlock
 			outFlock.Type = syscall.F_UNLCK
 		}
+		mS.volStruct.flockPruneList(inodeNumber)
 		break
 
-	case syscall.F_SETLK:
+	case syscall.F_SETLK, syscall.F_SETLKW:
 		if inFlock.Type == syscall.F_UNLCK {
 			err = mS.fileUnlock(inodeNumber, inFlock)
 		} else if inFlock.Type == syscall.F_WRLCK || inFlock.Type == syscall.F_RDLCK {
+			if (syscall.F_SETLKW == lockCmd) && (nil != mS.verifyLock(inodeNumber, inFlock)) {
+				if mS.volStruct.flockWouldDeadlock(inodeNumber, inFlock) {
+					err = blunder.NewError(blunder.DeadlockError, "EDEADLK")
+				} else {
+					waiter = mS.volStruct.flockEnqueueWaiter(mS.id, inodeNumber, inFlock)
+				}
+				return
+			}
 			err = mS.fileLockInsert(inodeNumber, inFlock)
 		} else {
 			err = blunder.NewError(blunder.InvalidArgError, "EINVAL")
 			return
 		}
 
+		mS.flockTrackInode(inodeNumber)
+		mS.volStruct.flockPruneList(inodeNumber)
+
+		if nil == err {
+			// Replacing (e.g. downgrading) or releasing a range may have resolved the conflict of a waiter
+			mS.volStruct.flockWakeWaiters(inodeNumber)
+		}
+
 		break
 
 	default:
@@ -649,7 +1082,6 @@ func (mS *mountStruct) Flock(userID inode.InodeUserID, groupID inode.InodeGroupI
 		return
 	}
 
-	stats.IncrementOperations(&stats.FsFlockOps)
 	return
 }
 
@@ -837,6 +1269,11 @@ func (mS *mountStruct) IsSymlink(userID inode.InodeUserID, groupID inode.InodeGr
 }
 
 func (mS *mountStruct) Link(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string, targetInodeNumber inode.InodeNumber) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	var (
 		inodeType inode.InodeType
 	)
@@ -1022,6 +1459,11 @@ func (mS *mountStruct) LookupPath(userID inode.InodeUserID, groupID inode.InodeG
 }
 
 func (mS *mountStruct) MiddlewareCoalesce(destPath string, elementPaths []string) (ino uint64, numWrites uint64, modificationTime uint64, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	// it'll hold a dir lock and a file lock for each element path, plus a lock on the destination dir and the root dir
 	heldLocks := make([]*dlm.RWLockStruct, 0, 2*len(elementPaths)+2)
 	defer func() {
@@ -1232,6 +1674,11 @@ func (mS *mountStruct) MiddlewareCoalesce(destPath string, elementPaths []string
 }
 
 func (mS *mountStruct) MiddlewareDelete(parentDir string, baseName string) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	// Get the inode, type, and lock for the parent directory
 	parentInodeNumber, parentInodeType, parentDirLock, err := mS.resolvePathForWrite(parentDir, nil)
 	if err != nil {
@@ -1564,7 +2011,7 @@ func (mS *mountStruct) MiddlewareGetContainer(vContainerName string, maxEntries
 	return
 }
 
-func (mS *mountStruct) MiddlewareGetObject(volumeName string, containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, ino uint64, numWrites uint64, serializedMetadata []byte, err error) {
+func (mS *mountStruct) MiddlewareGetObject(volumeName string, containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, ino uint64, numWrites uint64, serializedMetadata []byte, leaseID string, err error) {
 	inodeNumber, inodeType, inodeLock, err := mS.resolvePathForRead(containerObjectPath, nil)
 	ino = uint64(inodeNumber)
 	if err != nil {
@@ -1590,6 +2037,9 @@ func (mS *mountStruct) MiddlewareGetObject(volumeName string, containerObjectPat
 	lastModified = uint64(metadata.ModificationTime.UnixNano())
 	numWrites = metadata.NumWrites
 
+	// Log segments named in the returned read plan are pinned by a lease (see below)
+	logSegmentNumbers := make([]uint64, 0)
+
 	// If no ranges are given then get range of whole file.  Otherwise, get ranges.
 	if len(readRangeIn) == 0 {
 		// Get ReadPlan for file
@@ -1605,7 +2055,7 @@ func (mS *mountStruct) MiddlewareGetObject(volumeName string, containerObjectPat
 			err = err1
 			return
 		}
-		appendReadPlanEntries(tmpReadEnt, readRangeOut)
+		appendReadPlanEntries(tmpReadEnt, readRangeOut, &logSegmentNumbers)
 	} else {
 		volumeHandle, err1 := inode.FetchVolumeHandle(volumeName)
 		if err1 != nil {
@@ -1622,7 +2072,7 @@ func (mS *mountStruct) MiddlewareGetObject(volumeName string, containerObjectPat
 				err = err1
 				return
 			}
-			appendReadPlanEntries(tmpReadEnt, readRangeOut)
+			appendReadPlanEntries(tmpReadEnt, readRangeOut, &logSegmentNumbers)
 		}
 	}
 
@@ -1636,6 +2086,14 @@ func (mS *mountStruct) MiddlewareGetObject(volumeName string, containerObjectPat
 	} else {
 		err = nil
 	}
+
+	// Pin the log segments while we still hold the inode lock so that
+	// they cannot be deleted before the caller has had a chance to read them
+	leaseID, err = mS.volStruct.VolumeHandle.CreateLease(logSegmentNumbers)
+	if err != nil {
+		return
+	}
+
 	stats.IncrementOperations(&stats.FsMwGetObjOps)
 	return
 }
@@ -1674,6 +2132,11 @@ func (mS *mountStruct) MiddlewareHeadResponse(entityPath string) (response HeadR
 }
 
 func (mS *mountStruct) MiddlewarePost(parentDir string, baseName string, newMetaData []byte, oldMetaData []byte) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	// Find inode for container or object
 	fullPathName := parentDir + "/" + baseName
 	baseNameInodeNumber, _, baseInodeLock, err := mS.resolvePathForWrite(fullPathName, nil)
@@ -1927,6 +2390,50 @@ func putObjectHelper(mS *mountStruct, vContainerName string, vObjectPath string,
 }
 
 func (mS *mountStruct) MiddlewarePutComplete(vContainerName string, vObjectPath string, pObjectPaths []string, pObjectLengths []uint64, pObjectMetadata []byte) (mtime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
+	var (
+		bytesDelta       uint64
+		exists           bool
+		existingBytes    uint64
+		inodesDelta      uint64
+		pObjectLength    uint64
+		reservation      inode.QuotaReservation
+		totalObjectBytes uint64
+	)
+
+	for _, pObjectLength = range pObjectLengths {
+		totalObjectBytes += pObjectLength
+	}
+
+	// An overwrite replaces whatever is already at vObjectPath, so only the growth
+	// (if any) is charged... and a new inode only if the object didn't already exist
+
+	existingBytes, exists, err = mS.middlewareExistingObject(vContainerName, vObjectPath)
+	if nil != err {
+		return
+	}
+
+	if exists {
+		inodesDelta = 0
+	} else {
+		inodesDelta = 1
+	}
+
+	if totalObjectBytes > existingBytes {
+		bytesDelta = totalObjectBytes - existingBytes
+	} else {
+		bytesDelta = 0
+	}
+
+	// Note: the log segments have already been written... if this fails, they will be garbage collected
+	reservation, err = mS.volStruct.VolumeHandle.CheckQuota(inode.InodeRootUserID, inode.InodeRootGroupID, bytesDelta, inodesDelta)
+	if err != nil {
+		return
+	}
 
 	reifyTheFile := func() (fileInodeNumber inode.InodeNumber, err error) {
 		// Reify the Swift object into a ProxyFS file by making a new,
@@ -1961,10 +2468,63 @@ func (mS *mountStruct) MiddlewarePutComplete(vContainerName string, vObjectPath
 		return
 	}
 
-	return putObjectHelper(mS, vContainerName, vObjectPath, reifyTheFile)
+	mtime, fileInodeNumber, numWrites, err = putObjectHelper(mS, vContainerName, vObjectPath, reifyTheFile)
+	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
+		return
+	}
+	mS.volStruct.VolumeHandle.SettleQuotaReservation(fileInodeNumber, reservation)
+	return
+}
+
+// middlewareExistingObject reports whether a middleware PUT of vObjectPath would replace
+// an existing inode and, if that inode is a file, its size. Lookup failures are left for
+// putObjectHelper() to report and simply mean there is nothing to replace.
+func (mS *mountStruct) middlewareExistingObject(vContainerName string, vObjectPath string) (existingBytes uint64, exists bool, err error) {
+	inodeNumber, err := mS.LookupPath(inode.InodeRootUserID, inode.InodeRootGroupID, nil, vContainerName+"/"+vObjectPath)
+	if nil != err {
+		existingBytes = 0
+		exists = false
+		err = nil
+		return
+	}
+
+	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
+	if nil != err {
+		return
+	}
+	err = inodeLock.ReadLock()
+	if nil != err {
+		return
+	}
+	defer inodeLock.Unlock()
+
+	metadata, err := mS.volStruct.VolumeHandle.GetMetadata(inodeNumber)
+	if nil != err {
+		return
+	}
+
+	if inode.FileType == metadata.InodeType {
+		existingBytes = metadata.Size
+	} else {
+		existingBytes = 0
+	}
+	exists = true
+
+	err = nil
+	return
 }
 
 func (mS *mountStruct) MiddlewareMkdir(vContainerName string, vObjectPath string, metadata []byte) (mtime uint64, inodeNumber inode.InodeNumber, numWrites uint64, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
+	reservation, err := mS.volStruct.VolumeHandle.CheckQuota(inode.InodeRootUserID, inode.InodeRootGroupID, 0, 1)
+	if err != nil {
+		return
+	}
 
 	createTheDirectory := func() (dirInodeNumber inode.InodeNumber, err error) {
 		dirInodeNumber, err = mS.volStruct.VolumeHandle.CreateDir(inode.PosixModePerm, 0, 0)
@@ -1984,10 +2544,21 @@ func (mS *mountStruct) MiddlewareMkdir(vContainerName string, vObjectPath string
 		return
 	}
 
-	return putObjectHelper(mS, vContainerName, vObjectPath, createTheDirectory)
+	mtime, inodeNumber, numWrites, err = putObjectHelper(mS, vContainerName, vObjectPath, createTheDirectory)
+	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
+		return
+	}
+	mS.volStruct.VolumeHandle.SettleQuotaReservation(inodeNumber, reservation)
+	return
 }
 
 func (mS *mountStruct) MiddlewarePutContainer(containerName string, oldMetadata []byte, newMetadata []byte) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	var (
 		containerInodeLock   *dlm.RWLockStruct
 		containerInodeNumber inode.InodeNumber
@@ -2063,17 +2634,29 @@ func (mS *mountStruct) MiddlewarePutContainer(containerName string, oldMetadata
 }
 
 func (mS *mountStruct) Mkdir(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string, filePerm inode.InodeMode) (newDirInodeNumber inode.InodeNumber, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	// Make sure the file basename is not too long
 	err = validateBaseName(basename)
 	if err != nil {
 		return 0, err
 	}
 
+	reservation, err := mS.volStruct.VolumeHandle.CheckQuota(userID, groupID, 0, 1)
+	if err != nil {
+		return 0, err
+	}
+
 	newDirInodeNumber, err = mS.volStruct.VolumeHandle.CreateDir(filePerm, userID, groupID)
 	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
 		logger.ErrorWithError(err)
 		return 0, err
 	}
+	mS.volStruct.VolumeHandle.SettleQuotaReservation(newDirInodeNumber, reservation)
 
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
@@ -2115,6 +2698,11 @@ func (mS *mountStruct) Mkdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 }
 
 func (mS *mountStruct) RemoveXAttr(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, streamName string) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
 		return
@@ -2134,6 +2722,14 @@ func (mS *mountStruct) RemoveXAttr(userID inode.InodeUserID, groupID inode.Inode
 		return
 	}
 
+	_, err = mS.volStruct.VolumeHandle.GetStream(inodeNumber, streamName)
+	if nil != err {
+		if blunder.Is(err, blunder.StreamNotFound) {
+			err = blunder.NewError(blunder.StreamNotFound, "ENODATA")
+		}
+		return
+	}
+
 	err = mS.volStruct.VolumeHandle.DeleteStream(inodeNumber, streamName)
 	if err != nil {
 		logger.ErrorfWithError(err, "Failed to delete XAttr %v of inode %v", streamName, inodeNumber)
@@ -2146,6 +2742,11 @@ func (mS *mountStruct) RemoveXAttr(userID inode.InodeUserID, groupID inode.Inode
 }
 
 func (mS *mountStruct) Rename(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	err = validateBaseName(srcBasename)
 	if err != nil {
 		return
@@ -2483,6 +3084,11 @@ func (mS *mountStruct) Readsymlink(userID inode.InodeUserID, groupID inode.Inode
 }
 
 func (mS *mountStruct) Resize(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, newSize uint64) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
 		return
@@ -2502,13 +3108,28 @@ func (mS *mountStruct) Resize(userID inode.InodeUserID, groupID inode.InodeGroup
 		return
 	}
 
+	reservation, err := mS.checkQuotaForFileGrowth(inodeNumber, newSize)
+	if err != nil {
+		return
+	}
+
 	err = mS.volStruct.VolumeHandle.SetSize(inodeNumber, newSize)
+	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
+	} else {
+		mS.volStruct.VolumeHandle.SettleQuotaReservation(inodeNumber, reservation)
+	}
 	mS.volStruct.untrackInFlightFileInodeData(inodeNumber, false)
 	stats.IncrementOperations(&stats.FsSetsizeOps)
 	return err
 }
 
 func (mS *mountStruct) Rmdir(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	callerID := dlm.GenerateCallerID()
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, callerID)
 	if err != nil {
@@ -2586,6 +3207,11 @@ func (mS *mountStruct) Rmdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 }
 
 func (mS *mountStruct) Setstat(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, stat Stat) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
 		return
@@ -2687,6 +3313,29 @@ func (mS *mountStruct) Setstat(userID inode.InodeUserID, groupID inode.InodeGrou
 	}
 
 	if settingUserID || settingGroupID {
+		// The inode's usage moves to any new owner... so check that owner's limits first
+		metadata, err := mS.volStruct.VolumeHandle.GetMetadata(inodeNumber)
+		if err != nil {
+			logger.ErrorWithError(err)
+			return err
+		}
+		ownerUserID := metadata.UserID
+		if settingUserID {
+			ownerUserID = inode.InodeUserID(newUserID)
+		}
+		ownerGroupID := metadata.GroupID
+		if settingGroupID {
+			ownerGroupID = inode.InodeGroupID(newGroupID)
+		}
+		ownerBytes := uint64(0)
+		if inode.FileType == metadata.InodeType {
+			ownerBytes = metadata.Size
+		}
+		reservation, err := mS.volStruct.VolumeHandle.CheckOwnerChangeQuota(ownerUserID, ownerGroupID, metadata.UserID, metadata.GroupID, ownerBytes)
+		if err != nil {
+			return err
+		}
+
 		if settingUserID {
 			if settingGroupID {
 				err = mS.volStruct.VolumeHandle.SetOwnerUserIDGroupID(inodeNumber, inode.InodeUserID(newUserID), inode.InodeGroupID(newGroupID))
@@ -2697,9 +3346,11 @@ func (mS *mountStruct) Setstat(userID inode.InodeUserID, groupID inode.InodeGrou
 			err = mS.volStruct.VolumeHandle.SetOwnerGroupID(inodeNumber, inode.InodeGroupID(newGroupID))
 		}
 		if nil != err {
+			mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
 			logger.ErrorWithError(err)
 			return err
 		}
+		mS.volStruct.VolumeHandle.SettleQuotaReservation(inodeNumber, reservation)
 	}
 
 	// Set mode, if present in the map
@@ -2729,6 +3380,11 @@ const (
 )
 
 func (mS *mountStruct) SetXAttr(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, streamName string, value []byte, flags int) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
 		return
@@ -2748,21 +3404,32 @@ func (mS *mountStruct) SetXAttr(userID inode.InodeUserID, groupID inode.InodeGro
 		return
 	}
 
+	// Note that the existence check must be done directly against the inode under the
+	// WriteLock already held (i.e. not via mS.GetXAttr() which would itself ReadLock it)
+
 	switch flags {
 	case 0:
 		break
 	case xattr_create:
-		_, err = mS.GetXAttr(userID, groupID, otherGroupIDs, inodeNumber, streamName)
-		if err == nil {
-			return blunder.AddError(err, blunder.FileExistsError)
+		_, err = mS.volStruct.VolumeHandle.GetStream(inodeNumber, streamName)
+		if nil == err {
+			err = blunder.NewError(blunder.FileExistsError, "EEXIST")
+			return
+		}
+		if !blunder.Is(err, blunder.StreamNotFound) {
+			return
 		}
 	case xattr_replace:
-		_, err = mS.GetXAttr(userID, groupID, otherGroupIDs, inodeNumber, streamName)
-		if err != nil {
-			return blunder.AddError(err, blunder.StreamNotFound)
+		_, err = mS.volStruct.VolumeHandle.GetStream(inodeNumber, streamName)
+		if nil != err {
+			if blunder.Is(err, blunder.StreamNotFound) {
+				err = blunder.NewError(blunder.StreamNotFound, "ENODATA")
+			}
+			return
 		}
 	default:
-		return blunder.AddError(err, blunder.InvalidArgError)
+		err = blunder.NewError(blunder.InvalidArgError, "EINVAL")
+		return
 	}
 
 	err = mS.volStruct.VolumeHandle.PutStream(inodeNumber, streamName, value)
@@ -2776,26 +3443,83 @@ func (mS *mountStruct) SetXAttr(userID inode.InodeUserID, groupID inode.InodeGro
 	return
 }
 
+// GetQuota reports the usage and limits of userID and groupID in this volume (as would quota(1)).
+func (mS *mountStruct) GetQuota(userID inode.InodeUserID, groupID inode.InodeGroupID) (userQuota inode.OwnerQuotaStatus, groupQuota inode.OwnerQuotaStatus, err error) {
+	userQuota, groupQuota = mS.volStruct.VolumeHandle.FetchOwnerQuotaStatusByID(userID, groupID)
+
+	stats.IncrementOperations(&stats.FsGetQuotaOps)
+	return
+}
+
 func (mS *mountStruct) StatVfs() (statVFS StatVFS, err error) {
+	var (
+		freeBlocks  uint64
+		freeInodes  uint64
+		totalBlocks uint64
+		totalInodes uint64
+		usedBlocks  uint64
+	)
+
+	volumeStats := mS.volStruct.VolumeHandle.FetchVolumeStats()
+
+	// Block counts are in StatVFSFragmentSize units... and space held by log segments is what the volume consumes
+
+	usedBlocks = (volumeStats.LogSegmentBytes + FsOptimalTransferSize - 1) / FsOptimalTransferSize
+
+	if 0 == volumeStats.Capacity {
+		freeBlocks = VolUnboundedFreeBlocks
+		totalBlocks = usedBlocks + freeBlocks
+	} else {
+		totalBlocks = volumeStats.Capacity / FsOptimalTransferSize
+		if totalBlocks > usedBlocks {
+			freeBlocks = totalBlocks - usedBlocks
+		} else {
+			freeBlocks = 0
+		}
+	}
+
+	// The inode total is fixed (so that df -i reports usage rather than a total drifting with it)
+
+	totalInodes = mS.volStruct.VolumeHandle.FetchQuotaStatus().Limits.HardInodes
+	if 0 == totalInodes {
+		totalInodes = VolUnboundedTotalInodes
+	}
+	if totalInodes > volumeStats.InodeCount {
+		freeInodes = totalInodes - volumeStats.InodeCount
+	} else {
+		freeInodes = 0
+	}
+
 	statVFS = make(map[StatVFSKey]uint64)
 
 	statVFS[StatVFSFilesystemID] = mS.volStruct.VolumeHandle.GetFSID()
 	statVFS[StatVFSBlockSize] = FsBlockSize
 	statVFS[StatVFSFragmentSize] = FsOptimalTransferSize
-	statVFS[StatVFSTotalBlocks] = VolFakeTotalBlocks
-	statVFS[StatVFSFreeBlocks] = VolFakeFreeBlocks
-	statVFS[StatVFSAvailBlocks] = VolFakeAvailBlocks
-	statVFS[StatVFSTotalInodes] = VolFakeTotalInodes
-	statVFS[StatVFSFreeInodes] = VolFakeAvailInodes
-	statVFS[StatVFSAvailInodes] = VolFakeAvailInodes
-	statVFS[StatVFSMountFlags] = 0
+	statVFS[StatVFSTotalBlocks] = totalBlocks
+	statVFS[StatVFSFreeBlocks] = freeBlocks
+	statVFS[StatVFSAvailBlocks] = freeBlocks
+	statVFS[StatVFSTotalInodes] = totalInodes
+	statVFS[StatVFSFreeInodes] = freeInodes
+	statVFS[StatVFSAvailInodes] = freeInodes
+	if mS.isReadOnly() {
+		statVFS[StatVFSMountFlags] = StatVFSMountFlagReadOnly
+	} else {
+		statVFS[StatVFSMountFlags] = 0
+	}
 	statVFS[StatVFSMaxFilenameLen] = FileNameMax
+	statVFS[StatVFSLogSegmentBytes] = volumeStats.LogSegmentBytes
+	statVFS[StatVFSReferencedBytes] = volumeStats.ReferencedBytes
 
 	stats.IncrementOperations(&stats.FsStatvfsOps)
 	return statVFS, nil
 }
 
 func (mS *mountStruct) Symlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string, target string) (symlinkInodeNumber inode.InodeNumber, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	err = validateBaseName(basename)
 	if err != nil {
 		return
@@ -2806,11 +3530,18 @@ func (mS *mountStruct) Symlink(userID inode.InodeUserID, groupID inode.InodeGrou
 		return
 	}
 
+	reservation, err := mS.volStruct.VolumeHandle.CheckQuota(userID, groupID, 0, 1)
+	if err != nil {
+		return
+	}
+
 	// Mode for symlinks defaults to rwxrwxrwx, i.e. inode.PosixModePerm
 	symlinkInodeNumber, err = mS.volStruct.VolumeHandle.CreateSymlink(target, inode.PosixModePerm, userID, groupID)
 	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
 		return
 	}
+	mS.volStruct.VolumeHandle.SettleQuotaReservation(symlinkInodeNumber, reservation)
 
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, nil)
 	if err != nil {
@@ -2853,6 +3584,11 @@ func (mS *mountStruct) Symlink(userID inode.InodeUserID, groupID inode.InodeGrou
 }
 
 func (mS *mountStruct) Unlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string) (err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
+
 	callerID := dlm.GenerateCallerID()
 	inodeLock, err := mS.volStruct.initInodeLock(inodeNumber, callerID)
 	if err != nil {
@@ -2937,6 +3673,10 @@ func (mS *mountStruct) VolumeName() (volumeName string) {
 }
 
 func (mS *mountStruct) Write(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, buf []byte, profiler *utils.Profiler) (size uint64, err error) {
+	err = mS.checkWritable()
+	if nil != err {
+		return
+	}
 
 	logger.Tracef("fs.Write(): starting volume '%s' inode %d offset %d len %d",
 		mS.volStruct.volumeName, inodeNumber, offset, len(buf))
@@ -2960,13 +3700,21 @@ func (mS *mountStruct) Write(userID inode.InodeUserID, groupID inode.InodeGroupI
 		return
 	}
 
+	reservation, err := mS.checkQuotaForFileGrowth(inodeNumber, offset+uint64(len(buf)))
+	if err != nil {
+		return
+	}
+
 	profiler.AddEventNow("before inode.Write()")
 	err = mS.volStruct.VolumeHandle.Write(inodeNumber, offset, buf, profiler)
 	profiler.AddEventNow("after inode.Write()")
 	// write to Swift presumably succeeds or fails as a whole
 	if err != nil {
+		mS.volStruct.VolumeHandle.ReleaseQuotaReservation(reservation)
 		return 0, err
 	}
+	// Held until the data written is flushed
+	mS.volStruct.VolumeHandle.SettleQuotaReservation(inodeNumber, reservation)
 
 	logger.Tracef("fs.Write(): tracking write volume '%s' inode %d", mS.volStruct.volumeName, inodeNumber)
 	mS.volStruct.trackInFlightFileInodeData(inodeNumber)
@@ -2975,6 +3723,23 @@ func (mS *mountStruct) Write(userID inode.InodeUserID, groupID inode.InodeGroupI
 	return
 }
 
+// checkQuotaForFileGrowth charges only the bytes beyond the current EOF of inodeNumber against
+// the volume's (and the file owner's) quota so that overwrites (and shrinking) remain possible once a limit is reached.
+// The caller must settle or release the returned reservation.
+func (mS *mountStruct) checkQuotaForFileGrowth(inodeNumber inode.InodeNumber, newEOF uint64) (reservation inode.QuotaReservation, err error) {
+	metadata, err := mS.volStruct.VolumeHandle.GetMetadata(inodeNumber)
+	if err != nil {
+		return
+	}
+
+	if newEOF <= metadata.Size {
+		return
+	}
+
+	reservation, err = mS.volStruct.VolumeHandle.CheckQuota(metadata.UserID, metadata.GroupID, newEOF-metadata.Size, 0)
+	return
+}
+
 func validateBaseName(baseName string) (err error) {
 	// Make sure the file baseName is not too long
 	baseLen := len(baseName)
@@ -3273,11 +4038,14 @@ func removeObstacleToObjectPut(mount *mountStruct, callerID dlm.CallerID, dirIno
 	return nil
 }
 
-// Utility function to append entries to reply
-func appendReadPlanEntries(readPlan []inode.ReadPlanStep, readRangeOut *[]inode.ReadPlanStep) (numEntries uint64) {
+// Utility function to append entries to reply (and note the log segments they reference)
+func appendReadPlanEntries(readPlan []inode.ReadPlanStep, readRangeOut *[]inode.ReadPlanStep, logSegmentNumbers *[]uint64) (numEntries uint64) {
 	for i := range readPlan {
 		entry := inode.ReadPlanStep{ObjectPath: readPlan[i].ObjectPath, Offset: readPlan[i].Offset, Length: readPlan[i].Length}
 		*readRangeOut = append(*readRangeOut, entry)
+		if 0 != readPlan[i].LogSegmentNumber {
+			*logSegmentNumbers = append(*logSegmentNumbers, readPlan[i].LogSegmentNumber)
+		}
 		numEntries++
 	}
 	return
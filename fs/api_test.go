@@ -4,190 +4,31 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"os"
-	"os/exec"
 	"strings"
 	"syscall"
 	"testing"
 	"time"
 
-	"golang.org/x/sys/unix"
-
 	"github.com/swiftstack/ProxyFS/blunder"
-	"github.com/swiftstack/ProxyFS/conf"
-	"github.com/swiftstack/ProxyFS/dlm"
-	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/logger"
-	"github.com/swiftstack/ProxyFS/platform"
-	"github.com/swiftstack/ProxyFS/ramswift"
-	"github.com/swiftstack/ProxyFS/stats"
-	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/testenv"
 )
 
 // our global mountStruct to be used in tests
 var mS *mountStruct
 
 func testSetup() (err error) {
-	testDir, err := ioutil.TempDir(os.TempDir(), "ProxyFS_test_fs_")
-	if nil != err {
-		return
-	}
-
-	if platform.IsLinux {
-		cmd := exec.Command("sudo", "mount", "-t", "ramfs", "-o", "size=512m", "ext4", testDir)
-		err = cmd.Run()
-		if nil != err {
-			return
-		}
-
-		cmd = exec.Command("sudo", "chmod", "777", testDir)
-		err = cmd.Run()
-		if nil != err {
-			return
-		}
-	}
-
-	err = os.Chdir(testDir)
-	if nil != err {
-		return
-	}
-
-	err = os.Mkdir("TestVolume", os.ModePerm)
-
-	testConfMapStrings := []string{
-		"Stats.IPAddr=localhost",
-		"Stats.UDPPort=52184",
-		"Stats.BufferLength=100",
-		"Stats.MaxLatency=1s",
-		"Logging.LogFilePath=proxyfsd.log",
-		"SwiftClient.NoAuthTCPPort=45262",
-		"SwiftClient.Timeout=10s",
-		"SwiftClient.RetryLimit=5",
-		"SwiftClient.RetryLimitObject=5",
-		"SwiftClient.RetryDelay=1s",
-		"SwiftClient.RetryDelayObject=1s",
-		"SwiftClient.RetryExpBackoff=1.2",
-		"SwiftClient.RetryExpBackoffObject=2.0",
-		"SwiftClient.ChunkedConnectionPoolSize=64",
-		"SwiftClient.NonChunkedConnectionPoolSize=32",
-		"SwiftClient.StarvationCallbackFrequency=100ms",
-		"FlowControl:TestFlowControl.MaxFlushSize=10000000",
-		"FlowControl:TestFlowControl.MaxFlushTime=10s",
-		"FlowControl:TestFlowControl.ReadCacheLineSize=1000000",
-		"FlowControl:TestFlowControl.ReadCacheWeight=100",
-		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerStoragePolicy=silver",
-		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerNamePrefix=Replicated3Way_",
-		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainersPerPeer=1000",
-		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.MaxObjectsPerContainer=1000000",
-		"Peer:Peer0.PrivateIPAddr=localhost",
-		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
-		"Cluster.Peers=Peer0",
-		"Cluster.WhoAmI=Peer0",
-		"Volume:TestVolume.FSID=1",
-		"Volume:TestVolume.PrimaryPeer=Peer0",
-		"Volume:TestVolume.AccountName=CommonAccount",
-		"Volume:TestVolume.CheckpointContainerName=.__checkpoint__",
-		"Volume:TestVolume.CheckpointContainerStoragePolicy=gold",
-		"Volume:TestVolume.CheckpointInterval=10s",
-		"Volume:TestVolume.CheckpointIntervalsPerCompaction=100",
-		"Volume:TestVolume.DefaultPhysicalContainerLayout=PhysicalContainerLayoutReplicated3Way",
-		"Volume:TestVolume.FlowControl=TestFlowControl",
-		"Volume:TestVolume.NonceValuesToReserve=100",
-		"Volume:TestVolume.MaxEntriesPerDirNode=32",
-		"Volume:TestVolume.MaxExtentsPerFileNode=32",
-		"Volume:TestVolume.MaxInodesPerMetadataNode=32",
-		"Volume:TestVolume.MaxLogSegmentsPerMetadataNode=64",
-		"Volume:TestVolume.MaxDirFileNodesPerMetadataNode=16",
-		"FSGlobals.VolumeList=TestVolume",
-		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
-		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
-		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
-		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
-		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
-		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
-		"FSGlobals.DirEntryCacheEvictLowLimit=10000",
-		"FSGlobals.DirEntryCacheEvictHighLimit=10010",
-		"FSGlobals.FileExtentMapEvictLowLimit=10000",
-		"FSGlobals.FileExtentMapEvictHighLimit=10010",
-		"RamSwiftInfo.MaxAccountNameLength=256",
-		"RamSwiftInfo.MaxContainerNameLength=256",
-		"RamSwiftInfo.MaxObjectNameLength=1024",
-	}
-
-	testConfMap, err := conf.MakeConfMapFromStrings(testConfMapStrings)
-	if nil != err {
-		return
-	}
-
-	signalHandlerIsArmed := false
-	doneChan := make(chan bool, 1)
-	go ramswift.Daemon("/dev/null", testConfMapStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
-
-	err = stats.Up(testConfMap)
-	if nil != err {
-		return
-	}
-
-	err = logger.Up(testConfMap)
-	if nil != err {
-		stats.Down()
-		return
-	}
-
-	err = dlm.Up(testConfMap)
-	if nil != err {
-		logger.Down()
-		stats.Down()
-		return
-	}
-
-	err = swiftclient.Up(testConfMap)
-	if err != nil {
-		dlm.Down()
-		logger.Down()
-		stats.Down()
-		return err
-	}
-
-	err = headhunter.Format(testConfMap, "TestVolume")
-	if nil != err {
-		swiftclient.Down()
-		dlm.Down()
-		logger.Down()
-		stats.Down()
-		return
-	}
-
-	err = headhunter.Up(testConfMap)
-	if nil != err {
-		swiftclient.Down()
-		dlm.Down()
-		logger.Down()
-		stats.Down()
-		return
-	}
-
-	err = inode.Up(testConfMap)
+	testConfMap, err := testenv.Up("ProxyFS_test_fs_", 52184, 45262)
 	if nil != err {
-		headhunter.Down()
-		swiftclient.Down()
-		dlm.Down()
-		logger.Down()
-		stats.Down()
 		return
 	}
 
 	err = Up(testConfMap)
 	if nil != err {
-		inode.Down()
-		headhunter.Down()
-		swiftclient.Down()
-		dlm.Down()
-		logger.Down()
-		stats.Down()
+		_ = testenv.Down()
 		return
 	}
 
@@ -197,37 +38,9 @@ func testSetup() (err error) {
 
 func testTeardown() (err error) {
 	Down()
-	inode.Down()
-	headhunter.Down()
-	swiftclient.Down()
-	dlm.Down()
-	logger.Down()
-	stats.Down()
-
-	testDir, err := os.Getwd()
-	if nil != err {
-		return
-	}
 
-	err = os.Chdir("..")
-	if nil != err {
-		return
-	}
-
-	if platform.IsLinux {
-		cmd := exec.Command("sudo", "umount", testDir)
-		err = cmd.Run()
-		if nil != err {
-			return
-		}
-	} else {
-		err = os.RemoveAll(testDir)
-		if nil != err {
-			return
-		}
-	}
+	err = testenv.Down()
 
-	err = nil
 	return
 }
 
@@ -326,6 +139,51 @@ func TestCreateAndLookup(t *testing.T) {
 	}
 }
 
+func TestStatVfsInodes(t *testing.T) {
+	rootDirInodeNumber := inode.RootDirInodeNumber
+	basename := "statvfs_inodes.test"
+
+	statVFS, err := mS.StatVfs()
+	if nil != err {
+		t.Fatalf("StatVfs() returned error: %v", err)
+	}
+	if VolUnboundedTotalInodes != statVFS[StatVFSTotalInodes] {
+		t.Fatalf("StatVfs() returned unexpected total inodes: %v", statVFS[StatVFSTotalInodes])
+	}
+	freeInodes := statVFS[StatVFSFreeInodes]
+
+	// Consuming an inode should reduce the free count... but not change the total
+
+	_, err = mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename, inode.PosixModePerm)
+	if err != nil {
+		t.Fatalf("Unexpectedly couldn't create file: %v", err)
+	}
+
+	statVFS, err = mS.StatVfs()
+	if nil != err {
+		t.Fatalf("StatVfs() returned error: %v", err)
+	}
+	if VolUnboundedTotalInodes != statVFS[StatVFSTotalInodes] {
+		t.Fatalf("StatVfs() after Create() returned unexpected total inodes: %v", statVFS[StatVFSTotalInodes])
+	}
+	if (freeInodes-1 != statVFS[StatVFSFreeInodes]) || (freeInodes-1 != statVFS[StatVFSAvailInodes]) {
+		t.Fatalf("StatVfs() after Create() returned unexpected free inodes: %v (avail %v)", statVFS[StatVFSFreeInodes], statVFS[StatVFSAvailInodes])
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	statVFS, err = mS.StatVfs()
+	if nil != err {
+		t.Fatalf("StatVfs() returned error: %v", err)
+	}
+	if freeInodes != statVFS[StatVFSFreeInodes] {
+		t.Fatalf("StatVfs() after Unlink() returned unexpected free inodes: %v", statVFS[StatVFSFreeInodes])
+	}
+}
+
 func TestGetstat(t *testing.T) {
 	rootDirInodeNumber := inode.RootDirInodeNumber
 	basename := "getstat.test"
@@ -977,6 +835,346 @@ func TestFlock(t *testing.T) {
 	}
 }
 
+func TestFlockWait(t *testing.T) {
+	var err error
+
+	rootDirInodeNumber := inode.RootDirInodeNumber
+
+	basename := "TestLockWaitFile"
+	lockFileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename, inode.PosixModePerm)
+	if err != nil {
+		t.Fatalf("Create() %v returned error: %v", basename, err)
+	}
+
+	// pid1 write locks range 0 - 100, pid2 write locks range 100 - 200
+
+	lock10 := FlockStruct{Type: syscall.F_WRLCK, Start: 0, Len: 100, Pid: 1}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock10)
+	if err != nil {
+		t.Fatalf("Write lock of range 0 - 100 should have succeeded for pid1 err %v", err)
+	}
+
+	lock20 := FlockStruct{Type: syscall.F_WRLCK, Start: 100, Len: 100, Pid: 2}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock20)
+	if err != nil {
+		t.Fatalf("Write lock of range 100 - 200 should have succeeded for pid2 err %v", err)
+	}
+
+	// A non-blocking attempt by pid3 fails immediately
+
+	lock30 := FlockStruct{Type: syscall.F_RDLCK, Start: 50, Len: 100, Pid: 3}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock30)
+	if !blunder.Is(err, blunder.TryAgainError) {
+		t.Fatalf("F_SETLK of conflicting range should have returned TryAgainError but returned err %v", err)
+	}
+
+	// A blocking attempt by pid3 times out
+
+	_, err = mS.FlockWait(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, &lock30, 10*time.Millisecond, nil)
+	if !blunder.Is(err, blunder.TimedOut) {
+		t.Fatalf("FlockWait() of conflicting range should have returned TimedOut but returned err %v", err)
+	}
+
+	// A blocking attempt by pid3 is interrupted
+
+	stopChan := make(chan bool, 1)
+	stopChan <- true
+	_, err = mS.FlockWait(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, &lock30, 0, stopChan)
+	if !blunder.Is(err, blunder.InterruptedError) {
+		t.Fatalf("FlockWait() of conflicting range should have returned InterruptedError but returned err %v", err)
+	}
+
+	// pid1 blocks waiting for pid2's range... pid2 then requesting pid1's range would deadlock
+
+	lock11 := FlockStruct{Type: syscall.F_WRLCK, Start: 150, Len: 10, Pid: 1}
+	lock11Chan := make(chan error, 1)
+	go func() {
+		_, lock11Err := mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLKW, &lock11)
+		lock11Chan <- lock11Err
+	}()
+
+	for {
+		mS.volStruct.FLockMutex.Lock()
+		_, lock11Waiting := mS.volStruct.flockWaiterMap[flockOwnerStruct{mountID: mS.id, pid: lock11.Pid}]
+		mS.volStruct.FLockMutex.Unlock()
+		if lock11Waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	lock21 := FlockStruct{Type: syscall.F_WRLCK, Start: 50, Len: 10, Pid: 2}
+	_, err = mS.FlockWait(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, &lock21, 0, nil)
+	if !blunder.Is(err, blunder.DeadlockError) {
+		t.Fatalf("FlockWait() completing a cycle should have returned DeadlockError but returned err %v", err)
+	}
+
+	select {
+	case err = <-lock11Chan:
+		t.Fatalf("F_SETLKW by pid1 should still be waiting but returned err %v", err)
+	default:
+	}
+
+	// Once pid2 releases its range, pid1's F_SETLKW completes
+
+	lock2u := FlockStruct{Type: syscall.F_UNLCK, Start: 0, Len: 0, Pid: 2}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock2u)
+	if err != nil {
+		t.Fatalf("Unlock of whole file should have succeeded for pid2 err %v", err)
+	}
+
+	select {
+	case err = <-lock11Chan:
+		if err != nil {
+			t.Fatalf("F_SETLKW by pid1 should have succeeded but returned err %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("F_SETLKW by pid1 should have completed once pid2 released its range")
+	}
+
+	lock31 := FlockStruct{Type: syscall.F_WRLCK, Start: 155, Len: 10, Pid: 3}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock31)
+	if !blunder.Is(err, blunder.TryAgainError) {
+		t.Fatalf("F_SETLK of range now held by pid1 should have returned TryAgainError but returned err %v", err)
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if err != nil {
+		t.Fatalf("Unlink() %v returned error: %v", basename, err)
+	}
+}
+
+func TestUnmount(t *testing.T) {
+	var err error
+
+	rootDirInodeNumber := inode.RootDirInodeNumber
+
+	basename := "TestUnmountLockFile"
+	lockFileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename, inode.PosixModePerm)
+	if err != nil {
+		t.Fatalf("Create() %v returned error: %v", basename, err)
+	}
+
+	unmountHandle, err := Mount("TestVolume", MountOptions(0))
+	if err != nil {
+		t.Fatalf("Mount() returned error: %v", err)
+	}
+
+	// Write data via unmountHandle... and lock the file (as pid10) via unmountHandle
+
+	_, err = unmountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, 0, []byte("TestUnmount"), nil)
+	if err != nil {
+		t.Fatalf("Write() via unmountHandle returned error: %v", err)
+	}
+
+	lock10 := FlockStruct{Type: syscall.F_WRLCK, Start: 0, Len: 0, Pid: 10}
+	_, err = unmountHandle.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock10)
+	if err != nil {
+		t.Fatalf("Write lock via unmountHandle should have succeeded for pid10 err %v", err)
+	}
+
+	// pid11 waits (via mS) for pid10's lock... while pid12 waits (via unmountHandle) behind it
+
+	lock11 := FlockStruct{Type: syscall.F_WRLCK, Start: 0, Len: 0, Pid: 11}
+	lock11Chan := make(chan error, 1)
+	go func() {
+		_, lock11Err := mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLKW, &lock11)
+		lock11Chan <- lock11Err
+	}()
+
+	lock12 := FlockStruct{Type: syscall.F_RDLCK, Start: 0, Len: 0, Pid: 12}
+	lock12Chan := make(chan error, 1)
+	go func() {
+		_, lock12Err := unmountHandle.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLKW, &lock12)
+		lock12Chan <- lock12Err
+	}()
+
+	for {
+		mS.volStruct.FLockMutex.Lock()
+		_, lock11Waiting := mS.volStruct.flockWaiterMap[flockOwnerStruct{mountID: mS.id, pid: lock11.Pid}]
+		_, lock12Waiting := mS.volStruct.flockWaiterMap[flockOwnerStruct{mountID: unmountHandle.(*mountStruct).id, pid: lock12.Pid}]
+		mS.volStruct.FLockMutex.Unlock()
+		if lock11Waiting && lock12Waiting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Unmounting unmountHandle releases pid10's lock (granting pid11's) and interrupts pid12's wait
+
+	err = unmountHandle.Unmount()
+	if err != nil {
+		t.Fatalf("Unmount() returned error: %v", err)
+	}
+
+	select {
+	case err = <-lock11Chan:
+		if err != nil {
+			t.Fatalf("F_SETLKW by pid11 should have succeeded but returned err %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("F_SETLKW by pid11 should have completed once unmountHandle was unmounted")
+	}
+
+	select {
+	case err = <-lock12Chan:
+		if !blunder.Is(err, blunder.InterruptedError) {
+			t.Fatalf("F_SETLKW by pid12 should have returned InterruptedError but returned err %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("F_SETLKW by pid12 should have been interrupted once unmountHandle was unmounted")
+	}
+
+	mS.volStruct.Lock()
+	_, inFlight := mS.volStruct.inFlightFileInodeDataMap[lockFileInodeNumber]
+	mS.volStruct.Unlock()
+	if inFlight {
+		t.Fatalf("Unmount() should have flushed in-flight data")
+	}
+
+	err = unmountHandle.Unmount()
+	if !blunder.Is(err, blunder.BadMountIDError) {
+		t.Fatalf("Second Unmount() should have returned BadMountIDError but returned err %v", err)
+	}
+
+	lock11u := FlockStruct{Type: syscall.F_UNLCK, Start: 0, Len: 0, Pid: 11}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock11u)
+	if err != nil {
+		t.Fatalf("Unlock of whole file should have succeeded for pid11 err %v", err)
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if err != nil {
+		t.Fatalf("Unlink() %v returned error: %v", basename, err)
+	}
+}
+
+func TestFlockOwnership(t *testing.T) {
+	var err error
+
+	rootDirInodeNumber := inode.RootDirInodeNumber
+
+	basename := "TestFlockOwnershipFile"
+	lockFileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename, inode.PosixModePerm)
+	if err != nil {
+		t.Fatalf("Create() %v returned error: %v", basename, err)
+	}
+
+	otherHandle, err := Mount("TestVolume", MountOptions(0))
+	if err != nil {
+		t.Fatalf("Mount() returned error: %v", err)
+	}
+
+	// pid20 via mS and pid20 via otherHandle are distinct lock owners
+
+	lock20 := FlockStruct{Type: syscall.F_WRLCK, Start: 0, Len: 0, Pid: 20}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock20)
+	if err != nil {
+		t.Fatalf("Write lock via mS should have succeeded for pid20 err %v", err)
+	}
+
+	otherLock20 := FlockStruct{Type: syscall.F_WRLCK, Start: 0, Len: 0, Pid: 20}
+	_, err = otherHandle.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &otherLock20)
+	if !blunder.Is(err, blunder.TryAgainError) {
+		t.Fatalf("Write lock via otherHandle for pid20 should have conflicted but returned err %v", err)
+	}
+
+	otherLock20u := FlockStruct{Type: syscall.F_UNLCK, Start: 0, Len: 0, Pid: 20}
+	_, err = otherHandle.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &otherLock20u)
+	if err != nil {
+		t.Fatalf("Unlock via otherHandle for pid20 should have succeeded err %v", err)
+	}
+
+	// Neither the unlock nor the Unmount() of otherHandle may release mS's lock for pid20
+
+	err = otherHandle.Unmount()
+	if err != nil {
+		t.Fatalf("Unmount() returned error: %v", err)
+	}
+
+	lock21 := FlockStruct{Type: syscall.F_RDLCK, Start: 0, Len: 0, Pid: 21}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_GETLK, &lock21)
+	if !blunder.Is(err, blunder.TryAgainError) {
+		t.Fatalf("F_GETLK for pid21 should have found pid20's lock but returned err %v", err)
+	}
+
+	// Releasing the last lock on the inode discards its tracking
+
+	lock20u := FlockStruct{Type: syscall.F_UNLCK, Start: 0, Len: 0, Pid: 20}
+	_, err = mS.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, lockFileInodeNumber, syscall.F_SETLK, &lock20u)
+	if err != nil {
+		t.Fatalf("Unlock via mS for pid20 should have succeeded err %v", err)
+	}
+
+	mS.volStruct.FLockMutex.Lock()
+	_, inFlockMap := mS.flockMap[lockFileInodeNumber]
+	mS.volStruct.Lock()
+	_, inFLockMap := mS.volStruct.FLockMap[lockFileInodeNumber]
+	mS.volStruct.Unlock()
+	mS.volStruct.FLockMutex.Unlock()
+	if inFlockMap || inFLockMap {
+		t.Fatalf("Unlock of last lock should have pruned flockMap (%v) & FLockMap (%v)", inFlockMap, inFLockMap)
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if err != nil {
+		t.Fatalf("Unlink() %v returned error: %v", basename, err)
+	}
+}
+
+func TestMiddlewareExistingObject(t *testing.T) {
+	containerInodeNumber, err := mS.Mkdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "TestMiddlewareExistingObject", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+	fileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, containerInodeNumber, "file", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	_, err = mS.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	_, err = mS.Mkdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, containerInodeNumber, "dir", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	// An overwrite of a file is charged only for growth beyond the file's size
+
+	existingBytes, exists, err := mS.middlewareExistingObject("TestMiddlewareExistingObject", "file")
+	if (nil != err) || !exists || (10 != existingBytes) {
+		t.Fatalf("middlewareExistingObject() of file returned existingBytes: %v exists: %v err: %v", existingBytes, exists, err)
+	}
+
+	// Replacing a non-file consumes no new inode (but credits no bytes either)
+
+	existingBytes, exists, err = mS.middlewareExistingObject("TestMiddlewareExistingObject", "dir")
+	if (nil != err) || !exists || (0 != existingBytes) {
+		t.Fatalf("middlewareExistingObject() of dir returned existingBytes: %v exists: %v err: %v", existingBytes, exists, err)
+	}
+
+	// A new object (even below a missing directory) consumes a new inode
+
+	existingBytes, exists, err = mS.middlewareExistingObject("TestMiddlewareExistingObject", "missing/file")
+	if (nil != err) || exists || (0 != existingBytes) {
+		t.Fatalf("middlewareExistingObject() of missing/file returned existingBytes: %v exists: %v err: %v", existingBytes, exists, err)
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, containerInodeNumber, "file")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+	err = mS.Rmdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, containerInodeNumber, "dir")
+	if nil != err {
+		t.Fatalf("Rmdir() of dir returned error: %v", err)
+	}
+	err = mS.Rmdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "TestMiddlewareExistingObject")
+	if nil != err {
+		t.Fatalf("Rmdir() of container returned error: %v", err)
+	}
+}
+
 // Verify that the file system API works correctly with stale inode numbers,
 // as can happen if an NFS client cache gets out of sync because another NFS
 // client as removed a file or directory.
@@ -1212,3 +1410,137 @@ func TestStaleInodes(t *testing.T) {
 		t.Fatalf("Rmdir() of '%s' returned error: %v", testDirname, err)
 	}
 }
+
+func TestSnapshotMount(t *testing.T) {
+	rootDirInodeNumber := inode.RootDirInodeNumber
+	basename := "snapshot_mount.test"
+
+	fileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	_, err = mS.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, 0, []byte("snap"), nil)
+	if nil != err {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	err = mS.Flush(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+
+	_, err = mS.volStruct.VolumeHandle.CreateSnapshot("TestFsSnapshot")
+	if nil != err {
+		t.Fatalf("CreateSnapshot() returned error: %v", err)
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	_, err = Mount("TestVolume"+SnapshotNameSeparator+"TestFsSnapshot", MountOptions(0))
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("Mount() of snapshot without MountReadOnly should have failed with ReadOnlyError: %v", err)
+	}
+	_, err = Mount("TestVolume"+SnapshotNameSeparator+"NoSuchSnapshot", MountReadOnly)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Mount() of unknown snapshot should have failed with NotFoundError: %v", err)
+	}
+
+	snapshotMountHandle, err := Mount("TestVolume"+SnapshotNameSeparator+"TestFsSnapshot", MountReadOnly)
+	if nil != err {
+		t.Fatalf("Mount() of snapshot returned error: %v", err)
+	}
+
+	foundInodeNumber, err := snapshotMountHandle.Lookup(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if nil != err {
+		t.Fatalf("Lookup() via snapshot returned error: %v", err)
+	}
+	if fileInodeNumber != foundInodeNumber {
+		t.Fatalf("Lookup() via snapshot returned inode number %v, expected %v", foundInodeNumber, fileInodeNumber)
+	}
+
+	buf, err := snapshotMountHandle.Read(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, 0, 4, nil)
+	if nil != err {
+		t.Fatalf("Read() via snapshot returned error: %v", err)
+	}
+	if 0 != bytes.Compare([]byte("snap"), buf) {
+		t.Fatalf("Read() via snapshot returned unexpected buf: %v", buf)
+	}
+
+	err = mS.volStruct.VolumeHandle.DeleteSnapshot("TestFsSnapshot")
+	if nil != err {
+		t.Fatalf("DeleteSnapshot() returned error: %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	rootDirInodeNumber := inode.RootDirInodeNumber
+	basename := "read_only.test"
+
+	fileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	readOnlyMountHandle, err := Mount("TestVolume", MountReadOnly)
+	if nil != err {
+		t.Fatalf("Mount() with MountReadOnly returned error: %v", err)
+	}
+
+	_, err = readOnlyMountHandle.Lookup(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if nil != err {
+		t.Fatalf("Lookup() via read-only mount returned error: %v", err)
+	}
+	_, err = readOnlyMountHandle.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, "read_only.create", inode.PosixModePerm)
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("Create() via read-only mount should have failed with ReadOnlyError: %v", err)
+	}
+	_, err = readOnlyMountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, 0, []byte("data"), nil)
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("Write() via read-only mount should have failed with ReadOnlyError: %v", err)
+	}
+	err = readOnlyMountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("Unlink() via read-only mount should have failed with ReadOnlyError: %v", err)
+	}
+
+	statVFS, err := readOnlyMountHandle.StatVfs()
+	if nil != err {
+		t.Fatalf("StatVfs() via read-only mount returned error: %v", err)
+	}
+	if StatVFSMountFlagReadOnly != statVFS[StatVFSMountFlags] {
+		t.Fatalf("StatVfs() via read-only mount returned unexpected mount flags: %v", statVFS[StatVFSMountFlags])
+	}
+
+	// A read-only volume makes even read-write mounts read-only
+
+	mS.volStruct.Lock()
+	mS.volStruct.readOnly = true
+	mS.volStruct.Unlock()
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("Unlink() on read-only volume should have failed with ReadOnlyError: %v", err)
+	}
+
+	// ...and restricts FSCK to a dry run
+
+	_, err = ValidateVolume("TestVolume", true, nil)
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("ValidateVolume() repair on read-only volume should have failed with ReadOnlyError: %v", err)
+	}
+	_, err = ValidateVolume("TestVolume", false, nil)
+	if blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("ValidateVolume() dry run on read-only volume should not have failed with ReadOnlyError: %v", err)
+	}
+
+	mS.volStruct.Lock()
+	mS.volStruct.readOnly = false
+	mS.volStruct.Unlock()
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, rootDirInodeNumber, basename)
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+}
@@ -31,6 +31,7 @@ type mountStruct struct {
 	options                MountOptions
 	volStruct              *volumeStruct
 	headhunterVolumeHandle headhunter.VolumeHandle
+	flockMap               map[inode.InodeNumber]bool // Inodes on which this mount holds locks (protected by volStruct.FLockMutex)
 }
 
 type volumeStruct struct {
@@ -39,8 +40,13 @@ type volumeStruct struct {
 	doCheckpointPerFlush     bool
 	maxFlushTime             time.Duration
 	FLockMap                 map[inode.InodeNumber]*list.List
+	FLockMutex               sync.Mutex                              // Serializes access to FLockMap, FLockWaitMap, & flockWaiterMap
+	FLockWaitMap             map[inode.InodeNumber]*list.List        // Per-inode queue of *flockWaiterStruct's blocked in F_SETLKW
+	flockWaiterMap           map[flockOwnerStruct]*flockWaiterStruct // Key == owner of each *flockWaiterStruct (used to detect deadlocks)
 	inFlightFileInodeDataMap map[inode.InodeNumber]*inFlightFileInodeDataStruct
 	mountList                []MountID
+	readOnly                 bool   // [Volume:<VolumeName>]ReadOnly (always true for a snapshot)
+	snapshotOf               string // if != "", volumeName == snapshotOf + SnapshotNameSeparator + <snapshotName>
 	inode.VolumeHandle
 }
 
@@ -97,6 +103,8 @@ func Up(confMap conf.ConfMap) (err error) {
 				volume = &volumeStruct{
 					volumeName:               volumeName,
 					FLockMap:                 make(map[inode.InodeNumber]*list.List),
+					FLockWaitMap:             make(map[inode.InodeNumber]*list.List),
+					flockWaiterMap:           make(map[flockOwnerStruct]*flockWaiterStruct),
 					inFlightFileInodeDataMap: make(map[inode.InodeNumber]*inFlightFileInodeDataStruct),
 					mountList:                make([]MountID, 0),
 				}
@@ -109,6 +117,8 @@ func Up(confMap conf.ConfMap) (err error) {
 				}
 				logger.Infof("Checkpoint per Flush for volume %v is %v", volume.volumeName, volume.doCheckpointPerFlush)
 
+				volume.adoptReadOnly(confMap)
+
 				flowControlName, err = confMap.FetchOptionValueString(volumeSectionName, "FlowControl")
 				if nil != err {
 					return
@@ -196,8 +206,13 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 
 	removedVolumeList = make([]string, 0, len(globals.volumeMap))
 
-	for volumeName = range globals.volumeMap {
-		_, ok = updatedVolumeMap[volumeName]
+	for volumeName, volume = range globals.volumeMap {
+		if "" == volume.snapshotOf {
+			_, ok = updatedVolumeMap[volumeName]
+		} else {
+			// Mounted snapshots remain only so long as the volume they were taken of remains
+			_, ok = updatedVolumeMap[volume.snapshotOf]
+		}
 		if !ok {
 			removedVolumeList = append(removedVolumeList, volumeName)
 		}
@@ -209,6 +224,7 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 			delete(globals.mountMap, id)
 		}
 		volume.untrackInFlightFileInodeDataAll()
+		volume.flockInterruptWaitersAll()
 		globals.Lock()
 		delete(globals.volumeMap, volumeName)
 		globals.Unlock()
@@ -266,6 +282,8 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 					volume = &volumeStruct{
 						volumeName:               volumeName,
 						FLockMap:                 make(map[inode.InodeNumber]*list.List),
+						FLockWaitMap:             make(map[inode.InodeNumber]*list.List),
+						flockWaiterMap:           make(map[flockOwnerStruct]*flockWaiterStruct),
 						inFlightFileInodeDataMap: make(map[inode.InodeNumber]*inFlightFileInodeDataStruct),
 						mountList:                make([]MountID, 0),
 					}
@@ -296,6 +314,8 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 
 					globals.volumeMap[volumeName] = volume
 				}
+
+				volume.adoptReadOnly(confMap)
 			}
 		} else {
 			err = fmt.Errorf("%v.PrimaryPeer cannot be multi-valued", volumeName)
@@ -309,6 +329,33 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 	return
 }
 
+// adoptReadOnly applies [Volume:<VolumeName>]ReadOnly. Data still in flight when a volume becomes
+// read-only (e.g. for a maintenance window) is flushed so that nothing further need be written.
+func (vS *volumeStruct) adoptReadOnly(confMap conf.ConfMap) {
+	var (
+		err         error
+		readOnly    bool
+		wasReadOnly bool
+	)
+
+	readOnly, err = confMap.FetchOptionValueBool(utils.VolumeNameConfSection(vS.volumeName), "ReadOnly")
+	if nil != err {
+		readOnly = false // TODO: eventually, just return
+	}
+
+	vS.Lock()
+	wasReadOnly = vS.readOnly
+	vS.readOnly = readOnly
+	vS.Unlock()
+
+	if readOnly && !wasReadOnly {
+		logger.Infof("Volume %v is read-only", vS.volumeName)
+		vS.untrackInFlightFileInodeDataAll()
+	} else if !readOnly && wasReadOnly {
+		logger.Infof("Volume %v is no longer read-only", vS.volumeName)
+	}
+}
+
 func Down() (err error) {
 	var (
 		volume *volumeStruct
@@ -8,14 +8,12 @@ package fs
 //
 
 import (
-	"fmt"
-
 	"github.com/swiftstack/ProxyFS/dlm"
 	"github.com/swiftstack/ProxyFS/inode"
 )
 
 func (vS *volumeStruct) makeLockID(inodeNumber inode.InodeNumber) (lockID string, err error) {
-	myLockID := fmt.Sprintf("vol.%s:ino.%d", vS.volumeName, inodeNumber)
+	myLockID := dlm.InodeLockID(vS.volumeName, uint64(inodeNumber))
 
 	return myLockID, nil
 }
@@ -2,22 +2,71 @@ package fs
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
 )
 
+type treeWalkInodeStruct struct {
+	refCount     uint64 // directory entries (including "." and "..") found referencing the inode
+	reached      bool   // inode has been reached via an entry other than "." or ".." (or is the root directory)
+	path         string // path by which the inode was first reached
+	problemFound bool   // a finding already reports the inode as unusable (so its LinkCount is not checked)
+}
+
 type treeWalkStruct struct {
-	volumeName       string
-	volumeHandle     inode.VolumeHandle
-	stopChan         chan bool
-	inodeRefCountMap map[inode.InodeNumber]uint64
+	volumeName     string
+	volumeHandle   inode.VolumeHandle
+	repair         bool
+	stopChan       chan bool
+	inodeMap       map[inode.InodeNumber]*treeWalkInodeStruct
+	walkIncomplete bool // some directory could not be walked... so refCounts cannot be trusted
+	fsckReport     *FSCKReport
 }
 
-func validateVolume(volumeName string, stopChan chan bool) (err error) {
+func validateVolume(volumeName string, repair bool, stopChan chan bool) (fsckReport *FSCKReport, err error) {
+	var (
+		finding       FSCKFinding
+		ok            bool
+		readOnly      bool
+		repairedCount uint64
+		stopped       bool
+		volStruct     *volumeStruct
+	)
+
 	tWS := &treeWalkStruct{
-		volumeName:       volumeName,
-		stopChan:         stopChan,
-		inodeRefCountMap: make(map[inode.InodeNumber]uint64),
+		volumeName: volumeName,
+		repair:     repair,
+		stopChan:   stopChan,
+		inodeMap:   make(map[inode.InodeNumber]*treeWalkInodeStruct),
+		fsckReport: &FSCKReport{Repair: repair, Findings: make([]FSCKFinding, 0)},
+	}
+
+	fsckReport = tWS.fsckReport
+
+	if repair {
+		// Repairs are writes... so a read-only volume only permits a dry run
+
+		globals.Lock()
+		volStruct, ok = globals.volumeMap[volumeName]
+		globals.Unlock()
+
+		if ok {
+			volStruct.Lock()
+			readOnly = volStruct.readOnly
+			volStruct.Unlock()
+		}
+
+		if readOnly {
+			stats.IncrementOperations(&stats.FsReadOnlyRejectedOps)
+			err = fmt.Errorf("%s: volume \"%v\" is read-only", utils.GetFnName(), volumeName)
+			err = blunder.AddError(err, blunder.ReadOnlyError)
+			return
+		}
 	}
 
 	tWS.volumeHandle, err = inode.FetchVolumeHandle(volumeName)
@@ -25,111 +74,195 @@ func validateVolume(volumeName string, stopChan chan bool) (err error) {
 		return
 	}
 
-	stopped, err := tWS.validateDirInode(inode.RootDirInodeNumber)
-	if (nil != err) || stopped {
+	tWS.inodeMap[inode.RootDirInodeNumber] = &treeWalkInodeStruct{reached: true, path: "/"}
+	fsckReport.InodesValidated = 1
+
+	stopped = tWS.validateDirInode(inode.RootDirInodeNumber)
+	if stopped {
+		err = nil
 		return
 	}
 
-	linkCountsFixed := uint64(0)
+	stopped, err = tWS.validateLinkCounts()
+	if stopped || (nil != err) {
+		return
+	}
 
-	for inodeNumber, inodeRefCount := range tWS.inodeRefCountMap {
-		select {
-		case _ = <-tWS.stopChan:
-			return
-		default:
-			inodeLinkCount, nonShadowingErr := tWS.volumeHandle.GetLinkCount(inodeNumber)
-			if nil != nonShadowingErr {
-				err = fmt.Errorf("%v.GetLinkCount(%v) failed: %v", tWS.volumeName, inodeNumber, nonShadowingErr)
-				return
-			}
-			if inodeLinkCount != inodeRefCount {
-				// TODO: Need to stat & log this
-				nonShadowingErr = tWS.volumeHandle.SetLinkCount(inodeNumber, inodeRefCount)
-				if nil != nonShadowingErr {
-					err = fmt.Errorf("%v.SetLinkCount(%v,) failed: %v", tWS.volumeName, inodeNumber, nonShadowingErr)
-					return
-				}
-				linkCountsFixed++
-			}
+	for _, finding = range fsckReport.Findings {
+		if finding.Repaired {
+			repairedCount++
 		}
 	}
 
-	switch linkCountsFixed {
-	case uint64(0):
+	logger.Infof("FSCK of volume %v validated %v inodes finding %v problem(s) (%v repaired)", volumeName, fsckReport.InodesValidated, len(fsckReport.Findings), repairedCount)
+
+	switch len(fsckReport.Findings) {
+	case 0:
 		err = nil
-	case uint64(1):
-		err = fmt.Errorf("1 inode needed its LinkCount corrected")
+	case 1:
+		err = fmt.Errorf("1 problem found (%v repaired)", repairedCount)
 	default:
-		err = fmt.Errorf("%v inodes needed their LinkCount corrected", linkCountsFixed)
+		err = fmt.Errorf("%v problems found (%v repaired)", len(fsckReport.Findings), repairedCount)
 	}
 
 	return
 }
 
-func (tWS *treeWalkStruct) validateDirInode(dirInodeNumber inode.InodeNumber) (stopped bool, err error) {
-	err = tWS.volumeHandle.Validate(dirInodeNumber)
+func (tWS *treeWalkStruct) addFinding(inodeNumber inode.InodeNumber, problem FSCKProblem, detail string, action string, repaired bool) {
+	var (
+		path string
+	)
+
+	tWI, ok := tWS.inodeMap[inodeNumber]
+	if ok {
+		path = tWI.path
+		if FSCKProblemLinkCountMismatch != problem {
+			tWI.problemFound = true
+		}
+	}
+
+	stats.IncrementOperations(&stats.FsVolumeValidateFindingOps)
+
+	if repaired {
+		stats.IncrementOperations(&stats.FsVolumeValidateRepairOps)
+	}
+
+	logger.Warnf("FSCK of volume %v found %v at inode %v (\"%v\"): %v... action: %v", tWS.volumeName, problem, inodeNumber, path, detail, action)
+
+	tWS.fsckReport.Findings = append(tWS.fsckReport.Findings, FSCKFinding{
+		InodeNumber: inodeNumber,
+		Path:        path,
+		Problem:     problem,
+		Detail:      detail,
+		Action:      action,
+		Repaired:    repaired,
+	})
+}
+
+func (tWS *treeWalkStruct) validateDirInode(dirInodeNumber inode.InodeNumber) (stopped bool) {
+	err := tWS.volumeHandle.Validate(dirInodeNumber)
 	if nil != err {
+		tWS.walkIncomplete = true
+		tWS.addFinding(dirInodeNumber, FSCKProblemCorruptInode, fmt.Sprintf("Validate() failed: %v", err), "none (directory not walked)", false)
 		stopped = false
-		err = fmt.Errorf("%v.Validate(%v,,) failed: %v", tWS.volumeName, dirInodeNumber, err)
-		// TODO: Need to stat & log this unless inode.Validate() did so
 		return
 	}
 
 	dirEntrySlice, _, err := tWS.volumeHandle.ReadDir(dirInodeNumber, 0, 0)
 	if nil != err {
+		tWS.walkIncomplete = true
+		tWS.addFinding(dirInodeNumber, FSCKProblemUnreadableDirectory, fmt.Sprintf("ReadDir() failed: %v", err), "none (directory not walked)", false)
 		stopped = false
-		err = fmt.Errorf("%v.ReadDir(%v,,) failed: %v", tWS.volumeName, dirInodeNumber, err)
 		return
 	}
 
+	dirPath := tWS.inodeMap[dirInodeNumber].path
+
 	for _, dirEntry := range dirEntrySlice {
 		select {
 		case _ = <-tWS.stopChan:
 			stopped = true
-			err = nil
 			return
 		default:
-			prevInodeRefCount, ok := tWS.inodeRefCountMap[dirEntry.InodeNumber]
-			if ok {
-				tWS.inodeRefCountMap[dirEntry.InodeNumber] = 1 + prevInodeRefCount
+			tWI, ok := tWS.inodeMap[dirEntry.InodeNumber]
+			if !ok {
+				tWI = &treeWalkInodeStruct{}
+				tWS.inodeMap[dirEntry.InodeNumber] = tWI
+			}
+
+			tWI.refCount++
+
+			if ("." == dirEntry.Basename) || (".." == dirEntry.Basename) || tWI.reached {
+				continue
+			}
+
+			tWI.reached = true
+			if "/" == dirPath {
+				tWI.path = dirPath + dirEntry.Basename
 			} else {
-				tWS.inodeRefCountMap[dirEntry.InodeNumber] = 1
+				tWI.path = dirPath + "/" + dirEntry.Basename
 			}
 
-			if ("." != dirEntry.Basename) && (".." != dirEntry.Basename) {
-				inodeType, nonShadowingErr := tWS.volumeHandle.GetType(dirEntry.InodeNumber)
-				if nil != nonShadowingErr {
-					stopped = false
-					err = fmt.Errorf("%v.ReadDir(%v,,) failed: %v", tWS.volumeName, dirEntry.InodeNumber, nonShadowingErr)
-					return
-				}
+			tWS.fsckReport.InodesValidated++
 
-				if inode.DirType == inodeType {
-					stopped, err = tWS.validateDirInode(dirEntry.InodeNumber)
-					if stopped || (nil != err) {
-						return
-					}
-				} else {
-					err = tWS.validateNonDirInode(dirEntry.InodeNumber)
-					if nil != err {
-						stopped = false
-						return
-					}
+			inodeType, nonShadowingErr := tWS.volumeHandle.GetType(dirEntry.InodeNumber)
+			if nil != nonShadowingErr {
+				tWS.addFinding(dirEntry.InodeNumber, FSCKProblemDanglingDirEntry, fmt.Sprintf("GetType() failed: %v", nonShadowingErr), "none (manual repair required)", false)
+				continue
+			}
+
+			if inode.DirType == inodeType {
+				stopped = tWS.validateDirInode(dirEntry.InodeNumber)
+				if stopped {
+					return
 				}
+			} else {
+				tWS.validateNonDirInode(dirEntry.InodeNumber)
 			}
 		}
 	}
 
 	stopped = false
-	err = nil
 	return
 }
 
-func (tWS *treeWalkStruct) validateNonDirInode(nonDirInodeNumber inode.InodeNumber) (err error) {
-	err = tWS.volumeHandle.Validate(nonDirInodeNumber)
+func (tWS *treeWalkStruct) validateNonDirInode(nonDirInodeNumber inode.InodeNumber) {
+	err := tWS.volumeHandle.Validate(nonDirInodeNumber)
 	if nil != err {
-		err = fmt.Errorf("%v.Validate(%v,,) failed: %v", tWS.volumeName, nonDirInodeNumber, err)
-		// TODO: Need to stat & log this unless inode.Validate() did so
+		tWS.addFinding(nonDirInodeNumber, FSCKProblemCorruptInode, fmt.Sprintf("Validate() failed: %v", err), "none (manual repair required)", false)
 	}
+}
+
+// validateLinkCounts compares the LinkCount of each inode reached (in InodeNumber order) to its refCount.
+func (tWS *treeWalkStruct) validateLinkCounts() (stopped bool, err error) {
+	inodeNumbers := make([]inode.InodeNumber, 0, len(tWS.inodeMap))
+
+	for inodeNumber, tWI := range tWS.inodeMap {
+		if tWI.reached && !tWI.problemFound {
+			inodeNumbers = append(inodeNumbers, inodeNumber)
+		}
+	}
+
+	sort.Slice(inodeNumbers, func(i int, j int) bool { return inodeNumbers[i] < inodeNumbers[j] })
+
+	for _, inodeNumber := range inodeNumbers {
+		select {
+		case _ = <-tWS.stopChan:
+			stopped = true
+			err = nil
+			return
+		default:
+			inodeRefCount := tWS.inodeMap[inodeNumber].refCount
+
+			inodeLinkCount, nonShadowingErr := tWS.volumeHandle.GetLinkCount(inodeNumber)
+			if nil != nonShadowingErr {
+				stopped = false
+				err = fmt.Errorf("%v.GetLinkCount(%v) failed: %v", tWS.volumeName, inodeNumber, nonShadowingErr)
+				return
+			}
+			if inodeLinkCount == inodeRefCount {
+				continue
+			}
+
+			detail := fmt.Sprintf("LinkCount is %v but %v directory entries reference it", inodeLinkCount, inodeRefCount)
+
+			if tWS.walkIncomplete {
+				tWS.addFinding(inodeNumber, FSCKProblemLinkCountMismatch, detail, "none (directory tree could not be completely walked)", false)
+			} else if tWS.repair {
+				nonShadowingErr = tWS.volumeHandle.SetLinkCount(inodeNumber, inodeRefCount)
+				if nil != nonShadowingErr {
+					stopped = false
+					err = fmt.Errorf("%v.SetLinkCount(%v,) failed: %v", tWS.volumeName, inodeNumber, nonShadowingErr)
+					return
+				}
+				tWS.addFinding(inodeNumber, FSCKProblemLinkCountMismatch, detail, fmt.Sprintf("LinkCount set to %v", inodeRefCount), true)
+			} else {
+				tWS.addFinding(inodeNumber, FSCKProblemLinkCountMismatch, detail, fmt.Sprintf("set LinkCount to %v", inodeRefCount), false)
+			}
+		}
+	}
+
+	stopped = false
+	err = nil
 	return
 }
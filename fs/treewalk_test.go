@@ -0,0 +1,97 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+func testValidateVolumeFinding(t *testing.T, fsckReport *FSCKReport, inodeNumber inode.InodeNumber) (finding *FSCKFinding) {
+	for i := range fsckReport.Findings {
+		if inodeNumber == fsckReport.Findings[i].InodeNumber {
+			if nil != finding {
+				t.Fatalf("ValidateVolume() reported inode %v more than once: %+v", inodeNumber, fsckReport.Findings)
+			}
+			finding = &fsckReport.Findings[i]
+		}
+	}
+	return
+}
+
+func TestValidateVolume(t *testing.T) {
+	dirInodeNumber, err := mS.Mkdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "validate_volume.test", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() failed: %v", err)
+	}
+	fileInodeNumber, err := mS.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, dirInodeNumber, "file", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	err = mS.volStruct.SetLinkCount(fileInodeNumber, 3)
+	if nil != err {
+		t.Fatalf("SetLinkCount() failed: %v", err)
+	}
+
+	// A dry run reports, but does not repair, the incorrect LinkCount
+
+	fsckReport, err := ValidateVolume("TestVolume", false, make(chan bool, 1))
+	if nil == err {
+		t.Fatalf("ValidateVolume() dry run should have reported an error")
+	}
+	if fsckReport.Repair || (3 > fsckReport.InodesValidated) {
+		t.Fatalf("ValidateVolume() dry run returned unexpected fsckReport: %+v", fsckReport)
+	}
+	finding := testValidateVolumeFinding(t, fsckReport, fileInodeNumber)
+	if (nil == finding) || ("/validate_volume.test/file" != finding.Path) || (FSCKProblemLinkCountMismatch != finding.Problem) || finding.Repaired {
+		t.Fatalf("ValidateVolume() dry run returned unexpected finding: %+v", finding)
+	}
+	if nil != testValidateVolumeFinding(t, fsckReport, dirInodeNumber) {
+		t.Fatalf("ValidateVolume() dry run unexpectedly reported directory inode %v", dirInodeNumber)
+	}
+
+	linkCount, err := mS.volStruct.GetLinkCount(fileInodeNumber)
+	if (nil != err) || (3 != linkCount) {
+		t.Fatalf("ValidateVolume() dry run should not have modified LinkCount (got %v, err: %v)", linkCount, err)
+	}
+
+	// A repairing run corrects it
+
+	fsckReport, err = ValidateVolume("TestVolume", true, make(chan bool, 1))
+	if nil == err {
+		t.Fatalf("ValidateVolume() repair should have reported an error")
+	}
+	finding = testValidateVolumeFinding(t, fsckReport, fileInodeNumber)
+	if (nil == finding) || (FSCKProblemLinkCountMismatch != finding.Problem) || !finding.Repaired {
+		t.Fatalf("ValidateVolume() repair returned unexpected finding: %+v", finding)
+	}
+
+	linkCount, err = mS.volStruct.GetLinkCount(fileInodeNumber)
+	if (nil != err) || (1 != linkCount) {
+		t.Fatalf("ValidateVolume() repair should have set LinkCount to 1 (got %v, err: %v)", linkCount, err)
+	}
+
+	fsckReport, err = ValidateVolume("TestVolume", true, make(chan bool, 1))
+	if nil != testValidateVolumeFinding(t, fsckReport, fileInodeNumber) {
+		t.Fatalf("ValidateVolume() following repair unexpectedly reported inode %v (err: %v)", fileInodeNumber, err)
+	}
+
+	// A stopped run returns what it has found so far without error
+
+	stopChan := make(chan bool, 1)
+	stopChan <- true
+
+	fsckReport, err = ValidateVolume("TestVolume", false, stopChan)
+	if (nil != err) || (nil == fsckReport) {
+		t.Fatalf("ValidateVolume() stopped run returned unexpected fsckReport: %+v (err: %v)", fsckReport, err)
+	}
+
+	err = mS.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, dirInodeNumber, "file")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+	err = mS.Rmdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "validate_volume.test")
+	if nil != err {
+		t.Fatalf("Rmdir() failed: %v", err)
+	}
+}
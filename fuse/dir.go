@@ -309,3 +309,19 @@ func (d Dir) Link(ctx context.Context, req *fuselib.LinkRequest, old fusefslib.N
 
 	return old, err
 }
+
+func (d Dir) Getxattr(ctx context.Context, req *fuselib.GetxattrRequest, resp *fuselib.GetxattrResponse) error {
+	return getxattr(d.mountHandle, d.inodeNumber, req, resp)
+}
+
+func (d Dir) Listxattr(ctx context.Context, req *fuselib.ListxattrRequest, resp *fuselib.ListxattrResponse) error {
+	return listxattr(d.mountHandle, d.inodeNumber, req, resp)
+}
+
+func (d Dir) Setxattr(ctx context.Context, req *fuselib.SetxattrRequest) error {
+	return setxattr(d.mountHandle, d.inodeNumber, req)
+}
+
+func (d Dir) Removexattr(ctx context.Context, req *fuselib.RemovexattrRequest) error {
+	return removexattr(d.mountHandle, d.inodeNumber, req)
+}
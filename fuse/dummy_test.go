@@ -1,6 +0,0 @@
-package fuse
-
-import "testing"
-
-func TestDUMMY(t *testing.T) {
-}
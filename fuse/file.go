@@ -158,3 +158,19 @@ func (f File) Write(ctx context.Context, req *fuselib.WriteRequest, resp *fuseli
 	}
 	return err
 }
+
+func (f File) Getxattr(ctx context.Context, req *fuselib.GetxattrRequest, resp *fuselib.GetxattrResponse) error {
+	return getxattr(f.mountHandle, f.inodeNumber, req, resp)
+}
+
+func (f File) Listxattr(ctx context.Context, req *fuselib.ListxattrRequest, resp *fuselib.ListxattrResponse) error {
+	return listxattr(f.mountHandle, f.inodeNumber, req, resp)
+}
+
+func (f File) Setxattr(ctx context.Context, req *fuselib.SetxattrRequest) error {
+	return setxattr(f.mountHandle, f.inodeNumber, req)
+}
+
+func (f File) Removexattr(ctx context.Context, req *fuselib.RemovexattrRequest) error {
+	return removexattr(f.mountHandle, f.inodeNumber, req)
+}
@@ -113,3 +113,19 @@ func (s Symlink) Readlink(ctx context.Context, req *fuselib.ReadlinkRequest) (st
 	}
 	return target, err
 }
+
+func (s Symlink) Getxattr(ctx context.Context, req *fuselib.GetxattrRequest, resp *fuselib.GetxattrResponse) error {
+	return getxattr(s.mountHandle, s.inodeNumber, req, resp)
+}
+
+func (s Symlink) Listxattr(ctx context.Context, req *fuselib.ListxattrRequest, resp *fuselib.ListxattrResponse) error {
+	return listxattr(s.mountHandle, s.inodeNumber, req, resp)
+}
+
+func (s Symlink) Setxattr(ctx context.Context, req *fuselib.SetxattrRequest) error {
+	return setxattr(s.mountHandle, s.inodeNumber, req)
+}
+
+func (s Symlink) Removexattr(ctx context.Context, req *fuselib.RemovexattrRequest) error {
+	return removexattr(s.mountHandle, s.inodeNumber, req)
+}
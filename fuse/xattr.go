@@ -0,0 +1,94 @@
+package fuse
+
+import (
+	"fmt"
+
+	fuselib "bazil.org/fuse"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+// The extended attribute handlers below are shared by Dir, File, and Symlink. Each maps
+// onto an inode stream of the same name. The fs.MiddlewareStream carries the object
+// metadata maintained by the Swift middleware, so it is omitted from listings and may
+// not be set or removed via FUSE lest POSIX users corrupt it.
+
+func getxattr(mountHandle fs.MountHandle, inodeNumber inode.InodeNumber, req *fuselib.GetxattrRequest, resp *fuselib.GetxattrResponse) (err error) {
+	value, err := mountHandle.GetXAttr(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, inodeNumber, req.Name)
+	if nil != err {
+		err = newFuseError(err)
+		return
+	}
+
+	if (0 != req.Size) && (uint64(len(value)) > uint64(req.Size)) {
+		err = fmt.Errorf("[fuse]getxattr() value of %v is %v bytes but only %v requested", req.Name, len(value), req.Size)
+		err = blunder.AddError(err, blunder.OutOfRangeError)
+		err = newFuseError(err)
+		return
+	}
+
+	resp.Xattr = value
+
+	err = nil
+	return
+}
+
+func listxattr(mountHandle fs.MountHandle, inodeNumber inode.InodeNumber, req *fuselib.ListxattrRequest, resp *fuselib.ListxattrResponse) (err error) {
+	streamNames, err := mountHandle.ListXAttr(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, inodeNumber)
+	if nil != err {
+		err = newFuseError(err)
+		return
+	}
+
+	for _, streamName := range streamNames {
+		if fs.MiddlewareStream != streamName {
+			resp.Append(streamName)
+		}
+	}
+
+	if (0 != req.Size) && (uint64(len(resp.Xattr)) > uint64(req.Size)) {
+		err = fmt.Errorf("[fuse]listxattr() names are %v bytes but only %v requested", len(resp.Xattr), req.Size)
+		err = blunder.AddError(err, blunder.OutOfRangeError)
+		err = newFuseError(err)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func setxattr(mountHandle fs.MountHandle, inodeNumber inode.InodeNumber, req *fuselib.SetxattrRequest) (err error) {
+	if fs.MiddlewareStream == req.Name {
+		err = fmt.Errorf("[fuse]setxattr() of %v not permitted", req.Name)
+		err = blunder.AddError(err, blunder.NotPermError)
+		err = newFuseError(err)
+		return
+	}
+
+	// XATTR_CREATE and XATTR_REPLACE share their values with those fs.SetXAttr() expects
+
+	err = mountHandle.SetXAttr(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, inodeNumber, req.Name, req.Xattr, int(req.Flags))
+	if nil != err {
+		err = newFuseError(err)
+	}
+
+	return
+}
+
+func removexattr(mountHandle fs.MountHandle, inodeNumber inode.InodeNumber, req *fuselib.RemovexattrRequest) (err error) {
+	if fs.MiddlewareStream == req.Name {
+		err = fmt.Errorf("[fuse]removexattr() of %v not permitted", req.Name)
+		err = blunder.AddError(err, blunder.NotPermError)
+		err = newFuseError(err)
+		return
+	}
+
+	err = mountHandle.RemoveXAttr(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, inodeNumber, req.Name)
+	if nil != err {
+		err = newFuseError(err)
+	}
+
+	return
+}
@@ -0,0 +1,218 @@
+package fuse
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	fuselib "bazil.org/fuse"
+	"golang.org/x/net/context"
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/testenv"
+)
+
+var testMountHandle fs.MountHandle
+
+func testSetup() (err error) {
+	testConfMap, err := testenv.Up("ProxyFS_test_fuse_", 52185, 45267)
+	if nil != err {
+		return
+	}
+
+	err = fs.Up(testConfMap)
+	if nil != err {
+		_ = testenv.Down()
+		return
+	}
+
+	err = nil
+	return
+}
+
+func testTeardown() (err error) {
+	fs.Down()
+
+	err = testenv.Down()
+
+	return
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	err := testSetup()
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "fuse test setup failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	testMountHandle, err = fs.Mount("TestVolume", fs.MountOptions(0))
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "fs.Mount() failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	testResults := m.Run()
+
+	err = testTeardown()
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "fuse test teardown failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(testResults)
+}
+
+func expectErrno(t *testing.T, op string, err error, expectedErrno syscall.Errno) {
+	if 0 == expectedErrno {
+		if nil != err {
+			t.Fatalf("%s unexpectedly failed: %v", op, err)
+		}
+		return
+	}
+	if nil == err {
+		t.Fatalf("%s unexpectedly succeeded (expected %v)", op, expectedErrno)
+	}
+	fuseErr, ok := err.(fuselib.ErrorNumber)
+	if !ok {
+		t.Fatalf("%s returned non-errno error: %v", op, err)
+	}
+	if fuselib.Errno(expectedErrno) != fuseErr.Errno() {
+		t.Fatalf("%s returned errno %v (expected %v)", op, fuseErr.Errno(), expectedErrno)
+	}
+}
+
+type xattrNode interface {
+	Getxattr(ctx context.Context, req *fuselib.GetxattrRequest, resp *fuselib.GetxattrResponse) error
+	Listxattr(ctx context.Context, req *fuselib.ListxattrRequest, resp *fuselib.ListxattrResponse) error
+	Setxattr(ctx context.Context, req *fuselib.SetxattrRequest) error
+	Removexattr(ctx context.Context, req *fuselib.RemovexattrRequest) error
+}
+
+const (
+	testXattrCreate  = 1 // XATTR_CREATE
+	testXattrReplace = 2 // XATTR_REPLACE
+)
+
+func testXattrNode(t *testing.T, nodeName string, inodeNumber inode.InodeNumber, node xattrNode) {
+	var (
+		ctx           = context.Background()
+		err           error
+		getxattrResp  *fuselib.GetxattrResponse
+		header        = fuselib.Header{Uid: uint32(inode.InodeRootUserID), Gid: uint32(inode.InodeRootGroupID)}
+		listxattrResp *fuselib.ListxattrResponse
+	)
+
+	setxattr := func(name string, value string, flags uint32) error {
+		return node.Setxattr(ctx, &fuselib.SetxattrRequest{Header: header, Name: name, Xattr: []byte(value), Flags: flags})
+	}
+	getxattr := func(name string, size uint32) error {
+		getxattrResp = &fuselib.GetxattrResponse{}
+		return node.Getxattr(ctx, &fuselib.GetxattrRequest{Header: header, Name: name, Size: size}, getxattrResp)
+	}
+	listxattr := func(size uint32) error {
+		listxattrResp = &fuselib.ListxattrResponse{}
+		return node.Listxattr(ctx, &fuselib.ListxattrRequest{Header: header, Size: size}, listxattrResp)
+	}
+	removexattr := func(name string) error {
+		return node.Removexattr(ctx, &fuselib.RemovexattrRequest{Header: header, Name: name})
+	}
+
+	// XATTR_REPLACE of a missing xattr fails and XATTR_CREATE of it succeeds
+
+	expectErrno(t, nodeName+" Setxattr(XATTR_REPLACE) of missing xattr", setxattr("user.a", "one", testXattrReplace), unix.ENODATA)
+	expectErrno(t, nodeName+" Setxattr(XATTR_CREATE) of missing xattr", setxattr("user.a", "one", testXattrCreate), 0)
+
+	// XATTR_CREATE of an existing xattr fails and XATTR_REPLACE of it succeeds
+
+	expectErrno(t, nodeName+" Setxattr(XATTR_CREATE) of existing xattr", setxattr("user.a", "two", testXattrCreate), unix.EEXIST)
+	expectErrno(t, nodeName+" Getxattr() after failed XATTR_CREATE", getxattr("user.a", 0), 0)
+	if !bytes.Equal([]byte("one"), getxattrResp.Xattr) {
+		t.Fatalf("%s Getxattr() after failed XATTR_CREATE returned %q", nodeName, getxattrResp.Xattr)
+	}
+	expectErrno(t, nodeName+" Setxattr(XATTR_REPLACE) of existing xattr", setxattr("user.a", "three", testXattrReplace), 0)
+	expectErrno(t, nodeName+" Getxattr() after XATTR_REPLACE", getxattr("user.a", 5), 0)
+	if !bytes.Equal([]byte("three"), getxattrResp.Xattr) {
+		t.Fatalf("%s Getxattr() after XATTR_REPLACE returned %q", nodeName, getxattrResp.Xattr)
+	}
+
+	// Flags of zero either creates or replaces
+
+	expectErrno(t, nodeName+" Setxattr(0) of missing xattr", setxattr("user.b", "four", 0), 0)
+	expectErrno(t, nodeName+" Setxattr(0) of existing xattr", setxattr("user.b", "five", 0), 0)
+
+	// Getxattr() reports ERANGE for a short buffer and ENODATA for a missing xattr
+
+	expectErrno(t, nodeName+" Getxattr() into short buffer", getxattr("user.a", 4), unix.ERANGE)
+	expectErrno(t, nodeName+" Getxattr() of missing xattr", getxattr("user.c", 0), unix.ENODATA)
+
+	// The middleware stream is hidden from Listxattr() and may not be set or removed
+
+	err = testMountHandle.SetXAttr(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inodeNumber, fs.MiddlewareStream, []byte("metadata"), 0)
+	if nil != err {
+		t.Fatalf("%s SetXAttr(fs.MiddlewareStream) failed: %v", nodeName, err)
+	}
+
+	expectErrno(t, nodeName+" Listxattr()", listxattr(0), 0)
+	listedNames := strings.Split(strings.TrimSuffix(string(listxattrResp.Xattr), "\x00"), "\x00")
+	sort.Strings(listedNames)
+	if (2 != len(listedNames)) || ("user.a" != listedNames[0]) || ("user.b" != listedNames[1]) {
+		t.Fatalf("%s Listxattr() returned %q", nodeName, listxattrResp.Xattr)
+	}
+	expectErrno(t, nodeName+" Listxattr() into short buffer", listxattr(uint32(len("user.a\x00user.b\x00")-1)), unix.ERANGE)
+
+	expectErrno(t, nodeName+" Setxattr() of middleware stream", setxattr(fs.MiddlewareStream, "bogus", 0), unix.EPERM)
+	expectErrno(t, nodeName+" Removexattr() of middleware stream", removexattr(fs.MiddlewareStream), unix.EPERM)
+
+	middlewareMetadata, err := testMountHandle.GetXAttr(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inodeNumber, fs.MiddlewareStream)
+	if nil != err {
+		t.Fatalf("%s GetXAttr(fs.MiddlewareStream) failed: %v", nodeName, err)
+	}
+	if !bytes.Equal([]byte("metadata"), middlewareMetadata) {
+		t.Fatalf("%s middleware stream altered to %q", nodeName, middlewareMetadata)
+	}
+
+	// Removexattr() succeeds once and then reports ENODATA
+
+	expectErrno(t, nodeName+" Removexattr() of existing xattr", removexattr("user.a"), 0)
+	expectErrno(t, nodeName+" Removexattr() of missing xattr", removexattr("user.a"), unix.ENODATA)
+	expectErrno(t, nodeName+" Getxattr() of removed xattr", getxattr("user.a", 0), unix.ENODATA)
+}
+
+func TestXattr(t *testing.T) {
+	fileInodeNumber, err := testMountHandle.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "XattrFile", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	dirInodeNumber, err := testMountHandle.Mkdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "XattrDir", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() failed: %v", err)
+	}
+	symlinkInodeNumber, err := testMountHandle.Symlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "XattrSymlink", "XattrFile")
+	if nil != err {
+		t.Fatalf("Symlink() failed: %v", err)
+	}
+
+	testXattrNode(t, "File", fileInodeNumber, File{mountHandle: testMountHandle, inodeNumber: fileInodeNumber})
+	testXattrNode(t, "Dir", dirInodeNumber, Dir{mountHandle: testMountHandle, inodeNumber: dirInodeNumber})
+	testXattrNode(t, "Symlink", symlinkInodeNumber, Symlink{mountHandle: testMountHandle, inodeNumber: symlinkInodeNumber})
+
+	for _, basename := range []string{"XattrFile", "XattrSymlink"} {
+		err = testMountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, basename)
+		if nil != err {
+			t.Fatalf("Unlink(%v) failed: %v", basename, err)
+		}
+	}
+	err = testMountHandle.Rmdir(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "XattrDir")
+	if nil != err {
+		t.Fatalf("Rmdir() failed: %v", err)
+	}
+}
@@ -3,14 +3,54 @@ package headhunter
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
+// SnapshotNameForbiddenChars lists the characters that may not appear in a snapshot name.
+const SnapshotNameForbiddenChars = "/@"
+
+// VolumeStats holds the running totals maintained for a given volume. The totals are
+// persisted in each checkpoint and adjusted by any subsequently replayed transactions.
+type VolumeStats struct {
+	InodeCount      uint64 // number of inodeRecs
+	LogSegmentBytes uint64 // bytes held in live log segments (i.e. those with a logSegmentRec)
+	ReferencedBytes uint64 // bytes of those log segments still referenced by inodes
+}
+
+// OwnerStats holds the running totals maintained for each user or group owning inodes in a
+// given volume. Like VolumeStats, they are persisted in each checkpoint.
+type OwnerStats struct {
+	Bytes  uint64 // bytes of log segments referenced by inodes of this owner
+	Inodes uint64 // number of inodes of this owner
+}
+
+// SnapshotInfo describes a named, read-only snapshot of a volume.
+type SnapshotInfo struct {
+	ID           uint64
+	Name         string
+	CreationTime time.Time
+	VolumeStats  VolumeStats // as of the snapshot
+}
+
+// CheckpointStatus reports whether a volume's checkpoints are being persisted. Should a checkpoint
+// fail, the volume is degraded (and its checkpoint retried) until a subsequent checkpoint succeeds.
+type CheckpointStatus struct {
+	Degraded       bool
+	DegradedSince  time.Time // time of the first failed checkpoint (zero if !Degraded)
+	FailedAttempts uint64    // number of failed checkpoints since the last successful one
+	LastError      string    // error reported by the most recent failed checkpoint ("" if !Degraded)
+}
+
 // VolumeHandle is used to operate on a given volume's database
 type VolumeHandle interface {
 	FetchNextCheckPointDoneWaitGroup() (wg *sync.WaitGroup)
+	Fenced() (fenced bool)
 	FetchNonce() (nonce uint64, err error)
 	GetInodeRec(inodeNumber uint64) (value []byte, ok bool, err error)
+	NextInodeNumber(prevInodeNumber uint64) (nextInodeNumber uint64, ok bool, err error)
 	PutInodeRec(inodeNumber uint64, value []byte) (err error)
 	PutInodeRecs(inodeNumbers []uint64, values [][]byte) (err error)
 	DeleteInodeRec(inodeNumber uint64) (err error)
@@ -20,7 +60,24 @@ type VolumeHandle interface {
 	GetBPlusTreeObject(objectNumber uint64) (value []byte, err error)
 	PutBPlusTreeObject(objectNumber uint64, value []byte) (err error)
 	DeleteBPlusTreeObject(objectNumber uint64) (err error)
+	FetchVolumeStats() (volumeStats VolumeStats)
+	AdjustVolumeStats(logSegmentBytesDelta int64, referencedBytesDelta int64)
+	VolumeStatsRebuildNeeded() (rebuildNeeded bool)
+	RebuildVolumeStats(referencedBytes uint64) (err error)
+	FetchOwnerStats() (userStats map[uint32]OwnerStats, groupStats map[uint32]OwnerStats)
+	FetchOwnerStatsByID(userID uint32, groupID uint32) (userStats OwnerStats, groupStats OwnerStats)
+	AdjustOwnerStats(userID uint32, groupID uint32, bytesDelta int64, inodesDelta int64)
+	OwnerStatsRebuildNeeded() (rebuildNeeded bool)
+	RebuildOwnerStats(userStats map[uint32]OwnerStats, groupStats map[uint32]OwnerStats) (err error)
+	CreateSnapshot(snapshotName string) (snapshotID uint64, err error)
+	DeleteSnapshot(snapshotName string) (unreferencedLogSegments map[uint64][]byte, err error)
+	FetchSnapshotList() (snapshotList []SnapshotInfo)
+	SnapshotReferencesLogSegment(logSegmentNumber uint64) (referenced bool, err error)
+	FetchSnapshotVolumeHandle(snapshotName string) (volumeHandle VolumeHandle, err error)
 	DoCheckpoint() (err error)
+	FetchCheckpointStatus() (checkpointStatus CheckpointStatus)
+	FetchCheckpointContainerName() (checkpointContainerName string)
+	CheckpointReferencesObject(objectNumber uint64) (referenced bool)
 }
 
 // FetchVolumeHandle is used to fetch a VolumeHandle to use when operating on a given volume's database
@@ -36,3 +93,35 @@ func FetchVolumeHandle(volumeName string) (volumeHandle VolumeHandle, err error)
 
 	return
 }
+
+// A LogSegmentRec holds the ContainerName of the log segment's object. Once the object's length is
+// known (i.e. its PUT has completed), it is appended following a NUL in %016X form. As Swift does not
+// permit NULs in ContainerNames, LogSegmentRecs lacking a length remain unambiguous.
+const logSegmentRecLengthSeparator = "\x00"
+
+// EncodeLogSegmentRec forms the LogSegmentRec for an object in containerName of objectLength bytes (0 if not yet known).
+func EncodeLogSegmentRec(containerName string, objectLength uint64) (value []byte) {
+	if 0 == objectLength {
+		value = []byte(containerName)
+	} else {
+		value = []byte(fmt.Sprintf("%s%s%016X", containerName, logSegmentRecLengthSeparator, objectLength))
+	}
+	return
+}
+
+// DecodeLogSegmentRec returns the containerName and objectLength (0 if not yet known) recorded in a LogSegmentRec.
+func DecodeLogSegmentRec(value []byte) (containerName string, objectLength uint64, err error) {
+	separatorIndex := strings.Index(string(value), logSegmentRecLengthSeparator)
+	if 0 > separatorIndex {
+		containerName = string(value)
+		objectLength = 0
+		err = nil
+		return
+	}
+	containerName = string(value[:separatorIndex])
+	objectLength, err = strconv.ParseUint(string(value[separatorIndex+len(logSegmentRecLengthSeparator):]), 16, 64)
+	if nil != err {
+		err = fmt.Errorf("LogSegmentRec for ContainerName \"%v\" has malformed length: %v", containerName, err)
+	}
+	return
+}
@@ -4,7 +4,13 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/cluster"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
 )
 
 func (volume *volumeStruct) fetchNextCheckPointDoneWaitGroupWhileLocked() (wg *sync.WaitGroup) {
@@ -23,25 +29,73 @@ func (volume *volumeStruct) FetchNextCheckPointDoneWaitGroup() (wg *sync.WaitGro
 	return
 }
 
+// objectDeleteAfterCheckpointWhileLocked deletes the checkpoint container object once the next checkpoint
+// has succeeded. Should the Volume instead be found fenced by another Peer, the deletion is discarded.
+func (volume *volumeStruct) objectDeleteAfterCheckpointWhileLocked(objectNumber uint64) {
+	volume.reclaimCheckpointObjectWhileLocked(objectNumber)
+
+	checkpointDoneWaitGroup := volume.fetchNextCheckPointDoneWaitGroupWhileLocked()
+
+	go func() {
+		checkpointDoneWaitGroup.Wait()
+
+		if volume.Fenced() {
+			stats.IncrementOperations(&stats.ClusterFencedObjectDeleteOps)
+			return
+		}
+
+		volume.objectStore.ObjectDeleteAsync(volume.accountName, volume.checkpointContainerName, utils.Uint64ToHexStr(objectNumber), nil, nil)
+	}()
+}
+
+// reclaimCheckpointObjectWhileLocked forgets the length of a checkpoint object about to be deleted.
+// Should it be a compaction victim, what its deletion reclaims (its length less the bytes compaction
+// rewrote elsewhere) is reported... unless its length is unknown (i.e. it was PUT prior to Up()).
+func (volume *volumeStruct) reclaimCheckpointObjectWhileLocked(objectNumber uint64) {
+	objectLength, objectLengthKnown := volume.checkpointObjectLengthMap[objectNumber]
+	delete(volume.checkpointObjectLengthMap, objectNumber)
+
+	rewrittenBytes, compactionVictim := volume.compactionVictimMap[objectNumber]
+	delete(volume.compactionVictimMap, objectNumber)
+
+	if compactionVictim && objectLengthKnown && (objectLength > rewrittenBytes) {
+		stats.IncrementOperationsAndBytes(stats.HeadhunterCompaction, objectLength-rewrittenBytes)
+	}
+}
+
+// Fenced reports whether a checkpoint has been refused because another Peer has claimed the Volume.
+// Once fenced, the Volume is never again checkpointed... and anything awaiting a checkpoint (e.g. the
+// deletion of a log segment no longer referenced) must be discarded as the other Peer's checkpoint
+// may well still reference it.
+func (volume *volumeStruct) Fenced() (fenced bool) {
+	volume.Lock()
+	fenced = volume.fenced
+	volume.Unlock()
+	return
+}
+
 func (volume *volumeStruct) fetchNonceWhileLocked() (nonce uint64, err error) {
 	var (
 		checkpointContainerHeaders map[string][]string
 		checkpointHeaderValue      string
 		checkpointHeaderValues     []string
+		newCheckpointHeader        checkpointHeaderV5Struct
 		newReservedToNonce         uint64
 	)
 
 	if volume.nextNonce == volume.checkpointHeader.ReservedToNonce {
+		if cluster.VolumeIsFenced(volume.volumeName) {
+			err = fmt.Errorf("Volume %v fenced by another Peer... cannot reserve more nonces", volume.volumeName)
+			err = blunder.AddError(err, blunder.NotActiveError)
+			return
+		}
+
 		newReservedToNonce = volume.checkpointHeader.ReservedToNonce + uint64(volume.nonceValuesToReserve)
 
-		// checkpointHeaderVersion2 == volume.checkpointHeaderVersion
+		newCheckpointHeader = *volume.checkpointHeader
+		newCheckpointHeader.ReservedToNonce = newReservedToNonce
 
-		checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
-			checkpointHeaderVersion2,
-			volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
-			volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
-			newReservedToNonce,
-		)
+		checkpointHeaderValue = newCheckpointHeader.formatCheckpointHeaderValue()
 
 		checkpointHeaderValues = []string{checkpointHeaderValue}
 
@@ -49,7 +103,7 @@ func (volume *volumeStruct) fetchNonceWhileLocked() (nonce uint64, err error) {
 
 		checkpointContainerHeaders[CheckpointHeaderName] = checkpointHeaderValues
 
-		err = swiftclient.ContainerPost(volume.accountName, volume.checkpointContainerName, checkpointContainerHeaders)
+		err = volume.objectStore.ContainerPost(volume.accountName, volume.checkpointContainerName, checkpointContainerHeaders)
 		if nil != err {
 			return
 		}
@@ -93,6 +147,35 @@ func (volume *volumeStruct) GetInodeRec(inodeNumber uint64) (value []byte, ok bo
 	return
 }
 
+// NextInodeNumber returns the lowest inodeNumber with an inodeRec strictly greater than prevInodeNumber.
+// If there is no such inodeNumber, ok == false is returned.
+func (volume *volumeStruct) NextInodeNumber(prevInodeNumber uint64) (nextInodeNumber uint64, ok bool, err error) {
+	volume.Lock()
+
+	index, found, err := volume.inodeRecWrapper.bPlusTree.BisectRight(prevInodeNumber)
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if found {
+		index++
+	}
+
+	keyAsKey, _, ok, err := volume.inodeRecWrapper.bPlusTree.GetByIndex(index)
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if ok {
+		nextInodeNumber = keyAsKey.(uint64)
+	}
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
 func (volume *volumeStruct) PutInodeRec(inodeNumber uint64, value []byte) (err error) {
 	valueToTree := make([]byte, len(value))
 	copy(valueToTree, value)
@@ -110,6 +193,7 @@ func (volume *volumeStruct) PutInodeRec(inodeNumber uint64, value []byte) (err e
 			volume.Unlock()
 			return
 		}
+		volume.volumeStats.InodeCount++
 	}
 
 	volume.recordTransaction(transactionPutInodeRec, inodeNumber, value)
@@ -148,6 +232,7 @@ func (volume *volumeStruct) PutInodeRecs(inodeNumbers []uint64, values [][]byte)
 				volume.Unlock()
 				return
 			}
+			volume.volumeStats.InodeCount++
 		}
 	}
 
@@ -162,9 +247,12 @@ func (volume *volumeStruct) PutInodeRecs(inodeNumbers []uint64, values [][]byte)
 func (volume *volumeStruct) DeleteInodeRec(inodeNumber uint64) (err error) {
 	volume.Lock()
 
-	_, err = volume.inodeRecWrapper.bPlusTree.DeleteByKey(inodeNumber)
+	ok, err := volume.inodeRecWrapper.bPlusTree.DeleteByKey(inodeNumber)
+	if (nil == err) && ok {
+		volume.volumeStats.InodeCount--
+	}
 
-	volume.recordTransaction(transactionDeleteInodeRec, transactionDeleteInodeRec, nil)
+	volume.recordTransaction(transactionDeleteInodeRec, inodeNumber, nil)
 
 	volume.Unlock()
 
@@ -182,6 +270,7 @@ func (volume *volumeStruct) GetLogSegmentRec(logSegmentNumber uint64) (value []b
 	if !ok {
 		volume.Unlock()
 		err = fmt.Errorf("logSegmentNumber 0x%016X not found in volume \"%v\" logSegmentRecWrapper.bPlusTree", logSegmentNumber, volume.volumeName)
+		err = blunder.AddError(err, blunder.NotFoundError)
 		return
 	}
 	valueFromTree := valueAsValue.([]byte)
@@ -283,6 +372,8 @@ func (volume *volumeStruct) PutBPlusTreeObject(objectNumber uint64, value []byte
 	return
 }
 
+// DeleteBPlusTreeObject removes objectNumber from the live volume only. Any snapshot still referencing it
+// continues to do so as the checkpoint objects holding the snapshot's B+Trees are retained.
 func (volume *volumeStruct) DeleteBPlusTreeObject(objectNumber uint64) (err error) {
 	volume.Lock()
 
@@ -295,6 +386,280 @@ func (volume *volumeStruct) DeleteBPlusTreeObject(objectNumber uint64) (err erro
 	return
 }
 
+func (volume *volumeStruct) FetchVolumeStats() (volumeStats VolumeStats) {
+	volume.Lock()
+	volumeStats = volume.volumeStats
+	volume.Unlock()
+	return
+}
+
+// adjustVolumeStatsWhileLocked applies the deltas, clamping each byte total at zero should
+// it ever be asked to go negative (e.g. following a checkpoint that predated VolumeStats).
+func (volume *volumeStruct) adjustVolumeStatsWhileLocked(logSegmentBytesDelta int64, referencedBytesDelta int64) {
+	if (0 > logSegmentBytesDelta) && (uint64(-logSegmentBytesDelta) > volume.volumeStats.LogSegmentBytes) {
+		volume.volumeStats.LogSegmentBytes = 0
+	} else {
+		volume.volumeStats.LogSegmentBytes = uint64(int64(volume.volumeStats.LogSegmentBytes) + logSegmentBytesDelta)
+	}
+
+	if (0 > referencedBytesDelta) && (uint64(-referencedBytesDelta) > volume.volumeStats.ReferencedBytes) {
+		volume.volumeStats.ReferencedBytes = 0
+	} else {
+		volume.volumeStats.ReferencedBytes = uint64(int64(volume.volumeStats.ReferencedBytes) + referencedBytesDelta)
+	}
+}
+
+func (volume *volumeStruct) AdjustVolumeStats(logSegmentBytesDelta int64, referencedBytesDelta int64) {
+	if (0 == logSegmentBytesDelta) && (0 == referencedBytesDelta) {
+		return
+	}
+
+	volume.Lock()
+
+	volume.adjustVolumeStatsWhileLocked(logSegmentBytesDelta, referencedBytesDelta)
+
+	volume.recordTransaction(transactionAdjustVolumeStats, logSegmentBytesDelta, referencedBytesDelta)
+
+	volume.Unlock()
+}
+
+// VolumeStatsRebuildNeeded reports whether the volume's checkpoint predated VolumeStats. If so, no
+// checkpoint will be persisted until the caller (who alone can sum up the bytes referenced by the
+// inodes) has supplied them via RebuildVolumeStats().
+func (volume *volumeStruct) VolumeStatsRebuildNeeded() (rebuildNeeded bool) {
+	volume.Lock()
+	rebuildNeeded = volume.volumeStatsRebuildPending
+	volume.Unlock()
+	return
+}
+
+// RebuildVolumeStats recomputes VolumeStats from the inodeRec & logSegmentRec B+Trees. LogSegmentRecs
+// lacking a length have it fetched (and recorded) so that their eventual deletion is accounted for.
+func (volume *volumeStruct) RebuildVolumeStats(referencedBytes uint64) (err error) {
+	var (
+		containerName          string
+		inodeRecCount          int
+		logSegmentBytes        uint64
+		logSegmentNumber       uint64
+		logSegmentNumberAsKey  sortedmap.Key
+		logSegmentRecCount     int
+		logSegmentRecIndex     int
+		logSegmentRecAsValue   sortedmap.Value
+		objectLength           uint64
+		objectLengthMap        map[uint64]uint64 // key == logSegmentNumber lacking a length
+		objectContainerNameMap map[uint64]string // key == logSegmentNumber lacking a length
+		ok                     bool
+	)
+
+	objectLengthMap = make(map[uint64]uint64)
+	objectContainerNameMap = make(map[uint64]string)
+
+	volume.Lock()
+
+	logSegmentRecCount, err = volume.logSegmentRecWrapper.bPlusTree.Len()
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+
+	for logSegmentRecIndex = 0; logSegmentRecIndex < logSegmentRecCount; logSegmentRecIndex++ {
+		logSegmentNumberAsKey, logSegmentRecAsValue, ok, err = volume.logSegmentRecWrapper.bPlusTree.GetByIndex(logSegmentRecIndex)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+		if !ok {
+			volume.Unlock()
+			err = fmt.Errorf("Volume %v logSegmentRecWrapper.bPlusTree.GetByIndex(%v) returned !ok", volume.volumeName, logSegmentRecIndex)
+			return
+		}
+
+		containerName, objectLength, err = DecodeLogSegmentRec(logSegmentRecAsValue.([]byte))
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+
+		if 0 == objectLength {
+			logSegmentNumber = logSegmentNumberAsKey.(uint64)
+			objectContainerNameMap[logSegmentNumber] = containerName
+		} else {
+			logSegmentBytes += objectLength
+		}
+	}
+
+	volume.Unlock()
+
+	// HEAD those log segments lacking a length without holding the lock
+
+	for logSegmentNumber, containerName = range objectContainerNameMap {
+		objectLength, err = volume.objectStore.ObjectContentLength(volume.accountName, containerName, utils.Uint64ToHexStr(logSegmentNumber))
+		if nil != err {
+			if 404 != blunder.HTTPCode(err) {
+				return
+			}
+			logger.WarnfWithError(err, "Volume %v log segment %v/%016X missing... counted as empty", volume.volumeName, containerName, logSegmentNumber)
+			continue
+		}
+		objectLengthMap[logSegmentNumber] = objectLength
+	}
+
+	volume.Lock()
+
+	for logSegmentNumber, objectLength = range objectLengthMap {
+		logSegmentRecAsValue = EncodeLogSegmentRec(objectContainerNameMap[logSegmentNumber], objectLength)
+
+		ok, err = volume.logSegmentRecWrapper.bPlusTree.PatchByKey(logSegmentNumber, logSegmentRecAsValue)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+		if !ok {
+			continue // deleted since it was found above
+		}
+
+		volume.recordTransaction(transactionPutLogSegmentRec, logSegmentNumber, logSegmentRecAsValue.([]byte))
+
+		logSegmentBytes += objectLength
+	}
+
+	inodeRecCount, err = volume.inodeRecWrapper.bPlusTree.Len()
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+
+	volume.volumeStats = VolumeStats{
+		InodeCount:      uint64(inodeRecCount),
+		LogSegmentBytes: logSegmentBytes,
+		ReferencedBytes: referencedBytes,
+	}
+
+	volume.volumeStatsRebuildPending = false
+	volume.statsRebuilt = true
+
+	logger.Infof("Volume %v VolumeStats rebuilt: %+v", volume.volumeName, volume.volumeStats)
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) FetchOwnerStats() (userStats map[uint32]OwnerStats, groupStats map[uint32]OwnerStats) {
+	var (
+		ownerID    uint32
+		ownerStats OwnerStats
+	)
+
+	volume.Lock()
+
+	userStats = make(map[uint32]OwnerStats, len(volume.userStats))
+	for ownerID, ownerStats = range volume.userStats {
+		userStats[ownerID] = ownerStats
+	}
+
+	groupStats = make(map[uint32]OwnerStats, len(volume.groupStats))
+	for ownerID, ownerStats = range volume.groupStats {
+		groupStats[ownerID] = ownerStats
+	}
+
+	volume.Unlock()
+
+	return
+}
+
+func (volume *volumeStruct) FetchOwnerStatsByID(userID uint32, groupID uint32) (userStats OwnerStats, groupStats OwnerStats) {
+	volume.Lock()
+	userStats = volume.userStats[userID]
+	groupStats = volume.groupStats[groupID]
+	volume.Unlock()
+
+	return
+}
+
+// adjustOwnerStatsWhileLocked applies the deltas to both the userID's and groupID's OwnerStats,
+// clamping at zero as does adjustVolumeStatsWhileLocked(). Owners left with nothing are dropped.
+func (volume *volumeStruct) adjustOwnerStatsWhileLocked(userID uint32, groupID uint32, bytesDelta int64, inodesDelta int64) {
+	adjustOwnerStatsMap(volume.userStats, userID, bytesDelta, inodesDelta)
+	adjustOwnerStatsMap(volume.groupStats, groupID, bytesDelta, inodesDelta)
+}
+
+func adjustOwnerStatsMap(ownerStatsMap map[uint32]OwnerStats, ownerID uint32, bytesDelta int64, inodesDelta int64) {
+	ownerStats := ownerStatsMap[ownerID]
+
+	if (0 > bytesDelta) && (uint64(-bytesDelta) > ownerStats.Bytes) {
+		ownerStats.Bytes = 0
+	} else {
+		ownerStats.Bytes = uint64(int64(ownerStats.Bytes) + bytesDelta)
+	}
+
+	if (0 > inodesDelta) && (uint64(-inodesDelta) > ownerStats.Inodes) {
+		ownerStats.Inodes = 0
+	} else {
+		ownerStats.Inodes = uint64(int64(ownerStats.Inodes) + inodesDelta)
+	}
+
+	if (0 == ownerStats.Bytes) && (0 == ownerStats.Inodes) {
+		delete(ownerStatsMap, ownerID)
+	} else {
+		ownerStatsMap[ownerID] = ownerStats
+	}
+}
+
+func (volume *volumeStruct) AdjustOwnerStats(userID uint32, groupID uint32, bytesDelta int64, inodesDelta int64) {
+	if (0 == bytesDelta) && (0 == inodesDelta) {
+		return
+	}
+
+	volume.Lock()
+
+	volume.adjustOwnerStatsWhileLocked(userID, groupID, bytesDelta, inodesDelta)
+
+	volume.recordTransaction(transactionAdjustOwnerStats, []uint64{uint64(userID), uint64(groupID)}, []int64{bytesDelta, inodesDelta})
+
+	volume.Unlock()
+}
+
+// OwnerStatsRebuildNeeded reports whether the volume's checkpoint predated OwnerStats. As with
+// VolumeStatsRebuildNeeded(), no checkpoint will be persisted until RebuildOwnerStats() is called.
+func (volume *volumeStruct) OwnerStatsRebuildNeeded() (rebuildNeeded bool) {
+	volume.Lock()
+	rebuildNeeded = volume.ownerStatsRebuildPending
+	volume.Unlock()
+	return
+}
+
+// RebuildOwnerStats replaces the per-user & per-group totals with those summed up by the caller.
+func (volume *volumeStruct) RebuildOwnerStats(userStats map[uint32]OwnerStats, groupStats map[uint32]OwnerStats) (err error) {
+	var (
+		ownerID    uint32
+		ownerStats OwnerStats
+	)
+
+	volume.Lock()
+
+	volume.userStats = make(map[uint32]OwnerStats, len(userStats))
+	for ownerID, ownerStats = range userStats {
+		volume.userStats[ownerID] = ownerStats
+	}
+
+	volume.groupStats = make(map[uint32]OwnerStats, len(groupStats))
+	for ownerID, ownerStats = range groupStats {
+		volume.groupStats[ownerID] = ownerStats
+	}
+
+	volume.ownerStatsRebuildPending = false
+	volume.statsRebuilt = true
+
+	logger.Infof("Volume %v OwnerStats rebuilt for %v users & %v groups", volume.volumeName, len(volume.userStats), len(volume.groupStats))
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
 func (volume *volumeStruct) DoCheckpoint() (err error) {
 	var (
 		checkpointRequest checkpointRequestStruct
@@ -310,3 +675,49 @@ func (volume *volumeStruct) DoCheckpoint() (err error) {
 
 	return
 }
+
+func (volume *volumeStruct) FetchCheckpointStatus() (checkpointStatus CheckpointStatus) {
+	volume.Lock()
+	checkpointStatus = volume.checkpointStatus
+	volume.Unlock()
+	return
+}
+
+func (volume *volumeStruct) FetchCheckpointContainerName() (checkpointContainerName string) {
+	checkpointContainerName = volume.checkpointContainerName
+	return
+}
+
+// CheckpointReferencesObject reports whether the named object in the checkpoint container is (or is about
+// to be) referenced... either by one of the live B+Trees, as the current checkpoint record, or by a snapshot.
+func (volume *volumeStruct) CheckpointReferencesObject(objectNumber uint64) (referenced bool) {
+	volume.Lock()
+	defer volume.Unlock()
+
+	if objectNumber == volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber {
+		// Either the current checkpoint record or the object currently being written
+		referenced = true
+		return
+	}
+
+	_, referenced = volume.inodeRecBPlusTreeLayout[objectNumber]
+	if referenced {
+		return
+	}
+	_, referenced = volume.logSegmentRecBPlusTreeLayout[objectNumber]
+	if referenced {
+		return
+	}
+	_, referenced = volume.bPlusTreeObjectBPlusTreeLayout[objectNumber]
+	if referenced {
+		return
+	}
+	_, referenced = volume.failedCheckpointObjectMap[objectNumber]
+	if referenced {
+		return
+	}
+
+	referenced = volume.snapshotReferencesObjectWhileLocked(objectNumber)
+
+	return
+}
@@ -9,6 +9,7 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/conf"
 	"github.com/swiftstack/ProxyFS/dlm"
 	"github.com/swiftstack/ProxyFS/logger"
@@ -49,6 +50,45 @@ func logsegmentRecPutGet(t *testing.T, volume VolumeHandle, key uint64, value []
 	}
 }
 
+// testReplayFollowingCrash discards volume's in-memory state (without a checkpoint) and recovers it
+// just as upVolume() would following a crash... i.e. from the last checkpoint plus the Replay Log.
+func testReplayFollowingCrash(t *testing.T, volume *volumeStruct) {
+	volume.Lock()
+	defer volume.Unlock()
+
+	if nil == volume.replayLogFile {
+		t.Fatalf("testReplayFollowingCrash() expected a Replay Log to be open")
+	}
+
+	err := volume.replayLogFile.Close()
+	if nil != err {
+		t.Fatalf("replayLogFile.Close() returned error: %v", err)
+	}
+	volume.replayLogFile = nil
+
+	err = volume.getCheckpoint(false)
+	if nil != err {
+		t.Fatalf("getCheckpoint() following simulated crash returned error: %v", err)
+	}
+}
+
+func TestLogSegmentRecEncoding(t *testing.T) {
+	containerName, objectLength, err := DecodeLogSegmentRec([]byte("TestContainer"))
+	if (nil != err) || ("TestContainer" != containerName) || (0 != objectLength) {
+		t.Fatalf("DecodeLogSegmentRec() of LogSegmentRec lacking a length returned \"%v\" %v %v", containerName, objectLength, err)
+	}
+
+	containerName, objectLength, err = DecodeLogSegmentRec(EncodeLogSegmentRec("TestContainer", 12345))
+	if (nil != err) || ("TestContainer" != containerName) || (12345 != objectLength) {
+		t.Fatalf("DecodeLogSegmentRec() of EncodeLogSegmentRec() returned \"%v\" %v %v", containerName, objectLength, err)
+	}
+
+	_, _, err = DecodeLogSegmentRec([]byte("TestContainer\x00XYZ"))
+	if nil == err {
+		t.Fatalf("DecodeLogSegmentRec() of LogSegmentRec with malformed length should have failed")
+	}
+}
+
 func putInodeRecsTest(t *testing.T, volume VolumeHandle) {
 	var keys []uint64
 	var values [][]byte
@@ -70,6 +110,27 @@ func putInodeRecsTest(t *testing.T, volume VolumeHandle) {
 		t.Fatalf("Failed to PutInodeRecs: %v", err)
 	}
 
+	var nextInodeNumber uint64
+	var ok bool
+
+	nextInodeNumber = keys[0]
+	for i := 1; i < 10; i++ {
+		nextInodeNumber, ok, err = volume.NextInodeNumber(nextInodeNumber)
+		if nil != err || !ok {
+			t.Fatalf("Unable to get inode following %d", keys[i-1])
+		}
+		if nextInodeNumber != keys[i] {
+			t.Fatalf("NextInodeNumber(%v) returned %v (expected %v)", keys[i-1], nextInodeNumber, keys[i])
+		}
+	}
+	_, ok, err = volume.NextInodeNumber(keys[9])
+	if nil != err {
+		t.Fatalf("NextInodeNumber(%v) failed: %v", keys[9], err)
+	}
+	if ok {
+		t.Fatalf("NextInodeNumber(%v) should have returned ok == false", keys[9])
+	}
+
 	for i := 0; i < 10; i++ {
 		var value []byte
 		value, ok, err := volume.GetInodeRec(keys[i])
@@ -209,6 +270,124 @@ func TestHeadHunterAPI(t *testing.T) {
 		t.Fatalf("FetchNonce() [case 1] returned error: %v", err)
 	}
 
+	// VolumeStats should be tracked... and persisted across a Down()/Up() cycle
+
+	err = volume.PutInodeRec(5678, []byte("VolumeStats"))
+	if nil != err {
+		t.Fatalf("PutInodeRec() [case 1] returned error: %v", err)
+	}
+	err = volume.PutInodeRec(5678, []byte("VolumeStats again"))
+	if nil != err {
+		t.Fatalf("PutInodeRec() [case 1 again] returned error: %v", err)
+	}
+
+	volume.AdjustVolumeStats(100, 60)
+	volume.AdjustVolumeStats(-10, 0)
+
+	volumeStats := volume.FetchVolumeStats()
+	if (VolumeStats{InodeCount: 1, LogSegmentBytes: 90, ReferencedBytes: 60}) != volumeStats {
+		t.Fatalf("FetchVolumeStats() [case 1] returned unexpected %+v", volumeStats)
+	}
+
+	// OwnerStats should similarly be tracked & persisted
+
+	volume.AdjustOwnerStats(1000, 100, 500, 2)
+	volume.AdjustOwnerStats(1001, 100, 10, 1)
+
+	userStats, groupStats := volume.FetchOwnerStats()
+	if (2 != len(userStats)) || (OwnerStats{Bytes: 500, Inodes: 2} != userStats[1000]) || (OwnerStats{Bytes: 10, Inodes: 1} != userStats[1001]) {
+		t.Fatalf("FetchOwnerStats() [case 1] returned unexpected userStats %+v", userStats)
+	}
+	if (1 != len(groupStats)) || (OwnerStats{Bytes: 510, Inodes: 3} != groupStats[100]) {
+		t.Fatalf("FetchOwnerStats() [case 1] returned unexpected groupStats %+v", groupStats)
+	}
+
+	// A snapshot should preserve the checkpoint it was taken of
+
+	err = volume.PutLogSegmentRec(firstUpNonce, []byte("TestContainer"))
+	if nil != err {
+		t.Fatalf("PutLogSegmentRec() [case 1] returned error: %v", err)
+	}
+
+	snapshotID, err := volume.CreateSnapshot("TestSnapshot")
+	if nil != err {
+		t.Fatalf("CreateSnapshot() [case 1] returned error: %v", err)
+	}
+
+	_, err = volume.CreateSnapshot("TestSnapshot")
+	if !blunder.Is(err, blunder.FileExistsError) {
+		t.Fatalf("CreateSnapshot() [case 1] of existing snapshot should have failed with FileExistsError: %v", err)
+	}
+	_, err = volume.CreateSnapshot("Test@Snapshot")
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("CreateSnapshot() [case 1] of invalid snapshot name should have failed with InvalidArgError: %v", err)
+	}
+
+	err = volume.DeleteLogSegmentRec(firstUpNonce)
+	if nil != err {
+		t.Fatalf("DeleteLogSegmentRec() [case 1] returned error: %v", err)
+	}
+	err = volume.PutInodeRec(5678, []byte("VolumeStats after snapshot"))
+	if nil != err {
+		t.Fatalf("PutInodeRec() [case 1 after snapshot] returned error: %v", err)
+	}
+
+	referenced, err := volume.SnapshotReferencesLogSegment(firstUpNonce)
+	if nil != err {
+		t.Fatalf("SnapshotReferencesLogSegment() [case 1] returned error: %v", err)
+	}
+	if !referenced {
+		t.Fatalf("SnapshotReferencesLogSegment() [case 1] should have returned true")
+	}
+
+	snapshotVolume, err := volume.FetchSnapshotVolumeHandle("TestSnapshot")
+	if nil != err {
+		t.Fatalf("FetchSnapshotVolumeHandle() [case 1] returned error: %v", err)
+	}
+
+	snapshotValue, ok, err := snapshotVolume.GetInodeRec(5678)
+	if (nil != err) || !ok || ("VolumeStats again" != string(snapshotValue)) {
+		t.Fatalf("GetInodeRec() [case 1] of snapshot returned unexpected value \"%s\" (ok == %v, err == %v)", snapshotValue, ok, err)
+	}
+	snapshotValue, err = snapshotVolume.GetLogSegmentRec(firstUpNonce)
+	if (nil != err) || ("TestContainer" != string(snapshotValue)) {
+		t.Fatalf("GetLogSegmentRec() [case 1] of snapshot returned unexpected value \"%s\" (err == %v)", snapshotValue, err)
+	}
+
+	err = snapshotVolume.PutInodeRec(5678, []byte("Should not be allowed"))
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("PutInodeRec() [case 1] of snapshot should have failed with ReadOnlyError: %v", err)
+	}
+
+	// A DeleteInodeRec() not yet checkpointed should be replayed (following a crash) against the inode deleted
+
+	err = volume.PutInodeRec(6789, []byte("Replayed DeleteInodeRec"))
+	if nil != err {
+		t.Fatalf("PutInodeRec() [case 1 replay] returned error: %v", err)
+	}
+	err = volume.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() [case 1 replay] returned error: %v", err)
+	}
+	err = volume.DeleteInodeRec(6789)
+	if nil != err {
+		t.Fatalf("DeleteInodeRec() [case 1 replay] returned error: %v", err)
+	}
+
+	testReplayFollowingCrash(t, volume.(*volumeStruct))
+
+	_, ok, err = volume.GetInodeRec(6789)
+	if nil != err {
+		t.Fatalf("GetInodeRec() [case 1 replay] returned error: %v", err)
+	}
+	if ok {
+		t.Fatalf("GetInodeRec() [case 1 replay] should not have found inode deleted prior to simulated crash")
+	}
+	volumeStats = volume.FetchVolumeStats()
+	if 1 != volumeStats.InodeCount {
+		t.Fatalf("FetchVolumeStats() [case 1 replay] returned unexpected %+v", volumeStats)
+	}
+
 	err = Down()
 	if nil != err {
 		t.Fatalf("headhunter.Down() [case 1] returned error: %v", err)
@@ -272,6 +451,83 @@ func TestHeadHunterAPI(t *testing.T) {
 		t.Fatalf("FetchNonce() [case 2] returned unexpected nonce: %v (should have been > %v)", secondUpNonce, firstUpNonce)
 	}
 
+	volumeStats = volume.FetchVolumeStats()
+	if (VolumeStats{InodeCount: 1, LogSegmentBytes: 90, ReferencedBytes: 60}) != volumeStats {
+		t.Fatalf("FetchVolumeStats() [case 2] returned unexpected %+v", volumeStats)
+	}
+
+	err = volume.DeleteInodeRec(5678)
+	if nil != err {
+		t.Fatalf("DeleteInodeRec() [case 2] returned error: %v", err)
+	}
+
+	volume.AdjustVolumeStats(-1000, -60)
+
+	userStats, groupStats = volume.FetchOwnerStats()
+	if (2 != len(userStats)) || (OwnerStats{Bytes: 500, Inodes: 2} != userStats[1000]) || (OwnerStats{Bytes: 10, Inodes: 1} != userStats[1001]) {
+		t.Fatalf("FetchOwnerStats() [case 2] returned unexpected userStats %+v", userStats)
+	}
+	if (1 != len(groupStats)) || (OwnerStats{Bytes: 510, Inodes: 3} != groupStats[100]) {
+		t.Fatalf("FetchOwnerStats() [case 2] returned unexpected groupStats %+v", groupStats)
+	}
+
+	userOwnerStats, groupOwnerStats := volume.FetchOwnerStatsByID(1001, 100)
+	if (OwnerStats{Bytes: 10, Inodes: 1} != userOwnerStats) || (OwnerStats{Bytes: 510, Inodes: 3} != groupOwnerStats) {
+		t.Fatalf("FetchOwnerStatsByID() [case 2] returned unexpected %+v & %+v", userOwnerStats, groupOwnerStats)
+	}
+
+	// Snapshots should also persist across a Down()/Up() cycle... until deleted
+
+	snapshotList := volume.FetchSnapshotList()
+	if (1 != len(snapshotList)) || (snapshotID != snapshotList[0].ID) || ("TestSnapshot" != snapshotList[0].Name) {
+		t.Fatalf("FetchSnapshotList() [case 2] returned unexpected %+v", snapshotList)
+	}
+
+	snapshotVolume, err = volume.FetchSnapshotVolumeHandle("TestSnapshot")
+	if nil != err {
+		t.Fatalf("FetchSnapshotVolumeHandle() [case 2] returned error: %v", err)
+	}
+
+	snapshotValue, ok, err = snapshotVolume.GetInodeRec(5678)
+	if (nil != err) || !ok || ("VolumeStats again" != string(snapshotValue)) {
+		t.Fatalf("GetInodeRec() [case 2] of snapshot returned unexpected value \"%s\" (ok == %v, err == %v)", snapshotValue, ok, err)
+	}
+
+	unreferencedLogSegments, err := volume.DeleteSnapshot("TestSnapshot")
+	if nil != err {
+		t.Fatalf("DeleteSnapshot() [case 2] returned error: %v", err)
+	}
+	if (1 != len(unreferencedLogSegments)) || ("TestContainer" != string(unreferencedLogSegments[firstUpNonce])) {
+		t.Fatalf("DeleteSnapshot() [case 2] returned unexpected unreferencedLogSegments %v", unreferencedLogSegments)
+	}
+
+	_, err = volume.DeleteSnapshot("TestSnapshot")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("DeleteSnapshot() [case 2] of deleted snapshot should have failed with NotFoundError: %v", err)
+	}
+
+	snapshotList = volume.FetchSnapshotList()
+	if 0 != len(snapshotList) {
+		t.Fatalf("FetchSnapshotList() [case 2] returned unexpected %+v after DeleteSnapshot()", snapshotList)
+	}
+
+	// Owners left with nothing should be dropped
+
+	volume.AdjustOwnerStats(1001, 100, -10, -1)
+
+	userStats, groupStats = volume.FetchOwnerStats()
+	if (1 != len(userStats)) || (OwnerStats{Bytes: 500, Inodes: 2} != userStats[1000]) {
+		t.Fatalf("FetchOwnerStats() [case 2] returned unexpected userStats %+v after dropping user 1001", userStats)
+	}
+	if (1 != len(groupStats)) || (OwnerStats{Bytes: 500, Inodes: 2} != groupStats[100]) {
+		t.Fatalf("FetchOwnerStats() [case 2] returned unexpected groupStats %+v after dropping user 1001", groupStats)
+	}
+
+	volumeStats = volume.FetchVolumeStats()
+	if (VolumeStats{}) != volumeStats {
+		t.Fatalf("FetchVolumeStats() [case 2] should have returned all zeroes, not %+v", volumeStats)
+	}
+
 	var key uint64
 	key = 1234
 	value := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
@@ -12,9 +12,11 @@ import (
 	"unsafe"
 
 	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/cluster"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
 	"github.com/swiftstack/ProxyFS/platform"
-	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/utils"
 	"github.com/swiftstack/cstruct"
 	"github.com/swiftstack/sortedmap"
@@ -37,12 +39,84 @@ const (
 	// uint64 in %016X indicating length of               checkpoint record at tail of object
 	// ' '
 	// uint64 in %016X indicating reservedToNonce
+	checkpointHeaderVersion3
+	// uint64 in %016X indicating checkpointHeaderVersion3
+	// ' '
+	// uint64 in %016X indicating objectNumber containing checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating length of               checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating reservedToNonce
+	// ' '
+	// uint64 in %016X indicating VolumeStats.InodeCount      as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating VolumeStats.LogSegmentBytes as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating VolumeStats.ReferencedBytes as of this checkpoint
+	checkpointHeaderVersion4
+	// uint64 in %016X indicating checkpointHeaderVersion4
+	// ' '
+	// uint64 in %016X indicating objectNumber containing checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating length of               checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating reservedToNonce
+	// ' '
+	// uint64 in %016X indicating VolumeStats.InodeCount      as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating VolumeStats.LogSegmentBytes as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating VolumeStats.ReferencedBytes as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating number of elementOfOwnerStatsStruct's in checkpoint record at tail of object
+	checkpointHeaderVersion5
+	// uint64 in %016X indicating checkpointHeaderVersion5
+	// ' '
+	// uint64 in %016X indicating objectNumber containing checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating length of               checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating reservedToNonce
+	// ' '
+	// uint64 in %016X indicating VolumeStats.InodeCount      as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating VolumeStats.LogSegmentBytes as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating VolumeStats.ReferencedBytes as of this checkpoint
+	// ' '
+	// uint64 in %016X indicating number of elementOfOwnerStatsStruct's in checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating number of elementOfSnapshotListStruct's in checkpoint record at tail of object
+	// ' '
+	// uint64 in %016X indicating total length of the snapshot list        in checkpoint record at tail of object
 )
 
-type checkpointHeaderV2Struct struct {
+type checkpointHeaderV5Struct struct {
 	CheckpointObjectTrailerV2StructObjectNumber uint64 // checkpointObjectTrailerV2Struct found at "tail" of object
-	CheckpointObjectTrailerV2StructObjectLength uint64 // this length includes the three B+Tree "layouts" appended
+	CheckpointObjectTrailerV2StructObjectLength uint64 // this length includes the three B+Tree "layouts" and OwnerStats table appended
 	ReservedToNonce                             uint64 // highest nonce value reserved
+	InodeCount                                  uint64 // VolumeStats.InodeCount      as of this checkpoint (zero if checkpointHeaderVersion2)
+	LogSegmentBytes                             uint64 // VolumeStats.LogSegmentBytes as of this checkpoint (zero if checkpointHeaderVersion2)
+	ReferencedBytes                             uint64 // VolumeStats.ReferencedBytes as of this checkpoint (zero if checkpointHeaderVersion2)
+	OwnerStatsNumElements                       uint64 // elements follow the B+Tree "layouts" (zero if prior to checkpointHeaderVersion4)
+	SnapshotListNumElements                     uint64 // elements follow the OwnerStats table (zero if prior to checkpointHeaderVersion5)
+	SnapshotListLength                          uint64 // bytes occupied by those elements including their names
+}
+
+func (checkpointHeader *checkpointHeaderV5Struct) formatCheckpointHeaderValue() (checkpointHeaderValue string) {
+	checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X %016X %016X %016X %016X %016X %016X",
+		checkpointHeaderVersion5,
+		checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
+		checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
+		checkpointHeader.ReservedToNonce,
+		checkpointHeader.InodeCount,
+		checkpointHeader.LogSegmentBytes,
+		checkpointHeader.ReferencedBytes,
+		checkpointHeader.OwnerStatsNumElements,
+		checkpointHeader.SnapshotListNumElements,
+		checkpointHeader.SnapshotListLength,
+	)
+	return
 }
 
 type checkpointObjectTrailerV2Struct struct {
@@ -61,6 +135,8 @@ type checkpointObjectTrailerV2Struct struct {
 	// inodeRecBPlusTreeLayout        serialized as [inodeRecBPlusTreeLayoutNumElements       ]elementOfBPlusTreeLayoutStruct
 	// logSegmentBPlusTreeLayout      serialized as [logSegmentRecBPlusTreeLayoutNumElements  ]elementOfBPlusTreeLayoutStruct
 	// bPlusTreeObjectBPlusTreeLayout serialized as [bPlusTreeObjectBPlusTreeLayoutNumElements]elementOfBPlusTreeLayoutStruct
+	// ownerStats                     serialized as [checkpointHeaderV5Struct.OwnerStatsNumElements]elementOfOwnerStatsStruct
+	// snapshotList                   serialized as [checkpointHeaderV5Struct.SnapshotListNumElements]{elementOfSnapshotListStruct,name}
 }
 
 type elementOfBPlusTreeLayoutStruct struct {
@@ -68,10 +144,41 @@ type elementOfBPlusTreeLayoutStruct struct {
 	ObjectBytes  uint64
 }
 
+const (
+	ownerTypeUser uint64 = iota
+	ownerTypeGroup
+)
+
+type elementOfOwnerStatsStruct struct {
+	OwnerType uint64 // either ownerTypeUser or ownerTypeGroup
+	OwnerID   uint64 // userID or groupID
+	Bytes     uint64
+	Inodes    uint64
+}
+
+// elementOfSnapshotListStruct records a snapshot in the checkpoint record. It is immediately followed
+// by NameLength bytes of the snapshot's name. The snapshot itself is the checkpoint whose header values
+// are captured here... and whose checkpoint record is consulted to locate the B+Trees it preserves.
+type elementOfSnapshotListStruct struct {
+	ID                                          uint64 // nonce uniquely identifying this snapshot
+	CreationTime                                uint64 // time.Time.UnixNano() at which the snapshot was taken
+	NextNonce                                   uint64 // nonce values at or beyond this were unused when the snapshot was taken
+	CheckpointObjectTrailerV2StructObjectNumber uint64 // checkpointHeaderV5Struct values of the preserved checkpoint
+	CheckpointObjectTrailerV2StructObjectLength uint64
+	InodeCount                                  uint64
+	LogSegmentBytes                             uint64
+	ReferencedBytes                             uint64
+	OwnerStatsNumElements                       uint64
+	NameLength                                  uint64
+}
+
 type checkpointRequestStruct struct {
 	waitGroup        sync.WaitGroup
 	err              error
 	exitOnCompletion bool
+	snapshotName     string // if != "", a snapshot of the resulting checkpoint should be taken with this name
+	snapshotErr      error  // reports why the requested snapshot could not be taken (the checkpoint itself succeeded)
+	snapshotID       uint64 // if snapshotErr == nil, the ID of the requested snapshot
 }
 
 const (
@@ -87,12 +194,14 @@ const (
 	transactionDeleteLogSegmentRec
 	transactionPutBPlusTreeObject
 	transactionDeleteBPlusTreeObject
+	transactionAdjustVolumeStats
+	transactionAdjustOwnerStats
 )
 
 type replayLogTransactionFixedPartStruct struct { //          transactions begin on a replayLogWriteBufferAlignment boundary
 	CRC64                                           uint64 // checksum of everything after this field
 	BytesFollowing                                  uint64 // bytes following in this transaction
-	LastCheckpointObjectTrailerV2StructObjectNumber uint64 // last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+	LastCheckpointObjectTrailerV2StructObjectNumber uint64 // last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 	TransactionType                                 uint64 // transactionType from above const() block
 }
 
@@ -134,9 +243,13 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded                  uint64
 		err                          error
 		i                            int
+		logSegmentBytesDelta         int64
+		ownerDeltas                  []int64
+		ownerIDs                     []uint64
 		multipleKeys                 []uint64
 		multipleValues               [][]byte
 		packedUint64                 []byte
+		referencedBytesDelta         int64
 		replayLogWriteBuffer         []byte
 		replayLogWriteBufferPosition uint64
 		singleKey                    uint64
@@ -155,7 +268,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionPutInodeRec
 				globals.uint64Size + //               inodeNumber
 				globals.uint64Size + //               len(value)
@@ -169,7 +282,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionPutInodeRecs
 				globals.uint64Size //                 len(inodeNumbers) == len(values)
 		for i = 0; i < len(multipleKeys); i++ {
@@ -186,7 +299,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionDeleteInodeRec
 				globals.uint64Size //                 inodeNumber
 	case transactionPutLogSegmentRec:
@@ -195,7 +308,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionPutLogSegmentRec
 				globals.uint64Size + //               logSegmentNumber
 				globals.uint64Size + //               len(value)
@@ -208,7 +321,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionDeleteLogSegmentRec
 				globals.uint64Size //                 logSegmentNumber
 	case transactionPutBPlusTreeObject:
@@ -217,7 +330,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionPutBPlusTreeObject
 				globals.uint64Size + //               objectNumber
 				globals.uint64Size + //               len(value)
@@ -230,9 +343,34 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
 			globals.uint64Size + //                   checksum of everything after this field
 				globals.uint64Size + //               bytes following in this transaction
-				globals.uint64Size + //               last checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 				globals.uint64Size + //               transactionType == transactionDeleteBPlusTreeObject
 				globals.uint64Size //                 objectNumber
+	case transactionAdjustVolumeStats:
+		logSegmentBytesDelta = keys.(int64)
+		referencedBytesDelta = values.(int64)
+		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
+			globals.uint64Size + //                   checksum of everything after this field
+				globals.uint64Size + //               bytes following in this transaction
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               transactionType == transactionAdjustVolumeStats
+				globals.uint64Size + //               logSegmentBytesDelta
+				globals.uint64Size //                 referencedBytesDelta
+	case transactionAdjustOwnerStats:
+		ownerIDs = keys.([]uint64)
+		ownerDeltas = values.([]int64)
+		if (2 != len(ownerIDs)) || (2 != len(ownerDeltas)) {
+			logger.Fatalf("headhunter.recordTransaction(transactionType==transactionAdjustOwnerStats,,) requires keys == {userID, groupID} & values == {bytesDelta, inodesDelta}")
+		}
+		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
+			globals.uint64Size + //                   checksum of everything after this field
+				globals.uint64Size + //               bytes following in this transaction
+				globals.uint64Size + //               last checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
+				globals.uint64Size + //               transactionType == transactionAdjustOwnerStats
+				globals.uint64Size + //               userID
+				globals.uint64Size + //               groupID
+				globals.uint64Size + //               bytesDelta
+				globals.uint64Size //                 inodesDelta
 	default:
 		logger.Fatalf("headhunter.recordTransaction(transactionType==%v,,) invalid", transactionType)
 	}
@@ -256,7 +394,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 	_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
 	replayLogWriteBufferPosition += globals.uint64Size
 
-	// Fill in last checkpoint's checkpointHeaderV2Struct.CheckpointObjectTrailerV2StructObjectNumber
+	// Fill in last checkpoint's checkpointHeaderV5Struct.CheckpointObjectTrailerV2StructObjectNumber
 
 	packedUint64, err = cstruct.Pack(volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber, LittleEndian)
 	if nil != err {
@@ -401,6 +539,46 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		}
 		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
 		replayLogWriteBufferPosition += globals.uint64Size
+	case transactionAdjustVolumeStats:
+		// Fill in logSegmentBytesDelta
+
+		packedUint64, err = cstruct.Pack(uint64(logSegmentBytesDelta), LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		// Fill in referencedBytesDelta
+
+		packedUint64, err = cstruct.Pack(uint64(referencedBytesDelta), LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+	case transactionAdjustOwnerStats:
+		// Fill in userID & groupID
+
+		for i = 0; i < len(ownerIDs); i++ {
+			packedUint64, err = cstruct.Pack(ownerIDs[i], LittleEndian)
+			if nil != err {
+				logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+			}
+			_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+			replayLogWriteBufferPosition += globals.uint64Size
+		}
+
+		// Fill in bytesDelta & inodesDelta
+
+		for i = 0; i < len(ownerDeltas); i++ {
+			packedUint64, err = cstruct.Pack(uint64(ownerDeltas[i]), LittleEndian)
+			if nil != err {
+				logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+			}
+			_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+			replayLogWriteBufferPosition += globals.uint64Size
+		}
 	default:
 		logger.Fatalf("headhunter.recordTransaction(transactionType==%v,,) invalid", transactionType)
 	}
@@ -446,43 +624,47 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 
 func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 	var (
-		accountHeaderValues                 []string
-		accountHeaders                      map[string][]string
-		bytesConsumed                       uint64
-		bytesNeeded                         uint64
-		checkpointContainerHeaders          map[string][]string
-		checkpointHeader                    checkpointHeaderV2Struct
-		checkpointHeaderValue               string
-		checkpointHeaderValueSlice          []string
-		checkpointHeaderValues              []string
-		checkpointObjectTrailerBuf          []byte
-		checkpointVersion                   uint64
-		computedCRC64                       uint64
-		defaultReplayLogReadBuffer          []byte
-		elementOfBPlusTreeLayout            elementOfBPlusTreeLayoutStruct
-		expectedCheckpointObjectTrailerSize uint64
-		i                                   uint64
-		inodeNumber                         uint64
-		layoutReportIndex                   uint64
-		logSegmentNumber                    uint64
-		numInodes                           uint64
-		objectNumber                        uint64
-		ok                                  bool
-		replayLogReadBuffer                 []byte
-		replayLogReadBufferPosition         uint64
-		replayLogPosition                   int64
-		replayLogSize                       int64
-		replayLogTransactionFixedPart       replayLogTransactionFixedPartStruct
-		storagePolicyHeaderValues           []string
-		value                               []byte
-		valueLen                            uint64
+		accountHeaderValues           []string
+		accountHeaders                map[string][]string
+		bytesNeeded                   uint64
+		checkpointContainerHeaders    map[string][]string
+		checkpointContents            *checkpointContentsStruct
+		checkpointHeader              checkpointHeaderV5Struct
+		checkpointHeaderValue         string
+		checkpointHeaderValueSlice    []string
+		checkpointHeaderValues        []string
+		checkpointVersion             uint64
+		computedCRC64                 uint64
+		defaultReplayLogReadBuffer    []byte
+		groupID                       uint64
+		i                             uint64
+		inodeNumber                   uint64
+		inodeRecCount                 int
+		logSegmentBytesDelta          uint64
+		logSegmentNumber              uint64
+		numInodes                     uint64
+		objectNumber                  uint64
+		ok                            bool
+		ownerBytesDelta               uint64
+		ownerInodesDelta              uint64
+		referencedBytesDelta          uint64
+		replayLogReadBuffer           []byte
+		replayLogReadBufferPosition   uint64
+		replayLogPosition             int64
+		replayLogSize                 int64
+		replayLogTransactionFixedPart replayLogTransactionFixedPartStruct
+		snapshotListBuf               []byte
+		storagePolicyHeaderValues     []string
+		userID                        uint64
+		value                         []byte
+		valueLen                      uint64
 	)
 
 	volume.inodeRecWrapper = &bPlusTreeWrapperStruct{volume: volume, wrapperType: inodeRecBPlusTreeWrapperType}
 	volume.logSegmentRecWrapper = &bPlusTreeWrapperStruct{volume: volume, wrapperType: logSegmentRecBPlusTreeWrapperType}
 	volume.bPlusTreeObjectWrapper = &bPlusTreeWrapperStruct{volume: volume, wrapperType: bPlusTreeObjectBPlusTreeWrapperType}
 
-	checkpointContainerHeaders, err = swiftclient.ContainerHead(volume.accountName, volume.checkpointContainerName)
+	checkpointContainerHeaders, err = volume.objectStore.ContainerHead(volume.accountName, volume.checkpointContainerName)
 	if nil == err {
 		checkpointHeaderValues, ok = checkpointContainerHeaders[CheckpointHeaderName]
 		if !ok {
@@ -504,12 +686,16 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 
 			checkpointHeader.ReservedToNonce = firstNonceToProvide - 1
 
-			checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
-				checkpointHeaderVersion2,
-				checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
-				checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
-				checkpointHeader.ReservedToNonce,
-			)
+			checkpointHeader.InodeCount = 0
+			checkpointHeader.LogSegmentBytes = 0
+			checkpointHeader.ReferencedBytes = 0
+
+			checkpointHeader.OwnerStatsNumElements = 0
+
+			checkpointHeader.SnapshotListNumElements = 0
+			checkpointHeader.SnapshotListLength = 0
+
+			checkpointHeaderValue = checkpointHeader.formatCheckpointHeaderValue()
 
 			checkpointHeaderValues = []string{checkpointHeaderValue}
 
@@ -520,7 +706,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			checkpointContainerHeaders[CheckpointHeaderName] = checkpointHeaderValues
 			checkpointContainerHeaders[StoragePolicyHeaderName] = storagePolicyHeaderValues
 
-			err = swiftclient.ContainerPut(volume.accountName, volume.checkpointContainerName, checkpointContainerHeaders)
+			err = volume.objectStore.ContainerPut(volume.accountName, volume.checkpointContainerName, checkpointContainerHeaders)
 			if nil != err {
 				return
 			}
@@ -534,7 +720,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 
 			accountHeaders[AccountHeaderName] = accountHeaderValues
 
-			err = swiftclient.AccountPost(volume.accountName, accountHeaders)
+			err = volume.objectStore.AccountPost(volume.accountName, accountHeaders)
 			if nil != err {
 				return
 			}
@@ -556,17 +742,29 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 		return
 	}
 
-	if checkpointHeaderVersion2 == checkpointVersion {
-		// Read in checkpointHeaderV2Struct
-
-		volume.checkpointHeaderVersion = checkpointHeaderVersion2
-
-		if 4 != len(checkpointHeaderValueSlice) {
+	if (checkpointHeaderVersion2 == checkpointVersion) || (checkpointHeaderVersion3 == checkpointVersion) || (checkpointHeaderVersion4 == checkpointVersion) || (checkpointHeaderVersion5 == checkpointVersion) {
+		// Read in checkpointHeaderV5Struct (checkpointHeaderVersion2 lacks the VolumeStats fields,
+		// both checkpointHeaderVersion2 & checkpointHeaderVersion3 lack the OwnerStats table, and
+		// all but checkpointHeaderVersion5 lack the snapshot list)
+
+		volume.checkpointHeaderVersion = checkpointVersion
+
+		switch checkpointVersion {
+		case checkpointHeaderVersion2:
+			ok = (4 == len(checkpointHeaderValueSlice))
+		case checkpointHeaderVersion3:
+			ok = (7 == len(checkpointHeaderValueSlice))
+		case checkpointHeaderVersion4:
+			ok = (8 == len(checkpointHeaderValueSlice))
+		default: // checkpointHeaderVersion5
+			ok = (10 == len(checkpointHeaderValueSlice))
+		}
+		if !ok {
 			err = fmt.Errorf("Cannot parse %v/%v header %v: %v (wrong number of fields)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
 			return
 		}
 
-		volume.checkpointHeader = &checkpointHeaderV2Struct{}
+		volume.checkpointHeader = &checkpointHeaderV5Struct{}
 
 		volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber, err = strconv.ParseUint(checkpointHeaderValueSlice[1], 16, 64)
 		if nil != err {
@@ -586,151 +784,109 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			return
 		}
 
-		volume.inodeRecBPlusTreeLayout = make(sortedmap.LayoutReport)
-		volume.logSegmentRecBPlusTreeLayout = make(sortedmap.LayoutReport)
-		volume.bPlusTreeObjectBPlusTreeLayout = make(sortedmap.LayoutReport)
-
-		if 0 == volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber {
-			volume.checkpointObjectTrailer = &checkpointObjectTrailerV2Struct{
-				InodeRecBPlusTreeObjectNumber:             0,
-				InodeRecBPlusTreeObjectOffset:             0,
-				InodeRecBPlusTreeObjectLength:             0,
-				InodeRecBPlusTreeLayoutNumElements:        0,
-				LogSegmentRecBPlusTreeObjectNumber:        0,
-				LogSegmentRecBPlusTreeObjectOffset:        0,
-				LogSegmentRecBPlusTreeObjectLength:        0,
-				LogSegmentRecBPlusTreeLayoutNumElements:   0,
-				BPlusTreeObjectBPlusTreeObjectNumber:      0,
-				BPlusTreeObjectBPlusTreeObjectOffset:      0,
-				BPlusTreeObjectBPlusTreeObjectLength:      0,
-				BPlusTreeObjectBPlusTreeLayoutNumElements: 0,
-			}
-		} else {
-			// Read in checkpointObjectTrailerV2Struct
-			checkpointObjectTrailerBuf, err =
-				swiftclient.ObjectTail(
-					volume.accountName,
-					volume.checkpointContainerName,
-					utils.Uint64ToHexStr(volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber),
-					volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength)
+		if checkpointHeaderVersion2 != checkpointVersion {
+			volume.checkpointHeader.InodeCount, err = strconv.ParseUint(checkpointHeaderValueSlice[4], 16, 64)
 			if nil != err {
+				err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad inodeCount)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
 				return
 			}
 
-			volume.checkpointObjectTrailer = &checkpointObjectTrailerV2Struct{}
+			volume.checkpointHeader.LogSegmentBytes, err = strconv.ParseUint(checkpointHeaderValueSlice[5], 16, 64)
+			if nil != err {
+				err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad logSegmentBytes)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
+				return
+			}
 
-			bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, volume.checkpointObjectTrailer, LittleEndian)
+			volume.checkpointHeader.ReferencedBytes, err = strconv.ParseUint(checkpointHeaderValueSlice[6], 16, 64)
 			if nil != err {
+				err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad referencedBytes)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
 				return
 			}
+		}
 
-			// Deserialize volume.{inodeRec|logSegmentRec|bPlusTreeObject}BPlusTreeLayout LayoutReports
+		if (checkpointHeaderVersion4 == checkpointVersion) || (checkpointHeaderVersion5 == checkpointVersion) {
+			volume.checkpointHeader.OwnerStatsNumElements, err = strconv.ParseUint(checkpointHeaderValueSlice[7], 16, 64)
+			if nil != err {
+				err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad ownerStatsNumElements)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
+				return
+			}
+		}
 
-			expectedCheckpointObjectTrailerSize = volume.checkpointObjectTrailer.InodeRecBPlusTreeLayoutNumElements
-			expectedCheckpointObjectTrailerSize += volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeLayoutNumElements
-			expectedCheckpointObjectTrailerSize += volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeLayoutNumElements
-			expectedCheckpointObjectTrailerSize *= globals.elementOfBPlusTreeLayoutStructSize
-			expectedCheckpointObjectTrailerSize += bytesConsumed
+		if checkpointHeaderVersion5 == checkpointVersion {
+			volume.checkpointHeader.SnapshotListNumElements, err = strconv.ParseUint(checkpointHeaderValueSlice[8], 16, 64)
+			if nil != err {
+				err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad snapshotListNumElements)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
+				return
+			}
 
-			if uint64(len(checkpointObjectTrailerBuf)) != expectedCheckpointObjectTrailerSize {
-				err = fmt.Errorf("volume.checkpointObjectTrailer for volume %v does not match required size", volume.volumeName)
+			volume.checkpointHeader.SnapshotListLength, err = strconv.ParseUint(checkpointHeaderValueSlice[9], 16, 64)
+			if nil != err {
+				err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad snapshotListLength)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
 				return
 			}
+		}
 
-			for layoutReportIndex = 0; layoutReportIndex < volume.checkpointObjectTrailer.InodeRecBPlusTreeLayoutNumElements; layoutReportIndex++ {
-				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
-				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
-				if nil != err {
-					return
-				}
+		// Read in checkpointObjectTrailerV2Struct and what follows it
 
-				volume.inodeRecBPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
-			}
+		checkpointContents, snapshotListBuf, err = volume.fetchCheckpointContents(
+			volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
+			volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
+			volume.checkpointHeader.OwnerStatsNumElements)
+		if nil != err {
+			return
+		}
 
-			for layoutReportIndex = 0; layoutReportIndex < volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeLayoutNumElements; layoutReportIndex++ {
-				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
-				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
-				if nil != err {
-					return
-				}
+		if uint64(len(snapshotListBuf)) != volume.checkpointHeader.SnapshotListLength {
+			err = fmt.Errorf("volume.checkpointObjectTrailer for volume %v does not match required size", volume.volumeName)
+			return
+		}
 
-				volume.logSegmentRecBPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
-			}
+		volume.checkpointObjectTrailer = checkpointContents.checkpointObjectTrailer
+		volume.inodeRecBPlusTreeLayout = checkpointContents.inodeRecBPlusTreeLayout
+		volume.logSegmentRecBPlusTreeLayout = checkpointContents.logSegmentRecBPlusTreeLayout
+		volume.bPlusTreeObjectBPlusTreeLayout = checkpointContents.bPlusTreeObjectBPlusTreeLayout
+		volume.userStats = checkpointContents.userStats
+		volume.groupStats = checkpointContents.groupStats
 
-			for layoutReportIndex = 0; layoutReportIndex < volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeLayoutNumElements; layoutReportIndex++ {
-				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
-				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
-				if nil != err {
-					return
-				}
+		// Load volume.{inodeRec|logSegmentRec|bPlusTreeObject} B+Trees
 
-				volume.bPlusTreeObjectBPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
-			}
+		err = volume.loadBPlusTrees(volume.checkpointObjectTrailer, volume.inodeRecWrapper, volume.logSegmentRecWrapper, volume.bPlusTreeObjectWrapper)
+		if nil != err {
+			return
 		}
 
-		// Load volume.{inodeRec|logSegmentRec|bPlusTreeObject} B+Trees
+		// Load any snapshots recorded in the checkpoint
 
-		if 0 == volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber {
-			volume.inodeRecWrapper.bPlusTree =
-				sortedmap.NewBPlusTree(
-					volume.maxInodesPerMetadataNode,
-					sortedmap.CompareUint64,
-					volume.inodeRecWrapper,
-					globals.inodeRecCache)
-		} else {
-			volume.inodeRecWrapper.bPlusTree, err =
-				sortedmap.OldBPlusTree(
-					volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber,
-					volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectOffset,
-					volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectLength,
-					sortedmap.CompareUint64,
-					volume.inodeRecWrapper,
-					globals.inodeRecCache)
-			if nil != err {
-				return
-			}
+		err = volume.unpackSnapshotList(snapshotListBuf, volume.checkpointHeader.SnapshotListNumElements)
+		if nil != err {
+			return
 		}
 
-		if 0 == volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectNumber {
-			volume.logSegmentRecWrapper.bPlusTree =
-				sortedmap.NewBPlusTree(
-					volume.maxLogSegmentsPerMetadataNode,
-					sortedmap.CompareUint64,
-					volume.logSegmentRecWrapper,
-					globals.logSegmentRecCache)
-		} else {
-			volume.logSegmentRecWrapper.bPlusTree, err =
-				sortedmap.OldBPlusTree(
-					volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectNumber,
-					volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectOffset,
-					volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectLength,
-					sortedmap.CompareUint64,
-					volume.logSegmentRecWrapper,
-					globals.logSegmentRecCache)
+		if checkpointHeaderVersion2 == checkpointVersion {
+			// Only the inode count can be reconstructed here... byte totals await RebuildVolumeStats()
+
+			inodeRecCount, err = volume.inodeRecWrapper.bPlusTree.Len()
 			if nil != err {
 				return
 			}
+
+			volume.checkpointHeader.InodeCount = uint64(inodeRecCount)
+
+			volume.volumeStatsRebuildPending = true
+
+			logger.Warnf("Volume %v checkpoint predates VolumeStats... LogSegmentBytes & ReferencedBytes must be rebuilt", volume.volumeName)
 		}
 
-		if 0 == volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectNumber {
-			volume.bPlusTreeObjectWrapper.bPlusTree =
-				sortedmap.NewBPlusTree(
-					volume.maxDirFileNodesPerMetadataNode,
-					sortedmap.CompareUint64,
-					volume.bPlusTreeObjectWrapper,
-					globals.bPlusTreeObjectCache)
-		} else {
-			volume.bPlusTreeObjectWrapper.bPlusTree, err =
-				sortedmap.OldBPlusTree(
-					volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectNumber,
-					volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectOffset,
-					volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectLength,
-					sortedmap.CompareUint64,
-					volume.bPlusTreeObjectWrapper,
-					globals.bPlusTreeObjectCache)
-			if nil != err {
-				return
-			}
+		if (checkpointHeaderVersion4 != checkpointVersion) && (checkpointHeaderVersion5 != checkpointVersion) {
+			volume.ownerStatsRebuildPending = true
+
+			logger.Warnf("Volume %v checkpoint predates OwnerStats... per-user & per-group totals must be rebuilt", volume.volumeName)
+		}
+
+		volume.volumeStats = VolumeStats{
+			InodeCount:      volume.checkpointHeader.InodeCount,
+			LogSegmentBytes: volume.checkpointHeader.LogSegmentBytes,
+			ReferencedBytes: volume.checkpointHeader.ReferencedBytes,
 		}
 	} else {
 		err = fmt.Errorf("Cannot parse %v/%v header %v: %v (version: %v not supported)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue, checkpointVersion)
@@ -853,6 +1009,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 				if nil != err {
 					logger.Fatalf("Reply Log for Volume %s hit unexpected volume.inodeRecWrapper.bPlusTree.Put() failure: %v", volume.volumeName, err)
 				}
+				volume.volumeStats.InodeCount++
 			}
 		case transactionPutInodeRecs:
 			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &numInodes, LittleEndian)
@@ -884,6 +1041,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 					if nil != err {
 						logger.Fatalf("Reply Log for Volume %s hit unexpected volume.inodeRecWrapper.bPlusTree.Put() failure: %v", volume.volumeName, err)
 					}
+					volume.volumeStats.InodeCount++
 				}
 			}
 		case transactionDeleteInodeRec:
@@ -892,10 +1050,13 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
 			}
 
-			_, err = volume.inodeRecWrapper.bPlusTree.DeleteByKey(inodeNumber)
+			ok, err = volume.inodeRecWrapper.bPlusTree.DeleteByKey(inodeNumber)
 			if nil != err {
 				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.inodeRecWrapper.bPlusTree.DeleteByKey() failure: %v", volume.volumeName, err)
 			}
+			if ok {
+				volume.volumeStats.InodeCount--
+			}
 		case transactionPutLogSegmentRec:
 			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &logSegmentNumber, LittleEndian)
 			if nil != err {
@@ -964,6 +1125,40 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			if nil != err {
 				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.bPlusTreeObjectWrapper.bPlusTree.DeleteByKey() failure: %v", volume.volumeName, err)
 			}
+		case transactionAdjustVolumeStats:
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &logSegmentBytesDelta, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &referencedBytesDelta, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+
+			volume.adjustVolumeStatsWhileLocked(int64(logSegmentBytesDelta), int64(referencedBytesDelta))
+		case transactionAdjustOwnerStats:
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &userID, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &groupID, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &ownerBytesDelta, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &ownerInodesDelta, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+
+			volume.adjustOwnerStatsWhileLocked(uint32(userID), uint32(groupID), int64(ownerBytesDelta), int64(ownerInodesDelta))
 		default:
 			// Corruption in replayLogTransactionFixedPart - so exit as if Replay Log ended here
 
@@ -986,10 +1181,203 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 	return
 }
 
+// checkpointContentsStruct holds what was deserialized from a checkpoint record.
+type checkpointContentsStruct struct {
+	checkpointObjectTrailer        *checkpointObjectTrailerV2Struct
+	inodeRecBPlusTreeLayout        sortedmap.LayoutReport
+	logSegmentRecBPlusTreeLayout   sortedmap.LayoutReport
+	bPlusTreeObjectBPlusTreeLayout sortedmap.LayoutReport
+	userStats                      map[uint32]OwnerStats // key == userID
+	groupStats                     map[uint32]OwnerStats // key == groupID
+}
+
+// fetchCheckpointContents reads in the checkpoint record found at the tail of the specified object. Whatever
+// follows the OwnerStats table (i.e. the snapshot list of a checkpointHeaderVersion5 checkpoint) is returned
+// in remainderBuf. Note that an objectNumber of zero indicates an empty checkpoint.
+func (volume *volumeStruct) fetchCheckpointContents(objectNumber uint64, objectLength uint64, ownerStatsNumElements uint64) (checkpointContents *checkpointContentsStruct, remainderBuf []byte, err error) {
+	var (
+		bytesConsumed                       uint64
+		checkpointObjectTrailerBuf          []byte
+		elementOfBPlusTreeLayout            elementOfBPlusTreeLayoutStruct
+		elementOfOwnerStats                 elementOfOwnerStatsStruct
+		expectedCheckpointObjectTrailerSize uint64
+		layoutReportIndex                   uint64
+		ownerStatsIndex                     uint64
+	)
+
+	checkpointContents = &checkpointContentsStruct{
+		checkpointObjectTrailer:        &checkpointObjectTrailerV2Struct{},
+		inodeRecBPlusTreeLayout:        make(sortedmap.LayoutReport),
+		logSegmentRecBPlusTreeLayout:   make(sortedmap.LayoutReport),
+		bPlusTreeObjectBPlusTreeLayout: make(sortedmap.LayoutReport),
+		userStats:                      make(map[uint32]OwnerStats),
+		groupStats:                     make(map[uint32]OwnerStats),
+	}
+
+	if 0 == objectNumber {
+		remainderBuf = make([]byte, 0)
+		err = nil
+		return
+	}
+
+	checkpointObjectTrailerBuf, err =
+		volume.objectStore.ObjectTail(
+			volume.accountName,
+			volume.checkpointContainerName,
+			utils.Uint64ToHexStr(objectNumber),
+			objectLength)
+	if nil != err {
+		return
+	}
+
+	bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, checkpointContents.checkpointObjectTrailer, LittleEndian)
+	if nil != err {
+		return
+	}
+
+	// Deserialize {inodeRec|logSegmentRec|bPlusTreeObject}BPlusTreeLayout LayoutReports
+
+	expectedCheckpointObjectTrailerSize = checkpointContents.checkpointObjectTrailer.InodeRecBPlusTreeLayoutNumElements
+	expectedCheckpointObjectTrailerSize += checkpointContents.checkpointObjectTrailer.LogSegmentRecBPlusTreeLayoutNumElements
+	expectedCheckpointObjectTrailerSize += checkpointContents.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeLayoutNumElements
+	expectedCheckpointObjectTrailerSize *= globals.elementOfBPlusTreeLayoutStructSize
+	expectedCheckpointObjectTrailerSize += ownerStatsNumElements * globals.elementOfOwnerStatsStructSize
+	expectedCheckpointObjectTrailerSize += bytesConsumed
+
+	if uint64(len(checkpointObjectTrailerBuf)) < expectedCheckpointObjectTrailerSize {
+		err = fmt.Errorf("checkpointObjectTrailer 0x%016X for volume %v does not match required size", objectNumber, volume.volumeName)
+		return
+	}
+
+	for layoutReportIndex = 0; layoutReportIndex < checkpointContents.checkpointObjectTrailer.InodeRecBPlusTreeLayoutNumElements; layoutReportIndex++ {
+		checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+		bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
+		if nil != err {
+			return
+		}
+
+		checkpointContents.inodeRecBPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
+	}
+
+	for layoutReportIndex = 0; layoutReportIndex < checkpointContents.checkpointObjectTrailer.LogSegmentRecBPlusTreeLayoutNumElements; layoutReportIndex++ {
+		checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+		bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
+		if nil != err {
+			return
+		}
+
+		checkpointContents.logSegmentRecBPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
+	}
+
+	for layoutReportIndex = 0; layoutReportIndex < checkpointContents.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeLayoutNumElements; layoutReportIndex++ {
+		checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+		bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
+		if nil != err {
+			return
+		}
+
+		checkpointContents.bPlusTreeObjectBPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
+	}
+
+	// Deserialize {user|group}Stats
+
+	for ownerStatsIndex = 0; ownerStatsIndex < ownerStatsNumElements; ownerStatsIndex++ {
+		checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+		bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfOwnerStats, LittleEndian)
+		if nil != err {
+			return
+		}
+
+		switch elementOfOwnerStats.OwnerType {
+		case ownerTypeUser:
+			checkpointContents.userStats[uint32(elementOfOwnerStats.OwnerID)] = OwnerStats{Bytes: elementOfOwnerStats.Bytes, Inodes: elementOfOwnerStats.Inodes}
+		case ownerTypeGroup:
+			checkpointContents.groupStats[uint32(elementOfOwnerStats.OwnerID)] = OwnerStats{Bytes: elementOfOwnerStats.Bytes, Inodes: elementOfOwnerStats.Inodes}
+		default:
+			err = fmt.Errorf("checkpointObjectTrailer 0x%016X for volume %v contains unknown OwnerType %v", objectNumber, volume.volumeName, elementOfOwnerStats.OwnerType)
+			return
+		}
+	}
+
+	remainderBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+
+	err = nil
+	return
+}
+
+// loadBPlusTrees opens (or, if not yet present in the checkpoint, creates) the three B+Trees rooted as indicated
+// by checkpointObjectTrailer via the supplied wrappers.
+func (volume *volumeStruct) loadBPlusTrees(checkpointObjectTrailer *checkpointObjectTrailerV2Struct, inodeRecWrapper *bPlusTreeWrapperStruct, logSegmentRecWrapper *bPlusTreeWrapperStruct, bPlusTreeObjectWrapper *bPlusTreeWrapperStruct) (err error) {
+	if 0 == checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber {
+		inodeRecWrapper.bPlusTree =
+			sortedmap.NewBPlusTree(
+				volume.maxInodesPerMetadataNode,
+				sortedmap.CompareUint64,
+				inodeRecWrapper,
+				globals.inodeRecCache)
+	} else {
+		inodeRecWrapper.bPlusTree, err =
+			sortedmap.OldBPlusTree(
+				checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber,
+				checkpointObjectTrailer.InodeRecBPlusTreeObjectOffset,
+				checkpointObjectTrailer.InodeRecBPlusTreeObjectLength,
+				sortedmap.CompareUint64,
+				inodeRecWrapper,
+				globals.inodeRecCache)
+		if nil != err {
+			return
+		}
+	}
+
+	if 0 == checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectNumber {
+		logSegmentRecWrapper.bPlusTree =
+			sortedmap.NewBPlusTree(
+				volume.maxLogSegmentsPerMetadataNode,
+				sortedmap.CompareUint64,
+				logSegmentRecWrapper,
+				globals.logSegmentRecCache)
+	} else {
+		logSegmentRecWrapper.bPlusTree, err =
+			sortedmap.OldBPlusTree(
+				checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectNumber,
+				checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectOffset,
+				checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectLength,
+				sortedmap.CompareUint64,
+				logSegmentRecWrapper,
+				globals.logSegmentRecCache)
+		if nil != err {
+			return
+		}
+	}
+
+	if 0 == checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectNumber {
+		bPlusTreeObjectWrapper.bPlusTree =
+			sortedmap.NewBPlusTree(
+				volume.maxDirFileNodesPerMetadataNode,
+				sortedmap.CompareUint64,
+				bPlusTreeObjectWrapper,
+				globals.bPlusTreeObjectCache)
+	} else {
+		bPlusTreeObjectWrapper.bPlusTree, err =
+			sortedmap.OldBPlusTree(
+				checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectNumber,
+				checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectOffset,
+				checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectLength,
+				sortedmap.CompareUint64,
+				bPlusTreeObjectWrapper,
+				globals.bPlusTreeObjectCache)
+		if nil != err {
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
 func (volume *volumeStruct) putCheckpoint() (err error) {
 	var (
 		bytesUsedCumulative                    uint64
-		bytesUsedThisBPlusTree                 uint64
 		checkpointContainerHeaders             map[string][]string
 		checkpointHeaderValue                  string
 		checkpointHeaderValues                 []string
@@ -999,14 +1387,32 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		combinedBPlusTreeLayout                sortedmap.LayoutReport
 		elementOfBPlusTreeLayout               elementOfBPlusTreeLayoutStruct
 		elementOfBPlusTreeLayoutBuf            []byte
+		elementOfOwnerStats                    elementOfOwnerStatsStruct
+		elementOfOwnerStatsBuf                 []byte
 		objectNumber                           uint64
 		ok                                     bool
+		ownerID                                uint32
+		ownerStats                             OwnerStats
+		ownerStatsBuf                          []byte
+		previousCheckpointObjectNumber         uint64
+		snapshotListBuf                        []byte
 		treeLayoutBuf                          []byte
 		treeLayoutBufSize                      uint64
 	)
 
+	if volume.volumeStatsRebuildPending || volume.ownerStatsRebuildPending {
+		// Persisting now would record (as if known) totals yet to be rebuilt... so retain the prior
+		// checkpoint (and any Replay Log) until RebuildVolumeStats() & RebuildOwnerStats() have been called
+
+		logger.Warnf("Volume %v VolumeStats/OwnerStats awaiting rebuild... deferring checkpoint", volume.volumeName)
+		err = nil
+		return
+	}
+
 	volume.checkpointFlushedData = false
 
+	previousCheckpointObjectNumber = volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber
+
 	volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber,
 		volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectOffset,
 		volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectLength,
@@ -1029,8 +1435,11 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		return
 	}
 
-	if !volume.checkpointFlushedData {
-		return // since nothing was flushed, we can simply return
+	// A degraded volume must persist a checkpoint regardless... the B+Tree nodes successfully flushed by
+	// its failed checkpoint(s) remain clean yet aren't referenced by its last good checkpoint
+
+	if !volume.checkpointFlushedData && !volume.snapshotListChanged && !volume.statsRebuilt && !volume.checkpointStatus.Degraded {
+		return // since nothing was flushed (nor was the snapshot list changed nor stats rebuilt), we can simply return
 	}
 
 	err = volume.inodeRecWrapper.bPlusTree.Prune()
@@ -1086,6 +1495,37 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		treeLayoutBuf = append(treeLayoutBuf, elementOfBPlusTreeLayoutBuf...)
 	}
 
+	ownerStatsBuf = make([]byte, 0, uint64(len(volume.userStats)+len(volume.groupStats))*globals.elementOfOwnerStatsStructSize)
+
+	elementOfOwnerStats.OwnerType = ownerTypeUser
+	for ownerID, ownerStats = range volume.userStats {
+		elementOfOwnerStats.OwnerID = uint64(ownerID)
+		elementOfOwnerStats.Bytes = ownerStats.Bytes
+		elementOfOwnerStats.Inodes = ownerStats.Inodes
+		elementOfOwnerStatsBuf, err = cstruct.Pack(&elementOfOwnerStats, LittleEndian)
+		if nil != err {
+			return
+		}
+		ownerStatsBuf = append(ownerStatsBuf, elementOfOwnerStatsBuf...)
+	}
+
+	elementOfOwnerStats.OwnerType = ownerTypeGroup
+	for ownerID, ownerStats = range volume.groupStats {
+		elementOfOwnerStats.OwnerID = uint64(ownerID)
+		elementOfOwnerStats.Bytes = ownerStats.Bytes
+		elementOfOwnerStats.Inodes = ownerStats.Inodes
+		elementOfOwnerStatsBuf, err = cstruct.Pack(&elementOfOwnerStats, LittleEndian)
+		if nil != err {
+			return
+		}
+		ownerStatsBuf = append(ownerStatsBuf, elementOfOwnerStatsBuf...)
+	}
+
+	snapshotListBuf, err = volume.packSnapshotListWhileLocked()
+	if nil != err {
+		return
+	}
+
 	err = volume.openCheckpointChunkedPutContextIfNecessary()
 	if nil != err {
 		return
@@ -1106,6 +1546,20 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		return
 	}
 
+	if 0 < len(ownerStatsBuf) {
+		err = volume.sendChunkToCheckpointChunkedPutContext(ownerStatsBuf)
+		if nil != err {
+			return
+		}
+	}
+
+	if 0 < len(snapshotListBuf) {
+		err = volume.sendChunkToCheckpointChunkedPutContext(snapshotListBuf)
+		if nil != err {
+			return
+		}
+	}
+
 	checkpointObjectTrailerEndingOffset, err = volume.bytesPutToCheckpointChunkedPutContext()
 	if nil != err {
 		return
@@ -1118,12 +1572,16 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 
 	volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength = checkpointObjectTrailerEndingOffset - checkpointObjectTrailerBeginningOffset
 
-	checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
-		checkpointHeaderVersion2,
-		volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
-		volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
-		volume.checkpointHeader.ReservedToNonce,
-	)
+	volume.checkpointHeader.InodeCount = volume.volumeStats.InodeCount
+	volume.checkpointHeader.LogSegmentBytes = volume.volumeStats.LogSegmentBytes
+	volume.checkpointHeader.ReferencedBytes = volume.volumeStats.ReferencedBytes
+
+	volume.checkpointHeader.OwnerStatsNumElements = uint64(len(volume.userStats) + len(volume.groupStats))
+
+	volume.checkpointHeader.SnapshotListNumElements = uint64(len(volume.snapshotList))
+	volume.checkpointHeader.SnapshotListLength = uint64(len(snapshotListBuf))
+
+	checkpointHeaderValue = volume.checkpointHeader.formatCheckpointHeaderValue()
 
 	checkpointHeaderValues = []string{checkpointHeaderValue}
 
@@ -1131,12 +1589,15 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 
 	checkpointContainerHeaders[CheckpointHeaderName] = checkpointHeaderValues
 
-	err = swiftclient.ContainerPost(volume.accountName, volume.checkpointContainerName, checkpointContainerHeaders)
+	err = volume.objectStore.ContainerPost(volume.accountName, volume.checkpointContainerName, checkpointContainerHeaders)
 	if nil != err {
 		return
 	}
 
-	volume.checkpointHeaderVersion = checkpointHeaderVersion2
+	volume.checkpointHeaderVersion = checkpointHeaderVersion5
+
+	volume.snapshotListChanged = false
+	volume.statsRebuilt = false
 
 	if nil != volume.replayLogFile {
 		err = volume.replayLogFile.Close()
@@ -1155,32 +1616,7 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		}
 	}
 
-	combinedBPlusTreeLayout = make(sortedmap.LayoutReport)
-
-	for objectNumber, bytesUsedThisBPlusTree = range volume.inodeRecBPlusTreeLayout {
-		bytesUsedCumulative, ok = combinedBPlusTreeLayout[objectNumber]
-		if ok {
-			combinedBPlusTreeLayout[objectNumber] = bytesUsedCumulative + bytesUsedThisBPlusTree
-		} else {
-			combinedBPlusTreeLayout[objectNumber] = bytesUsedThisBPlusTree
-		}
-	}
-	for objectNumber, bytesUsedThisBPlusTree = range volume.logSegmentRecBPlusTreeLayout {
-		bytesUsedCumulative, ok = combinedBPlusTreeLayout[objectNumber]
-		if ok {
-			combinedBPlusTreeLayout[objectNumber] = bytesUsedCumulative + bytesUsedThisBPlusTree
-		} else {
-			combinedBPlusTreeLayout[objectNumber] = bytesUsedThisBPlusTree
-		}
-	}
-	for objectNumber, bytesUsedThisBPlusTree = range volume.bPlusTreeObjectBPlusTreeLayout {
-		bytesUsedCumulative, ok = combinedBPlusTreeLayout[objectNumber]
-		if ok {
-			combinedBPlusTreeLayout[objectNumber] = bytesUsedCumulative + bytesUsedThisBPlusTree
-		} else {
-			combinedBPlusTreeLayout[objectNumber] = bytesUsedThisBPlusTree
-		}
-	}
+	combinedBPlusTreeLayout = volume.combinedBPlusTreeLayoutWhileLocked()
 
 	for objectNumber, bytesUsedCumulative = range combinedBPlusTreeLayout {
 		if 0 == bytesUsedCumulative {
@@ -1188,12 +1624,18 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 			delete(volume.logSegmentRecBPlusTreeLayout, objectNumber)
 			delete(volume.bPlusTreeObjectBPlusTreeLayout, objectNumber)
 
-			swiftclient.ObjectDeleteAsync(
-				volume.accountName,
-				volume.checkpointContainerName,
-				utils.Uint64ToHexStr(objectNumber),
-				volume.fetchNextCheckPointDoneWaitGroupWhileLocked(),
-				nil)
+			if !volume.snapshotReferencesObjectWhileLocked(objectNumber) {
+				volume.objectDeleteAfterCheckpointWhileLocked(objectNumber)
+			}
+		}
+	}
+
+	// The previous checkpoint record may have been written to an object holding no B+Tree nodes
+
+	if (0 != previousCheckpointObjectNumber) && (previousCheckpointObjectNumber != volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber) {
+		_, ok = combinedBPlusTreeLayout[previousCheckpointObjectNumber]
+		if !ok && !volume.snapshotReferencesObjectWhileLocked(previousCheckpointObjectNumber) {
+			volume.objectDeleteAfterCheckpointWhileLocked(previousCheckpointObjectNumber)
 		}
 	}
 
@@ -1201,6 +1643,25 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 	return
 }
 
+// combinedBPlusTreeLayoutWhileLocked sums, for each checkpoint object, the bytes used by all three B+Trees.
+func (volume *volumeStruct) combinedBPlusTreeLayoutWhileLocked() (combinedBPlusTreeLayout sortedmap.LayoutReport) {
+	var (
+		bPlusTreeLayout        sortedmap.LayoutReport
+		bytesUsedThisBPlusTree uint64
+		objectNumber           uint64
+	)
+
+	combinedBPlusTreeLayout = make(sortedmap.LayoutReport)
+
+	for _, bPlusTreeLayout = range []sortedmap.LayoutReport{volume.inodeRecBPlusTreeLayout, volume.logSegmentRecBPlusTreeLayout, volume.bPlusTreeObjectBPlusTreeLayout} {
+		for objectNumber, bytesUsedThisBPlusTree = range bPlusTreeLayout {
+			combinedBPlusTreeLayout[objectNumber] += bytesUsedThisBPlusTree
+		}
+	}
+
+	return
+}
+
 func (volume *volumeStruct) openCheckpointChunkedPutContextIfNecessary() (err error) {
 	if nil == volume.checkpointChunkedPutContext {
 		volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber, err = volume.fetchNonceWhileLocked()
@@ -1208,7 +1669,7 @@ func (volume *volumeStruct) openCheckpointChunkedPutContextIfNecessary() (err er
 			return
 		}
 		volume.checkpointChunkedPutContext, err =
-			swiftclient.ObjectFetchChunkedPutContext(volume.accountName,
+			volume.objectStore.ObjectFetchChunkedPutContext(volume.accountName,
 				volume.checkpointContainerName,
 				utils.Uint64ToHexStr(volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber))
 		if nil != err {
@@ -1249,6 +1710,12 @@ func (volume *volumeStruct) closeCheckpointChunkedPutContextIfNecessary() (err e
 		if nil == err {
 			if bytesPut >= volume.maxFlushSize {
 				err = volume.checkpointChunkedPutContext.Close()
+				if nil == err {
+					delete(volume.checkpointNodeLocatorMap, volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber)
+					volume.checkpointObjectLengthMap[volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber] = bytesPut
+				} else {
+					volume.retainFailedCheckpointChunkedPutContext()
+				}
 				volume.checkpointChunkedPutContext = nil
 			}
 		}
@@ -1257,27 +1724,189 @@ func (volume *volumeStruct) closeCheckpointChunkedPutContextIfNecessary() (err e
 }
 
 func (volume *volumeStruct) closeCheckpointChunkedPutContext() (err error) {
+	var (
+		bytesPut    uint64
+		bytesPutErr error
+	)
+
 	if nil == volume.checkpointChunkedPutContext {
 		err = fmt.Errorf("closeCheckpointChunkedPutContext() called while volume.checkpointChunkedPutContext == nil")
 	} else {
+		bytesPut, bytesPutErr = volume.checkpointChunkedPutContext.BytesPut()
 		err = volume.checkpointChunkedPutContext.Close()
+		if nil == err {
+			delete(volume.checkpointNodeLocatorMap, volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber)
+			if nil == bytesPutErr {
+				volume.checkpointObjectLengthMap[volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber] = bytesPut
+			}
+		} else {
+			volume.retainFailedCheckpointChunkedPutContext()
+		}
 		volume.checkpointChunkedPutContext = nil
 	}
 	return // err set as appropriate regardless of path
 }
 
-// checkpointDaemon periodically and upon request persists a checkpoint/snapshot.
+// retainFailedCheckpointChunkedPutContext remembers the current checkpointChunkedPutContext
+// (whose Close() failed) so that any B+Tree nodes flushed to it may still be read back by
+// GetNode() until they have been persisted by a subsequent successful checkpoint.
+func (volume *volumeStruct) retainFailedCheckpointChunkedPutContext() {
+	volume.failedCheckpointObjectMap[volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber] = volume.checkpointChunkedPutContext
+}
+
+// attemptCheckpointWhileLocked performs a putCheckpoint()... recovering from its failure such
+// that a subsequent attempt will persist everything modified since the last good checkpoint.
+func (volume *volumeStruct) attemptCheckpointWhileLocked() (err error) {
+	var (
+		closeErr                 error
+		lastGoodCheckpointHeader checkpointHeaderV5Struct
+		touchErr                 error
+	)
+
+	if volume.fenced || cluster.VolumeIsFenced(volume.volumeName) {
+		// Another Peer has taken over the Volume... so we must not overwrite its checkpoint. Any
+		// updates since our last checkpoint are lost... as are deletions awaiting this checkpoint
+		// (see objectDeleteAfterCheckpointWhileLocked()) since the other Peer may yet reference them
+
+		if !volume.fenced {
+			logger.Warnf("Volume %v fenced by another Peer... no longer checkpointing", volume.volumeName)
+			volume.fenced = true
+		}
+		stats.IncrementOperations(&stats.ClusterFencedCheckpointOps)
+		err = fmt.Errorf("Volume %v fenced by another Peer... checkpoint refused", volume.volumeName)
+		err = blunder.AddError(err, blunder.NotActiveError)
+		return
+	}
+
+	if volume.checkpointTouchPending {
+		err = volume.touchFailedCheckpointNodesWhileLocked()
+		if nil != err {
+			volume.recordCheckpointFailureWhileLocked(err)
+			return
+		}
+	}
+
+	lastGoodCheckpointHeader = *volume.checkpointHeader
+
+	err = volume.putCheckpoint()
+	if nil == err {
+		if volume.checkpointStatus.Degraded {
+			logger.Infof("Volume %v checkpoint succeeded after %v failed attempt(s)... no longer degraded", volume.volumeName, volume.checkpointStatus.FailedAttempts)
+		}
+		volume.checkpointStatus = CheckpointStatus{}
+		volume.checkpointNodeLocatorMap = make(map[uint64][]nodeLocatorStruct)
+		volume.failedCheckpointObjectMap = make(map[uint64]objectstore.ChunkedPutContext)
+		return
+	}
+
+	// Finish off any partially written checkpoint object... should that fail, too, what was sent
+	// to it must remain readable as it may well hold the only copy of some B+Tree nodes
+
+	if nil != volume.checkpointChunkedPutContext {
+		closeErr = volume.checkpointChunkedPutContext.Close()
+		if nil == closeErr {
+			delete(volume.checkpointNodeLocatorMap, volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber)
+		} else {
+			volume.retainFailedCheckpointChunkedPutContext()
+		}
+		volume.checkpointChunkedPutContext = nil
+	}
+
+	// Revert to the last good checkpoint header (that the replay log, if any, also refers to)...
+	// though nonces reserved in the meantime must not be handed out again
+
+	if volume.checkpointHeader.ReservedToNonce > lastGoodCheckpointHeader.ReservedToNonce {
+		lastGoodCheckpointHeader.ReservedToNonce = volume.checkpointHeader.ReservedToNonce
+	}
+
+	*volume.checkpointHeader = lastGoodCheckpointHeader
+
+	// Nodes flushed by the failed checkpoint have been marked clean... those residing in objects
+	// whose PUT failed are re-marked dirty so that the next checkpoint re-persists them
+
+	volume.checkpointTouchPending = true
+
+	touchErr = volume.touchFailedCheckpointNodesWhileLocked()
+	if nil != touchErr {
+		logger.WarnfWithError(touchErr, "Volume %v unable to re-mark B+Tree nodes dirty following failed checkpoint... will retry", volume.volumeName)
+	}
+
+	volume.recordCheckpointFailureWhileLocked(err)
+
+	return
+}
+
+// touchFailedCheckpointNodesWhileLocked marks dirty each B+Tree node put to a checkpoint object whose
+// PUT failed (i.e. those remaining in volume.checkpointNodeLocatorMap). Nodes put to objects whose PUT
+// succeeded were persisted and need not be rewritten.
+func (volume *volumeStruct) touchFailedCheckpointNodesWhileLocked() (err error) {
+	var (
+		nodeLocator  nodeLocatorStruct
+		nodeLocators []nodeLocatorStruct
+		objectNumber uint64
+	)
+
+	for objectNumber, nodeLocators = range volume.checkpointNodeLocatorMap {
+		for _, nodeLocator = range nodeLocators {
+			err = nodeLocator.touchNode()
+			if nil != err {
+				return
+			}
+		}
+
+		delete(volume.checkpointNodeLocatorMap, objectNumber)
+	}
+
+	volume.checkpointTouchPending = false
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) recordCheckpointFailureWhileLocked(err error) {
+	stats.IncrementOperations(&stats.HeadhunterCheckpointFailedOps)
+
+	if !volume.checkpointStatus.Degraded {
+		volume.checkpointStatus.Degraded = true
+		volume.checkpointStatus.DegradedSince = time.Now()
+	}
+
+	volume.checkpointStatus.FailedAttempts++
+	volume.checkpointStatus.LastError = err.Error()
+
+	logger.ErrorfWithError(err, "Volume %v checkpoint failed (attempt %v)... volume degraded until a retried checkpoint succeeds", volume.volumeName, volume.checkpointStatus.FailedAttempts)
+}
+
+// checkpointDaemon periodically and upon request persists a checkpoint/snapshot. While
+// degraded (i.e. following a failed checkpoint), checkpoints are retried with backoff.
 func (volume *volumeStruct) checkpointDaemon() {
 	var (
 		checkpointRequest *checkpointRequestStruct
+		checkpointTimeout time.Duration
+		compactionErr     error
 		exitOnCompletion  bool
+		retryDelay        time.Duration
+		snapshot          *snapshotStruct
 	)
 
 	for {
+		volume.Lock()
+		if volume.checkpointStatus.Degraded {
+			checkpointTimeout = retryDelay
+			retryDelay *= 2
+			if retryDelay > volume.checkpointInterval {
+				retryDelay = volume.checkpointInterval
+			}
+		} else {
+			checkpointTimeout = volume.checkpointInterval
+			retryDelay = volume.checkpointRetryDelay
+		}
+		volume.Unlock()
+
 		select {
 		case checkpointRequest = <-volume.checkpointRequestChan:
 			// Explicitly requested checkpoint... use it below
-		case <-time.After(volume.checkpointInterval):
+		case <-time.After(checkpointTimeout):
 			// Time to automatically do a checkpoint... so dummy up a checkpointRequest
 			checkpointRequest = &checkpointRequestStruct{exitOnCompletion: false}
 			checkpointRequest.waitGroup.Add(1) // ...even though we won't be waiting on it...
@@ -1285,34 +1914,41 @@ func (volume *volumeStruct) checkpointDaemon() {
 
 		volume.Lock()
 
-		checkpointRequest.err = volume.putCheckpoint()
-
-		if nil != checkpointRequest.err {
-			// As part of conducting the checkpoint - and depending upon where the early non-nil
-			// error was reported - it is highly likely that e.g. pages of the B+Trees have been
-			// marked clean even though either their dirty data has not been successfully posted
-			// to Swift and/or the Checkpoint Header that points to it has not been successfully
-			// recorded in Swift. In either case, a subsequent checkpoint may, indeed, appear to
-			// succeed and quite probably miss some of the references nodes of the B+Trees not
-			// having made it to Swift... and, yet, wrongly presume all is (now) well.
+		checkpointRequest.err = volume.attemptCheckpointWhileLocked()
 
-			// It should also be noted that other activity (e.g. garbage collection of usually
-			// now unreferenced data) awaiting completion of this checkpoint should not have
-			// been allowed to proceed.
+		if (nil == checkpointRequest.err) && ("" != checkpointRequest.snapshotName) {
+			// The snapshot must be recorded before anything (e.g. a DiscardNode() call) alters
+			// the just persisted checkpoint... after which the updated snapshot list is persisted
 
-			// For now, we will instead promptly fail right here thus preventing that subsequent
-			// checkpoint from masking the data loss. While there are alternatives (e.g. going
-			// back and marking every node of the B+Trees as being dirty - or at least those that
-			// were marked clean), such an approach will not be pursued at this time.
+			snapshot, checkpointRequest.err = volume.recordSnapshotWhileLocked(checkpointRequest.snapshotName)
+			if nil == checkpointRequest.err {
+				checkpointRequest.snapshotID = snapshot.id
+				checkpointRequest.err = volume.attemptCheckpointWhileLocked()
+			} else {
+				checkpointRequest.snapshotErr = checkpointRequest.err
+				checkpointRequest.err = nil
+			}
+		}
 
-			logger.FatalfWithError(checkpointRequest.err, "Shutting down to prevent subsequent checkpoints from corrupting Swift")
+		if (nil == checkpointRequest.err) && !checkpointRequest.exitOnCompletion && (0 != volume.checkpointIntervalsPerCompaction) {
+			volume.checkpointsSinceCompaction++
+			if volume.checkpointsSinceCompaction >= volume.checkpointIntervalsPerCompaction {
+				volume.checkpointsSinceCompaction = 0
+				_, compactionErr = volume.compactWhileLocked()
+				if nil != compactionErr {
+					logger.WarnfWithError(compactionErr, "Volume %v compaction failed", volume.volumeName)
+				}
+			}
 		}
 
 		exitOnCompletion = checkpointRequest.exitOnCompletion // In case requestor re-uses checkpointRequest
 
 		checkpointRequest.waitGroup.Done() // Awake the checkpoint requestor
-		if nil != volume.checkpointDoneWaitGroup {
-			// Awake any others who were waiting on this checkpoint
+		if ((nil == checkpointRequest.err) || volume.fenced) && (nil != volume.checkpointDoneWaitGroup) {
+			// Awake any others who were waiting on this checkpoint... note that, should it have
+			// failed, activity awaiting it (e.g. garbage collection of log segments no longer
+			// referenced) remains held back until a retried checkpoint succeeds... while, should
+			// the Volume have been fenced, such activity is released only to be discarded
 			volume.checkpointDoneWaitGroup.Done()
 			volume.checkpointDoneWaitGroup = nil
 		}
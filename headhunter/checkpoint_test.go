@@ -0,0 +1,527 @@
+package headhunter
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// testSetRamSwiftChaos rewrites ramswift's confFile and has it (via SIGHUP) reload its chaos settings...
+// returning once a ContainerPost() to the checkpoint container fails (or succeeds) accordingly
+func testSetRamSwiftChaos(t *testing.T, ramswiftConfFileName string, chaosConfFileContents string, expectFailure bool) {
+	err := ioutil.WriteFile(ramswiftConfFileName, []byte(chaosConfFileContents), 0600)
+	if nil != err {
+		t.Fatalf("ioutil.WriteFile() returned error: %v", err)
+	}
+
+	err = unix.Kill(unix.Getpid(), unix.SIGHUP)
+	if nil != err {
+		t.Fatalf("unix.Kill(,unix.SIGHUP) returned error: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		err = swiftclient.ContainerPost("TestCheckpointAccount", ".__checkpoint__", map[string][]string{"X-Container-Meta-Chaos-Probe": []string{"probe"}})
+		if expectFailure == (nil != err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("ramswift chaos settings not applied (expectFailure == %v)", expectFailure)
+}
+
+func testWaitGroupDone(wg *sync.WaitGroup, timeout time.Duration) (done bool) {
+	doneChan := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(doneChan)
+	}()
+
+	select {
+	case <-doneChan:
+		done = true
+	case <-time.After(timeout):
+		done = false
+	}
+
+	return
+}
+
+func TestCheckpoint(t *testing.T) {
+	confStrings := []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"SwiftClient.NoAuthTCPPort=9998",
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=0",
+		"SwiftClient.RetryLimitObject=0",
+		"SwiftClient.RetryDelay=10ms",
+		"SwiftClient.RetryDelayObject=10ms",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+		"Cluster.WhoAmI=Peer0",
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+		"FlowControl:TestFlowControl.MaxFlushSize=10000000",
+		"Volume:TestCheckpointVolume.PrimaryPeer=Peer0",
+		"Volume:TestCheckpointVolume.AccountName=TestCheckpointAccount",
+		"Volume:TestCheckpointVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:TestCheckpointVolume.CheckpointContainerStoragePolicy=gold",
+		"Volume:TestCheckpointVolume.CheckpointInterval=10s",
+		"Volume:TestCheckpointVolume.CheckpointRetryDelay=10ms",
+		"Volume:TestCheckpointVolume.FlowControl=TestFlowControl",
+		"Volume:TestCheckpointVolume.NonceValuesToReserve=100",
+		"Volume:TestCheckpointVolume.MaxInodesPerMetadataNode=32",
+		"Volume:TestCheckpointVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:TestCheckpointVolume.MaxDirFileNodesPerMetadataNode=16",
+		"FSGlobals.VolumeList=TestCheckpointVolume",
+		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
+		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
+		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
+		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
+		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
+		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+	}
+
+	// ramswift reloads its chaos settings from ramswiftConfFile upon SIGHUP
+
+	ramswiftConfFile, err := ioutil.TempFile("", "TestCheckpoint_RamSwift_")
+	if nil != err {
+		t.Fatalf("ioutil.TempFile() returned error: %v", err)
+	}
+
+	ramswiftConfFileName := ramswiftConfFile.Name()
+	defer os.Remove(ramswiftConfFileName)
+
+	err = ramswiftConfFile.Close()
+	if nil != err {
+		t.Fatalf("ramswiftConfFile.Close() returned error: %v", err)
+	}
+
+	// Launch a ramswift instance
+
+	signalHandlerIsArmed := false
+	doneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go ramswift.Daemon(ramswiftConfFileName, confStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM, unix.SIGHUP)
+
+	for !signalHandlerIsArmed {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	confMap, err := conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) returned error: %v", err)
+	}
+
+	err = logger.Up(confMap)
+	if nil != err {
+		t.Fatalf("logger.Up() returned error: %v", err)
+	}
+
+	err = stats.Up(confMap)
+	if nil != err {
+		t.Fatalf("stats.Up() returned error: %v", err)
+	}
+
+	err = dlm.Up(confMap)
+	if nil != err {
+		t.Fatalf("dlm.Up() returned error: %v", err)
+	}
+
+	err = swiftclient.Up(confMap)
+	if nil != err {
+		t.Fatalf("swiftclient.Up() returned error: %v", err)
+	}
+
+	err = Format(confMap, "TestCheckpointVolume")
+	if nil != err {
+		t.Fatalf("headhunter.Format() returned error: %v", err)
+	}
+
+	testCheckpointFailure(t, confMap, ramswiftConfFileName)
+	testCheckpointCompaction(t, confMap)
+
+	// Shutdown packages
+
+	err = swiftclient.Down()
+	if nil != err {
+		t.Fatalf("swiftclient.Down() returned error: %v", err)
+	}
+
+	err = dlm.Down()
+	if nil != err {
+		t.Fatalf("dlm.Down() returned error: %v", err)
+	}
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("stats.Down() returned error: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("logger.Down() returned error: %v", err)
+	}
+
+	// Send ourself a SIGTERM to terminate ramswift.Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-doneChan
+}
+
+// Checkpoint failures degrade the volume... until a retried checkpoint succeeds
+func testCheckpointFailure(t *testing.T, confMap conf.ConfMap, ramswiftConfFileName string) {
+	err := Up(confMap)
+	if nil != err {
+		t.Fatalf("headhunter.Up() [case 1] returned error: %v", err)
+	}
+
+	volume, err := FetchVolumeHandle("TestCheckpointVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle() [case 1] returned error: %v", err)
+	}
+
+	err = volume.PutInodeRec(1, []byte("Before failure"))
+	if nil != err {
+		t.Fatalf("PutInodeRec(1,) returned error: %v", err)
+	}
+
+	err = volume.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() [before failure] returned error: %v", err)
+	}
+
+	// With every object PUT & container POST failing, checkpoints fail... degrading the volume
+
+	testSetRamSwiftChaos(t, ramswiftConfFileName, "[RamSwiftChaos]\nObjectPutFailureRate: 1\nContainerPostFailureRate: 1\n", true)
+
+	err = volume.PutInodeRec(1, []byte("During failure"))
+	if nil != err {
+		t.Fatalf("PutInodeRec(1,) returned error: %v", err)
+	}
+	err = volume.PutInodeRec(2, []byte("During failure"))
+	if nil != err {
+		t.Fatalf("PutInodeRec(2,) returned error: %v", err)
+	}
+
+	checkpointDoneWaitGroup := volume.FetchNextCheckPointDoneWaitGroup()
+
+	err = volume.DoCheckpoint()
+	if nil == err {
+		t.Fatalf("DoCheckpoint() [during failure] should have failed")
+	}
+
+	checkpointStatus := volume.FetchCheckpointStatus()
+	if !checkpointStatus.Degraded || (0 == checkpointStatus.FailedAttempts) || ("" == checkpointStatus.LastError) {
+		t.Fatalf("FetchCheckpointStatus() [during failure] returned unexpected %+v", checkpointStatus)
+	}
+
+	value, ok, err := volume.GetInodeRec(2)
+	if (nil != err) || !ok || ("During failure" != string(value)) {
+		t.Fatalf("GetInodeRec(2) [during failure] returned unexpected value \"%s\" (ok == %v, err == %v)", value, ok, err)
+	}
+
+	volume.(*volumeStruct).Lock()
+	if (0 != len(volume.(*volumeStruct).checkpointNodeLocatorMap)) || (0 == len(volume.(*volumeStruct).failedCheckpointObjectMap)) {
+		t.Fatalf("B+Tree nodes put to failed checkpoint objects should have been marked dirty again")
+	}
+	volume.(*volumeStruct).Unlock()
+
+	if testWaitGroupDone(checkpointDoneWaitGroup, 100*time.Millisecond) {
+		t.Fatalf("checkpointDoneWaitGroup should not be done while degraded")
+	}
+
+	// Once Swift recovers, a retried checkpoint succeeds... and the volume is no longer degraded
+
+	testSetRamSwiftChaos(t, ramswiftConfFileName, "", false)
+
+	if !testWaitGroupDone(checkpointDoneWaitGroup, 5*time.Second) {
+		t.Fatalf("checkpointDoneWaitGroup should be done once a retried checkpoint succeeded")
+	}
+
+	checkpointStatus = volume.FetchCheckpointStatus()
+	if checkpointStatus.Degraded {
+		t.Fatalf("FetchCheckpointStatus() [after recovery] returned unexpected %+v", checkpointStatus)
+	}
+
+	err = volume.PutInodeRec(3, []byte("After recovery"))
+	if nil != err {
+		t.Fatalf("PutInodeRec(3,) returned error: %v", err)
+	}
+
+	// Should only the container POST fail, the nodes flushed (to objects successfully PUT) needn't be
+	// rewritten... yet a retried checkpoint must still persist them
+
+	testSetRamSwiftChaos(t, ramswiftConfFileName, "[RamSwiftChaos]\nContainerPostFailureRate: 1\n", true)
+
+	checkpointDoneWaitGroup = volume.FetchNextCheckPointDoneWaitGroup()
+
+	err = volume.DoCheckpoint()
+	if nil == err {
+		t.Fatalf("DoCheckpoint() [during POST failure] should have failed")
+	}
+
+	volumeAsStruct := volume.(*volumeStruct)
+
+	volumeAsStruct.Lock()
+	if (0 != len(volumeAsStruct.checkpointNodeLocatorMap)) || (0 != len(volumeAsStruct.failedCheckpointObjectMap)) {
+		t.Fatalf("Following a failed POST, no B+Tree nodes should have needed to be rewritten")
+	}
+	layoutBeforeRecovery := volumeAsStruct.combinedBPlusTreeLayoutWhileLocked()
+	volumeAsStruct.Unlock()
+
+	testSetRamSwiftChaos(t, ramswiftConfFileName, "", false)
+
+	if !testWaitGroupDone(checkpointDoneWaitGroup, 5*time.Second) {
+		t.Fatalf("checkpointDoneWaitGroup should be done once a retried checkpoint succeeded [after POST failure]")
+	}
+
+	volumeAsStruct.Lock()
+	layoutAfterRecovery := volumeAsStruct.combinedBPlusTreeLayoutWhileLocked()
+	volumeAsStruct.Unlock()
+
+	for objectNumber, bytesUsed := range layoutBeforeRecovery {
+		if bytesUsed != layoutAfterRecovery[objectNumber] {
+			t.Fatalf("Checkpoint object 0x%016X went from %v to %v bytes in use... nodes should not have been rewritten", objectNumber, bytesUsed, layoutAfterRecovery[objectNumber])
+		}
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("headhunter.Down() [case 1] returned error: %v", err)
+	}
+
+	// Everything put before, during, and after the failure should have been persisted
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("headhunter.Up() [case 2] returned error: %v", err)
+	}
+
+	volume, err = FetchVolumeHandle("TestCheckpointVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle() [case 2] returned error: %v", err)
+	}
+
+	for inodeNumber, expectedValue := range map[uint64]string{1: "During failure", 2: "During failure", 3: "After recovery"} {
+		value, ok, err = volume.GetInodeRec(inodeNumber)
+		if (nil != err) || !ok || (expectedValue != string(value)) {
+			t.Fatalf("GetInodeRec(%v) [case 2] returned unexpected value \"%s\" (ok == %v, err == %v)", inodeNumber, value, ok, err)
+		}
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("headhunter.Down() [case 2] returned error: %v", err)
+	}
+}
+
+// Sparsely used checkpoint objects are rewritten... and subsequently deleted
+func testCheckpointCompaction(t *testing.T, confMap conf.ConfMap) {
+	err := confMap.UpdateFromString("Volume:TestCheckpointVolume.CheckpointIntervalsPerCompaction=0") // compactWhileLocked() invoked explicitly below
+	if nil != err {
+		t.Fatalf("UpdateFromString() returned error: %v", err)
+	}
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("headhunter.Up() [case 3] returned error: %v", err)
+	}
+
+	volumeHandle, err := FetchVolumeHandle("TestCheckpointVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle() [case 3] returned error: %v", err)
+	}
+
+	volume := volumeHandle.(*volumeStruct)
+
+	// Each checkpoint writes its dirtied B+Tree nodes to a new checkpoint object... leaving prior ones partially used
+
+	for inodeNumber := uint64(100); inodeNumber < 300; inodeNumber++ {
+		err = volume.PutInodeRec(inodeNumber, []byte("Initial"))
+		if nil != err {
+			t.Fatalf("PutInodeRec(%v,) returned error: %v", inodeNumber, err)
+		}
+	}
+
+	err = volume.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() returned error: %v", err)
+	}
+
+	for _, inodeNumber := range []uint64{100, 299} {
+		err = volume.PutInodeRec(inodeNumber, []byte("Updated"))
+		if nil != err {
+			t.Fatalf("PutInodeRec(%v,) returned error: %v", inodeNumber, err)
+		}
+
+		err = volume.DoCheckpoint()
+		if nil != err {
+			t.Fatalf("DoCheckpoint() returned error: %v", err)
+		}
+	}
+
+	volume.Lock()
+	expectedCompactedObjectNumbers := make(map[uint64]bool)
+	for objectNumber, bytesUsed := range volume.combinedBPlusTreeLayoutWhileLocked() {
+		if 0 < bytesUsed {
+			expectedCompactedObjectNumbers[objectNumber] = true
+		}
+	}
+	compactedObjectNumbers, err := volume.compactWhileLocked()
+	volume.Unlock()
+
+	if nil != err {
+		t.Fatalf("compactWhileLocked() returned error: %v", err)
+	}
+	if 2 > len(expectedCompactedObjectNumbers) {
+		t.Fatalf("Test should have left at least two checkpoint objects in use (not %v)", len(expectedCompactedObjectNumbers))
+	}
+	if len(expectedCompactedObjectNumbers) != len(compactedObjectNumbers) {
+		t.Fatalf("compactWhileLocked() returned %v... expected %v", compactedObjectNumbers, expectedCompactedObjectNumbers)
+	}
+	for _, objectNumber := range compactedObjectNumbers {
+		if !expectedCompactedObjectNumbers[objectNumber] {
+			t.Fatalf("compactWhileLocked() returned %v... expected %v", compactedObjectNumbers, expectedCompactedObjectNumbers)
+		}
+	}
+
+	// Each compacted object PUT since Up() has had its length recorded... what its deletion will reclaim is reported
+
+	expectedReclaimedBytes := uint64(0)
+	expectedReclaimedObjects := uint64(0)
+
+	volume.Lock()
+	for _, objectNumber := range compactedObjectNumbers {
+		objectLength, ok := volume.checkpointObjectLengthMap[objectNumber]
+		if !ok {
+			continue
+		}
+		contentLength, err := swiftclient.ObjectContentLength(volume.accountName, volume.checkpointContainerName, utils.Uint64ToHexStr(objectNumber))
+		if nil != err {
+			t.Fatalf("ObjectContentLength() returned error: %v", err)
+		}
+		if contentLength != objectLength {
+			t.Fatalf("Checkpoint object 0x%016X length recorded as %v... expected %v", objectNumber, objectLength, contentLength)
+		}
+		expectedReclaimedBytes += objectLength - volume.compactionVictimMap[objectNumber]
+		expectedReclaimedObjects++
+	}
+	volume.Unlock()
+
+	if 0 == expectedReclaimedObjects {
+		t.Fatalf("Test should have compacted at least one checkpoint object PUT since Up()")
+	}
+
+	reclaimedBytesBefore := stats.Dump()[stats.HeadhunterCompactionBytes]
+	reclaimedObjectsBefore := stats.Dump()[stats.HeadhunterCompactionOps]
+
+	// The next checkpoint rewrites every node from the compacted objects... the one following it deletes them
+
+	checkpointDoneWaitGroup := volume.FetchNextCheckPointDoneWaitGroup()
+
+	err = volume.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() [after compaction] returned error: %v", err)
+	}
+
+	volume.Lock()
+	combinedBPlusTreeLayout := volume.combinedBPlusTreeLayoutWhileLocked()
+	volume.Unlock()
+
+	for _, objectNumber := range compactedObjectNumbers {
+		if 0 != combinedBPlusTreeLayout[objectNumber] {
+			t.Fatalf("Compacted checkpoint object 0x%016X still holds %v bytes in use", objectNumber, combinedBPlusTreeLayout[objectNumber])
+		}
+	}
+
+	err = volume.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() [after compaction] returned error: %v", err)
+	}
+
+	if !testWaitGroupDone(checkpointDoneWaitGroup, 5*time.Second) {
+		t.Fatalf("checkpointDoneWaitGroup should be done")
+	}
+
+	for _, objectNumber := range compactedObjectNumbers {
+		for i := 0; ; i++ {
+			_, err = swiftclient.ObjectContentLength(volume.accountName, volume.checkpointContainerName, utils.Uint64ToHexStr(objectNumber))
+			if nil != err {
+				break // deleted
+			}
+			if 100 == i {
+				t.Fatalf("Compacted checkpoint object 0x%016X not deleted", objectNumber)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	for i := 0; ; i++ {
+		statMap := stats.Dump()
+		if (reclaimedBytesBefore+expectedReclaimedBytes == statMap[stats.HeadhunterCompactionBytes]) && (reclaimedObjectsBefore+expectedReclaimedObjects == statMap[stats.HeadhunterCompactionOps]) {
+			break
+		}
+		if 100 == i {
+			t.Fatalf("Compaction reported %v bytes reclaimed from %v objects... expected %v bytes from %v objects",
+				statMap[stats.HeadhunterCompactionBytes]-reclaimedBytesBefore, statMap[stats.HeadhunterCompactionOps]-reclaimedObjectsBefore,
+				expectedReclaimedBytes, expectedReclaimedObjects)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Nothing should have been lost
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("headhunter.Down() [case 3] returned error: %v", err)
+	}
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("headhunter.Up() [case 4] returned error: %v", err)
+	}
+
+	volumeHandle, err = FetchVolumeHandle("TestCheckpointVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle() [case 4] returned error: %v", err)
+	}
+
+	for inodeNumber := uint64(100); inodeNumber < 300; inodeNumber++ {
+		expectedValue := "Initial"
+		if (100 == inodeNumber) || (299 == inodeNumber) {
+			expectedValue = "Updated"
+		}
+		value, ok, err := volumeHandle.GetInodeRec(inodeNumber)
+		if (nil != err) || !ok || (expectedValue != string(value)) {
+			t.Fatalf("GetInodeRec(%v) [case 4] returned unexpected value \"%s\" (ok == %v, err == %v)", inodeNumber, value, ok, err)
+		}
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("headhunter.Down() [case 4] returned error: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package headhunter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/logger"
+)
+
+// compactWhileLocked is invoked following every volume.checkpointIntervalsPerCompaction successful
+// checkpoints. Checkpoint objects whose B+Tree bytes still in use have fallen below
+// volume.compactionThreshold are selected... and every B+Tree node residing in them is marked
+// dirty. The next putCheckpoint() thus rewrites those nodes, leaving the selected objects
+// unreferenced and (like any other unreferenced checkpoint object) deleted.
+//
+// To bound the work done while the volume is locked, the sparsest objects are selected first and
+// selection stops once about a checkpoint object's worth (volume.maxFlushSize) of nodes would be
+// rewritten. Any remaining candidates await a subsequent compaction. Note that compaction is only
+// undertaken if at least two objects would be coalesced.
+func (volume *volumeStruct) compactWhileLocked() (compactedObjectNumbers []uint64, err error) {
+	var (
+		bPlusTreeLayout         sortedmap.LayoutReport
+		bPlusTreeWrapper        *bPlusTreeWrapperStruct
+		bytesUsed               uint64
+		candidateObjectNumbers  []uint64
+		combinedBPlusTreeLayout sortedmap.LayoutReport
+		objectNumber            uint64
+		rewrittenBytes          uint64
+		rootObjectLength        uint64
+		rootObjectNumber        uint64
+		rootObjectOffset        uint64
+		victimMap               map[uint64]uint64 // key == objectNumber; value == bytesUsed (by the B+Tree being compacted)
+	)
+
+	compactedObjectNumbers = make([]uint64, 0)
+
+	if volume.checkpointStatus.Degraded || (nil != volume.checkpointChunkedPutContext) {
+		// Compaction must await a successful checkpoint
+		err = nil
+		return
+	}
+
+	combinedBPlusTreeLayout = volume.combinedBPlusTreeLayoutWhileLocked()
+
+	candidateObjectNumbers = make([]uint64, 0)
+
+	for objectNumber, bytesUsed = range combinedBPlusTreeLayout {
+		if (0 < bytesUsed) && (bytesUsed < volume.compactionThreshold) && !volume.snapshotReferencesObjectWhileLocked(objectNumber) {
+			candidateObjectNumbers = append(candidateObjectNumbers, objectNumber)
+		}
+	}
+
+	if 2 > len(candidateObjectNumbers) {
+		err = nil
+		return
+	}
+
+	sort.Slice(candidateObjectNumbers, func(i int, j int) bool {
+		return combinedBPlusTreeLayout[candidateObjectNumbers[i]] < combinedBPlusTreeLayout[candidateObjectNumbers[j]]
+	})
+
+	for _, objectNumber = range candidateObjectNumbers {
+		bytesUsed = combinedBPlusTreeLayout[objectNumber]
+		if (2 <= len(compactedObjectNumbers)) && ((rewrittenBytes + bytesUsed) > volume.maxFlushSize) {
+			break
+		}
+		compactedObjectNumbers = append(compactedObjectNumbers, objectNumber)
+		rewrittenBytes += bytesUsed
+	}
+
+	// As the just completed checkpoint left each B+Tree clean, the nodes residing in the selected
+	// objects are located by walking the persisted B+Trees (see compactBPlusTreeWhileLocked())
+
+	for _, bPlusTreeWrapper = range []*bPlusTreeWrapperStruct{volume.inodeRecWrapper, volume.logSegmentRecWrapper, volume.bPlusTreeObjectWrapper} {
+		switch bPlusTreeWrapper.wrapperType {
+		case inodeRecBPlusTreeWrapperType:
+			bPlusTreeLayout = volume.inodeRecBPlusTreeLayout
+			rootObjectNumber = volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber
+			rootObjectOffset = volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectOffset
+			rootObjectLength = volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectLength
+		case logSegmentRecBPlusTreeWrapperType:
+			bPlusTreeLayout = volume.logSegmentRecBPlusTreeLayout
+			rootObjectNumber = volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectNumber
+			rootObjectOffset = volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectOffset
+			rootObjectLength = volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectLength
+		case bPlusTreeObjectBPlusTreeWrapperType:
+			bPlusTreeLayout = volume.bPlusTreeObjectBPlusTreeLayout
+			rootObjectNumber = volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectNumber
+			rootObjectOffset = volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectOffset
+			rootObjectLength = volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectLength
+		}
+
+		victimMap = make(map[uint64]uint64)
+
+		for _, objectNumber = range compactedObjectNumbers {
+			bytesUsed = bPlusTreeLayout[objectNumber]
+			if 0 < bytesUsed {
+				victimMap[objectNumber] = bytesUsed
+			}
+		}
+
+		if (0 == len(victimMap)) || (0 == rootObjectNumber) {
+			// No nodes of this B+Tree need be rewritten
+			continue
+		}
+
+		err = volume.compactBPlusTreeWhileLocked(bPlusTreeWrapper, rootObjectNumber, rootObjectOffset, rootObjectLength, victimMap)
+		if nil != err {
+			return
+		}
+	}
+
+	// What each victim's deletion reclaims is reported once it leaves the checkpoint (see reclaimCheckpointObjectWhileLocked())
+
+	for _, objectNumber = range compactedObjectNumbers {
+		volume.compactionVictimMap[objectNumber] = combinedBPlusTreeLayout[objectNumber]
+	}
+
+	logger.Infof("Volume %v compaction rewriting %v bytes from %v checkpoint object(s)", volume.volumeName, rewrittenBytes, len(compactedObjectNumbers))
+
+	err = nil
+	return
+}
+
+// compactBPlusTreeWhileLocked marks dirty each node of the (clean) B+Tree rooted at rootObject{Number|Offset|Length}
+// residing in a victimMap object. Rather than loading the B+Tree, its persisted non-leaf nodes are read and decoded
+// directly... each referencing (along with how many items lie beneath) where its children reside. A child residing
+// in a victim is reached via TouchItem() on its first item. The walk ends once every victim byte has been accounted for.
+func (volume *volumeStruct) compactBPlusTreeWhileLocked(bPlusTreeWrapper *bPlusTreeWrapperStruct, rootObjectNumber uint64, rootObjectOffset uint64, rootObjectLength uint64, victimMap map[uint64]uint64) (err error) {
+	var (
+		children        []onDiskReferenceToNodeStruct
+		leaf            bool
+		leafDepth       uint64
+		nodeByteSlice   []byte
+		numberOfItems   int
+		onDiskReference onDiskReferenceToNodeStruct
+		remainingBytes  uint64
+		victimBytes     uint64
+	)
+
+	for _, victimBytes = range victimMap {
+		remainingBytes += victimBytes
+	}
+
+	touchIfVictim := func(onDiskReference onDiskReferenceToNodeStruct, firstItemIndex uint64) (err error) {
+		_, ok := victimMap[onDiskReference.ObjectNumber]
+		if !ok {
+			err = nil
+			return
+		}
+		if 0 == onDiskReference.Items {
+			err = bPlusTreeWrapper.bPlusTree.Touch() // only an empty root node holds no items
+		} else {
+			_, err = bPlusTreeWrapper.bPlusTree.TouchItem(firstItemIndex)
+		}
+		if nil != err {
+			return
+		}
+		if remainingBytes < onDiskReference.ObjectLength {
+			err = fmt.Errorf("Logic error: volume %v compaction found more bytes in victim objects than its layout reports", volume.volumeName)
+			return
+		}
+		remainingBytes -= onDiskReference.ObjectLength
+		return
+	}
+
+	numberOfItems, err = bPlusTreeWrapper.bPlusTree.Len()
+	if nil != err {
+		return
+	}
+
+	err = touchIfVictim(onDiskReferenceToNodeStruct{ObjectNumber: rootObjectNumber, ObjectOffset: rootObjectOffset, ObjectLength: rootObjectLength, Items: uint64(numberOfItems)}, 0)
+	if nil != err {
+		return
+	}
+
+	// All leaves reside at the same depth... found by following the leftmost children
+
+	onDiskReference = onDiskReferenceToNodeStruct{ObjectNumber: rootObjectNumber, ObjectOffset: rootObjectOffset, ObjectLength: rootObjectLength}
+	leafDepth = 0
+
+	for 0 < remainingBytes {
+		nodeByteSlice, err = bPlusTreeWrapper.GetNode(onDiskReference.ObjectNumber, onDiskReference.ObjectOffset, onDiskReference.ObjectLength)
+		if nil != err {
+			return
+		}
+		_, leaf, _, children, err = bPlusTreeWrapper.unpackNode(nodeByteSlice)
+		if nil != err {
+			return
+		}
+		if leaf || (0 == len(children)) {
+			break
+		}
+		onDiskReference = children[0]
+		leafDepth++
+	}
+
+	// Walk the non-leaf nodes (their children's references tell where leaves reside without reading them)
+
+	var walk func(onDiskReference onDiskReferenceToNodeStruct, depth uint64, firstItemIndex uint64) (err error)
+
+	walk = func(onDiskReference onDiskReferenceToNodeStruct, depth uint64, firstItemIndex uint64) (err error) {
+		nodeByteSlice, err := bPlusTreeWrapper.GetNode(onDiskReference.ObjectNumber, onDiskReference.ObjectOffset, onDiskReference.ObjectLength)
+		if nil != err {
+			return
+		}
+		_, _, _, children, err := bPlusTreeWrapper.unpackNode(nodeByteSlice)
+		if nil != err {
+			return
+		}
+
+		for _, child := range children {
+			if 0 == remainingBytes {
+				return
+			}
+			err = touchIfVictim(child, firstItemIndex)
+			if nil != err {
+				return
+			}
+			if (depth + 1) < leafDepth {
+				err = walk(child, depth+1, firstItemIndex)
+				if nil != err {
+					return
+				}
+			}
+			firstItemIndex += child.Items
+		}
+
+		err = nil
+		return
+	}
+
+	if (0 < remainingBytes) && (0 < leafDepth) {
+		err = walk(onDiskReferenceToNodeStruct{ObjectNumber: rootObjectNumber, ObjectOffset: rootObjectOffset, ObjectLength: rootObjectLength}, 0, 0)
+		if nil != err {
+			return
+		}
+	}
+
+	if 0 != remainingBytes {
+		err = fmt.Errorf("Logic error: volume %v compaction unable to locate %v bytes in victim objects", volume.volumeName, remainingBytes)
+		return
+	}
+
+	err = nil
+	return
+}
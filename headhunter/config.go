@@ -11,7 +11,7 @@ import (
 	"github.com/swiftstack/sortedmap"
 
 	"github.com/swiftstack/ProxyFS/conf"
-	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/objectstore"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -36,6 +36,7 @@ const (
 type bPlusTreeWrapperStruct struct {
 	volume      *volumeStruct
 	wrapperType uint32 // Either inodeRecBPlusTreeWrapperType, logSegmentRecBPlusTreeWrapperType, or bPlusTreeObjectBPlusTreeWrapperType
+	readOnly    bool   // if true, bPlusTree is that of a snapshot and may not be modified
 	bPlusTree   sortedmap.BPlusTree
 }
 
@@ -43,6 +44,7 @@ type volumeStruct struct {
 	sync.Mutex
 	volumeName                       string
 	accountName                      string
+	objectStore                      objectstore.ObjectStore
 	maxFlushSize                     uint64
 	nonceValuesToReserve             uint16
 	maxInodesPerMetadataNode         uint64
@@ -51,32 +53,52 @@ type volumeStruct struct {
 	checkpointContainerName          string
 	checkpointContainerStoragePolicy string
 	checkpointInterval               time.Duration
-	replayLogFileName                string   // if != "", use replay log to reduce RPO to zero
-	replayLogFile                    *os.File //   opened on first Put or Delete after checkpoint
-	//                                             closed/deleted on successful checkpoint
-	defaultReplayLogWriteBuffer    []byte //       used for O_DIRECT writes to replay log
+	checkpointRetryDelay             time.Duration // initial delay before retrying a failed checkpoint (doubling thereafter)
+	checkpointIntervalsPerCompaction uint64        // if == 0, compaction is disabled
+	compactionThreshold              uint64        // checkpoint objects with fewer bytes in use are compacted
+	replayLogFileName                string        // if != "", use replay log to reduce RPO to zero
+	replayLogFile                    *os.File      //   opened on first Put or Delete after checkpoint
+	//                                                  closed/deleted on successful checkpoint
+	defaultReplayLogWriteBuffer    []byte //            used for O_DIRECT writes to replay log
 	checkpointFlushedData          bool
-	checkpointChunkedPutContext    swiftclient.ChunkedPutContext
+	checkpointChunkedPutContext    objectstore.ChunkedPutContext
 	checkpointDoneWaitGroup        *sync.WaitGroup
 	nextNonce                      uint64
 	checkpointRequestChan          chan *checkpointRequestStruct
 	checkpointHeaderVersion        uint64
-	checkpointHeader               *checkpointHeaderV2Struct
+	checkpointHeader               *checkpointHeaderV5Struct
 	checkpointObjectTrailer        *checkpointObjectTrailerV2Struct
+	volumeStats                    VolumeStats
+	userStats                      map[uint32]OwnerStats // key == userID
+	groupStats                     map[uint32]OwnerStats // key == groupID
 	inodeRecWrapper                *bPlusTreeWrapperStruct
 	logSegmentRecWrapper           *bPlusTreeWrapperStruct
 	bPlusTreeObjectWrapper         *bPlusTreeWrapperStruct
 	inodeRecBPlusTreeLayout        sortedmap.LayoutReport
 	logSegmentRecBPlusTreeLayout   sortedmap.LayoutReport
 	bPlusTreeObjectBPlusTreeLayout sortedmap.LayoutReport
+	snapshotList                   []*snapshotStruct // ordered by snapshotStruct.creationTime
+	snapshotListChanged            bool              // if true, next putCheckpoint() must persist snapshotList
+	checkpointStatus               CheckpointStatus
+	checkpointTouchPending         bool                                     // if true, B+Tree nodes must be re-marked dirty before the next putCheckpoint()
+	checkpointNodeLocatorMap       map[uint64][]nodeLocatorStruct           // key == objectNumber whose PUT has yet to (successfully) complete
+	failedCheckpointObjectMap      map[uint64]objectstore.ChunkedPutContext // key == objectNumber whose PUT failed since last good checkpoint
+	checkpointsSinceCompaction     uint64
+	checkpointObjectLengthMap      map[uint64]uint64 // key == objectNumber of a checkpoint object PUT since Up(); value == its length
+	compactionVictimMap            map[uint64]uint64 // key == objectNumber selected by compactWhileLocked(); value == bytes in use (to be rewritten elsewhere)
+	volumeStatsRebuildPending      bool // if true, VolumeStats byte totals predate VolumeStats and await RebuildVolumeStats()
+	ownerStatsRebuildPending       bool // if true, OwnerStats predate OwnerStats and await RebuildOwnerStats()
+	statsRebuilt                   bool // if true, next putCheckpoint() must persist rebuilt VolumeStats and/or OwnerStats
+	fenced                         bool // if true, another Peer has claimed the Volume... so it must never again be checkpointed
 }
 
 type globalsStruct struct {
 	crc64ECMATable                          *crc64.Table
 	uint64Size                              uint64
-	checkpointHeaderV2StructSize            uint64
+	checkpointHeaderV5StructSize            uint64
 	checkpointObjectTrailerStructSize       uint64
 	elementOfBPlusTreeLayoutStructSize      uint64
+	elementOfOwnerStatsStructSize           uint64
 	replayLogTransactionFixedPartStructSize uint64
 	inodeRecCache                           sortedmap.BPlusTreeCache
 	logSegmentRecCache                      sortedmap.BPlusTreeCache
@@ -89,48 +111,19 @@ var globals globalsStruct
 // Up starts the headhunter package
 func Up(confMap conf.ConfMap) (err error) {
 	var (
-		bPlusTreeObjectCacheEvictHighLimit       uint64
-		bPlusTreeObjectCacheEvictLowLimit        uint64
-		dummyCheckpointHeaderV2Struct            checkpointHeaderV2Struct
-		dummyCheckpointObjectTrailerV2Struct     checkpointObjectTrailerV2Struct
-		dummyElementOfBPlusTreeLayoutStruct      elementOfBPlusTreeLayoutStruct
-		dummyReplayLogTransactionFixedPartStruct replayLogTransactionFixedPartStruct
-		dummyUint64                              uint64
-		inodeRecCacheEvictHighLimit              uint64
-		inodeRecCacheEvictLowLimit               uint64
-		logSegmentRecCacheEvictHighLimit         uint64
-		logSegmentRecCacheEvictLowLimit          uint64
-		primaryPeerList                          []string
-		volumeName                               string
-		volumeList                               []string
-		whoAmI                                   string
+		bPlusTreeObjectCacheEvictHighLimit uint64
+		bPlusTreeObjectCacheEvictLowLimit  uint64
+		inodeRecCacheEvictHighLimit        uint64
+		inodeRecCacheEvictLowLimit         uint64
+		logSegmentRecCacheEvictHighLimit   uint64
+		logSegmentRecCacheEvictLowLimit    uint64
+		primaryPeerList                    []string
+		volumeName                         string
+		volumeList                         []string
+		whoAmI                             string
 	)
 
-	// Pre-compute crc64 ECMA Table & useful cstruct sizes
-
-	globals.crc64ECMATable = crc64.MakeTable(crc64.ECMA)
-
-	globals.uint64Size, _, err = cstruct.Examine(dummyUint64)
-	if nil != err {
-		return
-	}
-
-	globals.checkpointHeaderV2StructSize, _, err = cstruct.Examine(dummyCheckpointHeaderV2Struct)
-	if nil != err {
-		return
-	}
-
-	globals.checkpointObjectTrailerStructSize, _, err = cstruct.Examine(dummyCheckpointObjectTrailerV2Struct)
-	if nil != err {
-		return
-	}
-
-	globals.elementOfBPlusTreeLayoutStructSize, _, err = cstruct.Examine(dummyElementOfBPlusTreeLayoutStruct)
-	if nil != err {
-		return
-	}
-
-	globals.replayLogTransactionFixedPartStructSize, _, err = cstruct.Examine(dummyReplayLogTransactionFixedPartStruct)
+	err = computeGlobals()
 	if nil != err {
 		return
 	}
@@ -340,18 +333,56 @@ func Down() (err error) {
 	return
 }
 
+// UpOneVolume starts the headhunter package for just the named volume regardless of its PrimaryPeer.
+// It is intended for offline tools (e.g. mkproxyfs) that must not run while the volume is being served.
+// Down should be called once the tool is done with the volume.
+func UpOneVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	err = computeGlobals()
+	if nil != err {
+		return
+	}
+
+	globals.volumeMap = make(map[string]*volumeStruct)
+
+	err = upVolume(confMap, volumeName, false)
+
+	return // err set as appropriate
+}
+
 // Format runs an instance of the headhunter package for formatting a new volume
 func Format(confMap conf.ConfMap, volumeName string) (err error) {
+	err = computeGlobals()
+	if nil != err {
+		return
+	}
+
+	// Init volume database...triggering format
+
+	globals.volumeMap = make(map[string]*volumeStruct)
+
+	err = upVolume(confMap, volumeName, true)
+	if nil != err {
+		return
+	}
+
+	// Shutdown and exit
+
+	err = downVolume(volumeName)
+
+	return
+}
+
+// computeGlobals pre-computes the crc64 ECMA Table & useful cstruct sizes
+func computeGlobals() (err error) {
 	var (
-		dummyCheckpointHeaderV2Struct            checkpointHeaderV2Struct
+		dummyCheckpointHeaderV5Struct            checkpointHeaderV5Struct
 		dummyCheckpointObjectTrailerV2Struct     checkpointObjectTrailerV2Struct
 		dummyElementOfBPlusTreeLayoutStruct      elementOfBPlusTreeLayoutStruct
+		dummyElementOfOwnerStatsStruct           elementOfOwnerStatsStruct
 		dummyReplayLogTransactionFixedPartStruct replayLogTransactionFixedPartStruct
 		dummyUint64                              uint64
 	)
 
-	// Pre-compute crc64 ECMA Table & useful cstruct sizes
-
 	globals.crc64ECMATable = crc64.MakeTable(crc64.ECMA)
 
 	globals.uint64Size, _, err = cstruct.Examine(dummyUint64)
@@ -359,7 +390,7 @@ func Format(confMap conf.ConfMap, volumeName string) (err error) {
 		return
 	}
 
-	globals.checkpointHeaderV2StructSize, _, err = cstruct.Examine(dummyCheckpointHeaderV2Struct)
+	globals.checkpointHeaderV5StructSize, _, err = cstruct.Examine(dummyCheckpointHeaderV5Struct)
 	if nil != err {
 		return
 	}
@@ -374,33 +405,27 @@ func Format(confMap conf.ConfMap, volumeName string) (err error) {
 		return
 	}
 
-	globals.replayLogTransactionFixedPartStructSize, _, err = cstruct.Examine(dummyReplayLogTransactionFixedPartStruct)
+	globals.elementOfOwnerStatsStructSize, _, err = cstruct.Examine(dummyElementOfOwnerStatsStruct)
 	if nil != err {
 		return
 	}
 
-	// Init volume database...triggering format
-
-	globals.volumeMap = make(map[string]*volumeStruct)
-
-	err = upVolume(confMap, volumeName, true)
+	globals.replayLogTransactionFixedPartStructSize, _, err = cstruct.Examine(dummyReplayLogTransactionFixedPartStruct)
 	if nil != err {
 		return
 	}
 
-	// Shutdown and exit
-
-	err = downVolume(volumeName)
-
+	err = nil
 	return
 }
 
 func upVolume(confMap conf.ConfMap, volumeName string, autoFormat bool) (err error) {
 	var (
-		flowControlName        string
-		flowControlSectionName string
-		volume                 *volumeStruct
-		volumeSectionName      string
+		compactionThresholdPercent uint64
+		flowControlName            string
+		flowControlSectionName     string
+		volume                     *volumeStruct
+		volumeSectionName          string
 	)
 
 	volumeSectionName = utils.VolumeNameConfSection(volumeName)
@@ -410,6 +435,10 @@ func upVolume(confMap conf.ConfMap, volumeName string, autoFormat bool) (err err
 		checkpointChunkedPutContext: nil,
 		checkpointDoneWaitGroup:     nil,
 		checkpointRequestChan:       make(chan *checkpointRequestStruct, 1),
+		checkpointNodeLocatorMap:    make(map[uint64][]nodeLocatorStruct),
+		failedCheckpointObjectMap:   make(map[uint64]objectstore.ChunkedPutContext),
+		checkpointObjectLengthMap:   make(map[uint64]uint64),
+		compactionVictimMap:         make(map[uint64]uint64),
 	}
 
 	volume.accountName, err = confMap.FetchOptionValueString(volumeSectionName, "AccountName")
@@ -417,6 +446,11 @@ func upVolume(confMap conf.ConfMap, volumeName string, autoFormat bool) (err err
 		return
 	}
 
+	volume.objectStore, err = objectstore.FetchObjectStore(confMap, volumeName)
+	if nil != err {
+		return
+	}
+
 	flowControlName, err = confMap.FetchOptionValueString(volumeSectionName, "FlowControl")
 	if nil != err {
 		return
@@ -463,6 +497,26 @@ func upVolume(confMap conf.ConfMap, volumeName string, autoFormat bool) (err err
 		return
 	}
 
+	volume.checkpointRetryDelay, err = confMap.FetchOptionValueDuration(volumeSectionName, "CheckpointRetryDelay")
+	if nil != err {
+		volume.checkpointRetryDelay = time.Second // TODO: eventually, just return
+	}
+
+	volume.checkpointIntervalsPerCompaction, err = confMap.FetchOptionValueUint64(volumeSectionName, "CheckpointIntervalsPerCompaction")
+	if nil != err {
+		volume.checkpointIntervalsPerCompaction = 100 // TODO: eventually, just return
+	}
+
+	compactionThresholdPercent, err = confMap.FetchOptionValueUint64(volumeSectionName, "CompactionThreshold")
+	if nil != err {
+		compactionThresholdPercent = 50 // TODO: eventually, just return
+	}
+	if 100 < compactionThresholdPercent {
+		err = fmt.Errorf("%v.CompactionThreshold (%v) must be a percentage (i.e. no more than 100)", volumeSectionName, compactionThresholdPercent)
+		return
+	}
+	volume.compactionThreshold = (volume.maxFlushSize * compactionThresholdPercent) / 100
+
 	volume.replayLogFileName, err = confMap.FetchOptionValueString(volumeSectionName, "ReplayLogFileName")
 	if nil == err {
 		// Provision aligned buffer used to write to Replay Log
@@ -506,6 +560,10 @@ func downVolume(volumeName string) (err error) {
 	checkpointRequest.waitGroup.Wait()
 
 	err = checkpointRequest.err
+	if (nil != err) && volume.fenced {
+		// Another Peer now serves the Volume... so there was nothing we were permitted to persist
+		err = nil
+	}
 
 	return
 }
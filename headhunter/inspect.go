@@ -0,0 +1,158 @@
+package headhunter
+
+// Offline inspection
+//
+// OpenVolumeReadOnly() reads a volume's checkpoint straight from its ObjectStore without the volume being Up()'d.
+// Neither the checkpointDaemon nor the Replay Log is involved... so nothing is ever written. The resulting
+// VolumeHandle is read-only (just as that of a snapshot) and presents the volume as of its last checkpoint.
+
+import (
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/objectstore"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// BPlusTreeRootReport locates the root node of one of a checkpoint's B+Trees (ObjectNumber == 0 if empty).
+type BPlusTreeRootReport struct {
+	ObjectNumber uint64
+	ObjectOffset uint64
+	ObjectLength uint64
+}
+
+// CheckpointReport describes a volume's checkpoint as decoded by OpenVolumeReadOnly(). It is composed of
+// the checkpoint header (the CheckpointHeaderName value of the checkpoint container) as well as the
+// checkpoint record found at the tail of the object it references.
+type CheckpointReport struct {
+	HeaderVersion                  uint64
+	CheckpointObjectNumber         uint64 // object holding the checkpoint record (0 if the volume has never been checkpointed)
+	CheckpointObjectLength         uint64 // length of that checkpoint record
+	ReservedToNonce                uint64
+	VolumeStats                    VolumeStats
+	InodeRecBPlusTreeRoot          BPlusTreeRootReport
+	LogSegmentRecBPlusTreeRoot     BPlusTreeRootReport
+	BPlusTreeObjectBPlusTreeRoot   BPlusTreeRootReport
+	InodeRecBPlusTreeLayout        map[uint64]uint64     // key == objectNumber; value == bytes in use
+	LogSegmentRecBPlusTreeLayout   map[uint64]uint64     // key == objectNumber; value == bytes in use
+	BPlusTreeObjectBPlusTreeLayout map[uint64]uint64     // key == objectNumber; value == bytes in use
+	UserStats                      map[uint32]OwnerStats // key == userID
+	GroupStats                     map[uint32]OwnerStats // key == groupID
+	SnapshotList                   []SnapshotInfo
+}
+
+// OpenVolumeReadOnly decodes the named volume's checkpoint returning both a report of its contents and a
+// read-only VolumeHandle with which to walk its B+Trees. Any Replay Log is ignored. It is intended for
+// offline tools (e.g. pfsinspect) and, as nothing is modified, may be used even while the volume is served
+// (though, of course, only what the serving peer has checkpointed will be seen). Only the logger, stats,
+// and (for a Swift-resident volume) swiftclient packages need be Up()'d beforehand... and no Down() is required afterwards.
+func OpenVolumeReadOnly(confMap conf.ConfMap, volumeName string) (volumeHandle VolumeHandle, checkpointReport *CheckpointReport, err error) {
+	var (
+		snapshot          *snapshotStruct
+		volume            *volumeStruct
+		volumeSectionName string
+	)
+
+	if nil == globals.crc64ECMATable {
+		// Neither Up(), UpOneVolume(), nor Format() has been called
+
+		err = computeGlobals()
+		if nil != err {
+			return
+		}
+	}
+
+	volumeSectionName = utils.VolumeNameConfSection(volumeName)
+
+	volume = &volumeStruct{
+		volumeName:        volumeName,
+		replayLogFileName: "", // Replay Log is disabled
+	}
+
+	volume.accountName, err = confMap.FetchOptionValueString(volumeSectionName, "AccountName")
+	if nil != err {
+		return
+	}
+
+	volume.objectStore, err = objectstore.FetchObjectStore(confMap, volumeName)
+	if nil != err {
+		return
+	}
+
+	volume.maxInodesPerMetadataNode, err = confMap.FetchOptionValueUint64(volumeSectionName, "MaxInodesPerMetadataNode")
+	if nil != err {
+		return
+	}
+
+	volume.maxLogSegmentsPerMetadataNode, err = confMap.FetchOptionValueUint64(volumeSectionName, "MaxLogSegmentsPerMetadataNode")
+	if nil != err {
+		return
+	}
+
+	volume.maxDirFileNodesPerMetadataNode, err = confMap.FetchOptionValueUint64(volumeSectionName, "MaxDirFileNodesPerMetadataNode")
+	if nil != err {
+		return
+	}
+
+	volume.checkpointContainerName, err = confMap.FetchOptionValueString(volumeSectionName, "CheckpointContainerName")
+	if nil != err {
+		return
+	}
+
+	err = volume.getCheckpoint(false)
+	if nil != err {
+		err = fmt.Errorf("Unable to read checkpoint of volume %v: %v", volumeName, err)
+		return
+	}
+
+	volume.inodeRecWrapper.readOnly = true
+	volume.logSegmentRecWrapper.readOnly = true
+	volume.bPlusTreeObjectWrapper.readOnly = true
+
+	checkpointReport = &CheckpointReport{
+		HeaderVersion:          volume.checkpointHeaderVersion,
+		CheckpointObjectNumber: volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
+		CheckpointObjectLength: volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
+		ReservedToNonce:        volume.checkpointHeader.ReservedToNonce,
+		VolumeStats:            volume.volumeStats,
+		InodeRecBPlusTreeRoot: BPlusTreeRootReport{
+			ObjectNumber: volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber,
+			ObjectOffset: volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectOffset,
+			ObjectLength: volume.checkpointObjectTrailer.InodeRecBPlusTreeObjectLength,
+		},
+		LogSegmentRecBPlusTreeRoot: BPlusTreeRootReport{
+			ObjectNumber: volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectNumber,
+			ObjectOffset: volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectOffset,
+			ObjectLength: volume.checkpointObjectTrailer.LogSegmentRecBPlusTreeObjectLength,
+		},
+		BPlusTreeObjectBPlusTreeRoot: BPlusTreeRootReport{
+			ObjectNumber: volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectNumber,
+			ObjectOffset: volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectOffset,
+			ObjectLength: volume.checkpointObjectTrailer.BPlusTreeObjectBPlusTreeObjectLength,
+		},
+		InodeRecBPlusTreeLayout:        volume.inodeRecBPlusTreeLayout,
+		LogSegmentRecBPlusTreeLayout:   volume.logSegmentRecBPlusTreeLayout,
+		BPlusTreeObjectBPlusTreeLayout: volume.bPlusTreeObjectBPlusTreeLayout,
+		UserStats:                      volume.userStats,
+		GroupStats:                     volume.groupStats,
+		SnapshotList:                   volume.FetchSnapshotList(),
+	}
+
+	// Present the checkpoint just as if it were a snapshot (though one not in volume.snapshotList)
+
+	snapshot = &snapshotStruct{
+		checkpointObjectNumber: volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
+		checkpointObjectLength: volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
+		volumeStats:            volume.volumeStats,
+		userStats:              volume.userStats,
+		groupStats:             volume.groupStats,
+		inodeRecWrapper:        volume.inodeRecWrapper,
+		logSegmentRecWrapper:   volume.logSegmentRecWrapper,
+		bPlusTreeObjectWrapper: volume.bPlusTreeObjectWrapper,
+	}
+
+	volumeHandle = &snapshotVolumeStruct{volume: volume, snapshot: snapshot}
+
+	err = nil
+	return
+}
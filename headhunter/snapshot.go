@@ -0,0 +1,757 @@
+package headhunter
+
+// Snapshots
+//
+// A snapshot preserves a checkpoint of a volume under a name. Every object in the checkpoint container
+// holding a node of any of the checkpoint's three B+Trees (as well as the object holding its checkpoint
+// record) is retained until the snapshot is deleted. As such, putCheckpoint() will not delete any object
+// a snapshot references even as the live B+Trees cease to reference it.
+//
+// Note that the B+Trees of files and directories (those whose nodes are stored via PutBPlusTreeObject())
+// are preserved simply because their nodes are values of the snapshot's bPlusTreeObject B+Tree. Thus, a
+// DeleteBPlusTreeObject() affects only the live volume. Log segments, on the other hand, are objects in
+// other containers. Callers removing a LogSegmentRec must consult SnapshotReferencesLogSegment() before
+// deleting the log segment itself... and must delete those returned by DeleteSnapshot().
+//
+// The list of snapshots is recorded at the end of each checkpoint record (following the OwnerStats table).
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/cstruct"
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+type snapshotStruct struct {
+	id                     uint64
+	name                   string
+	creationTime           time.Time
+	nextNonce              uint64              //              log segments numbered at or beyond this postdate the snapshot
+	checkpointObjectNumber uint64              //              object holding the snapshot's checkpoint record (0 if empty)
+	checkpointObjectLength uint64              //              length of that checkpoint record
+	objectNumbers          map[uint64]struct{} // objects in the checkpoint container referenced by this snapshot
+	volumeStats            VolumeStats
+	userStats              map[uint32]OwnerStats // key == userID
+	groupStats             map[uint32]OwnerStats // key == groupID
+	inodeRecWrapper        *bPlusTreeWrapperStruct
+	logSegmentRecWrapper   *bPlusTreeWrapperStruct
+	bPlusTreeObjectWrapper *bPlusTreeWrapperStruct
+}
+
+// snapshotVolumeStruct provides the read-only VolumeHandle returned by FetchSnapshotVolumeHandle().
+type snapshotVolumeStruct struct {
+	volume   *volumeStruct
+	snapshot *snapshotStruct
+}
+
+func validateSnapshotName(snapshotName string) (err error) {
+	if "" == snapshotName {
+		err = fmt.Errorf("Snapshot name must not be empty")
+		err = blunder.AddError(err, blunder.InvalidArgError)
+		return
+	}
+	if strings.ContainsAny(snapshotName, SnapshotNameForbiddenChars) {
+		err = fmt.Errorf("Snapshot name \"%v\" must not contain any of \"%v\"", snapshotName, SnapshotNameForbiddenChars)
+		err = blunder.AddError(err, blunder.InvalidArgError)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) findSnapshotWhileLocked(snapshotName string) (snapshotIndex int, snapshot *snapshotStruct, ok bool) {
+	for snapshotIndex, snapshot = range volume.snapshotList {
+		if snapshotName == snapshot.name {
+			ok = true
+			return
+		}
+	}
+
+	ok = false
+	return
+}
+
+func (volume *volumeStruct) snapshotReferencesObjectWhileLocked(objectNumber uint64) (referenced bool) {
+	var (
+		snapshot *snapshotStruct
+	)
+
+	for _, snapshot = range volume.snapshotList {
+		_, referenced = snapshot.objectNumbers[objectNumber]
+		if referenced {
+			return
+		}
+	}
+
+	referenced = false
+	return
+}
+
+func (volume *volumeStruct) snapshotReferencesLogSegmentWhileLocked(logSegmentNumber uint64) (referenced bool, err error) {
+	var (
+		snapshot *snapshotStruct
+	)
+
+	for _, snapshot = range volume.snapshotList {
+		if logSegmentNumber >= snapshot.nextNonce {
+			// Log segment was created after this snapshot was taken
+			continue
+		}
+
+		_, referenced, err = snapshot.logSegmentRecWrapper.bPlusTree.GetByKey(logSegmentNumber)
+		if nil != err {
+			return
+		}
+		if referenced {
+			return
+		}
+	}
+
+	referenced = false
+	err = nil
+	return
+}
+
+// openSnapshotWhileLocked fills in the remainder of snapshot from its checkpoint record.
+func (volume *volumeStruct) openSnapshotWhileLocked(snapshot *snapshotStruct, ownerStatsNumElements uint64) (err error) {
+	var (
+		bytesUsed          uint64
+		checkpointContents *checkpointContentsStruct
+		layout             sortedmap.LayoutReport
+		layouts            []sortedmap.LayoutReport
+		objectNumber       uint64
+	)
+
+	checkpointContents, _, err = volume.fetchCheckpointContents(snapshot.checkpointObjectNumber, snapshot.checkpointObjectLength, ownerStatsNumElements)
+	if nil != err {
+		return
+	}
+
+	snapshot.objectNumbers = make(map[uint64]struct{})
+
+	if 0 != snapshot.checkpointObjectNumber {
+		snapshot.objectNumbers[snapshot.checkpointObjectNumber] = struct{}{}
+	}
+
+	layouts = []sortedmap.LayoutReport{
+		checkpointContents.inodeRecBPlusTreeLayout,
+		checkpointContents.logSegmentRecBPlusTreeLayout,
+		checkpointContents.bPlusTreeObjectBPlusTreeLayout,
+	}
+
+	for _, layout = range layouts {
+		for objectNumber, bytesUsed = range layout {
+			if 0 < bytesUsed {
+				snapshot.objectNumbers[objectNumber] = struct{}{}
+			}
+		}
+	}
+
+	snapshot.userStats = checkpointContents.userStats
+	snapshot.groupStats = checkpointContents.groupStats
+
+	snapshot.inodeRecWrapper = &bPlusTreeWrapperStruct{volume: volume, wrapperType: inodeRecBPlusTreeWrapperType, readOnly: true}
+	snapshot.logSegmentRecWrapper = &bPlusTreeWrapperStruct{volume: volume, wrapperType: logSegmentRecBPlusTreeWrapperType, readOnly: true}
+	snapshot.bPlusTreeObjectWrapper = &bPlusTreeWrapperStruct{volume: volume, wrapperType: bPlusTreeObjectBPlusTreeWrapperType, readOnly: true}
+
+	err = volume.loadBPlusTrees(checkpointContents.checkpointObjectTrailer, snapshot.inodeRecWrapper, snapshot.logSegmentRecWrapper, snapshot.bPlusTreeObjectWrapper)
+
+	return // err set as appropriate
+}
+
+// recordSnapshotWhileLocked takes a snapshot of the just persisted checkpoint. It must be called immediately
+// following a successful putCheckpoint() without dropping volume's lock in between.
+func (volume *volumeStruct) recordSnapshotWhileLocked(snapshotName string) (snapshot *snapshotStruct, err error) {
+	var (
+		alreadyPresent bool
+	)
+
+	err = validateSnapshotName(snapshotName)
+	if nil != err {
+		return
+	}
+
+	_, _, alreadyPresent = volume.findSnapshotWhileLocked(snapshotName)
+	if alreadyPresent {
+		err = fmt.Errorf("Snapshot \"%v\" of volume \"%v\" already exists", snapshotName, volume.volumeName)
+		err = blunder.AddError(err, blunder.FileExistsError)
+		return
+	}
+
+	snapshot = &snapshotStruct{
+		name:                   snapshotName,
+		creationTime:           time.Now(),
+		checkpointObjectNumber: volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber,
+		checkpointObjectLength: volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectLength,
+		volumeStats: VolumeStats{
+			InodeCount:      volume.checkpointHeader.InodeCount,
+			LogSegmentBytes: volume.checkpointHeader.LogSegmentBytes,
+			ReferencedBytes: volume.checkpointHeader.ReferencedBytes,
+		},
+	}
+
+	snapshot.id, err = volume.fetchNonceWhileLocked()
+	if nil != err {
+		return
+	}
+
+	snapshot.nextNonce = volume.nextNonce
+
+	err = volume.openSnapshotWhileLocked(snapshot, volume.checkpointHeader.OwnerStatsNumElements)
+	if nil != err {
+		return
+	}
+
+	volume.snapshotList = append(volume.snapshotList, snapshot)
+	volume.snapshotListChanged = true
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) packSnapshotListWhileLocked() (snapshotListBuf []byte, err error) {
+	var (
+		elementOfSnapshotList    elementOfSnapshotListStruct
+		elementOfSnapshotListBuf []byte
+		snapshot                 *snapshotStruct
+	)
+
+	snapshotListBuf = make([]byte, 0)
+
+	for _, snapshot = range volume.snapshotList {
+		elementOfSnapshotList = elementOfSnapshotListStruct{
+			ID:           snapshot.id,
+			CreationTime: uint64(snapshot.creationTime.UnixNano()),
+			NextNonce:    snapshot.nextNonce,
+			CheckpointObjectTrailerV2StructObjectNumber: snapshot.checkpointObjectNumber,
+			CheckpointObjectTrailerV2StructObjectLength: snapshot.checkpointObjectLength,
+			InodeCount:            snapshot.volumeStats.InodeCount,
+			LogSegmentBytes:       snapshot.volumeStats.LogSegmentBytes,
+			ReferencedBytes:       snapshot.volumeStats.ReferencedBytes,
+			OwnerStatsNumElements: uint64(len(snapshot.userStats) + len(snapshot.groupStats)),
+			NameLength:            uint64(len(snapshot.name)),
+		}
+
+		elementOfSnapshotListBuf, err = cstruct.Pack(&elementOfSnapshotList, LittleEndian)
+		if nil != err {
+			return
+		}
+
+		snapshotListBuf = append(snapshotListBuf, elementOfSnapshotListBuf...)
+		snapshotListBuf = append(snapshotListBuf, utils.StringToByteSlice(snapshot.name)...)
+	}
+
+	err = nil
+	return
+}
+
+// unpackSnapshotList is called by getCheckpoint() to load the snapshots recorded in the checkpoint.
+func (volume *volumeStruct) unpackSnapshotList(snapshotListBuf []byte, snapshotListNumElements uint64) (err error) {
+	var (
+		bytesConsumed         uint64
+		elementOfSnapshotList elementOfSnapshotListStruct
+		snapshot              *snapshotStruct
+		snapshotListIndex     uint64
+	)
+
+	volume.snapshotList = make([]*snapshotStruct, 0, snapshotListNumElements)
+	volume.snapshotListChanged = false
+
+	for snapshotListIndex = 0; snapshotListIndex < snapshotListNumElements; snapshotListIndex++ {
+		bytesConsumed, err = cstruct.Unpack(snapshotListBuf, &elementOfSnapshotList, LittleEndian)
+		if nil != err {
+			return
+		}
+		snapshotListBuf = snapshotListBuf[bytesConsumed:]
+
+		if uint64(len(snapshotListBuf)) < elementOfSnapshotList.NameLength {
+			err = fmt.Errorf("Snapshot list for volume %v truncated", volume.volumeName)
+			return
+		}
+
+		snapshot = &snapshotStruct{
+			id:                     elementOfSnapshotList.ID,
+			name:                   utils.ByteSliceToString(snapshotListBuf[:elementOfSnapshotList.NameLength]),
+			creationTime:           time.Unix(0, int64(elementOfSnapshotList.CreationTime)),
+			nextNonce:              elementOfSnapshotList.NextNonce,
+			checkpointObjectNumber: elementOfSnapshotList.CheckpointObjectTrailerV2StructObjectNumber,
+			checkpointObjectLength: elementOfSnapshotList.CheckpointObjectTrailerV2StructObjectLength,
+			volumeStats: VolumeStats{
+				InodeCount:      elementOfSnapshotList.InodeCount,
+				LogSegmentBytes: elementOfSnapshotList.LogSegmentBytes,
+				ReferencedBytes: elementOfSnapshotList.ReferencedBytes,
+			},
+		}
+
+		snapshotListBuf = snapshotListBuf[elementOfSnapshotList.NameLength:]
+
+		err = volume.openSnapshotWhileLocked(snapshot, elementOfSnapshotList.OwnerStatsNumElements)
+		if nil != err {
+			return
+		}
+
+		volume.snapshotList = append(volume.snapshotList, snapshot)
+	}
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) CreateSnapshot(snapshotName string) (snapshotID uint64, err error) {
+	var (
+		alreadyPresent    bool
+		checkpointRequest checkpointRequestStruct
+	)
+
+	err = validateSnapshotName(snapshotName)
+	if nil != err {
+		return
+	}
+
+	volume.Lock()
+	_, _, alreadyPresent = volume.findSnapshotWhileLocked(snapshotName)
+	volume.Unlock()
+
+	if alreadyPresent {
+		err = fmt.Errorf("Snapshot \"%v\" of volume \"%v\" already exists", snapshotName, volume.volumeName)
+		err = blunder.AddError(err, blunder.FileExistsError)
+		return
+	}
+
+	checkpointRequest.exitOnCompletion = false
+	checkpointRequest.snapshotName = snapshotName
+
+	checkpointRequest.waitGroup.Add(1)
+	volume.checkpointRequestChan <- &checkpointRequest
+	checkpointRequest.waitGroup.Wait()
+
+	err = checkpointRequest.err
+	if nil != err {
+		return
+	}
+	err = checkpointRequest.snapshotErr
+	if nil != err {
+		return
+	}
+
+	snapshotID = checkpointRequest.snapshotID
+
+	err = nil
+	return
+}
+
+// DeleteSnapshot removes the named snapshot. Objects in the checkpoint container referenced solely by the
+// snapshot are deleted once the resulting checkpoint has been persisted. The LogSegmentRecs of log segments
+// no longer referenced by either the live volume or any remaining snapshot are returned. It is up to the
+// caller to delete these log segments.
+func (volume *volumeStruct) DeleteSnapshot(snapshotName string) (unreferencedLogSegments map[uint64][]byte, err error) {
+	var (
+		inLiveVolume            bool
+		logSegmentNumber        uint64
+		logSegmentNumberAsKey   interface{}
+		logSegmentRecAsValue    interface{}
+		logSegmentRecBPlusTreeN int
+		logSegmentRecIndex      int
+		objectNumber            uint64
+		ok                      bool
+		snapshot                *snapshotStruct
+		snapshotIndex           int
+	)
+
+	volume.Lock()
+
+	snapshotIndex, snapshot, ok = volume.findSnapshotWhileLocked(snapshotName)
+	if !ok {
+		volume.Unlock()
+		err = fmt.Errorf("Snapshot \"%v\" of volume \"%v\" not found", snapshotName, volume.volumeName)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	volume.snapshotList = append(volume.snapshotList[:snapshotIndex], volume.snapshotList[snapshotIndex+1:]...)
+	volume.snapshotListChanged = true
+
+	// Identify log segments only the removed snapshot referenced
+
+	unreferencedLogSegments = make(map[uint64][]byte)
+
+	logSegmentRecBPlusTreeN, err = snapshot.logSegmentRecWrapper.bPlusTree.Len()
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+
+	for logSegmentRecIndex = 0; logSegmentRecIndex < logSegmentRecBPlusTreeN; logSegmentRecIndex++ {
+		logSegmentNumberAsKey, logSegmentRecAsValue, _, err = snapshot.logSegmentRecWrapper.bPlusTree.GetByIndex(logSegmentRecIndex)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+		logSegmentNumber = logSegmentNumberAsKey.(uint64)
+
+		_, inLiveVolume, err = volume.logSegmentRecWrapper.bPlusTree.GetByKey(logSegmentNumber)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+		if inLiveVolume {
+			continue
+		}
+
+		ok, err = volume.snapshotReferencesLogSegmentWhileLocked(logSegmentNumber)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+		if ok {
+			continue
+		}
+
+		unreferencedLogSegments[logSegmentNumber] = logSegmentRecAsValue.([]byte)
+	}
+
+	// Schedule deletion of objects only the removed snapshot referenced
+
+	for objectNumber = range snapshot.objectNumbers {
+		if objectNumber == volume.checkpointHeader.CheckpointObjectTrailerV2StructObjectNumber {
+			continue
+		}
+		_, ok = volume.inodeRecBPlusTreeLayout[objectNumber]
+		if ok {
+			continue
+		}
+		_, ok = volume.logSegmentRecBPlusTreeLayout[objectNumber]
+		if ok {
+			continue
+		}
+		_, ok = volume.bPlusTreeObjectBPlusTreeLayout[objectNumber]
+		if ok {
+			continue
+		}
+		if volume.snapshotReferencesObjectWhileLocked(objectNumber) {
+			continue
+		}
+
+		volume.objectDeleteAfterCheckpointWhileLocked(objectNumber)
+	}
+
+	volume.Unlock()
+
+	// Persist the updated snapshot list (also triggering the above object deletions)
+
+	err = volume.DoCheckpoint()
+
+	return // err set as appropriate
+}
+
+func (volume *volumeStruct) FetchSnapshotList() (snapshotList []SnapshotInfo) {
+	var (
+		snapshot *snapshotStruct
+	)
+
+	volume.Lock()
+
+	snapshotList = make([]SnapshotInfo, 0, len(volume.snapshotList))
+
+	for _, snapshot = range volume.snapshotList {
+		snapshotList = append(snapshotList, SnapshotInfo{
+			ID:           snapshot.id,
+			Name:         snapshot.name,
+			CreationTime: snapshot.creationTime,
+			VolumeStats:  snapshot.volumeStats,
+		})
+	}
+
+	volume.Unlock()
+
+	return
+}
+
+func (volume *volumeStruct) SnapshotReferencesLogSegment(logSegmentNumber uint64) (referenced bool, err error) {
+	volume.Lock()
+	referenced, err = volume.snapshotReferencesLogSegmentWhileLocked(logSegmentNumber)
+	volume.Unlock()
+	return
+}
+
+func (volume *volumeStruct) FetchSnapshotVolumeHandle(snapshotName string) (volumeHandle VolumeHandle, err error) {
+	var (
+		ok       bool
+		snapshot *snapshotStruct
+	)
+
+	volume.Lock()
+	_, snapshot, ok = volume.findSnapshotWhileLocked(snapshotName)
+	volume.Unlock()
+
+	if !ok {
+		err = fmt.Errorf("Snapshot \"%v\" of volume \"%v\" not found", snapshotName, volume.volumeName)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	volumeHandle = &snapshotVolumeStruct{volume: volume, snapshot: snapshot}
+
+	err = nil
+	return
+}
+
+func snapshotReadOnlyError(fnName string) (err error) {
+	err = fmt.Errorf("%s not allowed on a snapshot", fnName)
+	err = blunder.AddError(err, blunder.ReadOnlyError)
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchNextCheckPointDoneWaitGroup() (wg *sync.WaitGroup) {
+	wg = &sync.WaitGroup{} // nothing is ever awaiting a checkpoint of a snapshot
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) Fenced() (fenced bool) {
+	fenced = false // nothing ever awaits a checkpoint of a snapshot
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchNonce() (nonce uint64, err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) GetInodeRec(inodeNumber uint64) (value []byte, ok bool, err error) {
+	snapshotVolume.volume.Lock()
+
+	valueAsValue, ok, err := snapshotVolume.snapshot.inodeRecWrapper.bPlusTree.GetByKey(inodeNumber)
+	if (nil != err) || !ok {
+		snapshotVolume.volume.Unlock()
+		return
+	}
+	valueFromTree := valueAsValue.([]byte)
+	value = make([]byte, len(valueFromTree))
+	copy(value, valueFromTree)
+
+	snapshotVolume.volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) NextInodeNumber(prevInodeNumber uint64) (nextInodeNumber uint64, ok bool, err error) {
+	snapshotVolume.volume.Lock()
+
+	index, found, err := snapshotVolume.snapshot.inodeRecWrapper.bPlusTree.BisectRight(prevInodeNumber)
+	if nil != err {
+		snapshotVolume.volume.Unlock()
+		return
+	}
+	if found {
+		index++
+	}
+
+	keyAsKey, _, ok, err := snapshotVolume.snapshot.inodeRecWrapper.bPlusTree.GetByIndex(index)
+	if nil != err {
+		snapshotVolume.volume.Unlock()
+		return
+	}
+	if ok {
+		nextInodeNumber = keyAsKey.(uint64)
+	}
+
+	snapshotVolume.volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) PutInodeRec(inodeNumber uint64, value []byte) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) PutInodeRecs(inodeNumbers []uint64, values [][]byte) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) DeleteInodeRec(inodeNumber uint64) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) GetLogSegmentRec(logSegmentNumber uint64) (value []byte, err error) {
+	snapshotVolume.volume.Lock()
+
+	valueAsValue, ok, err := snapshotVolume.snapshot.logSegmentRecWrapper.bPlusTree.GetByKey(logSegmentNumber)
+	if nil != err {
+		snapshotVolume.volume.Unlock()
+		return
+	}
+	if !ok {
+		snapshotVolume.volume.Unlock()
+		err = fmt.Errorf("logSegmentNumber 0x%016X not found in volume \"%v\" snapshot \"%v\" logSegmentRecWrapper.bPlusTree", logSegmentNumber, snapshotVolume.volume.volumeName, snapshotVolume.snapshot.name)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+	valueFromTree := valueAsValue.([]byte)
+	value = make([]byte, len(valueFromTree))
+	copy(value, valueFromTree)
+
+	snapshotVolume.volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) PutLogSegmentRec(logSegmentNumber uint64, value []byte) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) DeleteLogSegmentRec(logSegmentNumber uint64) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) GetBPlusTreeObject(objectNumber uint64) (value []byte, err error) {
+	snapshotVolume.volume.Lock()
+
+	valueAsValue, ok, err := snapshotVolume.snapshot.bPlusTreeObjectWrapper.bPlusTree.GetByKey(objectNumber)
+	if nil != err {
+		snapshotVolume.volume.Unlock()
+		return
+	}
+	if !ok {
+		snapshotVolume.volume.Unlock()
+		err = fmt.Errorf("objectNumber 0x%016X not found in volume \"%v\" snapshot \"%v\" bPlusTreeObjectWrapper.bPlusTree", objectNumber, snapshotVolume.volume.volumeName, snapshotVolume.snapshot.name)
+		return
+	}
+	valueFromTree := valueAsValue.([]byte)
+	value = make([]byte, len(valueFromTree))
+	copy(value, valueFromTree)
+
+	snapshotVolume.volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) PutBPlusTreeObject(objectNumber uint64, value []byte) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) DeleteBPlusTreeObject(objectNumber uint64) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchVolumeStats() (volumeStats VolumeStats) {
+	volumeStats = snapshotVolume.snapshot.volumeStats
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) AdjustVolumeStats(logSegmentBytesDelta int64, referencedBytesDelta int64) {
+	// A snapshot's VolumeStats are fixed
+}
+
+func (snapshotVolume *snapshotVolumeStruct) VolumeStatsRebuildNeeded() (rebuildNeeded bool) {
+	rebuildNeeded = false // a snapshot requires a checkpoint recording VolumeStats
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) RebuildVolumeStats(referencedBytes uint64) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchOwnerStats() (userStats map[uint32]OwnerStats, groupStats map[uint32]OwnerStats) {
+	var (
+		ownerID    uint32
+		ownerStats OwnerStats
+	)
+
+	userStats = make(map[uint32]OwnerStats, len(snapshotVolume.snapshot.userStats))
+	for ownerID, ownerStats = range snapshotVolume.snapshot.userStats {
+		userStats[ownerID] = ownerStats
+	}
+
+	groupStats = make(map[uint32]OwnerStats, len(snapshotVolume.snapshot.groupStats))
+	for ownerID, ownerStats = range snapshotVolume.snapshot.groupStats {
+		groupStats[ownerID] = ownerStats
+	}
+
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchOwnerStatsByID(userID uint32, groupID uint32) (userStats OwnerStats, groupStats OwnerStats) {
+	userStats = snapshotVolume.snapshot.userStats[userID]
+	groupStats = snapshotVolume.snapshot.groupStats[groupID]
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) AdjustOwnerStats(userID uint32, groupID uint32, bytesDelta int64, inodesDelta int64) {
+	// A snapshot's OwnerStats are fixed
+}
+
+func (snapshotVolume *snapshotVolumeStruct) OwnerStatsRebuildNeeded() (rebuildNeeded bool) {
+	rebuildNeeded = false // a snapshot requires a checkpoint recording OwnerStats
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) RebuildOwnerStats(userStats map[uint32]OwnerStats, groupStats map[uint32]OwnerStats) (err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) CreateSnapshot(snapshotName string) (snapshotID uint64, err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) DeleteSnapshot(snapshotName string) (unreferencedLogSegments map[uint64][]byte, err error) {
+	err = snapshotReadOnlyError(utils.GetFnName())
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchSnapshotList() (snapshotList []SnapshotInfo) {
+	snapshotList = make([]SnapshotInfo, 0)
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) SnapshotReferencesLogSegment(logSegmentNumber uint64) (referenced bool, err error) {
+	referenced = true // nothing may be deleted via a snapshot anyway
+	err = nil
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchSnapshotVolumeHandle(snapshotName string) (volumeHandle VolumeHandle, err error) {
+	err = fmt.Errorf("Snapshot \"%v\" of volume \"%v\" snapshot \"%v\" not found", snapshotName, snapshotVolume.volume.volumeName, snapshotVolume.snapshot.name)
+	err = blunder.AddError(err, blunder.NotFoundError)
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) DoCheckpoint() (err error) {
+	err = nil // nothing to checkpoint
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchCheckpointStatus() (checkpointStatus CheckpointStatus) {
+	checkpointStatus = CheckpointStatus{} // a snapshot is never degraded
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) FetchCheckpointContainerName() (checkpointContainerName string) {
+	checkpointContainerName = snapshotVolume.volume.checkpointContainerName
+	return
+}
+
+func (snapshotVolume *snapshotVolumeStruct) CheckpointReferencesObject(objectNumber uint64) (referenced bool) {
+	referenced = true // nothing may be deleted via a snapshot anyway
+	return
+}
@@ -3,11 +3,44 @@ package headhunter
 import (
 	"fmt"
 
-	"github.com/swiftstack/ProxyFS/swiftclient"
-	"github.com/swiftstack/ProxyFS/utils"
+	"github.com/swiftstack/cstruct"
 	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/utils"
 )
 
+// The following mirror sortedmap's on-disk B+Tree node format (see sortedmap's loadNode()). They permit
+// headhunter to locate the nodes residing in a given checkpoint object without loading them into a B+Tree.
+
+type onDiskUint64Struct struct {
+	U64 uint64
+}
+
+type onDiskReferenceToNodeStruct struct {
+	ObjectNumber uint64
+	ObjectOffset uint64
+	ObjectLength uint64
+	Items        uint64
+}
+
+type onDiskNodeStruct struct {
+	Items   uint64
+	Root    bool
+	Leaf    bool
+	Payload []byte // if Root == true,  maxKeysPerNode
+	//                if Leaf == true,  counted number <N> of Key:Value pairs
+	//                if Leaf == false, counted <N> number of children... the leftmost child's onDiskReferenceToNodeStruct
+	//                                  (if <N> > 0) followed by <N-1> Key:onDiskReferenceToNodeStruct pairs
+}
+
+// nodeLocatorStruct identifies a node put to a checkpoint object such that it may be marked dirty again.
+// Any Key held at or below a node suffices to reach it via TouchItem()... a root node needs none.
+type nodeLocatorStruct struct {
+	bPlusTreeWrapper *bPlusTreeWrapperStruct
+	root             bool
+	firstKey         sortedmap.Key // nil if the node holds no Key
+}
+
 func (bPlusTreeWrapper *bPlusTreeWrapperStruct) DumpKey(key sortedmap.Key) (keyAsString string, err error) {
 	keyAsUint64, ok := key.(uint64)
 	if !ok {
@@ -53,9 +86,129 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) DumpValue(value sortedmap.Value)
 	return
 }
 
+// unpackNode decodes nodeByteSlice returning whether it is a root and/or leaf node, the first Key it holds
+// (nil if none), and (for non-leaf nodes) references to its children (leftmost first).
+func (bPlusTreeWrapper *bPlusTreeWrapperStruct) unpackNode(nodeByteSlice []byte) (root bool, leaf bool, firstKey sortedmap.Key, children []onDiskReferenceToNodeStruct, err error) {
+	var (
+		bytesConsumed         uint64
+		count                 onDiskUint64Struct
+		i                     uint64
+		onDiskNode            onDiskNodeStruct
+		onDiskReferenceToNode onDiskReferenceToNodeStruct
+		payload               []byte
+	)
+
+	_, err = cstruct.Unpack(nodeByteSlice, &onDiskNode, sortedmap.OnDiskByteOrder)
+	if nil != err {
+		return
+	}
+
+	root = onDiskNode.Root
+	leaf = onDiskNode.Leaf
+	payload = onDiskNode.Payload
+
+	if root {
+		bytesConsumed, err = cstruct.Unpack(payload, &count, sortedmap.OnDiskByteOrder) // maxKeysPerNode
+		if nil != err {
+			return
+		}
+		payload = payload[bytesConsumed:]
+	}
+
+	bytesConsumed, err = cstruct.Unpack(payload, &count, sortedmap.OnDiskByteOrder)
+	if nil != err {
+		return
+	}
+	payload = payload[bytesConsumed:]
+
+	if leaf {
+		if 0 < count.U64 {
+			firstKey, _, err = bPlusTreeWrapper.UnpackKey(payload)
+		}
+		return
+	}
+
+	children = make([]onDiskReferenceToNodeStruct, 0, count.U64)
+
+	for i = 0; i < count.U64; i++ {
+		if 0 < i {
+			var key sortedmap.Key
+
+			key, bytesConsumed, err = bPlusTreeWrapper.UnpackKey(payload)
+			if nil != err {
+				return
+			}
+			payload = payload[bytesConsumed:]
+
+			if 1 == i {
+				firstKey = key
+			}
+		}
+
+		bytesConsumed, err = cstruct.Unpack(payload, &onDiskReferenceToNode, sortedmap.OnDiskByteOrder)
+		if nil != err {
+			return
+		}
+		payload = payload[bytesConsumed:]
+
+		children = append(children, onDiskReferenceToNode)
+	}
+
+	err = nil
+	return
+}
+
+// touchNode marks the node identified by nodeLocator (along with its ancestors) dirty
+func (nodeLocator *nodeLocatorStruct) touchNode() (err error) {
+	var (
+		bPlusTree     sortedmap.BPlusTree
+		itemIndex     int
+		numberOfItems int
+	)
+
+	bPlusTree = nodeLocator.bPlusTreeWrapper.bPlusTree
+
+	numberOfItems, err = bPlusTree.Len()
+	if nil != err {
+		return
+	}
+
+	if (0 == numberOfItems) || (!nodeLocator.root && (nil == nodeLocator.firstKey)) {
+		// Either just the root node remains... or (contrary to B+Tree invariants) the node held no Key
+		err = bPlusTree.Touch()
+		return
+	}
+
+	if nodeLocator.root {
+		itemIndex = 0
+	} else {
+		// Should firstKey have since been deleted, the node it resided in was already marked dirty
+
+		itemIndex, _, err = bPlusTree.BisectLeft(nodeLocator.firstKey)
+		if nil != err {
+			return
+		}
+		if (0 > itemIndex) || (numberOfItems <= itemIndex) {
+			err = nil
+			return
+		}
+	}
+
+	_, err = bPlusTree.TouchItem(uint64(itemIndex))
+
+	return
+}
+
 func (bPlusTreeWrapper *bPlusTreeWrapperStruct) GetNode(objectNumber uint64, objectOffset uint64, objectLength uint64) (nodeByteSlice []byte, err error) {
+	chunkedPutContext, ok := bPlusTreeWrapper.volume.failedCheckpointObjectMap[objectNumber]
+	if ok {
+		// Node was flushed to an object whose PUT failed... so read it back from what was sent
+		nodeByteSlice, err = chunkedPutContext.Read(objectOffset, objectLength)
+		return
+	}
+
 	nodeByteSlice, err =
-		swiftclient.ObjectGet(
+		bPlusTreeWrapper.volume.objectStore.ObjectGet(
 			bPlusTreeWrapper.volume.accountName,
 			bPlusTreeWrapper.volume.checkpointContainerName,
 			utils.Uint64ToHexStr(objectNumber),
@@ -70,6 +223,11 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) PutNode(nodeByteSlice []byte) (o
 		ok        bool
 	)
 
+	if bPlusTreeWrapper.readOnly {
+		err = fmt.Errorf("Logic error: bPlusTreeWrapper.PutNode() called for read-only snapshot B+Tree")
+		panic(err)
+	}
+
 	err = bPlusTreeWrapper.volume.openCheckpointChunkedPutContextIfNecessary()
 	if nil != err {
 		return
@@ -89,6 +247,17 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) PutNode(nodeByteSlice []byte) (o
 
 	bPlusTreeWrapper.volume.checkpointFlushedData = true
 
+	// Should this checkpoint object's PUT fail, the node must be found and marked dirty again
+
+	nodeLocator := nodeLocatorStruct{bPlusTreeWrapper: bPlusTreeWrapper}
+
+	nodeLocator.root, _, nodeLocator.firstKey, _, err = bPlusTreeWrapper.unpackNode(nodeByteSlice)
+	if nil != err {
+		return
+	}
+
+	bPlusTreeWrapper.volume.checkpointNodeLocatorMap[objectNumber] = append(bPlusTreeWrapper.volume.checkpointNodeLocatorMap[objectNumber], nodeLocator)
+
 	switch bPlusTreeWrapper.wrapperType {
 	case inodeRecBPlusTreeWrapperType:
 		bytesUsed, ok = bPlusTreeWrapper.volume.inodeRecBPlusTreeLayout[objectNumber]
@@ -127,6 +296,11 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) DiscardNode(objectNumber uint64,
 		ok        bool
 	)
 
+	if bPlusTreeWrapper.readOnly {
+		err = fmt.Errorf("Logic error: bPlusTreeWrapper.DiscardNode() called for read-only snapshot B+Tree")
+		return
+	}
+
 	switch bPlusTreeWrapper.wrapperType {
 	case inodeRecBPlusTreeWrapperType:
 		bytesUsed, ok = bPlusTreeWrapper.volume.inodeRecBPlusTreeLayout[objectNumber]
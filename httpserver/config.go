@@ -10,12 +10,15 @@ import (
 	"github.com/swiftstack/sortedmap"
 
 	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
 const (
-	fsckJobsHistoryMaxSize = 5 // TODO: May want to parameterize this ultimately
+	fsckJobsHistoryMaxSize  = 5 // TODO: May want to parameterize this ultimately
+	scrubJobsHistoryMaxSize = 5 // TODO: May want to parameterize this ultimately
 )
 
 type fsckJobState uint8
@@ -26,43 +29,129 @@ const (
 	fsckJobCompleted
 )
 
+// fsckJobStruct tracks either a dry run (repairing nothing) or a repairing run of fs.ValidateVolume().
+// The goroutine performing the job sets report before signaling errChan.
 type fsckJobStruct struct {
 	id        uint64
 	volume    *volumeStruct
+	repair    bool
 	stopChan  chan bool
 	errChan   chan error
 	state     fsckJobState
 	startTime time.Time
 	endTime   time.Time
+	report    *fs.FSCKReport
 	err       error
 }
 
-type fsckRunningJobStruct struct {
-	StartTime string `json:"start time"`
+type fsckFindingStruct struct {
+	InodeNumber uint64 `json:"inode number"`
+	Path        string `json:"path"`
+	Problem     string `json:"problem"`
+	Detail      string `json:"detail"`
+	Action      string `json:"action"`
+	Repaired    bool   `json:"repaired"`
 }
 
-type fsckHaltedJobStruct struct {
-	StartTime string `json:"start time"`
-	HaltTime  string `json:"halt time"`
+type fsckJobStatusStruct struct {
+	State           string              `json:"state"`
+	Repair          bool                `json:"repair"`
+	StartTime       string              `json:"start time"`
+	HaltTime        string              `json:"halt time,omitempty"`
+	DoneTime        string              `json:"done time,omitempty"`
+	Error           string              `json:"errors,omitempty"`
+	InodesValidated uint64              `json:"inodes validated"`
+	Findings        []fsckFindingStruct `json:"findings"` // if Repair, those with Repaired set were repaired
 }
 
-type fsckCompletedJobNoErrorStruct struct {
-	StartTime string `json:"start time"`
-	DoneTime  string `json:"done time"`
+// scrubJobStruct tracks either a dry run (finding orphaned objects) or the deletion of the orphans
+// reported by a completed dry run. Job states are as for FSCK jobs. The goroutine performing the
+// job sets report before signaling errChan.
+type scrubJobStruct struct {
+	id          uint64
+	volume      *volumeStruct
+	dryRun      bool
+	dryRunJobID uint64 // if !dryRun, the job whose orphans are to be deleted
+	stopChan    chan bool
+	errChan     chan error
+	state       fsckJobState
+	startTime   time.Time
+	endTime     time.Time
+	report      *inode.ScrubReport
+	err         error
 }
 
-type fsckCompletedJobWithErrorStruct struct {
-	StartTime string `json:"start time"`
-	DoneTime  string `json:"done time"`
-	Error     string `json:"errors"`
+type scrubOrphanStruct struct {
+	ContainerName string `json:"container"`
+	ObjectName    string `json:"object"`
+	ObjectLength  uint64 `json:"bytes"`
+}
+
+type scrubJobStatusStruct struct {
+	State             string              `json:"state"`
+	DryRun            bool                `json:"dry run"`
+	DryRunJobID       uint64              `json:"dry run job,omitempty"`
+	StartTime         string              `json:"start time"`
+	HaltTime          string              `json:"halt time,omitempty"`
+	DoneTime          string              `json:"done time,omitempty"`
+	Error             string              `json:"errors,omitempty"`
+	ContainersScanned uint64              `json:"containers scanned"`
+	ObjectsScanned    uint64              `json:"objects scanned"`
+	OrphanedBytes     uint64              `json:"orphaned bytes"`
+	DeleteFailures    uint64              `json:"delete failures"`
+	Orphans           []scrubOrphanStruct `json:"orphans"` // if !DryRun, those deleted
+}
+
+type defragStatusStruct struct {
+	Running           bool   `json:"running"`
+	StartTime         string `json:"start time,omitempty"`
+	StopTime          string `json:"stop time,omitempty"`
+	LastPassStartTime string `json:"last pass start time,omitempty"`
+	LastPassEndTime   string `json:"last pass end time,omitempty"`
+	PassesCompleted   uint64 `json:"passes completed"`
+	InodesScanned     uint64 `json:"inodes scanned"`
+	InodesOptimized   uint64 `json:"inodes optimized"`
+	OptimizeFailures  uint64 `json:"optimize failures"`
+	BytesRewritten    uint64 `json:"bytes rewritten"`
+}
+
+type ownerQuotaStruct struct {
+	ID         uint32 `json:"id"`
+	BytesUsed  uint64 `json:"bytes used"`
+	HardBytes  uint64 `json:"hard bytes limit"` // 0 means unlimited
+	InodesUsed uint64 `json:"inodes used"`
+	HardInodes uint64 `json:"hard inodes limit"` // 0 means unlimited
+}
+
+type volumeQuotaStruct struct {
+	BytesUsed               uint64             `json:"bytes used"`
+	SoftBytes               uint64             `json:"soft bytes limit"` // 0 means unlimited
+	HardBytes               uint64             `json:"hard bytes limit"` // 0 means unlimited
+	SoftBytesExceededSince  string             `json:"soft bytes limit exceeded since,omitempty"`
+	InodesUsed              uint64             `json:"inodes used"`
+	SoftInodes              uint64             `json:"soft inodes limit"` // 0 means unlimited
+	HardInodes              uint64             `json:"hard inodes limit"` // 0 means unlimited
+	SoftInodesExceededSince string             `json:"soft inodes limit exceeded since,omitempty"`
+	GracePeriod             string             `json:"grace period"`
+	Users                   []ownerQuotaStruct `json:"users"`  // sorted by ID
+	Groups                  []ownerQuotaStruct `json:"groups"` // sorted by ID
+}
+
+type snapshotStruct struct {
+	ID           uint64 `json:"id"`
+	Name         string `json:"name"`
+	CreationTime string `json:"creation time"`
 }
 
 type volumeStruct struct {
 	sync.Mutex
-	name             string
-	headhunterHandle headhunter.VolumeHandle
-	fsckActiveJob    *fsckJobStruct
-	fsckJobs         sortedmap.LLRBTree // Key == fsckJobStruct.id, Value == *fsckJobStruct
+	name              string
+	headhunterHandle  headhunter.VolumeHandle
+	inodeVolumeHandle inode.VolumeHandle
+	fsckActiveJob     *fsckJobStruct
+	fsckJobs          sortedmap.LLRBTree // Key == fsckJobStruct.id, Value == *fsckJobStruct
+	scrubActiveJob    *scrubJobStruct
+	scrubJobs         sortedmap.LLRBTree // Key == scrubJobStruct.id, Value == *scrubJobStruct
 }
 
 type globalsStruct struct {
@@ -117,9 +206,11 @@ func Up(confMap conf.ConfMap) (err error) {
 		} else if 1 == len(primaryPeerList) {
 			if globals.whoAmI == primaryPeerList[0] {
 				volume = &volumeStruct{
-					name:          volumeName,
-					fsckActiveJob: nil,
-					fsckJobs:      sortedmap.NewLLRBTree(sortedmap.CompareUint64, nil),
+					name:           volumeName,
+					fsckActiveJob:  nil,
+					fsckJobs:       sortedmap.NewLLRBTree(sortedmap.CompareUint64, nil),
+					scrubActiveJob: nil,
+					scrubJobs:      sortedmap.NewLLRBTree(sortedmap.CompareUint64, nil),
 				}
 
 				volume.headhunterHandle, err = headhunter.FetchVolumeHandle(volume.name)
@@ -127,6 +218,11 @@ func Up(confMap conf.ConfMap) (err error) {
 					return
 				}
 
+				volume.inodeVolumeHandle, err = inode.FetchVolumeHandle(volume.name)
+				if nil != err {
+					return
+				}
+
 				ok, err = globals.volumeLLRB.Put(volumeName, volume)
 				if nil != err {
 					err = fmt.Errorf("statsLLRB.Put(%v,) failed: %v", volumeName, err)
@@ -220,7 +316,7 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 
 	globals.active = false
 
-	err = stopRunningFSCKs()
+	err = stopRunningJobs()
 	if nil != err {
 		globals.active = true
 		return
@@ -336,9 +432,11 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 				}
 				if !ok {
 					volume = &volumeStruct{
-						name:          volumeName,
-						fsckActiveJob: nil,
-						fsckJobs:      sortedmap.NewLLRBTree(sortedmap.CompareUint64, nil),
+						name:           volumeName,
+						fsckActiveJob:  nil,
+						fsckJobs:       sortedmap.NewLLRBTree(sortedmap.CompareUint64, nil),
+						scrubActiveJob: nil,
+						scrubJobs:      sortedmap.NewLLRBTree(sortedmap.CompareUint64, nil),
 					}
 
 					volume.headhunterHandle, err = headhunter.FetchVolumeHandle(volume.name)
@@ -346,6 +444,11 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 						return
 					}
 
+					volume.inodeVolumeHandle, err = inode.FetchVolumeHandle(volume.name)
+					if nil != err {
+						return
+					}
+
 					ok, err = globals.volumeLLRB.Put(volumeName, volume)
 					if nil != err {
 						err = fmt.Errorf("statsLLRB.Put(%v,) failed: %v", volumeName, err)
@@ -372,7 +475,7 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 
 func Down() (err error) {
 	globals.Lock()
-	_ = stopRunningFSCKs()
+	_ = stopRunningJobs()
 	_ = globals.netListener.Close()
 	globals.Unlock()
 
@@ -382,7 +485,7 @@ func Down() (err error) {
 	return
 }
 
-func stopRunningFSCKs() (err error) {
+func stopRunningJobs() (err error) {
 	var (
 		numVolumes    int
 		ok            bool
@@ -425,9 +528,27 @@ func stopRunningFSCKs() (err error) {
 			volume.fsckActiveJob.endTime = time.Now()
 			volume.fsckActiveJob = nil
 		}
+		if nil != volume.scrubActiveJob {
+			volume.scrubActiveJob.halt()
+		}
 		volume.Unlock()
 	}
 
 	err = nil
 	return
 }
+
+// halt stops a running scrub job (which must be volume.scrubActiveJob) with volume locked.
+func (scrubJob *scrubJobStruct) halt() {
+	scrubJob.stopChan <- true
+	scrubJob.err = <-scrubJob.errChan
+	select {
+	case _, _ = <-scrubJob.stopChan:
+		// Swallow our stopChan write from above if the scrub finished before reading it
+	default:
+		// The scrub must have read and honored our stopChan write
+	}
+	scrubJob.state = fsckJobHalted
+	scrubJob.endTime = time.Now()
+	scrubJob.volume.scrubActiveJob = nil
+}
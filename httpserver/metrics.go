@@ -0,0 +1,407 @@
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/swiftstack/ProxyFS/jrpcfs"
+	"github.com/swiftstack/ProxyFS/logger"
+)
+
+// Rendering of the /metrics page in Prometheus text exposition format (version 0.0.4)
+//
+// StatsD-style stat names (as returned by stats.Dump()) are of the form:
+//
+//   proxyfs.<component>.<operation>[.<unit>][.size-<bucket>]
+//   logging.level.<level>
+//
+// where <operation> may itself contain "."'s and <unit> is one of the keys of statUnitToMetric. These become:
+//
+//   proxyfs_<component>_<unit metric>{operation="<operation>"}                      a counter
+//   proxyfs_<component>_operation_size_bytes_bucket{operation="<operation>",le="..."} a histogram (from the size-* buckets)
+//   proxyfs_logger_messages_total{level="<level>"}                                   a counter
+//
+// Values that are per-volume (rather than per-process) are labeled with volume="<volume name>".
+
+const (
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+)
+
+type metricUnitStruct struct {
+	suffix string // appended to "proxyfs_<component>_"
+	help   string // "%v" is replaced by <component>
+}
+
+var statUnitToMetric = map[string]metricUnitStruct{
+	"operations":  {suffix: "operations_total", help: "Count of %v operations"},
+	"bytes":       {suffix: "bytes_total", help: "Bytes transferred by %v operations"},
+	"entries":     {suffix: "entries_total", help: "Entries returned by %v operations"},
+	"appended":    {suffix: "appended_bytes_total", help: "Bytes appended by %v operations"},
+	"overwritten": {suffix: "overwritten_bytes_total", help: "Bytes overwritten by %v operations"},
+}
+
+// statBucketSuffixToUpperBound maps each suffix produced by stats.computeBucketSuffix() to its "le" label value
+var statBucketSuffixToUpperBound = map[string]float64{
+	"size-up-to-4KB":    4096,
+	"size-4KB-to-8KB":   8192,
+	"size-8KB-to-16KB":  16384,
+	"size-16KB-to-32KB": 32768,
+	"size-32KB-to-64KB": 65536,
+	"size-over-64KB":    math.Inf(+1),
+}
+
+type metricLabelStruct struct {
+	name  string
+	value string
+}
+
+type metricSampleStruct struct {
+	labels []metricLabelStruct
+	value  float64
+}
+
+type metricHistogramStruct struct {
+	labels  []metricLabelStruct
+	buckets map[float64]uint64 // Key == upper bound, Value == count of observations in (next lower bound, upper bound]
+	sum     float64
+}
+
+type metricFamilyStruct struct {
+	name       string
+	help       string
+	metricType string
+	samples    []metricSampleStruct     // if metricType != metricTypeHistogram
+	histograms []*metricHistogramStruct // if metricType == metricTypeHistogram
+}
+
+type metricsStruct struct {
+	familyMap map[string]*metricFamilyStruct // Key == metricFamilyStruct.name
+}
+
+func newMetrics() (metrics *metricsStruct) {
+	metrics = &metricsStruct{familyMap: make(map[string]*metricFamilyStruct)}
+	return
+}
+
+func (metrics *metricsStruct) fetchFamily(name string, help string, metricType string) (family *metricFamilyStruct) {
+	family, ok := metrics.familyMap[name]
+	if !ok {
+		family = &metricFamilyStruct{
+			name:       name,
+			help:       help,
+			metricType: metricType,
+			samples:    make([]metricSampleStruct, 0),
+			histograms: make([]*metricHistogramStruct, 0),
+		}
+		metrics.familyMap[name] = family
+	}
+	return
+}
+
+func (metrics *metricsStruct) add(name string, help string, metricType string, value float64, labels ...metricLabelStruct) {
+	family := metrics.fetchFamily(name, help, metricType)
+	family.samples = append(family.samples, metricSampleStruct{labels: labels, value: value})
+}
+
+func (metrics *metricsStruct) fetchHistogram(name string, help string, labels ...metricLabelStruct) (histogram *metricHistogramStruct) {
+	family := metrics.fetchFamily(name, help, metricTypeHistogram)
+
+	labelsAsString := labelsToString(labels, nil)
+	for _, histogram = range family.histograms {
+		if labelsToString(histogram.labels, nil) == labelsAsString {
+			return
+		}
+	}
+
+	histogram = &metricHistogramStruct{labels: labels, buckets: make(map[float64]uint64)}
+	family.histograms = append(family.histograms, histogram)
+	return
+}
+
+// sanitizeMetricName replaces each character not legal in a Prometheus metric name with an "_"
+func sanitizeMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || ('_' == r) || (':' == r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// addStats converts the StatsD-style stats (as returned by stats.Dump()) into metrics
+func (metrics *metricsStruct) addStats(statsMap map[string]uint64) {
+	var (
+		bucketedStats []string
+	)
+
+	for statName, statValue := range statsMap {
+		statNameSplit := strings.Split(statName, ".")
+
+		if (3 == len(statNameSplit)) && ("logging" == statNameSplit[0]) && ("level" == statNameSplit[1]) {
+			metrics.add("proxyfs_logger_messages_total", "Count of log messages (by level)", metricTypeCounter, float64(statValue),
+				metricLabelStruct{"level", statNameSplit[2]})
+			continue
+		}
+
+		if (3 > len(statNameSplit)) || ("proxyfs" != statNameSplit[0]) {
+			metrics.add(sanitizeMetricName(statName), "Untranslated stat "+statName, metricTypeCounter, float64(statValue))
+			continue
+		}
+
+		component := statNameSplit[1]
+		operationSplit := statNameSplit[2:]
+
+		if _, ok := statBucketSuffixToUpperBound[operationSplit[len(operationSplit)-1]]; ok && (3 <= len(operationSplit)) && ("operations" == operationSplit[len(operationSplit)-2]) {
+			bucketedStats = append(bucketedStats, statName) // deferred until all unit stats (i.e. ".bytes" for _sum) are known
+			continue
+		}
+
+		unit, ok := statUnitToMetric[operationSplit[len(operationSplit)-1]]
+		if ok {
+			operationSplit = operationSplit[:len(operationSplit)-1]
+		} else {
+			unit = statUnitToMetric["operations"] // e.g. proxyfs.swiftclient.account-get counts operations
+		}
+
+		metricName := sanitizeMetricName("proxyfs_" + component + "_" + unit.suffix)
+		metricHelp := fmt.Sprintf(unit.help, component)
+
+		if 0 == len(operationSplit) {
+			metrics.add(metricName, metricHelp, metricTypeCounter, float64(statValue))
+		} else {
+			metrics.add(metricName, metricHelp, metricTypeCounter, float64(statValue),
+				metricLabelStruct{"operation", strings.Join(operationSplit, ".")})
+		}
+	}
+
+	for _, statName := range bucketedStats {
+		statNameSplit := strings.Split(statName, ".")
+
+		// Form: proxyfs.<component>.<operation>.operations.size-<bucket>
+
+		component := statNameSplit[1]
+		operationSplit := statNameSplit[2 : len(statNameSplit)-2]
+		operation := strings.Join(operationSplit, ".")
+
+		histogram := metrics.fetchHistogram(sanitizeMetricName("proxyfs_"+component+"_operation_size_bytes"),
+			fmt.Sprintf("Sizes (in bytes) of %v operations", component), metricLabelStruct{"operation", operation})
+
+		histogram.buckets[statBucketSuffixToUpperBound[statNameSplit[len(statNameSplit)-1]]] += statsMap[statName]
+		histogram.sum = float64(statsMap["proxyfs."+component+"."+operation+".bytes"])
+	}
+}
+
+// addMemStats adds the Go runtime's memory allocator & garbage collector statistics
+func (metrics *metricsStruct) addMemStats() {
+	var (
+		memStats           runtime.MemStats
+		pauseNsAccumulator uint64
+		pauseNsAverage     uint64
+	)
+
+	runtime.ReadMemStats(&memStats)
+
+	metrics.add("go_goroutines", "Number of goroutines that currently exist", metricTypeGauge, float64(runtime.NumGoroutine()))
+
+	// General statistics.
+	metrics.add("go_memstats_alloc_bytes", "Bytes allocated and still in use", metricTypeGauge, float64(memStats.Alloc))
+	metrics.add("go_memstats_alloc_bytes_total", "Bytes allocated, even if freed", metricTypeCounter, float64(memStats.TotalAlloc))
+	metrics.add("go_memstats_sys_bytes", "Bytes obtained from system", metricTypeGauge, float64(memStats.Sys))
+	metrics.add("go_memstats_lookups_total", "Pointer lookups", metricTypeCounter, float64(memStats.Lookups))
+	metrics.add("go_memstats_mallocs_total", "Mallocs", metricTypeCounter, float64(memStats.Mallocs))
+	metrics.add("go_memstats_frees_total", "Frees", metricTypeCounter, float64(memStats.Frees))
+
+	// Main allocation heap statistics.
+	metrics.add("go_memstats_heap_alloc_bytes", "Heap bytes allocated and still in use", metricTypeGauge, float64(memStats.HeapAlloc))
+	metrics.add("go_memstats_heap_sys_bytes", "Heap bytes obtained from system", metricTypeGauge, float64(memStats.HeapSys))
+	metrics.add("go_memstats_heap_idle_bytes", "Heap bytes waiting to be used", metricTypeGauge, float64(memStats.HeapIdle))
+	metrics.add("go_memstats_heap_inuse_bytes", "Heap bytes in use", metricTypeGauge, float64(memStats.HeapInuse))
+	metrics.add("go_memstats_heap_released_bytes", "Heap bytes released to the system", metricTypeGauge, float64(memStats.HeapReleased))
+	metrics.add("go_memstats_heap_objects", "Allocated heap objects", metricTypeGauge, float64(memStats.HeapObjects))
+
+	// Low-level fixed-size structure allocator statistics.
+	//	Inuse is bytes used now.
+	//	Sys is bytes obtained from system.
+	metrics.add("go_memstats_stack_inuse_bytes", "Bytes in use by the stack allocator", metricTypeGauge, float64(memStats.StackInuse))
+	metrics.add("go_memstats_stack_sys_bytes", "Bytes obtained from system for the stack allocator", metricTypeGauge, float64(memStats.StackSys))
+	metrics.add("go_memstats_mspan_inuse_bytes", "Bytes in use by mspan structures", metricTypeGauge, float64(memStats.MSpanInuse))
+	metrics.add("go_memstats_mspan_sys_bytes", "Bytes obtained from system for mspan structures", metricTypeGauge, float64(memStats.MSpanSys))
+	metrics.add("go_memstats_mcache_inuse_bytes", "Bytes in use by mcache structures", metricTypeGauge, float64(memStats.MCacheInuse))
+	metrics.add("go_memstats_mcache_sys_bytes", "Bytes obtained from system for mcache structures", metricTypeGauge, float64(memStats.MCacheSys))
+	metrics.add("go_memstats_buck_hash_sys_bytes", "Bytes used by the profiling bucket hash table", metricTypeGauge, float64(memStats.BuckHashSys))
+	metrics.add("go_memstats_gc_sys_bytes", "Bytes used for garbage collection system metadata", metricTypeGauge, float64(memStats.GCSys))
+	metrics.add("go_memstats_other_sys_bytes", "Bytes used for other system allocations", metricTypeGauge, float64(memStats.OtherSys))
+
+	// Garbage collector statistics.
+	metrics.add("go_memstats_last_gc_time_seconds", "Seconds since the epoch of the last garbage collection", metricTypeGauge, float64(memStats.LastGC)/1e9)
+	metrics.add("go_memstats_gc_pause_seconds_total", "Seconds spent in garbage collection pauses", metricTypeCounter, float64(memStats.PauseTotalNs)/1e9)
+	metrics.add("go_memstats_gc_completed_total", "Completed garbage collection cycles", metricTypeCounter, float64(memStats.NumGC))
+	metrics.add("go_memstats_gc_cpu_fraction", "Fraction of available CPU time used by the garbage collector", metricTypeGauge, memStats.GCCPUFraction)
+
+	if 0 == memStats.NumGC {
+		pauseNsAverage = 0
+	} else if memStats.NumGC < 255 {
+		for i := 0; i < int(memStats.NumGC); i++ {
+			pauseNsAccumulator += memStats.PauseNs[i]
+		}
+		pauseNsAverage = pauseNsAccumulator / uint64(memStats.NumGC)
+	} else {
+		for i := 0; i < 256; i++ {
+			pauseNsAccumulator += memStats.PauseNs[i]
+		}
+		pauseNsAverage = pauseNsAccumulator / 256
+	}
+	metrics.add("go_memstats_gc_pause_average_seconds", "Average of recent garbage collection pauses", metricTypeGauge, float64(pauseNsAverage)/1e9)
+}
+
+// addFastPath adds the (instantaneous) state of the jrpcfs fast path
+func (metrics *metricsStruct) addFastPath() {
+	metrics.add("proxyfs_jrpcfs_pipelined_in_flight", "Tagged fast path requests currently being performed", metricTypeGauge, float64(jrpcfs.FetchFastInFlight()))
+}
+
+// addVolumes adds the per-volume metrics of each volume served. Caller must hold globals.Mutex.
+func (metrics *metricsStruct) addVolumes() {
+	volumeListLen, err := globals.volumeLLRB.Len()
+	if nil != err {
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+
+	for volumeListIndex := 0; volumeListIndex < volumeListLen; volumeListIndex++ {
+		_, volumeAsValue, ok, err := globals.volumeLLRB.GetByIndex(volumeListIndex)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+		if !ok {
+			err = fmt.Errorf("httpserver.addVolumes() indexing globals.volumeLLRB failed")
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+		volume := volumeAsValue.(*volumeStruct)
+
+		volumeLabel := metricLabelStruct{"volume", volume.name}
+
+		quotaStatus := volume.inodeVolumeHandle.FetchQuotaStatus()
+
+		metrics.add("proxyfs_volume_used_bytes", "Bytes referenced by the volume's inodes", metricTypeGauge, float64(quotaStatus.BytesUsed), volumeLabel)
+		metrics.add("proxyfs_volume_used_inodes", "Inodes in the volume", metricTypeGauge, float64(quotaStatus.InodesUsed), volumeLabel)
+		metrics.add("proxyfs_volume_quota_limit_bytes", "Quota limit on the volume's bytes (0 means unlimited)", metricTypeGauge, float64(quotaStatus.Limits.SoftBytes),
+			volumeLabel, metricLabelStruct{"limit", "soft"})
+		metrics.add("proxyfs_volume_quota_limit_bytes", "Quota limit on the volume's bytes (0 means unlimited)", metricTypeGauge, float64(quotaStatus.Limits.HardBytes),
+			volumeLabel, metricLabelStruct{"limit", "hard"})
+		metrics.add("proxyfs_volume_quota_limit_inodes", "Quota limit on the volume's inodes (0 means unlimited)", metricTypeGauge, float64(quotaStatus.Limits.SoftInodes),
+			volumeLabel, metricLabelStruct{"limit", "soft"})
+		metrics.add("proxyfs_volume_quota_limit_inodes", "Quota limit on the volume's inodes (0 means unlimited)", metricTypeGauge, float64(quotaStatus.Limits.HardInodes),
+			volumeLabel, metricLabelStruct{"limit", "hard"})
+		metrics.add("proxyfs_volume_quota_soft_limit_exceeded", "Whether (1) or not (0) the volume's soft quota limit is exceeded", metricTypeGauge, boolToFloat64(!quotaStatus.SoftBytesExceededSince.IsZero()),
+			volumeLabel, metricLabelStruct{"resource", "bytes"})
+		metrics.add("proxyfs_volume_quota_soft_limit_exceeded", "Whether (1) or not (0) the volume's soft quota limit is exceeded", metricTypeGauge, boolToFloat64(!quotaStatus.SoftInodesExceededSince.IsZero()),
+			volumeLabel, metricLabelStruct{"resource", "inodes"})
+
+		checkpointStatus := volume.headhunterHandle.FetchCheckpointStatus()
+
+		metrics.add("proxyfs_volume_checkpoint_degraded", "Whether (1) or not (0) the volume's most recent checkpoint failed", metricTypeGauge, boolToFloat64(checkpointStatus.Degraded), volumeLabel)
+		metrics.add("proxyfs_volume_checkpoint_failed_attempts", "Failed checkpoints since the volume's last successful one", metricTypeGauge, float64(checkpointStatus.FailedAttempts), volumeLabel)
+
+		defragmenterStatus := volume.inodeVolumeHandle.FetchDefragmenterStatus()
+
+		metrics.add("proxyfs_volume_defragmenter_running", "Whether (1) or not (0) the volume's defragmenter is running", metricTypeGauge, boolToFloat64(defragmenterStatus.Running), volumeLabel)
+		metrics.add("proxyfs_volume_defragmenter_passes_total", "Defragmenter passes completed since it was started", metricTypeCounter, float64(defragmenterStatus.PassesCompleted), volumeLabel)
+		metrics.add("proxyfs_volume_defragmenter_inodes_scanned_total", "Inodes scanned by the defragmenter since it was started", metricTypeCounter, float64(defragmenterStatus.InodesScanned), volumeLabel)
+		metrics.add("proxyfs_volume_defragmenter_inodes_optimized_total", "Inodes optimized by the defragmenter since it was started", metricTypeCounter, float64(defragmenterStatus.InodesOptimized), volumeLabel)
+		metrics.add("proxyfs_volume_defragmenter_optimize_failures_total", "Inodes the defragmenter failed to optimize since it was started", metricTypeCounter, float64(defragmenterStatus.OptimizeFailures), volumeLabel)
+		metrics.add("proxyfs_volume_defragmenter_rewritten_bytes_total", "Bytes rewritten by the defragmenter since it was started", metricTypeCounter, float64(defragmenterStatus.BytesRewritten), volumeLabel)
+	}
+}
+
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeLabelValue escapes backslash, double-quote, and line feed as the exposition format requires
+func escapeLabelValue(value string) string {
+	return strings.Replace(strings.Replace(strings.Replace(value, `\`, `\\`, -1), `"`, `\"`, -1), "\n", `\n`, -1)
+}
+
+// labelsToString renders labels (followed by extraLabel, if non-nil) as {name="value",...} (or "" if there are none)
+func labelsToString(labels []metricLabelStruct, extraLabel *metricLabelStruct) string {
+	if (0 == len(labels)) && (nil == extraLabel) {
+		return ""
+	}
+
+	labelStrings := make([]string, 0, len(labels)+1)
+	for _, label := range labels {
+		labelStrings = append(labelStrings, label.name+"=\""+escapeLabelValue(label.value)+"\"")
+	}
+	if nil != extraLabel {
+		labelStrings = append(labelStrings, extraLabel.name+"=\""+escapeLabelValue(extraLabel.value)+"\"")
+	}
+
+	return "{" + strings.Join(labelStrings, ",") + "}"
+}
+
+// write renders metrics with families (and, within each, label sets) sorted for stable output
+func (metrics *metricsStruct) write(w io.Writer) {
+	familyNames := make([]string, 0, len(metrics.familyMap))
+	for familyName := range metrics.familyMap {
+		familyNames = append(familyNames, familyName)
+	}
+	sort.Strings(familyNames)
+
+	for _, familyName := range familyNames {
+		family := metrics.familyMap[familyName]
+
+		fmt.Fprintf(w, "# HELP %v %v\n", family.name, strings.Replace(strings.Replace(family.help, `\`, `\\`, -1), "\n", `\n`, -1))
+		fmt.Fprintf(w, "# TYPE %v %v\n", family.name, family.metricType)
+
+		if metricTypeHistogram != family.metricType {
+			lines := make([]string, 0, len(family.samples))
+			for _, sample := range family.samples {
+				lines = append(lines, family.name+labelsToString(sample.labels, nil)+" "+formatMetricValue(sample.value)+"\n")
+			}
+			sort.Strings(lines)
+			for _, line := range lines {
+				_, _ = io.WriteString(w, line)
+			}
+			continue
+		}
+
+		sort.Slice(family.histograms, func(i, j int) bool {
+			return labelsToString(family.histograms[i].labels, nil) < labelsToString(family.histograms[j].labels, nil)
+		})
+
+		for _, histogram := range family.histograms {
+			upperBounds := make([]float64, 0, len(statBucketSuffixToUpperBound))
+			for _, upperBound := range statBucketSuffixToUpperBound {
+				upperBounds = append(upperBounds, upperBound)
+			}
+			sort.Float64s(upperBounds)
+
+			cumulativeCount := uint64(0)
+			for _, upperBound := range upperBounds {
+				cumulativeCount += histogram.buckets[upperBound]
+				leLabel := metricLabelStruct{"le", formatMetricValue(upperBound)}
+				fmt.Fprintf(w, "%v_bucket%v %v\n", family.name, labelsToString(histogram.labels, &leLabel), cumulativeCount)
+			}
+			fmt.Fprintf(w, "%v_sum%v %v\n", family.name, labelsToString(histogram.labels, nil), formatMetricValue(histogram.sum))
+			fmt.Fprintf(w, "%v_count%v %v\n", family.name, labelsToString(histogram.labels, nil), cumulativeCount)
+		}
+	}
+}
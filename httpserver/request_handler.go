@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"html"
 	"net/http"
-	"runtime"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/swiftstack/sortedmap"
 
+	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/utils"
@@ -74,7 +78,7 @@ func doGetOfIndexDotHTML(responseWriter http.ResponseWriter, request *http.Reque
 	_, _ = responseWriter.Write(utils.StringToByteSlice("  <body>\n"))
 	_, _ = responseWriter.Write(utils.StringToByteSlice("    <a href=\"/config\">Configuration Parameters</a>\n"))
 	_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
-	_, _ = responseWriter.Write(utils.StringToByteSlice("    <a href=\"/metrics\">StatsD/Prometheus Page</a>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    <a href=\"/metrics\">Prometheus Metrics</a>\n"))
 	_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
 	_, _ = responseWriter.Write(utils.StringToByteSlice("    <a href=\"/volume\">Volume Page</a>\n"))
 	_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
@@ -111,175 +115,54 @@ func doGetOfConfig(responseWriter http.ResponseWriter, request *http.Request) {
 }
 
 func doGetOfMetrics(responseWriter http.ResponseWriter, request *http.Request) {
-	var (
-		err                      error
-		format                   string
-		i                        int
-		keyAsKey                 sortedmap.Key
-		keyAsString              string
-		lenStatsLLRB             int
-		line                     string
-		longestStatKey           int
-		longestStatValueAsString int
-		memStats                 runtime.MemStats
-		ok                       bool
-		pauseNsAccumulator       uint64
-		statKey                  string
-		statsLLRB                sortedmap.LLRBTree
-		statsMap                 map[string]uint64
-		statValueAsString        string
-		statValueAsUint64        uint64
-		valueAsString            string
-		valueAsValue             sortedmap.Value
-	)
-
-	statsMap = stats.Dump()
-
-	runtime.ReadMemStats(&memStats)
-
-	// General statistics.
-	statsMap["go_runtime_MemStats_Alloc"] = memStats.Alloc
-	statsMap["go_runtime_MemStats_TotalAlloc"] = memStats.TotalAlloc
-	statsMap["go_runtime_MemStats_Sys"] = memStats.Sys
-	statsMap["go_runtime_MemStats_Lookups"] = memStats.Lookups
-	statsMap["go_runtime_MemStats_Mallocs"] = memStats.Mallocs
-	statsMap["go_runtime_MemStats_Frees"] = memStats.Frees
-
-	// Main allocation heap statistics.
-	statsMap["go_runtime_MemStats_HeapAlloc"] = memStats.HeapAlloc
-	statsMap["go_runtime_MemStats_HeapSys"] = memStats.HeapSys
-	statsMap["go_runtime_MemStats_HeapIdle"] = memStats.HeapIdle
-	statsMap["go_runtime_MemStats_HeapInuse"] = memStats.HeapInuse
-	statsMap["go_runtime_MemStats_HeapReleased"] = memStats.HeapReleased
-	statsMap["go_runtime_MemStats_HeapObjects"] = memStats.HeapObjects
-
-	// Low-level fixed-size structure allocator statistics.
-	//	Inuse is bytes used now.
-	//	Sys is bytes obtained from system.
-	statsMap["go_runtime_MemStats_StackInuse"] = memStats.StackInuse
-	statsMap["go_runtime_MemStats_StackSys"] = memStats.StackSys
-	statsMap["go_runtime_MemStats_MSpanInuse"] = memStats.MSpanInuse
-	statsMap["go_runtime_MemStats_MSpanSys"] = memStats.MSpanSys
-	statsMap["go_runtime_MemStats_MCacheInuse"] = memStats.MCacheInuse
-	statsMap["go_runtime_MemStats_MCacheSys"] = memStats.MCacheSys
-	statsMap["go_runtime_MemStats_BuckHashSys"] = memStats.BuckHashSys
-	statsMap["go_runtime_MemStats_GCSys"] = memStats.GCSys
-	statsMap["go_runtime_MemStats_OtherSys"] = memStats.OtherSys
-
-	// Garbage collector statistics (fixed portion).
-	statsMap["go_runtime_MemStats_LastGC"] = memStats.LastGC
-	statsMap["go_runtime_MemStats_PauseTotalNs"] = memStats.PauseTotalNs
-	statsMap["go_runtime_MemStats_NumGC"] = uint64(memStats.NumGC)
-	statsMap["go_runtime_MemStats_GCCPUPercentage"] = uint64(100.0 * memStats.GCCPUFraction)
-
-	// Garbage collector statistics (go_runtime_MemStats_PauseAverageNs).
-	if 0 == memStats.NumGC {
-		statsMap["go_runtime_MemStats_PauseAverageNs"] = 0
-	} else {
-		pauseNsAccumulator = 0
-		if memStats.NumGC < 255 {
-			for i = 0; i < int(memStats.NumGC); i++ {
-				pauseNsAccumulator += memStats.PauseNs[i]
-			}
-			statsMap["go_runtime_MemStats_PauseAverageNs"] = pauseNsAccumulator / uint64(memStats.NumGC)
-		} else {
-			for i = 0; i < 256; i++ {
-				pauseNsAccumulator += memStats.PauseNs[i]
-			}
-			statsMap["go_runtime_MemStats_PauseAverageNs"] = pauseNsAccumulator / 256
-		}
-	}
-
-	statsLLRB = sortedmap.NewLLRBTree(sortedmap.CompareString, nil)
-	for statKey, statValueAsUint64 = range statsMap {
-		statKey = strings.Replace(statKey, ".", "_", -1)
-		statKey = strings.Replace(statKey, "-", "_", -1)
-		if len(statKey) > longestStatKey {
-			longestStatKey = len(statKey)
-		}
-		statValueAsString = fmt.Sprintf("%v", statValueAsUint64)
-		if len(statValueAsString) > longestStatValueAsString {
-			longestStatValueAsString = len(statValueAsString)
-		}
-		ok, err = statsLLRB.Put(statKey, statValueAsString)
-		if nil != err {
-			err = fmt.Errorf("statsLLRB.Put(%v, %v) failed: %v", statKey, statValueAsString, err)
-			logger.Fatalf("HTTP Server Logic Error: %v", err)
-		}
-		if !ok {
-			err = fmt.Errorf("statsLLRB.Put(%v, %v) returned ok == false", statKey, statValueAsString)
-			logger.Fatalf("HTTP Server Logic Error: %v", err)
-		}
-	}
-
-	format = fmt.Sprintf("%%-%vs %%%vs\n", longestStatKey, longestStatValueAsString)
+	metrics := newMetrics()
 
-	lenStatsLLRB, err = statsLLRB.Len()
-	if nil != err {
-		err = fmt.Errorf("statsLLRB.Len()) failed: %v", err)
-		logger.Fatalf("HTTP Server Logic Error: %v", err)
-	}
-	if len(statsMap) != lenStatsLLRB {
-		err = fmt.Errorf("len(statsMap) != lenStatsLLRB")
-		logger.Fatalf("HTTP Server Logic Error: %v", err)
-	}
+	metrics.addStats(stats.Dump())
+	metrics.addMemStats()
+	metrics.addFastPath()
+	metrics.addVolumes()
 
-	responseWriter.Header().Set("Content-Type", "text/plain")
+	responseWriter.Header().Set("Content-Type", metricsContentType)
 	responseWriter.WriteHeader(http.StatusOK)
 
-	for i = 0; i < lenStatsLLRB; i++ {
-		keyAsKey, valueAsValue, ok, err = statsLLRB.GetByIndex(i)
-		if nil != err {
-			err = fmt.Errorf("statsLLRB.GetByIndex(%v) failed: %v", i, err)
-			logger.Fatalf("HTTP Server Logic Error: %v", err)
-		}
-		if !ok {
-			err = fmt.Errorf("statsLLRB.GetByIndex(%v) returned ok == false", i)
-			logger.Fatalf("HTTP Server Logic Error: %v", err)
-		}
-		keyAsString = keyAsKey.(string)
-		valueAsString = valueAsValue.(string)
-		line = fmt.Sprintf(format, keyAsString, valueAsString)
-		_, _ = responseWriter.Write(utils.StringToByteSlice(line))
-	}
+	metrics.write(responseWriter)
 }
 
 func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 	var (
-		acceptHeader              string
-		err                       error
-		formatResponseAsJSON      bool
-		formatResponseCompactly   bool
-		fsckCompletedJobNoError   fsckCompletedJobNoErrorStruct
-		fsckCompletedJobWithError fsckCompletedJobWithErrorStruct
-		fsckHaltedJob             fsckHaltedJobStruct
-		fsckInactive              bool
-		fsckJob                   *fsckJobStruct
-		fsckJobAsValue            sortedmap.Value
-		fsckJobID                 uint64
-		fsckJobIDAsKey            sortedmap.Key
-		fsckJobsIDListJSON        bytes.Buffer
-		fsckJobsIDListJSONPacked  []byte
-		fsckJobStatusJSON         bytes.Buffer
-		fsckJobStatusJSONPacked   []byte
-		fsckJobsCount             int
-		fsckJobsIDList            []uint64
-		fsckJobsIDListIndex       int
-		fsckJobsIndex             int
-		fsckRunningJob            fsckRunningJobStruct
-		numPathParts              int
-		ok                        bool
-		paramList                 []string
-		pathSplit                 []string
-		volume                    *volumeStruct
-		volumeAsValue             sortedmap.Value
-		volumeList                []string
-		volumeListIndex           int
-		volumeListJSON            bytes.Buffer
-		volumeListJSONPacked      []byte
-		volumeListLen             int
-		volumeName                string
-		volumeNameAsKey           sortedmap.Key
+		acceptHeader             string
+		err                      error
+		formatResponseAsJSON     bool
+		formatResponseCompactly  bool
+		fsckFinding              fsckFindingStruct
+		fsckInactive             bool
+		fsckJob                  *fsckJobStruct
+		fsckJobAsValue           sortedmap.Value
+		fsckJobID                uint64
+		fsckJobIDAsKey           sortedmap.Key
+		fsckJobsIDListJSON       bytes.Buffer
+		fsckJobsIDListJSONPacked []byte
+		fsckJobStatus            fsckJobStatusStruct
+		fsckJobStatusJSON        bytes.Buffer
+		fsckJobStatusJSONPacked  []byte
+		fsckJobsCount            int
+		fsckJobsIDList           []uint64
+		fsckJobsIDListIndex      int
+		fsckJobsIndex            int
+		numPathParts             int
+		ok                       bool
+		paramList                []string
+		pathSplit                []string
+		quotaStatus              inode.QuotaStatus
+		volume                   *volumeStruct
+		volumeAsValue            sortedmap.Value
+		volumeList               []string
+		volumeListIndex          int
+		volumeListJSON           bytes.Buffer
+		volumeListJSONPacked     []byte
+		volumeListLen            int
+		volumeName               string
+		volumeNameAsKey          sortedmap.Key
 	)
 
 	pathSplit = strings.Split(request.URL.Path, "/") // leading  "/" places "" in pathSplit[0]
@@ -295,8 +178,12 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 		// Form: /volume
 	case 3:
 		// Form: /volume/<volume-name/fsck-job
+		// Form: /volume/<volume-name/scrub-job
+		// Form: /volume/<volume-name/defrag
+		// Form: /volume/<volume-name/snapshot
 	case 4:
 		// Form: /volume/<volume-name/fsck-job/<job-id>
+		// Form: /volume/<volume-name/scrub-job/<job-id>
 	default:
 		responseWriter.WriteHeader(http.StatusNotFound)
 		return
@@ -370,14 +257,42 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			_, _ = responseWriter.Write(utils.StringToByteSlice("    <title>Volumes</title>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("  </head>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("  <body>\n"))
-			for volumeListIndex, volumeName = range volumeList {
-				if 0 < volumeListIndex {
-					_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Volume</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Checkpoint Status</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Bytes Used</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Soft Bytes Limit</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Hard Bytes Limit</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Inodes Used</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Soft Inodes Limit</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Hard Inodes Limit</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			for _, volumeName = range volumeList {
+				volumeAsValue, ok, err = globals.volumeLLRB.GetByKey(volumeName)
+				if nil != err {
+					logger.Fatalf("HTTP Server Logic Error: %v", err)
 				}
-				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <a href=\"/volume/%v/fsck-job\">\n", volumeName)))
-				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("      %v\n", volumeName)))
-				_, _ = responseWriter.Write(utils.StringToByteSlice("    </a>\n"))
+				if !ok {
+					err = fmt.Errorf("httpserver.doGetOfVolume() lookup of globals.volumeLLRB failed")
+					logger.Fatalf("HTTP Server Logic Error: %v", err)
+				}
+				volume = volumeAsValue.(*volumeStruct)
+
+				quotaStatus = volume.inodeVolumeHandle.FetchQuotaStatus()
+
+				_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td><a href=\"/volume/%v/fsck-job\">%v</a></td>\n", volumeName, volumeName)))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", checkpointStatusToString(volume.headhunterHandle.FetchCheckpointStatus()))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", quotaStatus.BytesUsed)))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", quotaLimitToString(quotaStatus.Limits.SoftBytes, quotaStatus.SoftBytesExceededSince))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", quotaLimitToString(quotaStatus.Limits.HardBytes, time.Time{}))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", quotaStatus.InodesUsed)))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", quotaLimitToString(quotaStatus.Limits.SoftInodes, quotaStatus.SoftInodesExceededSince))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", quotaLimitToString(quotaStatus.Limits.HardInodes, time.Time{}))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
 			}
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
 		}
@@ -399,6 +314,45 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 	}
 	volume = volumeAsValue.(*volumeStruct)
 
+	if "defrag" == pathSplit[3] {
+		if 3 != numPathParts {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		doGetOfVolumeDefrag(responseWriter, volume, formatResponseAsJSON, formatResponseCompactly)
+		return
+	}
+
+	if "quota" == pathSplit[3] {
+		if 3 != numPathParts {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		doGetOfVolumeQuota(responseWriter, volume, formatResponseCompactly)
+		return
+	}
+
+	if "snapshot" == pathSplit[3] {
+		if 3 != numPathParts {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		doGetOfVolumeSnapshot(responseWriter, volume, formatResponseAsJSON, formatResponseCompactly)
+		return
+	}
+
+	if "scrub-job" == pathSplit[3] {
+		if 3 == numPathParts {
+			doGetOfVolumeScrubJobs(responseWriter, volume, formatResponseAsJSON, formatResponseCompactly)
+		} else {
+			doGetOfVolumeScrubJob(responseWriter, volume, pathSplit[4], formatResponseAsJSON, formatResponseCompactly)
+		}
+		return
+	}
+
 	volume.Lock()
 
 	if "fsck-job" != pathSplit[3] {
@@ -491,6 +445,9 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/fsck-job\">\n", volumeName)))
 				_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Start\">\n"))
 				_, _ = responseWriter.Write(utils.StringToByteSlice("    </form>\n"))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/fsck-job?dry-run=true\">\n", volumeName)))
+				_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Start Dry Run\">\n"))
+				_, _ = responseWriter.Write(utils.StringToByteSlice("    </form>\n"))
 			}
 			_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
@@ -536,26 +493,7 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 		responseWriter.Header().Set("Content-Type", "application/json")
 		responseWriter.WriteHeader(http.StatusOK)
 
-		switch fsckJob.state {
-		case fsckJobRunning:
-			fsckRunningJob.StartTime = fsckJob.startTime.String()
-			fsckJobStatusJSONPacked, err = json.Marshal(fsckRunningJob)
-		case fsckJobHalted:
-			fsckHaltedJob.StartTime = fsckJob.startTime.String()
-			fsckHaltedJob.HaltTime = fsckJob.endTime.String()
-			fsckJobStatusJSONPacked, err = json.Marshal(fsckHaltedJob)
-		case fsckJobCompleted:
-			if nil == fsckJob.err {
-				fsckCompletedJobNoError.StartTime = fsckJob.startTime.String()
-				fsckCompletedJobNoError.DoneTime = fsckJob.endTime.String()
-				fsckJobStatusJSONPacked, err = json.Marshal(fsckCompletedJobNoError)
-			} else {
-				fsckCompletedJobWithError.StartTime = fsckJob.startTime.String()
-				fsckCompletedJobWithError.DoneTime = fsckJob.endTime.String()
-				fsckCompletedJobWithError.Error = fsckJob.err.Error()
-				fsckJobStatusJSONPacked, err = json.Marshal(fsckCompletedJobWithError)
-			}
-		}
+		fsckJobStatusJSONPacked, err = json.Marshal(fsckJob.status())
 		if nil != err {
 			logger.Fatalf("HTTP Server Logic Error: %v", err)
 		}
@@ -584,6 +522,7 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>State</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Running</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			writeHTMLTableRow(responseWriter, "Mode", fsckJobModeToString(fsckJob.repair))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Start Time</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", fsckJob.startTime.String())))
@@ -599,6 +538,7 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>State</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Halted</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			writeHTMLTableRow(responseWriter, "Mode", fsckJobModeToString(fsckJob.repair))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Start Time</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", fsckJob.startTime.String())))
@@ -607,6 +547,9 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Halt Time</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", fsckJob.endTime.String())))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			if nil != fsckJob.report {
+				writeHTMLTableRow(responseWriter, "Inodes Validated", fmt.Sprintf("%v", fsckJob.report.InodesValidated))
+			}
 			_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
 		case fsckJobCompleted:
 			_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
@@ -614,6 +557,7 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>State</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Completed</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			writeHTMLTableRow(responseWriter, "Mode", fsckJobModeToString(fsckJob.repair))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Start Time</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", fsckJob.startTime.String())))
@@ -622,6 +566,9 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Done Time</td>\n"))
 			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", fsckJob.endTime.String())))
 			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			if nil != fsckJob.report {
+				writeHTMLTableRow(responseWriter, "Inodes Validated", fmt.Sprintf("%v", fsckJob.report.InodesValidated))
+			}
 			if nil == fsckJob.err {
 				_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
 				_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>Errors</td>\n"))
@@ -635,6 +582,30 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 			}
 			_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
 		}
+		if (fsckJobRunning != fsckJob.state) && (nil != fsckJob.report) && (0 < len(fsckJob.report.Findings)) {
+			fsckJobStatus = fsckJob.status()
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Inode Number</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Path</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Problem</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Detail</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Action</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Repaired</th>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			for _, fsckFinding = range fsckJobStatus.Findings {
+				_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", fsckFinding.InodeNumber)))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(fsckFinding.Path))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(fsckFinding.Problem))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(fsckFinding.Detail))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(fsckFinding.Action))))
+				_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", fsckFinding.Repaired)))
+				_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+			}
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
+		}
 		_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
 		_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
 	}
@@ -644,6 +615,7 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 
 func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 	var (
+		dryRun         bool
 		err            error
 		fsckJob        *fsckJobStruct
 		fsckJobAsValue sortedmap.Value
@@ -651,6 +623,7 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 		fsckJobsCount  int
 		numPathParts   int
 		ok             bool
+		paramList      []string
 		pathSplit      []string
 		volume         *volumeStruct
 		volumeAsValue  sortedmap.Value
@@ -668,8 +641,16 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 	switch numPathParts {
 	case 3:
 		// Form: /volume/<volume-name/fsck-job
+		// Form: /volume/<volume-name/scrub-job
 	case 4:
 		// Form: /volume/<volume-name/fsck-job/<job-id>
+		// Form: /volume/<volume-name/scrub-job/<job-id>
+		// Form: /volume/<volume-name/defrag/start
+		// Form: /volume/<volume-name/defrag/stop
+		// Form: /volume/<volume-name/snapshot/<snapshot-name>
+	case 5:
+		// Form: /volume/<volume-name/scrub-job/<job-id>/delete
+		// Form: /volume/<volume-name/snapshot/<snapshot-name>/delete
 	default:
 		responseWriter.WriteHeader(http.StatusNotFound)
 		return
@@ -687,6 +668,45 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 	}
 	volume = volumeAsValue.(*volumeStruct)
 
+	if "defrag" == pathSplit[3] {
+		if 4 != numPathParts {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		doPostOfVolumeDefrag(responseWriter, volume, pathSplit[4])
+		return
+	}
+
+	if "snapshot" == pathSplit[3] {
+		if 4 == numPathParts {
+			doPostOfVolumeSnapshotCreate(responseWriter, volume, pathSplit[4])
+		} else if "delete" == pathSplit[5] {
+			doPostOfVolumeSnapshotDelete(responseWriter, volume, pathSplit[4])
+		} else {
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	if "scrub-job" == pathSplit[3] {
+		if 3 == numPathParts {
+			doPostOfVolumeScrubJobStart(responseWriter, volume)
+		} else if 4 == numPathParts {
+			doPostOfVolumeScrubJobStop(responseWriter, volume, pathSplit[4])
+		} else if "delete" == pathSplit[5] {
+			doPostOfVolumeScrubJobDelete(responseWriter, volume, pathSplit[4])
+		} else {
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	if 4 < numPathParts {
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	volume.Lock()
 
 	if "fsck-job" != pathSplit[3] {
@@ -734,8 +754,14 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 			}
 		}
 
+		// Unless a dry run is requested, problems found are repaired
+
+		paramList, ok = request.URL.Query()["dry-run"]
+		dryRun = (ok && (0 < len(paramList)) && (("true" == paramList[0]) || ("1" == paramList[0])))
+
 		fsckJob = &fsckJobStruct{
 			volume:    volume,
+			repair:    !dryRun,
 			stopChan:  make(chan bool, 1),
 			errChan:   make(chan error, 1),
 			state:     fsckJobRunning,
@@ -758,7 +784,7 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 
 		volume.fsckActiveJob = fsckJob
 
-		go fs.ValidateVolume(volumeName, fsckJob.stopChan, fsckJob.errChan)
+		go fsckJob.run()
 
 		volume.Unlock()
 
@@ -810,3 +836,889 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 
 	responseWriter.WriteHeader(http.StatusNoContent)
 }
+
+func doGetOfVolumeDefrag(responseWriter http.ResponseWriter, volume *volumeStruct, formatResponseAsJSON bool, formatResponseCompactly bool) {
+	var (
+		defragStatus           defragStatusStruct
+		defragStatusJSON       bytes.Buffer
+		defragStatusJSONPacked []byte
+		defragmenterStatus     inode.DefragmenterStatus
+		err                    error
+	)
+
+	defragmenterStatus = volume.inodeVolumeHandle.FetchDefragmenterStatus()
+
+	defragStatus = defragStatusStruct{
+		Running:           defragmenterStatus.Running,
+		StartTime:         timeToStringIfNonZero(defragmenterStatus.StartTime),
+		StopTime:          timeToStringIfNonZero(defragmenterStatus.StopTime),
+		LastPassStartTime: timeToStringIfNonZero(defragmenterStatus.LastPassStartTime),
+		LastPassEndTime:   timeToStringIfNonZero(defragmenterStatus.LastPassEndTime),
+		PassesCompleted:   defragmenterStatus.PassesCompleted,
+		InodesScanned:     defragmenterStatus.InodesScanned,
+		InodesOptimized:   defragmenterStatus.InodesOptimized,
+		OptimizeFailures:  defragmenterStatus.OptimizeFailures,
+		BytesRewritten:    defragmenterStatus.BytesRewritten,
+	}
+
+	if formatResponseAsJSON {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(http.StatusOK)
+
+		defragStatusJSONPacked, err = json.Marshal(defragStatus)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+
+		if formatResponseCompactly {
+			_, _ = responseWriter.Write(defragStatusJSONPacked)
+		} else {
+			json.Indent(&defragStatusJSON, defragStatusJSONPacked, "", "\t")
+			_, _ = responseWriter.Write(defragStatusJSON.Bytes())
+			_, _ = responseWriter.Write(utils.StringToByteSlice("\n"))
+		}
+
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/html")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<!DOCTYPE html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <title>%v Defragmenter</title>\n", volume.name)))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
+	if defragStatus.Running {
+		writeHTMLTableRow(responseWriter, "State", "Running")
+	} else {
+		writeHTMLTableRow(responseWriter, "State", "Stopped")
+	}
+	writeHTMLTableRow(responseWriter, "Start Time", defragStatus.StartTime)
+	if !defragStatus.Running {
+		writeHTMLTableRow(responseWriter, "Stop Time", defragStatus.StopTime)
+	}
+	writeHTMLTableRow(responseWriter, "Last Pass Start Time", defragStatus.LastPassStartTime)
+	writeHTMLTableRow(responseWriter, "Last Pass End Time", defragStatus.LastPassEndTime)
+	writeHTMLTableRow(responseWriter, "Passes Completed", fmt.Sprintf("%v", defragStatus.PassesCompleted))
+	writeHTMLTableRow(responseWriter, "Inodes Scanned", fmt.Sprintf("%v", defragStatus.InodesScanned))
+	writeHTMLTableRow(responseWriter, "Inodes Optimized", fmt.Sprintf("%v", defragStatus.InodesOptimized))
+	writeHTMLTableRow(responseWriter, "Optimize Failures", fmt.Sprintf("%v", defragStatus.OptimizeFailures))
+	writeHTMLTableRow(responseWriter, "Bytes Rewritten", fmt.Sprintf("%v", defragStatus.BytesRewritten))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+	if defragStatus.Running {
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/defrag/stop\">\n", volume.name)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Stop\">\n"))
+	} else {
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/defrag/start\">\n", volume.name)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Start\">\n"))
+	}
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    </form>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
+}
+
+// doGetOfVolumeQuota always responds with JSON as it is intended for consumption by quota reporting tools.
+func doGetOfVolumeQuota(responseWriter http.ResponseWriter, volume *volumeStruct, formatResponseCompactly bool) {
+	var (
+		err                   error
+		groupID               inode.InodeGroupID
+		groupQuotaStatus      map[inode.InodeGroupID]inode.OwnerQuotaStatus
+		ownerQuotaStatus      inode.OwnerQuotaStatus
+		quotaStatus           inode.QuotaStatus
+		userID                inode.InodeUserID
+		userQuotaStatus       map[inode.InodeUserID]inode.OwnerQuotaStatus
+		volumeQuota           volumeQuotaStruct
+		volumeQuotaJSON       bytes.Buffer
+		volumeQuotaJSONPacked []byte
+	)
+
+	quotaStatus = volume.inodeVolumeHandle.FetchQuotaStatus()
+	userQuotaStatus, groupQuotaStatus = volume.inodeVolumeHandle.FetchOwnerQuotaStatus()
+
+	volumeQuota = volumeQuotaStruct{
+		BytesUsed:               quotaStatus.BytesUsed,
+		SoftBytes:               quotaStatus.Limits.SoftBytes,
+		HardBytes:               quotaStatus.Limits.HardBytes,
+		SoftBytesExceededSince:  timeToStringIfNonZero(quotaStatus.SoftBytesExceededSince),
+		InodesUsed:              quotaStatus.InodesUsed,
+		SoftInodes:              quotaStatus.Limits.SoftInodes,
+		HardInodes:              quotaStatus.Limits.HardInodes,
+		SoftInodesExceededSince: timeToStringIfNonZero(quotaStatus.SoftInodesExceededSince),
+		GracePeriod:             quotaStatus.Limits.GracePeriod.String(),
+		Users:                   make([]ownerQuotaStruct, 0, len(userQuotaStatus)),
+		Groups:                  make([]ownerQuotaStruct, 0, len(groupQuotaStatus)),
+	}
+
+	for userID, ownerQuotaStatus = range userQuotaStatus {
+		volumeQuota.Users = append(volumeQuota.Users, ownerQuotaStatusToOwnerQuotaStruct(uint32(userID), ownerQuotaStatus))
+	}
+	sort.Slice(volumeQuota.Users, func(i, j int) bool { return volumeQuota.Users[i].ID < volumeQuota.Users[j].ID })
+
+	for groupID, ownerQuotaStatus = range groupQuotaStatus {
+		volumeQuota.Groups = append(volumeQuota.Groups, ownerQuotaStatusToOwnerQuotaStruct(uint32(groupID), ownerQuotaStatus))
+	}
+	sort.Slice(volumeQuota.Groups, func(i, j int) bool { return volumeQuota.Groups[i].ID < volumeQuota.Groups[j].ID })
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	volumeQuotaJSONPacked, err = json.Marshal(volumeQuota)
+	if nil != err {
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+
+	if formatResponseCompactly {
+		_, _ = responseWriter.Write(volumeQuotaJSONPacked)
+	} else {
+		json.Indent(&volumeQuotaJSON, volumeQuotaJSONPacked, "", "\t")
+		_, _ = responseWriter.Write(volumeQuotaJSON.Bytes())
+		_, _ = responseWriter.Write(utils.StringToByteSlice("\n"))
+	}
+}
+
+func ownerQuotaStatusToOwnerQuotaStruct(ownerID uint32, ownerQuotaStatus inode.OwnerQuotaStatus) (ownerQuota ownerQuotaStruct) {
+	ownerQuota = ownerQuotaStruct{
+		ID:         ownerID,
+		BytesUsed:  ownerQuotaStatus.BytesUsed,
+		HardBytes:  ownerQuotaStatus.Limits.HardBytes,
+		InodesUsed: ownerQuotaStatus.InodesUsed,
+		HardInodes: ownerQuotaStatus.Limits.HardInodes,
+	}
+	return
+}
+
+func doPostOfVolumeDefrag(responseWriter http.ResponseWriter, volume *volumeStruct, action string) {
+	var (
+		defragmenterStatus inode.DefragmenterStatus
+		err                error
+	)
+
+	defragmenterStatus = volume.inodeVolumeHandle.FetchDefragmenterStatus()
+
+	switch action {
+	case "start":
+		if defragmenterStatus.Running {
+			responseWriter.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		err = volume.inodeVolumeHandle.StartDefragmenter()
+	case "stop":
+		if !defragmenterStatus.Running {
+			responseWriter.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		err = volume.inodeVolumeHandle.StopDefragmenter()
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if nil != err {
+		// Lost a race with some other start or stop request
+		responseWriter.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func doGetOfVolumeSnapshot(responseWriter http.ResponseWriter, volume *volumeStruct, formatResponseAsJSON bool, formatResponseCompactly bool) {
+	var (
+		err                    error
+		snapshot               snapshotStruct
+		snapshotInfo           inode.SnapshotInfo
+		snapshotList           []snapshotStruct
+		snapshotListJSON       bytes.Buffer
+		snapshotListJSONPacked []byte
+	)
+
+	snapshotList = make([]snapshotStruct, 0)
+
+	for _, snapshotInfo = range volume.inodeVolumeHandle.FetchSnapshotList() {
+		snapshotList = append(snapshotList, snapshotStruct{
+			ID:           snapshotInfo.ID,
+			Name:         snapshotInfo.Name,
+			CreationTime: snapshotInfo.CreationTime.String(),
+		})
+	}
+
+	if formatResponseAsJSON {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(http.StatusOK)
+
+		snapshotListJSONPacked, err = json.Marshal(snapshotList)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+
+		if formatResponseCompactly {
+			_, _ = responseWriter.Write(snapshotListJSONPacked)
+		} else {
+			json.Indent(&snapshotListJSON, snapshotListJSONPacked, "", "\t")
+			_, _ = responseWriter.Write(snapshotListJSON.Bytes())
+			_, _ = responseWriter.Write(utils.StringToByteSlice("\n"))
+		}
+
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/html")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<!DOCTYPE html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <title>%v Snapshots</title>\n", volume.name)))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>ID</th>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Name</th>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Creation Time</th>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("        <th></th>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+	for _, snapshot = range snapshotList {
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", snapshot.ID)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(snapshot.Name))))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", snapshot.CreationTime)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("        <td>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("          <form method=\"post\" action=\"/volume/%v/snapshot/%v/delete\">\n", volume.name, html.EscapeString(url.PathEscape(snapshot.Name)))))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("            <input type=\"submit\" value=\"Delete\">\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("          </form>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("        </td>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+	}
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
+}
+
+func doPostOfVolumeSnapshotCreate(responseWriter http.ResponseWriter, volume *volumeStruct, snapshotName string) {
+	var (
+		err error
+	)
+
+	_, err = volume.inodeVolumeHandle.CreateSnapshot(snapshotName)
+	if nil != err {
+		responseWriter.WriteHeader(snapshotErrorToHTTPStatus(err))
+		return
+	}
+
+	responseWriter.Header().Set("Location", fmt.Sprintf("/volume/%v/snapshot", volume.name))
+	responseWriter.WriteHeader(http.StatusCreated)
+}
+
+func doPostOfVolumeSnapshotDelete(responseWriter http.ResponseWriter, volume *volumeStruct, snapshotName string) {
+	var (
+		err error
+	)
+
+	err = volume.inodeVolumeHandle.DeleteSnapshot(snapshotName)
+	if nil != err {
+		responseWriter.WriteHeader(snapshotErrorToHTTPStatus(err))
+		return
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func snapshotErrorToHTTPStatus(err error) (httpStatus int) {
+	switch {
+	case blunder.Is(err, blunder.InvalidArgError):
+		httpStatus = http.StatusBadRequest
+	case blunder.Is(err, blunder.NotFoundError):
+		httpStatus = http.StatusNotFound
+	case blunder.Is(err, blunder.FileExistsError):
+		httpStatus = http.StatusConflict
+	default:
+		logger.ErrorWithError(err)
+		httpStatus = http.StatusInternalServerError
+	}
+	return
+}
+
+// doGetOfVolumeScrubJobs lists the volume's scrub jobs (most recent first).
+func doGetOfVolumeScrubJobs(responseWriter http.ResponseWriter, volume *volumeStruct, formatResponseAsJSON bool, formatResponseCompactly bool) {
+	var (
+		err                       error
+		ok                        bool
+		scrubInactive             bool
+		scrubJobID                uint64
+		scrubJobIDAsKey           sortedmap.Key
+		scrubJobsCount            int
+		scrubJobsIDList           []uint64
+		scrubJobsIDListIndex      int
+		scrubJobsIDListJSON       bytes.Buffer
+		scrubJobsIDListJSONPacked []byte
+		scrubJobsIndex            int
+	)
+
+	volume.Lock()
+
+	scrubJobsCount, err = volume.scrubJobs.Len()
+	if nil != err {
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+
+	scrubJobsIDList = make([]uint64, 0, scrubJobsCount)
+	for scrubJobsIndex = scrubJobsCount - 1; scrubJobsIndex >= 0; scrubJobsIndex-- {
+		scrubJobIDAsKey, _, ok, err = volume.scrubJobs.GetByIndex(scrubJobsIndex)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+		if !ok {
+			err = fmt.Errorf("httpserver.doGetOfVolumeScrubJobs() indexing volume.scrubJobs failed")
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+		scrubJobID = scrubJobIDAsKey.(uint64)
+
+		scrubJobsIDList = append(scrubJobsIDList, scrubJobID)
+	}
+
+	scrubInactive = !volume.pollScrubActiveJobWhileLocked()
+
+	volume.Unlock()
+
+	if formatResponseAsJSON {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(http.StatusOK)
+
+		scrubJobsIDListJSONPacked, err = json.Marshal(scrubJobsIDList)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+
+		if formatResponseCompactly {
+			_, _ = responseWriter.Write(scrubJobsIDListJSONPacked)
+		} else {
+			json.Indent(&scrubJobsIDListJSON, scrubJobsIDListJSONPacked, "", "\t")
+			_, _ = responseWriter.Write(scrubJobsIDListJSON.Bytes())
+			_, _ = responseWriter.Write(utils.StringToByteSlice("\n"))
+		}
+
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/html")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<!DOCTYPE html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <title>%v Scrub Jobs</title>\n", volume.name)))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <body>\n"))
+	for scrubJobsIDListIndex, scrubJobID = range scrubJobsIDList {
+		if 0 < scrubJobsIDListIndex {
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+		}
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <a href=\"/volume/%v/scrub-job/%v\">\n", volume.name, scrubJobID)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("      %v\n", scrubJobID)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    </a>\n"))
+	}
+	if scrubInactive {
+		if 0 < scrubJobsCount {
+			_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+		}
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/scrub-job\">\n", volume.name)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Start Dry Run\">\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    </form>\n"))
+	}
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
+}
+
+func doGetOfVolumeScrubJob(responseWriter http.ResponseWriter, volume *volumeStruct, scrubJobIDAsString string, formatResponseAsJSON bool, formatResponseCompactly bool) {
+	var (
+		err                     error
+		orphan                  scrubOrphanStruct
+		scrubActive             bool
+		scrubJob                *scrubJobStruct
+		scrubJobStatus          scrubJobStatusStruct
+		scrubJobStatusJSON      bytes.Buffer
+		scrubJobStatusJSONBytes []byte
+	)
+
+	volume.Lock()
+
+	scrubActive = volume.pollScrubActiveJobWhileLocked()
+
+	scrubJob = volume.fetchScrubJobWhileLocked(scrubJobIDAsString)
+	if nil == scrubJob {
+		volume.Unlock()
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	scrubJobStatus = scrubJob.status()
+
+	volume.Unlock()
+
+	if formatResponseAsJSON {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(http.StatusOK)
+
+		scrubJobStatusJSONBytes, err = json.Marshal(scrubJobStatus)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+
+		if formatResponseCompactly {
+			_, _ = responseWriter.Write(scrubJobStatusJSONBytes)
+		} else {
+			json.Indent(&scrubJobStatusJSON, scrubJobStatusJSONBytes, "", "\t")
+			_, _ = responseWriter.Write(scrubJobStatusJSON.Bytes())
+			_, _ = responseWriter.Write(utils.StringToByteSlice("\n"))
+		}
+
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/html")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<!DOCTYPE html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("<html>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <title>%v Scrub Job %v</title>\n", volume.name, scrubJob.id)))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </head>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  <body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
+	writeHTMLTableRow(responseWriter, "State", scrubJobStatus.State)
+	if scrubJobStatus.DryRun {
+		writeHTMLTableRow(responseWriter, "Type", "Dry Run")
+	} else {
+		writeHTMLTableRow(responseWriter, "Type", fmt.Sprintf("Delete Orphans of Dry Run %v", scrubJobStatus.DryRunJobID))
+	}
+	writeHTMLTableRow(responseWriter, "Start Time", scrubJobStatus.StartTime)
+	if "" != scrubJobStatus.HaltTime {
+		writeHTMLTableRow(responseWriter, "Halt Time", scrubJobStatus.HaltTime)
+	}
+	if "" != scrubJobStatus.DoneTime {
+		writeHTMLTableRow(responseWriter, "Done Time", scrubJobStatus.DoneTime)
+	}
+	if fsckJobRunning != scrubJob.state {
+		if "" == scrubJobStatus.Error {
+			writeHTMLTableRow(responseWriter, "Errors", "None")
+		} else {
+			writeHTMLTableRow(responseWriter, "Errors", scrubJobStatus.Error)
+		}
+		if scrubJobStatus.DryRun {
+			writeHTMLTableRow(responseWriter, "Containers Scanned", fmt.Sprintf("%v", scrubJobStatus.ContainersScanned))
+		}
+		writeHTMLTableRow(responseWriter, "Objects Scanned", fmt.Sprintf("%v", scrubJobStatus.ObjectsScanned))
+		if scrubJobStatus.DryRun {
+			writeHTMLTableRow(responseWriter, "Orphans Found", fmt.Sprintf("%v", len(scrubJobStatus.Orphans)))
+		} else {
+			writeHTMLTableRow(responseWriter, "Orphans Deleted", fmt.Sprintf("%v", len(scrubJobStatus.Orphans)))
+			writeHTMLTableRow(responseWriter, "Delete Failures", fmt.Sprintf("%v", scrubJobStatus.DeleteFailures))
+		}
+		writeHTMLTableRow(responseWriter, "Orphaned Bytes", fmt.Sprintf("%v", scrubJobStatus.OrphanedBytes))
+	}
+	_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
+	if 0 < len(scrubJobStatus.Orphans) {
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    <table>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Container</th>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Object</th>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("        <th>Bytes</th>\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+		for _, orphan = range scrubJobStatus.Orphans {
+			_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(orphan.ContainerName))))
+			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", html.EscapeString(orphan.ObjectName))))
+			_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%v</td>\n", orphan.ObjectLength)))
+			_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+		}
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    </table>\n"))
+	}
+	if fsckJobRunning == scrubJob.state {
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/scrub-job/%v\">\n", volume.name, scrubJob.id)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Stop\">\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    </form>\n"))
+	} else if !scrubActive && scrubJob.deletable() {
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    <br />\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("    <form method=\"post\" action=\"/volume/%v/scrub-job/%v/delete\">\n", volume.name, scrubJob.id)))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("      <input type=\"submit\" value=\"Delete Orphans\">\n"))
+		_, _ = responseWriter.Write(utils.StringToByteSlice("    </form>\n"))
+	}
+	_, _ = responseWriter.Write(utils.StringToByteSlice("  </body>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("</html>\n"))
+}
+
+// doPostOfVolumeScrubJobStart starts a dry run... nothing is deleted until a completed dry run is confirmed.
+func doPostOfVolumeScrubJobStart(responseWriter http.ResponseWriter, volume *volumeStruct) {
+	var (
+		scrubJob *scrubJobStruct
+	)
+
+	volume.Lock()
+
+	if volume.pollScrubActiveJobWhileLocked() {
+		volume.Unlock()
+		responseWriter.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	scrubJob = volume.startScrubJobWhileLocked(nil)
+
+	volume.Unlock()
+
+	responseWriter.Header().Set("Location", fmt.Sprintf("/volume/%v/scrub-job/%v", volume.name, scrubJob.id))
+	responseWriter.WriteHeader(http.StatusCreated)
+}
+
+func doPostOfVolumeScrubJobStop(responseWriter http.ResponseWriter, volume *volumeStruct, scrubJobIDAsString string) {
+	var (
+		scrubJob *scrubJobStruct
+	)
+
+	volume.Lock()
+
+	scrubJob = volume.fetchScrubJobWhileLocked(scrubJobIDAsString)
+	if nil == scrubJob {
+		volume.Unlock()
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if volume.scrubActiveJob != scrubJob {
+		volume.Unlock()
+		responseWriter.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	scrubJob.halt()
+
+	volume.Unlock()
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// doPostOfVolumeScrubJobDelete confirms the deletion of the orphans reported by a completed dry run.
+func doPostOfVolumeScrubJobDelete(responseWriter http.ResponseWriter, volume *volumeStruct, dryRunJobIDAsString string) {
+	var (
+		dryRunJob *scrubJobStruct
+		scrubJob  *scrubJobStruct
+	)
+
+	volume.Lock()
+
+	dryRunJob = volume.fetchScrubJobWhileLocked(dryRunJobIDAsString)
+	if nil == dryRunJob {
+		volume.Unlock()
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if volume.pollScrubActiveJobWhileLocked() || !dryRunJob.deletable() {
+		volume.Unlock()
+		responseWriter.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	scrubJob = volume.startScrubJobWhileLocked(dryRunJob)
+
+	volume.Unlock()
+
+	responseWriter.Header().Set("Location", fmt.Sprintf("/volume/%v/scrub-job/%v", volume.name, scrubJob.id))
+	responseWriter.WriteHeader(http.StatusCreated)
+}
+
+// pollScrubActiveJobWhileLocked notes the completion of volume.scrubActiveJob (if any)... returning
+// whether or not it is still running.
+func (volume *volumeStruct) pollScrubActiveJobWhileLocked() (running bool) {
+	if nil == volume.scrubActiveJob {
+		running = false
+		return
+	}
+
+	select {
+	case volume.scrubActiveJob.err = <-volume.scrubActiveJob.errChan:
+		// Scrub finished at some point... make it look like it just finished now
+
+		volume.scrubActiveJob.state = fsckJobCompleted
+		volume.scrubActiveJob.endTime = time.Now()
+		volume.scrubActiveJob = nil
+
+		running = false
+	default:
+		// Scrub must still be running
+
+		running = true
+	}
+
+	return
+}
+
+func (volume *volumeStruct) fetchScrubJobWhileLocked(scrubJobIDAsString string) (scrubJob *scrubJobStruct) {
+	var (
+		err             error
+		ok              bool
+		scrubJobAsValue sortedmap.Value
+		scrubJobID      uint64
+	)
+
+	scrubJobID, err = strconv.ParseUint(scrubJobIDAsString, 10, 64)
+	if nil != err {
+		scrubJob = nil
+		return
+	}
+
+	scrubJobAsValue, ok, err = volume.scrubJobs.GetByKey(scrubJobID)
+	if nil != err {
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+	if !ok {
+		scrubJob = nil
+		return
+	}
+
+	scrubJob = scrubJobAsValue.(*scrubJobStruct)
+
+	return
+}
+
+func (fsckJob *fsckJobStruct) run() {
+	var (
+		err    error
+		report *fs.FSCKReport
+	)
+
+	report, err = fs.ValidateVolume(fsckJob.volume.name, fsckJob.repair, fsckJob.stopChan)
+
+	fsckJob.report = report
+
+	fsckJob.errChan <- err
+}
+
+// status must not be called on a running fsckJob (as fsckJob.report may not yet be set).
+func (fsckJob *fsckJobStruct) status() (fsckJobStatus fsckJobStatusStruct) {
+	var (
+		finding fs.FSCKFinding
+	)
+
+	fsckJobStatus = fsckJobStatusStruct{
+		Repair:    fsckJob.repair,
+		StartTime: fsckJob.startTime.String(),
+		Findings:  make([]fsckFindingStruct, 0),
+	}
+
+	switch fsckJob.state {
+	case fsckJobRunning:
+		fsckJobStatus.State = "Running"
+		return
+	case fsckJobHalted:
+		fsckJobStatus.State = "Halted"
+		fsckJobStatus.HaltTime = fsckJob.endTime.String()
+	case fsckJobCompleted:
+		fsckJobStatus.State = "Completed"
+		fsckJobStatus.DoneTime = fsckJob.endTime.String()
+	}
+
+	if nil != fsckJob.err {
+		fsckJobStatus.Error = fsckJob.err.Error()
+	}
+
+	if nil != fsckJob.report {
+		fsckJobStatus.InodesValidated = fsckJob.report.InodesValidated
+		for _, finding = range fsckJob.report.Findings {
+			fsckJobStatus.Findings = append(fsckJobStatus.Findings, fsckFindingStruct{
+				InodeNumber: uint64(finding.InodeNumber),
+				Path:        finding.Path,
+				Problem:     string(finding.Problem),
+				Detail:      finding.Detail,
+				Action:      finding.Action,
+				Repaired:    finding.Repaired,
+			})
+		}
+	}
+
+	return
+}
+
+func fsckJobModeToString(repair bool) (s string) {
+	if repair {
+		s = "Repair"
+	} else {
+		s = "Dry Run"
+	}
+	return
+}
+
+// startScrubJobWhileLocked launches a dry run (if dryRunJob == nil) or the deletion of the orphans dryRunJob found.
+func (volume *volumeStruct) startScrubJobWhileLocked(dryRunJob *scrubJobStruct) (scrubJob *scrubJobStruct) {
+	var (
+		dryRunReport   *inode.ScrubReport
+		err            error
+		ok             bool
+		scrubJobsCount int
+	)
+
+	for {
+		scrubJobsCount, err = volume.scrubJobs.Len()
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+
+		if scrubJobsCount < scrubJobsHistoryMaxSize {
+			break
+		}
+
+		ok, err = volume.scrubJobs.DeleteByIndex(0)
+		if nil != err {
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+		if !ok {
+			err = fmt.Errorf("httpserver.startScrubJobWhileLocked() delete of oldest element of volume.scrubJobs failed")
+			logger.Fatalf("HTTP Server Logic Error: %v", err)
+		}
+	}
+
+	scrubJob = &scrubJobStruct{
+		volume:    volume,
+		dryRun:    (nil == dryRunJob),
+		stopChan:  make(chan bool, 1),
+		errChan:   make(chan error, 1),
+		state:     fsckJobRunning,
+		startTime: time.Now(),
+	}
+
+	if nil != dryRunJob {
+		scrubJob.dryRunJobID = dryRunJob.id
+		dryRunReport = dryRunJob.report
+	}
+
+	scrubJob.id, err = volume.headhunterHandle.FetchNonce()
+	if nil != err {
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+
+	ok, err = volume.scrubJobs.Put(scrubJob.id, scrubJob)
+	if nil != err {
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+	if !ok {
+		err = fmt.Errorf("httpserver.startScrubJobWhileLocked() PUT to volume.scrubJobs failed")
+		logger.Fatalf("HTTP Server Logic Error: %v", err)
+	}
+
+	volume.scrubActiveJob = scrubJob
+
+	go scrubJob.run(dryRunReport)
+
+	return
+}
+
+func (scrubJob *scrubJobStruct) run(dryRunReport *inode.ScrubReport) {
+	var (
+		err    error
+		report *inode.ScrubReport
+	)
+
+	if scrubJob.dryRun {
+		report, err = scrubJob.volume.inodeVolumeHandle.FindOrphanedObjects(scrubJob.stopChan)
+	} else {
+		report, err = scrubJob.volume.inodeVolumeHandle.DeleteOrphanedObjects(dryRunReport, scrubJob.stopChan)
+	}
+
+	scrubJob.report = report
+
+	scrubJob.errChan <- err
+}
+
+// deletable indicates scrubJob is a dry run that completed successfully finding orphans.
+func (scrubJob *scrubJobStruct) deletable() (deletable bool) {
+	deletable = scrubJob.dryRun && (fsckJobCompleted == scrubJob.state) && (nil == scrubJob.err) && (nil != scrubJob.report) && (0 < len(scrubJob.report.Orphans))
+	return
+}
+
+// status must not be called on a running scrubJob (as scrubJob.report may not yet be set).
+func (scrubJob *scrubJobStruct) status() (scrubJobStatus scrubJobStatusStruct) {
+	var (
+		orphan inode.ScrubOrphan
+	)
+
+	scrubJobStatus = scrubJobStatusStruct{
+		DryRun:      scrubJob.dryRun,
+		DryRunJobID: scrubJob.dryRunJobID,
+		StartTime:   scrubJob.startTime.String(),
+		Orphans:     make([]scrubOrphanStruct, 0),
+	}
+
+	switch scrubJob.state {
+	case fsckJobRunning:
+		scrubJobStatus.State = "Running"
+		return
+	case fsckJobHalted:
+		scrubJobStatus.State = "Halted"
+		scrubJobStatus.HaltTime = scrubJob.endTime.String()
+	case fsckJobCompleted:
+		scrubJobStatus.State = "Completed"
+		scrubJobStatus.DoneTime = scrubJob.endTime.String()
+	}
+
+	if nil != scrubJob.err {
+		scrubJobStatus.Error = scrubJob.err.Error()
+	}
+
+	if nil != scrubJob.report {
+		scrubJobStatus.ContainersScanned = scrubJob.report.ContainersScanned
+		scrubJobStatus.ObjectsScanned = scrubJob.report.ObjectsScanned
+		scrubJobStatus.OrphanedBytes = scrubJob.report.OrphanedBytes
+		scrubJobStatus.DeleteFailures = scrubJob.report.DeleteFailures
+		for _, orphan = range scrubJob.report.Orphans {
+			scrubJobStatus.Orphans = append(scrubJobStatus.Orphans, scrubOrphanStruct{
+				ContainerName: orphan.ContainerName,
+				ObjectName:    orphan.ObjectName,
+				ObjectLength:  orphan.ObjectLength,
+			})
+		}
+	}
+
+	return
+}
+
+func writeHTMLTableRow(responseWriter http.ResponseWriter, name string, value string) {
+	_, _ = responseWriter.Write(utils.StringToByteSlice("      <tr>\n"))
+	_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", name)))
+	_, _ = responseWriter.Write(utils.StringToByteSlice(fmt.Sprintf("        <td>%s</td>\n", html.EscapeString(value))))
+	_, _ = responseWriter.Write(utils.StringToByteSlice("      </tr>\n"))
+}
+
+func quotaLimitToString(limit uint64, exceededSince time.Time) (s string) {
+	if 0 == limit {
+		s = "unlimited"
+	} else if exceededSince.IsZero() {
+		s = fmt.Sprintf("%v", limit)
+	} else {
+		s = fmt.Sprintf("%v (exceeded since %v)", limit, exceededSince)
+	}
+	return
+}
+
+func checkpointStatusToString(checkpointStatus headhunter.CheckpointStatus) (s string) {
+	if checkpointStatus.Degraded {
+		s = fmt.Sprintf("degraded since %v (%v failed checkpoints... last error: %v)", checkpointStatus.DegradedSince, checkpointStatus.FailedAttempts, html.EscapeString(checkpointStatus.LastError))
+	} else {
+		s = "ok"
+	}
+	return
+}
+
+func timeToStringIfNonZero(t time.Time) (s string) {
+	if t.IsZero() {
+		s = ""
+	} else {
+		s = t.String()
+	}
+	return
+}
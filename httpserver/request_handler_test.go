@@ -6,8 +6,11 @@ import (
 	"io/ioutil"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/swiftstack/sortedmap"
+
 	"github.com/swiftstack/ProxyFS/conf"
 )
 
@@ -24,6 +27,7 @@ func testSetup() (err error) {
 	}
 
 	globals.confMap = testConfMap
+	globals.volumeLLRB = sortedmap.NewLLRBTree(sortedmap.CompareString, nil)
 
 	return nil
 }
@@ -103,3 +107,87 @@ func TestConfigExpansion(t *testing.T) {
 
 	return
 }
+
+func TestMetrics(t *testing.T) {
+	var (
+		body bytes.Buffer
+	)
+
+	metrics := newMetrics()
+
+	metrics.addStats(map[string]uint64{
+		"proxyfs.fs.mount.operations":                         3,
+		"proxyfs.fs.volume_validate.finding.operations":       2,
+		"proxyfs.swiftclient.account-get":                     5,
+		"proxyfs.inode.file.read.operations":                  4,
+		"proxyfs.inode.file.read.bytes":                       20000,
+		"proxyfs.inode.file.read.operations.size-up-to-4KB":   2,
+		"proxyfs.inode.file.read.operations.size-8KB-to-16KB": 1,
+		"proxyfs.inode.file.read.operations.size-over-64KB":   1,
+		"logging.level.warn":                                  7,
+		"unexpected-stat":                                     1,
+	})
+
+	metrics.write(&body)
+
+	expected := `# HELP proxyfs_fs_operations_total Count of fs operations
+# TYPE proxyfs_fs_operations_total counter
+proxyfs_fs_operations_total{operation="mount"} 3
+proxyfs_fs_operations_total{operation="volume_validate.finding"} 2
+# HELP proxyfs_inode_bytes_total Bytes transferred by inode operations
+# TYPE proxyfs_inode_bytes_total counter
+proxyfs_inode_bytes_total{operation="file.read"} 20000
+# HELP proxyfs_inode_operation_size_bytes Sizes (in bytes) of inode operations
+# TYPE proxyfs_inode_operation_size_bytes histogram
+proxyfs_inode_operation_size_bytes_bucket{operation="file.read",le="4096"} 2
+proxyfs_inode_operation_size_bytes_bucket{operation="file.read",le="8192"} 2
+proxyfs_inode_operation_size_bytes_bucket{operation="file.read",le="16384"} 3
+proxyfs_inode_operation_size_bytes_bucket{operation="file.read",le="32768"} 3
+proxyfs_inode_operation_size_bytes_bucket{operation="file.read",le="65536"} 3
+proxyfs_inode_operation_size_bytes_bucket{operation="file.read",le="+Inf"} 4
+proxyfs_inode_operation_size_bytes_sum{operation="file.read"} 20000
+proxyfs_inode_operation_size_bytes_count{operation="file.read"} 4
+# HELP proxyfs_inode_operations_total Count of inode operations
+# TYPE proxyfs_inode_operations_total counter
+proxyfs_inode_operations_total{operation="file.read"} 4
+# HELP proxyfs_logger_messages_total Count of log messages (by level)
+# TYPE proxyfs_logger_messages_total counter
+proxyfs_logger_messages_total{level="warn"} 7
+# HELP proxyfs_swiftclient_operations_total Count of swiftclient operations
+# TYPE proxyfs_swiftclient_operations_total counter
+proxyfs_swiftclient_operations_total{operation="account-get"} 5
+# HELP unexpected_stat Untranslated stat unexpected-stat
+# TYPE unexpected_stat counter
+unexpected_stat 1
+`
+
+	if expected != body.String() {
+		t.Fatalf("metrics.write() produced:\n%v\nexpected:\n%v", body.String(), expected)
+	}
+
+	if `{volume="a\\b\"c\nd"}` != labelsToString([]metricLabelStruct{{"volume", "a\\b\"c\nd"}}, nil) {
+		t.Fatalf("labelsToString() failed to escape label value: %v", labelsToString([]metricLabelStruct{{"volume", "a\\b\"c\nd"}}, nil))
+	}
+
+	// Now via doGet()
+
+	req := httptest.NewRequest("GET", "http://pfs.com/metrics", nil)
+	w := httptest.NewRecorder()
+
+	doGet(w, req)
+	resp := w.Result()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+
+	if 200 != resp.StatusCode {
+		t.Fatalf("GET /metrics returned %d; expected 200", resp.StatusCode)
+	}
+	if metricsContentType != resp.Header.Get("Content-Type") {
+		t.Fatalf("GET /metrics returned Content-Type: %v", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(respBody), "# TYPE go_memstats_alloc_bytes gauge\ngo_memstats_alloc_bytes ") {
+		t.Fatalf("GET /metrics lacks go_memstats_alloc_bytes gauge:\n%v", string(respBody))
+	}
+	if !strings.Contains(string(respBody), "# TYPE go_memstats_alloc_bytes_total counter\n") {
+		t.Fatalf("GET /metrics lacks go_memstats_alloc_bytes_total counter:\n%v", string(respBody))
+	}
+}
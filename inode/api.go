@@ -7,6 +7,7 @@ import (
 
 	"golang.org/x/sys/unix"
 
+	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -59,10 +60,98 @@ type MetadataStruct struct {
 	GroupID              InodeGroupID
 }
 
+type LogSegmentReport struct {
+	LogSegmentNumber uint64
+	BytesReferenced  uint64 // file user data bytes in this log segment still referenced by the FileInode
+	BytesHeld        uint64 // size of the log segment object (i.e. what it costs to keep it around)
+}
+
 type FragmentationReport struct {
-	NumberOfFragments uint64 // used with BytesInFragments to compute average fragment size
-	BytesInFragments  uint64 // equivalent to size of file for FileInode that is not sparse
-	BytesTrapped      uint64 // unreferenced bytes trapped in referenced log segments
+	NumberOfFragments   uint64             // used with BytesInFragments to compute average fragment size
+	BytesInFragments    uint64             // equivalent to size of file for FileInode that is not sparse
+	BytesTrapped        uint64             // unreferenced bytes trapped in referenced log segments
+	NumberOfLogSegments uint64             // number of distinct log segments referenced by the FileInode
+	LogSegmentReports   []LogSegmentReport // one per referenced log segment, sorted by LogSegmentNumber
+}
+
+type DefragmenterStatus struct { // counts are reset each time the defragmenter is (re)started
+	Running           bool
+	StartTime         time.Time // when the defragmenter was most recently started
+	StopTime          time.Time // when the defragmenter was most recently stopped (if not Running)
+	LastPassStartTime time.Time
+	LastPassEndTime   time.Time // zero if no pass has yet completed
+	PassesCompleted   uint64
+	InodesScanned     uint64
+	InodesOptimized   uint64
+	OptimizeFailures  uint64
+	BytesRewritten    uint64
+}
+
+type ScrubOrphan struct {
+	ContainerName string
+	ObjectName    string
+	ObjectLength  uint64
+}
+
+type ScrubReport struct {
+	ContainersScanned uint64        // FindOrphanedObjects() only
+	ObjectsScanned    uint64        // for DeleteOrphanedObjects(), the number of orphans it was handed
+	Orphans           []ScrubOrphan // for DeleteOrphanedObjects(), those actually deleted
+	OrphanedBytes     uint64        // sum of Orphans[].ObjectLength
+	DeleteFailures    uint64        // DeleteOrphanedObjects() only
+	Nonce             uint64        // FindOrphanedObjects() only... objects named by a nonce at or beyond this postdate the scan
+}
+
+type VolumeStats struct {
+	Capacity        uint64 // from [Volume:<VolumeName>]Capacity (if == 0, not specified)
+	InodeCount      uint64 // number of inodes in the volume
+	LogSegmentBytes uint64 // bytes held in live log segments (i.e. what the volume costs to keep in Swift)
+	ReferencedBytes uint64 // bytes of those log segments still referenced by file inodes
+}
+
+type VolumeQuota struct { // for each limit, 0 means unlimited
+	HardBytes   uint64        // limit on VolumeStats.ReferencedBytes never to be exceeded
+	SoftBytes   uint64        // limit on VolumeStats.ReferencedBytes that may be exceeded for up to GracePeriod
+	HardInodes  uint64        // limit on VolumeStats.InodeCount never to be exceeded
+	SoftInodes  uint64        // limit on VolumeStats.InodeCount that may be exceeded for up to GracePeriod
+	GracePeriod time.Duration // how long a soft limit may be exceeded before being enforced like a hard limit
+}
+
+type QuotaStatus struct {
+	Limits                  VolumeQuota
+	BytesUsed               uint64
+	InodesUsed              uint64
+	SoftBytesExceededSince  time.Time // zero if SoftBytes not exceeded
+	SoftInodesExceededSince time.Time // zero if SoftInodes not exceeded
+}
+
+// QuotaReservation records the charge admitted by CheckQuota() until it is reflected in VolumeStats
+// (and the owners' stats)... see SettleQuotaReservation() and ReleaseQuotaReservation()
+type QuotaReservation struct {
+	userID        InodeUserID
+	groupID       InodeGroupID
+	bytes         uint64
+	inodes        uint64
+	volumeCharged bool // if false, charged only to the owners (e.g. for a change of ownership)
+	userCharged   bool
+	groupCharged  bool
+}
+
+type OwnerQuota struct { // for each limit, 0 means unlimited
+	HardBytes  uint64 // limit on bytes referenced by inodes of a given user or group never to be exceeded
+	HardInodes uint64 // limit on number of inodes of a given user or group never to be exceeded
+}
+
+type OwnerQuotaStatus struct {
+	Limits     OwnerQuota
+	BytesUsed  uint64
+	InodesUsed uint64
+}
+
+type SnapshotInfo struct {
+	ID           uint64
+	Name         string
+	CreationTime time.Time
 }
 
 type DirEntry struct {
@@ -118,10 +207,60 @@ func FetchVolumeHandle(volumeName string) (volumeHandle VolumeHandle, err error)
 	return
 }
 
+// FetchInspectionHandle returns an InspectionHandle decoding the inodes of volumeName found via headhunterVolumeHandle
+// (typically one returned by headhunter.OpenVolumeReadOnly()). Unlike FetchVolumeHandle(), Up() need not have been called.
+func FetchInspectionHandle(volumeName string, headhunterVolumeHandle headhunter.VolumeHandle) (inspectionHandle InspectionHandle, err error) {
+	inspectionHandle, err = fetchInspectionHandle(volumeName, headhunterVolumeHandle)
+	return
+}
+
+// RenewLease extends the expiration time of the lease (see VolumeHandle.CreateLease()) identified by leaseID.
+func RenewLease(leaseID string) (err error) {
+	err = renewLease(leaseID)
+	return
+}
+
+// ReleaseLease drops the lease (see VolumeHandle.CreateLease()) identified by leaseID. Any deletions of
+// log segments deferred solely on account of this lease are then issued.
+func ReleaseLease(leaseID string) (err error) {
+	err = releaseLease(leaseID)
+	return
+}
+
+type InodeReportDirEntry struct {
+	Basename    string
+	InodeNumber InodeNumber
+}
+
+type InodeReportFileExtent struct {
+	FileOffset       uint64
+	Length           uint64
+	LogSegmentNumber uint64
+	LogSegmentOffset uint64
+}
+
+// InodeReport is an inode record as persisted (i.e. its CorruptionDetected & Version preamble followed
+// by its onDiskInodeV1Struct fields) along with the contents of its payload B+Tree.
+type InodeReport struct {
+	CorruptionDetected
+	Version
+	onDiskInodeV1Struct
+	DirEntries  []InodeReportDirEntry   // DirType inodes only... in Basename order
+	FileExtents []InodeReportFileExtent // FileType inodes only... in FileOffset order
+}
+
+// InspectionHandle is used by offline tools (e.g. pfsinspect) to decode a volume's inodes. It never modifies the volume.
+type InspectionHandle interface {
+	NextInodeNumber(prevInodeNumber InodeNumber) (nextInodeNumber InodeNumber, ok bool, err error)
+	InspectInode(inodeNumber InodeNumber) (inodeReport *InodeReport, err error)
+	ResolvePath(path string) (inodeNumber InodeNumber, err error)
+}
+
 type VolumeHandle interface {
 	// Generic methods, implemented volume.go
 
 	GetFSID() (fsid uint64)
+	FetchVolumeStats() (volumeStats VolumeStats)
 
 	// Common Inode methods, implemented in inode.go
 
@@ -146,6 +285,38 @@ type VolumeHandle interface {
 	Optimize(inodeNumber InodeNumber, maxDuration time.Duration) (err error)
 	Validate(inodeNumber InodeNumber) (err error)
 
+	// Defragmenter methods, implemented in defrag.go
+
+	StartDefragmenter() (err error)
+	StopDefragmenter() (err error)
+	FetchDefragmenterStatus() (defragmenterStatus DefragmenterStatus)
+
+	// Scrub methods, implemented in scrub.go
+
+	FindOrphanedObjects(stopChan chan bool) (scrubReport *ScrubReport, err error)
+	DeleteOrphanedObjects(dryRunReport *ScrubReport, stopChan chan bool) (scrubReport *ScrubReport, err error)
+
+	// Quota methods, implemented in quota.go
+
+	CheckQuota(userID InodeUserID, groupID InodeGroupID, bytesDelta uint64, inodesDelta uint64) (reservation QuotaReservation, err error)
+	CheckOwnerChangeQuota(userID InodeUserID, groupID InodeGroupID, formerUserID InodeUserID, formerGroupID InodeGroupID, bytesDelta uint64) (reservation QuotaReservation, err error)
+	SettleQuotaReservation(inodeNumber InodeNumber, reservation QuotaReservation)
+	ReleaseQuotaReservation(reservation QuotaReservation)
+	FetchQuotaStatus() (quotaStatus QuotaStatus)
+	FetchOwnerQuotaStatus() (userQuotaStatus map[InodeUserID]OwnerQuotaStatus, groupQuotaStatus map[InodeGroupID]OwnerQuotaStatus)
+	FetchOwnerQuotaStatusByID(userID InodeUserID, groupID InodeGroupID) (userQuotaStatus OwnerQuotaStatus, groupQuotaStatus OwnerQuotaStatus)
+
+	// Lease methods, implemented in lease.go
+
+	CreateLease(logSegmentNumbers []uint64) (leaseID string, err error)
+
+	// Snapshot methods, implemented in snapshot.go
+
+	CreateSnapshot(snapshotName string) (snapshotID uint64, err error)
+	DeleteSnapshot(snapshotName string) (err error)
+	FetchSnapshotList() (snapshotList []SnapshotInfo)
+	FetchSnapshotVolumeHandle(snapshotName string) (volumeHandle VolumeHandle, err error)
+
 	// Directory Inode specific methods, implemented in dir.go
 
 	CreateDir(filePerm InodeMode, userID InodeUserID, groupID InodeGroupID) (dirInodeNumber InodeNumber, err error)
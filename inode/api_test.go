@@ -17,12 +17,15 @@ import (
 	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/platform"
+	"github.com/swiftstack/ProxyFS/rams3"
 	"github.com/swiftstack/ProxyFS/ramswift"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
+var testConfMap conf.ConfMap // retained so that tests may restart headhunter & inode
+
 func TestMain(m *testing.M) {
 	err := testSetup()
 	if nil != err {
@@ -110,7 +113,31 @@ func testSetup() (err error) {
 		"Volume:TestVolume.MaxInodesPerMetadataNode=32",
 		"Volume:TestVolume.MaxLogSegmentsPerMetadataNode=64",
 		"Volume:TestVolume.MaxDirFileNodesPerMetadataNode=16",
-		"FSGlobals.VolumeList=TestVolume",
+		"Volume:TestVolume.DefragmenterDutyCycle=100",
+		"Volume:TestS3Volume.FSID=2",
+		"Volume:TestS3Volume.PrimaryPeer=Peer0",
+		"Volume:TestS3Volume.AccountName=AUTH_s3test",
+		"Volume:TestS3Volume.ObjectStore=TestS3",
+		"Volume:TestS3Volume.CheckpointContainerName=.__checkpoint__",
+		"Volume:TestS3Volume.CheckpointContainerStoragePolicy=gold",
+		"Volume:TestS3Volume.CheckpointInterval=10s",
+		"Volume:TestS3Volume.CheckpointIntervalsPerCompaction=100",
+		"Volume:TestS3Volume.DefaultPhysicalContainerLayout=PhysicalContainerLayoutReplicated3Way",
+		"Volume:TestS3Volume.FlowControl=TestFlowControl",
+		"Volume:TestS3Volume.NonceValuesToReserve=100",
+		"Volume:TestS3Volume.MaxEntriesPerDirNode=32",
+		"Volume:TestS3Volume.MaxExtentsPerFileNode=32",
+		"Volume:TestS3Volume.MaxInodesPerMetadataNode=32",
+		"Volume:TestS3Volume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:TestS3Volume.MaxDirFileNodesPerMetadataNode=16",
+		"ObjectStore:TestS3.Type=S3",
+		"ObjectStore:TestS3.Endpoint=http://127.0.0.1:45269",
+		"ObjectStore:TestS3.Bucket=testbucket",
+		"ObjectStore:TestS3.AccessKeyID=TESTACCESSKEY",
+		"ObjectStore:TestS3.SecretAccessKey=TESTSECRETKEY",
+		"ObjectStore:TestS3.RetryDelay=10ms",
+		"ObjectStore:TestS3.PartSize=4096",
+		"FSGlobals.VolumeList=TestVolume,TestS3Volume",
 		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
 		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
 		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
@@ -121,12 +148,16 @@ func testSetup() (err error) {
 		"FSGlobals.DirEntryCacheEvictHighLimit=10010",
 		"FSGlobals.FileExtentMapEvictLowLimit=10000",
 		"FSGlobals.FileExtentMapEvictHighLimit=10010",
+		"FSGlobals.LeaseDuration=1s",
 		"RamSwiftInfo.MaxAccountNameLength=256",
 		"RamSwiftInfo.MaxContainerNameLength=256",
 		"RamSwiftInfo.MaxObjectNameLength=1024",
+		"RamS3.TCPPort=45269",
+		"RamS3.AccessKeyID=TESTACCESSKEY",
+		"RamS3.BucketList=testbucket",
 	}
 
-	testConfMap, err := conf.MakeConfMapFromStrings(testConfStrings)
+	testConfMap, err = conf.MakeConfMapFromStrings(testConfStrings)
 	if err != nil {
 		return err
 	}
@@ -135,6 +166,10 @@ func testSetup() (err error) {
 	doneChan := make(chan bool, 1)
 	go ramswift.Daemon("/dev/null", testConfStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
 
+	rams3SignalHandlerIsArmed := false
+	rams3DoneChan := make(chan bool, 1)
+	go rams3.Daemon("/dev/null", testConfStrings, &rams3SignalHandlerIsArmed, rams3DoneChan, unix.SIGTERM)
+
 	err = logger.Up(testConfMap)
 	if nil != err {
 		return
@@ -160,6 +195,11 @@ func testSetup() (err error) {
 		return
 	}
 
+	err = headhunter.Format(testConfMap, "TestS3Volume")
+	if nil != err {
+		return
+	}
+
 	err = headhunter.Up(testConfMap)
 	if nil != err {
 		return
@@ -2327,8 +2367,4 @@ func TestAPI(t *testing.T) {
 	if !fileInodeMetadataAfterSetSize.AccessTime.Equal(fileInodeMetadataAfterWrote.AccessTime) {
 		t.Fatalf("fileInodeMetadataAfterSetSize.AccessTime unexpected change")
 	}
-
-	// TODO: Need to test GetFragmentationReport()
-
-	// TODO: Once implemented, need to test Optimize()
 }
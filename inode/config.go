@@ -11,6 +11,7 @@ import (
 	"github.com/swiftstack/ProxyFS/conf"
 	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
 	"github.com/swiftstack/ProxyFS/platform"
 	"github.com/swiftstack/ProxyFS/utils"
 )
@@ -59,6 +60,7 @@ type volumeStruct struct {
 	fsid                           uint64
 	volumeName                     string
 	accountName                    string
+	objectStore                    objectstore.ObjectStore
 	active                         bool
 	activePeerPrivateIPAddr        string
 	maxEntriesPerDirNode           uint64
@@ -70,6 +72,19 @@ type volumeStruct struct {
 	flowControl                    *flowControlStruct
 	headhunterVolumeHandle         headhunter.VolumeHandle
 	inodeCache                     map[InodeNumber]*inMemoryInodeStruct //      key == InodeNumber
+	capacity                       uint64                               // if == 0, Capacity was not specified
+	readOnly                       bool                                 // [Volume:<VolumeName>]ReadOnly... no background work may alter the volume
+	defragmenter                   *defragmenterStruct
+	quota                          *quotaStruct
+	pinnedLogSegmentMap            map[uint64]*pinnedLogSegmentStruct // key == logSegmentNumber; protected by globals.lease
+	snapshotOf                     *volumeStruct                      // if != nil, this is a read-only snapshot of snapshotOf
+	snapshotVolumeMap              map[string]*volumeStruct           // key == snapshot name; see FetchSnapshotVolumeHandle()
+	provisionedObjectMap           map[uint64]time.Time               // key == objectNumber from ProvisionObject() not yet recorded; value == when provisioned
+	provisionedObjectTrackingStart time.Time                          // objects provisioned earlier (e.g. prior to a restart) are untracked
+	scrubGracePeriod               time.Duration                      // how long a provisioned object may await being recorded before it can be an orphan
+	leaseRecoveryStopChan          chan bool                          // non-nil only while leaseRecoveryDaemon() is running
+	leaseRecoveryDoneChan          chan bool                          // non-nil only while leaseRecoveryDaemon() is running
+	leaseRecoveryComplete          bool                               // set once leaseRecoveryDaemon() has swept the volume since it became active
 }
 
 type globalsStruct struct {
@@ -87,6 +102,7 @@ type globalsStruct struct {
 	corruptionDetectedFalseBuf   []byte                        // holds serialized CorruptionDetected == false
 	versionV1Buf                 []byte                        // holds serialized Version            == V1
 	inodeRecDefaultPreambleBuf   []byte                        // holds concatenated corruptionDetectedFalseBuf & versionV1Buf
+	lease                        leaseGlobalsStruct
 }
 
 var globals globalsStruct
@@ -197,6 +213,9 @@ func Up(confMap conf.ConfMap) (err error) {
 			physicalContainerNamePrefixSet: make(map[string]struct{}),
 			physicalContainerLayoutMap:     make(map[string]*physicalContainerLayoutStruct),
 			inodeCache:                     make(map[InodeNumber]*inMemoryInodeStruct),
+			pinnedLogSegmentMap:            make(map[uint64]*pinnedLogSegmentStruct),
+			provisionedObjectMap:           make(map[uint64]time.Time),
+			snapshotVolumeMap:              make(map[string]*volumeStruct),
 		}
 
 		volume.fsid, err = confMap.FetchOptionValueUint64(volumeSectionName, "FSID")
@@ -216,6 +235,11 @@ func Up(confMap conf.ConfMap) (err error) {
 			return
 		}
 
+		volume.objectStore, err = objectstore.FetchObjectStore(confMap, volumeName)
+		if nil != err {
+			return
+		}
+
 		_, alreadyInVolumeMap = globals.volumeMap[volume.volumeName]
 		if alreadyInVolumeMap {
 			err = fmt.Errorf("Volume \"%v\" only allowed once in [FSGlobals]VolumeList", volume.volumeName)
@@ -374,6 +398,30 @@ func Up(confMap conf.ConfMap) (err error) {
 			if nil != err {
 				return
 			}
+
+			volume.provisionedObjectTrackingStart = time.Now()
+
+			err = volume.adoptCapacityParameter(confMap)
+			if nil != err {
+				return
+			}
+
+			volume.adoptReadOnlyParameter(confMap)
+
+			err = volume.adoptQuotaParameters(confMap)
+			if nil != err {
+				return
+			}
+
+			err = volume.adoptScrubParameters(confMap)
+			if nil != err {
+				return
+			}
+
+			err = volume.adoptDefragmenterParameters(confMap)
+			if nil != err {
+				return
+			}
 		}
 
 		globals.volumeMap[volume.volumeName] = volume
@@ -408,6 +456,27 @@ func Up(confMap conf.ConfMap) (err error) {
 	globals.inodeRecDefaultPreambleBuf = append(globals.inodeRecDefaultPreambleBuf, globals.corruptionDetectedFalseBuf...)
 	globals.inodeRecDefaultPreambleBuf = append(globals.inodeRecDefaultPreambleBuf, globals.versionV1Buf...)
 
+	err = leaseUp(confMap)
+	if nil != err {
+		return
+	}
+
+	for _, volume = range globals.volumeMap {
+		if volume.active {
+			err = volume.rebuildStatsIfNeeded()
+			if nil != err {
+				return
+			}
+		}
+
+		err = volume.startDefragmenterIfEnabled()
+		if nil != err {
+			return
+		}
+
+		volume.startLeaseRecoveryIfNeeded()
+	}
+
 	err = nil
 	return
 }
@@ -475,6 +544,11 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 		newVolumeSet[volumeName] = true
 	}
 
+	for _, volume = range globals.volumeMap {
+		volume.stopDefragmenterForPause()
+		volume.stopLeaseRecovery()
+	}
+
 	volumesDeletedSet = make(map[string]bool)
 	volumesNewlyInactiveSet = make(map[string]bool)
 
@@ -506,6 +580,8 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 
 	for volumeName = range volumesDeletedSet {
 		volume = globals.volumeMap[volumeName]
+		volume.dropVolumeLeases()
+		volume.dropSnapshotVolumes()
 		volume.flowControl.refCount--
 		if 0 == volume.flowControl.refCount {
 			delete(globals.flowControlMap, volume.flowControl.flowControlName)
@@ -515,7 +591,10 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 
 	for volumeName = range volumesNewlyInactiveSet {
 		volume = globals.volumeMap[volumeName]
+		volume.dropVolumeLeases()
+		volume.dropSnapshotVolumes()
 		volume.active = false
+		volume.leaseRecoveryComplete = false
 		primaryPeerNameList, err = confMap.FetchOptionValueStringSlice(utils.VolumeNameConfSection(volumeName), "PrimaryPeer")
 		if nil != err {
 			return
@@ -687,6 +766,27 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 							return
 						}
 
+						err = volume.adoptCapacityParameter(confMap)
+						if nil != err {
+							return
+						}
+
+						volume.adoptReadOnlyParameter(confMap)
+
+						err = volume.adoptQuotaParameters(confMap)
+						if nil != err {
+							return
+						}
+
+						err = volume.adoptScrubParameters(confMap)
+						if nil != err {
+							return
+						}
+
+						err = volume.adoptDefragmenterParameters(confMap)
+						if nil != err {
+							return
+						}
 					} else {
 						err = fmt.Errorf("Volume \"%v\" changed its FlowControl name", volumeName)
 						return
@@ -720,6 +820,14 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 				physicalContainerNamePrefixSet: make(map[string]struct{}),
 				physicalContainerLayoutMap:     make(map[string]*physicalContainerLayoutStruct),
 				inodeCache:                     make(map[InodeNumber]*inMemoryInodeStruct),
+				pinnedLogSegmentMap:            make(map[uint64]*pinnedLogSegmentStruct),
+				provisionedObjectMap:           make(map[uint64]time.Time),
+				snapshotVolumeMap:              make(map[string]*volumeStruct),
+			}
+
+			volume.objectStore, err = objectstore.FetchObjectStore(confMap, volumeName)
+			if nil != err {
+				return
 			}
 
 			globals.volumeMap[volume.volumeName] = volume
@@ -858,17 +966,64 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 			if nil != err {
 				return
 			}
+
+			volume.provisionedObjectTrackingStart = time.Now()
+
+			err = volume.adoptCapacityParameter(confMap)
+			if nil != err {
+				return
+			}
+
+			volume.adoptReadOnlyParameter(confMap)
+
+			err = volume.adoptQuotaParameters(confMap)
+			if nil != err {
+				return
+			}
+
+			err = volume.adoptScrubParameters(confMap)
+			if nil != err {
+				return
+			}
+
+			err = volume.adoptDefragmenterParameters(confMap)
+			if nil != err {
+				return
+			}
 		}
 	}
 
 	adoptFlowControlReadCacheParameters(confMap, true)
 
+	for _, volume = range globals.volumeMap {
+		if volume.active {
+			err = volume.rebuildStatsIfNeeded()
+			if nil != err {
+				return
+			}
+		}
+
+		err = volume.startDefragmenterIfEnabled()
+		if nil != err {
+			return
+		}
+
+		volume.startLeaseRecoveryIfNeeded()
+	}
+
 	err = nil
 	return
 }
 
 func Down() (err error) {
-	err = nil // Nothing to do
+	for _, volume := range globals.volumeMap {
+		volume.stopDefragmenterForPause()
+		volume.stopLeaseRecovery()
+	}
+
+	leaseDown()
+
+	err = nil
 	return
 }
 
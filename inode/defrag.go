@@ -0,0 +1,572 @@
+package inode
+
+// Background defragmenter for a volume
+//
+// Each active volume may run a single defragmenter daemon. Each pass of the daemon walks (up to
+// DefragmenterMaxInodesPerPass of) the inodeRecs of the volume - resuming where the prior pass left
+// off - looking for FileInodes whose LogSegmentMap indicates they reference only a fraction of the
+// log segment objects they are keeping alive (or that are broken into more extents than log segments).
+// The sizes of those objects come from their LogSegmentRecs, so scanning an inode issues no object
+// requests. The worst of these (the "victims") are then handed to optimizeHelper() to have their
+// data rewritten into new log segments. Between each unit of work, the daemon sleeps long enough
+// to honor both the configured duty cycle and the configured bandwidth limit.
+//
+// Package inode does no locking of its own, so the daemon obtains the same DLM locks package fs
+// uses (see dlm.InodeLockID()). It only ever uses Try{Read|Write}Lock() so that it never blocks
+// (nor deadlocks with) foreground operations... busy inodes are simply skipped until a later pass.
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+const (
+	defragmenterIntervalDefault          = 10 * time.Minute
+	defragmenterDutyCycleDefault         = uint64(10) // percent
+	defragmenterMaxBandwidthDefault      = uint64(0)  // bytes/sec... 0 means unlimited
+	defragmenterMaxVictimsPerPassDefault = uint64(16)
+	defragmenterMaxInodesPerPassDefault  = uint64(10000)
+	defragmenterMaxOptimizeTimeDefault   = 10 * time.Second
+)
+
+type defragmenterVictimStruct struct {
+	inodeNumber         InodeNumber
+	fragmentationReport FragmentationReport
+}
+
+type defragmenterVictimSlice []defragmenterVictimStruct
+
+func (victims defragmenterVictimSlice) Len() int {
+	return len(victims)
+}
+
+func (victims defragmenterVictimSlice) Swap(i, j int) {
+	victims[i], victims[j] = victims[j], victims[i]
+}
+
+func (victims defragmenterVictimSlice) Less(i, j int) bool {
+	// Most BytesTrapped first... breaking ties by most excess fragments first
+
+	if victims[i].fragmentationReport.BytesTrapped != victims[j].fragmentationReport.BytesTrapped {
+		return victims[i].fragmentationReport.BytesTrapped > victims[j].fragmentationReport.BytesTrapped
+	}
+
+	return (victims[i].fragmentationReport.NumberOfFragments - victims[i].fragmentationReport.NumberOfLogSegments) >
+		(victims[j].fragmentationReport.NumberOfFragments - victims[j].fragmentationReport.NumberOfLogSegments)
+}
+
+type defragmenterStruct struct {
+	enabled           bool          //          [Volume:<volume-name>]DefragmenterEnabled
+	interval          time.Duration //          [Volume:<volume-name>]DefragmenterInterval
+	dutyCycle         uint64        // percent; [Volume:<volume-name>]DefragmenterDutyCycle
+	maxBandwidth      uint64        // B/sec;   [Volume:<volume-name>]DefragmenterMaxBandwidth (0 means unlimited)
+	maxVictimsPerPass uint64        //          [Volume:<volume-name>]DefragmenterMaxVictimsPerPass
+	maxInodesPerPass  uint64        //          [Volume:<volume-name>]DefragmenterMaxInodesPerPass
+	maxOptimizeTime   time.Duration //          [Volume:<volume-name>]DefragmenterMaxOptimizeTime
+	restartOnResume   bool          // set by PauseAndContract() if daemon was running
+	scanCursor        uint64        // InodeNumber following which the next pass resumes scanning (0 to start over)
+	stopChan          chan bool     // non-nil only while daemon is running
+	doneChan          chan bool     // non-nil only while daemon is running
+	status            DefragmenterStatus
+}
+
+func (vS *volumeStruct) adoptDefragmenterParameters(confMap conf.ConfMap) (err error) {
+	var (
+		defragmenter      *defragmenterStruct
+		volumeSectionName string
+	)
+
+	if nil == vS.defragmenter {
+		vS.defragmenter = &defragmenterStruct{}
+	}
+
+	defragmenter = vS.defragmenter
+
+	volumeSectionName = utils.VolumeNameConfSection(vS.volumeName)
+
+	defragmenter.enabled, err = confMap.FetchOptionValueBool(volumeSectionName, "DefragmenterEnabled")
+	if nil != err {
+		defragmenter.enabled = false // TODO: eventually, just return
+	}
+
+	defragmenter.interval, err = confMap.FetchOptionValueDuration(volumeSectionName, "DefragmenterInterval")
+	if nil != err {
+		defragmenter.interval = defragmenterIntervalDefault // TODO: eventually, just return
+	}
+
+	defragmenter.dutyCycle, err = confMap.FetchOptionValueUint64(volumeSectionName, "DefragmenterDutyCycle")
+	if nil != err {
+		defragmenter.dutyCycle = defragmenterDutyCycleDefault // TODO: eventually, just return
+	}
+	if (0 == defragmenter.dutyCycle) || (100 < defragmenter.dutyCycle) {
+		err = fmt.Errorf("%s.DefragmenterDutyCycle (%v) must be between 1 and 100", volumeSectionName, defragmenter.dutyCycle)
+		return
+	}
+
+	defragmenter.maxBandwidth, err = confMap.FetchOptionValueUint64(volumeSectionName, "DefragmenterMaxBandwidth")
+	if nil != err {
+		defragmenter.maxBandwidth = defragmenterMaxBandwidthDefault // TODO: eventually, just return
+	}
+
+	defragmenter.maxVictimsPerPass, err = confMap.FetchOptionValueUint64(volumeSectionName, "DefragmenterMaxVictimsPerPass")
+	if nil != err {
+		defragmenter.maxVictimsPerPass = defragmenterMaxVictimsPerPassDefault // TODO: eventually, just return
+	}
+	if 0 == defragmenter.maxVictimsPerPass {
+		err = fmt.Errorf("%s.DefragmenterMaxVictimsPerPass must be non-zero", volumeSectionName)
+		return
+	}
+
+	defragmenter.maxInodesPerPass, err = confMap.FetchOptionValueUint64(volumeSectionName, "DefragmenterMaxInodesPerPass")
+	if nil != err {
+		defragmenter.maxInodesPerPass = defragmenterMaxInodesPerPassDefault // TODO: eventually, just return
+	}
+	if 0 == defragmenter.maxInodesPerPass {
+		err = fmt.Errorf("%s.DefragmenterMaxInodesPerPass must be non-zero", volumeSectionName)
+		return
+	}
+
+	defragmenter.maxOptimizeTime, err = confMap.FetchOptionValueDuration(volumeSectionName, "DefragmenterMaxOptimizeTime")
+	if nil != err {
+		defragmenter.maxOptimizeTime = defragmenterMaxOptimizeTimeDefault // TODO: eventually, just return
+	}
+
+	err = nil
+	return
+}
+
+// startDefragmenterIfEnabled is called during Up() and ExpandAndResume() once all
+// active volumes are fully configured. The vS.Lock() must not be held by the caller.
+func (vS *volumeStruct) startDefragmenterIfEnabled() (err error) {
+	var (
+		shouldStart bool
+	)
+
+	vS.Lock()
+	shouldStart = (nil != vS.defragmenter) && vS.active && !vS.readOnly && (vS.defragmenter.enabled || vS.defragmenter.restartOnResume) && (nil == vS.defragmenter.stopChan)
+	if (nil != vS.defragmenter) && !vS.readOnly {
+		vS.defragmenter.restartOnResume = false // ...else retained until the volume is no longer read-only
+	}
+	vS.Unlock()
+
+	if shouldStart {
+		err = vS.StartDefragmenter()
+	} else {
+		err = nil
+	}
+
+	return
+}
+
+// stopDefragmenterForPause is called during PauseAndContract() and Down(). The vS.Lock() must not be held by the caller.
+func (vS *volumeStruct) stopDefragmenterForPause() {
+	var (
+		wasRunning bool
+	)
+
+	vS.Lock()
+	wasRunning = (nil != vS.defragmenter) && (nil != vS.defragmenter.stopChan)
+	vS.Unlock()
+
+	if wasRunning {
+		_ = vS.StopDefragmenter()
+
+		vS.Lock()
+		vS.defragmenter.restartOnResume = true
+		vS.Unlock()
+	}
+}
+
+func (vS *volumeStruct) StartDefragmenter() (err error) {
+	vS.Lock()
+	defer vS.Unlock()
+
+	if !vS.active {
+		err = fmt.Errorf("%s: volumeName \"%v\" not active", utils.GetFnName(), vS.volumeName)
+		err = blunder.AddError(err, blunder.NotActiveError)
+		return
+	}
+
+	if nil != vS.snapshotOf {
+		err = vS.snapshotReadOnlyError(utils.GetFnName())
+		return
+	}
+
+	if vS.readOnly {
+		err = vS.readOnlyError(utils.GetFnName())
+		return
+	}
+
+	if nil != vS.defragmenter.stopChan {
+		err = fmt.Errorf("%s: defragmenter for volumeName \"%v\" already running", utils.GetFnName(), vS.volumeName)
+		err = blunder.AddError(err, blunder.TryAgainError)
+		return
+	}
+
+	vS.defragmenter.stopChan = make(chan bool, 1)
+	vS.defragmenter.doneChan = make(chan bool, 1)
+
+	vS.defragmenter.status = DefragmenterStatus{
+		Running:   true,
+		StartTime: time.Now(),
+	}
+
+	go vS.defragmenterDaemon(vS.defragmenter.stopChan, vS.defragmenter.doneChan)
+
+	logger.Infof("Defragmenter for volume \"%v\" started", vS.volumeName)
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) StopDefragmenter() (err error) {
+	var (
+		doneChan chan bool
+	)
+
+	vS.Lock()
+
+	if nil == vS.defragmenter.stopChan {
+		vS.Unlock()
+		err = fmt.Errorf("%s: defragmenter for volumeName \"%v\" not running", utils.GetFnName(), vS.volumeName)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	vS.defragmenter.stopChan <- true
+	doneChan = vS.defragmenter.doneChan
+
+	vS.Unlock()
+
+	_ = <-doneChan
+
+	vS.Lock()
+	vS.defragmenter.stopChan = nil
+	vS.defragmenter.doneChan = nil
+	vS.defragmenter.status.Running = false
+	vS.defragmenter.status.StopTime = time.Now()
+	vS.Unlock()
+
+	logger.Infof("Defragmenter for volume \"%v\" stopped", vS.volumeName)
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) FetchDefragmenterStatus() (defragmenterStatus DefragmenterStatus) {
+	vS.Lock()
+	if nil != vS.defragmenter {
+		defragmenterStatus = vS.defragmenter.status
+	}
+	vS.Unlock()
+
+	return
+}
+
+func (vS *volumeStruct) defragmenterDaemon(stopChan chan bool, doneChan chan bool) {
+	var (
+		bytesRewritten uint64
+		err            error
+		optimized      bool
+		optimizeStart  time.Time
+		passStart      time.Time
+		stopped        bool
+		victim         defragmenterVictimStruct
+		victims        []defragmenterVictimStruct
+	)
+
+	for {
+		passStart = time.Now()
+
+		vS.Lock()
+		vS.defragmenter.status.LastPassStartTime = passStart
+		vS.Unlock()
+
+		victims, stopped = vS.defragmenterSelectVictims(stopChan)
+		if stopped {
+			break
+		}
+
+		for _, victim = range victims {
+			optimizeStart = time.Now()
+
+			optimized, bytesRewritten, err = vS.defragmenterOptimizeVictim(victim)
+
+			if nil == err {
+				if optimized {
+					vS.Lock()
+					vS.defragmenter.status.InodesOptimized++
+					vS.defragmenter.status.BytesRewritten += bytesRewritten
+					vS.Unlock()
+
+					stats.IncrementOperationsAndBytes(stats.DefragmenterOptimize, bytesRewritten)
+				}
+			} else {
+				vS.Lock()
+				vS.defragmenter.status.OptimizeFailures++
+				vS.Unlock()
+
+				stats.IncrementOperations(&stats.DefragmenterOptimizeFailedOps)
+
+				logger.WarnfWithError(err, "Defragmenter for volume \"%v\" unable to optimize inode %v", vS.volumeName, victim.inodeNumber)
+			}
+
+			stopped = vS.defragmenterThrottle(stopChan, time.Since(optimizeStart), bytesRewritten)
+			if stopped {
+				break
+			}
+		}
+
+		if stopped {
+			break
+		}
+
+		vS.Lock()
+		vS.defragmenter.status.PassesCompleted++
+		vS.defragmenter.status.LastPassEndTime = time.Now()
+		vS.Unlock()
+
+		stats.IncrementOperations(&stats.DefragmenterPassOps)
+
+		select {
+		case _ = <-stopChan:
+			stopped = true
+		case <-time.After(vS.defragmenter.interval):
+			// Time for another pass
+		}
+
+		if stopped {
+			break
+		}
+	}
+
+	doneChan <- true
+}
+
+// defragmenterThrottle sleeps long enough following workDuration of activity (that rewrote
+// bytesRewritten bytes) to satisfy both the duty cycle and the bandwidth limit. It returns
+// stopped == true if stopChan was signaled before or during the sleep.
+func (vS *volumeStruct) defragmenterThrottle(stopChan chan bool, workDuration time.Duration, bytesRewritten uint64) (stopped bool) {
+	var (
+		bandwidthDuration time.Duration
+		sleepDuration     time.Duration
+	)
+
+	sleepDuration = workDuration * time.Duration(100-vS.defragmenter.dutyCycle) / time.Duration(vS.defragmenter.dutyCycle)
+
+	if 0 != vS.defragmenter.maxBandwidth {
+		bandwidthDuration = time.Duration(bytesRewritten) * time.Second / time.Duration(vS.defragmenter.maxBandwidth)
+		if (bandwidthDuration - workDuration) > sleepDuration {
+			sleepDuration = bandwidthDuration - workDuration
+		}
+	}
+
+	if 0 >= sleepDuration {
+		select {
+		case _ = <-stopChan:
+			stopped = true
+		default:
+			stopped = false
+		}
+		return
+	}
+
+	select {
+	case _ = <-stopChan:
+		stopped = true
+	case <-time.After(sleepDuration):
+		stopped = false
+	}
+
+	return
+}
+
+// defragmenterSelectVictims walks (up to) maxInodesPerPass inodeRecs in the volume - starting after
+// scanCursor and wrapping back to the first inodeRec once the last has been scanned - returning the
+// (up to) maxVictimsPerPass FileInodes most in need of optimization. It returns stopped == true if
+// stopChan was signaled.
+func (vS *volumeStruct) defragmenterSelectVictims(stopChan chan bool) (victims defragmenterVictimSlice, stopped bool) {
+	var (
+		err                 error
+		fragmentationReport FragmentationReport
+		inodeNumberAsUint64 uint64
+		inodesScanned       uint64
+		isCandidate         bool
+		ok                  bool
+		scanStart           time.Time
+	)
+
+	victims = make(defragmenterVictimSlice, 0, vS.defragmenter.maxVictimsPerPass+1)
+
+	inodeNumberAsUint64 = vS.defragmenter.scanCursor
+
+	for inodesScanned = 0; inodesScanned < vS.defragmenter.maxInodesPerPass; inodesScanned++ {
+		scanStart = time.Now()
+
+		inodeNumberAsUint64, ok, err = vS.headhunterVolumeHandle.NextInodeNumber(inodeNumberAsUint64)
+		if nil != err {
+			logger.ErrorfWithError(err, "Defragmenter for volume \"%v\" unable to enumerate inodes", vS.volumeName)
+			break
+		}
+		if !ok {
+			// Reached the last inodeRec... so the next pass starts over
+
+			inodeNumberAsUint64 = 0
+			break
+		}
+
+		fragmentationReport, isCandidate, err = vS.defragmenterScanInode(InodeNumber(inodeNumberAsUint64))
+		if nil != err {
+			logger.WarnfWithError(err, "Defragmenter for volume \"%v\" unable to scan inode %v", vS.volumeName, inodeNumberAsUint64)
+		}
+
+		vS.Lock()
+		vS.defragmenter.status.InodesScanned++
+		vS.Unlock()
+
+		stats.IncrementOperations(&stats.DefragmenterScanOps)
+
+		if isCandidate {
+			victims = append(victims, defragmenterVictimStruct{
+				inodeNumber:         InodeNumber(inodeNumberAsUint64),
+				fragmentationReport: fragmentationReport,
+			})
+			sort.Sort(victims)
+			if uint64(len(victims)) > vS.defragmenter.maxVictimsPerPass {
+				victims = victims[:vS.defragmenter.maxVictimsPerPass]
+			}
+		}
+
+		stopped = vS.defragmenterThrottle(stopChan, time.Since(scanStart), 0)
+		if stopped {
+			vS.defragmenter.scanCursor = inodeNumberAsUint64
+			return
+		}
+	}
+
+	vS.defragmenter.scanCursor = inodeNumberAsUint64
+
+	stopped = false
+	return
+}
+
+// defragmenterScanInode computes the FragmentationReport for inodeNumber (if it is a FileInode) and
+// reports whether or not Optimize() would improve it. Inodes that are currently locked by some other
+// activity (or dirty) are skipped. If the inode was not previously cached, it is not added to the cache.
+func (vS *volumeStruct) defragmenterScanInode(inodeNumber InodeNumber) (fragmentationReport FragmentationReport, isCandidate bool, err error) {
+	var (
+		inode *inMemoryInodeStruct
+		lock  *dlm.RWLockStruct
+		ok    bool
+	)
+
+	isCandidate = false
+
+	lock = &dlm.RWLockStruct{
+		LockID:       dlm.InodeLockID(vS.volumeName, uint64(inodeNumber)),
+		Notify:       nil,
+		LockCallerID: dlm.GenerateCallerID(),
+	}
+
+	err = lock.TryReadLock()
+	if nil != err {
+		// Inode is busy... we'll just try again next pass
+		err = nil
+		return
+	}
+
+	vS.Lock()
+	inode, ok = vS.inodeCache[inodeNumber]
+	vS.Unlock()
+
+	if !ok {
+		inode, ok, err = vS.fetchOnDiskInode(inodeNumber)
+		if nil != err {
+			_ = lock.Unlock()
+			return
+		}
+		if !ok {
+			// Inode must have been destroyed since we enumerated it
+			_ = lock.Unlock()
+			err = nil
+			return
+		}
+	}
+
+	if (FileType != inode.InodeType) || inode.dirty {
+		_ = lock.Unlock()
+		err = nil
+		return
+	}
+
+	fragmentationReport, err = vS.getFragmentationReportHelper(inode)
+
+	_ = lock.Unlock()
+
+	if nil != err {
+		return
+	}
+
+	isCandidate = (0 < fragmentationReport.BytesTrapped) || (fragmentationReport.NumberOfFragments > fragmentationReport.NumberOfLogSegments)
+
+	return
+}
+
+// defragmenterOptimizeVictim rewrites the data of the FileInode selected by defragmenterSelectVictims().
+// As with defragmenterScanInode(), if the inode was not previously cached, it is not left in the cache.
+func (vS *volumeStruct) defragmenterOptimizeVictim(victim defragmenterVictimStruct) (optimized bool, bytesRewritten uint64, err error) {
+	var (
+		fileInode *inMemoryInodeStruct
+		lock      *dlm.RWLockStruct
+		wasCached bool
+	)
+
+	optimized = false
+
+	lock = &dlm.RWLockStruct{
+		LockID:       dlm.InodeLockID(vS.volumeName, uint64(victim.inodeNumber)),
+		Notify:       nil,
+		LockCallerID: dlm.GenerateCallerID(),
+	}
+
+	err = lock.TryWriteLock()
+	if nil != err {
+		// Inode has become busy... we'll just try again next pass
+		err = nil
+		return
+	}
+
+	vS.Lock()
+	_, wasCached = vS.inodeCache[victim.inodeNumber]
+	vS.Unlock()
+
+	fileInode, err = vS.fetchInodeType(victim.inodeNumber, FileType)
+	if nil != err {
+		_ = lock.Unlock()
+		if blunder.Is(err, blunder.NotFoundError) {
+			// Inode must have been destroyed since we scanned it
+			err = nil
+		}
+		return
+	}
+
+	bytesRewritten, err = vS.optimizeHelper(fileInode, vS.defragmenter.maxOptimizeTime)
+	if nil == err {
+		optimized = true
+		if !wasCached {
+			_ = vS.Purge(victim.inodeNumber)
+		}
+	}
+
+	_ = lock.Unlock()
+
+	return
+}
@@ -0,0 +1,269 @@
+package inode
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/objectstore"
+)
+
+// testCountingObjectStore counts the ObjectContentLength() (i.e. HEAD) requests made of the wrapped ObjectStore
+type testCountingObjectStore struct {
+	sync.Mutex
+	objectstore.ObjectStore
+	objectContentLengthCalls uint64
+}
+
+func (objectStore *testCountingObjectStore) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	objectStore.Lock()
+	objectStore.objectContentLengthCalls++
+	objectStore.Unlock()
+	length, err = objectStore.ObjectStore.ObjectContentLength(accountName, containerName, objectName)
+	return
+}
+
+// testMakeFragmentedFile produces a FileInode trapping bytes in a number of log segments (see TestFragmentationReportAndOptimize)
+func testMakeFragmentedFile(t *testing.T, testVolumeHandle VolumeHandle) (fileInodeNumber InodeNumber, expectedBuf []byte) {
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	expectedBuf = bytes.Repeat([]byte{'A'}, 64)
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, expectedBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() of initial content failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() of initial content failed: %v", err)
+	}
+
+	for offset := uint64(0); offset < 64; offset += 16 {
+		overwriteBuf := bytes.Repeat([]byte{'B'}, 8)
+		err = testVolumeHandle.Write(fileInodeNumber, offset, overwriteBuf, nil)
+		if nil != err {
+			t.Fatalf("Write() of overwrite at offset %v failed: %v", offset, err)
+		}
+		err = testVolumeHandle.Flush(fileInodeNumber, false)
+		if nil != err {
+			t.Fatalf("Flush() of overwrite at offset %v failed: %v", offset, err)
+		}
+		copy(expectedBuf[offset:], overwriteBuf)
+	}
+
+	return
+}
+
+func TestDefragmenter(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	defragmenterStatus := testVolumeHandle.FetchDefragmenterStatus()
+	if defragmenterStatus.Running {
+		t.Fatalf("FetchDefragmenterStatus() [initially] returned Running == true")
+	}
+
+	err = testVolumeHandle.StopDefragmenter()
+	if nil == err {
+		t.Fatalf("StopDefragmenter() [not running] should have failed")
+	}
+
+	fileInodeNumber, expectedBuf := testMakeFragmentedFile(t, testVolumeHandle)
+
+	err = testVolumeHandle.StartDefragmenter()
+	if nil != err {
+		t.Fatalf("StartDefragmenter() failed: %v", err)
+	}
+
+	err = testVolumeHandle.StartDefragmenter()
+	if nil == err {
+		t.Fatalf("StartDefragmenter() [already running] should have failed")
+	}
+
+	for {
+		defragmenterStatus = testVolumeHandle.FetchDefragmenterStatus()
+		if !defragmenterStatus.Running {
+			t.Fatalf("FetchDefragmenterStatus() [started] returned Running == false")
+		}
+		if 0 < defragmenterStatus.PassesCompleted {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = testVolumeHandle.StopDefragmenter()
+	if nil != err {
+		t.Fatalf("StopDefragmenter() failed: %v", err)
+	}
+
+	defragmenterStatus = testVolumeHandle.FetchDefragmenterStatus()
+	if defragmenterStatus.Running {
+		t.Fatalf("FetchDefragmenterStatus() [stopped] returned Running == true")
+	}
+	if 0 == defragmenterStatus.InodesScanned {
+		t.Fatalf("FetchDefragmenterStatus() [stopped] returned InodesScanned == 0")
+	}
+	if 0 == defragmenterStatus.InodesOptimized {
+		t.Fatalf("FetchDefragmenterStatus() [stopped] returned InodesOptimized == 0")
+	}
+	if 0 != defragmenterStatus.OptimizeFailures {
+		t.Fatalf("FetchDefragmenterStatus() [stopped] returned OptimizeFailures == %v", defragmenterStatus.OptimizeFailures)
+	}
+
+	fragmentationReport, err := testVolumeHandle.GetFragmentationReport(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("GetFragmentationReport() [after] failed: %v", err)
+	}
+	if 1 != fragmentationReport.NumberOfLogSegments {
+		t.Fatalf("GetFragmentationReport() [after] returned NumberOfLogSegments == %v (expected 1)", fragmentationReport.NumberOfLogSegments)
+	}
+	if 0 != fragmentationReport.BytesTrapped {
+		t.Fatalf("GetFragmentationReport() [after] returned BytesTrapped == %v (expected 0)", fragmentationReport.BytesTrapped)
+	}
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, 64, nil)
+	if nil != err {
+		t.Fatalf("Read() [after] failed: %v", err)
+	}
+	if 0 != bytes.Compare(expectedBuf, readBuf) {
+		t.Fatalf("Read() [after] returned %v (expected %v)", string(readBuf), string(expectedBuf))
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
+
+func TestDefragmenterReadOnly(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	err = testConfMap.UpdateFromString("Volume:TestVolume.ReadOnly=true")
+	if nil != err {
+		t.Fatalf("UpdateFromString() failed: %v", err)
+	}
+	vS.adoptReadOnlyParameter(testConfMap)
+
+	err = testVolumeHandle.StartDefragmenter()
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("StartDefragmenter() on read-only volume should have failed with ReadOnlyError: %v", err)
+	}
+
+	// A defragmenter stopped for a pause isn't restarted while the volume is read-only...
+
+	vS.Lock()
+	vS.defragmenter.restartOnResume = true
+	vS.Unlock()
+
+	err = vS.startDefragmenterIfEnabled()
+	if nil != err {
+		t.Fatalf("startDefragmenterIfEnabled() on read-only volume failed: %v", err)
+	}
+	if testVolumeHandle.FetchDefragmenterStatus().Running {
+		t.Fatalf("startDefragmenterIfEnabled() on read-only volume should not have started the defragmenter")
+	}
+
+	// ...but is once it no longer is
+
+	err = testConfMap.UpdateFromString("Volume:TestVolume.ReadOnly=false")
+	if nil != err {
+		t.Fatalf("UpdateFromString() failed: %v", err)
+	}
+	vS.adoptReadOnlyParameter(testConfMap)
+
+	err = vS.startDefragmenterIfEnabled()
+	if nil != err {
+		t.Fatalf("startDefragmenterIfEnabled() failed: %v", err)
+	}
+	if !testVolumeHandle.FetchDefragmenterStatus().Running {
+		t.Fatalf("startDefragmenterIfEnabled() should have restarted the defragmenter")
+	}
+
+	err = testVolumeHandle.StopDefragmenter()
+	if nil != err {
+		t.Fatalf("StopDefragmenter() failed: %v", err)
+	}
+}
+
+func TestDefragmenterIncrementalScan(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, _ := testMakeFragmentedFile(t, testVolumeHandle)
+
+	// Scan one inode per pass... without a single HEAD of a log segment object
+
+	countingObjectStore := &testCountingObjectStore{ObjectStore: vS.objectStore}
+
+	vS.Lock()
+	vS.objectStore = countingObjectStore
+	vS.defragmenter.maxInodesPerPass = 1
+	vS.defragmenter.scanCursor = 0
+	vS.Unlock()
+
+	stopChan := make(chan bool, 1)
+	passes := 0
+	victimFound := false
+
+	for {
+		inodesScannedBefore := testVolumeHandle.FetchDefragmenterStatus().InodesScanned
+
+		victims, stopped := vS.defragmenterSelectVictims(stopChan)
+		if stopped {
+			t.Fatalf("defragmenterSelectVictims() unexpectedly returned stopped == true")
+		}
+
+		passes++
+
+		inodesScanned := testVolumeHandle.FetchDefragmenterStatus().InodesScanned - inodesScannedBefore
+		if 1 < inodesScanned {
+			t.Fatalf("defragmenterSelectVictims() scanned %v inodes (expected at most 1)", inodesScanned)
+		}
+
+		for _, victim := range victims {
+			if fileInodeNumber == victim.inodeNumber {
+				victimFound = true
+			}
+		}
+
+		if 0 == vS.defragmenter.scanCursor {
+			break // Wrapped
+		}
+	}
+
+	vS.Lock()
+	vS.objectStore = countingObjectStore.ObjectStore
+	vS.defragmenter.maxInodesPerPass = defragmenterMaxInodesPerPassDefault
+	vS.Unlock()
+
+	if 2 >= passes {
+		t.Fatalf("defragmenterSelectVictims() covered the volume in only %v passes", passes)
+	}
+	if !victimFound {
+		t.Fatalf("defragmenterSelectVictims() never selected fragmented inode %v", fileInodeNumber)
+	}
+	if 0 != countingObjectStore.objectContentLengthCalls {
+		t.Fatalf("defragmenterSelectVictims() made %v ObjectContentLength() calls (expected 0)", countingObjectStore.objectContentLengthCalls)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
@@ -10,9 +10,9 @@ import (
 	"github.com/swiftstack/sortedmap"
 
 	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/stats"
-	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -658,9 +658,21 @@ func (vS *volumeStruct) Wrote(fileInodeNumber InodeNumber, fileOffset uint64, ob
 		return
 	}
 
-	err = fileInode.volume.setLogSegmentContainer(logSegmentNumber, containerName)
-	if nil != err {
-		return
+	// Swift wrote the object in its entirety, so its length is known now... and, unless some
+	// earlier call already accounted for (at least) this much of it, must be added to the volume
+
+	_, recordedObjectLength, getErr := fileInode.volume.getLogSegmentRec(logSegmentNumber)
+	if nil != getErr {
+		recordedObjectLength = 0
+	}
+	if (objectOffset + length) > recordedObjectLength {
+		err = fileInode.volume.putLogSegmentRec(logSegmentNumber, containerName, objectOffset+length)
+		if nil != err {
+			return
+		}
+		fileInode.Lock()
+		fileInode.logSegmentBytesPending += (objectOffset + length) - recordedObjectLength
+		fileInode.Unlock()
 	}
 
 	fileInode.dirty = true
@@ -956,18 +968,63 @@ func (vS *volumeStruct) Coalesce(containingDirInodeNumber InodeNumber, combinati
 	return
 }
 
-func (vS *volumeStruct) getLogSegmentContainer(logSegmentNumber uint64) (containerName string, err error) {
-	containerNameAsByteSlice, err := vS.headhunterVolumeHandle.GetLogSegmentRec(logSegmentNumber)
+func (fileInode *inMemoryInodeStruct) sumLogSegmentMap() (referencedBytes uint64) {
+	for _, logSegmentBytesUsed := range fileInode.LogSegmentMap {
+		referencedBytes += logSegmentBytesUsed
+	}
+	return
+}
+
+// collectVolumeStatsDeltas returns the changes to VolumeStats.{LogSegmentBytes|ReferencedBytes} made by
+// fileInode since they were last collected. Only called once fileInode's updated inodeRec has been put.
+func (fileInode *inMemoryInodeStruct) collectVolumeStatsDeltas() (logSegmentBytesDelta int64, referencedBytesDelta int64) {
+	referencedBytes := fileInode.sumLogSegmentMap()
+
+	fileInode.Lock()
+	logSegmentBytesDelta = int64(fileInode.logSegmentBytesPending)
+	fileInode.logSegmentBytesPending = 0
+	fileInode.Unlock()
+
+	referencedBytesDelta = int64(referencedBytes) - int64(fileInode.referencedBytesReported)
+	fileInode.referencedBytesReported = referencedBytes
+
+	return
+}
+
+func (vS *volumeStruct) getLogSegmentRec(logSegmentNumber uint64) (containerName string, objectLength uint64, err error) {
+	logSegmentRec, err := vS.headhunterVolumeHandle.GetLogSegmentRec(logSegmentNumber)
 	if nil != err {
 		return
 	}
-	containerName = utils.ByteSliceToString(containerNameAsByteSlice)
+	containerName, objectLength, err = headhunter.DecodeLogSegmentRec(logSegmentRec)
+	if nil != err {
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+	}
+	return
+}
+
+func (vS *volumeStruct) putLogSegmentRec(logSegmentNumber uint64, containerName string, objectLength uint64) (err error) {
+	err = vS.headhunterVolumeHandle.PutLogSegmentRec(logSegmentNumber, headhunter.EncodeLogSegmentRec(containerName, objectLength))
+	if nil != err {
+		return
+	}
+
+	// Now referenced by its LogSegmentRec, an object from ProvisionObject() need no longer be tracked
+
+	vS.Lock()
+	delete(vS.provisionedObjectMap, logSegmentNumber)
+	vS.Unlock()
+
+	return
+}
+
+func (vS *volumeStruct) getLogSegmentContainer(logSegmentNumber uint64) (containerName string, err error) {
+	containerName, _, err = vS.getLogSegmentRec(logSegmentNumber)
 	return
 }
 
 func (vS *volumeStruct) setLogSegmentContainer(logSegmentNumber uint64, containerName string) (err error) {
-	containerNameAsByteSlice := utils.StringToByteSlice(containerName)
-	err = vS.headhunterVolumeHandle.PutLogSegmentRec(logSegmentNumber, containerNameAsByteSlice)
+	err = vS.putLogSegmentRec(logSegmentNumber, containerName, 0)
 	return
 }
 
@@ -996,7 +1053,7 @@ func (vS *volumeStruct) getObjectPathFromContainerNameAndLogSegmentNumber(contai
 }
 
 func (vS *volumeStruct) deleteLogSegmentAsync(logSegmentNumber uint64, checkpointDoneWaitGroup *sync.WaitGroup) (err error) {
-	containerName, err := vS.getLogSegmentContainer(logSegmentNumber)
+	containerName, objectLength, err := vS.getLogSegmentRec(logSegmentNumber)
 	if nil != err {
 		return
 	}
@@ -1005,6 +1062,40 @@ func (vS *volumeStruct) deleteLogSegmentAsync(logSegmentNumber uint64, checkpoin
 	if nil != err {
 		return
 	}
-	swiftclient.ObjectDeleteAsync(vS.accountName, containerName, objectName, checkpointDoneWaitGroup, nil)
+	vS.headhunterVolumeHandle.AdjustVolumeStats(-int64(objectLength), 0)
+	referencedBySnapshot, snapshotErr := vS.headhunterVolumeHandle.SnapshotReferencesLogSegment(logSegmentNumber)
+	if nil != snapshotErr {
+		// Err on the side of leaking the log segment object
+		logger.ErrorfWithError(snapshotErr, "couldn't determine if a snapshot references log segment %v/%v", containerName, objectName)
+		return
+	}
+	if referencedBySnapshot {
+		// Object deletion will be issued once the last snapshot referencing it is deleted
+		stats.IncrementOperations(&stats.SnapshotLogSegmentRetainedOps)
+		return
+	}
+	if vS.deferLogSegmentDeleteIfPinned(logSegmentNumber, containerName, checkpointDoneWaitGroup) {
+		// Object deletion will be issued once the last lease pinning it is released or expires
+		return
+	}
+	vS.objectDeleteAfterCheckpoint(containerName, objectName, checkpointDoneWaitGroup)
 	return
 }
+
+// objectDeleteAfterCheckpoint deletes the object once the checkpoint awaited via checkpointDoneWaitGroup
+// has completed. Should the volume instead have been fenced by another Peer (whose checkpoint may well
+// still reference the object), the deletion is discarded.
+func (vS *volumeStruct) objectDeleteAfterCheckpoint(containerName string, objectName string, checkpointDoneWaitGroup *sync.WaitGroup) {
+	go func() {
+		if nil != checkpointDoneWaitGroup {
+			checkpointDoneWaitGroup.Wait()
+		}
+
+		if vS.headhunterVolumeHandle.Fenced() {
+			stats.IncrementOperations(&stats.ClusterFencedObjectDeleteOps)
+			return
+		}
+
+		vS.objectStore.ObjectDeleteAsync(vS.accountName, containerName, objectName, nil, nil)
+	}()
+}
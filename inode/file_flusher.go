@@ -6,7 +6,6 @@ import (
 	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/stats"
-	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -130,7 +129,7 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 				stats.IncrementOperations(&stats.FileReadcacheMissOps)
 				// Make readCacheHit true (at MRU, likely kicking out LRU)
 				cacheLineStartOffset = readCacheKey.cacheLineTag * readCacheLineSize
-				cacheLine, err = swiftclient.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, cacheLineStartOffset, readCacheLineSize)
+				cacheLine, err = vS.objectStore.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, cacheLineStartOffset, readCacheLineSize)
 				if nil != err {
 					logger.ErrorfWithError(err, "Reading from LogSegment object failed - optimal case")
 					err = blunder.AddError(err, blunder.SegReadError)
@@ -221,7 +220,7 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 						stats.IncrementOperations(&stats.FileReadcacheMissOps)
 						// Make readCacheHit true (at MRU, likely kicking out LRU)
 						cacheLineStartOffset = readCacheKey.cacheLineTag * readCacheLineSize
-						cacheLine, err = swiftclient.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, cacheLineStartOffset, readCacheLineSize)
+						cacheLine, err = vS.objectStore.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, cacheLineStartOffset, readCacheLineSize)
 						if nil != err {
 							logger.ErrorfWithError(err, "Reading from LogSegment object failed - general case")
 							err = blunder.AddError(err, blunder.SegReadError)
@@ -289,7 +288,7 @@ func (vS *volumeStruct) doSendChunk(fileInode *inMemoryInodeStruct, buf []byte)
 
 		fileInode.inFlightLogSegmentMap[fileInode.openLogSegment.logSegmentNumber] = fileInode.openLogSegment
 
-		fileInode.openLogSegment.ChunkedPutContext, err = swiftclient.ObjectFetchChunkedPutContext(fileInode.openLogSegment.accountName, fileInode.openLogSegment.containerName, fileInode.openLogSegment.objectName)
+		fileInode.openLogSegment.ChunkedPutContext, err = vS.objectStore.ObjectFetchChunkedPutContext(fileInode.openLogSegment.accountName, fileInode.openLogSegment.containerName, fileInode.openLogSegment.objectName)
 		if nil != err {
 			logger.ErrorfWithError(err, "Starting Chunked PUT to LogSegment failed")
 			return
@@ -310,6 +309,8 @@ func (vS *volumeStruct) doSendChunk(fileInode *inMemoryInodeStruct, buf []byte)
 		return
 	}
 
+	fileInode.logSegmentBytesPending += uint64(len(buf))
+
 	if (logSegmentOffset + uint64(len(buf))) >= fileInode.volume.flowControl.maxFlushSize {
 		fileInode.Add(1)
 		go inFlightLogSegmentFlusher(fileInode.openLogSegment)
@@ -344,11 +345,19 @@ func (vS *volumeStruct) doFileInodeDataFlush(fileInode *inMemoryInodeStruct) (er
 
 func inFlightLogSegmentFlusher(inFlightLogSegment *inFlightLogSegmentStruct) {
 	var (
-		err error
+		err          error
+		objectLength uint64
 	)
 
 	// Terminate Chunked PUT
 	err = inFlightLogSegment.Close()
+	if nil == err {
+		// Record the object's now final length so that deleting it needn't HEAD it
+		objectLength, err = inFlightLogSegment.BytesPut()
+		if nil == err {
+			err = inFlightLogSegment.fileInode.volume.putLogSegmentRec(inFlightLogSegment.logSegmentNumber, inFlightLogSegment.containerName, objectLength)
+		}
+	}
 	if nil != err {
 		err = blunder.AddError(err, blunder.InodeFlushError)
 		inFlightLogSegment.fileInode.Lock()
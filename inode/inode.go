@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,8 +15,8 @@ import (
 
 	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
 	"github.com/swiftstack/ProxyFS/stats"
-	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -60,7 +61,7 @@ type inFlightLogSegmentStruct struct { // Used as (by reference) Value for inMem
 	accountName      string
 	containerName    string
 	objectName       string
-	swiftclient.ChunkedPutContext
+	objectstore.ChunkedPutContext
 }
 
 type inMemoryInodeStruct struct {
@@ -73,6 +74,12 @@ type inMemoryInodeStruct struct {
 	openLogSegment           *inFlightLogSegmentStruct            // FileInode only... also in inFlightLogSegmentMap
 	inFlightLogSegmentMap    map[uint64]*inFlightLogSegmentStruct // FileInode: key == logSegmentNumber
 	inFlightLogSegmentErrors map[uint64]error                     // FileInode: key == logSegmentNumber; value == err (if non nil)
+	logSegmentBytesPending   uint64                               // FileInode: bytes sent to log segments not yet reflected in VolumeStats.LogSegmentBytes
+	referencedBytesReported  uint64                               // FileInode: sum of LogSegmentMap last reflected in VolumeStats.ReferencedBytes
+	ownerReported            bool                                 // set once this inode is reflected in headhunter's per-owner stats
+	userIDReported           InodeUserID                          // only valid if ownerReported == true
+	groupIDReported          InodeGroupID                         // only valid if ownerReported == true
+	quotaReservations        []QuotaReservation                   // settled on this inode... released once it is flushed
 	onDiskInodeV1Struct                                           // Real on-disk inode information embedded here
 }
 
@@ -142,6 +149,12 @@ func (vS *volumeStruct) fetchOnDiskInode(inodeNumber InodeNumber) (inMemoryInode
 		onDiskInodeV1Struct:      *onDiskInodeV1,
 	}
 
+	inMemoryInode.referencedBytesReported = inMemoryInode.sumLogSegmentMap()
+
+	inMemoryInode.ownerReported = true
+	inMemoryInode.userIDReported = inMemoryInode.UserID
+	inMemoryInode.groupIDReported = inMemoryInode.GroupID
+
 	switch inMemoryInode.InodeType {
 	case DirType:
 		if 0 == inMemoryInode.PayloadObjectNumber {
@@ -284,6 +297,28 @@ func (vS *volumeStruct) makeInMemoryInodeWithThisInodeNumber(inodeType InodeType
 	return
 }
 
+// reportOwnerStats is called once inode has been flushed to reflect it in headhunter's per-owner
+// stats. The first flush of a new inode charges its owner with the inode (and whatever bytes it
+// references). Subsequent flushes charge referencedBytesDelta to the current owner, first moving
+// everything previously charged to a prior owner should UserID or GroupID have since changed.
+func (inode *inMemoryInodeStruct) reportOwnerStats(referencedBytesDelta int64) {
+	headhunterVolumeHandle := inode.volume.headhunterVolumeHandle
+
+	if !inode.ownerReported {
+		headhunterVolumeHandle.AdjustOwnerStats(uint32(inode.UserID), uint32(inode.GroupID), int64(inode.referencedBytesReported), 1)
+	} else if (inode.UserID != inode.userIDReported) || (inode.GroupID != inode.groupIDReported) {
+		previouslyReferencedBytes := int64(inode.referencedBytesReported) - referencedBytesDelta
+		headhunterVolumeHandle.AdjustOwnerStats(uint32(inode.userIDReported), uint32(inode.groupIDReported), -previouslyReferencedBytes, -1)
+		headhunterVolumeHandle.AdjustOwnerStats(uint32(inode.UserID), uint32(inode.GroupID), int64(inode.referencedBytesReported), 1)
+	} else {
+		headhunterVolumeHandle.AdjustOwnerStats(uint32(inode.UserID), uint32(inode.GroupID), referencedBytesDelta, 0)
+	}
+
+	inode.ownerReported = true
+	inode.userIDReported = inode.UserID
+	inode.groupIDReported = inode.GroupID
+}
+
 func (vS *volumeStruct) makeInMemoryInode(inodeType InodeType, fileMode InodeMode, userID InodeUserID, groupID InodeGroupID) (inMemoryInode *inMemoryInodeStruct, err error) {
 	inodeNumberAsUint64, err := vS.headhunterVolumeHandle.FetchNonce()
 	if nil != err {
@@ -353,6 +388,9 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 		emptyLogSegments          []uint64
 		emptyLogSegmentsThisInode []uint64
 		inode                     *inMemoryInodeStruct
+		inodeLogSegmentBytesDelta int64
+		inodeReferencedBytesDelta int64
+		logSegmentBytesDelta      int64
 		logSegmentNumber          uint64
 		logSegmentValidBytes      uint64
 		payloadAsBPlusTree        sortedmap.BPlusTree
@@ -360,6 +398,7 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 		payloadObjectNumber       uint64
 		onDiskInodeV1             *onDiskInodeV1Struct
 		onDiskInodeV1Buf          []byte
+		referencedBytesDelta      int64
 	)
 
 	// Assemble slice of "dirty" inodes while flushing them
@@ -437,6 +476,18 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 		}
 		for _, inode = range inodes {
 			inode.dirty = false
+			if FileType == inode.InodeType {
+				inodeLogSegmentBytesDelta, inodeReferencedBytesDelta = inode.collectVolumeStatsDeltas()
+				logSegmentBytesDelta += inodeLogSegmentBytesDelta
+				referencedBytesDelta += inodeReferencedBytesDelta
+			} else {
+				inodeReferencedBytesDelta = 0
+			}
+			inode.reportOwnerStats(inodeReferencedBytesDelta)
+		}
+		vS.headhunterVolumeHandle.AdjustVolumeStats(logSegmentBytesDelta, referencedBytesDelta)
+		for _, inode = range inodes {
+			vS.releaseInodeQuotaReservations(inode)
 		}
 		checkpointDoneWaitGroup = vS.headhunterVolumeHandle.FetchNextCheckPointDoneWaitGroup()
 	} else {
@@ -589,7 +640,7 @@ func (vS *volumeStruct) provisionPhysicalContainer(physicalContainerLayout *phys
 		newContainerHeaders := make(map[string][]string)
 		newContainerHeaders["X-Storage-Policy"] = storagePolicyHeaderValues
 
-		err = swiftclient.ContainerPut(vS.accountName, newContainerName, newContainerHeaders)
+		err = vS.objectStore.ContainerPut(vS.accountName, newContainerName, newContainerHeaders)
 		if nil != err {
 			return
 		}
@@ -711,11 +762,23 @@ func (vS *volumeStruct) Access(inodeNumber InodeNumber, userID InodeUserID, grou
 }
 
 func (vS *volumeStruct) ProvisionObject() (objectPath string, err error) {
+	if nil != vS.snapshotOf {
+		err = vS.snapshotReadOnlyError(utils.GetFnName())
+		return
+	}
+
 	containerName, objectNumber, err := vS.provisionObject()
 	if nil != err {
 		return
 	}
 
+	// Until Wrote() records it, nothing references the object the caller is about to PUT... so
+	// remember it lest a concurrent scrub take it for an orphan (see putLogSegmentRec())
+
+	vS.Lock()
+	vS.provisionedObjectMap[objectNumber] = time.Now()
+	vS.Unlock()
+
 	objectPath = fmt.Sprintf("/v1/%s/%s/%016X", vS.accountName, containerName, objectNumber)
 
 	err = nil
@@ -780,6 +843,17 @@ func (vS *volumeStruct) Destroy(inodeNumber InodeNumber) (err error) {
 		return
 	}
 
+	if FileType == ourInode.InodeType {
+		// Account for data written since the last flush... none of which (nor any prior data) remains referenced
+		vS.headhunterVolumeHandle.AdjustVolumeStats(int64(ourInode.logSegmentBytesPending), -int64(ourInode.referencedBytesReported))
+	}
+
+	if ourInode.ownerReported {
+		vS.headhunterVolumeHandle.AdjustOwnerStats(uint32(ourInode.userIDReported), uint32(ourInode.groupIDReported), -int64(ourInode.referencedBytesReported), -1)
+	}
+
+	vS.releaseInodeQuotaReservations(ourInode)
+
 	if DirType == ourInode.InodeType {
 		dirMapping := ourInode.payload.(sortedmap.BPlusTree)
 
@@ -1323,12 +1397,277 @@ func (vS *volumeStruct) DeleteStream(inodeNumber InodeNumber, inodeStreamName st
 }
 
 func (vS *volumeStruct) GetFragmentationReport(inodeNumber InodeNumber) (fragmentationReport FragmentationReport, err error) {
-	err = fmt.Errorf("GetFragmentationReport not yet implemented")
+	fileInode, err := vS.fetchInodeType(inodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	// Flush first so that every referenced log segment has been closed (and, hence, has a known size)
+
+	if fileInode.dirty {
+		err = flush(fileInode, false)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	}
+
+	fragmentationReport, err = vS.getFragmentationReportHelper(fileInode)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	stats.IncrementOperations(&stats.FileFragmentationReportOps)
+
+	return
+}
+
+func (vS *volumeStruct) getFragmentationReportHelper(fileInode *inMemoryInodeStruct) (fragmentationReport FragmentationReport, err error) {
+	var (
+		containerName      string
+		extent             *fileExtentStruct
+		extentIndex        int
+		extentValue        sortedmap.Value
+		logSegmentNumber   uint64
+		logSegmentNumbers  []uint64
+		logSegmentReport   LogSegmentReport
+		numExtents         int
+		objectContentBytes uint64
+		ok                 bool
+	)
+
+	extents := fileInode.payload.(sortedmap.BPlusTree)
+
+	numExtents, err = extents.Len()
+	if nil != err {
+		panic(err)
+	}
+
+	for extentIndex = 0; extentIndex < numExtents; extentIndex++ {
+		_, extentValue, ok, err = extents.GetByIndex(extentIndex)
+		if nil != err {
+			panic(err)
+		}
+		if !ok {
+			err = fmt.Errorf("unexpected extents indexing problem")
+			panic(err)
+		}
+		extent = extentValue.(*fileExtentStruct)
+		fragmentationReport.BytesInFragments += extent.Length
+	}
+
+	fragmentationReport.NumberOfFragments = uint64(numExtents)
+
+	logSegmentNumbers = make([]uint64, 0, len(fileInode.LogSegmentMap))
+	for logSegmentNumber = range fileInode.LogSegmentMap {
+		logSegmentNumbers = append(logSegmentNumbers, logSegmentNumber)
+	}
+	sort.Slice(logSegmentNumbers, func(i int, j int) bool { return logSegmentNumbers[i] < logSegmentNumbers[j] })
+
+	fragmentationReport.NumberOfLogSegments = uint64(len(logSegmentNumbers))
+	fragmentationReport.LogSegmentReports = make([]LogSegmentReport, 0, len(logSegmentNumbers))
+
+	for _, logSegmentNumber = range logSegmentNumbers {
+		// The length of each closed log segment is recorded in its LogSegmentRec... only those
+		// recorded before that was the case (i.e. with no length) need their object be HEAD'd
+
+		containerName, objectContentBytes, err = vS.getLogSegmentRec(logSegmentNumber)
+		if nil != err {
+			return
+		}
+		if 0 == objectContentBytes {
+			objectContentBytes, err = vS.objectStore.ObjectContentLength(vS.accountName, containerName, utils.Uint64ToHexStr(logSegmentNumber))
+			if nil != err {
+				err = blunder.AddError(err, blunder.SegReadError)
+				return
+			}
+		}
+
+		logSegmentReport = LogSegmentReport{
+			LogSegmentNumber: logSegmentNumber,
+			BytesReferenced:  fileInode.LogSegmentMap[logSegmentNumber],
+			BytesHeld:        objectContentBytes,
+		}
+
+		if logSegmentReport.BytesHeld > logSegmentReport.BytesReferenced {
+			fragmentationReport.BytesTrapped += logSegmentReport.BytesHeld - logSegmentReport.BytesReferenced
+		}
+
+		fragmentationReport.LogSegmentReports = append(fragmentationReport.LogSegmentReports, logSegmentReport)
+	}
+
+	err = nil
 	return
 }
 
+// Optimize rewrites the live extents of a FileInode, in file offset order, into freshly provisioned
+// log segments. Log segments that are no longer referenced as a result are released via the normal
+// flushInodes() garbage collection path. Should maxDuration expire before every extent has been
+// rewritten, the FileInode is left in a consistent (albeit only partially optimized) state.
 func (vS *volumeStruct) Optimize(inodeNumber InodeNumber, maxDuration time.Duration) (err error) {
-	err = fmt.Errorf("Optimize not yet implemented")
+	fileInode, err := vS.fetchInodeType(inodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	_, err = vS.optimizeHelper(fileInode, maxDuration)
+
+	return
+}
+
+func (vS *volumeStruct) optimizeHelper(fileInode *inMemoryInodeStruct, maxDuration time.Duration) (bytesRewritten uint64, err error) {
+	var (
+		buf                 []byte
+		chunkLength         uint64
+		chunkOffset         uint64
+		containerName       string
+		deadline            time.Time
+		extent              *fileExtentStruct
+		extentFileOffset    uint64
+		extentIndex         int
+		extentLength        uint64
+		extentLogSegmentNum uint64
+		extentLogSegmentOff uint64
+		extentValue         sortedmap.Value
+		fileOffset          uint64
+		found               bool
+		fragmentationReport FragmentationReport
+		newLogSegmentNumber uint64
+		newLogSegmentOffset uint64
+		objectName          string
+		ok                  bool
+		outOfTime           bool
+	)
+
+	deadline = time.Now().Add(maxDuration)
+
+	if fileInode.dirty {
+		err = flush(fileInode, false)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	}
+
+	fragmentationReport, err = vS.getFragmentationReportHelper(fileInode)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	if (fragmentationReport.NumberOfFragments <= fragmentationReport.NumberOfLogSegments) && (0 == fragmentationReport.BytesTrapped) {
+		// Each log segment already holds a single contiguous extent with nothing trapped... nothing to gain
+
+		stats.IncrementOperationsAndBytes(stats.FileOptimize, 0)
+
+		err = nil
+		return
+	}
+
+	extents := fileInode.payload.(sortedmap.BPlusTree)
+
+	fileOffset = 0
+	outOfTime = false
+
+	for !outOfTime {
+		extentIndex, found, err = extents.BisectLeft(fileOffset)
+		if nil != err {
+			panic(err)
+		}
+		if !found {
+			// Any extent at extentIndex starts (and, since we rewrite whole extents, ends) before fileOffset
+			extentIndex++
+		}
+
+		_, extentValue, ok, err = extents.GetByIndex(extentIndex)
+		if nil != err {
+			panic(err)
+		}
+		if !ok {
+			// We have reached the end of extents
+			break
+		}
+
+		// Capture extent by value as recordWrite() will be trimming it out from under us
+
+		extent = extentValue.(*fileExtentStruct)
+
+		extentFileOffset = extent.FileOffset
+		extentLength = extent.Length
+		extentLogSegmentNum = extent.LogSegmentNumber
+		extentLogSegmentOff = extent.LogSegmentOffset
+
+		containerName, objectName, _, err = vS.getObjectLocationFromLogSegmentNumber(extentLogSegmentNum)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+
+		// Rewrite the extent in chunks no larger than a single flush so that we never hold much in memory
+
+		chunkOffset = 0
+
+		for chunkOffset < extentLength {
+			if time.Now().After(deadline) {
+				outOfTime = true
+				break
+			}
+
+			chunkLength = extentLength - chunkOffset
+			if chunkLength > vS.flowControl.maxFlushSize {
+				chunkLength = vS.flowControl.maxFlushSize
+			}
+
+			buf, err = vS.objectStore.ObjectGet(vS.accountName, containerName, objectName, extentLogSegmentOff+chunkOffset, chunkLength)
+			if nil != err {
+				logger.ErrorfWithError(err, "Reading from LogSegment object failed during Optimize()")
+				err = blunder.AddError(err, blunder.SegReadError)
+				return
+			}
+			if uint64(len(buf)) != chunkLength {
+				err = fmt.Errorf("Invalid range for LogSegment object 0x%016X during Optimize()", extentLogSegmentNum)
+				logger.ErrorWithError(err)
+				err = blunder.AddError(err, blunder.SegReadError)
+				return
+			}
+
+			fileInode.dirty = true
+
+			newLogSegmentNumber, newLogSegmentOffset, err = vS.doSendChunk(fileInode, buf)
+			if nil != err {
+				logger.ErrorWithError(err)
+				return
+			}
+
+			err = recordWrite(fileInode, extentFileOffset+chunkOffset, chunkLength, newLogSegmentNumber, newLogSegmentOffset)
+			if nil != err {
+				logger.ErrorWithError(err)
+				return
+			}
+
+			chunkOffset += chunkLength
+			bytesRewritten += chunkLength
+		}
+
+		fileOffset = extentFileOffset + chunkOffset
+	}
+
+	if 0 < bytesRewritten {
+		// Flushing will also release any log segments no longer referenced
+
+		err = vS.flushInode(fileInode)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	}
+
+	stats.IncrementOperationsAndBytes(stats.FileOptimize, bytesRewritten)
+
+	err = nil
 	return
 }
 
@@ -1403,7 +1742,7 @@ func validateFileExtents(ourInode *inMemoryInodeStruct) (err error) {
 			return err
 		}
 
-		contentLength, err := swiftclient.ObjectContentLength(accountName, containerName, objectName)
+		contentLength, err := ourInode.volume.objectStore.ObjectContentLength(accountName, containerName, objectName)
 		if err != nil {
 			logger.ErrorWithError(err)
 			return err
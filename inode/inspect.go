@@ -0,0 +1,266 @@
+package inode
+
+// Offline inspection
+//
+// An InspectionHandle decodes inode records (and their payload B+Trees) straight from a headhunter.VolumeHandle
+// without any of the caching, flushing, or validation performed on behalf of a served volume. Inodes marked as
+// corrupt are decoded anyway (reporting CorruptionDetected) as it is precisely such inodes one wants to examine.
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/swiftstack/cstruct"
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+type inspectionVolumeStruct struct {
+	volume *volumeStruct // only volumeName & headhunterVolumeHandle are set... not in globals.volumeMap
+}
+
+func fetchInspectionHandle(volumeName string, headhunterVolumeHandle headhunter.VolumeHandle) (inspectionHandle InspectionHandle, err error) {
+	if 0 == globals.fileExtentStructSize {
+		// Up() has not been called
+
+		globals.fileExtentStructSize, _, err = cstruct.Examine(fileExtentStruct{})
+		if nil != err {
+			return
+		}
+	}
+
+	inspectionHandle = &inspectionVolumeStruct{
+		volume: &volumeStruct{
+			volumeName:             volumeName,
+			headhunterVolumeHandle: headhunterVolumeHandle,
+		},
+	}
+
+	err = nil
+	return
+}
+
+func (inspectionVolume *inspectionVolumeStruct) NextInodeNumber(prevInodeNumber InodeNumber) (nextInodeNumber InodeNumber, ok bool, err error) {
+	nextInodeNumberAsUint64, ok, err := inspectionVolume.volume.headhunterVolumeHandle.NextInodeNumber(uint64(prevInodeNumber))
+	nextInodeNumber = InodeNumber(nextInodeNumberAsUint64)
+	return
+}
+
+// decodeInodeRec unpacks the inode record for inodeNumber opening its payload B+Tree (if any) on the way.
+func (inspectionVolume *inspectionVolumeStruct) decodeInodeRec(inodeNumber InodeNumber) (inodeReport *InodeReport, payload sortedmap.BPlusTree, err error) {
+	inodeReport, err = inspectionVolume.decodeOnDiskInode(inodeNumber)
+	if nil != err {
+		return
+	}
+
+	if 0 == inodeReport.PayloadObjectNumber {
+		payload = nil
+		err = nil
+		return
+	}
+
+	// The payload B+Tree is read via the treeNodeLoadable of an (otherwise unused) inMemoryInodeStruct
+
+	inMemoryInode := &inMemoryInodeStruct{volume: inspectionVolume.volume}
+
+	switch inodeReport.InodeType {
+	case DirType:
+		payload, err =
+			sortedmap.OldBPlusTree(
+				inodeReport.PayloadObjectNumber,
+				onDiskInodeV1PayloadObjectOffset,
+				inodeReport.PayloadObjectLength,
+				sortedmap.CompareString,
+				&dirInodeCallbacks{treeNodeLoadable{inode: inMemoryInode}},
+				nil)
+	case FileType:
+		payload, err =
+			sortedmap.OldBPlusTree(
+				inodeReport.PayloadObjectNumber,
+				onDiskInodeV1PayloadObjectOffset,
+				inodeReport.PayloadObjectLength,
+				sortedmap.CompareUint64,
+				&fileInodeCallbacks{treeNodeLoadable{inode: inMemoryInode}},
+				nil)
+	default:
+		err = fmt.Errorf("%s: inode %d of type %v unexpectedly has a payload", utils.GetFnName(), inodeNumber, inodeReport.InodeType)
+	}
+	if nil != err {
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+		return
+	}
+
+	err = nil
+	return
+}
+
+// decodeOnDiskInode unpacks the inode record for inodeNumber leaving its payload B+Tree (if any) unopened.
+func (inspectionVolume *inspectionVolumeStruct) decodeOnDiskInode(inodeNumber InodeNumber) (inodeReport *InodeReport, err error) {
+	var (
+		bytesConsumedByCorruptionDetected uint64
+		bytesConsumedByVersion            uint64
+		inodeRec                          []byte
+		ok                                bool
+	)
+
+	inodeRec, ok, err = inspectionVolume.volume.headhunterVolumeHandle.GetInodeRec(uint64(inodeNumber))
+	if nil != err {
+		err = fmt.Errorf("%s: unable to get inodeRec for inode %d: %v", utils.GetFnName(), inodeNumber, err)
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("%s: inode %d not found", utils.GetFnName(), inodeNumber)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	inodeReport = &InodeReport{
+		onDiskInodeV1Struct: onDiskInodeV1Struct{StreamMap: make(map[string][]byte)},
+	}
+
+	bytesConsumedByCorruptionDetected, err = cstruct.Unpack(inodeRec, &inodeReport.CorruptionDetected, cstruct.LittleEndian)
+	if nil != err {
+		err = fmt.Errorf("%s: unable to parse inodeRec.CorruptionDetected for inode %d: %v", utils.GetFnName(), inodeNumber, err)
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+		return
+	}
+
+	bytesConsumedByVersion, err = cstruct.Unpack(inodeRec[bytesConsumedByCorruptionDetected:], &inodeReport.Version, cstruct.LittleEndian)
+	if nil != err {
+		err = fmt.Errorf("%s: unable to get inodeRec.Version for inode %d: %v", utils.GetFnName(), inodeNumber, err)
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+		return
+	}
+	if V1 != inodeReport.Version {
+		err = fmt.Errorf("%s: inodeRec.Version for inode %d (%v) not supported", utils.GetFnName(), inodeNumber, inodeReport.Version)
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+		return
+	}
+
+	err = json.Unmarshal(inodeRec[bytesConsumedByCorruptionDetected+bytesConsumedByVersion:], &inodeReport.onDiskInodeV1Struct)
+	if nil != err {
+		err = fmt.Errorf("%s: inodeRec.<body> for inode %d json.Unmarshal() failed: %v", utils.GetFnName(), inodeNumber, err)
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (inspectionVolume *inspectionVolumeStruct) InspectInode(inodeNumber InodeNumber) (inodeReport *InodeReport, err error) {
+	var (
+		itemIndex     int
+		key           sortedmap.Key
+		numberOfItems int
+		ok            bool
+		payload       sortedmap.BPlusTree
+		value         sortedmap.Value
+	)
+
+	inodeReport, payload, err = inspectionVolume.decodeInodeRec(inodeNumber)
+	if nil != err {
+		return
+	}
+
+	switch inodeReport.InodeType {
+	case DirType:
+		inodeReport.DirEntries = make([]InodeReportDirEntry, 0)
+	case FileType:
+		inodeReport.FileExtents = make([]InodeReportFileExtent, 0)
+	}
+
+	if nil == payload {
+		err = nil
+		return
+	}
+
+	numberOfItems, err = payload.Len()
+	if nil != err {
+		err = blunder.AddError(err, blunder.CorruptInodeError)
+		return
+	}
+
+	for itemIndex = 0; itemIndex < numberOfItems; itemIndex++ {
+		key, value, ok, err = payload.GetByIndex(itemIndex)
+		if nil != err {
+			err = blunder.AddError(err, blunder.CorruptInodeError)
+			return
+		}
+		if !ok {
+			err = fmt.Errorf("%s: payload of inode %d unexpectedly missing item %v of %v", utils.GetFnName(), inodeNumber, itemIndex, numberOfItems)
+			err = blunder.AddError(err, blunder.CorruptInodeError)
+			return
+		}
+
+		if DirType == inodeReport.InodeType {
+			inodeReport.DirEntries = append(inodeReport.DirEntries, InodeReportDirEntry{
+				Basename:    key.(string),
+				InodeNumber: value.(InodeNumber),
+			})
+		} else {
+			fileExtent := value.(*fileExtentStruct)
+			inodeReport.FileExtents = append(inodeReport.FileExtents, InodeReportFileExtent{
+				FileOffset:       fileExtent.FileOffset,
+				Length:           fileExtent.Length,
+				LogSegmentNumber: fileExtent.LogSegmentNumber,
+				LogSegmentOffset: fileExtent.LogSegmentOffset,
+			})
+		}
+	}
+
+	err = nil
+	return
+}
+
+// ResolvePath walks path (relative to the root directory) one basename at a time... following no symlinks.
+func (inspectionVolume *inspectionVolumeStruct) ResolvePath(path string) (inodeNumber InodeNumber, err error) {
+	var (
+		basename    string
+		inodeReport *InodeReport
+		ok          bool
+		payload     sortedmap.BPlusTree
+		value       sortedmap.Value
+	)
+
+	inodeNumber = RootDirInodeNumber
+
+	for _, basename = range strings.Split(path, "/") {
+		if ("" == basename) || ("." == basename) {
+			continue
+		}
+
+		inodeReport, payload, err = inspectionVolume.decodeInodeRec(inodeNumber)
+		if nil != err {
+			return
+		}
+		if DirType != inodeReport.InodeType {
+			err = fmt.Errorf("%s: inode %d preceding \"%v\" in \"%v\" is not a directory", utils.GetFnName(), inodeNumber, basename, path)
+			err = blunder.AddError(err, blunder.NotDirError)
+			return
+		}
+
+		ok = false
+		if nil != payload {
+			value, ok, err = payload.GetByKey(basename)
+			if nil != err {
+				err = blunder.AddError(err, blunder.CorruptInodeError)
+				return
+			}
+		}
+		if !ok {
+			err = fmt.Errorf("%s: \"%v\" of \"%v\" not found in directory inode %d", utils.GetFnName(), basename, path, inodeNumber)
+			err = blunder.AddError(err, blunder.NotFoundError)
+			return
+		}
+
+		inodeNumber = value.(InodeNumber)
+	}
+
+	err = nil
+	return
+}
@@ -0,0 +1,145 @@
+package inode
+
+import (
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/headhunter"
+)
+
+func TestInspection(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	dirInodeNumber, err := testVolumeHandle.CreateDir(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateDir() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(RootDirInodeNumber, "InspectionDir", dirInodeNumber)
+	if nil != err {
+		t.Fatalf("Link() failed: %v", err)
+	}
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("inspect"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(dirInodeNumber, "InspectionFile", fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Link() failed: %v", err)
+	}
+
+	err = testVolumeHandle.(*volumeStruct).headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	// Decode the checkpoint just as pfsinspect would
+
+	confMap, err := conf.MakeConfMapFromStrings([]string{
+		"Volume:TestVolume.AccountName=AUTH_test",
+		"Volume:TestVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:TestVolume.MaxInodesPerMetadataNode=32",
+		"Volume:TestVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:TestVolume.MaxDirFileNodesPerMetadataNode=16",
+	})
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	headhunterVolumeHandle, checkpointReport, err := headhunter.OpenVolumeReadOnly(confMap, "TestVolume")
+	if nil != err {
+		t.Fatalf("headhunter.OpenVolumeReadOnly() failed: %v", err)
+	}
+	if (0 == checkpointReport.CheckpointObjectNumber) || (0 == checkpointReport.InodeRecBPlusTreeRoot.ObjectNumber) || (0 == len(checkpointReport.InodeRecBPlusTreeLayout)) {
+		t.Fatalf("headhunter.OpenVolumeReadOnly() returned unexpected checkpointReport: %+v", checkpointReport)
+	}
+
+	err = headhunterVolumeHandle.PutInodeRec(uint64(fileInodeNumber), []byte("nope"))
+	if blunder.IsNot(err, blunder.ReadOnlyError) {
+		t.Fatalf("PutInodeRec() via headhunter.OpenVolumeReadOnly() VolumeHandle should have failed with ReadOnlyError (got: %v)", err)
+	}
+
+	inspectionHandle, err := FetchInspectionHandle("TestVolume", headhunterVolumeHandle)
+	if nil != err {
+		t.Fatalf("FetchInspectionHandle() failed: %v", err)
+	}
+
+	inodeNumber, err := inspectionHandle.ResolvePath("/InspectionDir/./InspectionFile")
+	if nil != err {
+		t.Fatalf("ResolvePath() failed: %v", err)
+	}
+	if fileInodeNumber != inodeNumber {
+		t.Fatalf("ResolvePath() returned %v... expected %v", inodeNumber, fileInodeNumber)
+	}
+
+	_, err = inspectionHandle.ResolvePath("/InspectionDir/InspectionFile/NotThere")
+	if blunder.IsNot(err, blunder.NotDirError) {
+		t.Fatalf("ResolvePath() through a file should have failed with NotDirError (got: %v)", err)
+	}
+
+	_, err = inspectionHandle.ResolvePath("/InspectionDir/NotThere")
+	if blunder.IsNot(err, blunder.NotFoundError) {
+		t.Fatalf("ResolvePath() of a missing basename should have failed with NotFoundError (got: %v)", err)
+	}
+
+	dirInodeReport, err := inspectionHandle.InspectInode(dirInodeNumber)
+	if nil != err {
+		t.Fatalf("InspectInode(dirInodeNumber) failed: %v", err)
+	}
+	if (DirType != dirInodeReport.InodeType) || bool(dirInodeReport.CorruptionDetected) || (V1 != dirInodeReport.Version) {
+		t.Fatalf("InspectInode(dirInodeNumber) returned unexpected inodeReport: %+v", dirInodeReport)
+	}
+	if (3 != len(dirInodeReport.DirEntries)) ||
+		("." != dirInodeReport.DirEntries[0].Basename) || (dirInodeNumber != dirInodeReport.DirEntries[0].InodeNumber) ||
+		(".." != dirInodeReport.DirEntries[1].Basename) || (RootDirInodeNumber != dirInodeReport.DirEntries[1].InodeNumber) ||
+		("InspectionFile" != dirInodeReport.DirEntries[2].Basename) || (fileInodeNumber != dirInodeReport.DirEntries[2].InodeNumber) {
+		t.Fatalf("InspectInode(dirInodeNumber) returned unexpected DirEntries: %+v", dirInodeReport.DirEntries)
+	}
+
+	fileInodeReport, err := inspectionHandle.InspectInode(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("InspectInode(fileInodeNumber) failed: %v", err)
+	}
+	if (FileType != fileInodeReport.InodeType) || (7 != fileInodeReport.Size) || (1 != len(fileInodeReport.LogSegmentMap)) {
+		t.Fatalf("InspectInode(fileInodeNumber) returned unexpected inodeReport: %+v", fileInodeReport)
+	}
+	if (1 != len(fileInodeReport.FileExtents)) || (0 != fileInodeReport.FileExtents[0].FileOffset) || (7 != fileInodeReport.FileExtents[0].Length) {
+		t.Fatalf("InspectInode(fileInodeNumber) returned unexpected FileExtents: %+v", fileInodeReport.FileExtents)
+	}
+	if 7 != fileInodeReport.LogSegmentMap[fileInodeReport.FileExtents[0].LogSegmentNumber] {
+		t.Fatalf("InspectInode(fileInodeNumber) returned LogSegmentMap %v not matching FileExtents %+v", fileInodeReport.LogSegmentMap, fileInodeReport.FileExtents)
+	}
+
+	// Every inode (including the ones created above) should be enumerated
+
+	inodeNumber = 0
+	inodesFound := make(map[InodeNumber]bool)
+
+	for {
+		var ok bool
+
+		inodeNumber, ok, err = inspectionHandle.NextInodeNumber(inodeNumber)
+		if nil != err {
+			t.Fatalf("NextInodeNumber() failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		inodesFound[inodeNumber] = true
+	}
+	if !inodesFound[RootDirInodeNumber] || !inodesFound[dirInodeNumber] || !inodesFound[fileInodeNumber] {
+		t.Fatalf("NextInodeNumber() enumeration missed expected inodes: %v", inodesFound)
+	}
+}
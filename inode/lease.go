@@ -0,0 +1,487 @@
+package inode
+
+// Leases on log segments
+//
+// A read plan handed out to e.g. pfs_middleware names log segments that the caller will subsequently
+// GET directly from Swift. Absent some form of protection, a concurrent overwrite (or Destroy()) could
+// cause deleteLogSegmentAsync() to remove such a log segment before the caller has finished reading it.
+//
+// A lease pins a set of log segments until it is released or expires. While a log segment is pinned,
+// deleteLogSegmentAsync() still removes the LogSegmentRec but defers the Swift DELETE of the object
+// until the last lease pinning it has gone away. Leases that are neither renewed nor released before
+// their expirationTime are reaped by leaseReaperDaemon().
+//
+// Deferred deletions are tracked only in memory. A clean Down() (or a volume becoming inactive) drops
+// every lease, issuing them. Should a crash (or a failover) intervene, the objects are left behind with
+// no LogSegmentRec referencing them. Once a volume becomes active, leaseRecoveryDaemon() waits out
+// every lease that might have been handed out before (as well as the ScrubGracePeriod that must pass
+// before any log segment can be taken for an orphan) and then deletes such log segments.
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+const (
+	leaseDurationDefault = 30 * time.Second
+)
+
+type leaseStruct struct {
+	leaseID           string
+	volume            *volumeStruct
+	expirationTime    time.Time
+	logSegmentNumbers []uint64 // each log segment appears only once
+}
+
+type pinnedLogSegmentStruct struct {
+	leaseCount              uint64          // number of leases currently pinning this log segment
+	deletePending           bool            // set by deleteLogSegmentAsync() if called while pinned
+	containerName           string          // only valid if deletePending == true
+	checkpointDoneWaitGroup *sync.WaitGroup // only valid if deletePending == true
+}
+
+type pendingLogSegmentDeleteStruct struct {
+	volume                  *volumeStruct
+	logSegmentNumber        uint64
+	containerName           string
+	checkpointDoneWaitGroup *sync.WaitGroup
+}
+
+type leaseGlobalsStruct struct {
+	sync.Mutex                             // protects leaseMap as well as every volumeStruct.pinnedLogSegmentMap
+	leaseDuration  time.Duration           // [FSGlobals]LeaseDuration
+	leaseMap       map[string]*leaseStruct // key == leaseStruct.leaseID
+	reaperStopChan chan bool               // non-nil only while leaseReaperDaemon() is running
+	reaperDoneChan chan bool               // non-nil only while leaseReaperDaemon() is running
+}
+
+func leaseUp(confMap conf.ConfMap) (err error) {
+	globals.lease.leaseDuration, err = confMap.FetchOptionValueDuration("FSGlobals", "LeaseDuration")
+	if nil != err {
+		globals.lease.leaseDuration = leaseDurationDefault // TODO: eventually, just return
+	}
+	if 0 == globals.lease.leaseDuration {
+		err = fmt.Errorf("FSGlobals.LeaseDuration must be non-zero")
+		return
+	}
+
+	globals.lease.leaseMap = make(map[string]*leaseStruct)
+
+	globals.lease.reaperStopChan = make(chan bool, 1)
+	globals.lease.reaperDoneChan = make(chan bool, 1)
+
+	go leaseReaperDaemon(globals.lease.reaperStopChan, globals.lease.reaperDoneChan)
+
+	err = nil
+	return
+}
+
+func leaseDown() {
+	var (
+		lease          *leaseStruct
+		pendingDeletes []pendingLogSegmentDeleteStruct
+	)
+
+	if nil != globals.lease.reaperStopChan {
+		globals.lease.reaperStopChan <- true
+		_ = <-globals.lease.reaperDoneChan
+
+		globals.lease.reaperStopChan = nil
+		globals.lease.reaperDoneChan = nil
+	}
+
+	// Dropping every lease ensures no deferred log segment deletion is forgotten
+
+	globals.lease.Lock()
+	for _, lease = range globals.lease.leaseMap {
+		pendingDeletes = append(pendingDeletes, dropLeaseWhileLocked(lease)...)
+	}
+	globals.lease.Unlock()
+
+	issuePendingLogSegmentDeletes(pendingDeletes)
+}
+
+// dropVolumeLeases is called when a volume is removed or becomes inactive during PauseAndContract().
+func (vS *volumeStruct) dropVolumeLeases() {
+	var (
+		lease          *leaseStruct
+		pendingDeletes []pendingLogSegmentDeleteStruct
+	)
+
+	globals.lease.Lock()
+	for _, lease = range globals.lease.leaseMap {
+		if vS == lease.volume {
+			pendingDeletes = append(pendingDeletes, dropLeaseWhileLocked(lease)...)
+		}
+	}
+	globals.lease.Unlock()
+
+	issuePendingLogSegmentDeletes(pendingDeletes)
+}
+
+func (vS *volumeStruct) CreateLease(logSegmentNumbers []uint64) (leaseID string, err error) {
+	var (
+		alreadyPresent    bool
+		dedupedSegmentSet map[uint64]bool
+		lease             *leaseStruct
+		logSegmentNumber  uint64
+		nonce             uint64
+		pinnedLogSegment  *pinnedLogSegmentStruct
+	)
+
+	if nil == vS.snapshotOf {
+		nonce, err = vS.headhunterVolumeHandle.FetchNonce()
+	} else {
+		// A snapshot's headhunter.VolumeHandle cannot provide nonces
+		nonce, err = vS.snapshotOf.headhunterVolumeHandle.FetchNonce()
+	}
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	lease = &leaseStruct{
+		leaseID:           fmt.Sprintf("%016X%016X", vS.fsid, nonce),
+		volume:            vS,
+		logSegmentNumbers: make([]uint64, 0, len(logSegmentNumbers)),
+	}
+
+	dedupedSegmentSet = make(map[uint64]bool)
+
+	for _, logSegmentNumber = range logSegmentNumbers {
+		if 0 == logSegmentNumber {
+			// Zero-fill ReadPlanStep... nothing to pin
+			continue
+		}
+		_, alreadyPresent = dedupedSegmentSet[logSegmentNumber]
+		if !alreadyPresent {
+			dedupedSegmentSet[logSegmentNumber] = true
+			lease.logSegmentNumbers = append(lease.logSegmentNumbers, logSegmentNumber)
+		}
+	}
+
+	globals.lease.Lock()
+
+	lease.expirationTime = time.Now().Add(globals.lease.leaseDuration)
+
+	for _, logSegmentNumber = range lease.logSegmentNumbers {
+		pinnedLogSegment, alreadyPresent = vS.pinnedLogSegmentMap[logSegmentNumber]
+		if !alreadyPresent {
+			pinnedLogSegment = &pinnedLogSegmentStruct{}
+			vS.pinnedLogSegmentMap[logSegmentNumber] = pinnedLogSegment
+		}
+		pinnedLogSegment.leaseCount++
+	}
+
+	globals.lease.leaseMap[lease.leaseID] = lease
+
+	globals.lease.Unlock()
+
+	stats.IncrementOperations(&stats.LeaseCreateOps)
+
+	leaseID = lease.leaseID
+
+	err = nil
+	return
+}
+
+func renewLease(leaseID string) (err error) {
+	var (
+		lease *leaseStruct
+		ok    bool
+	)
+
+	globals.lease.Lock()
+
+	lease, ok = globals.lease.leaseMap[leaseID]
+	if !ok {
+		globals.lease.Unlock()
+		err = fmt.Errorf("%s: leaseID \"%v\" not found (perhaps it expired)", utils.GetFnName(), leaseID)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	lease.expirationTime = time.Now().Add(globals.lease.leaseDuration)
+
+	globals.lease.Unlock()
+
+	stats.IncrementOperations(&stats.LeaseRenewOps)
+
+	err = nil
+	return
+}
+
+func releaseLease(leaseID string) (err error) {
+	var (
+		lease          *leaseStruct
+		ok             bool
+		pendingDeletes []pendingLogSegmentDeleteStruct
+	)
+
+	globals.lease.Lock()
+
+	lease, ok = globals.lease.leaseMap[leaseID]
+	if !ok {
+		globals.lease.Unlock()
+		err = fmt.Errorf("%s: leaseID \"%v\" not found (perhaps it expired)", utils.GetFnName(), leaseID)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	pendingDeletes = dropLeaseWhileLocked(lease)
+
+	globals.lease.Unlock()
+
+	issuePendingLogSegmentDeletes(pendingDeletes)
+
+	stats.IncrementOperations(&stats.LeaseReleaseOps)
+
+	err = nil
+	return
+}
+
+// deferLogSegmentDeleteIfPinned reports whether or not any lease currently pins logSegmentNumber. If so,
+// the log segment is marked such that its Swift object will be deleted (following checkpointDoneWaitGroup)
+// once the last such lease is released or expires.
+func (vS *volumeStruct) deferLogSegmentDeleteIfPinned(logSegmentNumber uint64, containerName string, checkpointDoneWaitGroup *sync.WaitGroup) (deferred bool) {
+	var (
+		pinnedLogSegment *pinnedLogSegmentStruct
+	)
+
+	globals.lease.Lock()
+
+	pinnedLogSegment, deferred = vS.pinnedLogSegmentMap[logSegmentNumber]
+	if deferred {
+		pinnedLogSegment.deletePending = true
+		pinnedLogSegment.containerName = containerName
+		pinnedLogSegment.checkpointDoneWaitGroup = checkpointDoneWaitGroup
+	}
+
+	globals.lease.Unlock()
+
+	return
+}
+
+// dropLeaseWhileLocked removes lease and unpins its log segments. Any log segment deletions
+// deferred on account of this lease are returned so that they may be issued after the lock
+// is released.
+func dropLeaseWhileLocked(lease *leaseStruct) (pendingDeletes []pendingLogSegmentDeleteStruct) {
+	var (
+		logSegmentNumber uint64
+		ok               bool
+		pinnedLogSegment *pinnedLogSegmentStruct
+	)
+
+	pendingDeletes = make([]pendingLogSegmentDeleteStruct, 0)
+
+	for _, logSegmentNumber = range lease.logSegmentNumbers {
+		pinnedLogSegment, ok = lease.volume.pinnedLogSegmentMap[logSegmentNumber]
+		if !ok {
+			logger.Errorf("Lease \"%v\" pinned logSegmentNumber 0x%016X not found in volume \"%v\" pinnedLogSegmentMap", lease.leaseID, logSegmentNumber, lease.volume.volumeName)
+			continue
+		}
+
+		pinnedLogSegment.leaseCount--
+
+		if 0 == pinnedLogSegment.leaseCount {
+			delete(lease.volume.pinnedLogSegmentMap, logSegmentNumber)
+
+			if pinnedLogSegment.deletePending {
+				pendingDeletes = append(pendingDeletes, pendingLogSegmentDeleteStruct{
+					volume:                  lease.volume,
+					logSegmentNumber:        logSegmentNumber,
+					containerName:           pinnedLogSegment.containerName,
+					checkpointDoneWaitGroup: pinnedLogSegment.checkpointDoneWaitGroup,
+				})
+			}
+		}
+	}
+
+	delete(globals.lease.leaseMap, lease.leaseID)
+
+	return
+}
+
+func issuePendingLogSegmentDeletes(pendingDeletes []pendingLogSegmentDeleteStruct) {
+	var (
+		pendingDelete pendingLogSegmentDeleteStruct
+	)
+
+	for _, pendingDelete = range pendingDeletes {
+		pendingDelete.volume.objectDeleteAfterCheckpoint(pendingDelete.containerName, fmt.Sprintf("%016X", pendingDelete.logSegmentNumber), pendingDelete.checkpointDoneWaitGroup)
+		stats.IncrementOperations(&stats.LeaseDeferredLogSegmentDeleteOps)
+	}
+}
+
+func leaseReaperDaemon(stopChan chan bool, doneChan chan bool) {
+	var (
+		lease          *leaseStruct
+		now            time.Time
+		pendingDeletes []pendingLogSegmentDeleteStruct
+		reapedLeases   uint64
+	)
+
+	for {
+		select {
+		case _ = <-stopChan:
+			doneChan <- true
+			return
+		case <-time.After(globals.lease.leaseDuration):
+			// Time to look for expired leases
+		}
+
+		now = time.Now()
+		pendingDeletes = make([]pendingLogSegmentDeleteStruct, 0)
+		reapedLeases = 0
+
+		globals.lease.Lock()
+		for _, lease = range globals.lease.leaseMap {
+			if now.After(lease.expirationTime) {
+				pendingDeletes = append(pendingDeletes, dropLeaseWhileLocked(lease)...)
+				reapedLeases++
+				stats.IncrementOperations(&stats.LeaseExpiredOps)
+			}
+		}
+		globals.lease.Unlock()
+
+		issuePendingLogSegmentDeletes(pendingDeletes)
+
+		if 0 < reapedLeases {
+			logger.Infof("Reaped %v expired lease(s)", reapedLeases)
+		}
+	}
+}
+
+// startLeaseRecoveryIfNeeded is called during Up() and ExpandAndResume() once each volume is configured.
+func (vS *volumeStruct) startLeaseRecoveryIfNeeded() {
+	vS.Lock()
+	defer vS.Unlock()
+
+	if !vS.active || vS.readOnly || vS.leaseRecoveryComplete || (nil != vS.leaseRecoveryStopChan) {
+		return
+	}
+
+	vS.leaseRecoveryStopChan = make(chan bool, 1)
+	vS.leaseRecoveryDoneChan = make(chan bool, 1)
+
+	go vS.leaseRecoveryDaemon(vS.leaseRecoveryStopChan, vS.leaseRecoveryDoneChan)
+}
+
+// stopLeaseRecovery is called during PauseAndContract() and Down()... a sweep not yet complete will be
+// restarted by the next startLeaseRecoveryIfNeeded().
+func (vS *volumeStruct) stopLeaseRecovery() {
+	var (
+		doneChan chan bool
+		stopChan chan bool
+	)
+
+	vS.Lock()
+	stopChan = vS.leaseRecoveryStopChan
+	doneChan = vS.leaseRecoveryDoneChan
+	vS.leaseRecoveryStopChan = nil
+	vS.leaseRecoveryDoneChan = nil
+	vS.Unlock()
+
+	if nil == stopChan {
+		return
+	}
+
+	// Closing (rather than sending on) stopChan is noticed by each phase of the sweep
+
+	close(stopChan)
+	_ = <-doneChan
+}
+
+func (vS *volumeStruct) leaseRecoveryDaemon(stopChan chan bool, doneChan chan bool) {
+	var (
+		complete   bool
+		delay      time.Duration
+		scrubDelay time.Duration
+	)
+
+	delay = globals.lease.leaseDuration
+
+	vS.Lock()
+	scrubDelay = vS.scrubGracePeriod - time.Since(vS.provisionedObjectTrackingStart)
+	vS.Unlock()
+
+	if scrubDelay > delay {
+		delay = scrubDelay
+	}
+
+	select {
+	case _ = <-stopChan:
+		doneChan <- true
+		return
+	case <-time.After(delay):
+		// Every lease handed out before the volume became active has now expired
+	}
+
+	complete = vS.recoverLeaseDeferredDeletes(stopChan)
+
+	vS.Lock()
+	vS.leaseRecoveryComplete = complete
+	vS.Unlock()
+
+	doneChan <- true
+}
+
+// recoverLeaseDeferredDeletes deletes the log segments no longer referenced by any LogSegmentRec. It
+// returns true if the sweep ran to completion.
+func (vS *volumeStruct) recoverLeaseDeferredDeletes(stopChan chan bool) (complete bool) {
+	var (
+		checkpointContainerName string
+		deleteReport            *ScrubReport
+		dryRunReport            *ScrubReport
+		err                     error
+		logSegmentReport        *ScrubReport
+		orphan                  ScrubOrphan
+	)
+
+	dryRunReport, err = vS.FindOrphanedObjects(stopChan)
+	if nil != err {
+		logger.WarnfWithError(err, "Lease recovery of volume \"%v\" unable to find orphaned log segments", vS.volumeName)
+		complete = false
+		return
+	}
+
+	checkpointContainerName = vS.headhunterVolumeHandle.FetchCheckpointContainerName()
+
+	logSegmentReport = &ScrubReport{Nonce: dryRunReport.Nonce, Orphans: make([]ScrubOrphan, 0)}
+
+	for _, orphan = range dryRunReport.Orphans {
+		if checkpointContainerName != orphan.ContainerName {
+			logSegmentReport.Orphans = append(logSegmentReport.Orphans, orphan)
+			logSegmentReport.OrphanedBytes += orphan.ObjectLength
+		}
+	}
+
+	if 0 < len(logSegmentReport.Orphans) {
+		deleteReport, err = vS.DeleteOrphanedObjects(logSegmentReport, stopChan)
+		if nil != err {
+			logger.WarnfWithError(err, "Lease recovery of volume \"%v\" unable to delete orphaned log segments", vS.volumeName)
+			complete = false
+			return
+		}
+
+		stats.IncrementOperations(&stats.LeaseRecoveryOps)
+
+		logger.Infof("Lease recovery of volume \"%v\" deleted %v log segment(s)", vS.volumeName, len(deleteReport.Orphans))
+	}
+
+	select {
+	case _ = <-stopChan:
+		complete = false
+	default:
+		complete = true
+	}
+
+	return
+}
@@ -0,0 +1,281 @@
+package inode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+func fetchPinnedLogSegment(vS *volumeStruct, logSegmentNumber uint64) (pinnedLogSegment pinnedLogSegmentStruct, pinned bool) {
+	var (
+		pinnedLogSegmentPtr *pinnedLogSegmentStruct
+	)
+
+	globals.lease.Lock()
+	pinnedLogSegmentPtr, pinned = vS.pinnedLogSegmentMap[logSegmentNumber]
+	if pinned {
+		pinnedLogSegment = *pinnedLogSegmentPtr
+	}
+	globals.lease.Unlock()
+
+	return
+}
+
+func waitForLogSegmentObjectDeletion(accountName string, containerName string, objectName string) (deleted bool) {
+	for i := 0; i < 100; i++ {
+		_, err := swiftclient.ObjectContentLength(accountName, containerName, objectName)
+		if nil != err {
+			deleted = true
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	deleted = false
+	return
+}
+
+func TestLeases(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("leased"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	offset := uint64(0)
+	length := uint64(6)
+
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &offset, &length)
+	if nil != err {
+		t.Fatalf("GetReadPlan() failed: %v", err)
+	}
+	if (1 != len(readPlan)) || (0 == readPlan[0].LogSegmentNumber) {
+		t.Fatalf("GetReadPlan() returned unexpected readPlan: %v", readPlan)
+	}
+
+	logSegmentNumber := readPlan[0].LogSegmentNumber
+	objectName := fmt.Sprintf("%016X", logSegmentNumber)
+
+	containerName, err := vS.getLogSegmentContainer(logSegmentNumber)
+	if nil != err {
+		t.Fatalf("getLogSegmentContainer() failed: %v", err)
+	}
+
+	// Duplicate and zero-fill log segment numbers should be tolerated
+
+	leaseID1, err := testVolumeHandle.CreateLease([]uint64{logSegmentNumber, 0, logSegmentNumber})
+	if nil != err {
+		t.Fatalf("CreateLease() #1 failed: %v", err)
+	}
+	leaseID2, err := testVolumeHandle.CreateLease([]uint64{logSegmentNumber})
+	if nil != err {
+		t.Fatalf("CreateLease() #2 failed: %v", err)
+	}
+	if leaseID1 == leaseID2 {
+		t.Fatalf("CreateLease() returned the same leaseID twice")
+	}
+
+	pinnedLogSegment, pinned := fetchPinnedLogSegment(vS, logSegmentNumber)
+	if !pinned || (2 != pinnedLogSegment.leaseCount) || pinnedLogSegment.deletePending {
+		t.Fatalf("CreateLease() left pinnedLogSegment == %+v (pinned == %v)", pinnedLogSegment, pinned)
+	}
+
+	// Destroying the file should defer deletion of its (pinned) log segment
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	pinnedLogSegment, pinned = fetchPinnedLogSegment(vS, logSegmentNumber)
+	if !pinned || !pinnedLogSegment.deletePending {
+		t.Fatalf("Destroy() left pinnedLogSegment == %+v (pinned == %v)", pinnedLogSegment, pinned)
+	}
+
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, containerName, objectName)
+	if nil != err {
+		t.Fatalf("Pinned log segment object should not have been deleted: %v", err)
+	}
+
+	err = RenewLease(leaseID1)
+	if nil != err {
+		t.Fatalf("RenewLease() failed: %v", err)
+	}
+
+	err = ReleaseLease(leaseID1)
+	if nil != err {
+		t.Fatalf("ReleaseLease() #1 failed: %v", err)
+	}
+
+	pinnedLogSegment, pinned = fetchPinnedLogSegment(vS, logSegmentNumber)
+	if !pinned || (1 != pinnedLogSegment.leaseCount) {
+		t.Fatalf("ReleaseLease() #1 left pinnedLogSegment == %+v (pinned == %v)", pinnedLogSegment, pinned)
+	}
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, containerName, objectName)
+	if nil != err {
+		t.Fatalf("Still pinned log segment object should not have been deleted: %v", err)
+	}
+
+	err = ReleaseLease(leaseID1)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("ReleaseLease() of already released lease should have failed with NotFoundError: %v", err)
+	}
+	err = RenewLease(leaseID1)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("RenewLease() of already released lease should have failed with NotFoundError: %v", err)
+	}
+
+	// Letting the last lease expire should trigger the deferred deletion
+
+	timeout := time.Now().Add(10 * globals.lease.leaseDuration)
+
+	for {
+		_, pinned = fetchPinnedLogSegment(vS, logSegmentNumber)
+		if !pinned {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatalf("Lease on log segment never expired")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	err = RenewLease(leaseID2)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("RenewLease() of expired lease should have failed with NotFoundError: %v", err)
+	}
+
+	if !waitForLogSegmentObjectDeletion(vS.accountName, containerName, objectName) {
+		t.Fatalf("Log segment object should have been deleted once its last lease expired")
+	}
+}
+
+func TestLeaseRecovery(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("recovered"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	offset := uint64(0)
+	length := uint64(9)
+
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &offset, &length)
+	if nil != err {
+		t.Fatalf("GetReadPlan() failed: %v", err)
+	}
+	if (1 != len(readPlan)) || (0 == readPlan[0].LogSegmentNumber) {
+		t.Fatalf("GetReadPlan() returned unexpected readPlan: %v", readPlan)
+	}
+
+	logSegmentNumber := readPlan[0].LogSegmentNumber
+	objectName := fmt.Sprintf("%016X", logSegmentNumber)
+
+	containerName, err := vS.getLogSegmentContainer(logSegmentNumber)
+	if nil != err {
+		t.Fatalf("getLogSegmentContainer() failed: %v", err)
+	}
+
+	leaseID, err := testVolumeHandle.CreateLease([]uint64{logSegmentNumber})
+	if nil != err {
+		t.Fatalf("CreateLease() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	// Simulate a crash forgetting the lease (and the deletion it deferred)
+
+	globals.lease.Lock()
+	delete(globals.lease.leaseMap, leaseID)
+	delete(vS.pinnedLogSegmentMap, logSegmentNumber)
+	globals.lease.Unlock()
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, containerName, objectName)
+	if nil != err {
+		t.Fatalf("Log segment object should not yet have been deleted: %v", err)
+	}
+
+	// Restart the sweep as if the volume had been up for ScrubGracePeriod
+
+	vS.stopLeaseRecovery()
+
+	vS.Lock()
+	savedProvisionedObjectTrackingStart := vS.provisionedObjectTrackingStart
+	vS.provisionedObjectTrackingStart = time.Now().Add(-vS.scrubGracePeriod)
+	vS.leaseRecoveryComplete = false
+	vS.Unlock()
+
+	defer func() {
+		vS.Lock()
+		vS.provisionedObjectTrackingStart = savedProvisionedObjectTrackingStart
+		vS.Unlock()
+	}()
+
+	vS.startLeaseRecoveryIfNeeded()
+
+	if !waitForLogSegmentObjectDeletion(vS.accountName, containerName, objectName) {
+		t.Fatalf("Log segment object should have been deleted by leaseRecoveryDaemon()")
+	}
+
+	timeout := time.Now().Add(10 * globals.lease.leaseDuration)
+
+	for {
+		vS.Lock()
+		leaseRecoveryComplete := vS.leaseRecoveryComplete
+		vS.Unlock()
+		if leaseRecoveryComplete {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatalf("leaseRecoveryDaemon() never completed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,149 @@
+package inode
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFragmentationReportAndOptimize(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	// Lay down 64 bytes of 'A' then punch 'B's into every other 8 byte span... flushing between
+	// each Write() so that every overwrite lands in its own log segment
+
+	expectedBuf := bytes.Repeat([]byte{'A'}, 64)
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, expectedBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() of initial content failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() of initial content failed: %v", err)
+	}
+
+	for offset := uint64(0); offset < 64; offset += 16 {
+		overwriteBuf := bytes.Repeat([]byte{'B'}, 8)
+		err = testVolumeHandle.Write(fileInodeNumber, offset, overwriteBuf, nil)
+		if nil != err {
+			t.Fatalf("Write() of overwrite at offset %v failed: %v", offset, err)
+		}
+		err = testVolumeHandle.Flush(fileInodeNumber, false)
+		if nil != err {
+			t.Fatalf("Flush() of overwrite at offset %v failed: %v", offset, err)
+		}
+		copy(expectedBuf[offset:], overwriteBuf)
+	}
+
+	fragmentationReport, err := testVolumeHandle.GetFragmentationReport(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("GetFragmentationReport() [before] failed: %v", err)
+	}
+
+	if 8 != fragmentationReport.NumberOfFragments {
+		t.Fatalf("GetFragmentationReport() [before] returned NumberOfFragments == %v (expected 8)", fragmentationReport.NumberOfFragments)
+	}
+	if 64 != fragmentationReport.BytesInFragments {
+		t.Fatalf("GetFragmentationReport() [before] returned BytesInFragments == %v (expected 64)", fragmentationReport.BytesInFragments)
+	}
+	if 5 != fragmentationReport.NumberOfLogSegments {
+		t.Fatalf("GetFragmentationReport() [before] returned NumberOfLogSegments == %v (expected 5)", fragmentationReport.NumberOfLogSegments)
+	}
+	if 32 != fragmentationReport.BytesTrapped {
+		t.Fatalf("GetFragmentationReport() [before] returned BytesTrapped == %v (expected 32)", fragmentationReport.BytesTrapped)
+	}
+	if 5 != len(fragmentationReport.LogSegmentReports) {
+		t.Fatalf("GetFragmentationReport() [before] returned %v LogSegmentReports (expected 5)", len(fragmentationReport.LogSegmentReports))
+	}
+	for i, logSegmentReport := range fragmentationReport.LogSegmentReports {
+		if (0 < i) && (logSegmentReport.LogSegmentNumber <= fragmentationReport.LogSegmentReports[i-1].LogSegmentNumber) {
+			t.Fatalf("GetFragmentationReport() [before] returned LogSegmentReports out of order")
+		}
+		if logSegmentReport.BytesReferenced > logSegmentReport.BytesHeld {
+			t.Fatalf("GetFragmentationReport() [before] returned LogSegmentReport with BytesReferenced > BytesHeld")
+		}
+	}
+
+	// A zero maxDuration should leave things untouched
+
+	err = testVolumeHandle.Optimize(fileInodeNumber, time.Duration(0))
+	if nil != err {
+		t.Fatalf("Optimize(,0) failed: %v", err)
+	}
+
+	fragmentationReport, err = testVolumeHandle.GetFragmentationReport(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("GetFragmentationReport() [after Optimize(,0)] failed: %v", err)
+	}
+	if 8 != fragmentationReport.NumberOfFragments {
+		t.Fatalf("GetFragmentationReport() [after Optimize(,0)] returned NumberOfFragments == %v (expected 8)", fragmentationReport.NumberOfFragments)
+	}
+
+	err = testVolumeHandle.Optimize(fileInodeNumber, time.Minute)
+	if nil != err {
+		t.Fatalf("Optimize() failed: %v", err)
+	}
+
+	fragmentationReport, err = testVolumeHandle.GetFragmentationReport(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("GetFragmentationReport() [after] failed: %v", err)
+	}
+
+	if 1 != fragmentationReport.NumberOfFragments {
+		t.Fatalf("GetFragmentationReport() [after] returned NumberOfFragments == %v (expected 1)", fragmentationReport.NumberOfFragments)
+	}
+	if 64 != fragmentationReport.BytesInFragments {
+		t.Fatalf("GetFragmentationReport() [after] returned BytesInFragments == %v (expected 64)", fragmentationReport.BytesInFragments)
+	}
+	if 1 != fragmentationReport.NumberOfLogSegments {
+		t.Fatalf("GetFragmentationReport() [after] returned NumberOfLogSegments == %v (expected 1)", fragmentationReport.NumberOfLogSegments)
+	}
+	if 0 != fragmentationReport.BytesTrapped {
+		t.Fatalf("GetFragmentationReport() [after] returned BytesTrapped == %v (expected 0)", fragmentationReport.BytesTrapped)
+	}
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, 64, nil)
+	if nil != err {
+		t.Fatalf("Read() [after] failed: %v", err)
+	}
+	if 0 != bytes.Compare(expectedBuf, readBuf) {
+		t.Fatalf("Read() [after] returned %v (expected %v)", string(readBuf), string(expectedBuf))
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Validate() [after] failed: %v", err)
+	}
+
+	// Optimizing an already optimal FileInode should be a no-op
+
+	err = testVolumeHandle.Optimize(fileInodeNumber, time.Minute)
+	if nil != err {
+		t.Fatalf("Optimize() [again] failed: %v", err)
+	}
+
+	// Neither operation applies to a DirInode
+
+	_, err = testVolumeHandle.GetFragmentationReport(RootDirInodeNumber)
+	if nil == err {
+		t.Fatalf("GetFragmentationReport(RootDirInodeNumber) should have failed")
+	}
+	err = testVolumeHandle.Optimize(RootDirInodeNumber, time.Minute)
+	if nil == err {
+		t.Fatalf("Optimize(RootDirInodeNumber) should have failed")
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
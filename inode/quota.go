@@ -0,0 +1,540 @@
+package inode
+
+// Per-volume quotas
+//
+// A volume may limit both the bytes referenced by its FileInodes (VolumeStats.ReferencedBytes) and
+// the number of its inodes (VolumeStats.InodeCount). Each may have a hard limit, which is never allowed
+// to be exceeded, and a soft limit, which may be exceeded for up to QuotaGracePeriod after which it is
+// enforced just like a hard limit. Package inode does not enforce quotas itself... rather, package fs
+// calls CheckQuota() prior to each operation that would consume bytes or inodes.
+//
+// Individual users and groups may also be given hard limits on the bytes referenced by, and the number
+// of, the inodes they own. Usage for each owner is tracked by headhunter (see headhunter.OwnerStats) as
+// inodes are flushed. Such limits are optional... absent any, no per-owner enforcement takes place.
+// As a change of ownership moves an inode's usage to its new owner, package fs calls
+// CheckOwnerChangeQuota() prior to any such change.
+//
+// Note that ReferencedBytes (like InodeCount and the per-owner stats) is only updated as inodes are
+// flushed. So that concurrent operations cannot each pass CheckQuota() and together exceed a hard limit,
+// every charge CheckQuota() admits is reserved in a per-volume (and per-owner) pending count that later
+// checks include. The caller hands the resulting QuotaReservation back via SettleQuotaReservation() once
+// the consuming inode has been modified (the reservation is then released as that inode is flushed) or
+// via ReleaseQuotaReservation() should the operation fail.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+const (
+	quotaGracePeriodDefault = 7 * 24 * time.Hour
+)
+
+type quotaStruct struct {
+	limits                  VolumeQuota
+	softBytesExceededSince  time.Time // zero if SoftBytes not (known to be) exceeded
+	softInodesExceededSince time.Time // zero if SoftInodes not (known to be) exceeded
+	userLimits              map[InodeUserID]OwnerQuota
+	groupLimits             map[InodeGroupID]OwnerQuota
+	pendingBytes            uint64                                 // sum of outstanding QuotaReservation.bytes
+	pendingInodes           uint64                                 // sum of outstanding QuotaReservation.inodes
+	userPending             map[InodeUserID]headhunter.OwnerStats  // outstanding reservations by userID
+	groupPending            map[InodeGroupID]headhunter.OwnerStats // outstanding reservations by groupID
+}
+
+func (vS *volumeStruct) adoptQuotaParameters(confMap conf.ConfMap) (err error) {
+	var (
+		groupHardBytes    map[uint32]uint64
+		groupHardInodes   map[uint32]uint64
+		groupLimits       map[InodeGroupID]OwnerQuota
+		limits            VolumeQuota
+		ownerID           uint32
+		ownerLimit        uint64
+		ownerQuota        OwnerQuota
+		userHardBytes     map[uint32]uint64
+		userHardInodes    map[uint32]uint64
+		userLimits        map[InodeUserID]OwnerQuota
+		volumeSectionName string
+	)
+
+	volumeSectionName = utils.VolumeNameConfSection(vS.volumeName)
+
+	limits.HardBytes, err = confMap.FetchOptionValueUint64(volumeSectionName, "QuotaHardBytes")
+	if nil != err {
+		limits.HardBytes = 0 // TODO: eventually, just return
+	}
+
+	limits.SoftBytes, err = confMap.FetchOptionValueUint64(volumeSectionName, "QuotaSoftBytes")
+	if nil != err {
+		limits.SoftBytes = 0 // TODO: eventually, just return
+	}
+
+	limits.HardInodes, err = confMap.FetchOptionValueUint64(volumeSectionName, "QuotaHardInodes")
+	if nil != err {
+		limits.HardInodes = 0 // TODO: eventually, just return
+	}
+
+	limits.SoftInodes, err = confMap.FetchOptionValueUint64(volumeSectionName, "QuotaSoftInodes")
+	if nil != err {
+		limits.SoftInodes = 0 // TODO: eventually, just return
+	}
+
+	limits.GracePeriod, err = confMap.FetchOptionValueDuration(volumeSectionName, "QuotaGracePeriod")
+	if nil != err {
+		limits.GracePeriod = quotaGracePeriodDefault // TODO: eventually, just return
+	}
+
+	if (0 != limits.HardBytes) && (limits.SoftBytes > limits.HardBytes) {
+		err = fmt.Errorf("%s.QuotaSoftBytes (%v) must not exceed %s.QuotaHardBytes (%v)", volumeSectionName, limits.SoftBytes, volumeSectionName, limits.HardBytes)
+		return
+	}
+	if (0 != limits.HardInodes) && (limits.SoftInodes > limits.HardInodes) {
+		err = fmt.Errorf("%s.QuotaSoftInodes (%v) must not exceed %s.QuotaHardInodes (%v)", volumeSectionName, limits.SoftInodes, volumeSectionName, limits.HardInodes)
+		return
+	}
+
+	userHardBytes, err = fetchOwnerLimits(confMap, volumeSectionName, "UserQuotaHardBytes")
+	if nil != err {
+		return
+	}
+	userHardInodes, err = fetchOwnerLimits(confMap, volumeSectionName, "UserQuotaHardInodes")
+	if nil != err {
+		return
+	}
+	groupHardBytes, err = fetchOwnerLimits(confMap, volumeSectionName, "GroupQuotaHardBytes")
+	if nil != err {
+		return
+	}
+	groupHardInodes, err = fetchOwnerLimits(confMap, volumeSectionName, "GroupQuotaHardInodes")
+	if nil != err {
+		return
+	}
+
+	userLimits = make(map[InodeUserID]OwnerQuota)
+	for ownerID, ownerLimit = range userHardBytes {
+		ownerQuota = userLimits[InodeUserID(ownerID)]
+		ownerQuota.HardBytes = ownerLimit
+		userLimits[InodeUserID(ownerID)] = ownerQuota
+	}
+	for ownerID, ownerLimit = range userHardInodes {
+		ownerQuota = userLimits[InodeUserID(ownerID)]
+		ownerQuota.HardInodes = ownerLimit
+		userLimits[InodeUserID(ownerID)] = ownerQuota
+	}
+
+	groupLimits = make(map[InodeGroupID]OwnerQuota)
+	for ownerID, ownerLimit = range groupHardBytes {
+		ownerQuota = groupLimits[InodeGroupID(ownerID)]
+		ownerQuota.HardBytes = ownerLimit
+		groupLimits[InodeGroupID(ownerID)] = ownerQuota
+	}
+	for ownerID, ownerLimit = range groupHardInodes {
+		ownerQuota = groupLimits[InodeGroupID(ownerID)]
+		ownerQuota.HardInodes = ownerLimit
+		groupLimits[InodeGroupID(ownerID)] = ownerQuota
+	}
+
+	vS.Lock()
+	if nil == vS.quota {
+		vS.quota = &quotaStruct{
+			userPending:  make(map[InodeUserID]headhunter.OwnerStats),
+			groupPending: make(map[InodeGroupID]headhunter.OwnerStats),
+		}
+	}
+	vS.quota.limits = limits
+	vS.quota.userLimits = userLimits
+	vS.quota.groupLimits = groupLimits
+	vS.Unlock()
+
+	err = nil
+	return
+}
+
+// fetchOwnerLimits parses an optional list of ownerID:limit pairs (e.g. "UserQuotaHardBytes: 1000:1073741824 1001:2147483648").
+func fetchOwnerLimits(confMap conf.ConfMap, volumeSectionName string, optionName string) (ownerLimits map[uint32]uint64, err error) {
+	var (
+		ownerID         uint64
+		ownerLimit      uint64
+		ownerLimitSplit []string
+		ownerLimitSlice []string
+		ownerLimitValue string
+	)
+
+	ownerLimits = make(map[uint32]uint64)
+
+	ownerLimitSlice, err = confMap.FetchOptionValueStringSlice(volumeSectionName, optionName)
+	if nil != err {
+		ownerLimitSlice = make([]string, 0) // TODO: eventually, just return
+	}
+
+	for _, ownerLimitValue = range ownerLimitSlice {
+		ownerLimitSplit = strings.Split(ownerLimitValue, ":")
+		if 2 != len(ownerLimitSplit) {
+			err = fmt.Errorf("%s.%s value \"%s\" must be of the form <ID>:<limit>", volumeSectionName, optionName, ownerLimitValue)
+			return
+		}
+		ownerID, err = strconv.ParseUint(ownerLimitSplit[0], 10, 32)
+		if nil != err {
+			err = fmt.Errorf("%s.%s value \"%s\" has bad ID: %v", volumeSectionName, optionName, ownerLimitValue, err)
+			return
+		}
+		ownerLimit, err = strconv.ParseUint(ownerLimitSplit[1], 10, 64)
+		if nil != err {
+			err = fmt.Errorf("%s.%s value \"%s\" has bad limit: %v", volumeSectionName, optionName, ownerLimitValue, err)
+			return
+		}
+		ownerLimits[uint32(ownerID)] = ownerLimit
+	}
+
+	err = nil
+	return
+}
+
+// CheckQuota returns a QuotaExceededError if consuming an additional bytesDelta bytes and inodesDelta
+// inodes would exceed (or remain over) any of the volume's hard limits or any soft limit beyond its
+// grace period. Any hard limits configured for userID or groupID are similarly enforced. Charges
+// admitted by prior calls but not yet flushed are included. On success, the charge is itself reserved
+// until the returned reservation is settled (and the consuming inode flushed) or released.
+func (vS *volumeStruct) CheckQuota(userID InodeUserID, groupID InodeGroupID, bytesDelta uint64, inodesDelta uint64) (reservation QuotaReservation, err error) {
+	reservation, err = vS.reserveQuota(QuotaReservation{
+		userID:        userID,
+		groupID:       groupID,
+		bytes:         bytesDelta,
+		inodes:        inodesDelta,
+		volumeCharged: true,
+		userCharged:   true,
+		groupCharged:  true,
+	})
+	return
+}
+
+// CheckOwnerChangeQuota is the equivalent of CheckQuota for an inode (referencing bytesDelta bytes)
+// about to change ownership from formerUserID/formerGroupID to userID/groupID. As the volume's usage
+// is unaffected, only the hard limits of a new owner (i.e. userID if it differs from formerUserID and
+// groupID if it differs from formerGroupID) are enforced.
+func (vS *volumeStruct) CheckOwnerChangeQuota(userID InodeUserID, groupID InodeGroupID, formerUserID InodeUserID, formerGroupID InodeGroupID, bytesDelta uint64) (reservation QuotaReservation, err error) {
+	reservation, err = vS.reserveQuota(QuotaReservation{
+		userID:        userID,
+		groupID:       groupID,
+		bytes:         bytesDelta,
+		inodes:        1,
+		volumeCharged: false,
+		userCharged:   userID != formerUserID,
+		groupCharged:  groupID != formerGroupID,
+	})
+	return
+}
+
+// reserveQuota checks the charge described by request against the limits it applies to and, if
+// admitted, adds it to the pending counts. Absent a quota, an empty reservation is returned.
+func (vS *volumeStruct) reserveQuota(request QuotaReservation) (reservation QuotaReservation, err error) {
+	var (
+		groupLimits OwnerQuota
+		groupStats  headhunter.OwnerStats
+		now         time.Time
+		ok          bool
+		userLimits  OwnerQuota
+		userStats   headhunter.OwnerStats
+		volumeStats VolumeStats
+	)
+
+	if ((0 == request.bytes) && (0 == request.inodes)) || !(request.volumeCharged || request.userCharged || request.groupCharged) {
+		// Operations that consume nothing are always allowed (e.g. to let usage be reduced)
+		err = nil
+		return
+	}
+
+	now = time.Now()
+	volumeStats = vS.FetchVolumeStats()
+	userStats, groupStats = vS.headhunterVolumeHandle.FetchOwnerStatsByID(uint32(request.userID), uint32(request.groupID))
+
+	vS.Lock()
+	defer vS.Unlock()
+
+	if nil == vS.quota {
+		err = nil
+		return
+	}
+
+	if request.volumeCharged && (0 != request.bytes) {
+		err = vS.quota.checkLimit("bytes", volumeStats.ReferencedBytes+vS.quota.pendingBytes+request.bytes, vS.quota.limits.HardBytes, vS.quota.limits.SoftBytes, &vS.quota.softBytesExceededSince, now, vS.volumeName)
+		if nil != err {
+			return
+		}
+	}
+
+	if request.volumeCharged && (0 != request.inodes) {
+		err = vS.quota.checkLimit("inodes", volumeStats.InodeCount+vS.quota.pendingInodes+request.inodes, vS.quota.limits.HardInodes, vS.quota.limits.SoftInodes, &vS.quota.softInodesExceededSince, now, vS.volumeName)
+		if nil != err {
+			return
+		}
+	}
+
+	userLimits, ok = vS.quota.userLimits[request.userID]
+	if request.userCharged && ok {
+		userStats.Bytes += vS.quota.userPending[request.userID].Bytes
+		userStats.Inodes += vS.quota.userPending[request.userID].Inodes
+		err = checkOwnerLimits(fmt.Sprintf("userID %v", request.userID), userStats, userLimits, request.bytes, request.inodes, vS.volumeName)
+		if nil != err {
+			return
+		}
+	}
+
+	groupLimits, ok = vS.quota.groupLimits[request.groupID]
+	if request.groupCharged && ok {
+		groupStats.Bytes += vS.quota.groupPending[request.groupID].Bytes
+		groupStats.Inodes += vS.quota.groupPending[request.groupID].Inodes
+		err = checkOwnerLimits(fmt.Sprintf("groupID %v", request.groupID), groupStats, groupLimits, request.bytes, request.inodes, vS.volumeName)
+		if nil != err {
+			return
+		}
+	}
+
+	reservation = request
+	vS.quota.adjustPending(reservation, true)
+
+	err = nil
+	return
+}
+
+// SettleQuotaReservation hands reservation over to inodeNumber once the charge it admitted has been
+// applied to that inode. It remains reserved until the inode is next flushed (at which point the charge
+// is reflected in VolumeStats and the owners' stats)... or is released at once if the inode is clean.
+func (vS *volumeStruct) SettleQuotaReservation(inodeNumber InodeNumber, reservation QuotaReservation) {
+	var (
+		inode *inMemoryInodeStruct
+		ok    bool
+	)
+
+	if (0 == reservation.bytes) && (0 == reservation.inodes) {
+		return
+	}
+
+	vS.Lock()
+	defer vS.Unlock()
+
+	inode, ok = vS.inodeCache[inodeNumber]
+	if ok && inode.dirty {
+		inode.quotaReservations = append(inode.quotaReservations, reservation)
+		return
+	}
+
+	if nil != vS.quota {
+		vS.quota.adjustPending(reservation, false)
+	}
+}
+
+// ReleaseQuotaReservation returns reservation unused (e.g. because the operation it was for failed).
+func (vS *volumeStruct) ReleaseQuotaReservation(reservation QuotaReservation) {
+	if (0 == reservation.bytes) && (0 == reservation.inodes) {
+		return
+	}
+
+	vS.Lock()
+	if nil != vS.quota {
+		vS.quota.adjustPending(reservation, false)
+	}
+	vS.Unlock()
+}
+
+// releaseInodeQuotaReservations releases any reservations settled on inode now that it has been flushed
+// (or destroyed). Note that vS.Lock is only taken if there are any... as some inodes (e.g. the RootDirInode)
+// are flushed with vS.Lock already held.
+func (vS *volumeStruct) releaseInodeQuotaReservations(inode *inMemoryInodeStruct) {
+	var (
+		reservation QuotaReservation
+	)
+
+	if 0 == len(inode.quotaReservations) {
+		return
+	}
+
+	vS.Lock()
+	if nil != vS.quota {
+		for _, reservation = range inode.quotaReservations {
+			vS.quota.adjustPending(reservation, false)
+		}
+	}
+	inode.quotaReservations = nil
+	vS.Unlock()
+}
+
+// adjustPending adds (or, if !reserve, removes) reservation to the pending counts. Caller must hold vS.Lock.
+func (quota *quotaStruct) adjustPending(reservation QuotaReservation, reserve bool) {
+	var (
+		ownerPending headhunter.OwnerStats
+	)
+
+	if reservation.volumeCharged {
+		quota.pendingBytes = adjustPendingCount(quota.pendingBytes, reservation.bytes, reserve)
+		quota.pendingInodes = adjustPendingCount(quota.pendingInodes, reservation.inodes, reserve)
+	}
+
+	if reservation.userCharged {
+		ownerPending = quota.userPending[reservation.userID]
+		ownerPending.Bytes = adjustPendingCount(ownerPending.Bytes, reservation.bytes, reserve)
+		ownerPending.Inodes = adjustPendingCount(ownerPending.Inodes, reservation.inodes, reserve)
+		if (0 == ownerPending.Bytes) && (0 == ownerPending.Inodes) {
+			delete(quota.userPending, reservation.userID)
+		} else {
+			quota.userPending[reservation.userID] = ownerPending
+		}
+	}
+
+	if reservation.groupCharged {
+		ownerPending = quota.groupPending[reservation.groupID]
+		ownerPending.Bytes = adjustPendingCount(ownerPending.Bytes, reservation.bytes, reserve)
+		ownerPending.Inodes = adjustPendingCount(ownerPending.Inodes, reservation.inodes, reserve)
+		if (0 == ownerPending.Bytes) && (0 == ownerPending.Inodes) {
+			delete(quota.groupPending, reservation.groupID)
+		} else {
+			quota.groupPending[reservation.groupID] = ownerPending
+		}
+	}
+}
+
+func adjustPendingCount(pending uint64, delta uint64, reserve bool) uint64 {
+	if reserve {
+		return pending + delta
+	}
+	if delta > pending {
+		return 0 // never let a stray release wrap around
+	}
+	return pending - delta
+}
+
+func checkOwnerLimits(owner string, ownerStats headhunter.OwnerStats, ownerLimits OwnerQuota, bytesDelta uint64, inodesDelta uint64, volumeName string) (err error) {
+	if (0 != bytesDelta) && (0 != ownerLimits.HardBytes) && (ownerStats.Bytes+bytesDelta > ownerLimits.HardBytes) {
+		stats.IncrementOperations(&stats.QuotaHardLimitExceededOps)
+		err = fmt.Errorf("%s: %s in volumeName \"%v\" would exceed its hard limit of %v bytes", utils.GetFnName(), owner, volumeName, ownerLimits.HardBytes)
+		err = blunder.AddError(err, blunder.QuotaExceededError)
+		return
+	}
+
+	if (0 != inodesDelta) && (0 != ownerLimits.HardInodes) && (ownerStats.Inodes+inodesDelta > ownerLimits.HardInodes) {
+		stats.IncrementOperations(&stats.QuotaHardLimitExceededOps)
+		err = fmt.Errorf("%s: %s in volumeName \"%v\" would exceed its hard limit of %v inodes", utils.GetFnName(), owner, volumeName, ownerLimits.HardInodes)
+		err = blunder.AddError(err, blunder.QuotaExceededError)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (quota *quotaStruct) checkLimit(what string, projectedUsage uint64, hardLimit uint64, softLimit uint64, softExceededSince *time.Time, now time.Time, volumeName string) (err error) {
+	if (0 != hardLimit) && (projectedUsage > hardLimit) {
+		stats.IncrementOperations(&stats.QuotaHardLimitExceededOps)
+		err = fmt.Errorf("%s: volumeName \"%v\" would exceed its hard limit of %v %s", utils.GetFnName(), volumeName, hardLimit, what)
+		err = blunder.AddError(err, blunder.QuotaExceededError)
+		return
+	}
+
+	if (0 == softLimit) || (projectedUsage <= softLimit) {
+		*softExceededSince = time.Time{}
+		err = nil
+		return
+	}
+
+	if softExceededSince.IsZero() {
+		*softExceededSince = now
+		logger.Warnf("Volume \"%v\" has exceeded its soft limit of %v %s (grace period %v)", volumeName, softLimit, what, quota.limits.GracePeriod)
+	}
+
+	if now.Sub(*softExceededSince) >= quota.limits.GracePeriod {
+		stats.IncrementOperations(&stats.QuotaSoftLimitExceededOps)
+		err = fmt.Errorf("%s: volumeName \"%v\" has exceeded its soft limit of %v %s for longer than %v", utils.GetFnName(), volumeName, softLimit, what, quota.limits.GracePeriod)
+		err = blunder.AddError(err, blunder.QuotaExceededError)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) FetchQuotaStatus() (quotaStatus QuotaStatus) {
+	volumeStats := vS.FetchVolumeStats()
+
+	quotaStatus.BytesUsed = volumeStats.ReferencedBytes
+	quotaStatus.InodesUsed = volumeStats.InodeCount
+
+	vS.Lock()
+	if nil != vS.quota {
+		quotaStatus.Limits = vS.quota.limits
+		quotaStatus.SoftBytesExceededSince = vS.quota.softBytesExceededSince
+		quotaStatus.SoftInodesExceededSince = vS.quota.softInodesExceededSince
+	}
+	vS.Unlock()
+
+	return
+}
+
+// FetchOwnerQuotaStatus reports usage for every user and group either owning inodes in the volume or
+// having limits configured for it.
+func (vS *volumeStruct) FetchOwnerQuotaStatus() (userQuotaStatus map[InodeUserID]OwnerQuotaStatus, groupQuotaStatus map[InodeGroupID]OwnerQuotaStatus) {
+	var (
+		groupID     InodeGroupID
+		groupStats  map[uint32]headhunter.OwnerStats
+		ownerID     uint32
+		ownerLimits OwnerQuota
+		ownerStats  headhunter.OwnerStats
+		ownerStatus OwnerQuotaStatus
+		userID      InodeUserID
+		userStats   map[uint32]headhunter.OwnerStats
+	)
+
+	userStats, groupStats = vS.headhunterVolumeHandle.FetchOwnerStats()
+
+	userQuotaStatus = make(map[InodeUserID]OwnerQuotaStatus, len(userStats))
+	for ownerID, ownerStats = range userStats {
+		userQuotaStatus[InodeUserID(ownerID)] = OwnerQuotaStatus{BytesUsed: ownerStats.Bytes, InodesUsed: ownerStats.Inodes}
+	}
+
+	groupQuotaStatus = make(map[InodeGroupID]OwnerQuotaStatus, len(groupStats))
+	for ownerID, ownerStats = range groupStats {
+		groupQuotaStatus[InodeGroupID(ownerID)] = OwnerQuotaStatus{BytesUsed: ownerStats.Bytes, InodesUsed: ownerStats.Inodes}
+	}
+
+	vS.Lock()
+	if nil != vS.quota {
+		for userID, ownerLimits = range vS.quota.userLimits {
+			ownerStatus = userQuotaStatus[userID]
+			ownerStatus.Limits = ownerLimits
+			userQuotaStatus[userID] = ownerStatus
+		}
+		for groupID, ownerLimits = range vS.quota.groupLimits {
+			ownerStatus = groupQuotaStatus[groupID]
+			ownerStatus.Limits = ownerLimits
+			groupQuotaStatus[groupID] = ownerStatus
+		}
+	}
+	vS.Unlock()
+
+	return
+}
+
+// FetchOwnerQuotaStatusByID reports usage (and any limits) for just userID and groupID
+func (vS *volumeStruct) FetchOwnerQuotaStatusByID(userID InodeUserID, groupID InodeGroupID) (userQuotaStatus OwnerQuotaStatus, groupQuotaStatus OwnerQuotaStatus) {
+	userStats, groupStats := vS.headhunterVolumeHandle.FetchOwnerStatsByID(uint32(userID), uint32(groupID))
+
+	userQuotaStatus = OwnerQuotaStatus{BytesUsed: userStats.Bytes, InodesUsed: userStats.Inodes}
+	groupQuotaStatus = OwnerQuotaStatus{BytesUsed: groupStats.Bytes, InodesUsed: groupStats.Inodes}
+
+	vS.Lock()
+	if nil != vS.quota {
+		userQuotaStatus.Limits = vS.quota.userLimits[userID]
+		groupQuotaStatus.Limits = vS.quota.groupLimits[groupID]
+	}
+	vS.Unlock()
+
+	return
+}
@@ -0,0 +1,459 @@
+package inode
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestQuota(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	vS.Lock()
+	savedQuota := *vS.quota
+	vS.Unlock()
+
+	defer func() {
+		vS.Lock()
+		*vS.quota = savedQuota
+		vS.Unlock()
+	}()
+
+	quotaStatus := testVolumeHandle.FetchQuotaStatus()
+
+	if (VolumeQuota{GracePeriod: quotaGracePeriodDefault}) != quotaStatus.Limits {
+		t.Fatalf("FetchQuotaStatus() returned unexpected default Limits: %+v", quotaStatus.Limits)
+	}
+
+	reservation, err := testVolumeHandle.CheckQuota(0, 0, 1000000, 1000000)
+	if nil != err {
+		t.Fatalf("CheckQuota() without limits failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+
+	// Hard limits are enforced immediately
+
+	vS.Lock()
+	vS.quota.limits = VolumeQuota{
+		HardBytes:  quotaStatus.BytesUsed + 100,
+		HardInodes: quotaStatus.InodesUsed + 1,
+	}
+	vS.Unlock()
+
+	reservation, err = testVolumeHandle.CheckQuota(0, 0, 100, 1)
+	if nil != err {
+		t.Fatalf("CheckQuota() up to hard limits failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+	_, err = testVolumeHandle.CheckQuota(0, 0, 101, 0)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond HardBytes should have failed with QuotaExceededError: %v", err)
+	}
+	_, err = testVolumeHandle.CheckQuota(0, 0, 0, 2)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond HardInodes should have failed with QuotaExceededError: %v", err)
+	}
+	reservation, err = testVolumeHandle.CheckQuota(0, 0, 0, 0)
+	if nil != err {
+		t.Fatalf("CheckQuota() consuming nothing should always succeed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+
+	// Soft limits are only enforced once exceeded for GracePeriod
+
+	vS.Lock()
+	vS.quota.limits = VolumeQuota{
+		SoftBytes:   quotaStatus.BytesUsed + 100,
+		GracePeriod: time.Hour,
+	}
+	vS.Unlock()
+
+	reservation, err = testVolumeHandle.CheckQuota(0, 0, 200, 0)
+	if nil != err {
+		t.Fatalf("CheckQuota() beyond SoftBytes within GracePeriod failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+
+	quotaStatus = testVolumeHandle.FetchQuotaStatus()
+	if quotaStatus.SoftBytesExceededSince.IsZero() {
+		t.Fatalf("FetchQuotaStatus() should have reported SoftBytes exceeded")
+	}
+
+	vS.Lock()
+	vS.quota.softBytesExceededSince = time.Now().Add(-2 * time.Hour)
+	vS.Unlock()
+
+	_, err = testVolumeHandle.CheckQuota(0, 0, 200, 0)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond SoftBytes after GracePeriod should have failed with QuotaExceededError: %v", err)
+	}
+
+	// Dropping back under the soft limit resets the grace period
+
+	reservation, err = testVolumeHandle.CheckQuota(0, 0, 50, 0)
+	if nil != err {
+		t.Fatalf("CheckQuota() under SoftBytes failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+
+	quotaStatus = testVolumeHandle.FetchQuotaStatus()
+	if !quotaStatus.SoftBytesExceededSince.IsZero() {
+		t.Fatalf("FetchQuotaStatus() should no longer have reported SoftBytes exceeded")
+	}
+}
+
+func TestOwnerQuota(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	vS.Lock()
+	savedQuota := *vS.quota
+	vS.Unlock()
+
+	defer func() {
+		vS.Lock()
+		*vS.quota = savedQuota
+		vS.Unlock()
+	}()
+
+	// Usage is charged to a file's owner once flushed
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 2000, 3000)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	userQuotaStatus, groupQuotaStatus := testVolumeHandle.FetchOwnerQuotaStatus()
+	if (OwnerQuotaStatus{BytesUsed: 10, InodesUsed: 1}) != userQuotaStatus[2000] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for userID 2000: %+v", userQuotaStatus[2000])
+	}
+	if (OwnerQuotaStatus{BytesUsed: 10, InodesUsed: 1}) != groupQuotaStatus[3000] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for groupID 3000: %+v", groupQuotaStatus[3000])
+	}
+
+	// Per-owner hard limits are enforced only for the owners they name
+
+	vS.Lock()
+	vS.quota.userLimits = map[InodeUserID]OwnerQuota{2000: OwnerQuota{HardBytes: 15}}
+	vS.quota.groupLimits = map[InodeGroupID]OwnerQuota{3000: OwnerQuota{HardInodes: 1}}
+	vS.Unlock()
+
+	reservation, err := testVolumeHandle.CheckQuota(2000, 0, 5, 0)
+	if nil != err {
+		t.Fatalf("CheckQuota() up to userID 2000's HardBytes failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+	_, err = testVolumeHandle.CheckQuota(2000, 0, 6, 0)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond userID 2000's HardBytes should have failed with QuotaExceededError: %v", err)
+	}
+	_, err = testVolumeHandle.CheckQuota(0, 3000, 0, 1)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond groupID 3000's HardInodes should have failed with QuotaExceededError: %v", err)
+	}
+	reservation, err = testVolumeHandle.CheckQuota(2001, 3001, 1000, 1000)
+	if nil != err {
+		t.Fatalf("CheckQuota() for owners without limits failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+
+	userQuotaStatus, _ = testVolumeHandle.FetchOwnerQuotaStatus()
+	if (OwnerQuotaStatus{Limits: OwnerQuota{HardBytes: 15}, BytesUsed: 10, InodesUsed: 1}) != userQuotaStatus[2000] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for limited userID 2000: %+v", userQuotaStatus[2000])
+	}
+
+	userQuota, groupQuota := testVolumeHandle.FetchOwnerQuotaStatusByID(2000, 3000)
+	if (OwnerQuotaStatus{Limits: OwnerQuota{HardBytes: 15}, BytesUsed: 10, InodesUsed: 1}) != userQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for userID 2000: %+v", userQuota)
+	}
+	if (OwnerQuotaStatus{Limits: OwnerQuota{HardInodes: 1}, BytesUsed: 10, InodesUsed: 1}) != groupQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for groupID 3000: %+v", groupQuota)
+	}
+	userQuota, _ = testVolumeHandle.FetchOwnerQuotaStatusByID(2001, 3001)
+	if (OwnerQuotaStatus{}) != userQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for unused userID 2001: %+v", userQuota)
+	}
+
+	// Changing ownership is checked against only the new owner's limits
+
+	vS.Lock()
+	vS.quota.userLimits[2002] = OwnerQuota{HardBytes: 5}
+	vS.Unlock()
+
+	_, err = testVolumeHandle.CheckOwnerChangeQuota(2002, 3000, 2000, 3000, 10)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckOwnerChangeQuota() beyond userID 2002's HardBytes should have failed with QuotaExceededError: %v", err)
+	}
+	reservation, err = testVolumeHandle.CheckOwnerChangeQuota(2001, 3000, 2000, 3000, 10)
+	if nil != err {
+		t.Fatalf("CheckOwnerChangeQuota() keeping groupID 3000 at its HardInodes failed: %v", err)
+	}
+	testVolumeHandle.ReleaseQuotaReservation(reservation)
+	_, err = testVolumeHandle.CheckOwnerChangeQuota(2001, 3000, 2001, 3001, 10)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckOwnerChangeQuota() to groupID 3000 beyond its HardInodes should have failed with QuotaExceededError: %v", err)
+	}
+
+	vS.Lock()
+	delete(vS.quota.userLimits, 2002)
+	vS.Unlock()
+
+	// Changing ownership moves usage to the new owner
+
+	err = testVolumeHandle.SetOwnerUserID(fileInodeNumber, 2001)
+	if nil != err {
+		t.Fatalf("SetOwnerUserID() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	userQuotaStatus, groupQuotaStatus = testVolumeHandle.FetchOwnerQuotaStatus()
+	if (OwnerQuotaStatus{Limits: OwnerQuota{HardBytes: 15}}) != userQuotaStatus[2000] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for former userID 2000: %+v", userQuotaStatus[2000])
+	}
+	if (OwnerQuotaStatus{BytesUsed: 10, InodesUsed: 1}) != userQuotaStatus[2001] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for userID 2001: %+v", userQuotaStatus[2001])
+	}
+	if (OwnerQuotaStatus{Limits: OwnerQuota{HardInodes: 1}, BytesUsed: 10, InodesUsed: 1}) != groupQuotaStatus[3000] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for unchanged groupID 3000: %+v", groupQuotaStatus[3000])
+	}
+
+	// Destroying the file releases everything charged to its owner
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	userQuotaStatus, groupQuotaStatus = testVolumeHandle.FetchOwnerQuotaStatus()
+	_, ok := userQuotaStatus[2001]
+	if ok {
+		t.Fatalf("FetchOwnerQuotaStatus() should no longer have reported userID 2001: %+v", userQuotaStatus[2001])
+	}
+	if (OwnerQuotaStatus{Limits: OwnerQuota{HardInodes: 1}}) != groupQuotaStatus[3000] {
+		t.Fatalf("FetchOwnerQuotaStatus() returned unexpected status for groupID 3000 after Destroy(): %+v", groupQuotaStatus[3000])
+	}
+}
+
+func TestQuotaReservation(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	vS.Lock()
+	savedQuota := *vS.quota
+	vS.Unlock()
+
+	defer func() {
+		vS.Lock()
+		*vS.quota = savedQuota
+		vS.Unlock()
+	}()
+
+	quotaStatus := testVolumeHandle.FetchQuotaStatus()
+
+	vS.Lock()
+	vS.quota.limits = VolumeQuota{
+		HardBytes:  quotaStatus.BytesUsed + 100,
+		HardInodes: quotaStatus.InodesUsed + 1,
+	}
+	vS.Unlock()
+
+	// Concurrent checks may not together exceed a hard limit
+
+	var (
+		admitted     []QuotaReservation
+		admittedLock sync.Mutex
+		checkers     sync.WaitGroup
+	)
+
+	for i := 0; i < 20; i++ {
+		checkers.Add(1)
+		go func() {
+			reservation, checkErr := testVolumeHandle.CheckQuota(0, 0, 10, 0)
+			if nil == checkErr {
+				admittedLock.Lock()
+				admitted = append(admitted, reservation)
+				admittedLock.Unlock()
+			} else if !blunder.Is(checkErr, blunder.QuotaExceededError) {
+				t.Errorf("CheckQuota() failed unexpectedly: %v", checkErr)
+			}
+			checkers.Done()
+		}()
+	}
+	checkers.Wait()
+
+	if 10 != len(admitted) {
+		t.Fatalf("Concurrent CheckQuota() calls admitted %v charges of 10 bytes against a limit of 100", len(admitted))
+	}
+
+	for _, reservation := range admitted {
+		testVolumeHandle.ReleaseQuotaReservation(reservation)
+	}
+
+	// A settled reservation is held until the inode it was settled on is flushed
+
+	reservation, err := testVolumeHandle.CheckQuota(0, 0, 60, 1)
+	if nil != err {
+		t.Fatalf("CheckQuota() after releasing reservations failed: %v", err)
+	}
+	_, err = testVolumeHandle.CheckQuota(0, 0, 41, 0)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond HardBytes including reserved bytes should have failed with QuotaExceededError: %v", err)
+	}
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, make([]byte, 60), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	testVolumeHandle.SettleQuotaReservation(fileInodeNumber, reservation)
+
+	_, err = testVolumeHandle.CheckQuota(0, 0, 0, 1)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond HardInodes including unflushed inode should have failed with QuotaExceededError: %v", err)
+	}
+
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	vS.Lock()
+	pendingQuota := *vS.quota
+	vS.Unlock()
+
+	if (0 != pendingQuota.pendingBytes) || (0 != pendingQuota.pendingInodes) || (0 != len(pendingQuota.userPending)) || (0 != len(pendingQuota.groupPending)) {
+		t.Fatalf("Flush() should have released settled reservation: %+v", pendingQuota)
+	}
+
+	_, err = testVolumeHandle.CheckQuota(0, 0, 41, 0)
+	if !blunder.Is(err, blunder.QuotaExceededError) {
+		t.Fatalf("CheckQuota() beyond HardBytes including flushed bytes should have failed with QuotaExceededError: %v", err)
+	}
+	reservation, err = testVolumeHandle.CheckQuota(0, 0, 40, 0)
+	if nil != err {
+		t.Fatalf("CheckQuota() up to HardBytes including flushed bytes failed: %v", err)
+	}
+
+	// Settling on a clean inode releases the reservation at once
+
+	testVolumeHandle.SettleQuotaReservation(fileInodeNumber, reservation)
+
+	vS.Lock()
+	pendingQuota = *vS.quota
+	vS.Unlock()
+
+	if 0 != pendingQuota.pendingBytes {
+		t.Fatalf("SettleQuotaReservation() on a clean inode should have released it: %+v", pendingQuota)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
+
+func TestOwnerStatsRebuild(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 4000, 5000)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	otherFileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 4000, 5001)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(otherFileInodeNumber, 0, []byte("01234"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(otherFileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	// Restart from a checkpoint predating OwnerStats... the per-owner totals must be rebuilt from the inodes
+
+	testRestartWithDowngradedCheckpoint(t, "AUTH_test", ".__checkpoint__", 3)
+
+	testVolumeHandle, err = FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	userQuota, groupQuota := testVolumeHandle.FetchOwnerQuotaStatusByID(4000, 5000)
+	if (OwnerQuotaStatus{BytesUsed: 15, InodesUsed: 2}) != userQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for userID 4000: %+v", userQuota)
+	}
+	if (OwnerQuotaStatus{BytesUsed: 10, InodesUsed: 1}) != groupQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for groupID 5000: %+v", groupQuota)
+	}
+	_, groupQuota = testVolumeHandle.FetchOwnerQuotaStatusByID(4000, 5001)
+	if (OwnerQuotaStatus{BytesUsed: 5, InodesUsed: 1}) != groupQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for groupID 5001: %+v", groupQuota)
+	}
+
+	if 5 != testCheckpointHeaderVersion(t, "AUTH_test", ".__checkpoint__") {
+		t.Fatalf("checkpoint should have been rewritten as version 5")
+	}
+
+	// Subsequent activity is charged against the rebuilt totals
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	err = testVolumeHandle.Destroy(otherFileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	userQuota, groupQuota = testVolumeHandle.FetchOwnerQuotaStatusByID(4000, 5000)
+	if (OwnerQuotaStatus{}) != userQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for userID 4000: %+v", userQuota)
+	}
+	if (OwnerQuotaStatus{}) != groupQuota {
+		t.Fatalf("FetchOwnerQuotaStatusByID() returned unexpected status for groupID 5000: %+v", groupQuota)
+	}
+}
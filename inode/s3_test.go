@@ -0,0 +1,95 @@
+package inode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestS3Volume(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestS3Volume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestS3Volume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(RootDirInodeNumber, "S3File", fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Link() failed: %v", err)
+	}
+
+	// Write enough (in several chunks) that the LogSegment is sent as a multipart upload of several parts
+
+	expectedContents := make([]byte, 0)
+
+	for i := 0; i < 5; i++ {
+		buf := bytes.Repeat([]byte{byte('a' + i)}, 3000)
+		err = testVolumeHandle.Write(fileInodeNumber, uint64(len(expectedContents)), buf, nil)
+		if nil != err {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		expectedContents = append(expectedContents, buf...)
+	}
+
+	err = testVolumeHandle.Flush(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expectedContents)), nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(expectedContents, readBuf) {
+		t.Fatalf("Read() returned wrong data")
+	}
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 5999, 2, nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if "bc" != string(readBuf) {
+		t.Fatalf("Read() returned \"%v\" (expected \"bc\")", string(readBuf))
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	// The checkpoint must land in (and be recorded by) the S3 ObjectStore as well
+
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	checkpointContainerHeaders, err := vS.objectStore.ContainerHead(vS.accountName, ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("ContainerHead() of checkpoint container failed: %v", err)
+	}
+	if 1 != len(checkpointContainerHeaders["X-Container-Meta-Checkpoint"]) {
+		t.Fatalf("checkpoint container headers lack X-Container-Meta-Checkpoint: %v", checkpointContainerHeaders)
+	}
+
+	_, containerList, err := vS.objectStore.AccountGet(vS.accountName)
+	if nil != err {
+		t.Fatalf("AccountGet() failed: %v", err)
+	}
+	if 2 > len(containerList) {
+		t.Fatalf("AccountGet() returned containerList %v (expected both checkpoint & LogSegment containers)", containerList)
+	}
+
+	err = testVolumeHandle.Unlink(RootDirInodeNumber, "S3File")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
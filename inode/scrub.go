@@ -0,0 +1,381 @@
+package inode
+
+// Orphaned object scrubbing
+//
+// Should a crash land between provisioning a log segment (or flushing B+Tree nodes to a checkpoint object)
+// and the checkpoint that would have recorded it... or should an ObjectDeleteAsync() simply fail... objects
+// are left behind in the volume's PhysicalContainers and checkpoint container that nothing references.
+//
+// FindOrphanedObjects() lists each of those containers comparing what it finds against the volume's
+// LogSegmentRecs and checkpoint B+Trees (as well as against what snapshots and leases retain). Nothing is
+// modified. DeleteOrphanedObjects() is then handed the dry run's ScrubReport (perhaps trimmed of some of
+// the orphans it found)... each orphan being confirmed to remain unreferenced before it is deleted. As
+// objects are only ever named by a nonce, those named by a nonce at or beyond that fetched as the dry run
+// began (ScrubReport.Nonce) postdate it and are ignored by both passes (as are objects with names that are
+// not a nonce at all... e.g. the fencing token in the checkpoint container).
+//
+// An object handed out by ProvisionObject() is referenced by nothing until the middleware, having PUT it,
+// reports it via Wrote(). Such objects are tracked in provisionedObjectMap and are not orphans until
+// [Volume:<VolumeName>]ScrubGracePeriod has elapsed (the PUT presumably having been abandoned). Objects
+// provisioned prior to a restart are not tracked, so no log segment is taken for an orphan until the
+// volume has been up for at least ScrubGracePeriod.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+func (vS *volumeStruct) adoptScrubParameters(confMap conf.ConfMap) (err error) {
+	vS.scrubGracePeriod, err = confMap.FetchOptionValueDuration(utils.VolumeNameConfSection(vS.volumeName), "ScrubGracePeriod")
+	if nil != err {
+		vS.scrubGracePeriod = time.Hour // TODO: eventually, just return
+	}
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) FindOrphanedObjects(stopChan chan bool) (scrubReport *ScrubReport, err error) {
+	var (
+		checkpointContainerName string
+		containerList           []string
+		containerName           string
+		nonce                   uint64
+		objectList              []string
+		objectName              string
+		orphan                  ScrubOrphan
+		orphaned                bool
+	)
+
+	if nil != vS.snapshotOf {
+		err = vS.snapshotReadOnlyError(utils.GetFnName())
+		return
+	}
+
+	stats.IncrementOperations(&stats.ScrubFindOps)
+
+	scrubReport = &ScrubReport{Orphans: make([]ScrubOrphan, 0)}
+
+	nonce, err = vS.headhunterVolumeHandle.FetchNonce()
+	if nil != err {
+		return
+	}
+
+	scrubReport.Nonce = nonce
+
+	vS.pruneProvisionedObjects()
+
+	checkpointContainerName = vS.headhunterVolumeHandle.FetchCheckpointContainerName()
+
+	_, containerList, err = vS.objectStore.AccountGet(vS.accountName)
+	if nil != err {
+		err = fmt.Errorf("%s: unable to list containers of volume \"%v\": %v", utils.GetFnName(), vS.volumeName, err)
+		return
+	}
+
+	sort.Strings(containerList)
+
+	for _, containerName = range containerList {
+		if (checkpointContainerName != containerName) && !vS.isPhysicalContainer(containerName) {
+			continue
+		}
+
+		_, objectList, err = vS.objectStore.ContainerGet(vS.accountName, containerName)
+		if nil != err {
+			err = fmt.Errorf("%s: unable to list objects in container \"%v\" of volume \"%v\": %v", utils.GetFnName(), containerName, vS.volumeName, err)
+			return
+		}
+
+		scrubReport.ContainersScanned++
+
+		for _, objectName = range objectList {
+			select {
+			case _ = <-stopChan:
+				err = nil
+				return
+			default:
+			}
+
+			scrubReport.ObjectsScanned++
+
+			orphan, orphaned, err = vS.checkForOrphan(containerName, objectName, (checkpointContainerName == containerName), nonce)
+			if nil != err {
+				return
+			}
+			if orphaned {
+				stats.IncrementOperations(&stats.ScrubOrphanFoundOps)
+				scrubReport.Orphans = append(scrubReport.Orphans, orphan)
+				scrubReport.OrphanedBytes += orphan.ObjectLength
+			}
+		}
+	}
+
+	logger.Infof("Scrub of volume \"%v\" found %v orphaned object(s) totalling %v bytes", vS.volumeName, len(scrubReport.Orphans), scrubReport.OrphanedBytes)
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) DeleteOrphanedObjects(dryRunReport *ScrubReport, stopChan chan bool) (scrubReport *ScrubReport, err error) {
+	var (
+		checkpointContainerName string
+		confirmedOrphans        []ScrubOrphan
+		orphan                  ScrubOrphan
+		orphaned                bool
+		readOnly                bool
+	)
+
+	if nil != vS.snapshotOf {
+		err = vS.snapshotReadOnlyError(utils.GetFnName())
+		return
+	}
+
+	vS.Lock()
+	readOnly = vS.readOnly
+	vS.Unlock()
+
+	if readOnly {
+		err = vS.readOnlyError(utils.GetFnName())
+		return
+	}
+
+	stats.IncrementOperations(&stats.ScrubDeleteOps)
+
+	if (nil == dryRunReport) || (0 == dryRunReport.Nonce) {
+		err = fmt.Errorf("%s: volume \"%v\" requires the ScrubReport of a dry run", utils.GetFnName(), vS.volumeName)
+		err = blunder.AddError(err, blunder.InvalidArgError)
+		return
+	}
+
+	scrubReport = &ScrubReport{Orphans: make([]ScrubOrphan, 0)}
+
+	checkpointContainerName = vS.headhunterVolumeHandle.FetchCheckpointContainerName()
+
+	confirmedOrphans = make([]ScrubOrphan, 0, len(dryRunReport.Orphans))
+
+	for _, orphan = range dryRunReport.Orphans {
+		if (checkpointContainerName != orphan.ContainerName) && !vS.isPhysicalContainer(orphan.ContainerName) {
+			err = fmt.Errorf("%s: container \"%v\" does not belong to volume \"%v\"", utils.GetFnName(), orphan.ContainerName, vS.volumeName)
+			err = blunder.AddError(err, blunder.InvalidArgError)
+			return
+		}
+
+		scrubReport.ObjectsScanned++
+
+		orphan, orphaned, err = vS.checkForOrphan(orphan.ContainerName, orphan.ObjectName, (checkpointContainerName == orphan.ContainerName), dryRunReport.Nonce)
+		if nil != err {
+			return
+		}
+		if orphaned {
+			confirmedOrphans = append(confirmedOrphans, orphan)
+		}
+	}
+
+	// What the live B+Trees no longer reference may yet be referenced by the last checkpoint... so
+	// persist them before deleting anything (just as ObjectDeleteAsync() callers await a checkpoint)
+
+	if 0 < len(confirmedOrphans) {
+		err = vS.headhunterVolumeHandle.DoCheckpoint()
+		if nil != err {
+			return
+		}
+	}
+
+	for _, orphan = range confirmedOrphans {
+		select {
+		case _ = <-stopChan:
+			err = nil
+			return
+		default:
+		}
+
+		err = vS.objectStore.ObjectDeleteSync(vS.accountName, orphan.ContainerName, orphan.ObjectName)
+		if nil != err {
+			if blunder.Is(err, blunder.NotFoundError) {
+				continue // already gone
+			}
+			logger.WarnfWithError(err, "Scrub of volume \"%v\" unable to delete orphaned object %v/%v", vS.volumeName, orphan.ContainerName, orphan.ObjectName)
+			scrubReport.DeleteFailures++
+			continue
+		}
+
+		stats.IncrementOperations(&stats.ScrubOrphanDeletedOps)
+
+		scrubReport.Orphans = append(scrubReport.Orphans, orphan)
+		scrubReport.OrphanedBytes += orphan.ObjectLength
+	}
+
+	logger.Infof("Scrub of volume \"%v\" deleted %v orphaned object(s) totalling %v bytes", vS.volumeName, len(scrubReport.Orphans), scrubReport.OrphanedBytes)
+
+	if 0 < scrubReport.DeleteFailures {
+		err = fmt.Errorf("%v orphaned object(s) could not be deleted", scrubReport.DeleteFailures)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) isPhysicalContainer(containerName string) (isPhysicalContainer bool) {
+	var (
+		physicalContainerNamePrefix string
+	)
+
+	vS.Lock()
+	defer vS.Unlock()
+
+	for physicalContainerNamePrefix = range vS.physicalContainerNamePrefixSet {
+		if strings.HasPrefix(containerName, physicalContainerNamePrefix) {
+			isPhysicalContainer = true
+			return
+		}
+	}
+
+	isPhysicalContainer = false
+	return
+}
+
+// checkForOrphan determines whether or not the specified object is referenced. If not, orphan is filled in.
+func (vS *volumeStruct) checkForOrphan(containerName string, objectName string, inCheckpointContainer bool, nonce uint64) (orphan ScrubOrphan, orphaned bool, err error) {
+	var (
+		objectLength uint64
+		objectNumber uint64
+		referenced   bool
+	)
+
+	orphaned = false
+
+	objectNumber, err = utils.HexStrToUint64(objectName)
+	if (nil != err) || (utils.Uint64ToHexStr(objectNumber) != objectName) {
+		err = nil // not named by a nonce... so not ours
+		return
+	}
+
+	if objectNumber >= nonce {
+		err = nil // postdates the scan
+		return
+	}
+
+	if inCheckpointContainer {
+		referenced = vS.headhunterVolumeHandle.CheckpointReferencesObject(objectNumber)
+	} else {
+		// Consulted before the LogSegmentRec as Wrote() records the latter before ceasing to track the object
+
+		if vS.provisionedObjectPending(objectNumber) {
+			err = nil
+			return
+		}
+
+		referenced, err = vS.logSegmentReferenced(objectNumber)
+		if nil != err {
+			return
+		}
+	}
+	if referenced {
+		err = nil
+		return
+	}
+
+	objectLength, err = vS.objectStore.ObjectContentLength(vS.accountName, containerName, objectName)
+	if nil != err {
+		if blunder.Is(err, blunder.NotFoundError) {
+			err = nil // deleted since it was listed
+		}
+		return
+	}
+
+	orphan = ScrubOrphan{
+		ContainerName: containerName,
+		ObjectName:    objectName,
+		ObjectLength:  objectLength,
+	}
+	orphaned = true
+
+	err = nil
+	return
+}
+
+// provisionedObjectPending determines whether or not the specified object, if handed out by ProvisionObject(),
+// may yet be recorded via Wrote().
+func (vS *volumeStruct) provisionedObjectPending(objectNumber uint64) (pending bool) {
+	var (
+		ok            bool
+		provisionTime time.Time
+	)
+
+	vS.Lock()
+	defer vS.Unlock()
+
+	provisionTime, ok = vS.provisionedObjectMap[objectNumber]
+	if ok {
+		pending = (time.Since(provisionTime) < vS.scrubGracePeriod)
+		return
+	}
+
+	// Objects provisioned before tracking began are unknown... so any of them might still be pending
+
+	pending = (time.Since(vS.provisionedObjectTrackingStart) < vS.scrubGracePeriod)
+	return
+}
+
+// pruneProvisionedObjects stops tracking objects provisioned more than scrubGracePeriod ago (their PUTs presumably abandoned).
+func (vS *volumeStruct) pruneProvisionedObjects() {
+	var (
+		objectNumber  uint64
+		provisionTime time.Time
+	)
+
+	vS.Lock()
+	defer vS.Unlock()
+
+	for objectNumber, provisionTime = range vS.provisionedObjectMap {
+		if time.Since(provisionTime) >= vS.scrubGracePeriod {
+			delete(vS.provisionedObjectMap, objectNumber)
+		}
+	}
+}
+
+func (vS *volumeStruct) logSegmentReferenced(logSegmentNumber uint64) (referenced bool, err error) {
+	var (
+		lease *leaseStruct
+	)
+
+	_, err = vS.headhunterVolumeHandle.GetLogSegmentRec(logSegmentNumber)
+	if nil == err {
+		referenced = true
+		return
+	}
+	if blunder.IsNot(err, blunder.NotFoundError) {
+		return
+	}
+
+	referenced, err = vS.headhunterVolumeHandle.SnapshotReferencesLogSegment(logSegmentNumber)
+	if (nil != err) || referenced {
+		return
+	}
+
+	// A log segment whose deletion was deferred by a lease (obtained via the volume or one of its snapshots) is still in use
+
+	globals.lease.Lock()
+	for _, lease = range globals.lease.leaseMap {
+		if (vS != lease.volume) && (vS != lease.volume.snapshotOf) {
+			continue
+		}
+		_, referenced = lease.volume.pinnedLogSegmentMap[logSegmentNumber]
+		if referenced {
+			break
+		}
+	}
+	globals.lease.Unlock()
+
+	err = nil
+	return
+}
@@ -0,0 +1,283 @@
+package inode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+func testScrubPutObject(t *testing.T, accountName string, containerName string, objectName string, buf []byte) {
+	chunkedPutContext, err := swiftclient.ObjectFetchChunkedPutContext(accountName, containerName, objectName)
+	if nil != err {
+		t.Fatalf("ObjectFetchChunkedPutContext(,\"%v\",\"%v\") failed: %v", containerName, objectName, err)
+	}
+	err = chunkedPutContext.SendChunk(buf)
+	if nil != err {
+		t.Fatalf("SendChunk() to \"%v/%v\" failed: %v", containerName, objectName, err)
+	}
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("Close() of \"%v/%v\" failed: %v", containerName, objectName, err)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	// A referenced log segment
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("referenced"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	offset := uint64(0)
+	length := uint64(len("referenced"))
+
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &offset, &length)
+	if (nil != err) || (1 != len(readPlan)) {
+		t.Fatalf("GetReadPlan() returned unexpected readPlan %v (err: %v)", readPlan, err)
+	}
+
+	referencedObjectName := utils.Uint64ToHexStr(readPlan[0].LogSegmentNumber)
+
+	// An object provisioned (as if by the middleware) and PUT but not yet recorded via Wrote()
+
+	objectPath, err := testVolumeHandle.ProvisionObject()
+	if nil != err {
+		t.Fatalf("ProvisionObject() failed: %v", err)
+	}
+	_, physicalContainerName, physicalObjectName, err := utils.PathToAcctContObj(objectPath)
+	if nil != err {
+		t.Fatalf("PathToAcctContObj(\"%v\") failed: %v", objectPath, err)
+	}
+	physicalObjectNumber, err := utils.HexStrToUint64(physicalObjectName)
+	if nil != err {
+		t.Fatalf("HexStrToUint64(\"%v\") failed: %v", physicalObjectName, err)
+	}
+	testScrubPutObject(t, vS.accountName, physicalContainerName, physicalObjectName, []byte("pending log segment"))
+
+	pendingOrphan := ScrubOrphan{ContainerName: physicalContainerName, ObjectName: physicalObjectName, ObjectLength: uint64(len("pending log segment"))}
+
+	// An object in the same container not named by a nonce
+
+	testScrubPutObject(t, vS.accountName, physicalContainerName, "NotANonce", []byte("not ours"))
+
+	// A checkpoint object that never made it into a checkpoint
+
+	checkpointContainerName := vS.headhunterVolumeHandle.FetchCheckpointContainerName()
+
+	checkpointObjectNumber, err := vS.headhunterVolumeHandle.FetchNonce()
+	if nil != err {
+		t.Fatalf("FetchNonce() failed: %v", err)
+	}
+	checkpointObjectName := utils.Uint64ToHexStr(checkpointObjectNumber)
+	testScrubPutObject(t, vS.accountName, checkpointContainerName, checkpointObjectName, []byte("orphaned checkpoint object"))
+
+	checkpointOrphan := ScrubOrphan{ContainerName: checkpointContainerName, ObjectName: checkpointObjectName, ObjectLength: uint64(len("orphaned checkpoint object"))}
+
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	// Pretend the volume has been up long enough that untracked objects may be orphans
+
+	vS.Lock()
+	provisionedObjectTrackingStart := vS.provisionedObjectTrackingStart
+	vS.provisionedObjectTrackingStart = time.Now().Add(-vS.scrubGracePeriod)
+	vS.Unlock()
+
+	defer func() {
+		vS.Lock()
+		vS.provisionedObjectTrackingStart = provisionedObjectTrackingStart
+		vS.Unlock()
+	}()
+
+	// A dry run should report (but not delete) the checkpoint object... but not the object awaiting Wrote()
+
+	scrubReport, err := testVolumeHandle.FindOrphanedObjects(make(chan bool, 1))
+	if nil != err {
+		t.Fatalf("FindOrphanedObjects() failed: %v", err)
+	}
+	if (0 == scrubReport.ContainersScanned) || (0 == scrubReport.ObjectsScanned) || (checkpointObjectNumber >= scrubReport.Nonce) {
+		t.Fatalf("FindOrphanedObjects() returned unexpected scrubReport: %+v", scrubReport)
+	}
+
+	// Objects left behind by earlier tests (or awaiting deletion following the next checkpoint) may also be reported
+
+	reportedOrphans := make(map[ScrubOrphan]struct{})
+	for _, orphan := range scrubReport.Orphans {
+		reportedOrphans[orphan] = struct{}{}
+		if (physicalContainerName == orphan.ContainerName) && (("NotANonce" == orphan.ObjectName) || (referencedObjectName == orphan.ObjectName) || (physicalObjectName == orphan.ObjectName)) {
+			t.Fatalf("FindOrphanedObjects() unexpectedly reported %+v", orphan)
+		}
+	}
+	_, ok := reportedOrphans[checkpointOrphan]
+	if !ok {
+		t.Fatalf("FindOrphanedObjects() failed to report %+v", checkpointOrphan)
+	}
+	if checkpointOrphan.ObjectLength > scrubReport.OrphanedBytes {
+		t.Fatalf("FindOrphanedObjects() returned unexpected OrphanedBytes")
+	}
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, checkpointContainerName, checkpointObjectName)
+	if nil != err {
+		t.Fatalf("FindOrphanedObjects() should not have deleted %v/%v: %v", checkpointContainerName, checkpointObjectName, err)
+	}
+
+	// A checkpoint object created after the dry run began
+
+	postScanObjectNumber, err := vS.headhunterVolumeHandle.FetchNonce()
+	if nil != err {
+		t.Fatalf("FetchNonce() failed: %v", err)
+	}
+	postScanObjectName := utils.Uint64ToHexStr(postScanObjectNumber)
+	testScrubPutObject(t, vS.accountName, checkpointContainerName, postScanObjectName, []byte("post scan checkpoint object"))
+
+	postScanOrphan := ScrubOrphan{ContainerName: checkpointContainerName, ObjectName: postScanObjectName, ObjectLength: uint64(len("post scan checkpoint object"))}
+
+	// Only the checkpoint object should be deleted... even if the others are (wrongly) handed to DeleteOrphanedObjects()
+
+	dryRunReport := &ScrubReport{Orphans: []ScrubOrphan{checkpointOrphan, pendingOrphan, postScanOrphan}, Nonce: scrubReport.Nonce}
+
+	// ...but not while the volume is read-only
+
+	vS.Lock()
+	vS.readOnly = true
+	vS.Unlock()
+
+	_, err = testVolumeHandle.DeleteOrphanedObjects(dryRunReport, make(chan bool, 1))
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("DeleteOrphanedObjects() on read-only volume should have failed with ReadOnlyError: %v", err)
+	}
+
+	vS.Lock()
+	vS.readOnly = false
+	vS.Unlock()
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, checkpointContainerName, checkpointObjectName)
+	if nil != err {
+		t.Fatalf("DeleteOrphanedObjects() on read-only volume should not have deleted %v/%v: %v", checkpointContainerName, checkpointObjectName, err)
+	}
+
+	scrubReport, err = testVolumeHandle.DeleteOrphanedObjects(dryRunReport, make(chan bool, 1))
+	if nil != err {
+		t.Fatalf("DeleteOrphanedObjects() failed: %v", err)
+	}
+	if (3 != scrubReport.ObjectsScanned) || (1 != len(scrubReport.Orphans)) || (checkpointOrphan != scrubReport.Orphans[0]) || (checkpointOrphan.ObjectLength != scrubReport.OrphanedBytes) {
+		t.Fatalf("DeleteOrphanedObjects() returned unexpected scrubReport: %+v", scrubReport)
+	}
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, checkpointContainerName, checkpointObjectName)
+	if blunder.IsNot(err, blunder.NotFoundError) {
+		t.Fatalf("DeleteOrphanedObjects() should have deleted %v/%v: %v", checkpointContainerName, checkpointObjectName, err)
+	}
+	for _, orphan := range []ScrubOrphan{pendingOrphan, postScanOrphan} {
+		_, err = swiftclient.ObjectContentLength(vS.accountName, orphan.ContainerName, orphan.ObjectName)
+		if nil != err {
+			t.Fatalf("DeleteOrphanedObjects() should not have deleted %v/%v: %v", orphan.ContainerName, orphan.ObjectName, err)
+		}
+	}
+
+	// Once ScrubGracePeriod has elapsed, an object still awaiting Wrote() is presumed abandoned
+
+	vS.Lock()
+	vS.provisionedObjectMap[physicalObjectNumber] = time.Now().Add(-vS.scrubGracePeriod)
+	vS.Unlock()
+
+	scrubReport, err = testVolumeHandle.FindOrphanedObjects(make(chan bool, 1))
+	if nil != err {
+		t.Fatalf("FindOrphanedObjects() failed: %v", err)
+	}
+
+	reportedOrphans = make(map[ScrubOrphan]struct{})
+	for _, orphan := range scrubReport.Orphans {
+		reportedOrphans[orphan] = struct{}{}
+	}
+	for _, orphan := range []ScrubOrphan{pendingOrphan, postScanOrphan} {
+		_, ok = reportedOrphans[orphan]
+		if !ok {
+			t.Fatalf("FindOrphanedObjects() failed to report %+v", orphan)
+		}
+	}
+
+	// Yet, if recorded via Wrote() after all, the provisioned object is no longer an orphan and must survive deletion
+
+	err = testVolumeHandle.Wrote(fileInodeNumber, 0, objectPath, 0, pendingOrphan.ObjectLength, false)
+	if nil != err {
+		t.Fatalf("Wrote() failed: %v", err)
+	}
+
+	vS.Lock()
+	_, ok = vS.provisionedObjectMap[physicalObjectNumber]
+	vS.Unlock()
+	if ok {
+		t.Fatalf("Wrote() should have ceased tracking %v/%v", physicalContainerName, physicalObjectName)
+	}
+
+	dryRunReport = &ScrubReport{Orphans: []ScrubOrphan{pendingOrphan, postScanOrphan}, Nonce: scrubReport.Nonce}
+
+	scrubReport, err = testVolumeHandle.DeleteOrphanedObjects(dryRunReport, make(chan bool, 1))
+	if nil != err {
+		t.Fatalf("DeleteOrphanedObjects() failed: %v", err)
+	}
+	if (2 != scrubReport.ObjectsScanned) || (1 != len(scrubReport.Orphans)) || (postScanOrphan != scrubReport.Orphans[0]) {
+		t.Fatalf("DeleteOrphanedObjects() returned unexpected scrubReport: %+v", scrubReport)
+	}
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, checkpointContainerName, postScanObjectName)
+	if blunder.IsNot(err, blunder.NotFoundError) {
+		t.Fatalf("DeleteOrphanedObjects() should have deleted %v/%v: %v", checkpointContainerName, postScanObjectName, err)
+	}
+	_, err = swiftclient.ObjectContentLength(vS.accountName, physicalContainerName, physicalObjectName)
+	if nil != err {
+		t.Fatalf("DeleteOrphanedObjects() should not have deleted %v/%v: %v", physicalContainerName, physicalObjectName, err)
+	}
+	_, err = swiftclient.ObjectContentLength(vS.accountName, physicalContainerName, "NotANonce")
+	if nil != err {
+		t.Fatalf("DeleteOrphanedObjects() should not have deleted %v/NotANonce: %v", physicalContainerName, err)
+	}
+
+	buf, err := testVolumeHandle.Read(fileInodeNumber, 0, pendingOrphan.ObjectLength, nil)
+	if (nil != err) || ("pending log segment" != string(buf)) {
+		t.Fatalf("Read() following DeleteOrphanedObjects() returned \"%s\" (err: %v)", buf, err)
+	}
+
+	// Deletion requires the report of a dry run... and may not touch containers not belonging to the volume
+
+	_, err = testVolumeHandle.DeleteOrphanedObjects(nil, make(chan bool, 1))
+	if blunder.IsNot(err, blunder.InvalidArgError) {
+		t.Fatalf("DeleteOrphanedObjects() lacking a dry run's ScrubReport should have failed with InvalidArgError: %v", err)
+	}
+
+	dryRunReport = &ScrubReport{Orphans: []ScrubOrphan{{ContainerName: "NotAPhysicalContainer", ObjectName: checkpointObjectName}}, Nonce: dryRunReport.Nonce}
+
+	_, err = testVolumeHandle.DeleteOrphanedObjects(dryRunReport, make(chan bool, 1))
+	if blunder.IsNot(err, blunder.InvalidArgError) {
+		t.Fatalf("DeleteOrphanedObjects() of a foreign container should have failed with InvalidArgError: %v", err)
+	}
+
+	_ = swiftclient.ObjectDeleteSync(vS.accountName, physicalContainerName, "NotANonce")
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
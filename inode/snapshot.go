@@ -0,0 +1,204 @@
+package inode
+
+// Snapshots
+//
+// A snapshot is a named, read-only view of a volume as of a headhunter checkpoint (see headhunter/snapshot.go).
+// Only state already persisted via headhunter is captured... inodes and log segments still being written
+// must be flushed by the caller before CreateSnapshot() if they are to be included.
+//
+// The VolumeHandle returned by FetchSnapshotVolumeHandle() is a volumeStruct sharing the fsid, accountName,
+// and flowControl of the volume it was taken of but backed by the snapshot's headhunter.VolumeHandle. It is
+// not entered into globals.volumeMap nor globals.accountMap. Log segments referenced by any snapshot are
+// retained by deleteLogSegmentAsync() and, should they no longer be referenced by the live volume, deleted
+// once the last snapshot referencing them is deleted.
+
+import (
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+func (vS *volumeStruct) snapshotReadOnlyError(fnName string) (err error) {
+	err = fmt.Errorf("%s: volumeName \"%v\" snapshot is read-only", fnName, vS.volumeName)
+	err = blunder.AddError(err, blunder.ReadOnlyError)
+	return
+}
+
+func (vS *volumeStruct) CreateSnapshot(snapshotName string) (snapshotID uint64, err error) {
+	if nil != vS.snapshotOf {
+		err = vS.snapshotReadOnlyError(utils.GetFnName())
+		return
+	}
+
+	snapshotID, err = vS.headhunterVolumeHandle.CreateSnapshot(snapshotName)
+	if nil != err {
+		return
+	}
+
+	stats.IncrementOperations(&stats.SnapshotCreateOps)
+
+	logger.Infof("Created snapshot \"%v\" (ID 0x%016X) of volume \"%v\"", snapshotName, snapshotID, vS.volumeName)
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) DeleteSnapshot(snapshotName string) (err error) {
+	var (
+		containerName           string
+		logSegmentNumber        uint64
+		logSegmentRec           []byte
+		ok                      bool
+		snapshotVolume          *volumeStruct
+		unreferencedLogSegments map[uint64][]byte
+	)
+
+	if nil != vS.snapshotOf {
+		err = vS.snapshotReadOnlyError(utils.GetFnName())
+		return
+	}
+
+	unreferencedLogSegments, err = vS.headhunterVolumeHandle.DeleteSnapshot(snapshotName)
+	if nil != err {
+		return
+	}
+
+	vS.Lock()
+	snapshotVolume, ok = vS.snapshotVolumeMap[snapshotName]
+	if ok {
+		delete(vS.snapshotVolumeMap, snapshotName)
+	}
+	vS.Unlock()
+
+	if ok {
+		snapshotVolume.Lock()
+		snapshotVolume.active = false
+		snapshotVolume.Unlock()
+	}
+
+	// Log segments may still be pinned by leases obtained via either the live volume or the snapshot
+
+	for logSegmentNumber, logSegmentRec = range unreferencedLogSegments {
+		containerName, _, err = headhunter.DecodeLogSegmentRec(logSegmentRec)
+		if nil != err {
+			// Err on the side of leaking the log segment object
+			logger.ErrorfWithError(err, "couldn't decode LogSegmentRec of log segment 0x%016X", logSegmentNumber)
+			continue
+		}
+		if vS.deferLogSegmentDeleteIfPinned(logSegmentNumber, containerName, nil) {
+			continue
+		}
+		if ok && snapshotVolume.deferLogSegmentDeleteIfPinned(logSegmentNumber, containerName, nil) {
+			continue
+		}
+		vS.objectStore.ObjectDeleteAsync(vS.accountName, containerName, fmt.Sprintf("%016X", logSegmentNumber), nil, nil)
+	}
+
+	stats.IncrementOperations(&stats.SnapshotDeleteOps)
+
+	logger.Infof("Deleted snapshot \"%v\" of volume \"%v\" (releasing %v log segment(s))", snapshotName, vS.volumeName, len(unreferencedLogSegments))
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) FetchSnapshotList() (snapshotList []SnapshotInfo) {
+	var (
+		headhunterSnapshotInfo headhunter.SnapshotInfo
+	)
+
+	snapshotList = make([]SnapshotInfo, 0)
+
+	for _, headhunterSnapshotInfo = range vS.headhunterVolumeHandle.FetchSnapshotList() {
+		snapshotList = append(snapshotList, SnapshotInfo{
+			ID:           headhunterSnapshotInfo.ID,
+			Name:         headhunterSnapshotInfo.Name,
+			CreationTime: headhunterSnapshotInfo.CreationTime,
+		})
+	}
+
+	return
+}
+
+func (vS *volumeStruct) FetchSnapshotVolumeHandle(snapshotName string) (volumeHandle VolumeHandle, err error) {
+	var (
+		headhunterVolumeHandle headhunter.VolumeHandle
+		ok                     bool
+		snapshotVolume         *volumeStruct
+	)
+
+	vS.Lock()
+	defer vS.Unlock()
+
+	if !vS.active {
+		err = fmt.Errorf("%s: volumeName \"%v\" not active", utils.GetFnName(), vS.volumeName)
+		err = blunder.AddError(err, blunder.NotActiveError)
+		return
+	}
+
+	snapshotVolume, ok = vS.snapshotVolumeMap[snapshotName]
+	if ok {
+		volumeHandle = snapshotVolume
+		err = nil
+		return
+	}
+
+	headhunterVolumeHandle, err = vS.headhunterVolumeHandle.FetchSnapshotVolumeHandle(snapshotName)
+	if nil != err {
+		return
+	}
+
+	snapshotVolume = &volumeStruct{
+		fsid:                           vS.fsid,
+		volumeName:                     vS.volumeName,
+		accountName:                    vS.accountName,
+		objectStore:                    vS.objectStore,
+		active:                         true,
+		activePeerPrivateIPAddr:        vS.activePeerPrivateIPAddr,
+		maxEntriesPerDirNode:           vS.maxEntriesPerDirNode,
+		maxExtentsPerFileNode:          vS.maxExtentsPerFileNode,
+		physicalContainerLayoutSet:     make(map[string]struct{}),
+		physicalContainerNamePrefixSet: make(map[string]struct{}),
+		physicalContainerLayoutMap:     make(map[string]*physicalContainerLayoutStruct),
+		flowControl:                    vS.flowControl,
+		headhunterVolumeHandle:         headhunterVolumeHandle,
+		inodeCache:                     make(map[InodeNumber]*inMemoryInodeStruct),
+		capacity:                       vS.capacity,
+		defragmenter:                   &defragmenterStruct{},
+		quota:                          &quotaStruct{},
+		pinnedLogSegmentMap:            make(map[uint64]*pinnedLogSegmentStruct),
+		snapshotOf:                     vS,
+	}
+
+	vS.snapshotVolumeMap[snapshotName] = snapshotVolume
+
+	volumeHandle = snapshotVolume
+
+	err = nil
+	return
+}
+
+// dropSnapshotVolumes is called when a volume is removed or becomes inactive during PauseAndContract().
+// The VolumeHandles previously returned by FetchSnapshotVolumeHandle() become inactive.
+func (vS *volumeStruct) dropSnapshotVolumes() {
+	var (
+		snapshotVolume    *volumeStruct
+		snapshotVolumeMap map[string]*volumeStruct
+	)
+
+	vS.Lock()
+	snapshotVolumeMap = vS.snapshotVolumeMap
+	vS.snapshotVolumeMap = make(map[string]*volumeStruct)
+	vS.Unlock()
+
+	for _, snapshotVolume = range snapshotVolumeMap {
+		snapshotVolume.dropVolumeLeases()
+		snapshotVolume.Lock()
+		snapshotVolume.active = false
+		snapshotVolume.Unlock()
+	}
+}
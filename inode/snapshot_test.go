@@ -0,0 +1,120 @@
+package inode
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+func TestSnapshots(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	vS := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("snapshot"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	offset := uint64(0)
+	length := uint64(8)
+
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &offset, &length)
+	if nil != err {
+		t.Fatalf("GetReadPlan() failed: %v", err)
+	}
+	if (1 != len(readPlan)) || (0 == readPlan[0].LogSegmentNumber) {
+		t.Fatalf("GetReadPlan() returned unexpected readPlan: %v", readPlan)
+	}
+
+	logSegmentNumber := readPlan[0].LogSegmentNumber
+	objectName := fmt.Sprintf("%016X", logSegmentNumber)
+
+	containerName, err := vS.getLogSegmentContainer(logSegmentNumber)
+	if nil != err {
+		t.Fatalf("getLogSegmentContainer() failed: %v", err)
+	}
+
+	snapshotID, err := testVolumeHandle.CreateSnapshot("TestInodeSnapshot")
+	if nil != err {
+		t.Fatalf("CreateSnapshot() failed: %v", err)
+	}
+
+	snapshotList := testVolumeHandle.FetchSnapshotList()
+	if (1 != len(snapshotList)) || (snapshotID != snapshotList[0].ID) || ("TestInodeSnapshot" != snapshotList[0].Name) {
+		t.Fatalf("FetchSnapshotList() returned unexpected snapshotList: %+v", snapshotList)
+	}
+
+	// Destroying the file should not delete the log segment the snapshot still references
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	_, err = swiftclient.ObjectContentLength(vS.accountName, containerName, objectName)
+	if nil != err {
+		t.Fatalf("Log segment object referenced by snapshot should not have been deleted: %v", err)
+	}
+
+	snapshotVolumeHandle, err := testVolumeHandle.FetchSnapshotVolumeHandle("TestInodeSnapshot")
+	if nil != err {
+		t.Fatalf("FetchSnapshotVolumeHandle() failed: %v", err)
+	}
+
+	buf, err := snapshotVolumeHandle.Read(fileInodeNumber, 0, 8, nil)
+	if nil != err {
+		t.Fatalf("Read() via snapshot failed: %v", err)
+	}
+	if 0 != bytes.Compare([]byte("snapshot"), buf) {
+		t.Fatalf("Read() via snapshot returned unexpected buf: %v", buf)
+	}
+
+	_, err = testVolumeHandle.GetType(fileInodeNumber)
+	if nil == err {
+		t.Fatalf("GetType() of destroyed file should have failed")
+	}
+
+	_, err = snapshotVolumeHandle.CreateSnapshot("TestInodeSnapshotOfSnapshot")
+	if !blunder.Is(err, blunder.ReadOnlyError) {
+		t.Fatalf("CreateSnapshot() via snapshot should have failed with ReadOnlyError: %v", err)
+	}
+
+	// Deleting the snapshot should finally delete the log segment
+
+	err = testVolumeHandle.DeleteSnapshot("TestInodeSnapshot")
+	if nil != err {
+		t.Fatalf("DeleteSnapshot() failed: %v", err)
+	}
+	err = testVolumeHandle.DeleteSnapshot("TestInodeSnapshot")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("DeleteSnapshot() of already deleted snapshot should have failed with NotFoundError: %v", err)
+	}
+
+	if 0 != len(testVolumeHandle.FetchSnapshotList()) {
+		t.Fatalf("FetchSnapshotList() should have returned an empty snapshotList")
+	}
+
+	if !waitForLogSegmentObjectDeletion(vS.accountName, containerName, objectName) {
+		t.Fatalf("Log segment object should have been deleted once the snapshot referencing it was deleted")
+	}
+}
@@ -1,6 +1,155 @@
 package inode
 
+import (
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
 func (vS *volumeStruct) GetFSID() (fsid uint64) {
 	fsid = vS.fsid
 	return
 }
+
+func (vS *volumeStruct) adoptCapacityParameter(confMap conf.ConfMap) (err error) {
+	vS.capacity, err = confMap.FetchOptionValueUint64(utils.VolumeNameConfSection(vS.volumeName), "Capacity")
+	if nil != err {
+		vS.capacity = 0 // Capacity is optional
+	}
+
+	err = nil
+	return
+}
+
+// adoptReadOnlyParameter applies [Volume:<VolumeName>]ReadOnly (as does package fs). While read-only,
+// work package inode would initiate on its own (e.g. defragmenting or scrubbing) is refused.
+func (vS *volumeStruct) adoptReadOnlyParameter(confMap conf.ConfMap) {
+	readOnly, err := confMap.FetchOptionValueBool(utils.VolumeNameConfSection(vS.volumeName), "ReadOnly")
+	if nil != err {
+		readOnly = false // TODO: eventually, just return
+	}
+
+	vS.Lock()
+	vS.readOnly = readOnly
+	vS.Unlock()
+}
+
+func (vS *volumeStruct) readOnlyError(fnName string) (err error) {
+	err = fmt.Errorf("%s: volumeName \"%v\" is read-only", fnName, vS.volumeName)
+	err = blunder.AddError(err, blunder.ReadOnlyError)
+	return
+}
+
+func (vS *volumeStruct) FetchVolumeStats() (volumeStats VolumeStats) {
+	headhunterVolumeStats := vS.headhunterVolumeHandle.FetchVolumeStats()
+
+	volumeStats = VolumeStats{
+		Capacity:        vS.capacity,
+		InodeCount:      headhunterVolumeStats.InodeCount,
+		LogSegmentBytes: headhunterVolumeStats.LogSegmentBytes,
+		ReferencedBytes: headhunterVolumeStats.ReferencedBytes,
+	}
+
+	return
+}
+
+// rebuildStatsIfNeeded recomputes the headhunter totals for a volume whose checkpoint predated them.
+// Only inode can sum up the bytes referenced by each inodeRec's LogSegmentMap, so this is performed
+// (prior to serving the volume) by walking every inodeRec... after which a checkpoint records them.
+func (vS *volumeStruct) rebuildStatsIfNeeded() (err error) {
+	var (
+		groupStats               map[uint32]headhunter.OwnerStats
+		inodeNumber              InodeNumber
+		inodeReferencedBytes     uint64
+		inodeReport              *InodeReport
+		inspectionVolume         *inspectionVolumeStruct
+		logSegmentBytes          uint64
+		ok                       bool
+		ownerStats               headhunter.OwnerStats
+		ownerStatsRebuildNeeded  bool
+		referencedBytes          uint64
+		userStats                map[uint32]headhunter.OwnerStats
+		volumeStatsRebuildNeeded bool
+	)
+
+	volumeStatsRebuildNeeded = vS.headhunterVolumeHandle.VolumeStatsRebuildNeeded()
+	ownerStatsRebuildNeeded = vS.headhunterVolumeHandle.OwnerStatsRebuildNeeded()
+
+	if !volumeStatsRebuildNeeded && !ownerStatsRebuildNeeded {
+		err = nil
+		return
+	}
+
+	logger.Infof("Volume %v rebuilding VolumeStats (%v) & OwnerStats (%v)...", vS.volumeName, volumeStatsRebuildNeeded, ownerStatsRebuildNeeded)
+
+	userStats = make(map[uint32]headhunter.OwnerStats)
+	groupStats = make(map[uint32]headhunter.OwnerStats)
+
+	inspectionVolume = &inspectionVolumeStruct{volume: vS}
+
+	inodeNumber = InodeNumber(0)
+
+	for {
+		inodeNumber, ok, err = inspectionVolume.NextInodeNumber(inodeNumber)
+		if nil != err {
+			return
+		}
+		if !ok {
+			break
+		}
+
+		inodeReport, err = inspectionVolume.decodeOnDiskInode(inodeNumber)
+		if nil != err {
+			if blunder.IsNot(err, blunder.CorruptInodeError) {
+				return
+			}
+			// A corrupt inode's LogSegmentMap (and owner) is unknowable... so it contributes nothing
+			logger.WarnfWithError(err, "Volume %v skipping inode %v while rebuilding stats", vS.volumeName, inodeNumber)
+			continue
+		}
+
+		inodeReferencedBytes = 0
+
+		if FileType == inodeReport.InodeType {
+			for _, logSegmentBytes = range inodeReport.LogSegmentMap {
+				inodeReferencedBytes += logSegmentBytes
+			}
+		}
+
+		referencedBytes += inodeReferencedBytes
+
+		// Charge the owners just as reportOwnerStats() would upon this inode's first flush
+
+		ownerStats = userStats[uint32(inodeReport.UserID)]
+		ownerStats.Bytes += inodeReferencedBytes
+		ownerStats.Inodes++
+		userStats[uint32(inodeReport.UserID)] = ownerStats
+
+		ownerStats = groupStats[uint32(inodeReport.GroupID)]
+		ownerStats.Bytes += inodeReferencedBytes
+		ownerStats.Inodes++
+		groupStats[uint32(inodeReport.GroupID)] = ownerStats
+	}
+
+	if volumeStatsRebuildNeeded {
+		err = vS.headhunterVolumeHandle.RebuildVolumeStats(referencedBytes)
+		if nil != err {
+			return
+		}
+	}
+
+	if ownerStatsRebuildNeeded {
+		err = vS.headhunterVolumeHandle.RebuildOwnerStats(userStats, groupStats)
+		if nil != err {
+			return
+		}
+	}
+
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+
+	return
+}
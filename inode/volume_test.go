@@ -0,0 +1,456 @@
+package inode
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/cluster"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+func TestVolumeStats(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	volumeStatsBefore := testVolumeHandle.FetchVolumeStats()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	volumeStatsAfterWrite := testVolumeHandle.FetchVolumeStats()
+
+	if volumeStatsBefore.InodeCount+1 != volumeStatsAfterWrite.InodeCount {
+		t.Fatalf("InodeCount should have gone from %v to %v but was %v", volumeStatsBefore.InodeCount, volumeStatsBefore.InodeCount+1, volumeStatsAfterWrite.InodeCount)
+	}
+	if volumeStatsBefore.ReferencedBytes+10 != volumeStatsAfterWrite.ReferencedBytes {
+		t.Fatalf("ReferencedBytes should have gone from %v to %v but was %v", volumeStatsBefore.ReferencedBytes, volumeStatsBefore.ReferencedBytes+10, volumeStatsAfterWrite.ReferencedBytes)
+	}
+	if volumeStatsBefore.LogSegmentBytes+10 > volumeStatsAfterWrite.LogSegmentBytes {
+		t.Fatalf("LogSegmentBytes should have grown by at least 10 from %v but was %v", volumeStatsBefore.LogSegmentBytes, volumeStatsAfterWrite.LogSegmentBytes)
+	}
+
+	// Each closed log segment records its length for use when it is deleted
+
+	fileInode, err := testVolumeHandle.(*volumeStruct).fetchInodeType(fileInodeNumber, FileType)
+	if nil != err {
+		t.Fatalf("fetchInodeType() failed: %v", err)
+	}
+	for logSegmentNumber := range fileInode.LogSegmentMap {
+		_, objectLength, err := testVolumeHandle.(*volumeStruct).getLogSegmentRec(logSegmentNumber)
+		if nil != err {
+			t.Fatalf("getLogSegmentRec() failed: %v", err)
+		}
+		if 10 != objectLength {
+			t.Fatalf("getLogSegmentRec() should have recorded a length of 10 but was %v", objectLength)
+		}
+	}
+
+	// Overwriting part of the file leaves ReferencedBytes unchanged
+
+	err = testVolumeHandle.Write(fileInodeNumber, 5, []byte("ABCDE"), nil)
+	if nil != err {
+		t.Fatalf("Write() #2 failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() #2 failed: %v", err)
+	}
+
+	volumeStatsAfterOverwrite := testVolumeHandle.FetchVolumeStats()
+
+	if volumeStatsAfterWrite.ReferencedBytes != volumeStatsAfterOverwrite.ReferencedBytes {
+		t.Fatalf("ReferencedBytes should have remained %v but was %v", volumeStatsAfterWrite.ReferencedBytes, volumeStatsAfterOverwrite.ReferencedBytes)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	volumeStatsAfterDestroy := testVolumeHandle.FetchVolumeStats()
+
+	if volumeStatsBefore.InodeCount != volumeStatsAfterDestroy.InodeCount {
+		t.Fatalf("InodeCount should have returned to %v but was %v", volumeStatsBefore.InodeCount, volumeStatsAfterDestroy.InodeCount)
+	}
+	if volumeStatsBefore.ReferencedBytes != volumeStatsAfterDestroy.ReferencedBytes {
+		t.Fatalf("ReferencedBytes should have returned to %v but was %v", volumeStatsBefore.ReferencedBytes, volumeStatsAfterDestroy.ReferencedBytes)
+	}
+	if volumeStatsBefore.LogSegmentBytes != volumeStatsAfterDestroy.LogSegmentBytes {
+		t.Fatalf("LogSegmentBytes should have returned to %v but was %v", volumeStatsBefore.LogSegmentBytes, volumeStatsAfterDestroy.LogSegmentBytes)
+	}
+}
+
+// testRestartWithDowngradedCheckpoint brings headhunter & inode down, rewrites volumeName's checkpoint as
+// if recorded by checkpointHeaderVersion (2 or 3), then brings headhunter & inode back up. Only the header
+// and the tail of the checkpoint record (holding the OwnerStats table) need be rewritten to accomplish this.
+func testRestartWithDowngradedCheckpoint(t *testing.T, accountName string, checkpointContainerName string, checkpointHeaderVersion uint64) {
+	const (
+		elementOfOwnerStatsSize = 4 * 8 // OwnerType, OwnerID, Bytes, & Inodes
+	)
+
+	err := Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+	err = headhunter.Down()
+	if nil != err {
+		t.Fatalf("headhunter.Down() failed: %v", err)
+	}
+
+	headers, err := swiftclient.ContainerHead(accountName, checkpointContainerName)
+	if nil != err {
+		t.Fatalf("swiftclient.ContainerHead() failed: %v", err)
+	}
+	headerValues := strings.Split(headers[headhunter.CheckpointHeaderName][0], " ")
+	if 10 != len(headerValues) {
+		t.Fatalf("checkpoint header should have had 10 values but had %v", len(headerValues))
+	}
+	headerValuesAsUint64 := make([]uint64, len(headerValues))
+	for i, headerValue := range headerValues {
+		headerValuesAsUint64[i], err = strconv.ParseUint(headerValue, 16, 64)
+		if nil != err {
+			t.Fatalf("checkpoint header value %v malformed: %v", i, err)
+		}
+	}
+	if 0 != headerValuesAsUint64[8] {
+		t.Fatalf("checkpoint cannot be downgraded while snapshots exist")
+	}
+
+	objectName := utils.Uint64ToHexStr(headerValuesAsUint64[1])
+	ownerStatsSize := headerValuesAsUint64[7] * elementOfOwnerStatsSize
+
+	objectLength, err := swiftclient.ObjectContentLength(accountName, checkpointContainerName, objectName)
+	if nil != err {
+		t.Fatalf("swiftclient.ObjectContentLength() failed: %v", err)
+	}
+	objectBuf, err := swiftclient.ObjectGet(accountName, checkpointContainerName, objectName, 0, objectLength)
+	if nil != err {
+		t.Fatalf("swiftclient.ObjectGet() failed: %v", err)
+	}
+	chunkedPutContext, err := swiftclient.ObjectFetchChunkedPutContext(accountName, checkpointContainerName, objectName)
+	if nil != err {
+		t.Fatalf("swiftclient.ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk(objectBuf[:objectLength-ownerStatsSize])
+	if nil != err {
+		t.Fatalf("chunkedPutContext.SendChunk() failed: %v", err)
+	}
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("chunkedPutContext.Close() failed: %v", err)
+	}
+
+	headerValuesAsUint64[0] = checkpointHeaderVersion
+	headerValuesAsUint64[2] -= ownerStatsSize
+	if 2 == checkpointHeaderVersion {
+		headerValuesAsUint64 = headerValuesAsUint64[:4]
+	} else {
+		headerValuesAsUint64 = headerValuesAsUint64[:7]
+	}
+	headerValues = make([]string, len(headerValuesAsUint64))
+	for i, headerValueAsUint64 := range headerValuesAsUint64 {
+		headerValues[i] = utils.Uint64ToHexStr(headerValueAsUint64)
+	}
+	headers = map[string][]string{headhunter.CheckpointHeaderName: []string{strings.Join(headerValues, " ")}}
+	err = swiftclient.ContainerPost(accountName, checkpointContainerName, headers)
+	if nil != err {
+		t.Fatalf("swiftclient.ContainerPost() failed: %v", err)
+	}
+
+	err = headhunter.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("headhunter.Up() failed: %v", err)
+	}
+	err = Up(testConfMap)
+	if nil != err {
+		t.Fatalf("Up() failed: %v", err)
+	}
+}
+
+// testCheckpointHeaderVersion returns the version of the checkpoint currently recorded for the volume.
+func testCheckpointHeaderVersion(t *testing.T, accountName string, checkpointContainerName string) (checkpointHeaderVersion uint64) {
+	headers, err := swiftclient.ContainerHead(accountName, checkpointContainerName)
+	if nil != err {
+		t.Fatalf("swiftclient.ContainerHead() failed: %v", err)
+	}
+	checkpointHeaderVersion, err = strconv.ParseUint(strings.Split(headers[headhunter.CheckpointHeaderName][0], " ")[0], 16, 64)
+	if nil != err {
+		t.Fatalf("checkpoint header version malformed: %v", err)
+	}
+	return
+}
+
+func TestVolumeStatsRebuild(t *testing.T) {
+	// Other tests leave corrupt inodes behind whose bytes cannot be rebuilt... so begin with a rebuild
+
+	testRestartWithDowngradedCheckpoint(t, "AUTH_test", ".__checkpoint__", 2)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	volumeStatsBefore := testVolumeHandle.FetchVolumeStats()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	// Make the file's log segments look as if they predate LogSegmentRecs recording their length
+
+	fileInode, err := testVolumeHandle.(*volumeStruct).fetchInodeType(fileInodeNumber, FileType)
+	if nil != err {
+		t.Fatalf("fetchInodeType() failed: %v", err)
+	}
+	logSegmentNumbers := make([]uint64, 0, len(fileInode.LogSegmentMap))
+	for logSegmentNumber := range fileInode.LogSegmentMap {
+		containerName, _, err := testVolumeHandle.(*volumeStruct).getLogSegmentRec(logSegmentNumber)
+		if nil != err {
+			t.Fatalf("getLogSegmentRec() failed: %v", err)
+		}
+		err = testVolumeHandle.(*volumeStruct).headhunterVolumeHandle.PutLogSegmentRec(logSegmentNumber, headhunter.EncodeLogSegmentRec(containerName, 0))
+		if nil != err {
+			t.Fatalf("PutLogSegmentRec() failed: %v", err)
+		}
+		logSegmentNumbers = append(logSegmentNumbers, logSegmentNumber)
+	}
+
+	testRestartWithDowngradedCheckpoint(t, "AUTH_test", ".__checkpoint__", 2)
+
+	testVolumeHandle, err = FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	volumeStatsAfter := testVolumeHandle.FetchVolumeStats()
+
+	if volumeStatsBefore.InodeCount+1 != volumeStatsAfter.InodeCount {
+		t.Fatalf("InodeCount should have been rebuilt as %v but was %v", volumeStatsBefore.InodeCount+1, volumeStatsAfter.InodeCount)
+	}
+	if volumeStatsBefore.LogSegmentBytes+10 != volumeStatsAfter.LogSegmentBytes {
+		t.Fatalf("LogSegmentBytes should have been rebuilt as %v but was %v", volumeStatsBefore.LogSegmentBytes+10, volumeStatsAfter.LogSegmentBytes)
+	}
+	if volumeStatsBefore.ReferencedBytes+10 != volumeStatsAfter.ReferencedBytes {
+		t.Fatalf("ReferencedBytes should have been rebuilt as %v but was %v", volumeStatsBefore.ReferencedBytes+10, volumeStatsAfter.ReferencedBytes)
+	}
+
+	// The lengths of the file's log segments should have been recorded once more
+
+	for _, logSegmentNumber := range logSegmentNumbers {
+		_, objectLength, err := testVolumeHandle.(*volumeStruct).getLogSegmentRec(logSegmentNumber)
+		if nil != err {
+			t.Fatalf("getLogSegmentRec() failed: %v", err)
+		}
+		if 10 != objectLength {
+			t.Fatalf("getLogSegmentRec() should have recorded a length of 10 but was %v", objectLength)
+		}
+	}
+
+	// The rebuilt totals should have been persisted in a current checkpoint
+
+	if 5 != testCheckpointHeaderVersion(t, "AUTH_test", ".__checkpoint__") {
+		t.Fatalf("checkpoint should have been rewritten as version 5")
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	volumeStatsAfterDestroy := testVolumeHandle.FetchVolumeStats()
+
+	if volumeStatsBefore != volumeStatsAfterDestroy {
+		t.Fatalf("VolumeStats should have returned to %+v but were %+v", volumeStatsBefore, volumeStatsAfterDestroy)
+	}
+}
+
+func TestFencedVolumeRetainsLogSegments(t *testing.T) {
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	vS := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() failed: %v", err)
+	}
+
+	fileInode, err := vS.fetchInodeType(fileInodeNumber, FileType)
+	if nil != err {
+		t.Fatalf("fetchInodeType() failed: %v", err)
+	}
+	logSegmentContainerNames := make(map[uint64]string)
+	for logSegmentNumber := range fileInode.LogSegmentMap {
+		logSegmentContainerNames[logSegmentNumber], _, err = vS.getLogSegmentRec(logSegmentNumber)
+		if nil != err {
+			t.Fatalf("getLogSegmentRec() failed: %v", err)
+		}
+	}
+	if 0 == len(logSegmentContainerNames) {
+		t.Fatalf("file should have had at least one log segment")
+	}
+
+	// Bring up the cluster package (claiming the fencing token) and then have Peer1 claim it
+
+	clusterConfMap, err := conf.MakeConfMapFromStrings([]string{
+		"Peer:Peer0.PrivateIPAddr=127.0.0.1",
+		"Peer:Peer1.PrivateIPAddr=127.0.0.2",
+		"Cluster.WhoAmI=Peer0",
+		"Cluster.Peers=Peer0 Peer1",
+		"Cluster.ServerGuid=0bb51164-258f-4e04-a417-e16d736ca41c",
+		"Cluster.PrivateClusterUDPPort=5004",
+		"Cluster.HeartBeatInterval=50ms",
+		"Cluster.HeartBeatVariance=5ms",
+		"Cluster.HeartBeatExpiration=1m", // Peer1 must not be taken for dead
+		"Cluster.MessageExpiration=500ms",
+		"Cluster.FenceCheckInterval=10ms",
+		"Cluster.UDPReadSize=8000",
+		"Cluster.UDPWriteSize=7000",
+		"Volume:TestVolume.PrimaryPeer=Peer0",
+		"Volume:TestVolume.AccountName=" + vS.accountName,
+		"Volume:TestVolume.CheckpointContainerName=" + vS.headhunterVolumeHandle.FetchCheckpointContainerName(),
+		"FSGlobals.VolumeList=TestVolume",
+	})
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+	err = cluster.Up(clusterConfMap)
+	if nil != err {
+		t.Fatalf("cluster.Up() failed: %v", err)
+	}
+	if cluster.VolumeIsFenced("TestVolume") {
+		t.Fatalf("cluster.VolumeIsFenced(\"TestVolume\") should have returned false before Peer1 claimed it")
+	}
+
+	chunkedPutContext, err := swiftclient.ObjectFetchChunkedPutContext(vS.accountName, vS.headhunterVolumeHandle.FetchCheckpointContainerName(), cluster.FencingTokenObjectName)
+	if nil != err {
+		t.Fatalf("swiftclient.ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk([]byte("{\"PeerName\":\"Peer1\",\"Epoch\":2}"))
+	if nil != err {
+		t.Fatalf("chunkedPutContext.SendChunk() failed: %v", err)
+	}
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("chunkedPutContext.Close() failed: %v", err)
+	}
+
+	for !cluster.VolumeIsFenced("TestVolume") {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Destroying the file frees its log segments... but the checkpoint that would release their deletion is refused
+
+	fencedObjectDeletesBefore := stats.Dump()[stats.ClusterFencedObjectDeleteOps]
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	err = vS.headhunterVolumeHandle.DoCheckpoint()
+	if !blunder.Is(err, blunder.NotActiveError) {
+		t.Fatalf("DoCheckpoint() of fenced volume should have failed with NotActiveError but returned: %v", err)
+	}
+	if !vS.headhunterVolumeHandle.Fenced() {
+		t.Fatalf("Fenced() should have returned true")
+	}
+
+	for stats.Dump()[stats.ClusterFencedObjectDeleteOps] < fencedObjectDeletesBefore+uint64(len(logSegmentContainerNames)) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for logSegmentNumber, containerName := range logSegmentContainerNames {
+		_, err = swiftclient.ObjectContentLength(vS.accountName, containerName, utils.Uint64ToHexStr(logSegmentNumber))
+		if nil != err {
+			t.Fatalf("log segment %v/%016X of fenced volume should not have been deleted: %v", containerName, logSegmentNumber, err)
+		}
+	}
+
+	// Once taken back (here, via restart), the volume resumes from its last permitted checkpoint
+
+	err = cluster.Down()
+	if nil != err {
+		t.Fatalf("cluster.Down() failed: %v", err)
+	}
+	err = swiftclient.ObjectDeleteSync(vS.accountName, vS.headhunterVolumeHandle.FetchCheckpointContainerName(), cluster.FencingTokenObjectName)
+	if nil != err {
+		t.Fatalf("swiftclient.ObjectDeleteSync() failed: %v", err)
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+	err = headhunter.Down()
+	if nil != err {
+		t.Fatalf("headhunter.Down() of fenced volume failed: %v", err)
+	}
+	err = headhunter.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("headhunter.Up() failed: %v", err)
+	}
+	err = Up(testConfMap)
+	if nil != err {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	testVolumeHandle, err = FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	buf, err := testVolumeHandle.Read(fileInodeNumber, 0, 10, nil)
+	if nil != err {
+		t.Fatalf("Read() of file whose Destroy() was never checkpointed failed: %v", err)
+	}
+	if "0123456789" != string(buf) {
+		t.Fatalf("Read() of file whose Destroy() was never checkpointed returned \"%v\"", string(buf))
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() following restart failed: %v", err)
+	}
+}
@@ -189,6 +189,27 @@ type FlushRequest struct {
 	SendTimeNsec int64
 }
 
+// GetQuotaRequest is the request object for RpcGetQuota.
+type GetQuotaRequest struct {
+	MountID uint64
+	UserID  int32
+	GroupID int32
+}
+
+// QuotaStruct conveys the usage and (hard) limits of a single user or group. A limit of 0 means unlimited.
+type QuotaStruct struct {
+	BytesUsed  uint64
+	HardBytes  uint64
+	InodesUsed uint64
+	HardInodes uint64
+}
+
+// GetQuotaReply is the reply object for RpcGetQuota.
+type GetQuotaReply struct {
+	User  QuotaStruct
+	Group QuotaStruct
+}
+
 // GetStatRequest is the request object for RpcGetStat.
 type GetStatRequest struct {
 	InodeHandle
@@ -304,11 +325,19 @@ type MkdirPathRequest struct {
 }
 
 // MountRequest is the request object for RpcMount.
+//
+// MountOptions is a bit mask of fs.MountOptions (e.g. fs.MountReadOnly == 1). Mutating
+// requests against a read-only mount (or read-only volume) fail with EROFS.
+//
+// If UnmountOnClose is set, the returned MountID is unmounted (as if by RpcUnmount) once the
+// JSON-RPC connection the RpcMount arrived on is closed (e.g. because the client process died).
 type MountRequest struct {
-	VolumeName   string
-	MountOptions uint64
-	AuthUserID   uint64
-	AuthGroupID  uint64
+	VolumeName     string
+	MountOptions   uint64
+	AuthUserID     uint64
+	AuthGroupID    uint64
+	UnmountOnClose bool
+	conn           *jrpcConnStruct // set by jrpcConnStruct.ReadRequestBody()
 }
 
 // MountReply is the reply object for RpcMount.
@@ -470,17 +499,19 @@ type StatVFSRequest struct {
 
 // StatVFS is used when filesystem stats need to be conveyed. It is used by RpcStatVFS.
 type StatVFS struct {
-	BlockSize      uint64
-	FragmentSize   uint64
-	TotalBlocks    uint64
-	FreeBlocks     uint64
-	AvailBlocks    uint64
-	TotalInodes    uint64
-	FreeInodes     uint64
-	AvailInodes    uint64
-	FileSystemID   uint64
-	MountFlags     uint64
-	MaxFilenameLen uint64
+	BlockSize       uint64
+	FragmentSize    uint64
+	TotalBlocks     uint64
+	FreeBlocks      uint64
+	AvailBlocks     uint64
+	TotalInodes     uint64
+	FreeInodes      uint64
+	AvailInodes     uint64
+	FileSystemID    uint64
+	MountFlags      uint64
+	MaxFilenameLen  uint64
+	LogSegmentBytes uint64
+	ReferencedBytes uint64
 }
 
 // StatStruct is used when stats need to be conveyed. It is used as the response to RpcGetStat and RpcGetStatPath,
@@ -542,6 +573,14 @@ type UnlinkPathRequest struct {
 	PathHandle
 }
 
+// UnmountRequest is the request object for RpcUnmount.
+//
+// RpcUnmount flushes in-flight file data, releases all byte-range locks set via the MountID,
+// and invalidates the MountID.
+type UnmountRequest struct {
+	MountID uint64
+}
+
 // WriteRequest is the request object for RpcWrite.
 type WriteRequest struct {
 	InodeHandle
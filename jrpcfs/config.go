@@ -3,6 +3,7 @@ package jrpcfs
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/swiftstack/ProxyFS/conf"
 	"github.com/swiftstack/ProxyFS/fs"
@@ -17,19 +18,28 @@ type globalsStruct struct {
 	//                   API Requests RLock()/RUnlock
 	//                   SIGHUP confMap changes Lock()/Unlock()
 
-	whoAmI          string
-	ipAddr          string
-	portString      string
-	fastPortString  string
-	dataPathLogging bool
+	whoAmI           string
+	ipAddr           string
+	portString       string
+	fastPortString   string
+	dataPathLogging  bool
+	fastMaxInFlight  int    // per fast port connection limit on tagged requests being performed concurrently
+	fastMaxWriteSize uint64 // limit on the write data accompanying a fast port write request
+	fastInFlight     uint64 // tagged requests currently being performed across all fast port connections (accessed atomically)
 
 	// Map used to enumerate volumes served by this peer
 	volumeMap map[string]bool // key == volumeName; value is ignored
 
 	// Map used to store volumes already mounted for bimodal support
-	// TODO: These never get purged !!!
-	mountIDMap  map[uint64]fs.MountHandle
-	lastMountID uint64
+	// Entries are purged by RpcUnmount, closure of a connection whose RpcMount requested
+	// UnmountOnClose, or (if JSONRPCServer.MountIdleTimeout is non-zero) once idle
+	mountIDMap       map[uint64]fs.MountHandle
+	mountLastUsedMap map[uint64]time.Time // key == MountID; value is time of last lookupMountHandle()
+	lastMountID      uint64
+
+	mountIdleTimeout    time.Duration // if zero, idle mounts are not reaped
+	mountReaperStopChan chan bool
+	mountReaperDoneChan chan bool
 
 	// Map used to store volumes already mounted for bimodal support
 	bimodalMountMap map[string]fs.MountHandle
@@ -37,16 +47,24 @@ type globalsStruct struct {
 
 var globals globalsStruct
 
+const (
+	defaultFastMaxInFlight  = uint32(16)
+	defaultFastMaxWriteSize = uint64(64 * 1024 * 1024)
+)
+
 // NOTE: Don't use logger.Fatal* to error out from this function; it prevents us
 //       from handling returned errors and gracefully unwinding.
 func Up(confMap conf.ConfMap) (err error) {
 	var (
-		primaryPeerList []string
-		volumeList      []string
-		volumeName      string
+		fastMaxInFlight  uint32
+		fastMaxWriteSize uint64
+		primaryPeerList  []string
+		volumeList       []string
+		volumeName       string
 	)
 
 	globals.mountIDMap = make(map[uint64]fs.MountHandle)
+	globals.mountLastUsedMap = make(map[uint64]time.Time)
 	globals.lastMountID = uint64(0) // The only invalid MountID
 
 	globals.bimodalMountMap = make(map[string]fs.MountHandle)
@@ -87,6 +105,31 @@ func Up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
+	globals.mountIdleTimeout, err = confMap.FetchOptionValueDuration("JSONRPCServer", "MountIdleTimeout")
+	if nil != err {
+		globals.mountIdleTimeout = time.Duration(0) // TODO: Eventually, just return
+	}
+
+	fastMaxInFlight, err = confMap.FetchOptionValueUint32("JSONRPCServer", "FastMaxInFlight")
+	if nil != err {
+		fastMaxInFlight = defaultFastMaxInFlight // TODO: Eventually, just return
+	}
+	if 0 == fastMaxInFlight {
+		err = fmt.Errorf("JSONRPCServer.FastMaxInFlight must be non-zero")
+		return
+	}
+	globals.fastMaxInFlight = int(fastMaxInFlight)
+
+	fastMaxWriteSize, err = confMap.FetchOptionValueUint64("JSONRPCServer", "FastMaxWriteSize")
+	if nil != err {
+		fastMaxWriteSize = defaultFastMaxWriteSize // TODO: Eventually, just return
+	}
+	if 0 == fastMaxWriteSize {
+		err = fmt.Errorf("JSONRPCServer.FastMaxWriteSize must be non-zero")
+		return
+	}
+	globals.fastMaxWriteSize = fastMaxWriteSize
+
 	// Compute volumeMap
 	volumeList, err = confMap.FetchOptionValueStringSlice("FSGlobals", "VolumeList")
 	if nil != err {
@@ -121,14 +164,24 @@ func Up(confMap conf.ConfMap) (err error) {
 	// Now kick off our other, faster RPC server
 	ioServerUp(globals.ipAddr, globals.fastPortString)
 
+	if time.Duration(0) != globals.mountIdleTimeout {
+		globals.mountReaperStopChan = make(chan bool, 1)
+		globals.mountReaperDoneChan = make(chan bool, 1)
+		go mountReaper()
+	}
+
+	err = nil
 	return
 }
 
 func PauseAndContract(confMap conf.ConfMap) (err error) {
 	var (
 		dataPathLogging    bool
+		fastMaxInFlight    uint32
+		fastMaxWriteSize   uint64
 		fastPortString     string
 		ipAddr             string
+		mountIdleTimeout   time.Duration
 		mountHandle        fs.MountHandle
 		mountID            uint64
 		ok                 bool
@@ -192,6 +245,33 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 		return
 	}
 
+	mountIdleTimeout, err = confMap.FetchOptionValueDuration("JSONRPCServer", "MountIdleTimeout")
+	if nil != err {
+		mountIdleTimeout = time.Duration(0) // TODO: Eventually, just return
+	}
+	if mountIdleTimeout != globals.mountIdleTimeout {
+		err = fmt.Errorf("confMap change not allowed to alter [JSONRPCServer]MountIdleTimeout")
+		return
+	}
+
+	fastMaxInFlight, err = confMap.FetchOptionValueUint32("JSONRPCServer", "FastMaxInFlight")
+	if nil != err {
+		fastMaxInFlight = defaultFastMaxInFlight // TODO: Eventually, just return
+	}
+	if int(fastMaxInFlight) != globals.fastMaxInFlight {
+		err = fmt.Errorf("confMap change not allowed to alter [JSONRPCServer]FastMaxInFlight")
+		return
+	}
+
+	fastMaxWriteSize, err = confMap.FetchOptionValueUint64("JSONRPCServer", "FastMaxWriteSize")
+	if nil != err {
+		fastMaxWriteSize = defaultFastMaxWriteSize // TODO: Eventually, just return
+	}
+	if fastMaxWriteSize != globals.fastMaxWriteSize {
+		err = fmt.Errorf("confMap change not allowed to alter [JSONRPCServer]FastMaxWriteSize")
+		return
+	}
+
 	globals.gate.Lock()
 
 	volumeList, err = confMap.FetchOptionValueStringSlice("FSGlobals", "VolumeList")
@@ -244,8 +324,13 @@ func PauseAndContract(confMap conf.ConfMap) (err error) {
 		}
 	}
 
+	// Unmount (rather than merely forget) each removed MountID so that its locks & F_SETLKW's are released
+
 	for _, mountID = range removedMountIDList {
-		delete(globals.mountIDMap, mountID)
+		unmountErr := unmountMountID(mountID)
+		if nil != unmountErr {
+			logger.ErrorfWithError(unmountErr, "failed to unmount MountID %v of removed volume", mountID)
+		}
 	}
 
 	err = nil
@@ -297,6 +382,10 @@ func ExpandAndResume(confMap conf.ConfMap) (err error) {
 
 func Down() (err error) {
 	err = nil
+	if time.Duration(0) != globals.mountIdleTimeout {
+		globals.mountReaperStopChan <- true
+		_ = <-globals.mountReaperDoneChan
+	}
 	jsonRpcServerDown()
 	ioServerDown()
 	return
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/swiftstack/ProxyFS/blunder"
@@ -56,7 +57,12 @@ func jrpcServerLoop() {
 			return
 		}
 
-		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+		jrpcConn := &jrpcConnStruct{
+			ServerCodec: jsonrpc.NewServerCodec(conn),
+			mountIDs:    make(map[uint64]bool),
+		}
+
+		go srv.ServeCodec(jrpcConn)
 	}
 }
 
@@ -86,6 +92,7 @@ const (
 	ReaddirByLocOp
 	ReaddirPlusOp
 	ReaddirPlusByLocOp
+	ResizeOp
 	InvalidOp // Keep this as the last entry!
 )
 
@@ -104,6 +111,7 @@ var opTypeStrs = []string{
 	"ReaddirByLoc",
 	"ReaddirPlus",
 	"ReaddirPlusByLoc",
+	"Resize",
 	"InvalidOp",
 }
 
@@ -180,6 +188,7 @@ func allocateMountID(mountHandle fs.MountHandle) (mountID uint64) {
 	globals.lastMountID++
 	mountID = globals.lastMountID
 	globals.mountIDMap[mountID] = mountHandle
+	globals.mountLastUsedMap[mountID] = time.Now()
 	globals.Unlock()
 	return
 }
@@ -187,6 +196,9 @@ func allocateMountID(mountHandle fs.MountHandle) (mountID uint64) {
 func lookupMountHandle(mountID uint64) (mountHandle fs.MountHandle, err error) {
 	globals.Lock()
 	mountHandle, ok := globals.mountIDMap[mountID]
+	if ok {
+		globals.mountLastUsedMap[mountID] = time.Now()
+	}
 	globals.Unlock()
 	if ok {
 		err = nil
@@ -197,6 +209,143 @@ func lookupMountHandle(mountID uint64) (mountHandle fs.MountHandle, err error) {
 	return
 }
 
+// freeMountID invalidates mountID returning the fs.MountHandle (to be unmounted by the caller) it referenced
+func freeMountID(mountID uint64) (mountHandle fs.MountHandle, err error) {
+	globals.Lock()
+	mountHandle, ok := globals.mountIDMap[mountID]
+	if ok {
+		delete(globals.mountIDMap, mountID)
+		delete(globals.mountLastUsedMap, mountID)
+	}
+	globals.Unlock()
+	if ok {
+		err = nil
+	} else {
+		err = fmt.Errorf("MountID %v not found in jrpcfs globals.mountIDMap", mountID)
+		err = blunder.AddError(err, blunder.BadMountIDError)
+	}
+	return
+}
+
+// unmountMountID invalidates mountID and unmounts the fs.MountHandle it referenced
+func unmountMountID(mountID uint64) (err error) {
+	mountHandle, err := freeMountID(mountID)
+	if nil != err {
+		return
+	}
+
+	err = mountHandle.Unmount()
+	return
+}
+
+// mountReaper periodically unmounts any MountID unused for globals.mountIdleTimeout
+func mountReaper() {
+	reapInterval := globals.mountIdleTimeout / 2
+	if time.Duration(0) == reapInterval {
+		reapInterval = globals.mountIdleTimeout
+	}
+
+	ticker := time.NewTicker(reapInterval)
+
+	for {
+		select {
+		case <-globals.mountReaperStopChan:
+			ticker.Stop()
+			globals.mountReaperDoneChan <- true
+			return
+		case <-ticker.C:
+			reapIdleMounts()
+		}
+	}
+}
+
+func reapIdleMounts() {
+	var (
+		err          error
+		idleMountIDs []uint64
+		lastUsed     time.Time
+		mountID      uint64
+	)
+
+	globals.gate.RLock()
+	defer globals.gate.RUnlock()
+
+	globals.Lock()
+	idleMountIDs = make([]uint64, 0)
+	for mountID, lastUsed = range globals.mountLastUsedMap {
+		if time.Since(lastUsed) >= globals.mountIdleTimeout {
+			idleMountIDs = append(idleMountIDs, mountID)
+		}
+	}
+	globals.Unlock()
+
+	for _, mountID = range idleMountIDs {
+		logger.Infof("Unmounting MountID %v idle for at least %v", mountID, globals.mountIdleTimeout)
+		err = unmountMountID(mountID)
+		if nil != err {
+			logger.ErrorfWithError(err, "Unmount of idle MountID %v failed", mountID)
+		}
+	}
+}
+
+// jrpcConnStruct tracks the MountIDs to be unmounted when its JSON-RPC connection is closed
+type jrpcConnStruct struct {
+	sync.Mutex
+	rpc.ServerCodec
+	closed   bool
+	mountIDs map[uint64]bool
+}
+
+// ReadRequestBody decodes the next request... letting RpcMount know the connection it arrived on
+func (jrpcConn *jrpcConnStruct) ReadRequestBody(body interface{}) (err error) {
+	err = jrpcConn.ServerCodec.ReadRequestBody(body)
+	if nil == err {
+		mountRequest, ok := body.(*MountRequest)
+		if ok {
+			mountRequest.conn = jrpcConn
+		}
+	}
+	return
+}
+
+// bindMountID arranges for mountID to be unmounted when the connection is closed. Should the
+// connection already be closed, false is returned (and it is up to the caller to unmount).
+func (jrpcConn *jrpcConnStruct) bindMountID(mountID uint64) (bound bool) {
+	jrpcConn.Lock()
+	defer jrpcConn.Unlock()
+
+	if jrpcConn.closed {
+		return false
+	}
+
+	jrpcConn.mountIDs[mountID] = true
+	return true
+}
+
+// Close closes the connection and unmounts each MountID still bound to it
+func (jrpcConn *jrpcConnStruct) Close() (err error) {
+	jrpcConn.Lock()
+	jrpcConn.closed = true
+	mountIDs := jrpcConn.mountIDs
+	jrpcConn.mountIDs = make(map[uint64]bool)
+	jrpcConn.Unlock()
+
+	err = jrpcConn.ServerCodec.Close()
+
+	if 0 < len(mountIDs) {
+		globals.gate.RLock()
+		for mountID := range mountIDs {
+			unmountErr := unmountMountID(mountID)
+			if (nil != unmountErr) && !blunder.Is(unmountErr, blunder.BadMountIDError) {
+				logger.ErrorfWithError(unmountErr, "Unmount of MountID %v upon connection close failed", mountID)
+			}
+		}
+		globals.gate.RUnlock()
+	}
+
+	return
+}
+
 func NewServer() *Server {
 	s := Server{}
 
@@ -815,27 +964,44 @@ func (s *Server) RpcCreatePath(in *CreatePathRequest, reply *InodeReply) (err er
 	return
 }
 
+// An F_SETLKW is waited upon in slices of up to flockWaitSliceDuration, releasing globals.gate
+// between them so that a SIGHUP need not wait for the conflicting lock to be released. Should the
+// lock not be acquired within flockWaitMaxDuration, TimedOut is returned so that the RPC worker is
+// freed up (the client may simply re-issue the F_SETLKW).
+const (
+	flockWaitSliceDuration = 1 * time.Second
+	flockWaitMaxDuration   = 60 * time.Second
+)
+
 func (s *Server) RpcFlock(in *FlockRequest, reply *FlockReply) (err error) {
-	globals.gate.RLock()
-	defer globals.gate.RUnlock()
+	var (
+		flock      fs.FlockStruct
+		lockStruct *fs.FlockStruct
+		waitStart  time.Time
+	)
 
 	flog := logger.TraceEnter("in.", in)
 	defer func() { flog.TraceExitErr("reply.", err, reply) }()
 	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
 
-	mountHandle, err := lookupMountHandle(in.MountID)
-	if nil != err {
-		return
-	}
-
-	var flock fs.FlockStruct
 	flock.Type = in.FlockType
 	flock.Whence = in.FlockWhence
 	flock.Start = in.FlockStart
 	flock.Len = in.FlockLen
 	flock.Pid = in.FlockPid
 
-	lockStruct, err := mountHandle.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.InodeNumber(in.InodeNumber), in.FlockCmd, &flock)
+	waitStart = time.Now()
+
+	for {
+		lockStruct, err = s.rpcFlockSlice(in, &flock)
+		if (syscall.F_SETLKW != in.FlockCmd) || !blunder.Is(err, blunder.TimedOut) {
+			break
+		}
+		if time.Since(waitStart) >= flockWaitMaxDuration {
+			break
+		}
+	}
+
 	if lockStruct != nil {
 		reply.FlockType = lockStruct.Type
 		reply.FlockWhence = lockStruct.Whence
@@ -846,6 +1012,24 @@ func (s *Server) RpcFlock(in *FlockRequest, reply *FlockReply) (err error) {
 	return
 }
 
+// rpcFlockSlice performs in.FlockCmd holding globals.gate... waiting at most flockWaitSliceDuration for an F_SETLKW
+func (s *Server) rpcFlockSlice(in *FlockRequest, flock *fs.FlockStruct) (lockStruct *fs.FlockStruct, err error) {
+	globals.gate.RLock()
+	defer globals.gate.RUnlock()
+
+	mountHandle, err := lookupMountHandle(in.MountID)
+	if nil != err {
+		return
+	}
+
+	if syscall.F_SETLKW == in.FlockCmd {
+		lockStruct, err = mountHandle.FlockWait(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.InodeNumber(in.InodeNumber), flock, flockWaitSliceDuration, nil)
+	} else {
+		lockStruct, err = mountHandle.Flock(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.InodeNumber(in.InodeNumber), in.FlockCmd, flock)
+	}
+	return
+}
+
 func UnixSec(t time.Time) (sec int64) {
 	return t.Unix()
 }
@@ -913,6 +1097,37 @@ func (stat *StatStruct) fsStatToStatStruct(fsStat fs.Stat) {
 	stat.GroupID = uint32(fsStat[fs.StatGroupID])
 }
 
+func (quota *QuotaStruct) fsOwnerQuotaToQuotaStruct(ownerQuotaStatus inode.OwnerQuotaStatus) {
+	quota.BytesUsed = ownerQuotaStatus.BytesUsed
+	quota.HardBytes = ownerQuotaStatus.Limits.HardBytes
+	quota.InodesUsed = ownerQuotaStatus.InodesUsed
+	quota.HardInodes = ownerQuotaStatus.Limits.HardInodes
+}
+
+func (s *Server) RpcGetQuota(in *GetQuotaRequest, reply *GetQuotaReply) (err error) {
+	globals.gate.RLock()
+	defer globals.gate.RUnlock()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandle(in.MountID)
+	if nil != err {
+		return
+	}
+
+	userQuota, groupQuota, err := mountHandle.GetQuota(inode.InodeUserID(in.UserID), inode.InodeGroupID(in.GroupID))
+	if nil != err {
+		return
+	}
+
+	reply.User.fsOwnerQuotaToQuotaStruct(userQuota)
+	reply.Group.fsOwnerQuotaToQuotaStruct(groupQuota)
+
+	return
+}
+
 func (s *Server) RpcGetStat(in *GetStatRequest, reply *StatStruct) (err error) {
 	globals.gate.RLock()
 	defer globals.gate.RUnlock()
@@ -1272,10 +1487,28 @@ func (s *Server) RpcMount(in *MountRequest, reply *MountReply) (err error) {
 	if err == nil {
 		reply.MountID = allocateMountID(mountHandle)
 		reply.RootDirInodeNumber = uint64(inode.RootDirInodeNumber)
+
+		if in.UnmountOnClose && (nil != in.conn) && !in.conn.bindMountID(reply.MountID) {
+			_ = unmountMountID(reply.MountID)
+			err = fmt.Errorf("Connection closed before MountID %v could be bound to it", reply.MountID)
+			err = blunder.AddError(err, blunder.BadMountIDError)
+		}
 	}
 	return
 }
 
+func (s *Server) RpcUnmount(in *UnmountRequest, reply *Reply) (err error) {
+	globals.gate.RLock()
+	defer globals.gate.RUnlock()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	err = unmountMountID(in.MountID)
+	return
+}
+
 func (s *Server) RpcRead(in *ReadRequest, reply *ReadReply) (err error) {
 	globals.gate.RLock()
 	defer globals.gate.RUnlock()
@@ -1788,6 +2021,8 @@ func (s *Server) RpcStatVFS(in *StatVFSRequest, reply *StatVFS) (err error) {
 	reply.FileSystemID = statvfs[fs.StatVFSFilesystemID]
 	reply.MountFlags = statvfs[fs.StatVFSMountFlags]
 	reply.MaxFilenameLen = statvfs[fs.StatVFSMaxFilenameLen]
+	reply.LogSegmentBytes = statvfs[fs.StatVFSLogSegmentBytes]
+	reply.ReferencedBytes = statvfs[fs.StatVFSReferencedBytes]
 
 	return
 }
@@ -3,8 +3,12 @@ package jrpcfs
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"net"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 	"unsafe"
 
@@ -178,14 +182,61 @@ func getRequest(conn net.Conn, ctx *ioContext) (err error) {
 		logger.Infof("Got %v bytes, request: %+v", bytesRead, ctx.req)
 	}
 
-	if ctx.req.opType == 1001 {
-		// Write op
-		ctx.op = WriteOp
-	} else if ctx.req.opType == 1002 {
-		// Read op
-		ctx.op = ReadOp
+	if (ioOpV3Min <= ctx.req.opType) && (ioOpV3Max >= ctx.req.opType) {
+		// Version 3 requests are tagged version 2 requests
+
+		tagBytes := make([]byte, 8)
+
+		_, err = io.ReadFull(conn, tagBytes)
+		if err != nil {
+			logger.Errorf("Failed to read request tag from the socket: %v", err)
+			return err
+		}
+
+		ctx.tagged = true
+		ctx.tag = *(*uint64)(unsafe.Pointer(&tagBytes[0]))
+		ctx.req.opType -= ioOpV3Min - ioOpV2Min
 	} else {
-		return fmt.Errorf("getRequest: unsupported op %v!", ctx.req.opType)
+		ctx.tagged = false
+	}
+
+	if (ioOpV2Min <= ctx.req.opType) && (ioOpV2Max >= ctx.req.opType) {
+		err = getRequestCredentials(conn, ctx)
+		if err != nil {
+			return err
+		}
+
+		switch ctx.req.opType {
+		case ioOpWrite:
+			ctx.op = WriteOp
+		case ioOpRead:
+			ctx.op = ReadOp
+		case ioOpFlush:
+			ctx.op = FlushOp
+		case ioOpGetStat:
+			ctx.op = GetStatOp
+		case ioOpResize:
+			ctx.op = ResizeOp
+		default:
+			ctx.op = InvalidOp
+			ctx.err = blunder.NewError(blunder.NotImplementedError, "ENOSYS")
+		}
+	} else {
+		// Version 1 requests are performed as root
+
+		ctx.userID = inode.InodeRootUserID
+		ctx.groupID = inode.InodeRootGroupID
+		ctx.otherGroupIDs = nil
+
+		if ctx.req.opType == ioOpWriteV1 {
+			// Write op
+			ctx.op = WriteOp
+		} else if ctx.req.opType == ioOpReadV1 {
+			// Read op
+			ctx.op = ReadOp
+		} else {
+			return fmt.Errorf("getRequest: unsupported op %v!", ctx.req.opType)
+		}
 	}
 
 	// For writes, get write data
@@ -194,6 +245,25 @@ func getRequest(conn net.Conn, ctx *ioContext) (err error) {
 			logger.Infof("Reading %v bytes of write data, ctx.data len is %v.", ctx.req.length, len(ctx.data))
 		}
 
+		if ctx.req.length > globals.fastMaxWriteSize {
+			// Rather than allocate a buffer for it, discard the write data (keeping the connection usable) and reject the request
+
+			if ctx.req.length > uint64(math.MaxInt64) {
+				return fmt.Errorf("getRequest: write length (%v) is not plausible", ctx.req.length)
+			}
+
+			_, err = io.CopyN(ioutil.Discard, conn, int64(ctx.req.length))
+			if err != nil {
+				logger.Infof("Failed to discard oversized write buffer from the socket.")
+				return err
+			}
+
+			ctx.op = InvalidOp
+			ctx.err = blunder.NewError(blunder.TooBigError, "write length (%v) exceeds [JSONRPCServer]FastMaxWriteSize (%v)", ctx.req.length, globals.fastMaxWriteSize)
+
+			return nil
+		}
+
 		ctx.data = make([]byte, ctx.req.length)
 
 		_, err = io.ReadFull(conn, ctx.data)
@@ -208,6 +278,57 @@ func getRequest(conn net.Conn, ctx *ioContext) (err error) {
 	return nil
 }
 
+// getRequestCredentials reads the ioRequestCredentials (and supplementary group IDs) following a version 2 ioRequest
+func getRequestCredentials(conn net.Conn, ctx *ioContext) (err error) {
+	var (
+		creds       ioRequestCredentials
+		groupBytes  []byte
+		groupIndex  uint32
+		groupOffset int
+	)
+
+	credsBytes := makeBytesCreds(&creds)
+
+	_, err = io.ReadFull(conn, credsBytes)
+	if err != nil {
+		logger.Errorf("Failed to read request credentials from the socket: %v", err)
+		return
+	}
+
+	makeCreds(credsBytes, &creds)
+
+	if creds.numOtherGroupIDs > ioMaxOtherGroupIDs {
+		err = fmt.Errorf("getRequest: numOtherGroupIDs (%v) exceeds %v", creds.numOtherGroupIDs, ioMaxOtherGroupIDs)
+		logger.ErrorWithError(err)
+		return
+	}
+
+	ctx.userID = inode.InodeUserID(creds.userID)
+	ctx.groupID = inode.InodeGroupID(creds.groupID)
+
+	if 0 == creds.numOtherGroupIDs {
+		ctx.otherGroupIDs = nil
+		return
+	}
+
+	groupBytes = make([]byte, 4*creds.numOtherGroupIDs)
+
+	_, err = io.ReadFull(conn, groupBytes)
+	if err != nil {
+		logger.Errorf("Failed to read request supplementary group IDs from the socket: %v", err)
+		return
+	}
+
+	ctx.otherGroupIDs = make([]inode.InodeGroupID, creds.numOtherGroupIDs)
+
+	for groupIndex = 0; groupIndex < creds.numOtherGroupIDs; groupIndex++ {
+		groupOffset = 4 * int(groupIndex)
+		ctx.otherGroupIDs[groupIndex] = inode.InodeGroupID(*(*uint32)(unsafe.Pointer(&groupBytes[groupOffset])))
+	}
+
+	return
+}
+
 func putResponseWrite(conn net.Conn, buf []byte) (err error) {
 	var (
 		currentIndex    = int(0)
@@ -233,15 +354,13 @@ func putResponse(conn net.Conn, ctx *ioContext) (err error) {
 		respBytes []byte
 	)
 
-	if (ctx.op != ReadOp) && (ctx.op != WriteOp) {
-		// We only support read and write
-		return fmt.Errorf("putResponse: unsupported opType %v", ctx.op)
-	}
-
-	// NOTE: the far end expects errno, ioSize, (if a read), a buffer
+	// NOTE: the far end expects errno, ioSize, (if a read or getstat), a buffer
 
-	// "cast" response to bytes and send them
+	// "cast" response to bytes (preceded, if tagged, by the request's tag) and send them
 	respBytes = makeBytesResp(&ctx.resp)
+	if ctx.tagged {
+		respBytes = append(makeBytesUint64(ctx.tag), respBytes...)
+	}
 
 	// Send response header
 	err = putResponseWrite(conn, respBytes)
@@ -250,8 +369,8 @@ func putResponse(conn net.Conn, ctx *ioContext) (err error) {
 		return
 	}
 
-	// If (non-zero length) Read Payload, send it as well
-	if (ctx.op == ReadOp) && (len(ctx.data) > 0) {
+	// If (non-zero length) Read or GetStat Payload, send it as well
+	if ((ctx.op == ReadOp) || (ctx.op == GetStatOp)) && (len(ctx.data) > 0) {
 		err = putResponseWrite(conn, ctx.data)
 		if nil != err {
 			logger.Infof("putResponse() failed to send ctx.data: %v", err)
@@ -292,6 +411,34 @@ func putUint64Profiled(conn net.Conn, fieldName string, value uint64, field []by
 	return
 }
 
+// Fast path opTypes. A version 1 request (ioOpWriteV1 or ioOpReadV1) consists solely of an ioRequest
+// and is performed as root. A version 2 request (opType in [ioOpV2Min,ioOpV2Max]) follows the ioRequest
+// with an ioRequestCredentials (itself followed by numOtherGroupIDs uint32's) and is performed with those
+// credentials. An unknown version 2 opType is answered with ENOSYS.
+//
+// A version 3 request (opType in [ioOpV3Min,ioOpV3Max]) is a version 2 request (with opType offset by
+// ioOpV3Min-ioOpV2Min) whose ioRequest is followed by a (uint64) tag preceding its ioRequestCredentials.
+// Its response is preceded by that same tag. Version 1 & 2 requests on a connection are performed (and
+// responded to) one at a time, in order. Version 3 requests on a connection are performed concurrently
+// (up to [JSONRPCServer]FastMaxInFlight at a time), with their responses sent as each completes.
+const (
+	ioOpWriteV1 uint64 = 1001
+	ioOpReadV1  uint64 = 1002
+
+	ioOpV2Min   uint64 = 2000
+	ioOpWrite   uint64 = 2001 // ioRequest.length bytes of write data follow the request
+	ioOpRead    uint64 = 2002 // ioResponse.ioSize bytes of read data follow the response
+	ioOpFlush   uint64 = 2003
+	ioOpGetStat uint64 = 2004 // an ioStat (ioResponse.ioSize == ioStatSize) follows the response
+	ioOpResize  uint64 = 2005 // ioRequest.offset is the new size
+	ioOpV2Max   uint64 = 2999
+
+	ioOpV3Min uint64 = 3000
+	ioOpV3Max uint64 = 3999
+)
+
+const ioMaxOtherGroupIDs uint32 = 65536 // Linux's NGROUPS_MAX
+
 type ioRequest struct {
 	opType  uint64
 	mountID uint64
@@ -300,22 +447,52 @@ type ioRequest struct {
 	length  uint64
 }
 
+type ioRequestCredentials struct {
+	userID           uint32
+	groupID          uint32
+	numOtherGroupIDs uint32
+	reserved         uint32
+}
+
 type ioResponse struct {
 	errno  uint64 //out
 	ioSize uint64 //out
 }
 
+// ioStat is the payload of an ioOpGetStat response (see StatStruct)
+type ioStat struct {
+	cTimeNs         uint64
+	crTimeNs        uint64
+	mTimeNs         uint64
+	aTimeNs         uint64
+	size            uint64
+	numLinks        uint64
+	statInodeNumber uint64
+	fileMode        uint32
+	userID          uint32
+	groupID         uint32
+	reserved        uint32
+}
+
 type ioContext struct {
-	op   OpType
-	req  ioRequest
-	resp ioResponse
+	op            OpType
+	req           ioRequest
+	tagged        bool
+	tag           uint64
+	userID        inode.InodeUserID
+	groupID       inode.InodeGroupID
+	otherGroupIDs []inode.InodeGroupID
+	err           error // if op == InvalidOp, the (non-nil) reason the request is rejected
+	resp          ioResponse
 	// read/writeData buf* (in: write; out: read)
 	// Ideally this would be a pointer (?)
 	data []byte
 }
 
 const ioRequestSize int = 8 * 5
+const ioRequestCredentialsSize int = 4 * 4
 const ioResponseSize int = 8 * 2
+const ioStatSize int = (8 * 7) + (4 * 4)
 
 func makeBytesReq(req *ioRequest) []byte {
 	mem := *(*[ioRequestSize]byte)(unsafe.Pointer(req))
@@ -327,6 +504,16 @@ func makeBytesResp(resp *ioResponse) []byte {
 	return mem[:]
 }
 
+func makeBytesCreds(creds *ioRequestCredentials) []byte {
+	mem := *(*[ioRequestCredentialsSize]byte)(unsafe.Pointer(creds))
+	return mem[:]
+}
+
+func makeBytesStat(stat *ioStat) []byte {
+	mem := *(*[ioStatSize]byte)(unsafe.Pointer(stat))
+	return mem[:]
+}
+
 func makeBytesUint64(value uint64) []byte {
 	mem := *(*[8]byte)(unsafe.Pointer(&value))
 	return mem[:]
@@ -336,128 +523,247 @@ func makeReq(bytes []byte, req *ioRequest) {
 	*req = *(*ioRequest)(unsafe.Pointer(&bytes[0]))
 }
 
+func makeCreds(bytes []byte, creds *ioRequestCredentials) {
+	*creds = *(*ioRequestCredentials)(unsafe.Pointer(&bytes[0]))
+}
+
+// ioErrno maps err to the errno returned to the far end... EIO if err carries no errno
+func ioErrno(err error) uint64 {
+	if nil == err {
+		return 0
+	}
+
+	errno := blunder.Errno(err)
+	if 0 >= errno {
+		errno = int(syscall.EIO)
+	}
+
+	return uint64(errno)
+}
+
 func ioHandle(conn net.Conn) {
 	var (
-		mountHandle fs.MountHandle
+		connWriteMutex sync.Mutex     // Serializes responses to conn
+		inFlightChan   chan bool      // Each tagged request in flight holds a slot
+		inFlightWG     sync.WaitGroup // Tracks tagged requests in flight
 	)
 
-	// NOTE: Allocate 64k buffer and context on the stack; this function runs in a goroutine
-	//       and only processes one request at a time.
-	//
-	//var dataStorage [64 * 1024]byte
-	ctxStorage := ioContext{op: InvalidOp}
-	ctx := &ctxStorage
-	// XXX TODO: no sync.Pool for now, just alloc on the stack
+	inFlightChan = make(chan bool, globals.fastMaxInFlight)
 
 	if printDebugLogs {
 		logger.Infof("got a connection - starting read/write io thread")
 	}
 
 	for {
+		// Each request gets its own context as tagged requests may be performed concurrently
+
+		ctx := &ioContext{op: InvalidOp}
+
 		if printDebugLogs {
-			logger.Infof("Waiting for RPC request; ctx.data size is %v.", len(ctx.data))
+			logger.Infof("Waiting for RPC request")
 		}
 
-		// XXX TODO: no sync.Pool for now, just alloc on the stack
-		// Get a context struct to use
-		//ctx := ioContextPool.Get()
-
 		// Get RPC request
 		err := getRequest(conn, ctx)
 		// NOTE: Suppress this for now, we're seeing not much time spent up to here
 		//profiler.AddEventNow("after get request")
 		if err != nil {
 			//logger.Infof("Connection terminated; returning.")
+			inFlightWG.Wait()
 			return
 		}
 
-		// Wait until here to increment this as a worker; else we count wait time as work time.
-		incRunningWorkers()
+		if !ctx.tagged {
+			// Untagged requests are performed (and responded to) in order... including after any tagged requests sent before them
 
-		// Taking stats *after* socket read, because otherwise we unintentionally count wait time.
-		profiler := utils.NewProfilerIf(doProfiling, "") // We don't know the op type yet, gets set by SaveProfiler().
+			inFlightWG.Wait()
 
-		if debugPutGet {
-			logger.Infof("Got request: %+v", ctx.req)
+			err = ioPerform(conn, &connWriteMutex, ctx)
+			if err != nil {
+				inFlightWG.Wait()
+				return
+			}
+
+			continue
 		}
 
-		switch ctx.op {
-		case WriteOp:
-			if globals.dataPathLogging || printDebugLogs {
-				logger.Tracef(">> ioWrite in.{InodeHandle:{MountID:%v InodeNumber:%v} Offset:%v Buf.size:%v Buf.<buffer not printed>",
-					ctx.req.mountID, ctx.req.inodeID, ctx.req.offset, len(ctx.data))
-			}
+		// Tagged requests are performed concurrently (up to globals.fastMaxInFlight at a time)... completing in any order
 
-			profiler.AddEventNow("before fs.Write()")
-			mountHandle, err = lookupMountHandle(ctx.req.mountID)
-			if err == nil {
-				ctx.resp.ioSize, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.data, profiler)
-			}
-			profiler.AddEventNow("after fs.Write()")
+		select {
+		case inFlightChan <- true:
+		default:
+			stats.IncrementOperations(&stats.JrpcfsIoPipelinedStallOps)
+			inFlightChan <- true
+		}
 
-			stats.IncrementOperationsAndBucketedBytes(stats.JrpcfsIoWrite, ctx.resp.ioSize)
+		stats.IncrementOperations(&stats.JrpcfsIoPipelinedOps)
+		atomic.AddUint64(&globals.fastInFlight, 1)
 
-			if globals.dataPathLogging || printDebugLogs {
-				logger.Tracef("<< ioWrite errno:%v out.Size:%v", ctx.resp.errno, ctx.resp.ioSize)
-			}
+		inFlightWG.Add(1)
 
-		case ReadOp:
-			if globals.dataPathLogging || printDebugLogs {
-				logger.Tracef(">> ioRead in.{InodeHandle:{MountID:%v InodeNumber:%v} Offset:%v Length:%v}", ctx.req.mountID, ctx.req.inodeID, ctx.req.offset, ctx.req.length)
+		go func(ctx *ioContext) {
+			err := ioPerform(conn, &connWriteMutex, ctx)
+			if err != nil {
+				_ = conn.Close() // Ensures the loop above terminates
 			}
+			atomic.AddUint64(&globals.fastInFlight, ^uint64(0))
+			<-inFlightChan
+			inFlightWG.Done()
+		}(ctx)
+	}
+}
 
-			profiler.AddEventNow("before fs.Read()")
-			mountHandle, err = lookupMountHandle(ctx.req.mountID)
-			if err == nil {
-				ctx.data, err = mountHandle.Read(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.req.length, profiler)
-			}
-			profiler.AddEventNow("after fs.Read()")
+// FetchFastInFlight returns the number of tagged requests currently being performed across all fast port connections
+func FetchFastInFlight() (inFlight uint64) {
+	inFlight = atomic.LoadUint64(&globals.fastInFlight)
+	return
+}
 
-			// Set io size in response
-			ctx.resp.ioSize = uint64(len(ctx.data))
+// ioPerform performs the request in ctx and sends its response. An error is returned only if the
+// connection can no longer be used (e.g. the response could not be sent).
+func ioPerform(conn net.Conn, connWriteMutex *sync.Mutex, ctx *ioContext) (err error) {
+	var (
+		fsStat      fs.Stat
+		mountHandle fs.MountHandle
+		stat        StatStruct
+	)
 
-			stats.IncrementOperationsAndBucketedBytes(stats.JrpcfsIoRead, ctx.resp.ioSize)
+	// Wait until here to increment this as a worker; else we count wait time as work time.
+	incRunningWorkers()
+	defer decRunningWorkers()
 
-			if globals.dataPathLogging || printDebugLogs {
-				logger.Tracef("<< ioRead errno:%v out.Buf.size:%v out.Buf.<buffer not printed>", ctx.resp.errno, len(ctx.data))
-			}
+	// Taking stats *after* socket read, because otherwise we unintentionally count wait time.
+	profiler := utils.NewProfilerIf(doProfiling, "") // We don't know the op type yet, gets set by SaveProfiler().
 
-		default:
-			// Hmmm, this should have been caught by getRequest...
-			logger.Errorf("Error, unsupported op %v", ctx.op)
-			decRunningWorkers()
-			return
+	if debugPutGet {
+		logger.Infof("Got request: %+v", ctx.req)
+	}
+
+	switch ctx.op {
+	case WriteOp:
+		if globals.dataPathLogging || printDebugLogs {
+			logger.Tracef(">> ioWrite in.{InodeHandle:{MountID:%v InodeNumber:%v} Offset:%v Buf.size:%v Buf.<buffer not printed>",
+				ctx.req.mountID, ctx.req.inodeID, ctx.req.offset, len(ctx.data))
 		}
 
-		// Set error in context
-		ctx.resp.errno = uint64(blunder.Errno(err))
+		profiler.AddEventNow("before fs.Write()")
+		mountHandle, err = lookupMountHandle(ctx.req.mountID)
+		if err == nil {
+			ctx.resp.ioSize, err = mountHandle.Write(ctx.userID, ctx.groupID, ctx.otherGroupIDs, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.data, profiler)
+		}
+		profiler.AddEventNow("after fs.Write()")
 
-		// Write response
-		err = putResponse(conn, ctx)
-		// XXX TODO: Enable if we want to see this event specifically.
-		//           Otherwise this will show up under "remaining time".
-		//profiler.AddEventNow("after rpc send response")
-		if err != nil {
-			decRunningWorkers()
-			return
+		stats.IncrementOperationsAndBucketedBytes(stats.JrpcfsIoWrite, ctx.resp.ioSize)
+
+		if globals.dataPathLogging || printDebugLogs {
+			logger.Tracef("<< ioWrite errno:%v out.Size:%v", ctx.resp.errno, ctx.resp.ioSize)
 		}
 
-		// Save profiler with server op stats. Close it first so that save time isn't counted.
-		profiler.Close()
-		SaveProfiler(qserver, ctx.op, profiler)
+	case ReadOp:
+		if globals.dataPathLogging || printDebugLogs {
+			logger.Tracef(">> ioRead in.{InodeHandle:{MountID:%v InodeNumber:%v} Offset:%v Length:%v}", ctx.req.mountID, ctx.req.inodeID, ctx.req.offset, ctx.req.length)
+		}
 
-		// XXX TODO: no sync.Pool for now, just alloc on the stack
-		// Return context struct to pool
-		//		ioContextPool.Put(ctx)
+		profiler.AddEventNow("before fs.Read()")
+		mountHandle, err = lookupMountHandle(ctx.req.mountID)
+		if err == nil {
+			ctx.data, err = mountHandle.Read(ctx.userID, ctx.groupID, ctx.otherGroupIDs, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.req.length, profiler)
+		}
+		profiler.AddEventNow("after fs.Read()")
 
-		// Reset data buffer size in ctx to its full size
-		// ctx.data = dataStorage[:0]
-		ctx.data = nil
+		// Set io size in response
+		ctx.resp.ioSize = uint64(len(ctx.data))
 
-		if printDebugLogs {
-			logger.Infof("Done with op, back to beginning")
+		stats.IncrementOperationsAndBucketedBytes(stats.JrpcfsIoRead, ctx.resp.ioSize)
+
+		if globals.dataPathLogging || printDebugLogs {
+			logger.Tracef("<< ioRead errno:%v out.Buf.size:%v out.Buf.<buffer not printed>", ctx.resp.errno, len(ctx.data))
+		}
+
+	case FlushOp:
+		profiler.AddEventNow("before fs.Flush()")
+		mountHandle, err = lookupMountHandle(ctx.req.mountID)
+		if err == nil {
+			err = mountHandle.Flush(ctx.userID, ctx.groupID, ctx.otherGroupIDs, inode.InodeNumber(ctx.req.inodeID))
 		}
+		profiler.AddEventNow("after fs.Flush()")
 
-		decRunningWorkers()
+		ctx.resp.ioSize = 0
+
+		stats.IncrementOperations(&stats.JrpcfsIoFlushOps)
+
+	case GetStatOp:
+		profiler.AddEventNow("before fs.Getstat()")
+		mountHandle, err = lookupMountHandle(ctx.req.mountID)
+		if err == nil {
+			fsStat, err = mountHandle.Getstat(ctx.userID, ctx.groupID, ctx.otherGroupIDs, inode.InodeNumber(ctx.req.inodeID))
+		}
+		profiler.AddEventNow("after fs.Getstat()")
+
+		if err == nil {
+			stat.fsStatToStatStruct(fsStat)
+			ctx.data = makeBytesStat(&ioStat{
+				cTimeNs:         stat.CTimeNs,
+				crTimeNs:        stat.CRTimeNs,
+				mTimeNs:         stat.MTimeNs,
+				aTimeNs:         stat.ATimeNs,
+				size:            stat.Size,
+				numLinks:        stat.NumLinks,
+				statInodeNumber: stat.StatInodeNumber,
+				fileMode:        stat.FileMode,
+				userID:          stat.UserID,
+				groupID:         stat.GroupID,
+			})
+		}
+
+		ctx.resp.ioSize = uint64(len(ctx.data))
+
+		stats.IncrementOperations(&stats.JrpcfsIoGetStatOps)
+
+	case ResizeOp:
+		profiler.AddEventNow("before fs.Resize()")
+		mountHandle, err = lookupMountHandle(ctx.req.mountID)
+		if err == nil {
+			err = mountHandle.Resize(ctx.userID, ctx.groupID, ctx.otherGroupIDs, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset)
+		}
+		profiler.AddEventNow("after fs.Resize()")
+
+		ctx.resp.ioSize = 0
+
+		stats.IncrementOperations(&stats.JrpcfsIoResizeOps)
+
+	case InvalidOp:
+		err = ctx.err
+		ctx.resp.ioSize = 0
+
+	default:
+		// Hmmm, this should have been caught by getRequest...
+		logger.Errorf("Error, unsupported op %v", ctx.op)
+		err = fmt.Errorf("ioPerform: unsupported op %v", ctx.op)
+		return
+	}
+
+	// Set error in context
+	ctx.resp.errno = ioErrno(err)
+
+	// Write response
+	connWriteMutex.Lock()
+	err = putResponse(conn, ctx)
+	connWriteMutex.Unlock()
+	// XXX TODO: Enable if we want to see this event specifically.
+	//           Otherwise this will show up under "remaining time".
+	//profiler.AddEventNow("after rpc send response")
+	if err != nil {
+		return
+	}
+
+	// Save profiler with server op stats. Close it first so that save time isn't counted.
+	profiler.Close()
+	SaveProfiler(qserver, ctx.op, profiler)
+
+	if printDebugLogs {
+		logger.Infof("Done with op, back to beginning")
 	}
+
+	return
 }
@@ -0,0 +1,691 @@
+package jrpcfs
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+// ioDo sends a fast path request (version 2 if creds != nil) and returns the errno and any payload of its response
+func ioDo(t *testing.T, conn net.Conn, req ioRequest, creds *ioRequestCredentials, otherGroupIDs []uint32, data []byte) (errno uint64, payload []byte) {
+	var (
+		err  error
+		resp ioResponse
+	)
+
+	buf := makeBytesReq(&req)
+	if nil != creds {
+		creds.numOtherGroupIDs = uint32(len(otherGroupIDs))
+		buf = append(buf, makeBytesCreds(creds)...)
+		for _, otherGroupID := range otherGroupIDs {
+			otherGroupIDBytes := *(*[4]byte)(unsafe.Pointer(&otherGroupID))
+			buf = append(buf, otherGroupIDBytes[:]...)
+		}
+	}
+	buf = append(buf, data...)
+
+	_, err = conn.Write(buf)
+	if nil != err {
+		t.Fatalf("conn.Write() failed: %v", err)
+	}
+
+	respBytes := make([]byte, ioResponseSize)
+	_, err = io.ReadFull(conn, respBytes)
+	if nil != err {
+		t.Fatalf("io.ReadFull() of response failed: %v", err)
+	}
+	resp = *(*ioResponse)(unsafe.Pointer(&respBytes[0]))
+
+	errno = resp.errno
+	if ((ioOpReadV1 == req.opType) || (ioOpRead == req.opType) || (ioOpGetStat == req.opType)) && (0 < resp.ioSize) {
+		payload = make([]byte, resp.ioSize)
+		_, err = io.ReadFull(conn, payload)
+		if nil != err {
+			t.Fatalf("io.ReadFull() of payload failed: %v", err)
+		}
+	}
+
+	return
+}
+
+func TestIoFastPath(t *testing.T) {
+	var (
+		err        error
+		errno      uint64
+		mountReply MountReply
+		payload    []byte
+		stat       ioStat
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	mountHandle, err := lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() failed: %v", err)
+	}
+
+	// File is only accessible by root and members of group 2000
+
+	fileInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathFile")
+	err = mountHandle.Setstat(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, fs.Stat{fs.StatMode: 0660, fs.StatGroupID: 2000})
+	if nil != err {
+		t.Fatalf("Setstat() failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", "localhost:32346")
+	if nil != err {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Version 1 requests are performed as root
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpWriteV1, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 5}, nil, nil, []byte("ABCDE"))
+	if 0 != errno {
+		t.Fatalf("Version 1 write returned errno %v", errno)
+	}
+
+	errno, payload = ioDo(t, conn, ioRequest{opType: ioOpReadV1, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 5}, nil, nil, nil)
+	if (0 != errno) || ("ABCDE" != string(payload)) {
+		t.Fatalf("Version 1 read returned errno %v payload \"%s\"", errno, string(payload))
+	}
+
+	// Version 2 requests are performed with the supplied credentials
+
+	otherCreds := &ioRequestCredentials{userID: 1000, groupID: 1000}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpWrite, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 2}, otherCreds, nil, []byte("ab"))
+	if uint64(unix.EACCES) != errno {
+		t.Fatalf("Version 2 write by other returned errno %v (expected EACCES)", errno)
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 5}, otherCreds, nil, nil)
+	if uint64(unix.EACCES) != errno {
+		t.Fatalf("Version 2 read by other returned errno %v (expected EACCES)", errno)
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpWrite, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 2}, otherCreds, []uint32{3000, 2000}, []byte("ab"))
+	if 0 != errno {
+		t.Fatalf("Version 2 write by group member returned errno %v", errno)
+	}
+
+	errno, payload = ioDo(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 5}, otherCreds, []uint32{2000}, nil)
+	if (0 != errno) || ("abCDE" != string(payload)) {
+		t.Fatalf("Version 2 read by group member returned errno %v payload \"%s\"", errno, string(payload))
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpResize, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 3}, otherCreds, []uint32{2000}, nil)
+	if 0 != errno {
+		t.Fatalf("Version 2 resize returned errno %v", errno)
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpFlush, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber)}, otherCreds, []uint32{2000}, nil)
+	if 0 != errno {
+		t.Fatalf("Version 2 flush returned errno %v", errno)
+	}
+
+	errno, payload = ioDo(t, conn, ioRequest{opType: ioOpGetStat, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber)}, otherCreds, nil, nil)
+	if (0 != errno) || (ioStatSize != len(payload)) {
+		t.Fatalf("Version 2 getstat returned errno %v payload length %v", errno, len(payload))
+	}
+	stat = *(*ioStat)(unsafe.Pointer(&payload[0]))
+	if (3 != stat.size) || (uint64(fileInodeNumber) != stat.statInodeNumber) || (2000 != stat.groupID) {
+		t.Fatalf("Version 2 getstat returned %+v", stat)
+	}
+
+	// Errors lacking an errno map to EIO... and unknown version 2 opTypes to ENOSYS
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpGetStat, mountID: 0, inodeID: uint64(fileInodeNumber)}, otherCreds, nil, nil)
+	if uint64(unix.EINVAL) != errno {
+		t.Fatalf("Version 2 getstat of bad MountID returned errno %v (expected EINVAL)", errno)
+	}
+
+	if uint64(unix.EIO) != ioErrno(fmt.Errorf("no errno")) {
+		t.Fatalf("ioErrno() of error lacking errno returned %v (expected EIO)", ioErrno(fmt.Errorf("no errno")))
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpV2Max, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber)}, otherCreds, nil, nil)
+	if uint64(unix.ENOSYS) != errno {
+		t.Fatalf("Unknown version 2 opType returned errno %v (expected ENOSYS)", errno)
+	}
+
+	// ...after which the connection remains usable
+
+	errno, payload = ioDo(t, conn, ioRequest{opType: ioOpReadV1, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 5}, nil, nil, nil)
+	if (0 != errno) || ("abC" != string(payload)) {
+		t.Fatalf("Version 1 read after ENOSYS returned errno %v payload \"%s\"", errno, string(payload))
+	}
+
+	err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "TestIoFastPathFile")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+
+	err = s.RpcUnmount(&UnmountRequest{MountID: mountReply.MountID}, &Reply{})
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+}
+
+func TestIoFastPathPipelined(t *testing.T) {
+	var (
+		err        error
+		mountReply MountReply
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	mountHandle, err := lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() failed: %v", err)
+	}
+
+	fileInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathPipelinedFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, 0, []byte("0123456789"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", "localhost:32346")
+	if nil != err {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Send more tagged reads (each of a single byte at offset == tag) than may be in flight at once... plus one unknown opType
+
+	numReads := uint64(2*globals.fastMaxInFlight + 1)
+	buf := make([]byte, 0)
+	creds := ioRequestCredentials{}
+
+	for tag := uint64(0); tag < numReads; tag++ {
+		req := ioRequest{opType: ioOpRead + (ioOpV3Min - ioOpV2Min), mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: tag % 10, length: 1}
+		buf = append(buf, makeBytesReq(&req)...)
+		buf = append(buf, makeBytesUint64(tag)...)
+		buf = append(buf, makeBytesCreds(&creds)...)
+	}
+
+	req := ioRequest{opType: ioOpV3Max, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber)}
+	buf = append(buf, makeBytesReq(&req)...)
+	buf = append(buf, makeBytesUint64(numReads)...)
+	buf = append(buf, makeBytesCreds(&creds)...)
+
+	_, err = conn.Write(buf)
+	if nil != err {
+		t.Fatalf("conn.Write() failed: %v", err)
+	}
+
+	// Responses may arrive in any order... but each must match its tag
+
+	tagsSeen := make(map[uint64]bool)
+
+	for i := uint64(0); i <= numReads; i++ {
+		respBytes := make([]byte, 8+ioResponseSize)
+		_, err = io.ReadFull(conn, respBytes)
+		if nil != err {
+			t.Fatalf("io.ReadFull() of tagged response failed: %v", err)
+		}
+		tag := *(*uint64)(unsafe.Pointer(&respBytes[0]))
+		resp := *(*ioResponse)(unsafe.Pointer(&respBytes[8]))
+
+		if tagsSeen[tag] {
+			t.Fatalf("Tag %v responded to more than once", tag)
+		}
+		tagsSeen[tag] = true
+
+		if numReads == tag {
+			if (uint64(unix.ENOSYS) != resp.errno) || (0 != resp.ioSize) {
+				t.Fatalf("Unknown version 3 opType returned errno %v ioSize %v", resp.errno, resp.ioSize)
+			}
+			continue
+		}
+
+		if (0 != resp.errno) || (1 != resp.ioSize) {
+			t.Fatalf("Tagged read %v returned errno %v ioSize %v", tag, resp.errno, resp.ioSize)
+		}
+		payload := make([]byte, 1)
+		_, err = io.ReadFull(conn, payload)
+		if nil != err {
+			t.Fatalf("io.ReadFull() of tagged read payload failed: %v", err)
+		}
+		if byte('0'+tag%10) != payload[0] {
+			t.Fatalf("Tagged read %v returned \"%s\"", tag, string(payload))
+		}
+	}
+
+	// Untagged requests remain usable on the same connection
+
+	errno, payload := ioDo(t, conn, ioRequest{opType: ioOpReadV1, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: 10}, nil, nil, nil)
+	if (0 != errno) || ("0123456789" != string(payload)) {
+		t.Fatalf("Version 1 read after tagged reads returned errno %v payload \"%s\"", errno, string(payload))
+	}
+
+	err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "TestIoFastPathPipelinedFile")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+
+	err = s.RpcUnmount(&UnmountRequest{MountID: mountReply.MountID}, &Reply{})
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+}
+
+// ioSendTagged sends a version 3 (i.e. tagged version 2) request as root
+func ioSendTagged(t *testing.T, conn net.Conn, req ioRequest, tag uint64, data []byte) {
+	creds := ioRequestCredentials{}
+
+	req.opType += ioOpV3Min - ioOpV2Min
+
+	buf := makeBytesReq(&req)
+	buf = append(buf, makeBytesUint64(tag)...)
+	buf = append(buf, makeBytesCreds(&creds)...)
+	buf = append(buf, data...)
+
+	_, err := conn.Write(buf)
+	if nil != err {
+		t.Fatalf("conn.Write() failed: %v", err)
+	}
+}
+
+// ioRecvTagged receives the response to a version 3 read request (i.e. including its payload)
+func ioRecvTagged(t *testing.T, conn net.Conn) (tag uint64, errno uint64, payload []byte) {
+	respBytes := make([]byte, 8+ioResponseSize)
+	_, err := io.ReadFull(conn, respBytes)
+	if nil != err {
+		t.Fatalf("io.ReadFull() of tagged response failed: %v", err)
+	}
+	tag = *(*uint64)(unsafe.Pointer(&respBytes[0]))
+	resp := *(*ioResponse)(unsafe.Pointer(&respBytes[8]))
+
+	errno = resp.errno
+	payload = make([]byte, resp.ioSize)
+	_, err = io.ReadFull(conn, payload)
+	if nil != err {
+		t.Fatalf("io.ReadFull() of tagged read payload failed: %v", err)
+	}
+
+	return
+}
+
+// ioExpectNoResponse verifies that no response arrives on conn for a while
+func ioExpectNoResponse(t *testing.T, conn net.Conn, why string) {
+	err := conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if nil != err {
+		t.Fatalf("conn.SetReadDeadline() failed: %v", err)
+	}
+	_, err = conn.Read(make([]byte, 1))
+	if nil == err {
+		t.Fatalf("Response unexpectedly received %s", why)
+	}
+	netErr, ok := err.(net.Error)
+	if !ok || !netErr.Timeout() {
+		t.Fatalf("conn.Read() %s failed: %v", why, err)
+	}
+	err = conn.SetReadDeadline(time.Time{})
+	if nil != err {
+		t.Fatalf("conn.SetReadDeadline() failed: %v", err)
+	}
+}
+
+// ioBlockInode returns a (held) WriteLock on inodeNumber of SomeVolume... blocking fast path requests on it until Unlock()'d
+func ioBlockInode(t *testing.T, inodeNumber inode.InodeNumber) (inodeLock *dlm.RWLockStruct) {
+	inodeLock = &dlm.RWLockStruct{
+		LockID:       dlm.InodeLockID("SomeVolume", uint64(inodeNumber)),
+		Notify:       nil,
+		LockCallerID: dlm.GenerateCallerID(),
+	}
+
+	err := inodeLock.WriteLock()
+	if nil != err {
+		t.Fatalf("WriteLock() failed: %v", err)
+	}
+
+	return
+}
+
+func TestIoFastPathPipelinedOutOfOrder(t *testing.T) {
+	var (
+		err        error
+		mountReply MountReply
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	mountHandle, err := lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() failed: %v", err)
+	}
+
+	slowInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathOutOfOrderSlowFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, slowInodeNumber, 0, []byte("slow"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fastInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathOutOfOrderFastFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fastInodeNumber, 0, []byte("fast"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", "localhost:32346")
+	if nil != err {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// A read of the (blocked) slow file sent first must not delay the response to a later read of the fast file
+
+	slowInodeLock := ioBlockInode(t, slowInodeNumber)
+
+	ioSendTagged(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(slowInodeNumber), offset: 0, length: 4}, 1, nil)
+	ioSendTagged(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(fastInodeNumber), offset: 0, length: 4}, 2, nil)
+
+	tag, errno, payload := ioRecvTagged(t, conn)
+	if (2 != tag) || (0 != errno) || ("fast" != string(payload)) {
+		t.Fatalf("First tagged response was tag %v errno %v payload \"%s\" (expected tag 2)", tag, errno, string(payload))
+	}
+
+	ioExpectNoResponse(t, conn, "while slow file blocked")
+
+	err = slowInodeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	tag, errno, payload = ioRecvTagged(t, conn)
+	if (1 != tag) || (0 != errno) || ("slow" != string(payload)) {
+		t.Fatalf("Second tagged response was tag %v errno %v payload \"%s\" (expected tag 1)", tag, errno, string(payload))
+	}
+
+	for _, basename := range []string{"TestIoFastPathOutOfOrderSlowFile", "TestIoFastPathOutOfOrderFastFile"} {
+		err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, basename)
+		if nil != err {
+			t.Fatalf("Unlink() failed: %v", err)
+		}
+	}
+
+	err = s.RpcUnmount(&UnmountRequest{MountID: mountReply.MountID}, &Reply{})
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+}
+
+func TestIoFastPathPipelinedThenUntagged(t *testing.T) {
+	var (
+		err        error
+		mountReply MountReply
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	mountHandle, err := lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() failed: %v", err)
+	}
+
+	slowInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathThenUntaggedSlowFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, slowInodeNumber, 0, []byte("slow"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fastInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathThenUntaggedFastFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fastInodeNumber, 0, []byte("fast"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", "localhost:32346")
+	if nil != err {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// An untagged read of the fast file must not be performed before a tagged read of the (blocked) slow file sent first
+
+	slowInodeLock := ioBlockInode(t, slowInodeNumber)
+
+	ioSendTagged(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(slowInodeNumber), offset: 0, length: 4}, 1, nil)
+
+	_, err = conn.Write(makeBytesReq(&ioRequest{opType: ioOpReadV1, mountID: mountReply.MountID, inodeID: uint64(fastInodeNumber), offset: 0, length: 4}))
+	if nil != err {
+		t.Fatalf("conn.Write() failed: %v", err)
+	}
+
+	ioExpectNoResponse(t, conn, "while tagged read of slow file blocked")
+
+	err = slowInodeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	tag, errno, payload := ioRecvTagged(t, conn)
+	if (1 != tag) || (0 != errno) || ("slow" != string(payload)) {
+		t.Fatalf("Tagged response was tag %v errno %v payload \"%s\" (expected tag 1)", tag, errno, string(payload))
+	}
+
+	respBytes := make([]byte, ioResponseSize)
+	_, err = io.ReadFull(conn, respBytes)
+	if nil != err {
+		t.Fatalf("io.ReadFull() of untagged response failed: %v", err)
+	}
+	resp := *(*ioResponse)(unsafe.Pointer(&respBytes[0]))
+	payload = make([]byte, resp.ioSize)
+	_, err = io.ReadFull(conn, payload)
+	if nil != err {
+		t.Fatalf("io.ReadFull() of untagged read payload failed: %v", err)
+	}
+	if (0 != resp.errno) || ("fast" != string(payload)) {
+		t.Fatalf("Untagged response was errno %v payload \"%s\"", resp.errno, string(payload))
+	}
+
+	for _, basename := range []string{"TestIoFastPathThenUntaggedSlowFile", "TestIoFastPathThenUntaggedFastFile"} {
+		err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, basename)
+		if nil != err {
+			t.Fatalf("Unlink() failed: %v", err)
+		}
+	}
+
+	err = s.RpcUnmount(&UnmountRequest{MountID: mountReply.MountID}, &Reply{})
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+}
+
+func TestIoFastPathPipelinedThrottled(t *testing.T) {
+	var (
+		err        error
+		mountReply MountReply
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	mountHandle, err := lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() failed: %v", err)
+	}
+
+	slowInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathThrottledSlowFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, slowInodeNumber, 0, []byte("S"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fastInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathThrottledFastFile")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fastInodeNumber, 0, []byte("F"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", "localhost:32346")
+	if nil != err {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Fill every in flight slot with (blocked) reads of the slow file... then send as many more of each file
+
+	slowInodeLock := ioBlockInode(t, slowInodeNumber)
+
+	maxInFlight := uint64(globals.fastMaxInFlight)
+	numSlowReads := 2 * maxInFlight
+	numFastReads := maxInFlight
+
+	for tag := uint64(0); tag < numSlowReads; tag++ {
+		ioSendTagged(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(slowInodeNumber), offset: 0, length: 1}, tag, nil)
+	}
+	for tag := numSlowReads; tag < (numSlowReads + numFastReads); tag++ {
+		ioSendTagged(t, conn, ioRequest{opType: ioOpRead, mountID: mountReply.MountID, inodeID: uint64(fastInodeNumber), offset: 0, length: 1}, tag, nil)
+	}
+
+	// No more than maxInFlight may be performed at once... so even the fast file reads must await a free slot
+
+	for i := 0; FetchFastInFlight() < maxInFlight; i++ {
+		if 1000 == i {
+			t.Fatalf("In flight requests never reached %v (stuck at %v)", maxInFlight, FetchFastInFlight())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ioExpectNoResponse(t, conn, "while every in flight slot blocked")
+
+	if maxInFlight != FetchFastInFlight() {
+		t.Fatalf("In flight requests exceeded limit: %v (limit %v)", FetchFastInFlight(), maxInFlight)
+	}
+
+	err = slowInodeLock.Unlock()
+	if nil != err {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	tagsSeen := make(map[uint64]bool)
+
+	for i := uint64(0); i < (numSlowReads + numFastReads); i++ {
+		tag, errno, payload := ioRecvTagged(t, conn)
+		if tagsSeen[tag] {
+			t.Fatalf("Tag %v responded to more than once", tag)
+		}
+		tagsSeen[tag] = true
+
+		expectedPayload := "S"
+		if tag >= numSlowReads {
+			expectedPayload = "F"
+		}
+		if (0 != errno) || (expectedPayload != string(payload)) {
+			t.Fatalf("Tagged read %v returned errno %v payload \"%s\" (expected \"%s\")", tag, errno, string(payload), expectedPayload)
+		}
+	}
+
+	for i := 0; 0 != FetchFastInFlight(); i++ {
+		if 1000 == i {
+			t.Fatalf("In flight requests never drained (stuck at %v)", FetchFastInFlight())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, basename := range []string{"TestIoFastPathThrottledSlowFile", "TestIoFastPathThrottledFastFile"} {
+		err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, basename)
+		if nil != err {
+			t.Fatalf("Unlink() failed: %v", err)
+		}
+	}
+
+	err = s.RpcUnmount(&UnmountRequest{MountID: mountReply.MountID}, &Reply{})
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+}
+
+func TestIoFastPathMaxWriteSize(t *testing.T) {
+	var (
+		err        error
+		mountReply MountReply
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	mountHandle, err := lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() failed: %v", err)
+	}
+
+	fileInodeNumber := fsCreateFile(mountHandle, inode.RootDirInodeNumber, "TestIoFastPathMaxWriteSizeFile")
+
+	conn, err := net.Dial("tcp", "localhost:32346")
+	if nil != err {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer conn.Close()
+
+	// A write of exactly FastMaxWriteSize is accepted... one byte more is rejected (for both version 1 & 2)
+
+	data := make([]byte, globals.fastMaxWriteSize+1)
+
+	errno, _ := ioDo(t, conn, ioRequest{opType: ioOpWriteV1, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: globals.fastMaxWriteSize}, nil, nil, data[:globals.fastMaxWriteSize])
+	if 0 != errno {
+		t.Fatalf("Version 1 write of FastMaxWriteSize returned errno %v", errno)
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpWriteV1, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: uint64(len(data))}, nil, nil, data)
+	if uint64(unix.E2BIG) != errno {
+		t.Fatalf("Version 1 write beyond FastMaxWriteSize returned errno %v (expected E2BIG)", errno)
+	}
+
+	errno, _ = ioDo(t, conn, ioRequest{opType: ioOpWrite, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber), offset: 0, length: uint64(len(data))}, &ioRequestCredentials{}, nil, data)
+	if uint64(unix.E2BIG) != errno {
+		t.Fatalf("Version 2 write beyond FastMaxWriteSize returned errno %v (expected E2BIG)", errno)
+	}
+
+	// ...after which the connection remains usable (and the rejected writes were not performed)
+
+	errno, payload := ioDo(t, conn, ioRequest{opType: ioOpGetStat, mountID: mountReply.MountID, inodeID: uint64(fileInodeNumber)}, &ioRequestCredentials{}, nil, nil)
+	if (0 != errno) || (ioStatSize != len(payload)) {
+		t.Fatalf("Version 2 getstat returned errno %v payload length %v", errno, len(payload))
+	}
+	stat := *(*ioStat)(unsafe.Pointer(&payload[0]))
+	if globals.fastMaxWriteSize != stat.size {
+		t.Fatalf("Version 2 getstat returned size %v (expected %v)", stat.size, globals.fastMaxWriteSize)
+	}
+
+	err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, "TestIoFastPathMaxWriteSizeFile")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+
+	err = s.RpcUnmount(&UnmountRequest{MountID: mountReply.MountID}, &Reply{})
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+}
@@ -213,7 +213,7 @@ func (s *Server) RpcGetObject(in *GetObjectReq, reply *GetObjectReply) (err erro
 
 	mountRelativePath := vContainerName + "/" + objectName
 
-	reply.FileSize, reply.ModificationTime, reply.InodeNumber, reply.NumWrites, reply.Metadata, err = mountHandle.MiddlewareGetObject(volumeName, mountRelativePath, in.ReadEntsIn, &reply.ReadEntsOut)
+	reply.FileSize, reply.ModificationTime, reply.InodeNumber, reply.NumWrites, reply.Metadata, reply.LeaseId, err = mountHandle.MiddlewareGetObject(volumeName, mountRelativePath, in.ReadEntsIn, &reply.ReadEntsOut)
 	if err != nil {
 		return err
 	}
@@ -371,7 +371,7 @@ func (s *Server) RpcRenewLease(in *RenewLeaseReq, reply *RenewLeaseReply) (err e
 	defer func() { flog.TraceExitErr("reply.", err, reply) }()
 	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
 
-	// This is currently a stub, as there's not yet any idea of a lease, so there's nothing to renew.
+	err = fs.RenewLease(in.LeaseId)
 	return
 }
 
@@ -386,7 +386,7 @@ func (s *Server) RpcReleaseLease(in *ReleaseLeaseReq, reply *ReleaseLeaseReply)
 	defer func() { flog.TraceExitErr("reply.", err, reply) }()
 	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
 
-	// This is currently a stub, as there's not yet any idea of a lease, so there's nothing to release.
+	err = fs.ReleaseLease(in.LeaseId)
 	return
 }
 
@@ -3,6 +3,7 @@ package jrpcfs
 import (
 	"fmt"
 	"io/ioutil"
+	"net/rpc/jsonrpc"
 	"os"
 	"strings"
 	"testing"
@@ -116,6 +117,7 @@ func testSetup() []func() {
 		"PhysicalContainerLayout:SomeContainerLayout2.MaxObjectsPerContainer=1234567",
 		"JSONRPCServer.TCPPort=12346",     // 12346 instead of 12345 so that test can run if proxyfsd is already running
 		"JSONRPCServer.FastTCPPort=32346", // ...and similarly here...
+		"JSONRPCServer.FastMaxWriteSize=1048576",
 		"JSONRPCServer.DataPathLogging=false",
 	}
 
@@ -2312,3 +2314,107 @@ func TestRpcCoalesceSubdirOfAFile(t *testing.T) {
 	assert.NotNil(err)
 	assert.Equal(fmt.Sprintf("errno: %d", blunder.NotDirError), err.Error())
 }
+
+func TestRpcUnmount(t *testing.T) {
+	var (
+		err         error
+		mountReply  MountReply
+		unmountReq  UnmountRequest
+		unmountResp Reply
+	)
+
+	s := &Server{}
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+
+	unmountReq.MountID = mountReply.MountID
+	err = s.RpcUnmount(&unmountReq, &unmountResp)
+	if nil != err {
+		t.Fatalf("RpcUnmount() failed: %v", err)
+	}
+
+	_, err = lookupMountHandle(mountReply.MountID)
+	if !blunder.Is(err, blunder.BadMountIDError) {
+		t.Fatalf("lookupMountHandle() after RpcUnmount() should have failed with BadMountIDError: %v", err)
+	}
+
+	err = s.RpcUnmount(&unmountReq, &unmountResp)
+	if nil == err {
+		t.Fatalf("Second RpcUnmount() should have failed")
+	}
+
+	// An idle MountID is reaped... but a recently used one is not
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	idleMountID := mountReply.MountID
+
+	err = s.RpcMount(&MountRequest{VolumeName: "SomeVolume"}, &mountReply)
+	if nil != err {
+		t.Fatalf("RpcMount() failed: %v", err)
+	}
+	busyMountID := mountReply.MountID
+
+	globals.Lock()
+	globals.mountIdleTimeout = time.Minute
+	globals.mountLastUsedMap[idleMountID] = time.Now().Add(-time.Hour)
+	globals.Unlock()
+
+	reapIdleMounts()
+
+	globals.Lock()
+	globals.mountIdleTimeout = time.Duration(0)
+	globals.Unlock()
+
+	_, err = lookupMountHandle(idleMountID)
+	if !blunder.Is(err, blunder.BadMountIDError) {
+		t.Fatalf("lookupMountHandle() of idle MountID should have failed with BadMountIDError: %v", err)
+	}
+	_, err = lookupMountHandle(busyMountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() of recently used MountID failed: %v", err)
+	}
+
+	err = unmountMountID(busyMountID)
+	if nil != err {
+		t.Fatalf("unmountMountID() failed: %v", err)
+	}
+
+	// A MountID requesting UnmountOnClose is unmounted when its connection is closed
+
+	client, err := jsonrpc.Dial("tcp", "localhost:12346")
+	if nil != err {
+		t.Fatalf("jsonrpc.Dial() failed: %v", err)
+	}
+
+	err = client.Call("Server.RpcMount", &MountRequest{VolumeName: "SomeVolume", UnmountOnClose: true}, &mountReply)
+	if nil != err {
+		t.Fatalf("Server.RpcMount call failed: %v", err)
+	}
+
+	_, err = lookupMountHandle(mountReply.MountID)
+	if nil != err {
+		t.Fatalf("lookupMountHandle() of MountID bound to open connection failed: %v", err)
+	}
+
+	err = client.Close()
+	if nil != err {
+		t.Fatalf("client.Close() failed: %v", err)
+	}
+
+	for i := 0; ; i++ {
+		_, err = lookupMountHandle(mountReply.MountID)
+		if blunder.Is(err, blunder.BadMountIDError) {
+			break
+		}
+		if 1000 == i {
+			t.Fatalf("MountID bound to closed connection was not unmounted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
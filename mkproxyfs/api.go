@@ -10,6 +10,7 @@ import (
 	"github.com/swiftstack/ProxyFS/dlm"
 	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/utils"
@@ -32,6 +33,7 @@ func Format(mode Mode, volumeNameToFormat string, confFile string, confStrings [
 		isEmpty           bool
 		objectList        []string
 		objectName        string
+		objectStore       objectstore.ObjectStore
 		replayLogFileName string
 	)
 
@@ -108,9 +110,14 @@ func Format(mode Mode, volumeNameToFormat string, confFile string, confStrings [
 		_ = swiftclient.Down()
 	}()
 
+	objectStore, err = objectstore.FetchObjectStore(confMap, volumeNameToFormat)
+	if nil != err {
+		return
+	}
+
 	// Determine if underlying accountName is empty
 
-	_, containerList, err = swiftclient.AccountGet(accountName)
+	_, containerList, err = objectStore.AccountGet(accountName)
 	if nil == err {
 		// accountName exists (possibly auto-created)... consider it empty only if no containers therein
 		isEmpty = (0 == len(containerList))
@@ -140,10 +147,10 @@ func Format(mode Mode, volumeNameToFormat string, confFile string, confStrings [
 			// If Swift Account is not empty && ModeReformat, clear out accountName
 
 			for _, containerName = range containerList {
-				_, objectList, err = swiftclient.ContainerGet(accountName, containerName)
+				_, objectList, err = objectStore.ContainerGet(accountName, containerName)
 				if nil == err {
 					for _, objectName = range objectList {
-						err = swiftclient.ObjectDeleteSync(accountName, containerName, objectName)
+						err = objectStore.ObjectDeleteSync(accountName, containerName, objectName)
 						if nil != err {
 							err = fmt.Errorf("failed to DELETE %v/%v/%v: %v", accountName, containerName, objectName, err)
 							return
@@ -153,7 +160,7 @@ func Format(mode Mode, volumeNameToFormat string, confFile string, confStrings [
 					err = fmt.Errorf("failed to GET %v/%v: %v", accountName, containerName, err)
 					return
 				}
-				err = swiftclient.ContainerDelete(accountName, containerName)
+				err = objectStore.ContainerDelete(accountName, containerName)
 				if nil != err {
 					err = fmt.Errorf("failed to DELETE %v/%v: %v", accountName, containerName, err)
 					return
@@ -1,4 +1,5 @@
-// The mkproxyfs program is the command line form invoking the mkproxyfs package's Format() function.
+// The mkproxyfs program is the command line form invoking the mkproxyfs package's Format() function
+// as well as its offline snapshot management functions.
 
 package main
 
@@ -6,6 +7,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/mkproxyfs"
 )
 
@@ -21,6 +23,12 @@ func usage() {
 	fmt.Println("      Note: VolumeNameToFormat need not be marked as active on any peer")
 	fmt.Println("  ConfFile specifies the .conf file as also passed to proxyfsd et. al.")
 	fmt.Println("  ConfFileOverrides is an optional list of modifications to ConfFile to apply")
+	fmt.Println("mkproxyfs -S|-D VolumeName SnapshotName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -S creates a snapshot named SnapshotName of VolumeName")
+	fmt.Println("   -D deletes the snapshot named SnapshotName of VolumeName")
+	fmt.Println("mkproxyfs -L VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -L lists the snapshots of VolumeName")
+	fmt.Println("      Note: VolumeName must not be served by any peer while its snapshots are managed this way")
 }
 
 func main() {
@@ -40,6 +48,8 @@ func main() {
 	}
 
 	switch os.Args[1] {
+	case "-S", "-D", "-L":
+		doSnapshot()
 	case "-N":
 		mode = mkproxyfs.ModeNew
 	case "-I":
@@ -59,3 +69,47 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func doSnapshot() {
+	var (
+		err          error
+		snapshotID   uint64
+		snapshotInfo headhunter.SnapshotInfo
+		snapshotList []headhunter.SnapshotInfo
+	)
+
+	switch os.Args[1] {
+	case "-S":
+		if 5 > len(os.Args) {
+			usage()
+			os.Exit(1)
+		}
+		snapshotID, err = mkproxyfs.CreateSnapshot(os.Args[2], os.Args[3], os.Args[4], os.Args[5:])
+		if nil != err {
+			fmt.Printf("mkproxyfs.CreateSnapshot() returned error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created snapshot \"%v\" (ID %v)\n", os.Args[3], snapshotID)
+	case "-D":
+		if 5 > len(os.Args) {
+			usage()
+			os.Exit(1)
+		}
+		err = mkproxyfs.DeleteSnapshot(os.Args[2], os.Args[3], os.Args[4], os.Args[5:])
+		if nil != err {
+			fmt.Printf("mkproxyfs.DeleteSnapshot() returned error: %v\n", err)
+			os.Exit(1)
+		}
+	case "-L":
+		snapshotList, err = mkproxyfs.ListSnapshots(os.Args[2], os.Args[3], os.Args[4:])
+		if nil != err {
+			fmt.Printf("mkproxyfs.ListSnapshots() returned error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, snapshotInfo = range snapshotList {
+			fmt.Printf("%v\t%v\t%v\n", snapshotInfo.ID, snapshotInfo.CreationTime, snapshotInfo.Name)
+		}
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,170 @@
+package mkproxyfs
+
+import (
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// The snapshot functions below operate directly on a volume's checkpoint. As such, they
+// must not be invoked while the volume is being served (e.g. by proxyfsd). For a volume
+// being served, use the httpserver's /volume/<VolumeName>/snapshot endpoints instead.
+
+// CreateSnapshot records the volume's most recent checkpoint as a snapshot named snapshotName.
+func CreateSnapshot(volumeName string, snapshotName string, confFile string, confStrings []string) (snapshotID uint64, err error) {
+	err = doWithVolume(volumeName, confFile, confStrings, func(accountName string, objectStore objectstore.ObjectStore, volumeHandle headhunter.VolumeHandle) (err error) {
+		snapshotID, err = volumeHandle.CreateSnapshot(snapshotName)
+		return
+	})
+
+	return
+}
+
+// ListSnapshots returns the volume's snapshots in creation order.
+func ListSnapshots(volumeName string, confFile string, confStrings []string) (snapshotList []headhunter.SnapshotInfo, err error) {
+	err = doWithVolume(volumeName, confFile, confStrings, func(accountName string, objectStore objectstore.ObjectStore, volumeHandle headhunter.VolumeHandle) (err error) {
+		snapshotList = volumeHandle.FetchSnapshotList()
+		err = nil
+		return
+	})
+
+	return
+}
+
+// DeleteSnapshot removes the snapshot named snapshotName along with every log segment no longer referenced.
+func DeleteSnapshot(volumeName string, snapshotName string, confFile string, confStrings []string) (err error) {
+	err = doWithVolume(volumeName, confFile, confStrings, func(accountName string, objectStore objectstore.ObjectStore, volumeHandle headhunter.VolumeHandle) (err error) {
+		var (
+			containerName           string
+			logSegmentNumber        uint64
+			logSegmentRec           []byte
+			objectName              string
+			unreferencedLogSegments map[uint64][]byte
+		)
+
+		unreferencedLogSegments, err = volumeHandle.DeleteSnapshot(snapshotName)
+		if nil != err {
+			return
+		}
+
+		for logSegmentNumber, logSegmentRec = range unreferencedLogSegments {
+			objectName = fmt.Sprintf("%016X", logSegmentNumber)
+			containerName, _, err = headhunter.DecodeLogSegmentRec(logSegmentRec)
+			if nil != err {
+				return
+			}
+			err = objectStore.ObjectDeleteSync(accountName, containerName, objectName)
+			if nil != err {
+				err = fmt.Errorf("failed to DELETE %v/%v/%v: %v", accountName, containerName, objectName, err)
+				return
+			}
+		}
+
+		err = nil
+		return
+	})
+
+	return
+}
+
+// doWithVolume brings up just enough of the system to invoke fn on the named volume's headhunter.VolumeHandle.
+func doWithVolume(volumeName string, confFile string, confStrings []string, fn func(accountName string, objectStore objectstore.ObjectStore, volumeHandle headhunter.VolumeHandle) (err error)) (err error) {
+	var (
+		accountName  string
+		confMap      conf.ConfMap
+		objectStore  objectstore.ObjectStore
+		volumeHandle headhunter.VolumeHandle
+	)
+
+	// Load confFile & confStrings (overrides)
+
+	confMap, err = conf.MakeConfMapFromFile(confFile)
+	if nil != err {
+		err = fmt.Errorf("failed to load config: %v", err)
+		return
+	}
+
+	err = confMap.UpdateFromStrings(confStrings)
+	if nil != err {
+		err = fmt.Errorf("failed to apply config overrides: %v", err)
+		return
+	}
+
+	// TODO: Remove call to utils.AdjustConfSectionNamespacingAsNecessary() when appropriate
+	err = utils.AdjustConfSectionNamespacingAsNecessary(confMap)
+	if nil != err {
+		err = fmt.Errorf("utils.AdjustConfSectionNamespacingAsNecessary() failed: %v", err)
+		return
+	}
+
+	accountName, err = confMap.FetchOptionValueString(utils.VolumeNameConfSection(volumeName), "AccountName")
+	if nil != err {
+		return
+	}
+
+	// Call Up() for required packages (deferring their Down() calls until function return)
+
+	err = logger.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = logger.Down()
+	}()
+
+	err = stats.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = stats.Down()
+	}()
+
+	err = dlm.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = dlm.Down()
+	}()
+
+	err = swiftclient.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = swiftclient.Down()
+	}()
+
+	objectStore, err = objectstore.FetchObjectStore(confMap, volumeName)
+	if nil != err {
+		return
+	}
+
+	err = headhunter.UpOneVolume(confMap, volumeName)
+	if nil != err {
+		return
+	}
+	defer func() {
+		downErr := headhunter.Down()
+		if nil == err {
+			err = downErr
+		}
+	}()
+
+	volumeHandle, err = headhunter.FetchVolumeHandle(volumeName)
+	if nil != err {
+		return
+	}
+
+	err = fn(accountName, objectStore, volumeHandle)
+
+	return
+}
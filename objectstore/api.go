@@ -0,0 +1,53 @@
+// Package objectstore abstracts the object storage operations performed on behalf of a volume
+// (by inode and headhunter) so that a volume may reside in Swift (the default), S3, or a local directory tree.
+//
+// The interface is expressed in Swift terms (accounts, containers, & objects). Each ObjectStore
+// implementation maps these onto its own storage model.
+package objectstore
+
+import (
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+// ChunkedPutContext is used to incrementally PUT an object whose size is not known in advance.
+//
+// Note that swiftclient.ChunkedPutContext satisfies this interface.
+type ChunkedPutContext interface {
+	BytesPut() (bytesPut uint64, err error)                    // Report how many bytes have been sent via SendChunk() for this ChunkedPutContext
+	Close() (err error)                                        // Finish the PUT for this ChunkedPutContext (with possible retry)
+	Read(offset uint64, length uint64) (buf []byte, err error) // Read back bytes previously sent via SendChunk()
+	SendChunk(buf []byte) (err error)                          // Send the supplied "chunk" via this ChunkedPutContext
+}
+
+// ObjectStore is the set of operations a volume performs against its object storage.
+//
+// Errors returned for a missing account, container, or object carry (via blunder.AddHTTPCode) a 404 HTTP code.
+// ObjectPutIfNoneMatch creates an object only if it does not already exist... if it does, the existing
+// object is left untouched and the returned error carries a 412 HTTP code.
+type ObjectStore interface {
+	AccountGet(accountName string) (headers map[string][]string, containerList []string, err error)
+	AccountPost(accountName string, headers map[string][]string) (err error)
+	ContainerDelete(accountName string, containerName string) (err error)
+	ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error)
+	ContainerHead(accountName string, containerName string) (headers map[string][]string, err error)
+	ContainerPost(accountName string, containerName string, headers map[string][]string) (err error)
+	ContainerPut(accountName string, containerName string, headers map[string][]string) (err error)
+	ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error)
+	ObjectDeleteAsync(accountName string, containerName string, objectName string, wgPreCondition *sync.WaitGroup, wgPostSignal *sync.WaitGroup)
+	ObjectDeleteSync(accountName string, containerName string, objectName string) (err error)
+	ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string) (chunkedPutContext ChunkedPutContext, err error)
+	ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error)
+	ObjectPutIfNoneMatch(accountName string, containerName string, objectName string, buf []byte) (err error)
+	ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error)
+}
+
+// FetchObjectStore returns the ObjectStore for volumeName.
+//
+// If [Volume:<volumeName>]ObjectStore is not specified, the volume resides in Swift (accessed via swiftclient).
+// Otherwise, the named [ObjectStore:<name>] section's Type (Swift, S3, or Local) selects the implementation.
+func FetchObjectStore(confMap conf.ConfMap, volumeName string) (objectStore ObjectStore, err error) {
+	objectStore, err = fetchObjectStore(confMap, volumeName)
+	return
+}
@@ -0,0 +1,437 @@
+package objectstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/rams3"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+func TestAPI(t *testing.T) {
+	localPath, err := ioutil.TempDir("", "objectstore_test")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(localPath)
+
+	confStrings := []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"SwiftClient.NoAuthTCPPort=45266",
+
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=1",
+		"SwiftClient.RetryLimitObject=1",
+		"SwiftClient.RetryDelay=10ms",
+		"SwiftClient.RetryDelayObject=10ms",
+		"SwiftClient.RetryExpBackoff=1.0",
+		"SwiftClient.RetryExpBackoffObject=1.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+
+		"Cluster.WhoAmI=Peer0",
+
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+
+		"FSGlobals.VolumeList=",
+
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+
+		"RamS3.TCPPort=45267",
+		"RamS3.AccessKeyID=TESTACCESSKEY",
+		"RamS3.MaxKeys=2",
+		"RamS3.BucketList=testbucket",
+
+		"Volume:SwiftVolume.AccountName=AUTH_swift",
+
+		"Volume:S3Volume.AccountName=AUTH_s3",
+		"Volume:S3Volume.ObjectStore=TestS3",
+
+		"ObjectStore:TestS3.Type=S3",
+		"ObjectStore:TestS3.Endpoint=http://127.0.0.1:45267",
+		"ObjectStore:TestS3.Bucket=testbucket",
+		"ObjectStore:TestS3.AccessKeyID=TESTACCESSKEY",
+		"ObjectStore:TestS3.SecretAccessKey=TESTSECRETKEY",
+		"ObjectStore:TestS3.PartSize=1024",
+		"ObjectStore:TestS3.RetryDelay=100ms",
+
+		"ObjectStore:BadS3.Type=S3",
+		"ObjectStore:BadS3.Endpoint=http://127.0.0.1:45267",
+		"ObjectStore:BadS3.Bucket=testbucket",
+		"ObjectStore:BadS3.AccessKeyID=WRONGACCESSKEY",
+		"ObjectStore:BadS3.SecretAccessKey=TESTSECRETKEY",
+		"ObjectStore:BadS3.RetryLimit=0",
+
+		"Volume:BadS3Volume.AccountName=AUTH_bad",
+		"Volume:BadS3Volume.ObjectStore=BadS3",
+
+		"Volume:LocalVolume.AccountName=AUTH_local",
+		"Volume:LocalVolume.ObjectStore=TestLocal",
+
+		"ObjectStore:TestLocal.Type=Local",
+		"ObjectStore:TestLocal.Path=" + localPath,
+
+		"Volume:BadTypeVolume.AccountName=AUTH_badtype",
+		"Volume:BadTypeVolume.ObjectStore=BadType",
+
+		"ObjectStore:BadType.Type=Tape",
+	}
+
+	confMap, err := conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) failed: %v", err)
+	}
+
+	err = logger.Up(confMap)
+	if nil != err {
+		t.Fatalf("logger.Up(confMap) failed: %v", err)
+	}
+
+	ramswiftSignalHandlerIsArmed := false
+	ramswiftDoneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go ramswift.Daemon("/dev/null", confStrings, &ramswiftSignalHandlerIsArmed, ramswiftDoneChan, unix.SIGTERM)
+
+	rams3SignalHandlerIsArmed := false
+	rams3DoneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go rams3.Daemon("/dev/null", confStrings, &rams3SignalHandlerIsArmed, rams3DoneChan, unix.SIGTERM)
+
+	for !ramswiftSignalHandlerIsArmed || !rams3SignalHandlerIsArmed {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	err = stats.Up(confMap)
+	if nil != err {
+		t.Fatalf("stats.Up(confMap) failed: %v", err)
+	}
+
+	err = swiftclient.Up(confMap)
+	if nil != err {
+		t.Fatalf("swiftclient.Up(confMap) failed: %v", err)
+	}
+
+	err = swiftclient.AccountPut("AUTH_swift", make(map[string][]string))
+	if nil != err {
+		t.Fatalf("swiftclient.AccountPut(\"AUTH_swift\") failed: %v", err)
+	}
+
+	// Verify the ObjectStore selected for each volume
+
+	swiftObjectStore, err := FetchObjectStore(confMap, "SwiftVolume")
+	if nil != err {
+		t.Fatalf("FetchObjectStore(,\"SwiftVolume\") failed: %v", err)
+	}
+	_, ok := swiftObjectStore.(*swiftObjectStoreStruct)
+	if !ok {
+		t.Fatalf("FetchObjectStore(,\"SwiftVolume\") should have returned a Swift ObjectStore")
+	}
+
+	s3ObjectStore, err := FetchObjectStore(confMap, "S3Volume")
+	if nil != err {
+		t.Fatalf("FetchObjectStore(,\"S3Volume\") failed: %v", err)
+	}
+	_, ok = s3ObjectStore.(*s3ObjectStoreStruct)
+	if !ok {
+		t.Fatalf("FetchObjectStore(,\"S3Volume\") should have returned an S3 ObjectStore")
+	}
+
+	localObjectStore, err := FetchObjectStore(confMap, "LocalVolume")
+	if nil != err {
+		t.Fatalf("FetchObjectStore(,\"LocalVolume\") failed: %v", err)
+	}
+	_, ok = localObjectStore.(*localObjectStoreStruct)
+	if !ok {
+		t.Fatalf("FetchObjectStore(,\"LocalVolume\") should have returned a Local ObjectStore")
+	}
+
+	_, err = FetchObjectStore(confMap, "BadTypeVolume")
+	if nil == err {
+		t.Fatalf("FetchObjectStore(,\"BadTypeVolume\") should have failed")
+	}
+
+	badS3ObjectStore, err := FetchObjectStore(confMap, "BadS3Volume")
+	if nil != err {
+		t.Fatalf("FetchObjectStore(,\"BadS3Volume\") failed: %v", err)
+	}
+	err = badS3ObjectStore.ContainerPut("AUTH_bad", "Container", make(map[string][]string))
+	if nil == err {
+		t.Fatalf("ContainerPut() using the wrong AccessKeyID should have failed")
+	}
+	if http.StatusForbidden != blunder.HTTPCode(err) {
+		t.Fatalf("ContainerPut() using the wrong AccessKeyID returned HTTP code %v (expected %v)", blunder.HTTPCode(err), http.StatusForbidden)
+	}
+
+	// Each ObjectStore must behave identically
+
+	testObjectStore(t, "Swift", swiftObjectStore, "AUTH_swift")
+	testObjectStore(t, "S3", s3ObjectStore, "AUTH_s3")
+	testObjectStore(t, "Local", localObjectStore, "AUTH_local")
+
+	// Shutdown packages
+
+	err = swiftclient.Down()
+	if nil != err {
+		t.Fatalf("swiftclient.Down() failed: %v", err)
+	}
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("stats.Down() failed: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("logger.Down() failed: %v", err)
+	}
+
+	// Send ourself a SIGTERM to terminate ramswift.Daemon() & rams3.Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-ramswiftDoneChan
+	_ = <-rams3DoneChan
+}
+
+func testObjectStore(t *testing.T, storeType string, objectStore ObjectStore, accountName string) {
+	var (
+		containerName = "TestContainer"
+		objectNames   = []string{"0000000000000001", "0000000000000002", "0000000000000003"}
+	)
+
+	// Containers & their headers
+
+	_, err := objectStore.ContainerHead(accountName, containerName)
+	if nil == err {
+		t.Fatalf("%v: ContainerHead() of missing container should have failed", storeType)
+	}
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("%v: ContainerHead() of missing container returned HTTP code %v (expected 404)", storeType, blunder.HTTPCode(err))
+	}
+
+	err = objectStore.ContainerPost(accountName, containerName, map[string][]string{"X-Container-Meta-Test": []string{"post"}})
+	if nil == err {
+		t.Fatalf("%v: ContainerPost() of missing container should have failed", storeType)
+	}
+
+	err = objectStore.ContainerPut(accountName, containerName, map[string][]string{"X-Container-Meta-Test": []string{"put"}, "X-Container-Meta-Other": []string{"other"}})
+	if nil != err {
+		t.Fatalf("%v: ContainerPut() failed: %v", storeType, err)
+	}
+
+	err = objectStore.ContainerPost(accountName, containerName, map[string][]string{"X-Container-Meta-Test": []string{"post"}})
+	if nil != err {
+		t.Fatalf("%v: ContainerPost() failed: %v", storeType, err)
+	}
+
+	headers, err := objectStore.ContainerHead(accountName, containerName)
+	if nil != err {
+		t.Fatalf("%v: ContainerHead() failed: %v", storeType, err)
+	}
+	if (1 != len(headers["X-Container-Meta-Test"])) || ("post" != headers["X-Container-Meta-Test"][0]) {
+		t.Fatalf("%v: ContainerHead() returned X-Container-Meta-Test: %v (expected \"post\")", storeType, headers["X-Container-Meta-Test"])
+	}
+	if (1 != len(headers["X-Container-Meta-Other"])) || ("other" != headers["X-Container-Meta-Other"][0]) {
+		t.Fatalf("%v: ContainerHead() returned X-Container-Meta-Other: %v (expected \"other\")", storeType, headers["X-Container-Meta-Other"])
+	}
+
+	err = objectStore.AccountPost(accountName, map[string][]string{"X-Account-Meta-Test": []string{"true"}})
+	if nil != err {
+		t.Fatalf("%v: AccountPost() failed: %v", storeType, err)
+	}
+
+	_, containerList, err := objectStore.AccountGet(accountName)
+	if nil != err {
+		t.Fatalf("%v: AccountGet() failed: %v", storeType, err)
+	}
+	if (1 != len(containerList)) || (containerName != containerList[0]) {
+		t.Fatalf("%v: AccountGet() returned containerList %v", storeType, containerList)
+	}
+
+	// Chunked PUTs both smaller & (for S3, many multiples of PartSize) larger than PartSize
+
+	expectedContents := make(map[string][]byte)
+
+	for i, objectName := range objectNames {
+		contents := make([]byte, 0)
+
+		chunkedPutContext, err := objectStore.ObjectFetchChunkedPutContext(accountName, containerName, objectName)
+		if nil != err {
+			t.Fatalf("%v: ObjectFetchChunkedPutContext(,,\"%v\") failed: %v", storeType, objectName, err)
+		}
+
+		for chunkIndex := 0; chunkIndex < (1 + (i * 10)); chunkIndex++ {
+			chunk := bytes.Repeat([]byte{byte(i), byte(chunkIndex)}, 150)
+			err = chunkedPutContext.SendChunk(chunk)
+			if nil != err {
+				t.Fatalf("%v: SendChunk() to \"%v\" failed: %v", storeType, objectName, err)
+			}
+			contents = append(contents, chunk...)
+		}
+
+		bytesPut, err := chunkedPutContext.BytesPut()
+		if nil != err {
+			t.Fatalf("%v: BytesPut() failed: %v", storeType, err)
+		}
+		if uint64(len(contents)) != bytesPut {
+			t.Fatalf("%v: BytesPut() returned %v (expected %v)", storeType, bytesPut, len(contents))
+		}
+
+		readBuf, err := chunkedPutContext.Read(uint64(len(contents)-10), 10)
+		if nil != err {
+			t.Fatalf("%v: Read() failed: %v", storeType, err)
+		}
+		if !bytes.Equal(contents[len(contents)-10:], readBuf) {
+			t.Fatalf("%v: Read() returned wrong data", storeType)
+		}
+
+		err = chunkedPutContext.Close()
+		if nil != err {
+			t.Fatalf("%v: Close() of \"%v\" failed: %v", storeType, objectName, err)
+		}
+
+		expectedContents[objectName] = contents
+	}
+
+	_, objectList, err := objectStore.ContainerGet(accountName, containerName)
+	if nil != err {
+		t.Fatalf("%v: ContainerGet() failed: %v", storeType, err)
+	}
+	if len(objectNames) != len(objectList) {
+		t.Fatalf("%v: ContainerGet() returned objectList %v (expected %v)", storeType, objectList, objectNames)
+	}
+	for i, objectName := range objectNames {
+		if objectName != objectList[i] {
+			t.Fatalf("%v: ContainerGet() returned objectList %v (expected %v)", storeType, objectList, objectNames)
+		}
+	}
+
+	for _, objectName := range objectNames {
+		contents := expectedContents[objectName]
+
+		length, err := objectStore.ObjectContentLength(accountName, containerName, objectName)
+		if nil != err {
+			t.Fatalf("%v: ObjectContentLength(,,\"%v\") failed: %v", storeType, objectName, err)
+		}
+		if uint64(len(contents)) != length {
+			t.Fatalf("%v: ObjectContentLength(,,\"%v\") returned %v (expected %v)", storeType, objectName, length, len(contents))
+		}
+
+		buf, err := objectStore.ObjectGet(accountName, containerName, objectName, 1, uint64(len(contents)-2))
+		if nil != err {
+			t.Fatalf("%v: ObjectGet(,,\"%v\") failed: %v", storeType, objectName, err)
+		}
+		if !bytes.Equal(contents[1:len(contents)-1], buf) {
+			t.Fatalf("%v: ObjectGet(,,\"%v\") returned wrong data", storeType, objectName)
+		}
+
+		buf, err = objectStore.ObjectTail(accountName, containerName, objectName, 17)
+		if nil != err {
+			t.Fatalf("%v: ObjectTail(,,\"%v\") failed: %v", storeType, objectName, err)
+		}
+		if !bytes.Equal(contents[len(contents)-17:], buf) {
+			t.Fatalf("%v: ObjectTail(,,\"%v\") returned wrong data", storeType, objectName)
+		}
+	}
+
+	// Conditional creates
+
+	exclusiveObjectName := "0000000000000004"
+
+	err = objectStore.ObjectPutIfNoneMatch(accountName, containerName, exclusiveObjectName, []byte("first"))
+	if nil != err {
+		t.Fatalf("%v: ObjectPutIfNoneMatch() of missing object failed: %v", storeType, err)
+	}
+	err = objectStore.ObjectPutIfNoneMatch(accountName, containerName, exclusiveObjectName, []byte("second"))
+	if nil == err {
+		t.Fatalf("%v: ObjectPutIfNoneMatch() of existing object should have failed", storeType)
+	}
+	if http.StatusPreconditionFailed != blunder.HTTPCode(err) {
+		t.Fatalf("%v: ObjectPutIfNoneMatch() of existing object returned HTTP code %v (expected 412)", storeType, blunder.HTTPCode(err))
+	}
+
+	buf, err := objectStore.ObjectGet(accountName, containerName, exclusiveObjectName, 0, uint64(len("first")))
+	if nil != err {
+		t.Fatalf("%v: ObjectGet() following ObjectPutIfNoneMatch() failed: %v", storeType, err)
+	}
+	if "first" != string(buf) {
+		t.Fatalf("%v: ObjectPutIfNoneMatch() of existing object replaced its contents with \"%v\"", storeType, string(buf))
+	}
+
+	err = objectStore.ObjectDeleteSync(accountName, containerName, exclusiveObjectName)
+	if nil != err {
+		t.Fatalf("%v: ObjectDeleteSync() of conditionally created object failed: %v", storeType, err)
+	}
+
+	// Deletes
+
+	err = objectStore.ObjectDeleteSync(accountName, containerName, objectNames[0])
+	if nil != err {
+		t.Fatalf("%v: ObjectDeleteSync() failed: %v", storeType, err)
+	}
+
+	wgPreCondition := &sync.WaitGroup{}
+	wgPreCondition.Add(1)
+	wgPostSignal := &sync.WaitGroup{}
+	wgPostSignal.Add(2)
+
+	objectStore.ObjectDeleteAsync(accountName, containerName, objectNames[1], wgPreCondition, wgPostSignal)
+	objectStore.ObjectDeleteAsync(accountName, containerName, objectNames[2], wgPreCondition, wgPostSignal)
+
+	_, err = objectStore.ObjectContentLength(accountName, containerName, objectNames[1])
+	if nil != err {
+		t.Fatalf("%v: ObjectDeleteAsync() should have awaited wgPreCondition", storeType)
+	}
+
+	wgPreCondition.Done()
+	wgPostSignal.Wait()
+
+	for _, objectName := range objectNames {
+		_, err = objectStore.ObjectContentLength(accountName, containerName, objectName)
+		if nil == err {
+			t.Fatalf("%v: ObjectContentLength(,,\"%v\") after delete should have failed", storeType, objectName)
+		}
+		if http.StatusNotFound != blunder.HTTPCode(err) {
+			t.Fatalf("%v: ObjectContentLength(,,\"%v\") after delete returned HTTP code %v (expected 404)", storeType, objectName, blunder.HTTPCode(err))
+		}
+	}
+
+	_, objectList, err = objectStore.ContainerGet(accountName, containerName)
+	if nil != err {
+		t.Fatalf("%v: ContainerGet() after deletes failed: %v", storeType, err)
+	}
+	if 0 != len(objectList) {
+		t.Fatalf("%v: ContainerGet() after deletes returned objectList %v", storeType, objectList)
+	}
+
+	err = objectStore.ContainerDelete(accountName, containerName)
+	if nil != err {
+		t.Fatalf("%v: ContainerDelete() failed: %v", storeType, err)
+	}
+
+	_, containerList, err = objectStore.AccountGet(accountName)
+	if nil != err {
+		t.Fatalf("%v: AccountGet() after ContainerDelete() failed: %v", storeType, err)
+	}
+	if 0 != len(containerList) {
+		t.Fatalf("%v: AccountGet() after ContainerDelete() returned containerList %v", storeType, containerList)
+	}
+}
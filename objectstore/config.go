@@ -0,0 +1,141 @@
+package objectstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/s3client"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+const (
+	objectStoreTypeSwift = "Swift"
+	objectStoreTypeS3    = "S3"
+	objectStoreTypeLocal = "Local"
+
+	s3DefaultRegion     = "us-east-1"
+	s3DefaultPartSize   = uint64(5 * 1024 * 1024) // the smallest (non-final) part S3 accepts
+	s3DefaultTimeout    = 10 * time.Second
+	s3DefaultRetryLimit = uint16(5)
+	s3DefaultRetryDelay = 1 * time.Second
+)
+
+func fetchObjectStore(confMap conf.ConfMap, volumeName string) (objectStore ObjectStore, err error) {
+	var (
+		objectStoreName        string
+		objectStoreSectionName string
+		objectStoreType        string
+		volumeSectionName      string
+	)
+
+	volumeSectionName = utils.VolumeNameConfSection(volumeName)
+
+	objectStoreName, err = confMap.FetchOptionValueString(volumeSectionName, "ObjectStore")
+	if (nil != err) || ("" == objectStoreName) {
+		objectStore = &swiftObjectStoreStruct{} // TODO: Eventually, just return
+		err = nil
+		return
+	}
+
+	objectStoreSectionName = utils.ObjectStoreNameConfSection(objectStoreName)
+
+	objectStoreType, err = confMap.FetchOptionValueString(objectStoreSectionName, "Type")
+	if nil != err {
+		return
+	}
+
+	switch objectStoreType {
+	case objectStoreTypeSwift:
+		objectStore = &swiftObjectStoreStruct{}
+		err = nil
+	case objectStoreTypeS3:
+		objectStore, err = fetchS3ObjectStore(confMap, objectStoreName, objectStoreSectionName)
+	case objectStoreTypeLocal:
+		objectStore, err = fetchLocalObjectStore(confMap, objectStoreName, objectStoreSectionName)
+	default:
+		err = fmt.Errorf("%v.Type must be one of %v, %v, or %v (got \"%v\")", objectStoreSectionName, objectStoreTypeSwift, objectStoreTypeS3, objectStoreTypeLocal, objectStoreType)
+	}
+
+	return
+}
+
+func fetchS3ObjectStore(confMap conf.ConfMap, objectStoreName string, objectStoreSectionName string) (objectStore *s3ObjectStoreStruct, err error) {
+	var (
+		config s3client.Config
+	)
+
+	objectStore = &s3ObjectStoreStruct{name: objectStoreName}
+
+	config.Endpoint, err = confMap.FetchOptionValueString(objectStoreSectionName, "Endpoint")
+	if nil != err {
+		return
+	}
+
+	config.Region, err = confMap.FetchOptionValueString(objectStoreSectionName, "Region")
+	if nil != err {
+		config.Region = s3DefaultRegion // TODO: Eventually, just return
+	}
+
+	config.AccessKeyID, err = confMap.FetchOptionValueString(objectStoreSectionName, "AccessKeyID")
+	if nil != err {
+		return
+	}
+
+	config.SecretAccessKey, err = confMap.FetchOptionValueString(objectStoreSectionName, "SecretAccessKey")
+	if nil != err {
+		return
+	}
+
+	config.Timeout, err = confMap.FetchOptionValueDuration(objectStoreSectionName, "Timeout")
+	if nil != err {
+		config.Timeout = s3DefaultTimeout // TODO: Eventually, just return
+	}
+
+	config.RetryLimit, err = confMap.FetchOptionValueUint16(objectStoreSectionName, "RetryLimit")
+	if nil != err {
+		config.RetryLimit = s3DefaultRetryLimit // TODO: Eventually, just return
+	}
+
+	config.RetryDelay, err = confMap.FetchOptionValueDuration(objectStoreSectionName, "RetryDelay")
+	if nil != err {
+		config.RetryDelay = s3DefaultRetryDelay // TODO: Eventually, just return
+	}
+
+	objectStore.bucket, err = confMap.FetchOptionValueString(objectStoreSectionName, "Bucket")
+	if nil != err {
+		return
+	}
+	if "" == objectStore.bucket {
+		err = fmt.Errorf("%v.Bucket must not be empty", objectStoreSectionName)
+		return
+	}
+
+	objectStore.partSize, err = confMap.FetchOptionValueUint64(objectStoreSectionName, "PartSize")
+	if nil != err {
+		objectStore.partSize = s3DefaultPartSize // TODO: Eventually, just return
+	}
+	if 0 == objectStore.partSize {
+		err = fmt.Errorf("%v.PartSize must be non-zero", objectStoreSectionName)
+		return
+	}
+
+	objectStore.client, err = s3client.NewClient(config)
+
+	return
+}
+
+func fetchLocalObjectStore(confMap conf.ConfMap, objectStoreName string, objectStoreSectionName string) (objectStore *localObjectStoreStruct, err error) {
+	path, err := confMap.FetchOptionValueString(objectStoreSectionName, "Path")
+	if nil != err {
+		return
+	}
+	if "" == path {
+		err = fmt.Errorf("%v.Path must not be empty", objectStoreSectionName)
+		return
+	}
+
+	objectStore, err = lookupLocalObjectStore(objectStoreName, path)
+
+	return
+}
@@ -0,0 +1,722 @@
+package objectstore
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/logger"
+)
+
+// localObjectStoreStruct maps Swift's accounts, containers, & objects onto a local directory tree rooted at path:
+//
+//   <account>/headers                                   the account's headers (absent until AccountPost())
+//   <account>/containers/<container>/headers            the container's headers (its existence is the container's)
+//   <account>/containers/<container>/objects/<object>   an object
+//   <account>/containers/<container>/pending/           objects being written via a ChunkedPutContext (or ObjectPutIfNoneMatch())
+//
+// Headers are held as JSON. Every file is fsync()'d (and renamed into place, followed by an fsync() of its
+// directory) before the operation writing it returns, so the contents of a volume survive a restart.
+type localObjectStoreStruct struct {
+	sync.Mutex //                   serializes the read-modify-write of headers files & container creation/deletion
+	name       string
+	path       string
+}
+
+// localChunkedPutContextStruct appends each chunk to a file in the container's pending directory...
+// which Close() renames into the container's objects directory.
+type localChunkedPutContextStruct struct {
+	sync.Mutex
+	objectStore *localObjectStoreStruct
+	file        *os.File
+	objectPath  string
+	bytesPut    uint64
+	active      bool
+}
+
+const (
+	localHeadersFileName   = "headers"
+	localContainersDirName = "containers"
+	localObjectsDirName    = "objects"
+	localPendingDirName    = "pending"
+)
+
+var (
+	localObjectStoreMapMutex sync.Mutex
+	localObjectStoreMap      = make(map[string]*localObjectStoreStruct) // key == path... shared so that all volume users serialize alike
+)
+
+func lookupLocalObjectStore(name string, path string) (objectStore *localObjectStoreStruct, err error) {
+	path, err = filepath.Abs(path)
+	if nil != err {
+		return
+	}
+
+	localObjectStoreMapMutex.Lock()
+	defer localObjectStoreMapMutex.Unlock()
+
+	objectStore, ok := localObjectStoreMap[path]
+	if ok {
+		err = nil
+		return
+	}
+
+	err = os.MkdirAll(path, 0700)
+	if nil != err {
+		return
+	}
+
+	objectStore = &localObjectStoreStruct{name: name, path: path}
+
+	localObjectStoreMap[path] = objectStore
+
+	err = nil
+	return
+}
+
+// checkName ensures name may be used as a single path component
+func checkName(kind string, name string) (err error) {
+	if ("" == name) || ("." == name) || (".." == name) || strings.ContainsAny(name, "/\x00") {
+		err = blunder.NewError(blunder.InvalidArgError, "%s name \"%s\" not supported by local ObjectStore", kind, name)
+		err = blunder.AddHTTPCode(err, http.StatusBadRequest)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (objectStore *localObjectStoreStruct) accountPath(accountName string) (path string, err error) {
+	err = checkName("account", accountName)
+	if nil != err {
+		return
+	}
+
+	path = filepath.Join(objectStore.path, accountName)
+	return
+}
+
+func (objectStore *localObjectStoreStruct) containerPath(accountName string, containerName string) (path string, err error) {
+	accountPath, err := objectStore.accountPath(accountName)
+	if nil != err {
+		return
+	}
+
+	err = checkName("container", containerName)
+	if nil != err {
+		return
+	}
+
+	path = filepath.Join(accountPath, localContainersDirName, containerName)
+	return
+}
+
+func (objectStore *localObjectStoreStruct) objectPath(accountName string, containerName string, objectName string) (path string, err error) {
+	containerPath, err := objectStore.containerPath(accountName, containerName)
+	if nil != err {
+		return
+	}
+
+	err = checkName("object", objectName)
+	if nil != err {
+		return
+	}
+
+	path = filepath.Join(containerPath, localObjectsDirName, objectName)
+	return
+}
+
+// localError converts an error returned by package os into the blunder (and HTTP code) a Swift request would have produced
+func localError(err error, method string, path string) error {
+	if os.IsNotExist(err) {
+		err = blunder.NewError(blunder.NotFoundError, "%s %s not found", method, path)
+		return blunder.AddHTTPCode(err, http.StatusNotFound)
+	}
+
+	switch method {
+	case "DELETE":
+		err = blunder.NewError(blunder.BadHTTPDeleteError, "%s %s failed: %v", method, path, err)
+	case "GET":
+		err = blunder.NewError(blunder.BadHTTPGetError, "%s %s failed: %v", method, path, err)
+	case "HEAD":
+		err = blunder.NewError(blunder.BadHTTPHeadError, "%s %s failed: %v", method, path, err)
+	default:
+		err = blunder.NewError(blunder.BadHTTPPutError, "%s %s failed: %v", method, path, err)
+	}
+
+	return blunder.AddHTTPCode(err, http.StatusInternalServerError)
+}
+
+// syncDir fsync()'s the directory at path so that entries created in (or removed from) it are durable
+func syncDir(path string) (err error) {
+	dir, err := os.Open(path)
+	if nil != err {
+		return
+	}
+
+	err = dir.Sync()
+	if nil != err {
+		_ = dir.Close()
+		return
+	}
+
+	err = dir.Close()
+	return
+}
+
+// writeFileDurably replaces the file at path with buf such that either the old or new contents survive a crash
+func writeFileDurably(path string, buf []byte) (err error) {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if nil != err {
+		return
+	}
+
+	tempPath := file.Name()
+
+	_, err = file.Write(buf)
+	if nil == err {
+		err = file.Sync()
+	}
+	if nil != err {
+		_ = file.Close()
+		_ = os.Remove(tempPath)
+		return
+	}
+
+	err = file.Close()
+	if nil != err {
+		_ = os.Remove(tempPath)
+		return
+	}
+
+	err = os.Rename(tempPath, path)
+	if nil != err {
+		_ = os.Remove(tempPath)
+		return
+	}
+
+	err = syncDir(filepath.Dir(path))
+	return
+}
+
+func readHeaders(path string) (headers map[string][]string, err error) {
+	buf, err := ioutil.ReadFile(path)
+	if nil != err {
+		return
+	}
+
+	headers = make(map[string][]string)
+
+	err = json.Unmarshal(buf, &headers)
+	return
+}
+
+// mergeHeaders replaces (or adds) headers in the headers file at path... which must already exist
+// unless mustExist is false. Caller must hold objectStore.Mutex.
+func mergeHeaders(path string, headers map[string][]string, mustExist bool) (err error) {
+	mergedHeaders, err := readHeaders(path)
+	if nil != err {
+		if mustExist || !os.IsNotExist(err) {
+			return
+		}
+		mergedHeaders = make(map[string][]string)
+	}
+
+	for name, values := range headers {
+		mergedHeaders[http.CanonicalHeaderKey(name)] = values
+	}
+
+	buf, err := json.Marshal(mergedHeaders)
+	if nil != err {
+		return
+	}
+
+	err = writeFileDurably(path, buf)
+	return
+}
+
+// listDir returns the (sorted) names of the entries in the directory at path
+func listDir(path string) (nameList []string, err error) {
+	fileInfoList, err := ioutil.ReadDir(path)
+	if nil != err {
+		return
+	}
+
+	nameList = make([]string, 0, len(fileInfoList))
+	for _, fileInfo := range fileInfoList {
+		nameList = append(nameList, fileInfo.Name())
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+	accountPath, err := objectStore.accountPath(accountName)
+	if nil != err {
+		return
+	}
+
+	headers, err = readHeaders(filepath.Join(accountPath, localHeadersFileName))
+	if nil != err {
+		if !os.IsNotExist(err) {
+			err = localError(err, "GET", accountName)
+			return
+		}
+		headers = make(map[string][]string) // accounts need not have a headers file
+	}
+
+	containerList = make([]string, 0)
+
+	containerDirList, err := listDir(filepath.Join(accountPath, localContainersDirName))
+	if nil != err {
+		if !os.IsNotExist(err) {
+			err = localError(err, "GET", accountName)
+			return
+		}
+		containerDirList = make([]string, 0)
+	}
+
+	// Skip directories of containers whose ContainerPut() or ContainerDelete() was interrupted
+
+	for _, containerName := range containerDirList {
+		_, err = os.Stat(filepath.Join(accountPath, localContainersDirName, containerName, localHeadersFileName))
+		if nil == err {
+			containerList = append(containerList, containerName)
+		}
+	}
+
+	err = nil
+	return
+}
+
+func (objectStore *localObjectStoreStruct) AccountPost(accountName string, headers map[string][]string) (err error) {
+	accountPath, err := objectStore.accountPath(accountName)
+	if nil != err {
+		return
+	}
+
+	objectStore.Lock()
+	defer objectStore.Unlock()
+
+	err = os.MkdirAll(accountPath, 0700)
+	if nil == err {
+		err = mergeHeaders(filepath.Join(accountPath, localHeadersFileName), headers, false)
+	}
+	if nil != err {
+		err = localError(err, "POST", accountName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ContainerDelete(accountName string, containerName string) (err error) {
+	containerPath, err := objectStore.containerPath(accountName, containerName)
+	if nil != err {
+		return
+	}
+
+	objectStore.Lock()
+	defer objectStore.Unlock()
+
+	_, err = os.Stat(filepath.Join(containerPath, localHeadersFileName))
+	if nil != err {
+		err = localError(err, "DELETE", accountName+"/"+containerName)
+		return
+	}
+
+	objectList, err := listDir(filepath.Join(containerPath, localObjectsDirName))
+	if nil != err {
+		err = localError(err, "DELETE", accountName+"/"+containerName)
+		return
+	}
+	if 0 != len(objectList) {
+		err = blunder.NewError(blunder.NotEmptyError, "DELETE %s/%s found container non-empty", accountName, containerName)
+		err = blunder.AddHTTPCode(err, http.StatusConflict)
+		return
+	}
+
+	// Removing the headers file first ensures a crash mid-RemoveAll() leaves no (partial) container
+
+	err = os.Remove(filepath.Join(containerPath, localHeadersFileName))
+	if nil == err {
+		err = syncDir(containerPath)
+	}
+	if nil == err {
+		err = os.RemoveAll(containerPath)
+	}
+	if nil == err {
+		err = syncDir(filepath.Dir(containerPath))
+	}
+	if nil != err {
+		err = localError(err, "DELETE", accountName+"/"+containerName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	containerPath, err := objectStore.containerPath(accountName, containerName)
+	if nil != err {
+		return
+	}
+
+	headers, err = readHeaders(filepath.Join(containerPath, localHeadersFileName))
+	if nil == err {
+		objectList, err = listDir(filepath.Join(containerPath, localObjectsDirName))
+	}
+	if nil != err {
+		err = localError(err, "GET", accountName+"/"+containerName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+	containerPath, err := objectStore.containerPath(accountName, containerName)
+	if nil != err {
+		return
+	}
+
+	headers, err = readHeaders(filepath.Join(containerPath, localHeadersFileName))
+	if nil != err {
+		err = localError(err, "HEAD", accountName+"/"+containerName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
+	containerPath, err := objectStore.containerPath(accountName, containerName)
+	if nil != err {
+		return
+	}
+
+	objectStore.Lock()
+	err = mergeHeaders(filepath.Join(containerPath, localHeadersFileName), headers, true)
+	objectStore.Unlock()
+
+	if nil != err {
+		err = localError(err, "POST", accountName+"/"+containerName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
+	containerPath, err := objectStore.containerPath(accountName, containerName)
+	if nil != err {
+		return
+	}
+
+	objectStore.Lock()
+	defer objectStore.Unlock()
+
+	// The headers file is written last as its existence is that of the container
+
+	err = os.MkdirAll(filepath.Join(containerPath, localObjectsDirName), 0700)
+	if nil == err {
+		err = os.MkdirAll(filepath.Join(containerPath, localPendingDirName), 0700)
+	}
+	if nil == err {
+		err = syncDir(filepath.Dir(containerPath))
+	}
+	if nil == err {
+		err = syncDir(containerPath)
+	}
+	if nil == err {
+		err = mergeHeaders(filepath.Join(containerPath, localHeadersFileName), headers, false)
+	}
+	if nil != err {
+		err = localError(err, "PUT", accountName+"/"+containerName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	objectPath, err := objectStore.objectPath(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	fileInfo, err := os.Stat(objectPath)
+	if nil != err {
+		err = localError(err, "HEAD", accountName+"/"+containerName+"/"+objectName)
+		return
+	}
+
+	length = uint64(fileInfo.Size())
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ObjectDeleteAsync(accountName string, containerName string, objectName string, wgPreCondition *sync.WaitGroup, wgPostSignal *sync.WaitGroup) {
+	go func() {
+		if nil != wgPreCondition {
+			wgPreCondition.Wait()
+		}
+
+		err := objectStore.ObjectDeleteSync(accountName, containerName, objectName)
+		if nil != err {
+			logger.ErrorfWithError(err, "objectstore.ObjectDeleteAsync(\"%v/%v/%v\") on %v failed", accountName, containerName, objectName, objectStore.name)
+		}
+
+		if nil != wgPostSignal {
+			wgPostSignal.Done()
+		}
+	}()
+}
+
+func (objectStore *localObjectStoreStruct) ObjectDeleteSync(accountName string, containerName string, objectName string) (err error) {
+	objectPath, err := objectStore.objectPath(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	err = os.Remove(objectPath)
+	if nil == err {
+		err = syncDir(filepath.Dir(objectPath))
+	}
+	if nil != err {
+		err = localError(err, "DELETE", accountName+"/"+containerName+"/"+objectName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string) (chunkedPutContext ChunkedPutContext, err error) {
+	objectPath, err := objectStore.objectPath(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	pendingDirPath := filepath.Join(filepath.Dir(filepath.Dir(objectPath)), localPendingDirName)
+
+	file, err := ioutil.TempFile(pendingDirPath, objectName+".")
+	if nil != err {
+		err = localError(err, "PUT", accountName+"/"+containerName+"/"+objectName)
+		return
+	}
+
+	chunkedPutContext = &localChunkedPutContextStruct{
+		objectStore: objectStore,
+		file:        file,
+		objectPath:  objectPath,
+		bytesPut:    0,
+		active:      true,
+	}
+
+	err = nil
+	return
+}
+
+// readRange returns up to length bytes starting at offset of the object at objectPath... if length == 0,
+// the final tailLength bytes (or the entire object if shorter) are returned instead
+func readRange(objectPath string, offset uint64, length uint64, tailLength uint64) (buf []byte, err error) {
+	file, err := os.Open(objectPath)
+	if nil != err {
+		return
+	}
+	defer file.Close()
+
+	if 0 == length {
+		fileInfo, statErr := file.Stat()
+		if nil != statErr {
+			err = statErr
+			return
+		}
+		length = tailLength
+		if length > uint64(fileInfo.Size()) {
+			length = uint64(fileInfo.Size())
+		}
+		offset = uint64(fileInfo.Size()) - length
+	}
+
+	buf = make([]byte, length)
+
+	n, err := file.ReadAt(buf, int64(offset))
+	if io.EOF == err {
+		if (0 == n) && (0 < length) {
+			err = blunder.NewError(blunder.OutOfRangeError, "GET %s offset %v beyond EOF", objectPath, offset)
+			err = blunder.AddHTTPCode(err, http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		err = nil
+	}
+
+	buf = buf[:n]
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	objectPath, err := objectStore.objectPath(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	if 0 == length {
+		buf = make([]byte, 0)
+		return
+	}
+
+	buf, err = readRange(objectPath, offset, length, 0)
+	if (nil != err) && (http.StatusRequestedRangeNotSatisfiable != blunder.HTTPCode(err)) {
+		err = localError(err, "GET", accountName+"/"+containerName+"/"+objectName)
+	}
+
+	return
+}
+
+// ObjectPutIfNoneMatch durably writes buf to a pending file and then link()'s it into place... as link()
+// refuses to replace an existing file, at most one of any number of concurrent callers will succeed
+func (objectStore *localObjectStoreStruct) ObjectPutIfNoneMatch(accountName string, containerName string, objectName string, buf []byte) (err error) {
+	objectPath, err := objectStore.objectPath(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	pendingDirPath := filepath.Join(filepath.Dir(filepath.Dir(objectPath)), localPendingDirName)
+
+	file, err := ioutil.TempFile(pendingDirPath, objectName+".")
+	if nil != err {
+		err = localError(err, "PUT", accountName+"/"+containerName+"/"+objectName)
+		return
+	}
+
+	pendingPath := file.Name()
+
+	_, err = file.Write(buf)
+	if nil == err {
+		err = file.Sync()
+	}
+	if nil != err {
+		_ = file.Close()
+	} else {
+		err = file.Close()
+	}
+	if nil == err {
+		err = os.Link(pendingPath, objectPath)
+		if os.IsExist(err) {
+			_ = os.Remove(pendingPath)
+			err = blunder.NewError(blunder.FileExistsError, "PUT %s/%s/%s precondition failed: object exists", accountName, containerName, objectName)
+			err = blunder.AddHTTPCode(err, http.StatusPreconditionFailed)
+			return
+		}
+	}
+	if nil == err {
+		err = syncDir(filepath.Dir(objectPath))
+	}
+	_ = os.Remove(pendingPath)
+	if nil != err {
+		err = localError(err, "PUT", accountName+"/"+containerName+"/"+objectName)
+	}
+
+	return
+}
+
+func (objectStore *localObjectStoreStruct) ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	objectPath, err := objectStore.objectPath(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	if 0 == length {
+		buf = make([]byte, 0)
+		return
+	}
+
+	buf, err = readRange(objectPath, 0, 0, length)
+	if nil != err {
+		err = localError(err, "GET", accountName+"/"+containerName+"/"+objectName)
+	}
+
+	return
+}
+
+func (chunkedPutContext *localChunkedPutContextStruct) BytesPut() (bytesPut uint64, err error) {
+	chunkedPutContext.Lock()
+	bytesPut = chunkedPutContext.bytesPut
+	chunkedPutContext.Unlock()
+	err = nil
+	return
+}
+
+func (chunkedPutContext *localChunkedPutContextStruct) SendChunk(buf []byte) (err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if !chunkedPutContext.active {
+		err = blunder.NewError(blunder.BadHTTPPutError, "called while inactive")
+		logger.PanicfWithError(err, "objectstore.localChunkedPutContext.SendChunk(\"%v\") called while inactive", chunkedPutContext.objectPath)
+		return
+	}
+
+	_, err = chunkedPutContext.file.Write(buf)
+	if nil != err {
+		err = localError(err, "PUT", chunkedPutContext.objectPath)
+		return
+	}
+
+	chunkedPutContext.bytesPut += uint64(len(buf))
+
+	err = nil
+	return
+}
+
+func (chunkedPutContext *localChunkedPutContextStruct) Close() (err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if !chunkedPutContext.active {
+		err = blunder.NewError(blunder.BadHTTPPutError, "called while inactive")
+		logger.PanicfWithError(err, "objectstore.localChunkedPutContext.Close(\"%v\") called while inactive", chunkedPutContext.objectPath)
+		return
+	}
+
+	chunkedPutContext.active = false
+
+	pendingPath := chunkedPutContext.file.Name()
+
+	err = chunkedPutContext.file.Sync()
+	if nil != err {
+		_ = chunkedPutContext.file.Close()
+	} else {
+		err = chunkedPutContext.file.Close()
+	}
+	if nil == err {
+		err = os.Rename(pendingPath, chunkedPutContext.objectPath)
+	}
+	if nil == err {
+		err = syncDir(filepath.Dir(chunkedPutContext.objectPath))
+	}
+	if nil != err {
+		_ = os.Remove(pendingPath)
+		err = localError(err, "PUT", chunkedPutContext.objectPath)
+	}
+
+	return
+}
+
+func (chunkedPutContext *localChunkedPutContextStruct) Read(offset uint64, length uint64) (buf []byte, err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if (offset + length) > chunkedPutContext.bytesPut {
+		err = blunder.NewError(blunder.BadHTTPGetError, "offset + length (%v + %v) > bytes sent (%v)", offset, length, chunkedPutContext.bytesPut)
+		return
+	}
+
+	buf = make([]byte, length)
+
+	_, err = chunkedPutContext.file.ReadAt(buf, int64(offset))
+	if nil != err {
+		err = localError(err, "GET", chunkedPutContext.file.Name())
+	}
+
+	return
+}
@@ -0,0 +1,138 @@
+package objectstore
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestLocalRestart(t *testing.T) {
+	localPath, err := ioutil.TempDir("", "objectstore_local_test")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(localPath)
+
+	objectStore, err := lookupLocalObjectStore("TestLocal", localPath)
+	if nil != err {
+		t.Fatalf("lookupLocalObjectStore() failed: %v", err)
+	}
+
+	sameObjectStore, err := lookupLocalObjectStore("TestLocal", filepath.Join(localPath, "."))
+	if nil != err {
+		t.Fatalf("lookupLocalObjectStore() of same path failed: %v", err)
+	}
+	if objectStore != sameObjectStore {
+		t.Fatalf("lookupLocalObjectStore() of same path should have returned the same localObjectStoreStruct")
+	}
+
+	for _, badName := range []string{"", ".", "..", "a/b"} {
+		err = objectStore.ContainerPut("AUTH_test", badName, make(map[string][]string))
+		if http.StatusBadRequest != blunder.HTTPCode(err) {
+			t.Fatalf("ContainerPut() of container named \"%v\" should have returned 400 but returned: %v", badName, err)
+		}
+	}
+
+	err = objectStore.ContainerPut("AUTH_test", ".__checkpoint__", map[string][]string{"X-Container-Meta-Checkpoint": []string{"0000000000000001"}})
+	if nil != err {
+		t.Fatalf("ContainerPut() failed: %v", err)
+	}
+
+	chunkedPutContext, err := objectStore.ObjectFetchChunkedPutContext("AUTH_test", ".__checkpoint__", "0000000000000002")
+	if nil != err {
+		t.Fatalf("ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk([]byte("0123"))
+	if nil != err {
+		t.Fatalf("SendChunk() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk([]byte("456789"))
+	if nil != err {
+		t.Fatalf("SendChunk() failed: %v", err)
+	}
+
+	_, err = objectStore.ObjectContentLength("AUTH_test", ".__checkpoint__", "0000000000000002")
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("ObjectContentLength() before Close() should have returned 404 but returned: %v", err)
+	}
+
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	err = objectStore.ContainerPost("AUTH_test", ".__checkpoint__", map[string][]string{"X-Container-Meta-Checkpoint": []string{"0000000000000003"}})
+	if nil != err {
+		t.Fatalf("ContainerPost() failed: %v", err)
+	}
+
+	// Simulate a restart by forgetting the localObjectStoreStruct
+
+	localObjectStoreMapMutex.Lock()
+	delete(localObjectStoreMap, objectStore.path)
+	localObjectStoreMapMutex.Unlock()
+
+	objectStore, err = lookupLocalObjectStore("TestLocal", localPath)
+	if nil != err {
+		t.Fatalf("lookupLocalObjectStore() after restart failed: %v", err)
+	}
+	if objectStore == sameObjectStore {
+		t.Fatalf("lookupLocalObjectStore() after restart should have returned a new localObjectStoreStruct")
+	}
+
+	headers, objectList, err := objectStore.ContainerGet("AUTH_test", ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("ContainerGet() after restart failed: %v", err)
+	}
+	if (1 != len(headers["X-Container-Meta-Checkpoint"])) || ("0000000000000003" != headers["X-Container-Meta-Checkpoint"][0]) {
+		t.Fatalf("ContainerGet() after restart returned X-Container-Meta-Checkpoint: %v", headers["X-Container-Meta-Checkpoint"])
+	}
+	if (1 != len(objectList)) || ("0000000000000002" != objectList[0]) {
+		t.Fatalf("ContainerGet() after restart returned objectList %v", objectList)
+	}
+
+	buf, err := objectStore.ObjectGet("AUTH_test", ".__checkpoint__", "0000000000000002", 8, 5)
+	if nil != err {
+		t.Fatalf("ObjectGet() spanning EOF failed: %v", err)
+	}
+	if "89" != string(buf) {
+		t.Fatalf("ObjectGet() spanning EOF returned \"%v\" (expected \"89\")", string(buf))
+	}
+
+	_, err = objectStore.ObjectGet("AUTH_test", ".__checkpoint__", "0000000000000002", 10, 1)
+	if http.StatusRequestedRangeNotSatisfiable != blunder.HTTPCode(err) {
+		t.Fatalf("ObjectGet() beyond EOF should have returned 416 but returned: %v", err)
+	}
+
+	buf, err = objectStore.ObjectTail("AUTH_test", ".__checkpoint__", "0000000000000002", 100)
+	if nil != err {
+		t.Fatalf("ObjectTail() longer than object failed: %v", err)
+	}
+	if "0123456789" != string(buf) {
+		t.Fatalf("ObjectTail() longer than object returned \"%v\"", string(buf))
+	}
+
+	// A container directory lacking its headers file (e.g. from an interrupted ContainerPut()) is not listed
+
+	err = os.MkdirAll(filepath.Join(localPath, "AUTH_test", localContainersDirName, "Interrupted", localObjectsDirName), 0700)
+	if nil != err {
+		t.Fatalf("os.MkdirAll() failed: %v", err)
+	}
+
+	_, containerList, err := objectStore.AccountGet("AUTH_test")
+	if nil != err {
+		t.Fatalf("AccountGet() failed: %v", err)
+	}
+	if (1 != len(containerList)) || (".__checkpoint__" != containerList[0]) {
+		t.Fatalf("AccountGet() returned containerList %v", containerList)
+	}
+
+	err = objectStore.ContainerDelete("AUTH_test", ".__checkpoint__")
+	if http.StatusConflict != blunder.HTTPCode(err) {
+		t.Fatalf("ContainerDelete() of non-empty container should have returned 409 but returned: %v", err)
+	}
+}
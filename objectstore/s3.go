@@ -0,0 +1,394 @@
+package objectstore
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/s3client"
+)
+
+// s3ObjectStoreStruct maps Swift's accounts, containers, & objects onto keys in a single S3 bucket:
+//
+//   <account>/                      marker object holding the account's headers (absent until AccountPost())
+//   <account>/<container>/          marker object holding the container's headers (its existence is the container's)
+//   <account>/<container>/<object>  an object
+//
+// Headers are held as x-amz-meta-* metadata of the marker objects (with multiple values joined by ",").
+type s3ObjectStoreStruct struct {
+	sync.Mutex //                   serializes the read-modify-write of marker objects
+	name       string
+	client     *s3client.Client
+	bucket     string
+	partSize   uint64 //            bytes buffered by a ChunkedPutContext before each multipart upload part is sent
+}
+
+// s3ChunkedPutContextStruct retains all bytes sent (so that Read() may be serviced) while uploading them
+// as parts of a multipart upload. If Close() finds no part has been uploaded, a simple PUT is performed.
+type s3ChunkedPutContextStruct struct {
+	sync.Mutex
+	objectStore   *s3ObjectStoreStruct
+	key           string
+	buf           []byte
+	bytesUploaded uint64 //          leading bytes of buf already uploaded as parts
+	uploadID      string //          == "" until the multipart upload is initiated
+	parts         []s3client.Part
+	err           error //           if != nil, a part upload failed... so Close() will abort the upload
+	active        bool
+}
+
+const amzMetaHeaderPrefix = "X-Amz-Meta-"
+
+func (objectStore *s3ObjectStoreStruct) accountKey(accountName string) (key string) {
+	key = accountName + "/"
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) containerKey(accountName string, containerName string) (key string) {
+	key = accountName + "/" + containerName + "/"
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) objectKey(accountName string, containerName string, objectName string) (key string) {
+	key = accountName + "/" + containerName + "/" + objectName
+	return
+}
+
+// fetchMarkerHeaders returns the headers held by the marker object at key
+func (objectStore *s3ObjectStoreStruct) fetchMarkerHeaders(key string) (headers map[string][]string, err error) {
+	s3Headers, err := objectStore.client.HeadObject(objectStore.bucket, key)
+	if nil != err {
+		return
+	}
+
+	headers = make(map[string][]string)
+
+	for name, values := range s3Headers {
+		if strings.HasPrefix(name, amzMetaHeaderPrefix) && (0 < len(values)) {
+			headers[http.CanonicalHeaderKey(strings.TrimPrefix(name, amzMetaHeaderPrefix))] = []string{values[0]}
+		}
+	}
+
+	err = nil
+	return
+}
+
+// mergeMarkerHeaders replaces (or adds) headers in the marker object at key... which must
+// already exist unless mustExist is false. Caller must hold objectStore.Mutex.
+func (objectStore *s3ObjectStoreStruct) mergeMarkerHeaders(key string, headers map[string][]string, mustExist bool) (err error) {
+	mergedHeaders, err := objectStore.fetchMarkerHeaders(key)
+	if nil != err {
+		if mustExist || (http.StatusNotFound != blunder.HTTPCode(err)) {
+			return
+		}
+		mergedHeaders = make(map[string][]string)
+	}
+
+	for name, values := range headers {
+		mergedHeaders[http.CanonicalHeaderKey(name)] = values
+	}
+
+	metadata := make(map[string]string)
+	for name, values := range mergedHeaders {
+		metadata[name] = strings.Join(values, ",")
+	}
+
+	err = objectStore.client.PutObject(objectStore.bucket, key, metadata, []byte{})
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+	headers, err = objectStore.fetchMarkerHeaders(objectStore.accountKey(accountName))
+	if nil != err {
+		if http.StatusNotFound != blunder.HTTPCode(err) {
+			return
+		}
+		headers = make(map[string][]string) // accounts need not have a marker object
+	}
+
+	_, commonPrefixes, err := objectStore.client.ListObjects(objectStore.bucket, objectStore.accountKey(accountName), "/")
+	if nil != err {
+		return
+	}
+
+	containerList = make([]string, 0, len(commonPrefixes))
+	for _, commonPrefix := range commonPrefixes {
+		containerList = append(containerList, strings.TrimSuffix(strings.TrimPrefix(commonPrefix, objectStore.accountKey(accountName)), "/"))
+	}
+
+	err = nil
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) AccountPost(accountName string, headers map[string][]string) (err error) {
+	objectStore.Lock()
+	err = objectStore.mergeMarkerHeaders(objectStore.accountKey(accountName), headers, false)
+	objectStore.Unlock()
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ContainerDelete(accountName string, containerName string) (err error) {
+	containerKey := objectStore.containerKey(accountName, containerName)
+
+	objectStore.Lock()
+	defer objectStore.Unlock()
+
+	_, err = objectStore.client.HeadObject(objectStore.bucket, containerKey)
+	if nil != err {
+		return
+	}
+
+	keys, _, err := objectStore.client.ListObjects(objectStore.bucket, containerKey, "")
+	if nil != err {
+		return
+	}
+	for _, key := range keys {
+		if containerKey != key {
+			err = blunder.NewError(blunder.NotEmptyError, "DELETE %s/%s found container non-empty", accountName, containerName)
+			err = blunder.AddHTTPCode(err, http.StatusConflict)
+			return
+		}
+	}
+
+	err = objectStore.client.DeleteObject(objectStore.bucket, containerKey)
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	containerKey := objectStore.containerKey(accountName, containerName)
+
+	headers, err = objectStore.fetchMarkerHeaders(containerKey)
+	if nil != err {
+		return
+	}
+
+	keys, _, err := objectStore.client.ListObjects(objectStore.bucket, containerKey, "")
+	if nil != err {
+		return
+	}
+
+	objectList = make([]string, 0, len(keys))
+	for _, key := range keys {
+		if containerKey != key {
+			objectList = append(objectList, strings.TrimPrefix(key, containerKey))
+		}
+	}
+
+	err = nil
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+	headers, err = objectStore.fetchMarkerHeaders(objectStore.containerKey(accountName, containerName))
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
+	objectStore.Lock()
+	err = objectStore.mergeMarkerHeaders(objectStore.containerKey(accountName, containerName), headers, true)
+	objectStore.Unlock()
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
+	objectStore.Lock()
+	err = objectStore.mergeMarkerHeaders(objectStore.containerKey(accountName, containerName), headers, false)
+	objectStore.Unlock()
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	headers, err := objectStore.client.HeadObject(objectStore.bucket, objectStore.objectKey(accountName, containerName, objectName))
+	if nil != err {
+		return
+	}
+
+	length, err = strconv.ParseUint(headers.Get("Content-Length"), 10, 64)
+	if nil != err {
+		err = blunder.NewError(blunder.BadHTTPHeadError, "HEAD %s/%s/%s returned bad Content-Length \"%v\"", accountName, containerName, objectName, headers.Get("Content-Length"))
+		return
+	}
+
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectDeleteAsync(accountName string, containerName string, objectName string, wgPreCondition *sync.WaitGroup, wgPostSignal *sync.WaitGroup) {
+	go func() {
+		if nil != wgPreCondition {
+			wgPreCondition.Wait()
+		}
+
+		err := objectStore.ObjectDeleteSync(accountName, containerName, objectName)
+		if nil != err {
+			logger.ErrorfWithError(err, "objectstore.ObjectDeleteAsync(\"%v/%v/%v\") on %v failed", accountName, containerName, objectName, objectStore.name)
+		}
+
+		if nil != wgPostSignal {
+			wgPostSignal.Done()
+		}
+	}()
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectDeleteSync(accountName string, containerName string, objectName string) (err error) {
+	err = objectStore.client.DeleteObject(objectStore.bucket, objectStore.objectKey(accountName, containerName, objectName))
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string) (chunkedPutContext ChunkedPutContext, err error) {
+	chunkedPutContext = &s3ChunkedPutContextStruct{
+		objectStore: objectStore,
+		key:         objectStore.objectKey(accountName, containerName, objectName),
+		buf:         make([]byte, 0),
+		parts:       make([]s3client.Part, 0),
+		active:      true,
+	}
+
+	err = nil
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	byteRange := "bytes=" + strconv.FormatUint(offset, 10) + "-" + strconv.FormatUint((offset+length-1), 10)
+
+	buf, err = objectStore.client.GetObject(objectStore.bucket, objectStore.objectKey(accountName, containerName, objectName), byteRange)
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectPutIfNoneMatch(accountName string, containerName string, objectName string, buf []byte) (err error) {
+	err = objectStore.client.PutObjectIfNoneMatch(objectStore.bucket, objectStore.objectKey(accountName, containerName, objectName), buf)
+	return
+}
+
+func (objectStore *s3ObjectStoreStruct) ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	byteRange := "bytes=-" + strconv.FormatUint(length, 10)
+
+	buf, err = objectStore.client.GetObject(objectStore.bucket, objectStore.objectKey(accountName, containerName, objectName), byteRange)
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) BytesPut() (bytesPut uint64, err error) {
+	chunkedPutContext.Lock()
+	bytesPut = uint64(len(chunkedPutContext.buf))
+	chunkedPutContext.Unlock()
+	err = nil
+	return
+}
+
+// uploadPart uploads buf[bytesUploaded:bytesUploaded+partLength] as the next part... initiating the multipart upload if
+// necessary. Caller must hold chunkedPutContext.Mutex.
+func (chunkedPutContext *s3ChunkedPutContextStruct) uploadPart(partLength uint64) (err error) {
+	var (
+		eTag string
+	)
+
+	objectStore := chunkedPutContext.objectStore
+
+	if "" == chunkedPutContext.uploadID {
+		chunkedPutContext.uploadID, err = objectStore.client.CreateMultipartUpload(objectStore.bucket, chunkedPutContext.key)
+		if nil != err {
+			return
+		}
+	}
+
+	partNumber := uint16(len(chunkedPutContext.parts) + 1)
+	partBuf := chunkedPutContext.buf[chunkedPutContext.bytesUploaded : chunkedPutContext.bytesUploaded+partLength]
+
+	eTag, err = objectStore.client.UploadPart(objectStore.bucket, chunkedPutContext.key, chunkedPutContext.uploadID, partNumber, partBuf)
+	if nil != err {
+		return
+	}
+
+	chunkedPutContext.parts = append(chunkedPutContext.parts, s3client.Part{PartNumber: partNumber, ETag: eTag})
+	chunkedPutContext.bytesUploaded += partLength
+
+	err = nil
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) SendChunk(buf []byte) (err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if !chunkedPutContext.active {
+		err = blunder.NewError(blunder.BadHTTPPutError, "called while inactive")
+		logger.PanicfWithError(err, "objectstore.s3ChunkedPutContext.SendChunk(\"%v\") called while inactive", chunkedPutContext.key)
+		return
+	}
+
+	if nil != chunkedPutContext.err {
+		err = chunkedPutContext.err
+		return
+	}
+
+	chunkedPutContext.buf = append(chunkedPutContext.buf, buf...)
+
+	for (uint64(len(chunkedPutContext.buf)) - chunkedPutContext.bytesUploaded) >= chunkedPutContext.objectStore.partSize {
+		err = chunkedPutContext.uploadPart(chunkedPutContext.objectStore.partSize)
+		if nil != err {
+			chunkedPutContext.err = err
+			logger.ErrorfWithError(err, "objectstore.s3ChunkedPutContext.SendChunk(\"%v\") part upload failed", chunkedPutContext.key)
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) Close() (err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if !chunkedPutContext.active {
+		err = blunder.NewError(blunder.BadHTTPPutError, "called while inactive")
+		logger.PanicfWithError(err, "objectstore.s3ChunkedPutContext.Close(\"%v\") called while inactive", chunkedPutContext.key)
+		return
+	}
+
+	chunkedPutContext.active = false
+
+	objectStore := chunkedPutContext.objectStore
+
+	if (nil == chunkedPutContext.err) && ("" == chunkedPutContext.uploadID) {
+		// Never reached PartSize... so just PUT the object
+
+		err = objectStore.client.PutObject(objectStore.bucket, chunkedPutContext.key, nil, chunkedPutContext.buf)
+		return
+	}
+
+	err = chunkedPutContext.err
+	if (nil == err) && (uint64(len(chunkedPutContext.buf)) > chunkedPutContext.bytesUploaded) {
+		err = chunkedPutContext.uploadPart(uint64(len(chunkedPutContext.buf)) - chunkedPutContext.bytesUploaded)
+	}
+	if nil == err {
+		err = objectStore.client.CompleteMultipartUpload(objectStore.bucket, chunkedPutContext.key, chunkedPutContext.uploadID, chunkedPutContext.parts)
+	}
+
+	if (nil != err) && ("" != chunkedPutContext.uploadID) {
+		abortErr := objectStore.client.AbortMultipartUpload(objectStore.bucket, chunkedPutContext.key, chunkedPutContext.uploadID)
+		if nil != abortErr {
+			logger.WarnfWithError(abortErr, "objectstore.s3ChunkedPutContext.Close(\"%v\") unable to abort multipart upload %v", chunkedPutContext.key, chunkedPutContext.uploadID)
+		}
+	}
+
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) Read(offset uint64, length uint64) (buf []byte, err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if (offset + length) > uint64(len(chunkedPutContext.buf)) {
+		err = blunder.NewError(blunder.BadHTTPGetError, "offset + length (%v + %v) > bytes sent (%v)", offset, length, len(chunkedPutContext.buf))
+		return
+	}
+
+	buf = make([]byte, length)
+	copy(buf, chunkedPutContext.buf[offset:offset+length])
+
+	err = nil
+	return
+}
@@ -0,0 +1,83 @@
+package objectstore
+
+import (
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+// swiftObjectStoreStruct simply forwards to the package-level funcs of swiftclient (which must be Up)
+type swiftObjectStoreStruct struct{}
+
+func (objectStore *swiftObjectStoreStruct) AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+	headers, containerList, err = swiftclient.AccountGet(accountName)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) AccountPost(accountName string, headers map[string][]string) (err error) {
+	err = swiftclient.AccountPost(accountName, headers)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ContainerDelete(accountName string, containerName string) (err error) {
+	err = swiftclient.ContainerDelete(accountName, containerName)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	headers, objectList, err = swiftclient.ContainerGet(accountName, containerName)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+	headers, err = swiftclient.ContainerHead(accountName, containerName)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
+	err = swiftclient.ContainerPost(accountName, containerName, headers)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
+	err = swiftclient.ContainerPut(accountName, containerName, headers)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	length, err = swiftclient.ObjectContentLength(accountName, containerName, objectName)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectDeleteAsync(accountName string, containerName string, objectName string, wgPreCondition *sync.WaitGroup, wgPostSignal *sync.WaitGroup) {
+	swiftclient.ObjectDeleteAsync(accountName, containerName, objectName, wgPreCondition, wgPostSignal)
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectDeleteSync(accountName string, containerName string, objectName string) (err error) {
+	err = swiftclient.ObjectDeleteSync(accountName, containerName, objectName)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string) (chunkedPutContext ChunkedPutContext, err error) {
+	swiftChunkedPutContext, err := swiftclient.ObjectFetchChunkedPutContext(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+	chunkedPutContext = swiftChunkedPutContext
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	buf, err = swiftclient.ObjectGet(accountName, containerName, objectName, offset, length)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectPutIfNoneMatch(accountName string, containerName string, objectName string, buf []byte) (err error) {
+	err = swiftclient.ObjectPutIfNoneMatch(accountName, containerName, objectName, buf)
+	return
+}
+
+func (objectStore *swiftObjectStoreStruct) ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	buf, err = swiftclient.ObjectTail(accountName, containerName, objectName, length)
+	return
+}
@@ -753,6 +753,12 @@ class PfsMiddleware(object):
                 headers={"Content-Type": "text/plain"},
                 body="RPC timeout: {0}".format(err))
         except utils.RpcError as err:
+            if err.errno == pfs_errno.QuotaExceededError:
+                # Not exceptional; the volume is simply full
+                return swob.HTTPRequestEntityTooLarge(
+                    request=req,
+                    headers={"Content-Type": "text/plain"},
+                    body="Volume quota exceeded")
             self.logger.error(
                 "RPC error: %s; consulting proxyfsd logs may be helpful", err)
             return swob.HTTPInternalServerError(
@@ -29,6 +29,7 @@ errorcode = {
     21: "IsDirError",
     31: "TooManyLinksError",
     39: "NotEmptyError",
+    122: "QuotaExceededError",
 }
 
 g = globals()
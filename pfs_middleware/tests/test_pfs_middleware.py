@@ -2766,6 +2766,27 @@ class TestObjectPut(BaseMiddlewareTest):
         status, headers, body = self.call_pfs(req)
         self.assertEqual(status, '409 Conflict')
 
+    def test_quota_exceeded(self):
+        # If the volume is over quota, we get an error that the middleware
+        # turns into a 413 Request Entity Too Large response.
+        wsgi_input = StringIO("hypocrateriform-dipnoan")
+        cl = str(len(wsgi_input.getvalue()))
+
+        def mock_RpcPutComplete_quota(put_complete_req):
+            return {
+                "error": "errno: 122",  # EDQUOT
+                "result": None}
+
+        self.fake_rpc.register_handler(
+            "Server.RpcPutComplete", mock_RpcPutComplete_quota)
+
+        req = swob.Request.blank("/v1/AUTH_test/a-container/thing.txt",
+                                 environ={"REQUEST_METHOD": "PUT",
+                                          "wsgi.input": wsgi_input,
+                                          "CONTENT_LENGTH": cl})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '413 Request Entity Too Large')
+
     def test_stripping_bad_headers(self):
         # Someday, we'll have to figure out how to expire objects in
         # proxyfs. For now, though, we remove X-Delete-At and X-Delete-After
@@ -0,0 +1,355 @@
+// The pfsinspect program decodes a volume's checkpoint (and the inodes it references) directly from Swift
+// for diagnostic purposes. As nothing is ever modified, it may be run even while the volume is being served
+// (though only what has been checkpointed will be seen).
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+func usage() {
+	fmt.Println("pfsinspect -?")
+	fmt.Println("   Prints this help text")
+	fmt.Println("pfsinspect -C VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -C prints the checkpoint header & record (B+Tree roots & layouts, OwnerStats, and snapshots)")
+	fmt.Println("pfsinspect -L VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -L lists every inode (InodeNumber, InodeType, LinkCount, Size, and Mode)")
+	fmt.Println("pfsinspect -I InodeNumber VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -I prints the inode's on-disk fields along with its directory entries or file extent map")
+	fmt.Println("pfsinspect -P Path VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -P walks Path from the root directory and then behaves as -I for the inode found")
+	fmt.Println("pfsinspect -X InodeNumber|Path VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -X prints the file's extent map & LogSegmentMap (a Path must begin with \"/\")")
+	fmt.Println("pfsinspect -J VolumeName ConfFile [ConfFileOverrides]*")
+	fmt.Println("   -J exports the checkpoint and every inode as a single JSON document")
+	fmt.Println("  VolumeName indicates which Volume in ConfFile is to be inspected")
+	fmt.Println("  ConfFile specifies the .conf file as also passed to proxyfsd et. al.")
+	fmt.Println("  ConfFileOverrides is an optional list of modifications to ConfFile to apply")
+	fmt.Println("  InodeNumber may be given in decimal or (prefixed by \"0x\") hexadecimal")
+}
+
+func main() {
+	var (
+		argIndex int
+		err      error
+		fn       func(checkpointReport *headhunter.CheckpointReport, inspectionHandle inode.InspectionHandle) (err error)
+	)
+
+	if (2 == len(os.Args)) && ("-?" == os.Args[1]) {
+		usage()
+		os.Exit(0)
+	}
+
+	if 4 > len(os.Args) {
+		usage()
+		os.Exit(1)
+	}
+
+	argIndex = 2
+
+	switch os.Args[1] {
+	case "-C":
+		fn = doCheckpoint
+	case "-L":
+		fn = doList
+	case "-J":
+		fn = doExport
+	case "-I", "-P", "-X":
+		if 5 > len(os.Args) {
+			usage()
+			os.Exit(1)
+		}
+		argIndex = 3
+		fn = func(checkpointReport *headhunter.CheckpointReport, inspectionHandle inode.InspectionHandle) (err error) {
+			err = doInode(os.Args[1], os.Args[2], inspectionHandle)
+			return
+		}
+	default:
+		usage()
+		os.Exit(1)
+	}
+
+	err = doWithVolume(os.Args[argIndex], os.Args[argIndex+1], os.Args[argIndex+2:], fn)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "pfsinspect %v failed: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+// doWithVolume brings up just enough of the system to decode the named volume's checkpoint before invoking fn.
+func doWithVolume(volumeName string, confFile string, confStrings []string, fn func(checkpointReport *headhunter.CheckpointReport, inspectionHandle inode.InspectionHandle) (err error)) (err error) {
+	var (
+		checkpointReport       *headhunter.CheckpointReport
+		confMap                conf.ConfMap
+		headhunterVolumeHandle headhunter.VolumeHandle
+		inspectionHandle       inode.InspectionHandle
+	)
+
+	confMap, err = conf.MakeConfMapFromFile(confFile)
+	if nil != err {
+		err = fmt.Errorf("failed to load config: %v", err)
+		return
+	}
+
+	err = confMap.UpdateFromStrings(confStrings)
+	if nil != err {
+		err = fmt.Errorf("failed to apply config overrides: %v", err)
+		return
+	}
+
+	// TODO: Remove call to utils.AdjustConfSectionNamespacingAsNecessary() when appropriate
+	err = utils.AdjustConfSectionNamespacingAsNecessary(confMap)
+	if nil != err {
+		err = fmt.Errorf("utils.AdjustConfSectionNamespacingAsNecessary() failed: %v", err)
+		return
+	}
+
+	// Call Up() for required packages (deferring their Down() calls until function return)
+
+	err = logger.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = logger.Down()
+	}()
+
+	err = stats.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = stats.Down()
+	}()
+
+	err = swiftclient.Up(confMap)
+	if nil != err {
+		return
+	}
+	defer func() {
+		_ = swiftclient.Down()
+	}()
+
+	headhunterVolumeHandle, checkpointReport, err = headhunter.OpenVolumeReadOnly(confMap, volumeName)
+	if nil != err {
+		return
+	}
+
+	inspectionHandle, err = inode.FetchInspectionHandle(volumeName, headhunterVolumeHandle)
+	if nil != err {
+		return
+	}
+
+	err = fn(checkpointReport, inspectionHandle)
+
+	return
+}
+
+func printJSON(v interface{}) (err error) {
+	var (
+		vAsJSON []byte
+	)
+
+	vAsJSON, err = json.MarshalIndent(v, "", "\t")
+	if nil != err {
+		return
+	}
+
+	fmt.Printf("%s\n", vAsJSON)
+
+	err = nil
+	return
+}
+
+func doCheckpoint(checkpointReport *headhunter.CheckpointReport, inspectionHandle inode.InspectionHandle) (err error) {
+	err = printJSON(checkpointReport)
+	return
+}
+
+func doList(checkpointReport *headhunter.CheckpointReport, inspectionHandle inode.InspectionHandle) (err error) {
+	var (
+		inodeNumber inode.InodeNumber
+		inodeReport *inode.InodeReport
+		ok          bool
+	)
+
+	fmt.Printf("%-18s %-7s %9s %20s %7s\n", "InodeNumber", "Type", "LinkCount", "Size", "Mode")
+
+	inodeNumber = 0
+
+	for {
+		inodeNumber, ok, err = inspectionHandle.NextInodeNumber(inodeNumber)
+		if nil != err {
+			return
+		}
+		if !ok {
+			err = nil
+			return
+		}
+
+		inodeReport, err = inspectionHandle.InspectInode(inodeNumber)
+		if nil != err {
+			fmt.Printf("0x%016X %v\n", uint64(inodeNumber), err)
+			continue
+		}
+
+		fmt.Printf("0x%016X %-7s %9d %20d %07o\n", uint64(inodeNumber), inodeTypeToString(inodeReport.InodeType), inodeReport.LinkCount, inodeReport.Size, inodeReport.Mode)
+	}
+}
+
+func doInode(mode string, inodeNumberOrPath string, inspectionHandle inode.InspectionHandle) (err error) {
+	var (
+		fileExtent          inode.InodeReportFileExtent
+		inodeNumber         inode.InodeNumber
+		inodeNumberAsUint64 uint64
+		inodeReport         *inode.InodeReport
+		logSegmentBytes     uint64
+		logSegmentNumber    uint64
+		logSegmentNumbers   []uint64
+	)
+
+	if ("-P" == mode) || (("-X" == mode) && (0 < len(inodeNumberOrPath)) && ('/' == inodeNumberOrPath[0])) {
+		inodeNumber, err = inspectionHandle.ResolvePath(inodeNumberOrPath)
+		if nil != err {
+			return
+		}
+	} else {
+		inodeNumberAsUint64, err = strconv.ParseUint(inodeNumberOrPath, 0, 64)
+		if nil != err {
+			err = fmt.Errorf("unable to parse InodeNumber \"%v\": %v", inodeNumberOrPath, err)
+			return
+		}
+		inodeNumber = inode.InodeNumber(inodeNumberAsUint64)
+	}
+
+	inodeReport, err = inspectionHandle.InspectInode(inodeNumber)
+	if nil != err {
+		return
+	}
+
+	if "-X" != mode {
+		err = printJSON(inodeReport)
+		return
+	}
+
+	if inode.FileType != inodeReport.InodeType {
+		err = fmt.Errorf("inode 0x%016X is a %v (not a file)", uint64(inodeNumber), inodeTypeToString(inodeReport.InodeType))
+		return
+	}
+
+	fmt.Printf("ExtentMap of inode 0x%016X (Size %d):\n", uint64(inodeNumber), inodeReport.Size)
+	fmt.Printf("  %-18s %-18s %-18s %-18s\n", "FileOffset", "Length", "LogSegmentNumber", "LogSegmentOffset")
+
+	for _, fileExtent = range inodeReport.FileExtents {
+		fmt.Printf("  0x%016X 0x%016X 0x%016X 0x%016X\n", fileExtent.FileOffset, fileExtent.Length, fileExtent.LogSegmentNumber, fileExtent.LogSegmentOffset)
+	}
+
+	logSegmentNumbers = make([]uint64, 0, len(inodeReport.LogSegmentMap))
+	for logSegmentNumber = range inodeReport.LogSegmentMap {
+		logSegmentNumbers = append(logSegmentNumbers, logSegmentNumber)
+	}
+	sort.Slice(logSegmentNumbers, func(i int, j int) bool { return logSegmentNumbers[i] < logSegmentNumbers[j] })
+
+	fmt.Printf("LogSegmentMap of inode 0x%016X:\n", uint64(inodeNumber))
+	fmt.Printf("  %-18s %-18s\n", "LogSegmentNumber", "BytesReferenced")
+
+	for _, logSegmentNumber = range logSegmentNumbers {
+		logSegmentBytes = inodeReport.LogSegmentMap[logSegmentNumber]
+		fmt.Printf("  0x%016X 0x%016X\n", logSegmentNumber, logSegmentBytes)
+	}
+
+	err = nil
+	return
+}
+
+// doExport streams a single JSON object of the form {"Checkpoint":<CheckpointReport>,"Inodes":[<InodeReport>,...]}
+// so as not to hold every inode in memory at once. Inodes that cannot be decoded are reported in an "Errors" array.
+func doExport(checkpointReport *headhunter.CheckpointReport, inspectionHandle inode.InspectionHandle) (err error) {
+	var (
+		errorStrings []string
+		firstInode   bool
+		inodeNumber  inode.InodeNumber
+		inodeReport  *inode.InodeReport
+		ok           bool
+		vAsJSON      []byte
+	)
+
+	vAsJSON, err = json.Marshal(checkpointReport)
+	if nil != err {
+		return
+	}
+
+	fmt.Printf("{\"Checkpoint\":%s,\"Inodes\":[", vAsJSON)
+
+	errorStrings = make([]string, 0)
+
+	firstInode = true
+	inodeNumber = 0
+
+	for {
+		inodeNumber, ok, err = inspectionHandle.NextInodeNumber(inodeNumber)
+		if nil != err {
+			return
+		}
+		if !ok {
+			break
+		}
+
+		inodeReport, err = inspectionHandle.InspectInode(inodeNumber)
+		if nil != err {
+			errorStrings = append(errorStrings, fmt.Sprintf("inode 0x%016X: %v", uint64(inodeNumber), err))
+			continue
+		}
+
+		vAsJSON, err = json.Marshal(inodeReport)
+		if nil != err {
+			return
+		}
+
+		if firstInode {
+			fmt.Printf("%s", vAsJSON)
+			firstInode = false
+		} else {
+			fmt.Printf(",%s", vAsJSON)
+		}
+	}
+
+	vAsJSON, err = json.Marshal(errorStrings)
+	if nil != err {
+		return
+	}
+
+	fmt.Printf("],\"Errors\":%s}\n", vAsJSON)
+
+	err = nil
+	return
+}
+
+func inodeTypeToString(inodeType inode.InodeType) (inodeTypeAsString string) {
+	switch inodeType {
+	case inode.DirType:
+		inodeTypeAsString = "dir"
+	case inode.FileType:
+		inodeTypeAsString = "file"
+	case inode.SymlinkType:
+		inodeTypeAsString = "symlink"
+	default:
+		inodeTypeAsString = fmt.Sprintf("0x%04X", uint16(inodeType))
+	}
+	return
+}
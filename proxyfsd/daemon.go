@@ -10,6 +10,7 @@ import (
 
 	"github.com/pkg/profile"
 
+	"github.com/swiftstack/ProxyFS/cluster"
 	"github.com/swiftstack/ProxyFS/conf"
 	"github.com/swiftstack/ProxyFS/dlm"
 	"github.com/swiftstack/ProxyFS/fs"
@@ -29,6 +30,7 @@ func Daemon(confFile string, confStrings []string, signalHandlerIsArmed *bool, e
 	var (
 		confMap        conf.ConfMap
 		err            error
+		failoverChan   chan struct{}
 		signalReceived os.Signal
 	)
 
@@ -111,51 +113,81 @@ func Daemon(confFile string, confStrings []string, signalHandlerIsArmed *bool, e
 		wg.Done()
 	}()
 
-	err = dlm.Up(confMap)
+	err = swiftclient.Up(confMap)
 	if nil != err {
-		logger.Errorf("dlm.Up() failed: %v", err)
+		logger.Errorf("swiftclient.Up() failed: %v", err)
 		errChan <- err
 		return
 	}
 	wg.Add(1)
 	defer func() {
-		err = dlm.Down()
+		err = swiftclient.Down()
 		if nil != err {
-			logger.Errorf("dlm.Down() failed: %v", err)
+			logger.Errorf("swiftclient.Down() failed: %v", err)
 		}
 		wg.Done()
 	}()
 
-	err = swiftclient.Up(confMap)
+	err = statslogger.Up(confMap)
 	if nil != err {
-		logger.Errorf("swiftclient.Up() failed: %v", err)
+		logger.Errorf("statslogger.Up() failed: %v", err)
 		errChan <- err
 		return
 	}
 	wg.Add(1)
 	defer func() {
-		err = swiftclient.Down()
+		err = statslogger.Down()
 		if nil != err {
-			logger.Errorf("swiftclient.Down() failed: %v", err)
+			logger.Errorf("statslogger.Down() failed: %v", err)
 		}
 		wg.Done()
 	}()
 
-	err = statslogger.Up(confMap)
+	err = cluster.Up(confMap) // Note: Must precede any .Up() step consulting [Volume:<VolumeName>]PrimaryPeer
 	if nil != err {
-		logger.Errorf("statslogger.Up() failed: %v", err)
+		logger.Errorf("cluster.Up() failed: %v", err)
 		errChan <- err
 		return
 	}
 	wg.Add(1)
 	defer func() {
-		err = statslogger.Down()
+		err = cluster.Down()
 		if nil != err {
-			logger.Errorf("statslogger.Down() failed: %v", err)
+			logger.Errorf("cluster.Down() failed: %v", err)
+		}
+		wg.Done()
+	}()
+
+	err = dlm.Up(confMap) // Note: Consults [Volume:<VolumeName>]PrimaryPeer (so must follow cluster.Up())
+	if nil != err {
+		logger.Errorf("dlm.Up() failed: %v", err)
+		errChan <- err
+		return
+	}
+	wg.Add(1)
+	defer func() {
+		err = dlm.Down()
+		if nil != err {
+			logger.Errorf("dlm.Down() failed: %v", err)
 		}
 		wg.Done()
 	}()
 
+	// Volume take over/relinquishment is applied just like a SIGHUP
+	//
+	// Note: failoverChan must be buffered so that the callback need not block
+
+	failoverChan = make(chan struct{}, 1)
+
+	cluster.SetFailoverCallbackFunc(func() {
+		select {
+		case failoverChan <- struct{}{}:
+		default:
+			// A reconfig is already pending
+		}
+	})
+	defer cluster.SetFailoverCallbackFunc(nil)
+
 	err = headhunter.Up(confMap)
 	if nil != err {
 		logger.Errorf("headhunter.Up() failed: %v", err)
@@ -259,193 +291,228 @@ func Daemon(confFile string, confStrings []string, signalHandlerIsArmed *bool, e
 		*signalHandlerIsArmed = true
 	}
 
-	// Await a signal - reloading confFile each SIGHUP - exiting otherwise
+	// Await a signal - reloading confFile each SIGHUP (or Volume take over/relinquishment) - exiting otherwise
 
 	for {
-		signalReceived = <-signalChan
-		logger.Infof("Received signal: '%v'", signalReceived)
+		select {
+		case signalReceived = <-signalChan:
+			logger.Infof("Received signal: '%v'", signalReceived)
 
-		if unix.SIGHUP != signalReceived { // signalReceived either SIGINT or SIGTERM... so just exit
+			if unix.SIGHUP != signalReceived { // signalReceived either SIGINT or SIGTERM... so just exit
 
-			errChan <- nil
+				errChan <- nil
 
-			// if the following message doesn't appear in the log,
-			// it may be because the caller has not called wg.Wait()
-			// to wait for all of the defer'ed routines (above) to
-			// finish before it exits
-			logger.Infof("signal catcher is shutting down proxyfsd (PID %d)", os.Getpid())
+				// if the following message doesn't appear in the log,
+				// it may be because the caller has not called wg.Wait()
+				// to wait for all of the defer'ed routines (above) to
+				// finish before it exits
+				logger.Infof("signal catcher is shutting down proxyfsd (PID %d)", os.Getpid())
+				return
+			}
+		case <-failoverChan:
+			logger.Infof("Volume ownership changed")
+		}
+
+		// caught SIGHUP (or Volume ownership change) -- recompute confMap and re-apply
+		//
+		// Note: cluster.ApplyVolumeOwnership() retries ObjectStore failures itself... so a
+		//       failing reconfig here reflects a genuinely bad config
+		err = reconfig(confFile, confStrings)
+
+		// if one of the daemons didn't make it, log the error
+		if nil != err {
+			logger.Errorf("Reconfig failed: %v", err)
+			errChan <- err
 			return
 		}
+	}
+}
 
-		// caught SIGHUP -- recompute confMap and re-apply
-		// break out of loop at first error and handle it
-		for {
-			logger.Infof("Reconfig started; pausing daemons")
+// reconfig reloads confFile (applying confStrings and Volume ownership) and has
+// each dæmon package pause & contract and then expand & resume accordingly.
+func reconfig(confFile string, confStrings []string) (err error) {
+	var (
+		confMap conf.ConfMap
+	)
 
-			confMap, err = conf.MakeConfMapFromFile(confFile)
-			if nil != err {
-				err = fmt.Errorf("failed to load updated config: %v", err)
-				break
-			}
+	logger.Infof("Reconfig started; pausing daemons")
 
-			err = confMap.UpdateFromStrings(confStrings)
-			if nil != err {
-				err = fmt.Errorf("failed to reapply config overrides: %v", err)
-				break
-			}
+	confMap, err = conf.MakeConfMapFromFile(confFile)
+	if nil != err {
+		err = fmt.Errorf("failed to load updated config: %v", err)
+		return
+	}
 
-			// TODO: Remove call to utils.AdjustConfSectionNamespacingAsNecessary() when appropriate
-			err = utils.AdjustConfSectionNamespacingAsNecessary(confMap)
-			if nil != err {
-				err = fmt.Errorf("utils.AdjustConfSectionNamespacingAsNecessary() failed: %v", err)
-				break
-			}
+	err = confMap.UpdateFromStrings(confStrings)
+	if nil != err {
+		err = fmt.Errorf("failed to reapply config overrides: %v", err)
+		return
+	}
 
-			// tell each daemon to pause and apply "contracting" confMap changes
-			err = httpserver.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("httpserver.PauseAndContract(): %v", err)
-				break
-			}
+	// TODO: Remove call to utils.AdjustConfSectionNamespacingAsNecessary() when appropriate
+	err = utils.AdjustConfSectionNamespacingAsNecessary(confMap)
+	if nil != err {
+		err = fmt.Errorf("utils.AdjustConfSectionNamespacingAsNecessary() failed: %v", err)
+		return
+	}
 
-			err = jrpcfs.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("jrpcfs.PauseAndContract(): %v", err)
-				break
-			}
+	// [Volume:<VolumeName>]PrimaryPeer must reflect each Volume's fencing token before any daemon sees confMap
+	err = cluster.ApplyVolumeOwnership(confMap)
+	if nil != err {
+		err = fmt.Errorf("cluster.ApplyVolumeOwnership(): %v", err)
+		return
+	}
 
-			err = fuse.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("fuse.PauseAndContract(): %v", err)
-				break
-			}
+	// tell each daemon to pause and apply "contracting" confMap changes
+	err = httpserver.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("httpserver.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = fs.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("fs.PauseAndContract(): %v", err)
-				break
-			}
+	err = jrpcfs.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("jrpcfs.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = inode.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("inode.PauseAndContract(): %v", err)
-				break
-			}
+	err = fuse.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("fuse.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = headhunter.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("headhunter.PauseAndContract(): %v", err)
-				break
-			}
+	err = fs.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("fs.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = statslogger.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("statslogger.PauseAndContract(): %v", err)
-				break
-			}
+	err = inode.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("inode.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = swiftclient.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("swiftclient.PauseAndContract(): %v", err)
-				break
-			}
+	err = headhunter.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("headhunter.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = dlm.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("dlm.PauseAndContract(): %v", err)
-				break
-			}
+	err = dlm.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("dlm.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = stats.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("stats.PauseAndContract(): %v", err)
-				break
-			}
+	err = cluster.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("cluster.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = logger.PauseAndContract(confMap)
-			if nil != err {
-				err = fmt.Errorf("logger.PauseAndContract(): %v", err)
-				break
-			}
+	err = statslogger.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("statslogger.PauseAndContract(): %v", err)
+		return
+	}
 
-			// tell each daemon to apply "expanding" confMap changes and result
-			logger.Infof("Reconfig starting; resuming daemons")
+	err = swiftclient.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("swiftclient.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = logger.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("logger.ExpandAndResume(): %v", err)
-				break
-			}
+	err = stats.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("stats.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = stats.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("stats.ExpandAndResume(): %v", err)
-				break
-			}
+	err = logger.PauseAndContract(confMap)
+	if nil != err {
+		err = fmt.Errorf("logger.PauseAndContract(): %v", err)
+		return
+	}
 
-			err = dlm.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("dlm.ExpandAndResume(): %v", err)
-				break
-			}
+	// tell each daemon to apply "expanding" confMap changes and result
+	logger.Infof("Reconfig starting; resuming daemons")
 
-			err = swiftclient.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("swiftclient.ExpandAndResume(): %v", err)
-				break
-			}
+	err = logger.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("logger.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = statslogger.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("statslogger.ExpandAndResume(): %v", err)
-				break
-			}
+	err = stats.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("stats.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = headhunter.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("headhunter.ExpandAndResume(): %v", err)
-				break
-			}
+	err = swiftclient.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("swiftclient.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = inode.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("inode.ExpandAndResume(): %v", err)
-				break
-			}
+	err = statslogger.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("statslogger.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = fs.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("fs.ExpandAndResume(): %v", err)
-				break
-			}
+	err = cluster.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("cluster.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = fuse.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("fuse.ExpandAndResume(): %v", err)
-				break
-			}
+	err = dlm.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("dlm.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = jrpcfs.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("jrpcfs.ExpandAndResume(): %v", err)
-				break
-			}
+	err = headhunter.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("headhunter.ExpandAndResume(): %v", err)
+		return
+	}
 
-			err = httpserver.ExpandAndResume(confMap)
-			if nil != err {
-				err = fmt.Errorf("httpserver.ExpandAndResume(): %v", err)
-				break
-			}
+	err = inode.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("inode.ExpandAndResume(): %v", err)
+		return
+	}
 
-			logger.Infof("Reconfig finished successfully")
-			break
-		}
+	err = fs.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("fs.ExpandAndResume(): %v", err)
+		return
+	}
 
-		// if one of the daemons didn't make it, log the error
-		if nil != err {
-			logger.Errorf("Reconfig failed: %v", err)
-			errChan <- err
-			return
-		}
+	err = fuse.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("fuse.ExpandAndResume(): %v", err)
+		return
 	}
+
+	err = jrpcfs.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("jrpcfs.ExpandAndResume(): %v", err)
+		return
+	}
+
+	err = httpserver.ExpandAndResume(confMap)
+	if nil != err {
+		err = fmt.Errorf("httpserver.ExpandAndResume(): %v", err)
+		return
+	}
+
+	logger.Infof("Reconfig finished successfully")
+
+	err = nil
+	return
 }
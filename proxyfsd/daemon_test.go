@@ -1,30 +1,44 @@
 package proxyfsd
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"golang.org/x/sys/unix"
 
+	"github.com/swiftstack/ProxyFS/cluster"
 	"github.com/swiftstack/ProxyFS/conf"
 	"github.com/swiftstack/ProxyFS/dlm"
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/objectstore"
 	"github.com/swiftstack/ProxyFS/ramswift"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/swiftclient"
 )
 
 func TestMain(m *testing.M) {
+	// TestDaemonFailover re-executes this test binary once per Peer... each such process runs proxyfsd
+
+	failoverTestPeerName := os.Getenv(failoverTestPeerEnvName)
+	if "" != failoverTestPeerName {
+		os.Exit(failoverTestPeer(failoverTestPeerName))
+	}
+
 	mRunReturn := m.Run()
 	os.Exit(mRunReturn)
 }
@@ -502,3 +516,896 @@ func fetchTestVersionSectionDotVersion(t *testing.T) (version uint64) {
 
 	return
 }
+
+func TestDaemonWithLocalObjectStore(t *testing.T) {
+	var (
+		confMapStrings               []string
+		createdFileInodeNumber       inode.InodeNumber
+		err                          error
+		errChan                      chan error
+		localObjectStore             objectstore.ObjectStore
+		localPath                    string
+		mountHandle                  fs.MountHandle
+		proxyfsdSignalHandlerIsArmed bool
+		readData                     []byte
+		testConfMap                  conf.ConfMap
+		toReadFileInodeNumber        inode.InodeNumber
+		wg                           sync.WaitGroup
+	)
+
+	// No ramswift instance is launched... nothing listens on SwiftClient.NoAuthTCPPort
+
+	localPath, err = ioutil.TempDir(os.TempDir(), "proxyfsdLocalObjectStoreTest_")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(localPath)
+
+	confMapStrings = []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+
+		"StatsLogger.Period=10m",
+
+		"Logging.LogFilePath=",
+
+		"Peer:Peer0.PublicIPAddr=127.0.0.1",
+		"Peer:Peer0.PrivateIPAddr=127.0.0.1",
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+
+		"Cluster.WhoAmI=Peer0",
+		"Cluster.Peers=Peer0",
+		"Cluster.ServerGuid=a66488e9-a051-4ff7-865d-87bfb84cc2ae",
+		"Cluster.PrivateClusterUDPPort=5002", // 5002 instead of 5001 so that test can run if proxyfsd is already running
+		"Cluster.HeartBeatInterval=100ms",
+		"Cluster.HeartBeatVariance=5ms",
+		"Cluster.HeartBeatExpiration=400ms",
+		"Cluster.MessageExpiration=700ms",
+		"Cluster.RequestExpiration=1s",
+		"Cluster.UDPReadSize=8000",
+		"Cluster.UDPWriteSize=7000",
+
+		"HTTPServer.TCPPort=53461",
+
+		"SwiftClient.NoAuthTCPPort=45262",
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=1",
+		"SwiftClient.RetryLimitObject=1",
+		"SwiftClient.RetryDelay=10ms",
+		"SwiftClient.RetryDelayObject=10ms",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+
+		"FlowControl:CommonFlowControl.MaxFlushSize=10000000",
+		"FlowControl:CommonFlowControl.MaxFlushTime=10s",
+		"FlowControl:CommonFlowControl.ReadCacheLineSize=1000000",
+		"FlowControl:CommonFlowControl.ReadCacheWeight=100",
+
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerStoragePolicy=silver",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerNamePrefix=Replicated3Way_",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainersPerPeer=1000",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.MaxObjectsPerContainer=1000000",
+
+		"ObjectStore:LocalObjectStore.Type=Local",
+		"ObjectStore:LocalObjectStore.Path=" + localPath,
+
+		"Volume:LocalVolume.FSID=1",
+		"Volume:LocalVolume.FUSEMountPointName=LocalMountPoint",
+		"Volume:LocalVolume.NFSExportName=LocalExport",
+		"Volume:LocalVolume.SMBShareName=LocalShare",
+		"Volume:LocalVolume.PrimaryPeer=Peer0",
+		"Volume:LocalVolume.AccountName=AUTH_LocalAccount",
+		"Volume:LocalVolume.ObjectStore=LocalObjectStore",
+		"Volume:LocalVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:LocalVolume.CheckpointContainerStoragePolicy=gold",
+		"Volume:LocalVolume.CheckpointInterval=10s",
+		"Volume:LocalVolume.CheckpointIntervalsPerCompaction=100",
+		"Volume:LocalVolume.DefaultPhysicalContainerLayout=PhysicalContainerLayoutReplicated3Way",
+		"Volume:LocalVolume.FlowControl=CommonFlowControl",
+		"Volume:LocalVolume.NonceValuesToReserve=100",
+		"Volume:LocalVolume.MaxEntriesPerDirNode=32",
+		"Volume:LocalVolume.MaxExtentsPerFileNode=32",
+		"Volume:LocalVolume.MaxInodesPerMetadataNode=32",
+		"Volume:LocalVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:LocalVolume.MaxDirFileNodesPerMetadataNode=16",
+
+		"FSGlobals.VolumeList=LocalVolume",
+		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
+		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
+		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
+		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
+		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
+		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
+		"FSGlobals.DirEntryCacheEvictLowLimit=10000",
+		"FSGlobals.DirEntryCacheEvictHighLimit=10010",
+		"FSGlobals.FileExtentMapEvictLowLimit=10000",
+		"FSGlobals.FileExtentMapEvictHighLimit=10010",
+
+		"JSONRPCServer.TCPPort=12346",     // 12346 instead of 12345 so that test can run if proxyfsd is already running
+		"JSONRPCServer.FastTCPPort=32346", // ...and similarly here...
+		"JSONRPCServer.DataPathLogging=false",
+	}
+
+	// Format LocalVolume
+
+	testConfMap, err = conf.MakeConfMapFromStrings(confMapStrings)
+	if nil != err {
+		t.Fatalf("While doing pre-format, conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	err = logger.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("While doing pre-format, logger.Up() failed: %v", err)
+	}
+
+	err = stats.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("While doing pre-format, stats.Up() failed: %v", err)
+	}
+
+	err = headhunter.Format(testConfMap, "LocalVolume")
+	if nil != err {
+		t.Fatalf("headhunter.Format() failed: %v", err)
+	}
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("While doing pre-format, stats.Down() failed: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("While doing pre-format, logger.Down() failed: %v", err)
+	}
+
+	// Launch an instance of proxyfsd using that same config
+
+	proxyfsdSignalHandlerIsArmed = false
+	errChan = make(chan error, 1) // Must be buffered to avoid race
+
+	go Daemon("/dev/null", confMapStrings, &proxyfsdSignalHandlerIsArmed, errChan, &wg, unix.SIGTERM, unix.SIGHUP)
+
+	for !proxyfsdSignalHandlerIsArmed {
+		select {
+		case err = <-errChan:
+			if nil == err {
+				t.Fatalf("Daemon() exited successfully despite not being told to do so [case 1]")
+			} else {
+				t.Fatalf("Daemon() exited with error [case 1a]: %v", err)
+			}
+		default:
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	// The fencing token was claimed in LocalVolume's ObjectStore
+
+	localObjectStore, err = objectstore.FetchObjectStore(testConfMap, "LocalVolume")
+	if nil != err {
+		t.Fatalf("objectstore.FetchObjectStore() failed: %v", err)
+	}
+
+	_, err = localObjectStore.ObjectContentLength("AUTH_LocalAccount", ".__checkpoint__", cluster.FencingTokenObjectName)
+	if nil != err {
+		t.Fatalf("LocalVolume's fencing token not found in its ObjectStore: %v", err)
+	}
+
+	// Write to the volume (with no flush so that only time-based/restart flush is performed)
+
+	mountHandle, err = fs.Mount("LocalVolume", fs.MountOptions(0))
+	if nil != err {
+		t.Fatalf("fs.Mount() failed [case 1]: %v", err)
+	}
+
+	createdFileInodeNumber, err = mountHandle.Create(
+		inode.InodeRootUserID,
+		inode.InodeRootGroupID,
+		nil,
+		inode.RootDirInodeNumber,
+		"TestFile",
+		inode.R_OK|inode.W_OK,
+	)
+	if nil != err {
+		t.Fatalf("fs.Create() failed: %v", err)
+	}
+
+	_, err = mountHandle.Write(
+		inode.InodeRootUserID,
+		inode.InodeRootGroupID,
+		nil,
+		createdFileInodeNumber,
+		0,
+		[]byte{0x00, 0x01, 0x02, 0x03},
+		nil,
+	)
+	if nil != err {
+		t.Fatalf("fs.Write() failed: %v", err)
+	}
+
+	// Send ourself a SIGTERM to signal normal termination of mainWithArgs()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	err = <-errChan
+
+	wg.Wait() // wait for services to go Down()
+
+	if nil != err {
+		t.Fatalf("Daemon() exited with error [case 1b]: == %v", err)
+	}
+
+	// Relaunch an instance of proxyfsd
+
+	proxyfsdSignalHandlerIsArmed = false
+	errChan = make(chan error, 1) // Must be buffered to avoid race
+
+	go Daemon("/dev/null", confMapStrings, &proxyfsdSignalHandlerIsArmed, errChan, &wg, unix.SIGTERM, unix.SIGHUP)
+
+	for !proxyfsdSignalHandlerIsArmed {
+		select {
+		case err = <-errChan:
+			if nil == err {
+				t.Fatalf("Daemon() exited successfully despite not being told to do so [case 2]")
+			} else {
+				t.Fatalf("Daemon() exited with error [case 2a]: %v", err)
+			}
+		default:
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	// Verify written data survived the restart
+
+	mountHandle, err = fs.Mount("LocalVolume", fs.MountOptions(0))
+	if nil != err {
+		t.Fatalf("fs.Mount() failed [case 2]: %v", err)
+	}
+
+	toReadFileInodeNumber, err = mountHandle.Lookup(
+		inode.InodeRootUserID,
+		inode.InodeRootGroupID,
+		nil,
+		inode.RootDirInodeNumber,
+		"TestFile",
+	)
+	if nil != err {
+		t.Fatalf("fs.Lookup() failed: %v", err)
+	}
+
+	readData, err = mountHandle.Read(
+		inode.InodeRootUserID,
+		inode.InodeRootGroupID,
+		nil,
+		toReadFileInodeNumber,
+		0,
+		4,
+		nil,
+	)
+	if nil != err {
+		t.Fatalf("fs.Read() failed: %v", err)
+	}
+	if 0 != bytes.Compare([]byte{0x00, 0x01, 0x02, 0x03}, readData) {
+		t.Fatalf("fs.Read() returned unexpected readData")
+	}
+
+	// Send ourself a SIGTERM to signal normal termination of mainWithArgs()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	err = <-errChan
+
+	wg.Wait() // wait for services to go Down()
+
+	if nil != err {
+		t.Fatalf("Daemon() exited with error [case 2b]: %v", err)
+	}
+}
+
+const (
+	failoverTestPeerEnvName = "PROXYFSD_FAILOVER_TEST_PEER"
+	failoverTestHTTPPort    = "53462"
+	failoverTestFileName    = "FailoverTestFile"
+)
+
+var failoverTestFileData = []byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
+
+var failoverTestPeerIPAddrMap = map[string]string{"Peer0": "127.0.0.1", "Peer1": "127.0.0.2"}
+
+// failoverTestConfMapStrings returns the config of one of the two Peers of TestDaemonFailover.
+// Each Peer's services listen on its own PrivateIPAddr so that both may use the same ports.
+func failoverTestConfMapStrings(whoAmI string) (confMapStrings []string) {
+	confMapStrings = []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+
+		"StatsLogger.Period=10m",
+
+		"Logging.LogFilePath=",
+
+		"Peer:Peer0.PublicIPAddr=" + failoverTestPeerIPAddrMap["Peer0"],
+		"Peer:Peer0.PrivateIPAddr=" + failoverTestPeerIPAddrMap["Peer0"],
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+
+		"Peer:Peer1.PublicIPAddr=" + failoverTestPeerIPAddrMap["Peer1"],
+		"Peer:Peer1.PrivateIPAddr=" + failoverTestPeerIPAddrMap["Peer1"],
+		"Peer:Peer1.ReadCacheQuotaFraction=0.20",
+
+		"Cluster.WhoAmI=" + whoAmI,
+		"Cluster.Peers=Peer0,Peer1",
+		"Cluster.ServerGuid=a66488e9-a051-4ff7-865d-87bfb84cc2ae",
+		"Cluster.PrivateClusterUDPPort=5003", // 5003 so as to not collide with TestDaemon
+		"Cluster.HeartBeatInterval=100ms",
+		"Cluster.HeartBeatVariance=5ms",
+		"Cluster.HeartBeatExpiration=400ms",
+		"Cluster.MessageExpiration=700ms",
+		"Cluster.RequestExpiration=1s",
+		"Cluster.FenceCheckInterval=100ms",
+		"Cluster.UDPReadSize=8000",
+		"Cluster.UDPWriteSize=7000",
+
+		"HTTPServer.TCPPort=" + failoverTestHTTPPort,
+
+		"SwiftClient.NoAuthTCPPort=45263",
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=1",
+		"SwiftClient.RetryLimitObject=1",
+		"SwiftClient.RetryDelay=10ms",
+		"SwiftClient.RetryDelayObject=10ms",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+
+		"FlowControl:CommonFlowControl.MaxFlushSize=10000000",
+		"FlowControl:CommonFlowControl.MaxFlushTime=10s",
+		"FlowControl:CommonFlowControl.ReadCacheLineSize=1000000",
+		"FlowControl:CommonFlowControl.ReadCacheWeight=100",
+
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerStoragePolicy=silver",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerNamePrefix=Replicated3Way_",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainersPerPeer=1000",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.MaxObjectsPerContainer=1000000",
+
+		"Volume:CommonVolume.FSID=1",
+		"Volume:CommonVolume.FUSEMountPointName=CommonMountPoint",
+		"Volume:CommonVolume.NFSExportName=CommonExport",
+		"Volume:CommonVolume.SMBShareName=CommonShare",
+		"Volume:CommonVolume.PrimaryPeer=Peer0",
+		"Volume:CommonVolume.StandbyPeerList=Peer1",
+		"Volume:CommonVolume.AccountName=AUTH_FailoverAccount", // ramswift retains TestDaemon's AUTH_CommonAccount
+		"Volume:CommonVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:CommonVolume.CheckpointContainerStoragePolicy=gold",
+		"Volume:CommonVolume.CheckpointInterval=1h", // only Flush()'s (and Down()'s) checkpoints are performed
+		"Volume:CommonVolume.CheckpointIntervalsPerCompaction=100",
+		"Volume:CommonVolume.DefaultPhysicalContainerLayout=PhysicalContainerLayoutReplicated3Way",
+		"Volume:CommonVolume.FlowControl=CommonFlowControl",
+		"Volume:CommonVolume.NonceValuesToReserve=100",
+		"Volume:CommonVolume.MaxEntriesPerDirNode=32",
+		"Volume:CommonVolume.MaxExtentsPerFileNode=32",
+		"Volume:CommonVolume.MaxInodesPerMetadataNode=32",
+		"Volume:CommonVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:CommonVolume.MaxDirFileNodesPerMetadataNode=16",
+
+		"FSGlobals.VolumeList=CommonVolume",
+		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
+		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
+		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
+		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
+		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
+		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
+		"FSGlobals.DirEntryCacheEvictLowLimit=10000",
+		"FSGlobals.DirEntryCacheEvictHighLimit=10010",
+		"FSGlobals.FileExtentMapEvictLowLimit=10000",
+		"FSGlobals.FileExtentMapEvictHighLimit=10010",
+
+		"JSONRPCServer.TCPPort=12347",
+		"JSONRPCServer.FastTCPPort=32347",
+		"JSONRPCServer.DataPathLogging=false",
+
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+	}
+
+	return
+}
+
+// failoverTestPeer is the body of a TestDaemonFailover Peer process. Once proxyfsd is up, Peer0
+// checkpoints failoverTestFileName and then removes it without checkpointing... leaving the deletion
+// of its LogSegment awaiting the next checkpoint. "ready" is then written to stdout.
+func failoverTestPeer(peerName string) (exitCode int) {
+	var (
+		err                          error
+		errChan                      chan error
+		fileInodeNumber              inode.InodeNumber
+		mountHandle                  fs.MountHandle
+		proxyfsdSignalHandlerIsArmed bool
+		wg                           sync.WaitGroup
+	)
+
+	proxyfsdSignalHandlerIsArmed = false
+	errChan = make(chan error, 1) // Must be buffered to avoid race
+
+	go Daemon("/dev/null", failoverTestConfMapStrings(peerName), &proxyfsdSignalHandlerIsArmed, errChan, &wg, unix.SIGTERM, unix.SIGHUP)
+
+	for !proxyfsdSignalHandlerIsArmed {
+		select {
+		case err = <-errChan:
+			fmt.Fprintf(os.Stderr, "%v: Daemon() exited prematurely: %v\n", peerName, err)
+			exitCode = 1
+			return
+		default:
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if "Peer0" == peerName {
+		mountHandle, err = fs.Mount("CommonVolume", fs.MountOptions(0))
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "%v: fs.Mount() failed: %v\n", peerName, err)
+			exitCode = 1
+			return
+		}
+
+		fileInodeNumber, err = mountHandle.Create(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, failoverTestFileName, inode.R_OK|inode.W_OK)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "%v: fs.Create() failed: %v\n", peerName, err)
+			exitCode = 1
+			return
+		}
+
+		_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber, 0, failoverTestFileData, nil)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "%v: fs.Write() failed: %v\n", peerName, err)
+			exitCode = 1
+			return
+		}
+
+		err = mountHandle.Flush(inode.InodeRootUserID, inode.InodeRootGroupID, nil, fileInodeNumber) // also checkpoints
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "%v: fs.Flush() failed: %v\n", peerName, err)
+			exitCode = 1
+			return
+		}
+
+		err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeRootGroupID, nil, inode.RootDirInodeNumber, failoverTestFileName)
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "%v: fs.Unlink() failed: %v\n", peerName, err)
+			exitCode = 1
+			return
+		}
+	}
+
+	fmt.Println("ready")
+
+	err = <-errChan
+
+	wg.Wait() // wait for services to go Down()
+
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "%v: Daemon() exited with error: %v\n", peerName, err)
+		exitCode = 1
+		return
+	}
+
+	exitCode = 0
+	return
+}
+
+// launchFailoverTestPeer starts a TestDaemonFailover Peer process and awaits its "ready"
+func launchFailoverTestPeer(t *testing.T, peerName string) (cmd *exec.Cmd) {
+	var (
+		err        error
+		line       string
+		stdoutPipe io.ReadCloser
+	)
+
+	cmd = exec.Command(os.Args[0], "-test.run=TestDaemonFailover")
+	cmd.Env = append(os.Environ(), failoverTestPeerEnvName+"="+peerName)
+	cmd.Stderr = os.Stderr
+
+	stdoutPipe, err = cmd.StdoutPipe()
+	if nil != err {
+		t.Fatalf("cmd.StdoutPipe() for %v failed: %v", peerName, err)
+	}
+
+	err = cmd.Start()
+	if nil != err {
+		t.Fatalf("cmd.Start() for %v failed: %v", peerName, err)
+	}
+
+	line, err = bufio.NewReader(stdoutPipe).ReadString('\n')
+	if nil != err {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Fatalf("%v exited before becoming ready: %v", peerName, err)
+	}
+	if "ready\n" != line {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Fatalf("%v wrote unexpected \"%v\" rather than \"ready\"", peerName, line)
+	}
+
+	return
+}
+
+// fetchFailoverTestPage GETs path from peerName's httpserver... retrying while a reconfig has it paused
+func fetchFailoverTestPage(t *testing.T, peerName string, path string) (body []byte) {
+	var (
+		deadline time.Time
+		err      error
+		resp     *http.Response
+	)
+
+	deadline = time.Now().Add(10 * time.Second)
+
+	for {
+		resp, err = http.Get("http://" + failoverTestPeerIPAddrMap[peerName] + ":" + failoverTestHTTPPort + path)
+		if nil != err {
+			t.Fatalf("http.Get() of %v's %v failed: %v", peerName, path, err)
+		}
+		body, err = ioutil.ReadAll(resp.Body)
+		if nil != err {
+			t.Fatalf("ioutil.ReadAll() of %v's %v failed: %v", peerName, path, err)
+		}
+		err = resp.Body.Close()
+		if nil != err {
+			t.Fatalf("resp.Body.Close() of %v's %v failed: %v", peerName, path, err)
+		}
+
+		if http.StatusOK == resp.StatusCode {
+			return
+		}
+		if (http.StatusServiceUnavailable != resp.StatusCode) || time.Now().After(deadline) {
+			t.Fatalf("http.Get() of %v's %v returned unexpected StatusCode %v", peerName, path, resp.StatusCode)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// fetchFailoverTestPrimaryPeer returns [Volume:CommonVolume]PrimaryPeer of peerName's current config
+func fetchFailoverTestPrimaryPeer(t *testing.T, peerName string) (primaryPeer string) {
+	var (
+		body            []byte
+		bodySections    map[string]map[string][]string
+		err             error
+		ok              bool
+		primaryPeerList []string
+		volumeSection   map[string][]string
+	)
+
+	body = fetchFailoverTestPage(t, peerName, "/config")
+
+	err = json.Unmarshal(body, &bodySections)
+	if nil != err {
+		t.Fatalf("json.Unmarshal() of %v's /config failed: %v", peerName, err)
+	}
+	volumeSection, ok = bodySections["Volume:CommonVolume"]
+	if !ok {
+		t.Fatalf("%v's /config missing [Volume:CommonVolume]", peerName)
+	}
+	primaryPeerList, ok = volumeSection["PrimaryPeer"]
+	if !ok {
+		t.Fatalf("%v's /config missing [Volume:CommonVolume]PrimaryPeer", peerName)
+	}
+
+	if 0 == len(primaryPeerList) {
+		primaryPeer = ""
+	} else {
+		primaryPeer = primaryPeerList[0]
+	}
+
+	return
+}
+
+// fetchFailoverTestClusterOperations returns the count of proxyfs.cluster.<operation>.operations
+// reported by peerName's /metrics (or zero if not yet reported)
+func fetchFailoverTestClusterOperations(t *testing.T, peerName string, operation string) (operations uint64) {
+	var (
+		body       []byte
+		err        error
+		line       string
+		linePrefix string
+		value      float64
+	)
+
+	body = fetchFailoverTestPage(t, peerName, "/metrics")
+
+	linePrefix = "proxyfs_cluster_operations_total{operation=\"" + operation + "\"} "
+
+	for _, line = range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, linePrefix) {
+			value, err = strconv.ParseFloat(strings.TrimPrefix(line, linePrefix), 64)
+			if nil != err {
+				t.Fatalf("%v's /metrics contained unparseable \"%v\": %v", peerName, line, err)
+			}
+			operations = uint64(value)
+			return
+		}
+	}
+
+	operations = 0
+	return
+}
+
+// TestDaemonFailover runs a proxyfsd process for each of Peer0 (CommonVolume's PrimaryPeer) and
+// Peer1 (its StandbyPeerList) against a shared ramswift. Peer0 is then suspended long enough for
+// Peer1 to claim CommonVolume's fencing token and, via its failover callback, reconfig to serve it.
+// Once resumed, Peer0 must notice it has been fenced, refuse to checkpoint, discard the LogSegment
+// deletion that was awaiting its next checkpoint, and reconfig to no longer serve CommonVolume.
+// Finally, the file whose removal Peer0 never managed to checkpoint must still be fully readable.
+func TestDaemonFailover(t *testing.T) {
+	var (
+		confMapStrings               []string
+		deadline                     time.Time
+		err                          error
+		errChan                      chan error
+		fileInodeNumber              inode.InodeNumber
+		mountHandle                  fs.MountHandle
+		peer0Cmd                     *exec.Cmd
+		peer1Cmd                     *exec.Cmd
+		primaryPeer                  string
+		proxyfsdSignalHandlerIsArmed bool
+		ramswiftDoneChan             chan bool
+		ramswiftSignalHandlerIsArmed bool
+		readData                     []byte
+		testConfMap                  conf.ConfMap
+		wg                           sync.WaitGroup
+	)
+
+	// Setup a ramswift instance shared by both Peers
+
+	confMapStrings = failoverTestConfMapStrings("Peer0")
+
+	ramswiftSignalHandlerIsArmed = false
+	ramswiftDoneChan = make(chan bool, 1)
+
+	go ramswift.Daemon("/dev/null", confMapStrings, &ramswiftSignalHandlerIsArmed, ramswiftDoneChan, unix.SIGINT)
+
+	for !ramswiftSignalHandlerIsArmed {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Format CommonVolume
+
+	testConfMap, err = conf.MakeConfMapFromStrings(confMapStrings)
+	if nil != err {
+		t.Fatalf("While doing pre-format, conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	err = logger.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("While doing pre-format, logger.Up() failed: %v", err)
+	}
+
+	err = stats.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("While doing pre-format, stats.Up() failed: %v", err)
+	}
+
+	err = dlm.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("While doing pre-format, dlm.Up() failed: %v", err)
+	}
+
+	err = swiftclient.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("While doing pre-format, swiftclient.Up() failed: %v", err)
+	}
+
+	err = headhunter.Format(testConfMap, "CommonVolume")
+	if nil != err {
+		t.Fatalf("headhunter.Format() failed: %v", err)
+	}
+
+	err = swiftclient.Down()
+	if nil != err {
+		t.Fatalf("While doing pre-format, swiftclient.Down() failed: %v", err)
+	}
+
+	err = dlm.Down()
+	if nil != err {
+		t.Fatalf("While doing pre-format, dlm.Down() failed: %v", err)
+	}
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("While doing pre-format, stats.Down() failed: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("While doing pre-format, logger.Down() failed: %v", err)
+	}
+
+	// Launch Peer0 (which claims CommonVolume's fencing token) and then Peer1
+
+	peer0Cmd = launchFailoverTestPeer(t, "Peer0")
+	defer func() {
+		_ = peer0Cmd.Process.Kill() // SIGKILL also terminates a stopped process
+		_ = peer0Cmd.Wait()
+	}()
+
+	peer1Cmd = launchFailoverTestPeer(t, "Peer1")
+	defer func() {
+		_ = peer1Cmd.Process.Kill()
+		_ = peer1Cmd.Wait()
+	}()
+
+	primaryPeer = fetchFailoverTestPrimaryPeer(t, "Peer0")
+	if "Peer0" != primaryPeer {
+		t.Fatalf("Peer0 initially considered CommonVolume served by \"%v\"", primaryPeer)
+	}
+	primaryPeer = fetchFailoverTestPrimaryPeer(t, "Peer1")
+	if "Peer0" != primaryPeer {
+		t.Fatalf("Peer1 initially considered CommonVolume served by \"%v\"", primaryPeer)
+	}
+
+	// Suspend Peer0 so that it stops heart beating... Peer1 should then take over CommonVolume
+
+	err = peer0Cmd.Process.Signal(unix.SIGSTOP)
+	if nil != err {
+		t.Fatalf("Sending SIGSTOP to Peer0 failed: %v", err)
+	}
+
+	deadline = time.Now().Add(10 * time.Second)
+
+	for {
+		primaryPeer = fetchFailoverTestPrimaryPeer(t, "Peer1")
+		if "Peer1" == primaryPeer {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Peer1 never reconfigured to serve CommonVolume")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if 1 != fetchFailoverTestClusterOperations(t, "Peer1", "volume.take-over") {
+		t.Fatalf("Peer1 should have reported exactly one take over")
+	}
+
+	// Resume Peer0... it should notice it has been fenced and reconfig to no longer serve CommonVolume
+
+	err = peer0Cmd.Process.Signal(unix.SIGCONT)
+	if nil != err {
+		t.Fatalf("Sending SIGCONT to Peer0 failed: %v", err)
+	}
+
+	deadline = time.Now().Add(10 * time.Second)
+
+	for {
+		primaryPeer = fetchFailoverTestPrimaryPeer(t, "Peer0")
+		if "Peer1" == primaryPeer {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Peer0 never reconfigured to consider CommonVolume served by Peer1")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if 0 == fetchFailoverTestClusterOperations(t, "Peer0", "volume.step-down") {
+		t.Fatalf("Peer0 should have reported stepping down")
+	}
+
+	// Peer0's final checkpoint (as CommonVolume was dropped) must have been refused... and the
+	// LogSegment deletion awaiting it discarded
+
+	deadline = time.Now().Add(10 * time.Second)
+
+	for (0 == fetchFailoverTestClusterOperations(t, "Peer0", "volume.fenced-checkpoint")) ||
+		(0 == fetchFailoverTestClusterOperations(t, "Peer0", "volume.fenced-object-delete")) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Peer0 never reported both a refused checkpoint and a discarded object deletion")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Peer1 never fenced itself
+
+	if 0 != fetchFailoverTestClusterOperations(t, "Peer1", "volume.fenced-checkpoint") {
+		t.Fatalf("Peer1 should not have reported a refused checkpoint")
+	}
+
+	// Terminate both Peers normally
+
+	err = peer0Cmd.Process.Signal(unix.SIGTERM)
+	if nil != err {
+		t.Fatalf("Sending SIGTERM to Peer0 failed: %v", err)
+	}
+	err = peer0Cmd.Wait()
+	if nil != err {
+		t.Fatalf("Peer0 exited with error: %v", err)
+	}
+
+	err = peer1Cmd.Process.Signal(unix.SIGTERM)
+	if nil != err {
+		t.Fatalf("Sending SIGTERM to Peer1 failed: %v", err)
+	}
+	err = peer1Cmd.Wait()
+	if nil != err {
+		t.Fatalf("Peer1 exited with error: %v", err)
+	}
+
+	// Launch an instance of proxyfsd as Peer1 (which now holds CommonVolume's fencing token)
+
+	proxyfsdSignalHandlerIsArmed = false
+	errChan = make(chan error, 1) // Must be buffered to avoid race
+
+	go Daemon("/dev/null", failoverTestConfMapStrings("Peer1"), &proxyfsdSignalHandlerIsArmed, errChan, &wg, unix.SIGTERM, unix.SIGHUP)
+
+	for !proxyfsdSignalHandlerIsArmed {
+		select {
+		case err = <-errChan:
+			if nil == err {
+				t.Fatalf("Daemon() exited successfully despite not being told to do so")
+			} else {
+				t.Fatalf("Daemon() exited with error [case 1]: %v", err)
+			}
+		default:
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	// Verify the file Peer0 removed (but never checkpointed the removal of) remains intact
+
+	mountHandle, err = fs.Mount("CommonVolume", fs.MountOptions(0))
+	if nil != err {
+		t.Fatalf("fs.Mount() failed: %v", err)
+	}
+
+	fileInodeNumber, err = mountHandle.Lookup(
+		inode.InodeRootUserID,
+		inode.InodeRootGroupID,
+		nil,
+		inode.RootDirInodeNumber,
+		failoverTestFileName,
+	)
+	if nil != err {
+		t.Fatalf("fs.Lookup() failed: %v", err)
+	}
+
+	readData, err = mountHandle.Read(
+		inode.InodeRootUserID,
+		inode.InodeRootGroupID,
+		nil,
+		fileInodeNumber,
+		0,
+		uint64(len(failoverTestFileData)),
+		nil,
+	)
+	if nil != err {
+		t.Fatalf("fs.Read() failed: %v", err)
+	}
+	if 0 != bytes.Compare(failoverTestFileData, readData) {
+		t.Fatalf("fs.Read() returned unexpected readData")
+	}
+
+	// Send ourself a SIGTERM to signal normal termination of mainWithArgs()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	err = <-errChan
+
+	wg.Wait() // wait for services to go Down()
+
+	if nil != err {
+		t.Fatalf("Daemon() exited with error [case 2]: %v", err)
+	}
+
+	// Send ourself a SIGINT to also terminate ramswift
+
+	unix.Kill(unix.Getpid(), unix.SIGINT)
+
+	_ = <-ramswiftDoneChan
+}
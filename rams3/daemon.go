@@ -0,0 +1,670 @@
+// Package rams3 provides an in-memory emulation of the subset of the Amazon S3 API
+// used by s3client, which can be run as a goroutine from another package, or as a
+// standalone binary.
+package rams3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+type objectStruct struct {
+	contents []byte
+	metadata http.Header // only X-Amz-Meta-* headers
+}
+
+type multipartUploadStruct struct {
+	key     string
+	partMap map[uint16][]byte // key is PartNumber
+}
+
+type bucketStruct struct {
+	name               string
+	objectMap          map[string]*objectStruct          // key is object key
+	multipartUploadMap map[string]*multipartUploadStruct // key is UploadId
+}
+
+type globalsStruct struct {
+	sync.Mutex   //                            protects globalsStruct.bucketMap (and everything reachable from it)
+	tcpPort      uint16
+	accessKeyID  string                   // if empty, any Credential is accepted
+	maxKeys      int                      // limits the number of entries in each ListObjectsV2 response
+	bucketMap    map[string]*bucketStruct // key is bucketStruct.name
+	nextUploadID uint64
+}
+
+var globals globalsStruct
+
+type errorResponseStruct struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+type listBucketResultContentsStruct struct {
+	Key  string `xml:"Key"`
+	Size int    `xml:"Size"`
+}
+
+type listBucketResultCommonPrefixStruct struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResultStruct struct {
+	XMLName               xml.Name                             `xml:"ListBucketResult"`
+	Name                  string                               `xml:"Name"`
+	Prefix                string                               `xml:"Prefix"`
+	Delimiter             string                               `xml:"Delimiter,omitempty"`
+	KeyCount              int                                  `xml:"KeyCount"`
+	MaxKeys               int                                  `xml:"MaxKeys"`
+	IsTruncated           bool                                 `xml:"IsTruncated"`
+	NextContinuationToken string                               `xml:"NextContinuationToken,omitempty"`
+	Contents              []listBucketResultContentsStruct     `xml:"Contents"`
+	CommonPrefixes        []listBucketResultCommonPrefixStruct `xml:"CommonPrefixes"`
+}
+
+type initiateMultipartUploadResultStruct struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUploadStruct struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []struct {
+		PartNumber uint16 `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResultStruct struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+func eTagOf(buf []byte) (eTag string) {
+	sum := md5.Sum(buf)
+	eTag = "\"" + hex.EncodeToString(sum[:]) + "\""
+	return
+}
+
+func writeXML(responseWriter http.ResponseWriter, httpStatus int, v interface{}) {
+	buf, err := xml.Marshal(v)
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/xml")
+	responseWriter.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(buf)))
+	responseWriter.WriteHeader(httpStatus)
+	_, _ = responseWriter.Write([]byte(xml.Header))
+	_, _ = responseWriter.Write(buf)
+}
+
+func writeError(responseWriter http.ResponseWriter, request *http.Request, httpStatus int, code string, message string) {
+	if "HEAD" == request.Method {
+		responseWriter.WriteHeader(httpStatus)
+		return
+	}
+	writeXML(responseWriter, httpStatus, &errorResponseStruct{Code: code, Message: message, Resource: request.URL.Path})
+}
+
+// parsePath splits a path-style request's path into its bucket & key (either of which may be empty)
+func parsePath(request *http.Request) (bucketName string, key string) {
+	pathSplit := strings.SplitN(strings.TrimPrefix(request.URL.Path, "/"), "/", 2)
+
+	bucketName = pathSplit[0]
+	if 2 == len(pathSplit) {
+		key = pathSplit[1]
+	}
+
+	return
+}
+
+// parseRangeHeader returns the [startOffset,stopOffset) of contentLength bytes selected by a Range header
+func parseRangeHeader(rangeHeader string, contentLength int) (startOffset int, stopOffset int, ok bool) {
+	var (
+		err error
+	)
+
+	if !strings.HasPrefix(rangeHeader, "bytes=") {
+		return
+	}
+
+	rangeSplit := strings.SplitN(strings.TrimPrefix(rangeHeader, "bytes="), "-", 2)
+	if 2 != len(rangeSplit) {
+		return
+	}
+
+	if "" == rangeSplit[0] {
+		// "bytes=-N" selects the final N bytes
+		startOffset, err = strconv.Atoi(rangeSplit[1])
+		if (nil != err) || (0 >= startOffset) {
+			return
+		}
+		if startOffset > contentLength {
+			startOffset = contentLength
+		}
+		startOffset = contentLength - startOffset
+		stopOffset = contentLength
+	} else {
+		startOffset, err = strconv.Atoi(rangeSplit[0])
+		if (nil != err) || (startOffset >= contentLength) {
+			return
+		}
+		if "" == rangeSplit[1] {
+			stopOffset = contentLength
+		} else {
+			stopOffset, err = strconv.Atoi(rangeSplit[1])
+			if (nil != err) || (stopOffset < startOffset) {
+				return
+			}
+			stopOffset++
+			if stopOffset > contentLength {
+				stopOffset = contentLength
+			}
+		}
+	}
+
+	ok = true
+	return
+}
+
+// isAuthorized checks that the request is signed with the expected access key ID
+func isAuthorized(request *http.Request) (authorized bool) {
+	authorization := request.Header.Get("Authorization")
+
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=") {
+		return
+	}
+	if !strings.Contains(authorization, "Signature=") {
+		return
+	}
+	if "" == globals.accessKeyID {
+		authorized = true
+		return
+	}
+
+	credential := strings.TrimPrefix(authorization, "AWS4-HMAC-SHA256 Credential=")
+	authorized = strings.HasPrefix(credential, globals.accessKeyID+"/")
+	return
+}
+
+func doBucketPut(responseWriter http.ResponseWriter, request *http.Request, bucketName string) {
+	globals.Lock()
+	_, ok := globals.bucketMap[bucketName]
+	if ok {
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusConflict, "BucketAlreadyOwnedByYou", "bucket already exists")
+		return
+	}
+	globals.bucketMap[bucketName] = &bucketStruct{
+		name:               bucketName,
+		objectMap:          make(map[string]*objectStruct),
+		multipartUploadMap: make(map[string]*multipartUploadStruct),
+	}
+	globals.Unlock()
+
+	responseWriter.WriteHeader(http.StatusOK)
+}
+
+func doListObjects(responseWriter http.ResponseWriter, request *http.Request, bucket *bucketStruct) {
+	var (
+		commonPrefixSet   map[string]struct{}
+		continuationToken string
+		delimiter         string
+		key               string
+		keys              []string
+		lastReturned      string
+		prefix            string
+		result            listBucketResultStruct
+	)
+
+	query := request.URL.Query()
+
+	if "2" != query.Get("list-type") {
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusNotImplemented, "NotImplemented", "only ListObjectsV2 (list-type=2) is supported")
+		return
+	}
+
+	prefix = query.Get("prefix")
+	delimiter = query.Get("delimiter")
+	continuationToken = query.Get("continuation-token") // the last key (or common prefix) previously returned
+
+	keys = make([]string, 0, len(bucket.objectMap))
+	for key = range bucket.objectMap {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	globals.Unlock()
+
+	sort.Strings(keys)
+
+	result = listBucketResultStruct{
+		Name:           bucket.name,
+		Prefix:         prefix,
+		Delimiter:      delimiter,
+		MaxKeys:        globals.maxKeys,
+		Contents:       make([]listBucketResultContentsStruct, 0),
+		CommonPrefixes: make([]listBucketResultCommonPrefixStruct, 0),
+	}
+
+	commonPrefixSet = make(map[string]struct{})
+
+	for _, key = range keys {
+		entry := key
+		isCommonPrefix := false
+
+		if "" != delimiter {
+			delimiterIndex := strings.Index(key[len(prefix):], delimiter)
+			if 0 <= delimiterIndex {
+				entry = key[:len(prefix)+delimiterIndex+len(delimiter)]
+				isCommonPrefix = true
+			}
+		}
+
+		if ("" != continuationToken) && (entry <= continuationToken) {
+			continue
+		}
+
+		if isCommonPrefix {
+			_, ok := commonPrefixSet[entry]
+			if ok {
+				continue
+			}
+		}
+
+		if result.KeyCount == globals.maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = lastReturned
+			break
+		}
+
+		if isCommonPrefix {
+			commonPrefixSet[entry] = struct{}{}
+			result.CommonPrefixes = append(result.CommonPrefixes, listBucketResultCommonPrefixStruct{Prefix: entry})
+		} else {
+			result.Contents = append(result.Contents, listBucketResultContentsStruct{Key: key})
+		}
+
+		result.KeyCount++
+		lastReturned = entry
+	}
+
+	writeXML(responseWriter, http.StatusOK, &result)
+}
+
+func doObjectGetOrHead(responseWriter http.ResponseWriter, request *http.Request, bucket *bucketStruct, key string) {
+	object, ok := bucket.objectMap[key]
+	globals.Unlock()
+	if !ok {
+		writeError(responseWriter, request, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+		return
+	}
+
+	for name, values := range object.metadata {
+		responseWriter.Header()[name] = values
+	}
+	responseWriter.Header().Set("ETag", eTagOf(object.contents))
+
+	if "HEAD" == request.Method {
+		responseWriter.Header().Set("Content-Length", strconv.Itoa(len(object.contents)))
+		responseWriter.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rangeHeader := request.Header.Get("Range")
+	if "" == rangeHeader {
+		responseWriter.Header().Set("Content-Length", strconv.Itoa(len(object.contents)))
+		responseWriter.WriteHeader(http.StatusOK)
+		_, _ = responseWriter.Write(object.contents)
+		return
+	}
+
+	startOffset, stopOffset, ok := parseRangeHeader(rangeHeader, len(object.contents))
+	if !ok {
+		writeError(responseWriter, request, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "the requested range is not satisfiable")
+		return
+	}
+
+	responseWriter.Header().Set("Content-Length", strconv.Itoa(stopOffset-startOffset))
+	responseWriter.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", startOffset, stopOffset-1, len(object.contents)))
+	responseWriter.WriteHeader(http.StatusPartialContent)
+	_, _ = responseWriter.Write(object.contents[startOffset:stopOffset])
+}
+
+func doObjectPut(responseWriter http.ResponseWriter, request *http.Request, bucket *bucketStruct, key string, body []byte) {
+	query := request.URL.Query()
+
+	uploadID := query.Get("uploadId")
+	if "" != uploadID {
+		// UploadPart
+
+		multipartUpload, ok := bucket.multipartUploadMap[uploadID]
+		if !ok || (key != multipartUpload.key) {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+			return
+		}
+		partNumber, err := strconv.ParseUint(query.Get("partNumber"), 10, 16)
+		if (nil != err) || (1 > partNumber) || (10000 < partNumber) {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusBadRequest, "InvalidArgument", "partNumber must be an integer between 1 and 10000")
+			return
+		}
+		multipartUpload.partMap[uint16(partNumber)] = body
+		globals.Unlock()
+
+		responseWriter.Header().Set("ETag", eTagOf(body))
+		responseWriter.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// PutObject
+
+	if "*" == request.Header.Get("If-None-Match") {
+		_, ok := bucket.objectMap[key]
+		if ok {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusPreconditionFailed, "PreconditionFailed", "at least one of the pre-conditions you specified did not hold")
+			return
+		}
+	}
+
+	object := &objectStruct{contents: body, metadata: make(http.Header)}
+	for name, values := range request.Header {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			object.metadata[name] = values
+		}
+	}
+	bucket.objectMap[key] = object
+	globals.Unlock()
+
+	responseWriter.Header().Set("ETag", eTagOf(body))
+	responseWriter.WriteHeader(http.StatusOK)
+}
+
+func doObjectPost(responseWriter http.ResponseWriter, request *http.Request, bucket *bucketStruct, key string, body []byte) {
+	var (
+		completeMultipartUpload completeMultipartUploadStruct
+		contents                []byte
+		previousPartNumber      uint16
+	)
+
+	query := request.URL.Query()
+
+	_, ok := query["uploads"]
+	if ok {
+		// CreateMultipartUpload
+
+		globals.nextUploadID++
+		uploadID := fmt.Sprintf("rams3-upload-%016X", globals.nextUploadID)
+		bucket.multipartUploadMap[uploadID] = &multipartUploadStruct{key: key, partMap: make(map[uint16][]byte)}
+		globals.Unlock()
+
+		writeXML(responseWriter, http.StatusOK, &initiateMultipartUploadResultStruct{Bucket: bucket.name, Key: key, UploadID: uploadID})
+		return
+	}
+
+	uploadID := query.Get("uploadId")
+	if "" == uploadID {
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST requires ?uploads or ?uploadId")
+		return
+	}
+
+	// CompleteMultipartUpload
+
+	multipartUpload, ok := bucket.multipartUploadMap[uploadID]
+	if !ok || (key != multipartUpload.key) {
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+
+	err := xml.Unmarshal(body, &completeMultipartUpload)
+	if (nil != err) || (0 == len(completeMultipartUpload.Parts)) {
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusBadRequest, "MalformedXML", "unable to parse CompleteMultipartUpload")
+		return
+	}
+
+	contents = make([]byte, 0)
+
+	for _, part := range completeMultipartUpload.Parts {
+		if part.PartNumber <= previousPartNumber {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusBadRequest, "InvalidPartOrder", "parts must be listed in ascending PartNumber order")
+			return
+		}
+		previousPartNumber = part.PartNumber
+
+		partContents, ok := multipartUpload.partMap[part.PartNumber]
+		if !ok || (eTagOf(partContents) != part.ETag) {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %v not found or ETag mismatch", part.PartNumber))
+			return
+		}
+
+		contents = append(contents, partContents...)
+	}
+
+	bucket.objectMap[key] = &objectStruct{contents: contents, metadata: make(http.Header)}
+	delete(bucket.multipartUploadMap, uploadID)
+	globals.Unlock()
+
+	writeXML(responseWriter, http.StatusOK, &completeMultipartUploadResultStruct{Bucket: bucket.name, Key: key, ETag: eTagOf(contents)})
+}
+
+func doObjectDelete(responseWriter http.ResponseWriter, request *http.Request, bucket *bucketStruct, key string) {
+	uploadID := request.URL.Query().Get("uploadId")
+	if "" != uploadID {
+		// AbortMultipartUpload
+
+		_, ok := bucket.multipartUploadMap[uploadID]
+		if !ok {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+			return
+		}
+		delete(bucket.multipartUploadMap, uploadID)
+		globals.Unlock()
+
+		responseWriter.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// DeleteObject (which, per S3 semantics, succeeds even if key does not exist)
+
+	delete(bucket.objectMap, key)
+	globals.Unlock()
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+type httpRequestHandler struct{}
+
+func (h httpRequestHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if !isAuthorized(request) {
+		writeError(responseWriter, request, http.StatusForbidden, "InvalidAccessKeyId", "the access key ID provided does not exist in our records")
+		return
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if nil != err {
+		writeError(responseWriter, request, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	bucketName, key := parsePath(request)
+	if "" == bucketName {
+		writeError(responseWriter, request, http.StatusNotImplemented, "NotImplemented", "ListBuckets is not supported")
+		return
+	}
+
+	if ("" == key) && ("PUT" == request.Method) {
+		doBucketPut(responseWriter, request, bucketName)
+		return
+	}
+
+	globals.Lock() // released by each of the do...() funcs below
+
+	bucket, ok := globals.bucketMap[bucketName]
+	if !ok {
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusNotFound, "NoSuchBucket", "the specified bucket does not exist")
+		return
+	}
+
+	if "" == key {
+		if "GET" == request.Method {
+			doListObjects(responseWriter, request, bucket)
+		} else {
+			globals.Unlock()
+			writeError(responseWriter, request, http.StatusNotImplemented, "NotImplemented", "only PUT & GET (list) of a bucket are supported")
+		}
+		return
+	}
+
+	switch request.Method {
+	case "DELETE":
+		doObjectDelete(responseWriter, request, bucket, key)
+	case "GET", "HEAD":
+		doObjectGetOrHead(responseWriter, request, bucket, key)
+	case "POST":
+		doObjectPost(responseWriter, request, bucket, key, body)
+	case "PUT":
+		doObjectPut(responseWriter, request, bucket, key, body)
+	default:
+		globals.Unlock()
+		writeError(responseWriter, request, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not supported")
+	}
+}
+
+func serveRamS3(confMap conf.ConfMap) {
+	var (
+		bucketList []string
+		bucketName string
+		err        error
+	)
+
+	globals.tcpPort, err = confMap.FetchOptionValueUint16("RamS3", "TCPPort")
+	if nil != err {
+		log.Fatalf("failed fetch of RamS3.TCPPort: %v", err)
+	}
+
+	globals.accessKeyID, err = confMap.FetchOptionValueString("RamS3", "AccessKeyID")
+	if nil != err {
+		globals.accessKeyID = "" // TODO: Eventually, just return
+	}
+
+	maxKeys, err := confMap.FetchOptionValueUint32("RamS3", "MaxKeys")
+	if (nil != err) || (0 == maxKeys) {
+		maxKeys = 1000 // TODO: Eventually, just return
+	}
+	globals.maxKeys = int(maxKeys)
+
+	globals.Lock()
+
+	if nil == globals.bucketMap {
+		globals.bucketMap = make(map[string]*bucketStruct)
+	}
+
+	bucketList, err = confMap.FetchOptionValueStringSlice("RamS3", "BucketList")
+	if nil != err {
+		bucketList = []string{} // TODO: Eventually, just return
+	}
+
+	for _, bucketName = range bucketList {
+		_, ok := globals.bucketMap[bucketName]
+		if !ok {
+			globals.bucketMap[bucketName] = &bucketStruct{
+				name:               bucketName,
+				objectMap:          make(map[string]*objectStruct),
+				multipartUploadMap: make(map[string]*multipartUploadStruct),
+			}
+		}
+	}
+
+	globals.Unlock()
+
+	// Launch HTTP Server on the requested TCPPort
+
+	http.ListenAndServe("127.0.0.1:"+strconv.Itoa(int(globals.tcpPort)), httpRequestHandler{})
+}
+
+// Daemon is launched as a GoRoutine that launches rams3 that runs until signals... is received.
+// Unlike ramswift.Daemon(), SIGHUP is ignored as no settings may be updated.
+func Daemon(confFile string, confStrings []string, signalHandlerIsArmed *bool, doneChan chan bool, signals ...os.Signal) {
+	var (
+		confMap        conf.ConfMap
+		err            error
+		signalChan     chan os.Signal
+		signalReceived os.Signal
+	)
+
+	// Compute confMap
+
+	confMap, err = conf.MakeConfMapFromFile(confFile)
+	if nil != err {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	err = confMap.UpdateFromStrings(confStrings)
+	if nil != err {
+		log.Fatalf("failed to apply config overrides: %v", err)
+	}
+
+	// Kick off S3 Emulator
+
+	go serveRamS3(confMap)
+
+	// Arm signal handler used to indicate termination and wait on it
+	//
+	// Note: signalled chan must be buffered to avoid race with window between
+	// arming handler and blocking on the chan read
+
+	signalChan = make(chan os.Signal, 1)
+
+	signal.Notify(signalChan, signals...)
+
+	if nil != signalHandlerIsArmed {
+		*signalHandlerIsArmed = true
+	}
+
+	// Await a signal - ignoring SIGHUP - exiting otherwise
+
+	for {
+		signalReceived = <-signalChan
+
+		if unix.SIGHUP != signalReceived {
+			// signalReceived either SIGINT or SIGTERM... so just exit
+
+			doneChan <- true
+
+			return
+		}
+	}
+}
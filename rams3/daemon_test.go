@@ -0,0 +1,233 @@
+package rams3
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/s3client"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+func TestViaS3Client(t *testing.T) {
+	confStrings := []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+
+		"RamS3.TCPPort=45268",
+		"RamS3.AccessKeyID=TESTACCESSKEY",
+		"RamS3.MaxKeys=3",
+	}
+
+	confMap, err := conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) failed: %v", err)
+	}
+
+	err = logger.Up(confMap)
+	if nil != err {
+		t.Fatalf("logger.Up(confMap) failed: %v", err)
+	}
+
+	err = stats.Up(confMap)
+	if nil != err {
+		t.Fatalf("stats.Up(confMap) failed: %v", err)
+	}
+
+	signalHandlerIsArmed := false
+	doneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go Daemon("/dev/null", confStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
+
+	for !signalHandlerIsArmed {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	client, err := s3client.NewClient(s3client.Config{
+		Endpoint:        "http://127.0.0.1:45268",
+		Region:          "us-east-1",
+		AccessKeyID:     "TESTACCESSKEY",
+		SecretAccessKey: "TESTSECRETKEY",
+		Timeout:         10 * time.Second,
+		RetryLimit:      5,
+		RetryDelay:      100 * time.Millisecond,
+	})
+	if nil != err {
+		t.Fatalf("s3client.NewClient() failed: %v", err)
+	}
+
+	// Buckets
+
+	err = client.PutObject("bucket", "a", nil, []byte{})
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("PutObject() to missing bucket should have returned 404 but returned: %v", err)
+	}
+
+	err = client.CreateBucket("bucket")
+	if nil != err {
+		t.Fatalf("CreateBucket() failed: %v", err)
+	}
+	err = client.CreateBucket("bucket")
+	if nil != err {
+		t.Fatalf("CreateBucket() of existing bucket failed: %v", err)
+	}
+
+	// Objects, metadata, & ranges
+
+	err = client.PutObject("bucket", "dir/obj", map[string]string{"Test-Name": "test-value"}, []byte("0123456789"))
+	if nil != err {
+		t.Fatalf("PutObject() failed: %v", err)
+	}
+
+	headers, err := client.HeadObject("bucket", "dir/obj")
+	if nil != err {
+		t.Fatalf("HeadObject() failed: %v", err)
+	}
+	if ("10" != headers.Get("Content-Length")) || ("test-value" != headers.Get("X-Amz-Meta-Test-Name")) {
+		t.Fatalf("HeadObject() returned unexpected headers: %v", headers)
+	}
+
+	for byteRange, expected := range map[string]string{"": "0123456789", "bytes=2-4": "234", "bytes=7-": "789", "bytes=-3": "789", "bytes=8-100": "89"} {
+		buf, err := client.GetObject("bucket", "dir/obj", byteRange)
+		if nil != err {
+			t.Fatalf("GetObject(,,\"%v\") failed: %v", byteRange, err)
+		}
+		if expected != string(buf) {
+			t.Fatalf("GetObject(,,\"%v\") returned \"%v\" (expected \"%v\")", byteRange, string(buf), expected)
+		}
+	}
+
+	_, err = client.GetObject("bucket", "dir/obj", "bytes=10-11")
+	if http.StatusRequestedRangeNotSatisfiable != blunder.HTTPCode(err) {
+		t.Fatalf("GetObject() beyond EOF should have returned 416 but returned: %v", err)
+	}
+
+	// Listing (with MaxKeys small enough to require continuation)
+
+	for _, key := range []string{"dir/a", "dir/b", "dir/sub/x", "dir/sub/y", "dir2/z", "top"} {
+		err = client.PutObject("bucket", key, nil, []byte(key))
+		if nil != err {
+			t.Fatalf("PutObject(,\"%v\") failed: %v", key, err)
+		}
+	}
+
+	keys, commonPrefixes, err := client.ListObjects("bucket", "", "/")
+	if nil != err {
+		t.Fatalf("ListObjects(,\"\",\"/\") failed: %v", err)
+	}
+	if ("[top]" != fmt.Sprint(keys)) || ("[dir/ dir2/]" != fmt.Sprint(commonPrefixes)) {
+		t.Fatalf("ListObjects(,\"\",\"/\") returned keys %v commonPrefixes %v", keys, commonPrefixes)
+	}
+
+	keys, commonPrefixes, err = client.ListObjects("bucket", "dir/", "/")
+	if nil != err {
+		t.Fatalf("ListObjects(,\"dir/\",\"/\") failed: %v", err)
+	}
+	if ("[dir/a dir/b dir/obj]" != fmt.Sprint(keys)) || ("[dir/sub/]" != fmt.Sprint(commonPrefixes)) {
+		t.Fatalf("ListObjects(,\"dir/\",\"/\") returned keys %v commonPrefixes %v", keys, commonPrefixes)
+	}
+
+	keys, _, err = client.ListObjects("bucket", "dir/", "")
+	if nil != err {
+		t.Fatalf("ListObjects(,\"dir/\",\"\") failed: %v", err)
+	}
+	if "[dir/a dir/b dir/obj dir/sub/x dir/sub/y]" != fmt.Sprint(keys) {
+		t.Fatalf("ListObjects(,\"dir/\",\"\") returned keys %v", keys)
+	}
+
+	// Multipart uploads
+
+	uploadID, err := client.CreateMultipartUpload("bucket", "multi")
+	if nil != err {
+		t.Fatalf("CreateMultipartUpload() failed: %v", err)
+	}
+	parts := make([]s3client.Part, 0)
+	expectedContents := make([]byte, 0)
+	for partNumber := uint16(1); partNumber <= 3; partNumber++ {
+		partBuf := bytes.Repeat([]byte{byte(partNumber)}, 100)
+		eTag, err := client.UploadPart("bucket", "multi", uploadID, partNumber, partBuf)
+		if nil != err {
+			t.Fatalf("UploadPart(,,,%v,) failed: %v", partNumber, err)
+		}
+		parts = append(parts, s3client.Part{PartNumber: partNumber, ETag: eTag})
+		expectedContents = append(expectedContents, partBuf...)
+	}
+
+	_, err = client.HeadObject("bucket", "multi")
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("HeadObject() of incomplete multipart upload should have returned 404 but returned: %v", err)
+	}
+
+	err = client.CompleteMultipartUpload("bucket", "multi", uploadID, parts[1:2])
+	if nil != err {
+		t.Fatalf("CompleteMultipartUpload() of a subset of parts failed: %v", err)
+	}
+	buf, err := client.GetObject("bucket", "multi", "")
+	if (nil != err) || !bytes.Equal(expectedContents[100:200], buf) {
+		t.Fatalf("GetObject() of completed multipart upload returned wrong data (err: %v)", err)
+	}
+
+	err = client.CompleteMultipartUpload("bucket", "multi", uploadID, parts)
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("CompleteMultipartUpload() of completed upload should have returned 404 but returned: %v", err)
+	}
+
+	uploadID, err = client.CreateMultipartUpload("bucket", "aborted")
+	if nil != err {
+		t.Fatalf("CreateMultipartUpload() failed: %v", err)
+	}
+	_, err = client.UploadPart("bucket", "aborted", uploadID, 1, []byte("abc"))
+	if nil != err {
+		t.Fatalf("UploadPart() failed: %v", err)
+	}
+	err = client.AbortMultipartUpload("bucket", "aborted", uploadID)
+	if nil != err {
+		t.Fatalf("AbortMultipartUpload() failed: %v", err)
+	}
+	_, err = client.HeadObject("bucket", "aborted")
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("HeadObject() of aborted multipart upload should have returned 404 but returned: %v", err)
+	}
+
+	// Deletes (which succeed even for missing keys)
+
+	err = client.DeleteObject("bucket", "dir/obj")
+	if nil != err {
+		t.Fatalf("DeleteObject() failed: %v", err)
+	}
+	err = client.DeleteObject("bucket", "dir/obj")
+	if nil != err {
+		t.Fatalf("DeleteObject() of missing key failed: %v", err)
+	}
+	_, err = client.GetObject("bucket", "dir/obj", "")
+	if http.StatusNotFound != blunder.HTTPCode(err) {
+		t.Fatalf("GetObject() of deleted key should have returned 404 but returned: %v", err)
+	}
+
+	// Shutdown packages
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("stats.Down() failed: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("logger.Down() failed: %v", err)
+	}
+
+	// Send ourself a SIGTERM to terminate Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-doneChan
+}
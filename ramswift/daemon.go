@@ -4,6 +4,7 @@
 package ramswift
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -14,6 +15,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"golang.org/x/sys/unix"
 
@@ -70,15 +72,21 @@ type globalsStruct struct {
 	containerMethodCount            methodStruct
 	objectMethodCount               methodStruct
 	chaosFailureHTTPStatus          int
-	accountMethodChaosFailureRate   methodStruct // fail method if corresponding accountMethodCount divisible by accountMethodChaosFailureRate
-	containerMethodChaosFailureRate methodStruct // fail method if corresponding containerMethodCount divisible by containerMethodChaosFailureRate
-	objectMethodChaosFailureRate    methodStruct // fail method if corresponding objectMethodCount divisible by objectMethodChaosFailureRate
+	chaosStallDuration              time.Duration // if non-zero, injected failures first stall this long
+	accountMethodChaosFailureRate   methodStruct  // fail method if corresponding accountMethodCount divisible by accountMethodChaosFailureRate
+	containerMethodChaosFailureRate methodStruct  // fail method if corresponding containerMethodCount divisible by containerMethodChaosFailureRate
+	objectMethodChaosFailureRate    methodStruct  // fail method if corresponding objectMethodCount divisible by objectMethodChaosFailureRate
 	maxAccountNameLength            uint64
 	maxContainerNameLength          uint64
 	maxObjectNameLength             uint64
+	authUser                        string               // if "", requests need not (and cannot) be authenticated
+	authKey                         string               // TempAuth key or Keystone password
+	authTokenLifetime               time.Duration        // reported via X-Auth-Token-Expires (TempAuth) or expires_at (Keystone)
+	authTokenNonce                  uint64               // used to generate unique tokens
+	authTokenMap                    map[string]time.Time // key is token, value is its expiry
 }
 
-var globals = globalsStruct{swiftAccountMap: make(map[string]*swiftAccountStruct)}
+var globals = globalsStruct{swiftAccountMap: make(map[string]*swiftAccountStruct), authTokenMap: make(map[string]time.Time)}
 
 type stringSet map[string]bool
 
@@ -461,6 +469,28 @@ func createOrLocateSwiftObject(swiftContainer *swiftContainerStruct, swiftObject
 	return
 }
 
+// createSwiftObjectIfAbsent atomically creates the named Object with the supplied contents
+// unless it already exists (in which case the existing Object is left untouched).
+func createSwiftObjectIfAbsent(swiftContainer *swiftContainerStruct, swiftObjectName string, contents []byte) (wasCreated bool) {
+	swiftContainer.Lock()
+	_, ok, err := swiftContainer.swiftObjectTree.GetByKey(swiftObjectName)
+	if nil != err {
+		panic(err)
+	}
+	if ok {
+		wasCreated = false
+	} else {
+		swiftObject := &swiftObjectStruct{name: swiftObjectName, swiftContainer: swiftContainer, contents: contents}
+		_, err = swiftContainer.swiftObjectTree.Put(swiftObjectName, swiftObject)
+		if nil != err {
+			panic(err)
+		}
+		wasCreated = true
+	}
+	swiftContainer.Unlock()
+	return
+}
+
 func deleteSwiftObject(swiftContainer *swiftContainerStruct, swiftObjectName string) (errno syscall.Errno) {
 	swiftContainer.Lock()
 	_, ok, err := swiftContainer.swiftObjectTree.GetByKey(swiftObjectName)
@@ -493,7 +523,7 @@ func doDelete(responseWriter http.ResponseWriter, request *http.Request) {
 			globals.accountMethodCount.delete++
 			if (0 != globals.accountMethodChaosFailureRate.delete) && (0 == globals.accountMethodCount.delete%globals.accountMethodChaosFailureRate.delete) {
 				globals.Unlock()
-				responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+				injectChaosFailure(responseWriter)
 			} else {
 				globals.Unlock()
 				errno := deleteSwiftAccount(swiftAccountName, false)
@@ -520,7 +550,7 @@ func doDelete(responseWriter http.ResponseWriter, request *http.Request) {
 					globals.containerMethodCount.delete++
 					if (0 != globals.containerMethodChaosFailureRate.delete) && (0 == globals.containerMethodCount.delete%globals.containerMethodChaosFailureRate.delete) {
 						globals.Unlock()
-						responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+						injectChaosFailure(responseWriter)
 					} else {
 						globals.Unlock()
 						errno := deleteSwiftContainer(swiftAccount, swiftContainerName)
@@ -542,7 +572,7 @@ func doDelete(responseWriter http.ResponseWriter, request *http.Request) {
 					globals.objectMethodCount.delete++
 					if (0 != globals.objectMethodChaosFailureRate.delete) && (0 == globals.objectMethodCount.delete%globals.objectMethodChaosFailureRate.delete) {
 						globals.Unlock()
-						responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+						injectChaosFailure(responseWriter)
 					} else {
 						globals.Unlock()
 						swiftContainer, errno := locateSwiftContainer(swiftAccount, swiftContainerName)
@@ -599,7 +629,7 @@ func doGet(responseWriter http.ResponseWriter, request *http.Request) {
 					globals.accountMethodCount.get++
 					if (0 != globals.accountMethodChaosFailureRate.get) && (0 == globals.accountMethodCount.get%globals.accountMethodChaosFailureRate.get) {
 						globals.Unlock()
-						responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+						injectChaosFailure(responseWriter)
 					} else {
 						globals.Unlock()
 						swiftAccount.Lock()
@@ -638,7 +668,7 @@ func doGet(responseWriter http.ResponseWriter, request *http.Request) {
 							globals.containerMethodCount.get++
 							if (0 != globals.containerMethodChaosFailureRate.get) && (0 == globals.containerMethodCount.get%globals.containerMethodChaosFailureRate.get) {
 								globals.Unlock()
-								responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+								injectChaosFailure(responseWriter)
 							} else {
 								globals.Unlock()
 								swiftContainer.Lock()
@@ -672,7 +702,7 @@ func doGet(responseWriter http.ResponseWriter, request *http.Request) {
 							globals.objectMethodCount.get++
 							if (0 != globals.objectMethodChaosFailureRate.get) && (0 == globals.objectMethodCount.get%globals.objectMethodChaosFailureRate.get) {
 								globals.Unlock()
-								responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+								injectChaosFailure(responseWriter)
 							} else {
 								globals.Unlock()
 								swiftObject, errno := locateSwiftObject(swiftContainer, swiftObjectName)
@@ -754,7 +784,7 @@ func doHead(responseWriter http.ResponseWriter, request *http.Request) {
 				globals.accountMethodCount.head++
 				if (0 != globals.accountMethodChaosFailureRate.head) && (0 == globals.accountMethodCount.head%globals.accountMethodChaosFailureRate.head) {
 					globals.Unlock()
-					responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+					injectChaosFailure(responseWriter)
 				} else {
 					globals.Unlock()
 					swiftAccount.Lock()
@@ -777,7 +807,7 @@ func doHead(responseWriter http.ResponseWriter, request *http.Request) {
 						globals.containerMethodCount.head++
 						if (0 != globals.containerMethodChaosFailureRate.head) && (0 == globals.containerMethodCount.head%globals.containerMethodChaosFailureRate.head) {
 							globals.Unlock()
-							responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+							injectChaosFailure(responseWriter)
 						} else {
 							globals.Unlock()
 							swiftContainer.Lock()
@@ -795,7 +825,7 @@ func doHead(responseWriter http.ResponseWriter, request *http.Request) {
 						globals.objectMethodCount.head++
 						if (0 != globals.objectMethodChaosFailureRate.head) && (0 == globals.objectMethodCount.head%globals.objectMethodChaosFailureRate.head) {
 							globals.Unlock()
-							responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+							injectChaosFailure(responseWriter)
 						} else {
 							globals.Unlock()
 							swiftObject, errno := locateSwiftObject(swiftContainer, swiftObjectName)
@@ -841,7 +871,7 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 				globals.accountMethodCount.post++
 				if (0 != globals.accountMethodChaosFailureRate.post) && (0 == globals.accountMethodCount.post%globals.accountMethodChaosFailureRate.post) {
 					globals.Unlock()
-					responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+					injectChaosFailure(responseWriter)
 				} else {
 					globals.Unlock()
 					swiftAccount.Lock()
@@ -876,7 +906,7 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 						globals.containerMethodCount.post++
 						if (0 != globals.containerMethodChaosFailureRate.post) && (0 == globals.containerMethodCount.post%globals.containerMethodChaosFailureRate.post) {
 							globals.Unlock()
-							responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+							injectChaosFailure(responseWriter)
 						} else {
 							globals.Unlock()
 							swiftContainer.Lock()
@@ -906,7 +936,7 @@ func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 						globals.objectMethodCount.post++
 						if (0 != globals.objectMethodChaosFailureRate.post) && (0 == globals.objectMethodCount.post%globals.objectMethodChaosFailureRate.post) {
 							globals.Unlock()
-							responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+							injectChaosFailure(responseWriter)
 						} else {
 							globals.Unlock()
 							responseWriter.WriteHeader(http.StatusForbidden)
@@ -939,7 +969,7 @@ func doPut(responseWriter http.ResponseWriter, request *http.Request) {
 			globals.accountMethodCount.put++
 			if (0 != globals.accountMethodChaosFailureRate.put) && (0 == globals.accountMethodCount.put%globals.accountMethodChaosFailureRate.put) {
 				globals.Unlock()
-				responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+				injectChaosFailure(responseWriter)
 			} else {
 				globals.Unlock()
 				swiftAccount, wasCreated := createOrLocateSwiftAccount(swiftAccountName)
@@ -982,7 +1012,7 @@ func doPut(responseWriter http.ResponseWriter, request *http.Request) {
 					globals.containerMethodCount.put++
 					if (0 != globals.containerMethodChaosFailureRate.put) && (0 == globals.containerMethodCount.put%globals.containerMethodChaosFailureRate.put) {
 						globals.Unlock()
-						responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+						injectChaosFailure(responseWriter)
 					} else {
 						globals.Unlock()
 						swiftContainer, wasCreated := createOrLocateSwiftContainer(swiftAccount, swiftContainerName)
@@ -1020,12 +1050,21 @@ func doPut(responseWriter http.ResponseWriter, request *http.Request) {
 					globals.objectMethodCount.put++
 					if (0 != globals.objectMethodChaosFailureRate.put) && (0 == globals.objectMethodCount.put%globals.objectMethodChaosFailureRate.put) {
 						globals.Unlock()
-						responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
+						injectChaosFailure(responseWriter)
 					} else {
 						globals.Unlock()
 						swiftContainer, errno := locateSwiftContainer(swiftAccount, swiftContainerName)
 						switch errno {
 						case 0:
+							if "*" == request.Header.Get("If-None-Match") {
+								contents, _ := ioutil.ReadAll(request.Body)
+								if createSwiftObjectIfAbsent(swiftContainer, swiftObjectName, contents) {
+									responseWriter.WriteHeader(http.StatusCreated)
+								} else {
+									responseWriter.WriteHeader(http.StatusPreconditionFailed)
+								}
+								break
+							}
 							swiftObject, wasCreated := createOrLocateSwiftObject(swiftContainer, swiftObjectName)
 							swiftObject.Lock()
 							swiftObject.contents, _ = ioutil.ReadAll(request.Body)
@@ -1056,6 +1095,19 @@ func doPut(responseWriter http.ResponseWriter, request *http.Request) {
 type httpRequestHandler struct{}
 
 func (h httpRequestHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if (http.MethodGet == request.Method) && ("/auth/v1.0" == request.URL.Path) {
+		doTempAuth(responseWriter, request)
+		return
+	}
+	if (http.MethodPost == request.Method) && ("/v3/auth/tokens" == request.URL.Path) {
+		doKeystoneAuth(responseWriter, request)
+		return
+	}
+	if ("/info" != request.URL.Path) && !isAuthorized(request) {
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	switch request.Method {
 	case http.MethodDelete:
 		doDelete(responseWriter, request)
@@ -1135,6 +1187,10 @@ func serveNoAuthSwift(confMap conf.ConfMap) {
 
 	fetchSwiftInfo(confMap)
 
+	// Fetch auth settings
+
+	fetchAuthSettings(confMap)
+
 	// Launch HTTP Server on the requested noAuthTCPPort
 
 	http.ListenAndServe("127.0.0.1:"+strconv.Itoa(int(globals.noAuthTCPPort)), httpRequestHandler{})
@@ -1238,6 +1294,20 @@ func updateConf(confMap conf.ConfMap) {
 	// Fetch (potentially updated) responses for GETs on /info
 
 	fetchSwiftInfo(confMap)
+
+	// Fetch (potentially updated) auth settings
+
+	fetchAuthSettings(confMap)
+}
+
+// injectChaosFailure responds with globals.chaosFailureHTTPStatus... after first stalling (emulating
+// a hung Swift Proxy) if globals.chaosStallDuration is non-zero.
+func injectChaosFailure(responseWriter http.ResponseWriter) {
+	if 0 != globals.chaosStallDuration {
+		time.Sleep(globals.chaosStallDuration)
+	}
+
+	responseWriter.WriteHeader(globals.chaosFailureHTTPStatus)
 }
 
 func fetchChaosSettings(confMap conf.ConfMap) {
@@ -1253,6 +1323,11 @@ func fetchChaosSettings(confMap conf.ConfMap) {
 		globals.chaosFailureHTTPStatus = http.StatusInternalServerError
 	}
 
+	globals.chaosStallDuration, err = confMap.FetchOptionValueDuration("RamSwiftChaos", "StallDuration")
+	if nil != err {
+		globals.chaosStallDuration = 0
+	}
+
 	globals.accountMethodChaosFailureRate.delete, err = confMap.FetchOptionValueUint64("RamSwiftChaos", "AccountDeleteFailureRate")
 	if nil != err {
 		globals.accountMethodChaosFailureRate.delete = 0
@@ -1347,6 +1422,164 @@ func fetchSwiftInfo(confMap conf.ConfMap) {
 	}
 }
 
+func fetchAuthSettings(confMap conf.ConfMap) {
+	var (
+		err error
+	)
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	globals.authUser, err = confMap.FetchOptionValueString("RamSwiftAuth", "User")
+	if nil != err {
+		globals.authUser = "" // TODO: Eventually, just return
+	}
+	globals.authKey, err = confMap.FetchOptionValueString("RamSwiftAuth", "Key")
+	if nil != err {
+		globals.authKey = "" // TODO: Eventually, just return
+	}
+	globals.authTokenLifetime, err = confMap.FetchOptionValueDuration("RamSwiftAuth", "TokenLifetime")
+	if nil != err {
+		globals.authTokenLifetime = time.Hour // TODO: Eventually, just return
+	}
+}
+
+// issueAuthToken returns a new token for globals.authUser (along with its expiry) and the account it may access.
+func issueAuthToken() (token string, tokenExpiry time.Time, accountName string) {
+	globals.Lock()
+	globals.authTokenNonce++
+	token = fmt.Sprintf("AUTH_tk%016X", globals.authTokenNonce)
+	tokenExpiry = time.Now().Add(globals.authTokenLifetime)
+	globals.authTokenMap[token] = tokenExpiry
+	globals.Unlock()
+
+	// TempAuth users are typically of the form <account>:<user>
+
+	accountName = "AUTH_" + strings.SplitN(globals.authUser, ":", 2)[0]
+
+	return
+}
+
+func isAuthorized(request *http.Request) (authorized bool) {
+	globals.Lock()
+	defer globals.Unlock()
+
+	if "" == globals.authUser {
+		authorized = true
+		return
+	}
+
+	tokenExpiry, ok := globals.authTokenMap[request.Header.Get("X-Auth-Token")]
+	authorized = ok && time.Now().Before(tokenExpiry)
+
+	return
+}
+
+func doTempAuth(responseWriter http.ResponseWriter, request *http.Request) {
+	globals.Lock()
+	authUser := globals.authUser
+	authKey := globals.authKey
+	globals.Unlock()
+
+	if ("" == authUser) || (request.Header.Get("X-Auth-User") != authUser) || (request.Header.Get("X-Auth-Key") != authKey) {
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	token, tokenExpiry, accountName := issueAuthToken()
+
+	responseWriter.Header().Set("X-Auth-Token", token)
+	responseWriter.Header().Set("X-Storage-Token", token)
+	responseWriter.Header().Set("X-Storage-Url", "http://"+request.Host+"/v1/"+accountName)
+	responseWriter.Header().Set("X-Auth-Token-Expires", strconv.FormatInt(int64(tokenExpiry.Sub(time.Now())/time.Second), 10))
+	responseWriter.WriteHeader(http.StatusOK)
+}
+
+type keystoneAuthRequestStruct struct {
+	Auth struct {
+		Identity struct {
+			Methods  []string `json:"methods"`
+			Password struct {
+				User struct {
+					Name     string `json:"name"`
+					Password string `json:"password"`
+				} `json:"user"`
+			} `json:"password"`
+		} `json:"identity"`
+	} `json:"auth"`
+}
+
+type keystoneEndpointStruct struct {
+	Interface string `json:"interface"`
+	URL       string `json:"url"`
+}
+
+type keystoneServiceStruct struct {
+	Type      string                   `json:"type"`
+	Endpoints []keystoneEndpointStruct `json:"endpoints"`
+}
+
+type keystoneTokenStruct struct {
+	ExpiresAt string                  `json:"expires_at"`
+	Catalog   []keystoneServiceStruct `json:"catalog"`
+}
+
+type keystoneAuthResponseStruct struct {
+	Token keystoneTokenStruct `json:"token"`
+}
+
+func doKeystoneAuth(responseWriter http.ResponseWriter, request *http.Request) {
+	var (
+		authRequest keystoneAuthRequestStruct
+	)
+
+	globals.Lock()
+	authUser := globals.authUser
+	authKey := globals.authKey
+	globals.Unlock()
+
+	requestBody, err := ioutil.ReadAll(request.Body)
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	err = json.Unmarshal(requestBody, &authRequest)
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if ("" == authUser) || (authRequest.Auth.Identity.Password.User.Name != authUser) || (authRequest.Auth.Identity.Password.User.Password != authKey) {
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	token, tokenExpiry, accountName := issueAuthToken()
+
+	responseBody, err := json.Marshal(keystoneAuthResponseStruct{
+		Token: keystoneTokenStruct{
+			ExpiresAt: tokenExpiry.UTC().Format(time.RFC3339Nano),
+			Catalog: []keystoneServiceStruct{
+				{
+					Type: "object-store",
+					Endpoints: []keystoneEndpointStruct{
+						{Interface: "public", URL: "http://" + request.Host + "/v1/" + accountName},
+					},
+				},
+			},
+		},
+	})
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	responseWriter.Header().Set("X-Subject-Token", token)
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusCreated)
+	_, _ = responseWriter.Write(responseBody)
+}
+
 func Daemon(confFile string, confStrings []string, signalHandlerIsArmed *bool, doneChan chan bool, signals ...os.Signal) {
 	var (
 		confMap        conf.ConfMap
@@ -34,15 +34,20 @@ PACKAGES = ["blunder",
             "jrpcfs",
             "logger",
             "mkproxyfs", "mkproxyfs/mkproxyfs",
+            "objectstore",
             "pfs-stress",
             "pfsconfjson", "pfsconfjsonpacked",
+            "pfsinspect",
             "pfsworkout",
             "platform",
             "proxyfsd", "proxyfsd/proxyfsd",
+            "rams3", "rams3/rams3",
             "ramswift", "ramswift/ramswift",
+            "s3client",
             "stats",
             "statslogger",
             "swiftclient",
+            "testenv",
             "utils"]
 
 
@@ -0,0 +1,130 @@
+// Package s3client provides API access to the subset of the Amazon S3 REST API (path-style requests signed
+// via AWS Signature Version 4) needed to hold a ProxyFS volume's LogSegments and checkpoints.
+package s3client
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+// Config describes an S3 (or S3-compatible) endpoint and the credentials used to access it.
+type Config struct {
+	Endpoint        string        // e.g. "https://s3.us-east-1.amazonaws.com"... buckets are addressed path-style
+	Region          string        // e.g. "us-east-1"
+	AccessKeyID     string        //
+	SecretAccessKey string        //
+	Timeout         time.Duration // bounds each request (0 means unbounded)
+	RetryLimit      uint16        // number of times a request failing with a network error or 5xx status is retried
+	RetryDelay      time.Duration // delay prior to the first retry... doubling for each subsequent retry
+}
+
+// Client issues signed requests to the S3 endpoint described by its Config.
+type Client struct {
+	config     Config
+	endpoint   *url.URL
+	httpClient *http.Client
+}
+
+// Part identifies an uploaded part of a multipart upload.
+type Part struct {
+	PartNumber uint16
+	ETag       string
+}
+
+// NewClient returns a Client for the S3 endpoint described by config.
+func NewClient(config Config) (client *Client, err error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if nil != err {
+		err = blunder.NewError(blunder.InvalidArgError, "s3client.NewClient() unable to parse Endpoint \"%v\": %v", config.Endpoint, err)
+		return
+	}
+	if (("http" != endpoint.Scheme) && ("https" != endpoint.Scheme)) || ("" == endpoint.Host) {
+		err = blunder.NewError(blunder.InvalidArgError, "s3client.NewClient() requires an http:// or https:// Endpoint (got \"%v\")", config.Endpoint)
+		return
+	}
+	if "" == config.Region {
+		err = blunder.NewError(blunder.InvalidArgError, "s3client.NewClient() requires a Region")
+		return
+	}
+
+	client = &Client{
+		config:     config,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+
+	err = nil
+	return
+}
+
+// CreateBucket creates bucket (succeeding if it already exists and is owned by us).
+func (client *Client) CreateBucket(bucket string) (err error) {
+	err = client.createBucket(bucket)
+	return
+}
+
+// HeadObject returns the headers (including any x-amz-meta-* metadata) of the object at key.
+func (client *Client) HeadObject(bucket string, key string) (headers http.Header, err error) {
+	headers, err = client.headObject(bucket, key)
+	return
+}
+
+// GetObject returns the contents of the object at key. If byteRange is non-empty, it is sent
+// as the Range header (e.g. "bytes=0-9" or "bytes=-10").
+func (client *Client) GetObject(bucket string, key string, byteRange string) (buf []byte, err error) {
+	buf, err = client.getObject(bucket, key, byteRange)
+	return
+}
+
+// PutObject creates (or replaces) the object at key with buf and the supplied x-amz-meta-* metadata.
+func (client *Client) PutObject(bucket string, key string, metadata map[string]string, buf []byte) (err error) {
+	err = client.putObject(bucket, key, metadata, buf)
+	return
+}
+
+// PutObjectIfNoneMatch creates the object at key with buf only if no object exists at key. Otherwise,
+// the existing object is left untouched and the returned err carries HTTP StatusCode 412.
+func (client *Client) PutObjectIfNoneMatch(bucket string, key string, buf []byte) (err error) {
+	err = client.putObjectIfNoneMatch(bucket, key, buf)
+	return
+}
+
+// DeleteObject removes the object at key. Note that, per S3 semantics, deleting a missing object succeeds.
+func (client *Client) DeleteObject(bucket string, key string) (err error) {
+	err = client.deleteObject(bucket, key)
+	return
+}
+
+// ListObjects returns the keys beginning with prefix. If delimiter is non-empty, keys containing delimiter
+// after prefix are instead rolled up into commonPrefixes. Truncated listings are followed to completion.
+func (client *Client) ListObjects(bucket string, prefix string, delimiter string) (keys []string, commonPrefixes []string, err error) {
+	keys, commonPrefixes, err = client.listObjects(bucket, prefix, delimiter)
+	return
+}
+
+// CreateMultipartUpload initiates a multipart upload to key.
+func (client *Client) CreateMultipartUpload(bucket string, key string) (uploadID string, err error) {
+	uploadID, err = client.createMultipartUpload(bucket, key)
+	return
+}
+
+// UploadPart uploads buf as part partNumber (1 to 10000) of the multipart upload.
+func (client *Client) UploadPart(bucket string, key string, uploadID string, partNumber uint16, buf []byte) (eTag string, err error) {
+	eTag, err = client.uploadPart(bucket, key, uploadID, partNumber, buf)
+	return
+}
+
+// CompleteMultipartUpload assembles the uploaded parts (in ascending PartNumber order) into the object at key.
+func (client *Client) CompleteMultipartUpload(bucket string, key string, uploadID string, parts []Part) (err error) {
+	err = client.completeMultipartUpload(bucket, key, uploadID, parts)
+	return
+}
+
+// AbortMultipartUpload discards the multipart upload and any parts uploaded for it.
+func (client *Client) AbortMultipartUpload(bucket string, key string, uploadID string) (err error) {
+	err = client.abortMultipartUpload(bucket, key, uploadID)
+	return
+}
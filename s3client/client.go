@@ -0,0 +1,364 @@
+package s3client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+type errorResponseStruct struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type listBucketResultStruct struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+type initiateMultipartUploadResultStruct struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUploadPartStruct struct {
+	PartNumber uint16 `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUploadStruct struct {
+	XMLName xml.Name                            `xml:"CompleteMultipartUpload"`
+	Parts   []completeMultipartUploadPartStruct `xml:"Part"`
+}
+
+// httpStatusToFsError maps an unsuccessful HTTP status to the blunder.FsError reported for it
+func httpStatusToFsError(method string, httpStatus int) (fsErr blunder.FsError) {
+	switch httpStatus {
+	case http.StatusNotFound:
+		fsErr = blunder.NotFoundError
+	case http.StatusForbidden:
+		fsErr = blunder.PermDeniedError
+	case http.StatusPreconditionFailed:
+		fsErr = blunder.FileExistsError
+	case http.StatusRequestedRangeNotSatisfiable:
+		fsErr = blunder.OutOfRangeError
+	default:
+		switch method {
+		case "DELETE":
+			fsErr = blunder.BadHTTPDeleteError
+		case "GET":
+			fsErr = blunder.BadHTTPGetError
+		case "HEAD":
+			fsErr = blunder.BadHTTPHeadError
+		default:
+			fsErr = blunder.BadHTTPPutError
+		}
+	}
+	return
+}
+
+// doRequest issues a signed request, retrying (per Config.RetryLimit & Config.RetryDelay) those failing
+// with a network error or 5xx status. Any other non-2xx status is returned as an error (with its HTTP code).
+func (client *Client) doRequest(method string, bucket string, key string, query url.Values, headers http.Header, body []byte) (responseHeaders http.Header, responseBody []byte, err error) {
+	var (
+		errorResponse errorResponseStruct
+		httpResponse  *http.Response
+		httpStatus    int
+		path          string
+		request       *http.Request
+		retryCount    uint16
+		retryDelay    time.Duration
+	)
+
+	stats.IncrementOperations(&stats.S3RequestOps)
+
+	path = "/" + bucket
+	if "" != key {
+		path += "/" + key
+	}
+
+	retryDelay = client.config.RetryDelay
+
+	for {
+		request, err = http.NewRequest(method, client.endpoint.String(), bytes.NewReader(body))
+		if nil != err {
+			err = blunder.NewError(blunder.InvalidArgError, "s3client %v /%v/%v unable to form request: %v", method, bucket, key, err)
+			return
+		}
+
+		request.URL.Path = path
+		request.URL.RawPath = uriEncode(path, false)
+		request.URL.RawQuery = canonicalQueryString(query)
+		request.ContentLength = int64(len(body))
+
+		for name, values := range headers {
+			for _, value := range values {
+				request.Header.Add(name, value)
+			}
+		}
+
+		signRequest(request, payloadHash(body), client.config.AccessKeyID, client.config.SecretAccessKey, client.config.Region, time.Now())
+
+		httpResponse, err = client.httpClient.Do(request)
+		if nil == err {
+			responseBody, err = ioutil.ReadAll(httpResponse.Body)
+			_ = httpResponse.Body.Close()
+			if nil == err {
+				httpStatus = httpResponse.StatusCode
+				responseHeaders = httpResponse.Header
+				if (http.StatusOK <= httpStatus) && (http.StatusMultipleChoices > httpStatus) {
+					if 0 < retryCount {
+						logger.Infof("s3client %v /%v/%v succeeded after %v retries", method, bucket, key, retryCount)
+					}
+					err = nil
+					return
+				}
+
+				errorResponse = errorResponseStruct{}
+				_ = xml.Unmarshal(responseBody, &errorResponse)
+
+				err = blunder.NewError(httpStatusToFsError(method, httpStatus), "s3client %v /%v/%v returned HTTP StatusCode %d (%v: %v)", method, bucket, key, httpStatus, errorResponse.Code, errorResponse.Message)
+				err = blunder.AddHTTPCode(err, httpStatus)
+
+				if http.StatusInternalServerError > httpStatus {
+					stats.IncrementOperations(&stats.S3RequestFailureOps)
+					return
+				}
+			}
+		}
+
+		if retryCount >= client.config.RetryLimit {
+			stats.IncrementOperations(&stats.S3RequestFailureOps)
+			logger.ErrorfWithError(err, "s3client %v /%v/%v failed after %v retries", method, bucket, key, retryCount)
+			return
+		}
+
+		if 0 == retryCount {
+			stats.IncrementOperations(&stats.S3RequestRetryOps)
+		}
+
+		retryCount++
+
+		logger.WarnfWithError(err, "s3client %v /%v/%v failed... retry %v of %v in %v", method, bucket, key, retryCount, client.config.RetryLimit, retryDelay)
+
+		time.Sleep(retryDelay)
+		retryDelay *= 2
+	}
+}
+
+func (client *Client) createBucket(bucket string) (err error) {
+	_, _, err = client.doRequest("PUT", bucket, "", nil, nil, nil)
+	if (nil != err) && (http.StatusConflict == blunder.HTTPCode(err)) {
+		err = nil // BucketAlreadyOwnedByYou
+	}
+	return
+}
+
+func (client *Client) headObject(bucket string, key string) (headers http.Header, err error) {
+	headers, _, err = client.doRequest("HEAD", bucket, key, nil, nil, nil)
+	return
+}
+
+func (client *Client) getObject(bucket string, key string, byteRange string) (buf []byte, err error) {
+	var (
+		headers http.Header
+	)
+
+	if "" != byteRange {
+		headers = make(http.Header)
+		headers.Set("Range", byteRange)
+	}
+
+	_, buf, err = client.doRequest("GET", bucket, key, nil, headers, nil)
+	return
+}
+
+func (client *Client) putObject(bucket string, key string, metadata map[string]string, buf []byte) (err error) {
+	headers := make(http.Header)
+
+	for name, value := range metadata {
+		headers.Set("X-Amz-Meta-"+name, value)
+	}
+
+	_, _, err = client.doRequest("PUT", bucket, key, nil, headers, buf)
+	return
+}
+
+func (client *Client) putObjectIfNoneMatch(bucket string, key string, buf []byte) (err error) {
+	headers := make(http.Header)
+
+	headers.Set("If-None-Match", "*")
+
+	_, _, err = client.doRequest("PUT", bucket, key, nil, headers, buf)
+	return
+}
+
+func (client *Client) deleteObject(bucket string, key string) (err error) {
+	_, _, err = client.doRequest("DELETE", bucket, key, nil, nil, nil)
+	return
+}
+
+func (client *Client) listObjects(bucket string, prefix string, delimiter string) (keys []string, commonPrefixes []string, err error) {
+	var (
+		continuationToken string
+		listBucketResult  listBucketResultStruct
+		responseBody      []byte
+	)
+
+	keys = make([]string, 0)
+	commonPrefixes = make([]string, 0)
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if "" != delimiter {
+			query.Set("delimiter", delimiter)
+		}
+		if "" != continuationToken {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		_, responseBody, err = client.doRequest("GET", bucket, "", query, nil, nil)
+		if nil != err {
+			return
+		}
+
+		listBucketResult = listBucketResultStruct{}
+
+		err = xml.Unmarshal(responseBody, &listBucketResult)
+		if nil != err {
+			err = blunder.NewError(blunder.BadHTTPGetError, "s3client GET /%v?prefix=%v returned unparseable ListBucketResult: %v", bucket, prefix, err)
+			return
+		}
+
+		for _, contents := range listBucketResult.Contents {
+			keys = append(keys, contents.Key)
+		}
+		for _, commonPrefix := range listBucketResult.CommonPrefixes {
+			commonPrefixes = append(commonPrefixes, commonPrefix.Prefix)
+		}
+
+		if !listBucketResult.IsTruncated || ("" == listBucketResult.NextContinuationToken) {
+			err = nil
+			return
+		}
+
+		continuationToken = listBucketResult.NextContinuationToken
+	}
+}
+
+func (client *Client) createMultipartUpload(bucket string, key string) (uploadID string, err error) {
+	var (
+		initiateMultipartUploadResult initiateMultipartUploadResultStruct
+		responseBody                  []byte
+	)
+
+	stats.IncrementOperations(&stats.S3MultipartUploadOps)
+
+	query := url.Values{}
+	query.Set("uploads", "")
+
+	_, responseBody, err = client.doRequest("POST", bucket, key, query, nil, nil)
+	if nil != err {
+		return
+	}
+
+	err = xml.Unmarshal(responseBody, &initiateMultipartUploadResult)
+	if (nil != err) || ("" == initiateMultipartUploadResult.UploadID) {
+		err = blunder.NewError(blunder.BadHTTPPutError, "s3client POST /%v/%v?uploads returned no UploadId", bucket, key)
+		return
+	}
+
+	uploadID = initiateMultipartUploadResult.UploadID
+
+	err = nil
+	return
+}
+
+func (client *Client) uploadPart(bucket string, key string, uploadID string, partNumber uint16, buf []byte) (eTag string, err error) {
+	var (
+		responseHeaders http.Header
+	)
+
+	stats.IncrementOperations(&stats.S3MultipartPartPutOps)
+
+	query := url.Values{}
+	query.Set("partNumber", strconv.Itoa(int(partNumber)))
+	query.Set("uploadId", uploadID)
+
+	responseHeaders, _, err = client.doRequest("PUT", bucket, key, query, nil, buf)
+	if nil != err {
+		return
+	}
+
+	eTag = responseHeaders.Get("ETag")
+	if "" == eTag {
+		err = blunder.NewError(blunder.BadHTTPPutError, "s3client PUT /%v/%v?partNumber=%v returned no ETag", bucket, key, partNumber)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (client *Client) completeMultipartUpload(bucket string, key string, uploadID string, parts []Part) (err error) {
+	var (
+		errorResponse errorResponseStruct
+		requestBody   []byte
+		responseBody  []byte
+	)
+
+	completeMultipartUpload := completeMultipartUploadStruct{Parts: make([]completeMultipartUploadPartStruct, 0, len(parts))}
+	for _, part := range parts {
+		completeMultipartUpload.Parts = append(completeMultipartUpload.Parts, completeMultipartUploadPartStruct{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	requestBody, err = xml.Marshal(&completeMultipartUpload)
+	if nil != err {
+		return
+	}
+
+	query := url.Values{}
+	query.Set("uploadId", uploadID)
+
+	_, responseBody, err = client.doRequest("POST", bucket, key, query, nil, requestBody)
+	if nil != err {
+		return
+	}
+
+	// S3 may report a failure to complete the upload in the body of a 200 OK response
+
+	err = xml.Unmarshal(responseBody, &errorResponse)
+	if nil == err {
+		err = blunder.NewError(blunder.BadHTTPPutError, "s3client POST /%v/%v?uploadId=%v failed (%v: %v)", bucket, key, uploadID, errorResponse.Code, errorResponse.Message)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (client *Client) abortMultipartUpload(bucket string, key string, uploadID string) (err error) {
+	query := url.Values{}
+	query.Set("uploadId", uploadID)
+
+	_, _, err = client.doRequest("DELETE", bucket, key, query, nil, nil)
+	return
+}
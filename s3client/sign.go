@@ -0,0 +1,160 @@
+package s3client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signatureAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat      = "20060102T150405Z"
+	amzDateOnlyFormat  = "20060102"
+	serviceName        = "s3"
+	emptyPayloadHash   = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // sha256 of a zero-length payload
+)
+
+func payloadHash(payload []byte) (hash string) {
+	sum := sha256.Sum256(payload)
+	hash = hex.EncodeToString(sum[:])
+	return
+}
+
+func hmacSHA256(key []byte, data string) (mac []byte) {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	mac = h.Sum(nil)
+	return
+}
+
+// uriEncode escapes all but the RFC 3986 unreserved characters (optionally leaving '/' alone) as AWS requires
+func uriEncode(s string, encodeSlash bool) (encoded string) {
+	var (
+		b byte
+		i int
+	)
+
+	encodedBuf := make([]byte, 0, len(s))
+
+	for i = 0; i < len(s); i++ {
+		b = s[i]
+		if (('A' <= b) && (b <= 'Z')) || (('a' <= b) && (b <= 'z')) || (('0' <= b) && (b <= '9')) || ('-' == b) || ('.' == b) || ('_' == b) || ('~' == b) || (('/' == b) && !encodeSlash) {
+			encodedBuf = append(encodedBuf, b)
+		} else {
+			encodedBuf = append(encodedBuf, []byte(fmt.Sprintf("%%%02X", b))...)
+		}
+	}
+
+	encoded = string(encodedBuf)
+	return
+}
+
+// canonicalQueryString sorts and escapes query (which is also how the query is sent on the wire)
+func canonicalQueryString(query url.Values) (canonical string) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	canonical = strings.Join(pairs, "&")
+	return
+}
+
+// signRequest adds the X-Amz-Date, X-Amz-Content-Sha256, & Authorization headers for an AWS Signature Version 4
+// signed request. The request's URL.RawPath (if set) must already be the canonical (uriEncode'd) path.
+func signRequest(request *http.Request, payloadHash string, accessKeyID string, secretAccessKey string, region string, now time.Time) {
+	var (
+		canonicalHeaders []string
+		canonicalURI     string
+		host             string
+		signedHeaders    []string
+	)
+
+	amzDate := now.UTC().Format(amzDateFormat)
+	amzDateOnly := now.UTC().Format(amzDateOnlyFormat)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host = request.Host
+	if "" == host {
+		host = request.URL.Host
+	}
+
+	headerMap := make(map[string]string)
+	headerMap["host"] = host
+	for name, values := range request.Header {
+		lowerName := strings.ToLower(name)
+		if ("range" == lowerName) || ("content-md5" == lowerName) || ("content-type" == lowerName) || strings.HasPrefix(lowerName, "x-amz-") {
+			trimmedValues := make([]string, len(values))
+			for i, value := range values {
+				trimmedValues[i] = strings.Join(strings.Fields(value), " ")
+			}
+			headerMap[lowerName] = strings.Join(trimmedValues, ",")
+		}
+	}
+
+	for name := range headerMap {
+		signedHeaders = append(signedHeaders, name)
+	}
+	sort.Strings(signedHeaders)
+	for _, name := range signedHeaders {
+		canonicalHeaders = append(canonicalHeaders, name+":"+headerMap[name]+"\n")
+	}
+
+	canonicalURI = request.URL.RawPath
+	if "" == canonicalURI {
+		canonicalURI = uriEncode(request.URL.Path, false)
+	}
+	if "" == canonicalURI {
+		canonicalURI = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalURI,
+		canonicalQueryString(request.URL.Query()),
+		strings.Join(canonicalHeaders, ""),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := amzDateOnly + "/" + region + "/" + serviceName + "/aws4_request"
+
+	stringToSign := strings.Join([]string{
+		signatureAlgorithm,
+		amzDate,
+		scope,
+		payloadHashOfString(canonicalRequest),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), amzDateOnly)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, serviceName)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", signatureAlgorithm, accessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func payloadHashOfString(s string) (hash string) {
+	hash = payloadHash([]byte(s))
+	return
+}
@@ -13,6 +13,9 @@ const (
 	FileReadplan                                // uses operations, op bucketed bytes, and bytes stats
 	FileWrite                                   // uses operations, op bucketed bytes, bytes, appended and overwritten stats
 	FileWrote                                   // uses operations, op bucketed bytes, and bytes stats
+	FileOptimize                                // uses operations and bytes stats
+	DefragmenterOptimize                        // uses operations and bytes stats
+	HeadhunterCompaction                        // uses operations and bytes stats
 	JrpcfsIoWrite                               // uses operations, op bucketed bytes, and bytes stats
 	JrpcfsIoRead                                // uses operations, op bucketed bytes, and bytes stats
 	SwiftObjGet                                 // uses operations, op bucketed bytes, and bytes stats
@@ -101,6 +104,18 @@ func (ms MultipleStat) findStatStrings(numBytes uint64) (ops *string, bytes *str
 		} else {
 			bbytes = &FileWroteOpsOver64K
 		}
+	case FileOptimize:
+		// file optimize uses operations and bytes stats
+		ops = &FileOptimizeOps
+		bytes = &FileOptimizeBytes
+	case DefragmenterOptimize:
+		// defragmenter optimize uses operations and bytes stats
+		ops = &DefragmenterOptimizeOps
+		bytes = &DefragmenterOptimizeBytes
+	case HeadhunterCompaction:
+		// headhunter compaction uses operations and bytes stats
+		ops = &HeadhunterCompactionOps
+		bytes = &HeadhunterCompactionBytes
 	case JrpcfsIoWrite:
 		// jrpcfs write uses operations, op bucketed bytes, and bytes stats
 		ops = &JrpcfsIoWriteOps
@@ -13,9 +13,13 @@ var (
 	FsBasenameValidateOps             = "proxyfs.fs.statName_validate.operations"
 	FsFullpathValidateOps             = "proxyfs.fs.fullpath_validate.operations"
 	FsVolumeValidateOps               = "proxyfs.fs.volume_validate.operations"
+	FsVolumeValidateFindingOps        = "proxyfs.fs.volume_validate.finding.operations"
+	FsVolumeValidateRepairOps         = "proxyfs.fs.volume_validate.repair.operations"
 	FsMountOps                        = "proxyfs.fs.mount.operations"
+	FsUnmountOps                      = "proxyfs.fs.unmount.operations"
 	FsRenameOps                       = "proxyfs.fs.rename.operations"
 	FsStatvfsOps                      = "proxyfs.fs.statvfs.operations"
+	FsGetQuotaOps                     = "proxyfs.fs.getquota.operations"
 	FsPathLookupOps                   = "proxyfs.fs.path_lookup.operations"
 	FsCreateOps                       = "proxyfs.fs.create.operations"
 	FsFlushOps                        = "proxyfs.fs.flush.operations"
@@ -35,6 +39,8 @@ var (
 	FsMwGetContainerOps               = "proxyfs.fs.middleware_get_container.operations"
 	FsMwPutContainerOps               = "proxyfs.fs.middleware_put_container.operations"
 	FsMwGetObjOps                     = "proxyfs.fs.middleware_get_object.operations"
+	FsMwRenewLeaseOps                 = "proxyfs.fs.middleware_renew_lease.operations"
+	FsMwReleaseLeaseOps               = "proxyfs.fs.middleware_release_lease.operations"
 	FsReaddirOps                      = "proxyfs.fs.readdir.operations"
 	FsReaddirOneOps                   = "proxyfs.fs.one_readdir.operations"
 	FsReaddirPlusOps                  = "proxyfs.fs.plus_readdir.operations"
@@ -55,6 +61,7 @@ var (
 	FsListXattrOps                    = "proxyfs.fs.list_xattr.operations"
 	FsRemoveXattrOps                  = "proxyfs.fs.remove_xattr.operations"
 	FsSetXattrOps                     = "proxyfs.fs.set_xattr.operations"
+	FsReadOnlyRejectedOps             = "proxyfs.fs.read_only_rejected.operations"
 	FsFlockOps                        = "proxyfs.fs.flock.operations"
 	DirCreateOps                      = "proxyfs.inode.directory.create.operations"
 	DirCreateSuccessOps               = "proxyfs.inode.directory.create.success.operations"
@@ -111,6 +118,29 @@ var (
 	FileWroteBytes                    = "proxyfs.inode.file.wrote.bytes"
 	DirSetsizeOps                     = "proxyfs.inode.directory.setsize.operations"
 	FileFlushOps                      = "proxyfs.inode.file.flush.operations"
+	FileFragmentationReportOps        = "proxyfs.inode.file.fragmentation-report.operations"
+	FileOptimizeOps                   = "proxyfs.inode.file.optimize.operations"
+	FileOptimizeBytes                 = "proxyfs.inode.file.optimize.bytes"
+	DefragmenterPassOps               = "proxyfs.inode.defragmenter.pass.operations"
+	DefragmenterScanOps               = "proxyfs.inode.defragmenter.scan.operations"
+	DefragmenterOptimizeOps           = "proxyfs.inode.defragmenter.optimize.operations"
+	DefragmenterOptimizeBytes         = "proxyfs.inode.defragmenter.optimize.bytes"
+	DefragmenterOptimizeFailedOps     = "proxyfs.inode.defragmenter.optimize.failed.operations"
+	LeaseCreateOps                    = "proxyfs.inode.lease.create.operations"
+	LeaseRenewOps                     = "proxyfs.inode.lease.renew.operations"
+	LeaseReleaseOps                   = "proxyfs.inode.lease.release.operations"
+	LeaseExpiredOps                   = "proxyfs.inode.lease.expired.operations"
+	LeaseDeferredLogSegmentDeleteOps  = "proxyfs.inode.lease.deferred-log-segment-delete.operations"
+	LeaseRecoveryOps                  = "proxyfs.inode.lease.recovery.operations"
+	QuotaHardLimitExceededOps         = "proxyfs.inode.quota.hard-limit-exceeded.operations"
+	QuotaSoftLimitExceededOps         = "proxyfs.inode.quota.soft-limit-exceeded.operations"
+	SnapshotCreateOps                 = "proxyfs.inode.snapshot.create.operations"
+	SnapshotDeleteOps                 = "proxyfs.inode.snapshot.delete.operations"
+	SnapshotLogSegmentRetainedOps     = "proxyfs.inode.snapshot.log-segment-retained.operations"
+	ScrubFindOps                      = "proxyfs.inode.scrub.find.operations"
+	ScrubDeleteOps                    = "proxyfs.inode.scrub.delete.operations"
+	ScrubOrphanFoundOps               = "proxyfs.inode.scrub.orphan-found.operations"
+	ScrubOrphanDeletedOps             = "proxyfs.inode.scrub.orphan-deleted.operations"
 	LogSegCreateOps                   = "proxyfs.inode.file.log-segment.create.operations"
 	GcLogSegDeleteOps                 = "proxyfs.inode.garbage-collection.log-segment.delete.operations"
 	GcLogSegOps                       = "proxyfs.inode.garbage-collection.log-segment.operations"
@@ -137,6 +167,11 @@ var (
 	JrpcfsIoReadOps64K                = "proxyfs.jrpcfs.read.operations.size-32KB-to-64KB"
 	JrpcfsIoReadOpsOver64K            = "proxyfs.jrpcfs.read.operations.size-over-64KB"
 	JrpcfsIoReadBytes                 = "proxyfs.jrpcfs.read.bytes"
+	JrpcfsIoFlushOps                  = "proxyfs.jrpcfs.flush.operations"
+	JrpcfsIoGetStatOps                = "proxyfs.jrpcfs.getstat.operations"
+	JrpcfsIoResizeOps                 = "proxyfs.jrpcfs.resize.operations"
+	JrpcfsIoPipelinedOps              = "proxyfs.jrpcfs.pipelined.operations"
+	JrpcfsIoPipelinedStallOps         = "proxyfs.jrpcfs.pipelined_stall.operations"
 	SwiftAccountDeleteOps             = "proxyfs.swiftclient.account-delete"
 	SwiftAccountGetOps                = "proxyfs.swiftclient.account-get"
 	SwiftAccountHeadOps               = "proxyfs.swiftclient.account-head"
@@ -167,6 +202,7 @@ var (
 	SwiftObjLoadOps64K                = "proxyfs.swiftclient.object-load.operations.size-32KB-to-64KB"
 	SwiftObjLoadOpsOver64K            = "proxyfs.swiftclient.object-load.operations.size-over-64KB"
 	SwiftObjLoadBytes                 = "proxyfs.swiftclient.object-load.bytes"
+	SwiftObjPutExclOps                = "proxyfs.swiftclient.object-put-if-none-match"
 	SwiftObjTailOps                   = "proxyfs.swiftclient.object-tail.operations"
 	SwiftObjTailBytes                 = "proxyfs.swiftclient.object-tail.bytes"
 	SwiftObjPutCtxFetchOps            = "proxyfs.swiftclient.object-put-context.fetch.operations"
@@ -194,6 +230,35 @@ var (
 	SwiftNonchunkedConnsCreateOps     = "proxyfs.swiftclient.non-chunked-connections-create.operations"
 	SwiftNonchunkedConnsReuseOps      = "proxyfs.swiftclient.non-chunked-connections-reuse.operations"
 	SwiftChunkedStarvationCallbacks   = "proxyfs.swiftclient.chunked-connections-starved-callback.operations"
+	SwiftAuthOps                      = "proxyfs.swiftclient.auth.operations"
+	SwiftAuthFailureOps               = "proxyfs.swiftclient.auth.failure.operations"
+	SwiftAuthTokenRejectedOps         = "proxyfs.swiftclient.auth.token-rejected.operations"
+
+	S3RequestOps          = "proxyfs.s3client.request.operations"
+	S3RequestRetryOps     = "proxyfs.s3client.request.retry.operations"   // requests that were retried (*not* number of retries)
+	S3RequestFailureOps   = "proxyfs.s3client.request.failure.operations" // requests that ultimately failed
+	S3MultipartUploadOps  = "proxyfs.s3client.multipart-upload.operations"
+	S3MultipartPartPutOps = "proxyfs.s3client.multipart-upload.part-put.operations"
+
+	ClusterHeartBeatSentOps      = "proxyfs.cluster.heartbeat.sent.operations"
+	ClusterHeartBeatReceivedOps  = "proxyfs.cluster.heartbeat.received.operations"
+	ClusterPeerExpiredOps        = "proxyfs.cluster.peer.expired.operations"
+	ClusterTakeOverOps           = "proxyfs.cluster.volume.take-over.operations"
+	ClusterStepDownOps           = "proxyfs.cluster.volume.step-down.operations"
+	ClusterFencedCheckpointOps   = "proxyfs.cluster.volume.fenced-checkpoint.operations"
+	ClusterFencedObjectDeleteOps = "proxyfs.cluster.volume.fenced-object-delete.operations" // deletions discarded as the Volume was fenced
+
+	DLMNodeLockAcquireOps      = "proxyfs.dlm.node-lock.acquire.operations"
+	DLMNodeLockReleaseOps      = "proxyfs.dlm.node-lock.release.operations"
+	DLMNodeLockRevokeOps       = "proxyfs.dlm.node-lock.revoke.operations"
+	DLMNodeLockLostOps         = "proxyfs.dlm.node-lock.lost.operations"
+	DLMServerGrantOps          = "proxyfs.dlm.server.grant.operations"
+	DLMServerRevokeOps         = "proxyfs.dlm.server.revoke.operations"
+	DLMServerSessionDroppedOps = "proxyfs.dlm.server.session-dropped.operations"
+
+	HeadhunterCheckpointFailedOps = "proxyfs.headhunter.checkpoint.failed.operations"
+	HeadhunterCompactionOps       = "proxyfs.headhunter.compaction.operations"      // checkpoint objects reclaimed by compaction
+	HeadhunterCompactionBytes     = "proxyfs.headhunter.compaction.reclaimed.bytes" // their lengths less what compaction rewrote elsewhere
 
 	SwiftAccountDeleteRetryOps        = "proxyfs.swiftclient.account-delete.retry.operations"         // failed operations that were retried (*not* number of retries)
 	SwiftAccountDeleteRetrySuccessOps = "proxyfs.swiftclient.account-delete.retry.success.operations" // failed operations where retry fixed the problem
@@ -231,6 +296,8 @@ var (
 	SwiftObjHeadRetrySuccessOps          = "proxyfs.swiftclient.object-head.retry.success.operations"
 	SwiftObjLoadRetryOps                 = "proxyfs.swiftclient.object-load.retry.operations"
 	SwiftObjLoadRetrySuccessOps          = "proxyfs.swiftclient.object-load.retry.success.operations"
+	SwiftObjPutExclRetryOps              = "proxyfs.swiftclient.object-put-if-none-match.retry.operations"
+	SwiftObjPutExclRetrySuccessOps       = "proxyfs.swiftclient.object-put-if-none-match.retry.success.operations"
 	SwiftObjTailRetryOps                 = "proxyfs.swiftclient.object-tail.retry.operations"
 	SwiftObjTailRetrySuccessOps          = "proxyfs.swiftclient.object-tail.retry.success.operations"
 )
@@ -3,6 +3,7 @@
 package swiftclient
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/swiftstack/ProxyFS/blunder"
@@ -10,19 +11,19 @@ import (
 	"github.com/swiftstack/ProxyFS/stats"
 )
 
-func accountDeleteWithRetry(accountName string) (err error) {
+func accountDeleteWithRetry(ctx context.Context, accountName string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// accountDelete() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
 	// and then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = accountDelete(accountName)
+		err = accountDelete(ctx, accountName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.accountDelete(\"%v\")", accountName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftAccountDeleteRetryOps,
@@ -32,7 +33,7 @@ func accountDeleteWithRetry(accountName string) (err error) {
 	return err
 }
 
-func accountDelete(accountName string) (err error) {
+func accountDelete(ctx context.Context, accountName string) (err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -41,7 +42,7 @@ func accountDelete(accountName string) (err error) {
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "DELETE", "/"+swiftVersion+"/"+accountName, nil)
 	if nil != err {
@@ -74,7 +75,7 @@ func accountDelete(accountName string) (err error) {
 	return
 }
 
-func accountGetWithRetry(accountName string) (map[string][]string, []string, error) {
+func accountGetWithRetry(ctx context.Context, accountName string) (map[string][]string, []string, error) {
 	// request is a function that, through the miracle of closure, calls
 	// accountGet() with the paramaters passed to this function, stashes the
 	// relevant return values into the local variables of this function, and
@@ -85,12 +86,12 @@ func accountGetWithRetry(accountName string) (map[string][]string, []string, err
 	)
 	request := func() (bool, error) {
 		var err error
-		headers, containerList, err = accountGet(accountName)
+		headers, containerList, err = accountGet(ctx, accountName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.accountGet(\"%v\")", accountName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftAccountGetRetryOps,
@@ -100,7 +101,7 @@ func accountGetWithRetry(accountName string) (map[string][]string, []string, err
 	return headers, containerList, err
 }
 
-func accountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+func accountGet(ctx context.Context, accountName string) (headers map[string][]string, containerList []string, err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -108,7 +109,7 @@ func accountGet(accountName string) (headers map[string][]string, containerList
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+accountName, nil)
 	if nil != err {
@@ -151,7 +152,7 @@ func accountGet(accountName string) (headers map[string][]string, containerList
 	return
 }
 
-func accountHeadWithRetry(accountName string) (map[string][]string, error) {
+func accountHeadWithRetry(ctx context.Context, accountName string) (map[string][]string, error) {
 	// request is a function that, through the miracle of closure, calls
 	// accountHead() with the paramaters passed to this function, stashes the
 	// relevant return values into the local variables of this function, and
@@ -161,12 +162,12 @@ func accountHeadWithRetry(accountName string) (map[string][]string, error) {
 	)
 	request := func() (bool, error) {
 		var err error
-		headers, err = accountHead(accountName)
+		headers, err = accountHead(ctx, accountName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.accountHead(\"%v\")", accountName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftAccountHeadRetryOps,
@@ -176,7 +177,7 @@ func accountHeadWithRetry(accountName string) (map[string][]string, error) {
 	return headers, err
 }
 
-func accountHead(accountName string) (headers map[string][]string, err error) {
+func accountHead(ctx context.Context, accountName string) (headers map[string][]string, err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -184,7 +185,7 @@ func accountHead(accountName string) (headers map[string][]string, err error) {
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "HEAD", "/"+swiftVersion+"/"+accountName, nil)
 	if nil != err {
@@ -217,19 +218,19 @@ func accountHead(accountName string) (headers map[string][]string, err error) {
 	return
 }
 
-func accountPostWithRetry(accountName string, requestHeaders map[string][]string) (err error) {
+func accountPostWithRetry(ctx context.Context, accountName string, requestHeaders map[string][]string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// accountPost() with the paramaters passed to this function, stashes the
 	// relevant return values into the local variables of this function, and
 	// then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = accountPost(accountName, requestHeaders)
+		err = accountPost(ctx, accountName, requestHeaders)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.accountPost(\"%v\")", accountName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftAccountPostRetryOps,
@@ -239,7 +240,7 @@ func accountPostWithRetry(accountName string, requestHeaders map[string][]string
 	return err
 }
 
-func accountPost(accountName string, requestHeaders map[string][]string) (err error) {
+func accountPost(ctx context.Context, accountName string, requestHeaders map[string][]string) (err error) {
 	var (
 		connection      *connectionStruct
 		contentLength   int
@@ -249,7 +250,7 @@ func accountPost(accountName string, requestHeaders map[string][]string) (err er
 		responseHeaders map[string][]string
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
@@ -300,19 +301,19 @@ func accountPost(accountName string, requestHeaders map[string][]string) (err er
 	return
 }
 
-func accountPutWithRetry(accountName string, requestHeaders map[string][]string) (err error) {
+func accountPutWithRetry(ctx context.Context, accountName string, requestHeaders map[string][]string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// accountPut() with the paramaters passed to this function, stashes the
 	// relevant return values into the local variables of this function, and
 	// then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = accountPut(accountName, requestHeaders)
+		err = accountPut(ctx, accountName, requestHeaders)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.accountPut(\"%v\")", accountName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftAccountPutRetryOps,
@@ -322,7 +323,7 @@ func accountPutWithRetry(accountName string, requestHeaders map[string][]string)
 	return err
 }
 
-func accountPut(accountName string, requestHeaders map[string][]string) (err error) {
+func accountPut(ctx context.Context, accountName string, requestHeaders map[string][]string) (err error) {
 	var (
 		connection      *connectionStruct
 		contentLength   int
@@ -332,7 +333,7 @@ func accountPut(accountName string, requestHeaders map[string][]string) (err err
 		responseHeaders map[string][]string
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
@@ -1,7 +1,8 @@
-// Package swiftclient provides API access to the local Swift NoAuth Pipeline.
+// Package swiftclient provides API access to Swift... either via the local NoAuth Pipeline or an authenticated (TempAuth or Keystone) endpoint.
 package swiftclient
 
 import (
+	"context"
 	"sync"
 )
 
@@ -20,10 +21,12 @@ type ChunkedCopyContext interface {
 // returns an error, or else we will leak open connections (although SendChunk()
 // does its best).
 type ChunkedPutContext interface {
-	BytesPut() (bytesPut uint64, err error)                    // Report how many bytes have been sent via SendChunk() for this ChunkedPutContext
-	Close() (err error)                                        // Finish the "chunked" HTTP PUT for this ChunkedPutContext (with possible retry)
-	Read(offset uint64, length uint64) (buf []byte, err error) // Read back bytes previously sent via SendChunk()
-	SendChunk(buf []byte) (err error)                          // Send the supplied "chunk" via this ChunkedPutContext
+	BytesPut() (bytesPut uint64, err error)                           // Report how many bytes have been sent via SendChunk() for this ChunkedPutContext
+	Close() (err error)                                               // Finish the "chunked" HTTP PUT for this ChunkedPutContext (with possible retry)
+	CloseWithContext(ctx context.Context) (err error)                 // Close() bounded by ctx
+	Read(offset uint64, length uint64) (buf []byte, err error)        // Read back bytes previously sent via SendChunk()
+	SendChunk(buf []byte) (err error)                                 // Send the supplied "chunk" via this ChunkedPutContext
+	SendChunkWithContext(ctx context.Context, buf []byte) (err error) // SendChunk() bounded by ctx
 }
 
 // StavationCallbackFunc specifies the signature of a callback function to be invoked when
@@ -43,62 +46,122 @@ func SetStarvationCallbackFunc(starvationCallback StarvationCallbackFunc) {
 
 // AccountDelete invokes HTTP DELETE on the named Swift Account.
 func AccountDelete(accountName string) (err error) {
-	return accountDeleteWithRetry(accountName)
+	return AccountDeleteWithContext(context.Background(), accountName)
+}
+
+// AccountDeleteWithContext is AccountDelete bounded by ctx.
+func AccountDeleteWithContext(ctx context.Context, accountName string) (err error) {
+	return accountDeleteWithRetry(ctx, accountName)
 }
 
 // AccountGet invokes HTTP GET on the named Swift Account.
 func AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
-	return accountGetWithRetry(accountName)
+	return AccountGetWithContext(context.Background(), accountName)
+}
+
+// AccountGetWithContext is AccountGet bounded by ctx.
+func AccountGetWithContext(ctx context.Context, accountName string) (headers map[string][]string, containerList []string, err error) {
+	return accountGetWithRetry(ctx, accountName)
 }
 
 // AccountHead invokes HTTP HEAD on the named Swift Account.
 func AccountHead(accountName string) (headers map[string][]string, err error) {
-	return accountHeadWithRetry(accountName)
+	return AccountHeadWithContext(context.Background(), accountName)
+}
+
+// AccountHeadWithContext is AccountHead bounded by ctx.
+func AccountHeadWithContext(ctx context.Context, accountName string) (headers map[string][]string, err error) {
+	return accountHeadWithRetry(ctx, accountName)
 }
 
 // AccountPost invokes HTTP PUT on the named Swift Account.
 func AccountPost(accountName string, headers map[string][]string) (err error) {
-	return accountPostWithRetry(accountName, headers)
+	return AccountPostWithContext(context.Background(), accountName, headers)
+}
+
+// AccountPostWithContext is AccountPost bounded by ctx.
+func AccountPostWithContext(ctx context.Context, accountName string, headers map[string][]string) (err error) {
+	return accountPostWithRetry(ctx, accountName, headers)
 }
 
 // AccountPut invokes HTTP PUT on the named Swift Account.
 func AccountPut(accountName string, headers map[string][]string) (err error) {
-	return accountPutWithRetry(accountName, headers)
+	return AccountPutWithContext(context.Background(), accountName, headers)
+}
+
+// AccountPutWithContext is AccountPut bounded by ctx.
+func AccountPutWithContext(ctx context.Context, accountName string, headers map[string][]string) (err error) {
+	return accountPutWithRetry(ctx, accountName, headers)
 }
 
 // ContainerDelete invokes HTTP DELETE on the named Swift Container.
 func ContainerDelete(accountName string, containerName string) (err error) {
-	return containerDeleteWithRetry(accountName, containerName)
+	return ContainerDeleteWithContext(context.Background(), accountName, containerName)
+}
+
+// ContainerDeleteWithContext is ContainerDelete bounded by ctx.
+func ContainerDeleteWithContext(ctx context.Context, accountName string, containerName string) (err error) {
+	return containerDeleteWithRetry(ctx, accountName, containerName)
 }
 
 // ContainerGet invokes HTTP GET on the named Swift Container.
 func ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
-	return containerGetWithRetry(accountName, containerName)
+	return ContainerGetWithContext(context.Background(), accountName, containerName)
+}
+
+// ContainerGetWithContext is ContainerGet bounded by ctx.
+func ContainerGetWithContext(ctx context.Context, accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	return containerGetWithRetry(ctx, accountName, containerName)
 }
 
 // ContainerHead invokes HTTP HEAD on the named Swift Container.
 func ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
-	return containerHeadWithRetry(accountName, containerName)
+	return ContainerHeadWithContext(context.Background(), accountName, containerName)
+}
+
+// ContainerHeadWithContext is ContainerHead bounded by ctx.
+func ContainerHeadWithContext(ctx context.Context, accountName string, containerName string) (headers map[string][]string, err error) {
+	return containerHeadWithRetry(ctx, accountName, containerName)
 }
 
 // ContainerPost invokes HTTP PUT on the named Swift Container.
 func ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
-	return containerPostWithRetry(accountName, containerName, headers)
+	return ContainerPostWithContext(context.Background(), accountName, containerName, headers)
+}
+
+// ContainerPostWithContext is ContainerPost bounded by ctx.
+func ContainerPostWithContext(ctx context.Context, accountName string, containerName string, headers map[string][]string) (err error) {
+	return containerPostWithRetry(ctx, accountName, containerName, headers)
 }
 
 // ContainerPut invokes HTTP PUT on the named Swift Container.
 func ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
-	return containerPutWithRetry(accountName, containerName, headers)
+	return ContainerPutWithContext(context.Background(), accountName, containerName, headers)
+}
+
+// ContainerPutWithContext is ContainerPut bounded by ctx.
+func ContainerPutWithContext(ctx context.Context, accountName string, containerName string, headers map[string][]string) (err error) {
+	return containerPutWithRetry(ctx, accountName, containerName, headers)
 }
 
 // ObjectContentLength invokes HTTP HEAD on the named Swift Object and returns value of Content-Length Header.
 func ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
-	return objectContentLengthWithRetry(accountName, containerName, objectName)
+	return ObjectContentLengthWithContext(context.Background(), accountName, containerName, objectName)
+}
+
+// ObjectContentLengthWithContext is ObjectContentLength bounded by ctx.
+func ObjectContentLengthWithContext(ctx context.Context, accountName string, containerName string, objectName string) (length uint64, err error) {
+	return objectContentLengthWithRetry(ctx, accountName, containerName, objectName)
 }
 
 // ObjectCopy asynchronously creates a copy of the named Swift Object Source called the named Swift Object Destination.
 func ObjectCopy(srcAccountName string, srcContainerName string, srcObjectName string, dstAccountName string, dstContainerName string, dstObjectName string, chunkedCopyContext ChunkedCopyContext) (err error) {
-	return objectCopy(srcAccountName, srcContainerName, srcObjectName, dstAccountName, dstContainerName, dstObjectName, chunkedCopyContext)
+	return ObjectCopyWithContext(context.Background(), srcAccountName, srcContainerName, srcObjectName, dstAccountName, dstContainerName, dstObjectName, chunkedCopyContext)
+}
+
+// ObjectCopyWithContext is ObjectCopy bounded by ctx.
+func ObjectCopyWithContext(ctx context.Context, srcAccountName string, srcContainerName string, srcObjectName string, dstAccountName string, dstContainerName string, dstObjectName string, chunkedCopyContext ChunkedCopyContext) (err error) {
+	return objectCopy(ctx, srcAccountName, srcContainerName, srcObjectName, dstAccountName, dstContainerName, dstObjectName, chunkedCopyContext)
 }
 
 // ObjectDeleteAsync asynchronously invokes HTTP DELETE on the named Swift Object.
@@ -111,32 +174,74 @@ func ObjectDeleteAsync(accountName string, containerName string, objectName stri
 
 // ObjectDeleteSync synchronously invokes HTTP DELETE on the named Swift Object.
 func ObjectDeleteSync(accountName string, containerName string, objectName string) (err error) {
-	return objectDeleteSyncWithRetry(accountName, containerName, objectName)
+	return ObjectDeleteSyncWithContext(context.Background(), accountName, containerName, objectName)
+}
+
+// ObjectDeleteSyncWithContext is ObjectDeleteSync bounded by ctx.
+func ObjectDeleteSyncWithContext(ctx context.Context, accountName string, containerName string, objectName string) (err error) {
+	return objectDeleteSyncWithRetry(ctx, accountName, containerName, objectName)
 }
 
 // ObjectFetchChunkedPutContext provisions a context to use for an HTTP PUT using "chunked" Transfer-Encoding on the named Swift Object.
 func ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string) (chunkedPutContext ChunkedPutContext, err error) {
-	return objectFetchChunkedPutContextWithRetry(accountName, containerName, objectName)
+	return ObjectFetchChunkedPutContextWithContext(context.Background(), accountName, containerName, objectName)
+}
+
+// ObjectFetchChunkedPutContextWithContext is ObjectFetchChunkedPutContext bounded by ctx.
+func ObjectFetchChunkedPutContextWithContext(ctx context.Context, accountName string, containerName string, objectName string) (chunkedPutContext ChunkedPutContext, err error) {
+	return objectFetchChunkedPutContextWithRetry(ctx, accountName, containerName, objectName)
 }
 
 // ObjectGet invokes HTTP GET on the named Swift Object for the specified byte range.
 func ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
-	return objectGetWithRetry(accountName, containerName, objectName, offset, length)
+	return ObjectGetWithContext(context.Background(), accountName, containerName, objectName, offset, length)
+}
+
+// ObjectGetWithContext is ObjectGet bounded by ctx.
+func ObjectGetWithContext(ctx context.Context, accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	return objectGetWithRetry(ctx, accountName, containerName, objectName, offset, length)
 }
 
 // ObjectHead invokes HTTP HEAD on the named Swift Object.
 func ObjectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
-	return objectHeadWithRetry(accountName, containerName, objectName)
+	return ObjectHeadWithContext(context.Background(), accountName, containerName, objectName)
+}
+
+// ObjectHeadWithContext is ObjectHead bounded by ctx.
+func ObjectHeadWithContext(ctx context.Context, accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
+	return objectHeadWithRetry(ctx, accountName, containerName, objectName)
 }
 
 // ObjectLoad invokes HTTP GET on the named Swift Object for the entire object.
 func ObjectLoad(accountName string, containerName string, objectName string) (buf []byte, err error) {
-	return objectLoadWithRetry(accountName, containerName, objectName)
+	return ObjectLoadWithContext(context.Background(), accountName, containerName, objectName)
+}
+
+// ObjectLoadWithContext is ObjectLoad bounded by ctx.
+func ObjectLoadWithContext(ctx context.Context, accountName string, containerName string, objectName string) (buf []byte, err error) {
+	return objectLoadWithRetry(ctx, accountName, containerName, objectName)
+}
+
+// ObjectPutIfNoneMatch invokes HTTP PUT with "If-None-Match: *" on the named Swift Object.
+//
+// If the Object already exists, it is left unmodified and the returned err carries HTTP StatusCode 412.
+func ObjectPutIfNoneMatch(accountName string, containerName string, objectName string, buf []byte) (err error) {
+	return ObjectPutIfNoneMatchWithContext(context.Background(), accountName, containerName, objectName, buf)
+}
+
+// ObjectPutIfNoneMatchWithContext is ObjectPutIfNoneMatch bounded by ctx.
+func ObjectPutIfNoneMatchWithContext(ctx context.Context, accountName string, containerName string, objectName string, buf []byte) (err error) {
+	return objectPutIfNoneMatchWithRetry(ctx, accountName, containerName, objectName, buf)
 }
 
 // ObjectTail invokes HTTP GET on the named Swift Object with a byte range selecting the specified length of trailing bytes.
 func ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
-	return objectTailWithRetry(accountName, containerName, objectName, length)
+	return ObjectTailWithContext(context.Background(), accountName, containerName, objectName, length)
+}
+
+// ObjectTailWithContext is ObjectTail bounded by ctx.
+func ObjectTailWithContext(ctx context.Context, accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	return objectTailWithRetry(ctx, accountName, containerName, objectName, length)
 }
 
 // Number of chunked connections that are idle
@@ -0,0 +1,403 @@
+// Swift authentication implementation
+
+package swiftclient
+
+// Absent [SwiftClient]AuthURL, requests are sent (unauthenticated) to the NoAuth pipeline of the Swift Proxy
+// on this node at [SwiftClient]NoAuthTCPPort. Otherwise, a token is obtained from AuthURL... either via
+// TempAuth (a GET supplying X-Auth-User & X-Auth-Key) or, if AuthURL ends in "/v3", via Keystone (a POST of
+// a project-scoped password authentication request to <AuthURL>/auth/tokens). The token is cached and sent
+// (as X-Auth-Token) with every request. It is refreshed once it expires or upon a request being rejected
+// with 401 Unauthorized (in which case RequestWithRetry() immediately retries the request). Requests are
+// sent to the storage URL returned (or, if set, [SwiftClient]StorageURL)... via TLS if its scheme is https.
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// authTokenExpiryMargin is how long before its reported expiry a token is refreshed (capped at half its lifetime)
+const authTokenExpiryMargin = 60 * time.Second
+
+type authStruct struct {
+	sync.Mutex
+	url         string
+	keystone    bool
+	user        string
+	key         string
+	projectName string       // Keystone only
+	domainName  string       // Keystone only (applies to both user and project)
+	httpClient  *http.Client // used only to authenticate
+	token       string
+	tokenExpiry time.Time // zero if not reported
+	tokenStale  bool
+}
+
+type keystoneTokenResponseStruct struct {
+	Token struct {
+		ExpiresAt string `json:"expires_at"`
+		Catalog   []struct {
+			Type      string `json:"type"`
+			Endpoints []struct {
+				Interface string `json:"interface"`
+				URL       string `json:"url"`
+			} `json:"endpoints"`
+		} `json:"catalog"`
+	} `json:"token"`
+}
+
+func authUp(confMap conf.ConfMap, authURL string) (err error) {
+	var (
+		auth               *authStruct
+		caCertFile         string
+		caCertPEM          []byte
+		storageURL         string
+		storageURLOverride string
+		storageURLPtr      *url.URL
+		tlsConfig          *tls.Config
+		v1Index            int
+	)
+
+	auth = &authStruct{
+		url:        strings.TrimSuffix(authURL, "/"),
+		tokenStale: true,
+	}
+
+	auth.keystone = strings.HasSuffix(auth.url, "/v3")
+
+	auth.user, err = confMap.FetchOptionValueString("SwiftClient", "AuthUser")
+	if nil != err {
+		return
+	}
+	auth.key, err = confMap.FetchOptionValueString("SwiftClient", "AuthKey")
+	if nil != err {
+		return
+	}
+
+	if auth.keystone {
+		auth.projectName, err = confMap.FetchOptionValueString("SwiftClient", "AuthProjectName")
+		if nil != err {
+			return
+		}
+		auth.domainName, err = confMap.FetchOptionValueString("SwiftClient", "AuthDomainName")
+		if (nil != err) || ("" == auth.domainName) {
+			auth.domainName = "Default" // TODO: Eventually, just return
+		}
+	}
+
+	tlsConfig = &tls.Config{}
+
+	caCertFile, err = confMap.FetchOptionValueString("SwiftClient", "TLSCACertFile")
+	if (nil == err) && ("" != caCertFile) {
+		caCertPEM, err = ioutil.ReadFile(caCertFile)
+		if nil != err {
+			err = fmt.Errorf("SwiftClient.TLSCACertFile (\"%v\") could not be read: %v", caCertFile, err)
+			return
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCertPEM) {
+			err = fmt.Errorf("SwiftClient.TLSCACertFile (\"%v\") contains no PEM-encoded certificates", caCertFile)
+			return
+		}
+	}
+
+	auth.httpClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: globals.timeout,
+	}
+
+	auth.Lock()
+	storageURL, err = auth.authenticateWhileLocked()
+	auth.Unlock()
+	if nil != err {
+		return
+	}
+
+	storageURLOverride, err = confMap.FetchOptionValueString("SwiftClient", "StorageURL")
+	if (nil == err) && ("" != storageURLOverride) {
+		storageURL = storageURLOverride
+	}
+
+	storageURLPtr, err = url.Parse(storageURL)
+	if nil != err {
+		err = fmt.Errorf("storage URL \"%v\" could not be parsed: %v", storageURL, err)
+		return
+	}
+
+	switch storageURLPtr.Scheme {
+	case "http":
+		globals.swiftTLSConfig = nil
+		globals.swiftAddr = storageURLPtr.Host
+		if "" == storageURLPtr.Port() {
+			globals.swiftAddr = net.JoinHostPort(storageURLPtr.Hostname(), "80")
+		}
+	case "https":
+		globals.swiftTLSConfig = tlsConfig
+		globals.swiftAddr = storageURLPtr.Host
+		if "" == storageURLPtr.Port() {
+			globals.swiftAddr = net.JoinHostPort(storageURLPtr.Hostname(), "443")
+		}
+	default:
+		err = fmt.Errorf("storage URL \"%v\" must be either http or https", storageURL)
+		return
+	}
+
+	globals.swiftHost = storageURLPtr.Host
+
+	// Requests are constructed as "/v1/<accountName>/..." so retain only what precedes "/v1/" in the storage URL
+
+	v1Index = strings.Index(storageURLPtr.Path, "/"+swiftVersion+"/")
+	if 0 > v1Index {
+		err = fmt.Errorf("storage URL \"%v\" path must include \"/%v/<account>\"", storageURL, swiftVersion)
+		return
+	}
+
+	globals.swiftPathPrefix = storageURLPtr.Path[:v1Index]
+
+	globals.auth = auth
+
+	logger.Infof("SwiftClient authenticated via %v... sending requests to %v", auth.url, storageURL)
+
+	err = nil
+	return
+}
+
+// fetchToken returns the current token (first refreshing it if it is stale or expired).
+func (auth *authStruct) fetchToken() (token string) {
+	auth.Lock()
+
+	if auth.tokenStale || (!auth.tokenExpiry.IsZero() && time.Now().After(auth.tokenExpiry)) {
+		_, err := auth.authenticateWhileLocked()
+		if nil != err {
+			// Requests will be sent with the prior token... and, if rejected, prompt another attempt
+
+			logger.ErrorfWithError(err, "swiftclient unable to refresh token via %v", auth.url)
+		}
+	}
+
+	token = auth.token
+
+	auth.Unlock()
+
+	return
+}
+
+// invalidateToken is called upon a request being rejected with 401 Unauthorized.
+func (auth *authStruct) invalidateToken() {
+	auth.Lock()
+	auth.tokenStale = true
+	auth.Unlock()
+
+	stats.IncrementOperations(&stats.SwiftAuthTokenRejectedOps)
+}
+
+func (auth *authStruct) authenticateWhileLocked() (storageURL string, err error) {
+	var (
+		token       string
+		tokenExpiry time.Time
+	)
+
+	if auth.keystone {
+		token, tokenExpiry, storageURL, err = auth.authenticateViaKeystone()
+	} else {
+		token, tokenExpiry, storageURL, err = auth.authenticateViaTempAuth()
+	}
+	if nil != err {
+		stats.IncrementOperations(&stats.SwiftAuthFailureOps)
+		return
+	}
+
+	auth.token = token
+	auth.tokenExpiry = tokenExpiry
+	auth.tokenStale = false
+
+	stats.IncrementOperations(&stats.SwiftAuthOps)
+
+	return
+}
+
+func (auth *authStruct) authenticateViaTempAuth() (token string, tokenExpiry time.Time, storageURL string, err error) {
+	var (
+		expiresIn    float64
+		httpRequest  *http.Request
+		httpResponse *http.Response
+	)
+
+	httpRequest, err = http.NewRequest("GET", auth.url, nil)
+	if nil != err {
+		return
+	}
+
+	httpRequest.Header.Set("X-Auth-User", auth.user)
+	httpRequest.Header.Set("X-Auth-Key", auth.key)
+
+	httpResponse, err = auth.httpClient.Do(httpRequest)
+	if nil != err {
+		return
+	}
+
+	_, _ = io.Copy(ioutil.Discard, httpResponse.Body)
+	_ = httpResponse.Body.Close()
+
+	if (http.StatusOK > httpResponse.StatusCode) || (http.StatusMultipleChoices <= httpResponse.StatusCode) {
+		err = fmt.Errorf("GET %v returned HTTP StatusCode %d", auth.url, httpResponse.StatusCode)
+		return
+	}
+
+	token = httpResponse.Header.Get("X-Auth-Token")
+	if "" == token {
+		token = httpResponse.Header.Get("X-Storage-Token")
+		if "" == token {
+			err = fmt.Errorf("GET %v returned neither X-Auth-Token nor X-Storage-Token", auth.url)
+			return
+		}
+	}
+
+	storageURL = httpResponse.Header.Get("X-Storage-Url")
+	if "" == storageURL {
+		err = fmt.Errorf("GET %v did not return X-Storage-Url", auth.url)
+		return
+	}
+
+	expiresIn, err = strconv.ParseFloat(httpResponse.Header.Get("X-Auth-Token-Expires"), 64)
+	if nil == err {
+		tokenExpiry = computeTokenExpiry(time.Duration(expiresIn * float64(time.Second)))
+	} else {
+		tokenExpiry = time.Time{}
+	}
+
+	err = nil
+	return
+}
+
+func (auth *authStruct) authenticateViaKeystone() (token string, tokenExpiry time.Time, storageURL string, err error) {
+	var (
+		expiresAt       time.Time
+		httpRequest     *http.Request
+		httpResponse    *http.Response
+		requestBody     []byte
+		responseBody    []byte
+		tokenResponse   keystoneTokenResponseStruct
+		tokensURL       string
+		nonShadowingErr error
+	)
+
+	tokensURL = auth.url + "/auth/tokens"
+
+	requestBody, err = json.Marshal(map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"password"},
+				"password": map[string]interface{}{
+					"user": map[string]interface{}{
+						"name":     auth.user,
+						"domain":   map[string]string{"name": auth.domainName},
+						"password": auth.key,
+					},
+				},
+			},
+			"scope": map[string]interface{}{
+				"project": map[string]interface{}{
+					"name":   auth.projectName,
+					"domain": map[string]string{"name": auth.domainName},
+				},
+			},
+		},
+	})
+	if nil != err {
+		return
+	}
+
+	httpRequest, err = http.NewRequest("POST", tokensURL, bytes.NewReader(requestBody))
+	if nil != err {
+		return
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err = auth.httpClient.Do(httpRequest)
+	if nil != err {
+		return
+	}
+
+	responseBody, err = ioutil.ReadAll(httpResponse.Body)
+	_ = httpResponse.Body.Close()
+	if nil != err {
+		return
+	}
+
+	if (http.StatusOK > httpResponse.StatusCode) || (http.StatusMultipleChoices <= httpResponse.StatusCode) {
+		err = fmt.Errorf("POST %v returned HTTP StatusCode %d", tokensURL, httpResponse.StatusCode)
+		return
+	}
+
+	token = httpResponse.Header.Get("X-Subject-Token")
+	if "" == token {
+		err = fmt.Errorf("POST %v did not return X-Subject-Token", tokensURL)
+		return
+	}
+
+	err = json.Unmarshal(responseBody, &tokenResponse)
+	if nil != err {
+		err = fmt.Errorf("POST %v returned an unparseable token: %v", tokensURL, err)
+		return
+	}
+
+	for _, service := range tokenResponse.Token.Catalog {
+		if "object-store" != service.Type {
+			continue
+		}
+		for _, endpoint := range service.Endpoints {
+			if "public" == endpoint.Interface {
+				storageURL = endpoint.URL
+				break
+			}
+		}
+	}
+	if "" == storageURL {
+		err = fmt.Errorf("POST %v returned a catalog lacking a public object-store endpoint", tokensURL)
+		return
+	}
+
+	expiresAt, nonShadowingErr = time.Parse(time.RFC3339Nano, tokenResponse.Token.ExpiresAt)
+	if nil == nonShadowingErr {
+		tokenExpiry = computeTokenExpiry(expiresAt.Sub(time.Now()))
+	} else {
+		tokenExpiry = time.Time{}
+	}
+
+	err = nil
+	return
+}
+
+func computeTokenExpiry(expiresIn time.Duration) (tokenExpiry time.Time) {
+	var (
+		margin = authTokenExpiryMargin
+	)
+
+	if margin > (expiresIn / 2) {
+		margin = expiresIn / 2
+	}
+
+	tokenExpiry = time.Now().Add(expiresIn - margin)
+
+	return
+}
@@ -0,0 +1,206 @@
+package swiftclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+func TestAuth(t *testing.T) {
+	confStrings := []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"SwiftClient.NoAuthTCPPort=45264",
+
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=5",
+		"SwiftClient.RetryLimitObject=5",
+		"SwiftClient.RetryDelay=250ms",
+		"SwiftClient.RetryDelayObject=250ms",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+
+		"SwiftClient.AuthURL=http://127.0.0.1:45264/auth/v1.0",
+		"SwiftClient.AuthUser=test:tester",
+		"SwiftClient.AuthKey=testing",
+
+		"Cluster.WhoAmI=Peer0",
+
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+
+		"FSGlobals.VolumeList=",
+
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+
+		"RamSwiftAuth.User=test:tester",
+		"RamSwiftAuth.Key=testing",
+		"RamSwiftAuth.TokenLifetime=1h",
+	}
+
+	confMap, err := conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) failed: %v", err)
+	}
+
+	err = logger.Up(confMap)
+	if nil != err {
+		t.Fatalf("logger.Up(confMap) failed: %v", err)
+	}
+
+	signalHandlerIsArmed := false
+	doneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go ramswift.Daemon("/dev/null", confStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
+
+	for !signalHandlerIsArmed {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	err = stats.Up(confMap)
+	if nil != err {
+		t.Fatalf("stats.Up(confMap) failed: %v", err)
+	}
+
+	// Ensure unauthenticated requests are rejected
+
+	httpResponse, err := http.Get("http://127.0.0.1:45264/v1/AUTH_test")
+	if nil != err {
+		t.Fatalf("unauthenticated GET failed: %v", err)
+	}
+	_ = httpResponse.Body.Close()
+	if http.StatusUnauthorized != httpResponse.StatusCode {
+		t.Fatalf("unauthenticated GET returned StatusCode %v (expected %v)", httpResponse.StatusCode, http.StatusUnauthorized)
+	}
+
+	// Ensure bad credentials cause Up() to fail
+
+	err = confMap.UpdateFromString("SwiftClient.AuthKey=wrong")
+	if nil != err {
+		t.Fatalf("confMap.UpdateFromString(\"SwiftClient.AuthKey=wrong\") failed: %v", err)
+	}
+
+	err = Up(confMap)
+	if nil == err {
+		t.Fatalf("Up(confMap) with bad AuthKey should have failed")
+	}
+
+	err = confMap.UpdateFromString("SwiftClient.AuthKey=testing")
+	if nil != err {
+		t.Fatalf("confMap.UpdateFromString(\"SwiftClient.AuthKey=testing\") failed: %v", err)
+	}
+
+	// Exercise TempAuth
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("Up(confMap) via TempAuth failed: %v", err)
+	}
+
+	globals.chaosSendChunkFailureRate = 0
+	globals.chaosFetchChunkedPutFailureRate = 0
+
+	testAuthOps(t, "TempAuth")
+
+	// Invalidate the cached token behind swiftclient's back... the next request should obtain a new one
+
+	globals.auth.Lock()
+	globals.auth.token = "bogus"
+	globals.auth.Unlock()
+
+	_, err = AccountHead("AUTH_test")
+	if nil != err {
+		t.Fatalf("AccountHead(\"AUTH_test\") with bogus token failed: %v", err)
+	}
+
+	if "bogus" == globals.auth.fetchToken() {
+		t.Fatalf("bogus token was not refreshed")
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	// Exercise Keystone
+
+	err = confMap.UpdateFromStrings([]string{
+		"SwiftClient.AuthURL=http://127.0.0.1:45264/v3",
+		"SwiftClient.AuthProjectName=test",
+	})
+	if nil != err {
+		t.Fatalf("confMap.UpdateFromStrings() for Keystone failed: %v", err)
+	}
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("Up(confMap) via Keystone failed: %v", err)
+	}
+
+	testAuthOps(t, "Keystone")
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	// Shutdown packages
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("stats.Down() failed: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("logger.Down() failed: %v", err)
+	}
+
+	// Send ourself a SIGTERM to terminate ramswift.Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-doneChan
+}
+
+// testAuthOps performs a representative set of operations (including a chunked PUT) over authenticated connections
+func testAuthOps(t *testing.T, authType string) {
+	var (
+		accountName   = "AUTH_test"
+		containerName = "AuthContainer"
+		objectName    = "authObj" + authType
+	)
+
+	err := AccountPut(accountName, make(map[string][]string))
+	if nil != err {
+		t.Fatalf("%v: AccountPut(\"%v\") failed: %v", authType, accountName, err)
+	}
+
+	err = ContainerPut(accountName, containerName, make(map[string][]string))
+	if nil != err {
+		t.Fatalf("%v: ContainerPut(\"%v\", \"%v\") failed: %v", authType, accountName, containerName, err)
+	}
+
+	err = testObjectWriteVerify(t, accountName, containerName, objectName, 4096, 4)
+	if nil != err {
+		t.Fatalf("%v: testObjectWriteVerify(\"%v\", \"%v\", \"%v\") failed: %v", authType, accountName, containerName, objectName, err)
+	}
+
+	err = ObjectDeleteSync(accountName, containerName, objectName)
+	if nil != err {
+		t.Fatalf("%v: ObjectDeleteSync(\"%v\", \"%v\", \"%v\") failed: %v", authType, accountName, containerName, objectName, err)
+	}
+}
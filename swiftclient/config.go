@@ -2,8 +2,8 @@ package swiftclient
 
 import (
 	"container/list"
+	"crypto/tls"
 	"fmt"
-	"net"
 	"strconv"
 	"sync"
 	"time"
@@ -13,8 +13,8 @@ import (
 )
 
 type connectionStruct struct {
-	connectionNonce uint64 // globals.connectionNonce at time connection was established
-	tcpConn         *net.TCPConn
+	connectionNonce uint64             // globals.connectionNonce at time connection was established
+	tcpConn         *timeoutConnStruct // wraps a *tls.Conn if globals.swiftTLSConfig != nil
 }
 
 type connectionPoolStruct struct {
@@ -54,9 +54,12 @@ type pendingDeletesStruct struct {
 }
 
 type globalsStruct struct {
-	noAuthStringAddr                string
-	noAuthTCPAddr                   *net.TCPAddr
-	timeout                         time.Duration // TODO: Currently not enforced
+	swiftHost                       string        // sent as the Host header of each request
+	swiftAddr                       string        // "<host>:<port>" to which connections are established
+	swiftPathPrefix                 string        // prepended to the path of each request (e.g. "/swift" if StorageURL is ".../swift/v1/AUTH_test")
+	swiftTLSConfig                  *tls.Config   // if nil, connections are not via TLS
+	auth                            *authStruct   // if nil, requests are sent (unauthenticated) to the local NoAuth pipeline
+	timeout                         time.Duration // bounds each connect, Read(), and Write() (0 means unbounded)
 	retryLimit                      uint16        // maximum retries
 	retryLimitObject                uint16        // maximum retries for object ops
 	retryDelay                      time.Duration // delay before first retry
@@ -81,6 +84,7 @@ var globals globalsStruct
 // Up reads the Swift configuration to enable subsequent communication
 func Up(confMap conf.ConfMap) (err error) {
 	var (
+		authURL                      string
 		chunkedConnectionPoolSize    uint16
 		freeConnectionIndex          uint16
 		noAuthTCPPort                uint16
@@ -88,25 +92,33 @@ func Up(confMap conf.ConfMap) (err error) {
 		pendingDeletes               *pendingDeletesStruct
 	)
 
-	noAuthTCPPort, err = confMap.FetchOptionValueUint16("SwiftClient", "NoAuthTCPPort")
+	globals.timeout, err = confMap.FetchOptionValueDuration("SwiftClient", "Timeout")
 	if nil != err {
 		return
 	}
-	if uint16(0) == noAuthTCPPort {
-		err = fmt.Errorf("SwiftClient.NoAuthTCPPort must be a non-zero uint16")
-		return
-	}
 
-	globals.noAuthStringAddr = "127.0.0.1:" + strconv.Itoa(int(noAuthTCPPort))
+	authURL, err = confMap.FetchOptionValueString("SwiftClient", "AuthURL")
+	if (nil == err) && ("" != authURL) {
+		err = authUp(confMap, authURL)
+		if nil != err {
+			return
+		}
+	} else {
+		globals.auth = nil
 
-	globals.noAuthTCPAddr, err = net.ResolveTCPAddr("tcp4", globals.noAuthStringAddr)
-	if nil != err {
-		return
-	}
+		noAuthTCPPort, err = confMap.FetchOptionValueUint16("SwiftClient", "NoAuthTCPPort")
+		if nil != err {
+			return
+		}
+		if uint16(0) == noAuthTCPPort {
+			err = fmt.Errorf("SwiftClient.NoAuthTCPPort must be a non-zero uint16")
+			return
+		}
 
-	globals.timeout, err = confMap.FetchOptionValueDuration("SwiftClient", "Timeout")
-	if nil != err {
-		return
+		globals.swiftHost = "127.0.0.1:" + strconv.Itoa(int(noAuthTCPPort))
+		globals.swiftAddr = globals.swiftHost
+		globals.swiftPathPrefix = ""
+		globals.swiftTLSConfig = nil
 	}
 
 	globals.retryLimit, err = confMap.FetchOptionValueUint16("SwiftClient", "RetryLimit")
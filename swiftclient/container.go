@@ -3,6 +3,7 @@
 package swiftclient
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/swiftstack/ProxyFS/blunder"
@@ -10,19 +11,19 @@ import (
 	"github.com/swiftstack/ProxyFS/stats"
 )
 
-func containerDeleteWithRetry(accountName string, containerName string) (err error) {
+func containerDeleteWithRetry(ctx context.Context, accountName string, containerName string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// containerDelete() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
 	// and then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = containerDelete(accountName, containerName)
+		err = containerDelete(ctx, accountName, containerName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.containerDelete(\"%v/%v\")", accountName, containerName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftContainerDeleteRetryOps,
@@ -32,7 +33,7 @@ func containerDeleteWithRetry(accountName string, containerName string) (err err
 	return err
 }
 
-func containerDelete(accountName string, containerName string) (err error) {
+func containerDelete(ctx context.Context, accountName string, containerName string) (err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -41,7 +42,7 @@ func containerDelete(accountName string, containerName string) (err error) {
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "DELETE", "/"+swiftVersion+"/"+accountName+"/"+containerName, nil)
 	if nil != err {
@@ -74,7 +75,7 @@ func containerDelete(accountName string, containerName string) (err error) {
 	return
 }
 
-func containerGetWithRetry(accountName string, containerName string) (map[string][]string, []string, error) {
+func containerGetWithRetry(ctx context.Context, accountName string, containerName string) (map[string][]string, []string, error) {
 	// request is a function that, through the miracle of closure, calls
 	// containerGet() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
@@ -86,12 +87,12 @@ func containerGetWithRetry(accountName string, containerName string) (map[string
 	)
 	request := func() (bool, error) {
 		var err error
-		headers, objectList, err = containerGet(accountName, containerName)
+		headers, objectList, err = containerGet(ctx, accountName, containerName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.containerGet(\"%v/%v\")", accountName, containerName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftContainerGetRetryOps,
@@ -100,7 +101,7 @@ func containerGetWithRetry(accountName string, containerName string) (map[string
 	err = retryObj.RequestWithRetry(request, &opname, &statnm)
 	return headers, objectList, err
 }
-func containerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+func containerGet(ctx context.Context, accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -108,7 +109,7 @@ func containerGet(accountName string, containerName string) (headers map[string]
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+accountName+"/"+containerName, nil)
 	if nil != err {
@@ -151,7 +152,7 @@ func containerGet(accountName string, containerName string) (headers map[string]
 	return
 }
 
-func containerHeadWithRetry(accountName string, containerName string) (map[string][]string, error) {
+func containerHeadWithRetry(ctx context.Context, accountName string, containerName string) (map[string][]string, error) {
 	// request is a function that, through the miracle of closure, calls
 	// containerHead() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
@@ -162,12 +163,12 @@ func containerHeadWithRetry(accountName string, containerName string) (map[strin
 	)
 	request := func() (bool, error) {
 		var err error
-		headers, err = containerHead(accountName, containerName)
+		headers, err = containerHead(ctx, accountName, containerName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.containerHead(\"%v/%v\")", accountName, containerName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftContainerHeadRetryOps,
@@ -177,7 +178,7 @@ func containerHeadWithRetry(accountName string, containerName string) (map[strin
 	return headers, err
 }
 
-func containerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+func containerHead(ctx context.Context, accountName string, containerName string) (headers map[string][]string, err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -185,7 +186,7 @@ func containerHead(accountName string, containerName string) (headers map[string
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "HEAD", "/"+swiftVersion+"/"+accountName+"/"+containerName, nil)
 	if nil != err {
@@ -218,19 +219,19 @@ func containerHead(accountName string, containerName string) (headers map[string
 	return
 }
 
-func containerPostWithRetry(accountName string, containerName string, requestHeaders map[string][]string) (err error) {
+func containerPostWithRetry(ctx context.Context, accountName string, containerName string, requestHeaders map[string][]string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// containerPost() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
 	// and then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = containerPost(accountName, containerName, requestHeaders)
+		err = containerPost(ctx, accountName, containerName, requestHeaders)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.containerPost(\"%v/%v\")", accountName, containerName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftContainerPostRetryOps,
@@ -240,7 +241,7 @@ func containerPostWithRetry(accountName string, containerName string, requestHea
 	return err
 }
 
-func containerPost(accountName string, containerName string, requestHeaders map[string][]string) (err error) {
+func containerPost(ctx context.Context, accountName string, containerName string, requestHeaders map[string][]string) (err error) {
 	var (
 		connection      *connectionStruct
 		contentLength   int
@@ -250,7 +251,7 @@ func containerPost(accountName string, containerName string, requestHeaders map[
 		responseHeaders map[string][]string
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
@@ -301,19 +302,19 @@ func containerPost(accountName string, containerName string, requestHeaders map[
 	return
 }
 
-func containerPutWithRetry(accountName string, containerName string, requestHeaders map[string][]string) (err error) {
+func containerPutWithRetry(ctx context.Context, accountName string, containerName string, requestHeaders map[string][]string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// containerPut() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
 	// and then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = containerPut(accountName, containerName, requestHeaders)
+		err = containerPut(ctx, accountName, containerName, requestHeaders)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimit, globals.retryDelay, globals.retryExpBackoff)
 		opname   string      = fmt.Sprintf("swiftclient.containerPut(\"%v/%v\")", accountName, containerName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftContainerPutRetryOps,
@@ -323,7 +324,7 @@ func containerPutWithRetry(accountName string, containerName string, requestHead
 	return err
 }
 
-func containerPut(accountName string, containerName string, requestHeaders map[string][]string) (err error) {
+func containerPut(ctx context.Context, accountName string, containerName string, requestHeaders map[string][]string) (err error) {
 	var (
 		connection      *connectionStruct
 		contentLength   int
@@ -333,7 +334,7 @@ func containerPut(accountName string, containerName string, requestHeaders map[s
 		responseHeaders map[string][]string
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
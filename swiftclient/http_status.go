@@ -77,9 +77,13 @@ func httpStatusIsError(httpStatus int) (isError bool, fsErr blunder.FsError) {
 		// Swift returns this error when one tries to delete a container that isn't empty
 		return true, blunder.NotEmptyError
 
+	// 412 Precondition Failed
+	case httpStatus == 412:
+		// Swift returns this error when an If-None-Match: * PUT finds the Object already exists
+		return true, blunder.FileExistsError
+
 	// 410 Gone
 	// 411 Length Required
-	// 412 Precondition Failed
 	// 413 Request Entity Too Large
 	// 414 Request-URI Too Long
 	// 415 Unsupported Media Type
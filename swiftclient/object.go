@@ -3,7 +3,9 @@
 package swiftclient
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"sync"
 
@@ -14,7 +16,7 @@ import (
 	"github.com/swiftstack/ProxyFS/stats"
 )
 
-func objectContentLengthWithRetry(accountName string, containerName string, objectName string) (uint64, error) {
+func objectContentLengthWithRetry(ctx context.Context, accountName string, containerName string, objectName string) (uint64, error) {
 	// request is a function that, through the miracle of closure, calls
 	// objectContentLength() with the paramaters passed to this function,
 	// stashes the relevant return values into the local variables of this
@@ -26,12 +28,12 @@ func objectContentLengthWithRetry(accountName string, containerName string, obje
 	)
 	request := func() (bool, error) {
 		var err error
-		length, err = objectContentLength(accountName, containerName, objectName)
+		length, err = objectContentLength(ctx, accountName, containerName, objectName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string     = fmt.Sprintf("swiftclient.objectContentLength(\"%v/%v/%v\")",
 			accountName, containerName, objectName)
 		statnm RetryStatNm = RetryStatNm{
@@ -42,7 +44,7 @@ func objectContentLengthWithRetry(accountName string, containerName string, obje
 	return length, err
 }
 
-func objectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+func objectContentLength(ctx context.Context, accountName string, containerName string, objectName string) (length uint64, err error) {
 	var (
 		connection         *connectionStruct
 		contentLengthAsInt int
@@ -52,7 +54,7 @@ func objectContentLength(accountName string, containerName string, objectName st
 		isError            bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "HEAD", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, nil)
 	if nil != err {
@@ -95,7 +97,7 @@ func objectContentLength(accountName string, containerName string, objectName st
 	return
 }
 
-func objectCopy(srcAccountName string, srcContainerName string, srcObjectName string, dstAccountName string, dstContainerName string, dstObjectName string, chunkedCopyContext ChunkedCopyContext) (err error) {
+func objectCopy(ctx context.Context, srcAccountName string, srcContainerName string, srcObjectName string, dstAccountName string, dstContainerName string, dstObjectName string, chunkedCopyContext ChunkedCopyContext) (err error) {
 	var (
 		chunk                []byte
 		chunkSize            uint64
@@ -104,12 +106,12 @@ func objectCopy(srcAccountName string, srcContainerName string, srcObjectName st
 		srcObjectSize        uint64
 	)
 
-	srcObjectSize, err = objectContentLengthWithRetry(srcAccountName, srcContainerName, srcObjectName)
+	srcObjectSize, err = objectContentLengthWithRetry(ctx, srcAccountName, srcContainerName, srcObjectName)
 	if nil != err {
 		return
 	}
 
-	dstChunkedPutContext, err = objectFetchChunkedPutContextWithRetry(dstAccountName, dstContainerName, dstObjectName)
+	dstChunkedPutContext, err = objectFetchChunkedPutContextWithRetry(ctx, dstAccountName, dstContainerName, dstObjectName)
 	if nil != err {
 		return
 	}
@@ -117,27 +119,27 @@ func objectCopy(srcAccountName string, srcContainerName string, srcObjectName st
 	for srcObjectPosition < srcObjectSize {
 		chunkSize = chunkedCopyContext.BytesRemaining(srcObjectSize - srcObjectPosition)
 		if 0 == chunkSize {
-			err = dstChunkedPutContext.Close()
+			err = dstChunkedPutContext.CloseWithContext(ctx)
 			return
 		}
 
 		if (srcObjectPosition + chunkSize) > srcObjectSize {
 			chunkSize = srcObjectSize - srcObjectPosition
 
-			chunk, err = objectTailWithRetry(srcAccountName, srcContainerName, srcObjectName, chunkSize)
+			chunk, err = objectTailWithRetry(ctx, srcAccountName, srcContainerName, srcObjectName, chunkSize)
 		} else {
-			chunk, err = objectGetWithRetry(srcAccountName, srcContainerName, srcObjectName, srcObjectPosition, chunkSize)
+			chunk, err = objectGetWithRetry(ctx, srcAccountName, srcContainerName, srcObjectName, srcObjectPosition, chunkSize)
 		}
 
 		srcObjectPosition += chunkSize
 
-		err = dstChunkedPutContext.SendChunk(chunk)
+		err = dstChunkedPutContext.SendChunkWithContext(ctx, chunk)
 		if nil != err {
 			return
 		}
 	}
 
-	err = dstChunkedPutContext.Close()
+	err = dstChunkedPutContext.CloseWithContext(ctx)
 
 	stats.IncrementOperations(&stats.SwiftObjCopyOps)
 
@@ -204,7 +206,7 @@ func objectDeleteAsyncDaemon() {
 				pendingDelete.wgPreCondition.Wait()
 			}
 
-			_ = objectDeleteSyncWithRetry(pendingDelete.accountName, pendingDelete.containerName, pendingDelete.objectName)
+			_ = objectDeleteSyncWithRetry(context.Background(), pendingDelete.accountName, pendingDelete.containerName, pendingDelete.objectName)
 
 			if nil != pendingDelete.wgPostSignal {
 				// TODO: what if the delete failed?
@@ -216,19 +218,19 @@ func objectDeleteAsyncDaemon() {
 	}
 }
 
-func objectDeleteSyncWithRetry(accountName string, containerName string, objectName string) (err error) {
+func objectDeleteSyncWithRetry(ctx context.Context, accountName string, containerName string, objectName string) (err error) {
 	// request is a function that, through the miracle of closure, calls
 	// objectDeleteSync() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
 	// and then returns err and whether it is retriable to RequestWithRetry()
 	request := func() (bool, error) {
 		var err error
-		err = objectDeleteSync(accountName, containerName, objectName)
+		err = objectDeleteSync(ctx, accountName, containerName, objectName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string      = fmt.Sprintf("swiftclient.objectDeleteSync(\"%v/%v/%v\")", accountName, containerName, objectName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftObjDeleteRetryOps,
@@ -238,7 +240,7 @@ func objectDeleteSyncWithRetry(accountName string, containerName string, objectN
 	return err
 }
 
-func objectDeleteSync(accountName string, containerName string, objectName string) (err error) {
+func objectDeleteSync(ctx context.Context, accountName string, containerName string, objectName string) (err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -247,7 +249,7 @@ func objectDeleteSync(accountName string, containerName string, objectName strin
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "DELETE", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, nil)
 	if nil != err {
@@ -280,7 +282,7 @@ func objectDeleteSync(accountName string, containerName string, objectName strin
 	return
 }
 
-func objectGetWithRetry(accountName string, containerName string, objectName string,
+func objectGetWithRetry(ctx context.Context, accountName string, containerName string, objectName string,
 	offset uint64, length uint64) ([]byte, error) {
 
 	// request is a function that, through the miracle of closure, calls
@@ -293,12 +295,12 @@ func objectGetWithRetry(accountName string, containerName string, objectName str
 	)
 	request := func() (bool, error) {
 		var err error
-		buf, err = objectGet(accountName, containerName, objectName, offset, length)
+		buf, err = objectGet(ctx, accountName, containerName, objectName, offset, length)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string      = fmt.Sprintf("swiftclient.objectGet(\"%v/%v/%v\")", accountName, containerName, objectName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftObjGetRetryOps,
@@ -308,7 +310,7 @@ func objectGetWithRetry(accountName string, containerName string, objectName str
 	return buf, err
 }
 
-func objectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+func objectGet(ctx context.Context, accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
 	var (
 		connection    *connectionStruct
 		chunk         []byte
@@ -322,7 +324,7 @@ func objectGet(accountName string, containerName string, objectName string, offs
 	headers = make(map[string][]string)
 	headers["Range"] = []string{"bytes=" + strconv.FormatUint(offset, 10) + "-" + strconv.FormatUint((offset+length-1), 10)}
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, headers)
 	if nil != err {
@@ -394,7 +396,7 @@ func objectGet(accountName string, containerName string, objectName string, offs
 	return
 }
 
-func objectHeadWithRetry(accountName string, containerName string, objectName string) (map[string][]string, error) {
+func objectHeadWithRetry(ctx context.Context, accountName string, containerName string, objectName string) (map[string][]string, error) {
 	// request is a function that, through the miracle of closure, calls
 	// objectHead() with the paramaters passed to this function, stashes
 	// the relevant return values into the local variables of this function,
@@ -405,12 +407,12 @@ func objectHeadWithRetry(accountName string, containerName string, objectName st
 	)
 	request := func() (bool, error) {
 		var err error
-		headers, err = objectHead(accountName, containerName, objectName)
+		headers, err = objectHead(ctx, accountName, containerName, objectName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string      = fmt.Sprintf("swiftclient.objectHead(\"%v/%v/%v\")", accountName, containerName, objectName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftObjHeadRetryOps,
@@ -420,7 +422,7 @@ func objectHeadWithRetry(accountName string, containerName string, objectName st
 	return headers, err
 }
 
-func objectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
+func objectHead(ctx context.Context, accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
 	var (
 		connection *connectionStruct
 		fsErr      blunder.FsError
@@ -428,7 +430,7 @@ func objectHead(accountName string, containerName string, objectName string) (he
 		isError    bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "HEAD", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, nil)
 	if nil != err {
@@ -461,7 +463,7 @@ func objectHead(accountName string, containerName string, objectName string) (he
 	return
 }
 
-func objectLoadWithRetry(accountName string, containerName string, objectName string) ([]byte, error) {
+func objectLoadWithRetry(ctx context.Context, accountName string, containerName string, objectName string) ([]byte, error) {
 	// request is a function that, through the miracle of closure, calls
 	// objectLoad() with the paramaters passed to this function, stashes the
 	// relevant return values into the local variables of this function, and
@@ -472,12 +474,12 @@ func objectLoadWithRetry(accountName string, containerName string, objectName st
 	)
 	request := func() (bool, error) {
 		var err error
-		buf, err = objectLoad(accountName, containerName, objectName)
+		buf, err = objectLoad(ctx, accountName, containerName, objectName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string      = fmt.Sprintf("swiftclient.objectLoad(\"%v/%v/%v\")", accountName, containerName, objectName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftObjLoadRetryOps,
@@ -487,7 +489,7 @@ func objectLoadWithRetry(accountName string, containerName string, objectName st
 	return buf, err
 }
 
-func objectLoad(accountName string, containerName string, objectName string) (buf []byte, err error) {
+func objectLoad(ctx context.Context, accountName string, containerName string, objectName string) (buf []byte, err error) {
 	var (
 		connection    *connectionStruct
 		chunk         []byte
@@ -498,7 +500,7 @@ func objectLoad(accountName string, containerName string, objectName string) (bu
 		isError       bool
 	)
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, nil)
 	if nil != err {
@@ -570,7 +572,107 @@ func objectLoad(accountName string, containerName string, objectName string) (bu
 	return
 }
 
-func objectTailWithRetry(accountName string, containerName string, objectName string,
+func objectPutIfNoneMatchWithRetry(ctx context.Context, accountName string, containerName string, objectName string, buf []byte) (err error) {
+	// request is a function that, through the miracle of closure, calls
+	// objectPutIfNoneMatch() with the paramaters passed to this function,
+	// stashes the relevant return values into the local variables of this
+	// function, and then returns err and whether it is retriable to
+	// RequestWithRetry()
+	//
+	// Note that a failed precondition (i.e. the Object already exists) is
+	// not retriable... though the caller must be prepared to discover that
+	// it was an earlier attempt of its own that created the Object
+	request := func() (bool, error) {
+		var err error
+		err = objectPutIfNoneMatch(ctx, accountName, containerName, objectName, buf)
+		return http.StatusPreconditionFailed != blunder.HTTPCode(err), err
+	}
+
+	var (
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		opname   string      = fmt.Sprintf("swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\")", accountName, containerName, objectName)
+		statnm   RetryStatNm = RetryStatNm{
+			retryCnt:        &stats.SwiftObjPutExclRetryOps,
+			retrySuccessCnt: &stats.SwiftObjPutExclRetrySuccessOps}
+	)
+	err = retryObj.RequestWithRetry(request, &opname, &statnm)
+	return err
+}
+
+func objectPutIfNoneMatch(ctx context.Context, accountName string, containerName string, objectName string, buf []byte) (err error) {
+	var (
+		connection    *connectionStruct
+		contentLength int
+		fsErr         blunder.FsError
+		headers       map[string][]string
+		httpStatus    int
+		isError       bool
+	)
+
+	connection = acquireNonChunkedConnection(ctx)
+
+	headers = make(map[string][]string)
+	headers["Content-Length"] = []string{strconv.Itoa(len(buf))}
+	headers["If-None-Match"] = []string{"*"}
+
+	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "PUT", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, headers)
+	if nil != err {
+		releaseNonChunkedConnection(connection, false)
+		err = blunder.AddError(err, blunder.BadHTTPPutError)
+		logger.ErrorfWithError(err, "swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\") got writeHTTPRequestLineAndHeaders() error", accountName, containerName, objectName)
+		return
+	}
+
+	err = writeBytesToTCPConn(connection.tcpConn, buf)
+	if nil != err {
+		releaseNonChunkedConnection(connection, false)
+		err = blunder.AddError(err, blunder.BadHTTPPutError)
+		logger.ErrorfWithError(err, "swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\") got writeBytesToTCPConn() error", accountName, containerName, objectName)
+		return
+	}
+
+	httpStatus, headers, err = readHTTPStatusAndHeaders(connection.tcpConn)
+	if nil != err {
+		releaseNonChunkedConnection(connection, false)
+		err = blunder.AddError(err, blunder.BadHTTPPutError)
+		logger.ErrorfWithError(err, "swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\") got readHTTPStatusAndHeaders() error", accountName, containerName, objectName)
+		return
+	}
+	isError, fsErr = httpStatusIsError(httpStatus)
+	if isError {
+		releaseNonChunkedConnection(connection, false)
+		err = blunder.NewError(fsErr, "PUT %s/%s/%s returned HTTP StatusCode %d", accountName, containerName, objectName, httpStatus)
+		err = blunder.AddHTTPCode(err, httpStatus)
+		if http.StatusPreconditionFailed != httpStatus {
+			logger.ErrorfWithError(err, "swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\") got readHTTPStatusAndHeaders() bad status", accountName, containerName, objectName)
+		}
+		return
+	}
+	contentLength, err = parseContentLength(headers)
+	if nil != err {
+		releaseNonChunkedConnection(connection, false)
+		err = blunder.AddError(err, blunder.BadHTTPPutError)
+		logger.ErrorfWithError(err, "swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\") got parseContentLength() error", accountName, containerName, objectName)
+		return
+	}
+	if 0 < contentLength {
+		_, err = readBytesFromTCPConn(connection.tcpConn, contentLength)
+		if nil != err {
+			releaseNonChunkedConnection(connection, false)
+			err = blunder.AddError(err, blunder.BadHTTPPutError)
+			logger.ErrorfWithError(err, "swiftclient.objectPutIfNoneMatch(\"%v/%v/%v\") got readBytesFromTCPConn() error", accountName, containerName, objectName)
+			return
+		}
+	}
+
+	releaseNonChunkedConnection(connection, parseConnection(headers))
+
+	stats.IncrementOperations(&stats.SwiftObjPutExclOps)
+
+	return
+}
+
+func objectTailWithRetry(ctx context.Context, accountName string, containerName string, objectName string,
 	length uint64) ([]byte, error) {
 
 	// request is a function that, through the miracle of closure, calls
@@ -583,12 +685,12 @@ func objectTailWithRetry(accountName string, containerName string, objectName st
 	)
 	request := func() (bool, error) {
 		var err error
-		buf, err = objectTail(accountName, containerName, objectName, length)
+		buf, err = objectTail(ctx, accountName, containerName, objectName, length)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string      = fmt.Sprintf("swiftclient.objectTail(\"%v/%v/%v\")", accountName, containerName, objectName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftObjTailRetryOps,
@@ -598,7 +700,7 @@ func objectTailWithRetry(accountName string, containerName string, objectName st
 	return buf, err
 }
 
-func objectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+func objectTail(ctx context.Context, accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
 	var (
 		chunk         []byte
 		connection    *connectionStruct
@@ -612,7 +714,7 @@ func objectTail(accountName string, containerName string, objectName string, len
 	headers = make(map[string][]string)
 	headers["Range"] = []string{"bytes=-" + strconv.FormatUint(length, 10)}
 
-	connection = acquireNonChunkedConnection()
+	connection = acquireNonChunkedConnection(ctx)
 
 	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+accountName+"/"+containerName+"/"+objectName, headers)
 	if nil != err {
@@ -724,7 +826,7 @@ func (chunkedPutContext *chunkedPutContextStruct) DumpValue(value sortedmap.Valu
 	return
 }
 
-func objectFetchChunkedPutContextWithRetry(accountName string, containerName string, objectName string) (*chunkedPutContextStruct, error) {
+func objectFetchChunkedPutContextWithRetry(ctx context.Context, accountName string, containerName string, objectName string) (*chunkedPutContextStruct, error) {
 	// request is a function that, through the miracle of closure, calls
 	// objectFetchChunkedPutContext() with the paramaters passed to this
 	// function, stashes the relevant return values into the local variables of
@@ -735,12 +837,12 @@ func objectFetchChunkedPutContextWithRetry(accountName string, containerName str
 	)
 	request := func() (bool, error) {
 		var err error
-		chunkedPutContext, err = objectFetchChunkedPutContext(accountName, containerName, objectName)
+		chunkedPutContext, err = objectFetchChunkedPutContext(ctx, accountName, containerName, objectName)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl  = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl  = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string      = fmt.Sprintf("swiftclient.objectFetchChunkedPutContext(\"%v/%v/%v\")", accountName, containerName, objectName)
 		statnm   RetryStatNm = RetryStatNm{
 			retryCnt:        &stats.SwiftObjFetchPutCtxtRetryOps,
@@ -753,14 +855,14 @@ func objectFetchChunkedPutContextWithRetry(accountName string, containerName str
 // used during testing for error injection
 var objectFetchChunkedPutContextCnt uint64
 
-func objectFetchChunkedPutContext(accountName string, containerName string, objectName string) (chunkedPutContext *chunkedPutContextStruct, err error) {
+func objectFetchChunkedPutContext(ctx context.Context, accountName string, containerName string, objectName string) (chunkedPutContext *chunkedPutContextStruct, err error) {
 	var (
 		connection *connectionStruct
 		headers    map[string][]string
 	)
 	objectFetchChunkedPutContextCnt += 1
 
-	connection = acquireChunkedConnection()
+	connection = acquireChunkedConnection(ctx)
 
 	headers = make(map[string][]string)
 	headers["Transfer-Encoding"] = []string{"chunked"}
@@ -779,6 +881,10 @@ func objectFetchChunkedPutContext(accountName string, containerName string, obje
 		return
 	}
 
+	// Subsequent SendChunkWithContext() & CloseWithContext() calls supply their own contexts
+
+	connection.tcpConn.clearContext()
+
 	chunkedPutContext = &chunkedPutContextStruct{
 		accountName:   accountName,
 		containerName: containerName,
@@ -808,8 +914,12 @@ func (chunkedPutContext *chunkedPutContextStruct) BytesPut() (bytesPut uint64, e
 }
 
 func (chunkedPutContext *chunkedPutContextStruct) Close() (err error) {
+	return chunkedPutContext.CloseWithContext(context.Background())
+}
 
-	err = chunkedPutContext.closeHelper()
+func (chunkedPutContext *chunkedPutContextStruct) CloseWithContext(ctx context.Context) (err error) {
+
+	err = chunkedPutContext.closeHelper(ctx)
 	if nil == err {
 		return
 	}
@@ -830,18 +940,18 @@ func (chunkedPutContext *chunkedPutContextStruct) Close() (err error) {
 	request := func() (bool, error) {
 		var err error
 
-		err = chunkedPutContext.retry()
+		err = chunkedPutContext.retry(ctx)
 		if err != nil {
 			// closeHelper() will shutdown the TCP connection and
 			// clean up, but it needs to know there was an error
 			chunkedPutContext.err = err
 		}
-		err = chunkedPutContext.closeHelper()
+		err = chunkedPutContext.closeHelper(ctx)
 		return true, err
 	}
 
 	var (
-		retryObj *RetryCtrl = NewRetryCtrl(globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
+		retryObj *RetryCtrl = NewRetryCtrlWithContext(ctx, globals.retryLimitObject, globals.retryDelayObject, globals.retryExpBackoffObject)
 		opname   string     = fmt.Sprintf("swiftclient.chunkedPutContext.Close(\"%v/%v/%v\")",
 			chunkedPutContext.accountName, chunkedPutContext.containerName, chunkedPutContext.objectName)
 		statnm RetryStatNm = RetryStatNm{
@@ -852,7 +962,7 @@ func (chunkedPutContext *chunkedPutContextStruct) Close() (err error) {
 	return err
 }
 
-func (chunkedPutContext *chunkedPutContextStruct) closeHelper() (err error) {
+func (chunkedPutContext *chunkedPutContextStruct) closeHelper(ctx context.Context) (err error) {
 	var (
 		fsErr      blunder.FsError
 		headers    map[string][]string
@@ -882,6 +992,8 @@ func (chunkedPutContext *chunkedPutContextStruct) closeHelper() (err error) {
 		return
 	}
 
+	chunkedPutContext.connection.tcpConn.setContext(ctx)
+
 	err = writeHTTPPutChunk(chunkedPutContext.connection.tcpConn, []byte{})
 	if nil != err {
 		releaseChunkedConnection(chunkedPutContext.connection, false)
@@ -1048,7 +1160,7 @@ func (chunkedPutContext *chunkedPutContextStruct) Read(offset uint64, length uin
 	return
 }
 
-func (chunkedPutContext *chunkedPutContextStruct) retry() (err error) {
+func (chunkedPutContext *chunkedPutContextStruct) retry(ctx context.Context) (err error) {
 	var (
 		chunkBufAsByteSlice []byte
 		chunkBufAsValue     sortedmap.Value
@@ -1070,7 +1182,7 @@ func (chunkedPutContext *chunkedPutContextStruct) retry() (err error) {
 	// clear error from the previous attempt
 	chunkedPutContext.err = nil
 
-	chunkedPutContext.connection = acquireChunkedConnection()
+	chunkedPutContext.connection = acquireChunkedConnection(ctx)
 
 	chunkedPutContext.active = true
 
@@ -1163,6 +1275,10 @@ var (
 // error, SendChunk() also cleans up the TCP connection.
 //
 func (chunkedPutContext *chunkedPutContextStruct) SendChunk(buf []byte) (err error) {
+	return chunkedPutContext.SendChunkWithContext(context.Background(), buf)
+}
+
+func (chunkedPutContext *chunkedPutContextStruct) SendChunkWithContext(ctx context.Context, buf []byte) (err error) {
 
 	chunkedPutContext.Lock()
 	sendChunkCnt += 1
@@ -1238,7 +1354,9 @@ func (chunkedPutContext *chunkedPutContextStruct) SendChunk(buf []byte) (err err
 		sendChunkCnt%globals.chaosSendChunkFailureRate == 0 {
 		err = fmt.Errorf("writeHTTPPutChunk() simulated error")
 	} else {
+		chunkedPutContext.connection.tcpConn.setContext(ctx)
 		err = writeHTTPPutChunk(chunkedPutContext.connection.tcpConn, buf)
+		chunkedPutContext.connection.tcpConn.clearContext()
 	}
 	if nil != err {
 		err = blunder.AddError(err, blunder.BadHTTPPutError)
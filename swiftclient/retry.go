@@ -3,9 +3,12 @@
 package swiftclient
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/stats"
 )
@@ -15,15 +18,17 @@ import (
 // on subsequent replay attempts.  lastReq tracks the time that the last request
 // was made so that time consumed by the request can be subtracted from the
 // backoff amount (if a request takes 30 sec to timeout and the initial delay
-// is 10 sec, we don't want 40 sec between requests).
+// is 10 sec, we don't want 40 sec between requests).  Once ctx is done (its
+// deadline has passed or it has been cancelled), no further attempts are made.
 //
 type RetryCtrl struct {
-	attemptMax uint          // maximum attempts
-	attemptCnt uint          // number of attempts
-	delay      time.Duration // backoff amount (grows each attempt)
-	expBackoff float64       // factor to increase delay by
-	firstReq   time.Time     // first request start time
-	lastReq    time.Time     // most recent request start time
+	ctx        context.Context // bounds the entire (retried) operation
+	attemptMax uint            // maximum attempts
+	attemptCnt uint            // number of attempts
+	delay      time.Duration   // backoff amount (grows each attempt)
+	expBackoff float64         // factor to increase delay by
+	firstReq   time.Time       // first request start time
+	lastReq    time.Time       // most recent request start time
 }
 
 type RetryStatNm struct {
@@ -32,7 +37,11 @@ type RetryStatNm struct {
 }
 
 func NewRetryCtrl(maxAttempt uint16, delay time.Duration, expBackoff float64) *RetryCtrl {
-	var ctrl = RetryCtrl{attemptCnt: 0, attemptMax: uint(maxAttempt), delay: delay, expBackoff: expBackoff}
+	return NewRetryCtrlWithContext(context.Background(), maxAttempt, delay, expBackoff)
+}
+
+func NewRetryCtrlWithContext(ctx context.Context, maxAttempt uint16, delay time.Duration, expBackoff float64) *RetryCtrl {
+	var ctrl = RetryCtrl{ctx: ctx, attemptCnt: 0, attemptMax: uint(maxAttempt), delay: delay, expBackoff: expBackoff}
 	ctrl.firstReq = time.Now()
 	ctrl.lastReq = ctrl.firstReq
 
@@ -41,13 +50,16 @@ func NewRetryCtrl(maxAttempt uint16, delay time.Duration, expBackoff float64) *R
 
 // Wait until this.delay has elapsed since the last request started and then
 // update the delay with the exponential backoff and record when the next
-// request was started
+// request was started (returning early should this.ctx become done)
 //
 func (this *RetryCtrl) RetryWait() {
 	var delay time.Duration = time.Now().Sub(this.lastReq)
 
 	if this.delay > delay {
-		time.Sleep(this.delay - delay)
+		select {
+		case <-time.After(this.delay - delay):
+		case <-this.ctx.Done():
+		}
 	}
 	this.delay = time.Duration(float64(this.delay) * this.expBackoff)
 	this.lastReq = time.Now()
@@ -71,6 +83,10 @@ func (this *RetryCtrl) RequestWithRetry(doRequest func() (bool, error), opid *st
 
 	this.attemptCnt = 1
 	retriable, lastErr = doRequest()
+	if (lastErr != nil) && (nil != globals.auth) && (http.StatusUnauthorized == blunder.HTTPCode(lastErr)) {
+		// The auth token was rejected (and has since been invalidated)... so immediately retry with a fresh one
+		retriable, lastErr = doRequest()
+	}
 	if lastErr == nil {
 		return nil
 	}
@@ -90,6 +106,15 @@ func (this *RetryCtrl) RequestWithRetry(doRequest func() (bool, error), opid *st
 	for retriable && this.attemptCnt <= this.attemptMax {
 		this.RetryWait()
 
+		if nil != this.ctx.Err() {
+			elapsed := float64(time.Since(this.firstReq)) / float64(time.Second)
+			errstring := fmt.Sprintf(
+				"retry.RequestWithRetry(): %s abandoned after %d attempts in %4.3f sec: %v",
+				*opid, this.attemptCnt, elapsed, this.ctx.Err())
+			logger.ErrorWithError(lastErr, errstring)
+			return lastErr
+		}
+
 		this.attemptCnt++
 		retriable, lastErr = doRequest()
 		if lastErr == nil {
@@ -0,0 +1,220 @@
+package swiftclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+func TestTimeout(t *testing.T) {
+	confStrings := []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"SwiftClient.NoAuthTCPPort=45265",
+
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=1",
+		"SwiftClient.RetryLimitObject=1",
+		"SwiftClient.RetryDelay=100ms",
+		"SwiftClient.RetryDelayObject=100ms",
+		"SwiftClient.RetryExpBackoff=1.0",
+		"SwiftClient.RetryExpBackoffObject=1.0",
+		"SwiftClient.ChunkedConnectionPoolSize=8",
+		"SwiftClient.NonChunkedConnectionPoolSize=8",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+
+		"Cluster.WhoAmI=Peer0",
+
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+
+		"FSGlobals.VolumeList=",
+
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+
+		"RamSwiftChaos.StallDuration=5s",
+		"RamSwiftChaos.ObjectGetFailureRate=1",
+		"RamSwiftChaos.ObjectPutFailureRate=1",
+	}
+
+	// Each Object GET & PUT will stall well beyond the deadlines applied below
+
+	maxElapsed := 2 * time.Second
+
+	confMap, err := conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) failed: %v", err)
+	}
+
+	err = logger.Up(confMap)
+	if nil != err {
+		t.Fatalf("logger.Up(confMap) failed: %v", err)
+	}
+
+	signalHandlerIsArmed := false
+	doneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go ramswift.Daemon("/dev/null", confStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
+
+	for !signalHandlerIsArmed {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	err = stats.Up(confMap)
+	if nil != err {
+		t.Fatalf("stats.Up(confMap) failed: %v", err)
+	}
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("Up(confMap) failed: %v", err)
+	}
+
+	globals.chaosSendChunkFailureRate = 0
+	globals.chaosFetchChunkedPutFailureRate = 0
+
+	err = AccountPut("TimeoutAccount", make(map[string][]string))
+	if nil != err {
+		t.Fatalf("AccountPut(\"TimeoutAccount\") failed: %v", err)
+	}
+	err = ContainerPut("TimeoutAccount", "TimeoutContainer", make(map[string][]string))
+	if nil != err {
+		t.Fatalf("ContainerPut(\"TimeoutAccount\", \"TimeoutContainer\") failed: %v", err)
+	}
+
+	// A context deadline should bound ObjectGetWithContext()... and the timed-out connection must not be reused
+
+	globals.nonChunkedConnectionPool.Lock()
+	lifoIndexBefore := globals.nonChunkedConnectionPool.lifoIndex
+	globals.nonChunkedConnectionPool.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	start := time.Now()
+	_, err = ObjectGetWithContext(ctx, "TimeoutAccount", "TimeoutContainer", "TimeoutObject", 0, 1)
+	elapsed := time.Since(start)
+	cancel()
+	if nil == err {
+		t.Fatalf("ObjectGetWithContext() of stalled object should have failed")
+	}
+	if elapsed > maxElapsed {
+		t.Fatalf("ObjectGetWithContext() of stalled object took %v (deadline was 250ms)", elapsed)
+	}
+	if context.DeadlineExceeded != ctx.Err() {
+		t.Fatalf("ctx.Err() should have been context.DeadlineExceeded but was %v", ctx.Err())
+	}
+
+	globals.nonChunkedConnectionPool.Lock()
+	lifoIndexAfter := globals.nonChunkedConnectionPool.lifoIndex
+	for _, connection := range globals.nonChunkedConnectionPool.lifoOfActiveConnections[:lifoIndexAfter] {
+		if connection.tcpConn.timedOut {
+			t.Fatalf("timed-out connection was returned to nonChunkedConnectionPool")
+		}
+	}
+	globals.nonChunkedConnectionPool.Unlock()
+	if lifoIndexAfter > lifoIndexBefore {
+		t.Fatalf("nonChunkedConnectionPool.lifoIndex grew from %v to %v", lifoIndexBefore, lifoIndexAfter)
+	}
+
+	// Cancellation should promptly abort ObjectGetWithContext()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		cancel()
+	}()
+	start = time.Now()
+	_, err = ObjectGetWithContext(ctx, "TimeoutAccount", "TimeoutContainer", "TimeoutObject", 0, 1)
+	elapsed = time.Since(start)
+	if nil == err {
+		t.Fatalf("cancelled ObjectGetWithContext() of stalled object should have failed")
+	}
+	if elapsed > maxElapsed {
+		t.Fatalf("cancelled ObjectGetWithContext() of stalled object took %v (cancelled after 250ms)", elapsed)
+	}
+	if context.Canceled != ctx.Err() {
+		t.Fatalf("ctx.Err() should have been context.Canceled but was %v", ctx.Err())
+	}
+
+	// A context deadline should bound a chunked PUT's CloseWithContext()
+
+	chunkedPutContext, err := ObjectFetchChunkedPutContext("TimeoutAccount", "TimeoutContainer", "TimeoutObject")
+	if nil != err {
+		t.Fatalf("ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	start = time.Now()
+	err = chunkedPutContext.SendChunkWithContext(ctx, []byte{0x01, 0x02, 0x03})
+	if nil != err {
+		t.Fatalf("SendChunkWithContext() failed: %v", err)
+	}
+	err = chunkedPutContext.CloseWithContext(ctx)
+	elapsed = time.Since(start)
+	cancel()
+	if nil == err {
+		t.Fatalf("CloseWithContext() of stalled PUT should have failed")
+	}
+	if elapsed > maxElapsed {
+		t.Fatalf("CloseWithContext() of stalled PUT took %v (deadline was 500ms)", elapsed)
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	// Absent a context, [SwiftClient]Timeout should bound each attempt
+
+	err = confMap.UpdateFromString("SwiftClient.Timeout=250ms")
+	if nil != err {
+		t.Fatalf("confMap.UpdateFromString(\"SwiftClient.Timeout=250ms\") failed: %v", err)
+	}
+
+	err = Up(confMap)
+	if nil != err {
+		t.Fatalf("Up(confMap) failed: %v", err)
+	}
+
+	start = time.Now()
+	_, err = ObjectGet("TimeoutAccount", "TimeoutContainer", "TimeoutObject", 0, 1)
+	elapsed = time.Since(start)
+	if nil == err {
+		t.Fatalf("ObjectGet() of stalled object should have failed")
+	}
+	if elapsed > maxElapsed {
+		t.Fatalf("ObjectGet() of stalled object took %v (Timeout was 250ms with 1 retry)", elapsed)
+	}
+
+	err = Down()
+	if nil != err {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	// Shutdown packages
+
+	err = stats.Down()
+	if nil != err {
+		t.Fatalf("stats.Down() failed: %v", err)
+	}
+
+	err = logger.Down()
+	if nil != err {
+		t.Fatalf("logger.Down() failed: %v", err)
+	}
+
+	// Send ourself a SIGTERM to terminate ramswift.Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-doneChan
+}
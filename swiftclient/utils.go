@@ -3,8 +3,11 @@ package swiftclient
 import (
 	"bytes"
 	"container/list"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,6 +43,190 @@ func drainConnectionPools() {
 	globals.nonChunkedConnectionPool.Unlock()
 }
 
+// dialSwift establishes a connection to globals.swiftAddr (via TLS if globals.swiftTLSConfig is set).
+func dialSwift() (conn *timeoutConnStruct, err error) {
+	var (
+		dialer  = &net.Dialer{Timeout: globals.timeout}
+		netConn net.Conn
+	)
+
+	if nil == globals.swiftTLSConfig {
+		netConn, err = dialer.Dial("tcp", globals.swiftAddr)
+	} else {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", globals.swiftAddr, globals.swiftTLSConfig)
+	}
+	if nil != err {
+		return
+	}
+
+	conn = &timeoutConnStruct{Conn: netConn, ctx: context.Background()}
+
+	return
+}
+
+// timeoutConnStruct wraps a connection to Swift such that each Read() or Write() must complete within
+// globals.timeout (if non-zero) and before the deadline (if any) of the context of the operation currently
+// using the connection. Should that context be cancelled, a Read() or Write() underway fails promptly.
+//
+// Once a Read() or Write() has failed for either reason, timedOut is set... as the response to the request
+// underway may yet arrive, the connection must not be reused.
+type timeoutConnStruct struct {
+	sync.Mutex // serializes deadline updates between Read()/Write() and cancellation of ctx
+	net.Conn   //
+	ctx        context.Context
+	cancelChan chan struct{} // if non-nil, closed to terminate the goroutine awaiting ctx.Done()
+	deadline   time.Time     // deadline most recently applied to Conn
+	timedOut   bool
+}
+
+// setContext associates ctx with subsequent Read() and Write() calls (until a call to clearContext()).
+func (conn *timeoutConnStruct) setContext(ctx context.Context) {
+	conn.clearContext()
+
+	conn.Lock()
+	conn.ctx = ctx
+	if nil != ctx.Done() {
+		conn.cancelChan = make(chan struct{})
+		go conn.awaitCancellation(ctx, conn.cancelChan)
+	}
+	conn.Unlock()
+}
+
+// clearContext reverts Read() and Write() to being bounded solely by globals.timeout.
+func (conn *timeoutConnStruct) clearContext() {
+	conn.Lock()
+	if nil != conn.cancelChan {
+		close(conn.cancelChan)
+		conn.cancelChan = nil
+	}
+	conn.ctx = context.Background()
+	conn.Unlock()
+}
+
+func (conn *timeoutConnStruct) awaitCancellation(ctx context.Context, cancelChan chan struct{}) {
+	select {
+	case _ = <-ctx.Done():
+		conn.Lock()
+		if cancelChan == conn.cancelChan {
+			// Any Read() or Write() underway (or subsequently attempted) will now fail
+			conn.deadline = time.Unix(1, 0)
+			_ = conn.Conn.SetDeadline(conn.deadline)
+		}
+		conn.Unlock()
+	case _ = <-cancelChan:
+		// ctx no longer applies to conn
+	}
+}
+
+// armDeadline sets the deadline for a Read() or Write() about to be attempted (failing if conn.ctx is done).
+func (conn *timeoutConnStruct) armDeadline() (err error) {
+	var (
+		ctxDeadline time.Time
+		deadline    time.Time
+		ok          bool
+	)
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	err = conn.ctx.Err()
+	if nil != err {
+		conn.timedOut = true
+		return
+	}
+
+	if 0 != globals.timeout {
+		deadline = time.Now().Add(globals.timeout)
+	}
+
+	ctxDeadline, ok = conn.ctx.Deadline()
+	if ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+
+	// Avoid updating the deadline for each (e.g. single byte) Read() if it would only move out slightly
+
+	if deadline.Equal(conn.deadline) {
+		err = nil
+		return
+	}
+	if !conn.deadline.IsZero() && deadline.After(conn.deadline) && (deadline.Sub(conn.deadline) < (globals.timeout / 16)) {
+		err = nil
+		return
+	}
+
+	conn.deadline = deadline
+
+	err = conn.Conn.SetDeadline(deadline)
+
+	return
+}
+
+// noteError records a Read() or Write() having failed due to a deadline or cancellation (returning ctx.Err() for the latter).
+func (conn *timeoutConnStruct) noteError(err error) error {
+	netErr, ok := err.(net.Error)
+	if !ok || !netErr.Timeout() {
+		return err
+	}
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	conn.timedOut = true
+
+	if nil != conn.ctx.Err() {
+		return conn.ctx.Err()
+	}
+
+	return err
+}
+
+func (conn *timeoutConnStruct) Read(b []byte) (n int, err error) {
+	err = conn.armDeadline()
+	if nil != err {
+		return
+	}
+
+	n, err = conn.Conn.Read(b)
+	if nil != err {
+		err = conn.noteError(err)
+	}
+
+	return
+}
+
+func (conn *timeoutConnStruct) Write(b []byte) (n int, err error) {
+	err = conn.armDeadline()
+	if nil != err {
+		return
+	}
+
+	n, err = conn.Conn.Write(b)
+	if nil != err {
+		err = conn.noteError(err)
+	}
+
+	return
+}
+
+// isReusable returns whether conn may be returned to its connection pool (resetting its deadline if so).
+func (conn *timeoutConnStruct) isReusable() (reusable bool) {
+	conn.clearContext()
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	if conn.timedOut {
+		reusable = false
+		return
+	}
+
+	conn.deadline = time.Time{}
+	reusable = (nil == conn.Conn.SetDeadline(conn.deadline))
+
+	return
+}
+
 func chunkedConnectionPoolInStarvationMode() {
 	var (
 		starvationCallback StarvationCallbackFunc
@@ -59,7 +246,10 @@ func chunkedConnectionPoolInStarvationMode() {
 	}
 }
 
-func acquireChunkedConnection() (connection *connectionStruct) {
+// acquireChunkedConnection returns a connection to be used for the duration of an operation bounded by ctx.
+//
+// Note that ctx does not bound the wait for a connection pool slot to become available.
+func acquireChunkedConnection(ctx context.Context) (connection *connectionStruct) {
 	var (
 		cv  *sync.Cond
 		err error
@@ -81,9 +271,9 @@ func acquireChunkedConnection() (connection *connectionStruct) {
 
 	if 0 == globals.chunkedConnectionPool.lifoIndex {
 		connection = &connectionStruct{connectionNonce: globals.connectionNonce}
-		connection.tcpConn, err = net.DialTCP("tcp4", nil, globals.noAuthTCPAddr)
+		connection.tcpConn, err = dialSwift()
 		if nil != err {
-			logger.FatalfWithError(err, "swiftclient.acquireChunkedConnection() cannot connect to Swift NoAuth Pipeline @ %s", globals.noAuthStringAddr)
+			logger.FatalfWithError(err, "swiftclient.acquireChunkedConnection() cannot connect to Swift @ %s", globals.swiftAddr)
 		}
 	} else {
 		globals.chunkedConnectionPool.lifoIndex--
@@ -93,6 +283,8 @@ func acquireChunkedConnection() (connection *connectionStruct) {
 
 	globals.chunkedConnectionPool.Unlock()
 
+	connection.tcpConn.setContext(ctx)
+
 	return
 }
 
@@ -102,6 +294,10 @@ func releaseChunkedConnection(connection *connectionStruct, keepAlive bool) {
 		cv     *sync.Cond
 	)
 
+	// Timed-out (or cancelled) connections are never reused
+
+	keepAlive = keepAlive && connection.tcpConn.isReusable()
+
 	globals.chunkedConnectionPool.Lock()
 	if keepAlive &&
 		(connection.connectionNonce == globals.connectionNonce) &&
@@ -127,7 +323,10 @@ func releaseChunkedConnection(connection *connectionStruct, keepAlive bool) {
 	globals.chunkedConnectionPool.Unlock()
 }
 
-func acquireNonChunkedConnection() (connection *connectionStruct) {
+// acquireNonChunkedConnection returns a connection to be used for the duration of an operation bounded by ctx.
+//
+// Note that ctx does not bound the wait for a connection pool slot to become available.
+func acquireNonChunkedConnection(ctx context.Context) (connection *connectionStruct) {
 	var (
 		cv  *sync.Cond
 		err error
@@ -145,9 +344,9 @@ func acquireNonChunkedConnection() (connection *connectionStruct) {
 
 	if 0 == globals.nonChunkedConnectionPool.lifoIndex {
 		connection = &connectionStruct{connectionNonce: globals.connectionNonce}
-		connection.tcpConn, err = net.DialTCP("tcp4", nil, globals.noAuthTCPAddr)
+		connection.tcpConn, err = dialSwift()
 		if nil != err {
-			logger.FatalfWithError(err, "swiftclient.acquireNonChunkedConnection() cannot connect to Swift NoAuth Pipeline @ %s", globals.noAuthStringAddr)
+			logger.FatalfWithError(err, "swiftclient.acquireNonChunkedConnection() cannot connect to Swift @ %s", globals.swiftAddr)
 		}
 	} else {
 		globals.nonChunkedConnectionPool.lifoIndex--
@@ -157,6 +356,8 @@ func acquireNonChunkedConnection() (connection *connectionStruct) {
 
 	globals.nonChunkedConnectionPool.Unlock()
 
+	connection.tcpConn.setContext(ctx)
+
 	return
 }
 
@@ -166,6 +367,10 @@ func releaseNonChunkedConnection(connection *connectionStruct, keepAlive bool) {
 		cv     *sync.Cond
 	)
 
+	// Timed-out (or cancelled) connections are never reused
+
+	keepAlive = keepAlive && connection.tcpConn.isReusable()
+
 	globals.nonChunkedConnectionPool.Lock()
 	if keepAlive &&
 		(connection.connectionNonce == globals.connectionNonce) &&
@@ -201,7 +406,7 @@ func nonChunkedConnectionFreeCnt() (freeNonChunkedConnections int64) {
 	return
 }
 
-func writeBytesToTCPConn(tcpConn *net.TCPConn, buf []byte) (err error) {
+func writeBytesToTCPConn(tcpConn net.Conn, buf []byte) (err error) {
 	var (
 		bufPos  = int(0)
 		written int
@@ -220,7 +425,7 @@ func writeBytesToTCPConn(tcpConn *net.TCPConn, buf []byte) (err error) {
 	return
 }
 
-func writeHTTPRequestLineAndHeaders(tcpConn *net.TCPConn, method string, path string, headers map[string][]string) (err error) {
+func writeHTTPRequestLineAndHeaders(tcpConn net.Conn, method string, path string, headers map[string][]string) (err error) {
 	var (
 		bytesBuffer      bytes.Buffer
 		headerName       string
@@ -229,11 +434,15 @@ func writeHTTPRequestLineAndHeaders(tcpConn *net.TCPConn, method string, path st
 		headerValues     []string
 	)
 
-	_, _ = bytesBuffer.WriteString(method + " " + path + " HTTP/1.1\r\n")
+	_, _ = bytesBuffer.WriteString(method + " " + globals.swiftPathPrefix + path + " HTTP/1.1\r\n")
 
-	_, _ = bytesBuffer.WriteString("Host: " + globals.noAuthStringAddr + "\r\n")
+	_, _ = bytesBuffer.WriteString("Host: " + globals.swiftHost + "\r\n")
 	_, _ = bytesBuffer.WriteString("User-Agent: ProxyFS\r\n")
 
+	if nil != globals.auth {
+		_, _ = bytesBuffer.WriteString("X-Auth-Token: " + globals.auth.fetchToken() + "\r\n")
+	}
+
 	for headerName, headerValues = range headers {
 		_, _ = bytesBuffer.WriteString(headerName + ": ")
 		for headerValueIndex, headerValue = range headerValues {
@@ -253,7 +462,7 @@ func writeHTTPRequestLineAndHeaders(tcpConn *net.TCPConn, method string, path st
 	return
 }
 
-func writeHTTPPutChunk(tcpConn *net.TCPConn, buf []byte) (err error) {
+func writeHTTPPutChunk(tcpConn net.Conn, buf []byte) (err error) {
 	err = writeBytesToTCPConn(tcpConn, []byte(fmt.Sprintf("%X\r\n", len(buf))))
 	if nil != err {
 		return
@@ -271,7 +480,7 @@ func writeHTTPPutChunk(tcpConn *net.TCPConn, buf []byte) (err error) {
 	return
 }
 
-func readByteFromTCPConn(tcpConn *net.TCPConn) (b byte, err error) {
+func readByteFromTCPConn(tcpConn net.Conn) (b byte, err error) {
 	var (
 		numBytesRead int
 		oneByteBuf   = []byte{byte(0)}
@@ -291,7 +500,7 @@ func readByteFromTCPConn(tcpConn *net.TCPConn) (b byte, err error) {
 	}
 }
 
-func readBytesFromTCPConn(tcpConn *net.TCPConn, bufLen int) (buf []byte, err error) {
+func readBytesFromTCPConn(tcpConn net.Conn, bufLen int) (buf []byte, err error) {
 	var (
 		numBytesRead int
 		bufPos       = int(0)
@@ -312,7 +521,7 @@ func readBytesFromTCPConn(tcpConn *net.TCPConn, bufLen int) (buf []byte, err err
 	return
 }
 
-func readHTTPEmptyLineCRLF(tcpConn *net.TCPConn) (err error) {
+func readHTTPEmptyLineCRLF(tcpConn net.Conn) (err error) {
 	var (
 		b byte
 	)
@@ -339,7 +548,7 @@ func readHTTPEmptyLineCRLF(tcpConn *net.TCPConn) (err error) {
 	return
 }
 
-func readHTTPLineCRLF(tcpConn *net.TCPConn) (line string, err error) {
+func readHTTPLineCRLF(tcpConn net.Conn) (line string, err error) {
 	var (
 		b           byte
 		bytesBuffer bytes.Buffer
@@ -374,7 +583,7 @@ func readHTTPLineCRLF(tcpConn *net.TCPConn) (line string, err error) {
 	}
 }
 
-func readHTTPLineLF(tcpConn *net.TCPConn) (line string, err error) {
+func readHTTPLineLF(tcpConn net.Conn) (line string, err error) {
 	var (
 		b           byte
 		bytesBuffer bytes.Buffer
@@ -399,7 +608,7 @@ func readHTTPLineLF(tcpConn *net.TCPConn) (line string, err error) {
 	}
 }
 
-func readHTTPStatusAndHeaders(tcpConn *net.TCPConn) (httpStatus int, headers map[string][]string, err error) {
+func readHTTPStatusAndHeaders(tcpConn net.Conn) (httpStatus int, headers map[string][]string, err error) {
 	var (
 		colonSplit      []string
 		commaSplit      []string
@@ -428,6 +637,10 @@ func readHTTPStatusAndHeaders(tcpConn *net.TCPConn) (httpStatus int, headers map
 		return
 	}
 
+	if (http.StatusUnauthorized == httpStatus) && (nil != globals.auth) {
+		globals.auth.invalidateToken()
+	}
+
 	headers = make(map[string][]string)
 
 	for {
@@ -583,7 +796,7 @@ func parseConnection(headers map[string][]string) (connectionStillOpen bool) {
 	return
 }
 
-func readHTTPPayloadLines(tcpConn *net.TCPConn, headers map[string][]string) (lines []string, err error) {
+func readHTTPPayloadLines(tcpConn net.Conn, headers map[string][]string) (lines []string, err error) {
 	var (
 		buf                  []byte
 		bufCurrentPosition   int
@@ -632,7 +845,7 @@ func readHTTPPayloadLines(tcpConn *net.TCPConn, headers map[string][]string) (li
 	return
 }
 
-func readHTTPChunk(tcpConn *net.TCPConn) (chunk []byte, err error) {
+func readHTTPChunk(tcpConn net.Conn) (chunk []byte, err error) {
 	var (
 		chunkLenAsInt    int
 		chunkLenAsUint64 uint64
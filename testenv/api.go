@@ -0,0 +1,219 @@
+// Package testenv provides the ramswift-backed environment shared by the unit tests of packages layered
+// above inode (e.g. fs and fuse).
+//
+// Up() mounts a ramfs (on Linux) at a fresh temporary directory, makes it the current working directory,
+// launches ramswift, and brings up each package beneath fs configured with a single, freshly formatted
+// TestVolume. The caller then brings up fs (and whatever lies above it) using the returned confMap. Once
+// those have been taken back down, Down() undoes what Up() did.
+package testenv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/platform"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+// Up is called from a package's TestMain(). The testDirPrefix, statsUDPPort, and noAuthTCPPort must be
+// unique to the calling package as go test may run the tests of several packages concurrently.
+func Up(testDirPrefix string, statsUDPPort uint16, noAuthTCPPort uint16) (confMap conf.ConfMap, err error) {
+	testDir, err := ioutil.TempDir(os.TempDir(), testDirPrefix)
+	if nil != err {
+		return
+	}
+
+	if platform.IsLinux {
+		cmd := exec.Command("sudo", "mount", "-t", "ramfs", "-o", "size=512m", "ext4", testDir)
+		err = cmd.Run()
+		if nil != err {
+			return
+		}
+
+		cmd = exec.Command("sudo", "chmod", "777", testDir)
+		err = cmd.Run()
+		if nil != err {
+			return
+		}
+	}
+
+	err = os.Chdir(testDir)
+	if nil != err {
+		return
+	}
+
+	err = os.Mkdir("TestVolume", os.ModePerm)
+
+	testConfMapStrings := []string{
+		"Stats.IPAddr=localhost",
+		fmt.Sprintf("Stats.UDPPort=%d", statsUDPPort),
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"Logging.LogFilePath=proxyfsd.log",
+		fmt.Sprintf("SwiftClient.NoAuthTCPPort=%d", noAuthTCPPort),
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=5",
+		"SwiftClient.RetryLimitObject=5",
+		"SwiftClient.RetryDelay=1s",
+		"SwiftClient.RetryDelayObject=1s",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"SwiftClient.StarvationCallbackFrequency=100ms",
+		"FlowControl:TestFlowControl.MaxFlushSize=10000000",
+		"FlowControl:TestFlowControl.MaxFlushTime=10s",
+		"FlowControl:TestFlowControl.ReadCacheLineSize=1000000",
+		"FlowControl:TestFlowControl.ReadCacheWeight=100",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerStoragePolicy=silver",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerNamePrefix=Replicated3Way_",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainersPerPeer=1000",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.MaxObjectsPerContainer=1000000",
+		"Peer:Peer0.PrivateIPAddr=localhost",
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+		"Cluster.Peers=Peer0",
+		"Cluster.WhoAmI=Peer0",
+		"Volume:TestVolume.FSID=1",
+		"Volume:TestVolume.PrimaryPeer=Peer0",
+		"Volume:TestVolume.AccountName=CommonAccount",
+		"Volume:TestVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:TestVolume.CheckpointContainerStoragePolicy=gold",
+		"Volume:TestVolume.CheckpointInterval=10s",
+		"Volume:TestVolume.CheckpointIntervalsPerCompaction=100",
+		"Volume:TestVolume.DefaultPhysicalContainerLayout=PhysicalContainerLayoutReplicated3Way",
+		"Volume:TestVolume.FlowControl=TestFlowControl",
+		"Volume:TestVolume.NonceValuesToReserve=100",
+		"Volume:TestVolume.MaxEntriesPerDirNode=32",
+		"Volume:TestVolume.MaxExtentsPerFileNode=32",
+		"Volume:TestVolume.MaxInodesPerMetadataNode=32",
+		"Volume:TestVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:TestVolume.MaxDirFileNodesPerMetadataNode=16",
+		"FSGlobals.VolumeList=TestVolume",
+		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
+		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
+		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
+		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
+		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
+		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
+		"FSGlobals.DirEntryCacheEvictLowLimit=10000",
+		"FSGlobals.DirEntryCacheEvictHighLimit=10010",
+		"FSGlobals.FileExtentMapEvictLowLimit=10000",
+		"FSGlobals.FileExtentMapEvictHighLimit=10010",
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+	}
+
+	confMap, err = conf.MakeConfMapFromStrings(testConfMapStrings)
+	if nil != err {
+		return
+	}
+
+	signalHandlerIsArmed := false
+	doneChan := make(chan bool, 1)
+	go ramswift.Daemon("/dev/null", testConfMapStrings, &signalHandlerIsArmed, doneChan, unix.SIGTERM)
+
+	err = stats.Up(confMap)
+	if nil != err {
+		return
+	}
+
+	err = logger.Up(confMap)
+	if nil != err {
+		stats.Down()
+		return
+	}
+
+	err = dlm.Up(confMap)
+	if nil != err {
+		logger.Down()
+		stats.Down()
+		return
+	}
+
+	err = swiftclient.Up(confMap)
+	if err != nil {
+		dlm.Down()
+		logger.Down()
+		stats.Down()
+		return
+	}
+
+	err = headhunter.Format(confMap, "TestVolume")
+	if nil != err {
+		swiftclient.Down()
+		dlm.Down()
+		logger.Down()
+		stats.Down()
+		return
+	}
+
+	err = headhunter.Up(confMap)
+	if nil != err {
+		swiftclient.Down()
+		dlm.Down()
+		logger.Down()
+		stats.Down()
+		return
+	}
+
+	err = inode.Up(confMap)
+	if nil != err {
+		headhunter.Down()
+		swiftclient.Down()
+		dlm.Down()
+		logger.Down()
+		stats.Down()
+		return
+	}
+
+	err = nil
+	return
+}
+
+// Down is called from a package's TestMain() once it has taken down whatever it brought up atop Up().
+func Down() (err error) {
+	inode.Down()
+	headhunter.Down()
+	swiftclient.Down()
+	dlm.Down()
+	logger.Down()
+	stats.Down()
+
+	testDir, err := os.Getwd()
+	if nil != err {
+		return
+	}
+
+	err = os.Chdir("..")
+	if nil != err {
+		return
+	}
+
+	if platform.IsLinux {
+		cmd := exec.Command("sudo", "umount", testDir)
+		err = cmd.Run()
+		if nil != err {
+			return
+		}
+	} else {
+		err = os.RemoveAll(testDir)
+		if nil != err {
+			return
+		}
+	}
+
+	err = nil
+	return
+}
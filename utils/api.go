@@ -20,6 +20,7 @@ var (
 	physicalContinaerLayoutNameConfSectionPrefix = "PhysicalContainerLayout:"
 	flowControlNameConfSectionPrefix             = "FlowControl:"
 	peerNameConfSectionPrefix                    = "Peer:"
+	objectStoreNameConfSectionPrefix             = "ObjectStore:" // always namespaced (no un-namespaced form ever existed)
 )
 
 // TODO: Remove AdjustConfSectionNamespacingAsNecessary() when no longer needed
@@ -82,6 +83,11 @@ func PeerNameConfSection(peerName string) (sectionName string) {
 	return
 }
 
+func ObjectStoreNameConfSection(objectStoreName string) (sectionName string) {
+	sectionName = objectStoreNameConfSectionPrefix + objectStoreName
+	return
+}
+
 // TryLockMutex is used to support a timeout a the lock request
 type TryLockMutex struct {
 	c chan struct{} // a  lock()    request             writes a struct{} to   c